@@ -30,8 +30,43 @@ const (
 	// The value is interpreted as a token, and must equal the value of
 	// meta.ReconcileRequestAnnotation in order to reset the failure counts.
 	ResetRequestAnnotation string = "reconcile.fluxcd.io/resetAt"
+
+	// DebugAnnotation is the annotation used for enabling debug logging for
+	// the reconciliations of a single HelmRelease. When set to "true", the
+	// debug logs of the HelmRelease are logged regardless of the log level
+	// of the controller, and more Helm log lines are retained.
+	DebugAnnotation string = "helm.toolkit.fluxcd.io/debug"
+
+	// ValuesChecksumAnnotation is the annotation set on the pod templates of
+	// the workloads of a release by the ValuesChecksum post-renderer. Its
+	// value is the checksum of the selected configuration.
+	ValuesChecksumAnnotation string = "helm.toolkit.fluxcd.io/values-checksum"
 )
 
+// RolloutGroupLabel is the label used for adding a HelmRelease to a rollout
+// group. The value is the name of the group. The controller limits the number
+// of HelmReleases in a group which upgrade their release at the same time,
+// and holds back the upgrades of the other members of the group until those
+// have become ready.
+const RolloutGroupLabel string = "helm.toolkit.fluxcd.io/rollout-group"
+
+// PullSecretWatchLabel is the label which must be set to "true" on a Secret
+// with the credentials of the source of a HelmRelease, for the controller to
+// watch it for credential rotations. Other Secrets are not watched.
+const PullSecretWatchLabel string = "helm.toolkit.fluxcd.io/watch"
+
+// IsDebugEnabled returns true if the HelmRelease has the DebugAnnotation set
+// to "true".
+func IsDebugEnabled(obj *HelmRelease) bool {
+	return obj.GetAnnotations()[DebugAnnotation] == "true"
+}
+
+// GetRolloutGroup returns the name of the rollout group of the HelmRelease,
+// or an empty string if it is not a member of a rollout group.
+func GetRolloutGroup(obj *HelmRelease) string {
+	return obj.GetLabels()[RolloutGroupLabel]
+}
+
 // ShouldHandleResetRequest returns true if the HelmRelease has a reset request
 // annotation, and the value of the annotation matches the value of the
 // meta.ReconcileRequestAnnotation annotation.
@@ -163,3 +163,29 @@ func Test_handleRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestIsDebugEnabled(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        bool
+	}{
+		{name: "enabled", annotations: map[string]string{DebugAnnotation: "true"}, want: true},
+		{name: "disabled", annotations: map[string]string{DebugAnnotation: "false"}, want: false},
+		{name: "invalid value", annotations: map[string]string{DebugAnnotation: "yes"}, want: false},
+		{name: "missing annotation", annotations: map[string]string{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tt.annotations,
+				},
+			}
+			if got := IsDebugEnabled(obj); got != tt.want {
+				t.Errorf("IsDebugEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
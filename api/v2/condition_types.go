@@ -29,6 +29,54 @@ const (
 	// (uninstall/rollback) due to a failure of the last release attempt against the
 	// latest desired state.
 	RemediatedCondition string = "Remediated"
+
+	// UpgradeAvailableCondition represents the fact that the source of the
+	// HelmRelease advertises a newer chart version than the version of the
+	// latest release.
+	UpgradeAvailableCondition string = "UpgradeAvailable"
+
+	// ReferencesValidCondition represents the status of the validation of the
+	// Secrets and ConfigMaps referenced by the HelmRelease.
+	ReferencesValidCondition string = "ReferencesValid"
+
+	// PinnedCondition represents the fact that the Helm release of the
+	// HelmRelease is pinned to its current revision.
+	PinnedCondition string = "Pinned"
+
+	// SuspendedCondition represents the fact that the reconciliation of the
+	// HelmRelease is suspended.
+	SuspendedCondition string = "Suspended"
+
+	// RolloutGroupCondition represents the status of the HelmRelease in the
+	// coordinated rollout of its rollout group.
+	RolloutGroupCondition string = "RolloutGroup"
+
+	// SecurityScanPassedCondition represents the status of the check of the
+	// vulnerability attestations of the chart of the HelmRelease against the
+	// chart vulnerability policy of the controller.
+	SecurityScanPassedCondition string = "SecurityScanPassed"
+
+	// PreflightPassedCondition represents the status of the preflight checks
+	// of the Helm upgrade of the HelmRelease against the target cluster.
+	PreflightPassedCondition string = "PreflightPassed"
+
+	// RolloutApprovedCondition represents the approval of the pending Helm
+	// upgrade of the HelmRelease. It is set by an external controller, e.g.
+	// a progressive delivery controller, when Upgrade.RequireApproval is set.
+	RolloutApprovedCondition string = "RolloutApproved"
+
+	// ValuesTypesValidCondition represents the status of the comparison of
+	// the types of the values of the HelmRelease to the types expected by
+	// the chart.
+	ValuesTypesValidCondition string = "ValuesTypesValid"
+
+	// FrozenCondition represents the fact that the Helm actions of the
+	// HelmRelease are held by a cluster-wide change freeze.
+	FrozenCondition string = "Frozen"
+
+	// MigratedCondition represents the status of the migration of an
+	// existing Helm release to the management of the HelmRelease.
+	MigratedCondition string = "Migrated"
 )
 
 const (
@@ -48,6 +96,10 @@ const (
 	// HelmRelease failed.
 	UpgradeFailedReason string = "UpgradeFailed"
 
+	// UpgradePreviewReason represents the fact that the Helm upgrade for the
+	// HelmRelease is about to make the listed changes.
+	UpgradePreviewReason string = "UpgradePreview"
+
 	// TestSucceededReason represents the fact that the Helm tests for the
 	// HelmRelease succeeded.
 	TestSucceededReason string = "TestSucceeded"
@@ -72,11 +124,189 @@ const (
 	// HelmRelease failed.
 	UninstallFailedReason string = "UninstallFailed"
 
+	// UninstallPreviewReason represents the fact that the Helm uninstall for
+	// the HelmRelease is about to delete the listed resources.
+	UninstallPreviewReason string = "UninstallPreview"
+
+	// UninstallForcedReason represents the fact that the Helm uninstall for
+	// the deleted HelmRelease was given up on after the force timeout.
+	UninstallForcedReason string = "UninstallForced"
+
+	// ReleaseOrphanedReason represents the fact that the Helm storage records
+	// of the release of the deleted HelmRelease were removed, leaving its
+	// resources in the cluster.
+	ReleaseOrphanedReason string = "ReleaseOrphaned"
+
+	// ReleasePinnedReason represents the fact that the Helm release of the
+	// HelmRelease is pinned to its current revision.
+	ReleasePinnedReason string = "ReleasePinned"
+
+	// ReconciliationSuspendedReason represents the fact that the
+	// reconciliation of the HelmRelease is suspended.
+	ReconciliationSuspendedReason string = "ReconciliationSuspended"
+
+	// CanaryAnalysisReason represents the fact that the canary release of
+	// the Helm upgrade of the HelmRelease is being analyzed.
+	CanaryAnalysisReason string = "CanaryAnalysis"
+
+	// CanarySucceededReason represents the fact that the canary release of
+	// the Helm upgrade of the HelmRelease remained ready for the analysis
+	// window.
+	CanarySucceededReason string = "CanarySucceeded"
+
+	// CanaryFailedReason represents the fact that the canary release of the
+	// Helm upgrade of the HelmRelease failed, and the release was not
+	// upgraded.
+	CanaryFailedReason string = "CanaryFailed"
+
+	// ApprovalPendingReason represents the fact that the Helm upgrade of the
+	// HelmRelease waits for the RolloutApproved condition.
+	ApprovalPendingReason string = "ApprovalPending"
+
+	// ChangeFreezeReason represents the fact that the Helm action of the
+	// HelmRelease is held by a cluster-wide change freeze.
+	ChangeFreezeReason string = "ChangeFreeze"
+
+	// MigrationPendingReason represents the fact that the Helm release of the
+	// HelmRelease is observed only, until it is migrated.
+	MigrationPendingReason string = "MigrationPending"
+
+	// RolloutWaitingReason represents the fact that the Helm upgrade of the
+	// HelmRelease waits for other members of its rollout group to complete
+	// their upgrade.
+	RolloutWaitingReason string = "RolloutWaiting"
+
+	// RolloutProgressingReason represents the fact that the HelmRelease is
+	// one of the members of its rollout group which are allowed to upgrade.
+	RolloutProgressingReason string = "RolloutProgressing"
+
+	// RenderSucceededReason represents the fact that the manifest of the Helm
+	// release of the HelmRelease was rendered in render-only mode.
+	RenderSucceededReason string = "RenderSucceeded"
+
+	// RenderFailedReason represents the fact that the manifest of the Helm
+	// release of the HelmRelease failed to render, in render-only mode or
+	// during a Helm install or upgrade.
+	RenderFailedReason string = "RenderFailed"
+
+	// PolicyViolationReason represents the fact that the Helm install or
+	// upgrade of the HelmRelease was not performed, because the rendered Pods
+	// violate the Pod Security Standard enforced on the release namespace, or
+	// reference container images without a valid signature.
+	PolicyViolationReason string = "PolicyViolation"
+
+	// QuotaExceededReason represents the fact that the Helm install or
+	// upgrade of the HelmRelease was not performed, because the compute
+	// resources requested by the rendered workloads would exceed a
+	// ResourceQuota of the release namespace.
+	QuotaExceededReason string = "QuotaExceeded"
+
+	// IncompatibleAPIsReason represents the fact that the Helm install or
+	// upgrade of the HelmRelease was not performed, because rendered objects
+	// use API versions which are not served by the target cluster.
+	IncompatibleAPIsReason string = "IncompatibleAPIs"
+
+	// InvalidCustomResourcesReason represents the fact that the Helm install
+	// or upgrade of the HelmRelease was not performed, because rendered
+	// custom resources do not match the schemas of their
+	// CustomResourceDefinitions.
+	InvalidCustomResourcesReason string = "InvalidCustomResources"
+
+	// RecreateRequiredReason represents the fact that the Helm upgrade of the
+	// HelmRelease was not performed, because it would require StatefulSets or
+	// PersistentVolumeClaims to be recreated, and Upgrade.AllowRecreate is
+	// not set.
+	RecreateRequiredReason string = "RecreateRequired"
+
+	// StorageTooLargeReason represents the fact that the Helm install or
+	// upgrade of the HelmRelease failed, because the release exceeds the size
+	// limit of the Helm storage Secret.
+	StorageTooLargeReason string = "StorageTooLarge"
+
+	// StorageFailedReason represents the fact that the Helm install or upgrade
+	// of the HelmRelease failed, because the Helm storage could not be read
+	// or written.
+	StorageFailedReason string = "StorageFailed"
+
+	// AdmissionDeniedReason represents the fact that the Helm install or
+	// upgrade of the HelmRelease failed, because a request was denied by an
+	// admission controller of the cluster.
+	AdmissionDeniedReason string = "AdmissionDenied"
+
+	// RBACDeniedReason represents the fact that the Helm install or upgrade of
+	// the HelmRelease failed, because the identity used by the controller is
+	// not permitted to perform a request.
+	RBACDeniedReason string = "RBACDenied"
+
+	// WaitTimeoutReason represents the fact that the Helm install or upgrade
+	// of the HelmRelease failed, because the resources or hooks of the release
+	// did not become ready within the timeout.
+	WaitTimeoutReason string = "WaitTimeout"
+
+	// SecurityScanSucceededReason represents the fact that the chart of the
+	// HelmRelease passed the check of its vulnerability attestations.
+	SecurityScanSucceededReason string = "SecurityScanSucceeded"
+
+	// SecurityScanFailedReason represents the fact that the chart of the
+	// HelmRelease failed the check of its vulnerability attestations.
+	SecurityScanFailedReason string = "SecurityScanFailed"
+
+	// SecurityScanErrorReason represents the fact that the vulnerability
+	// attestations of the chart of the HelmRelease could not be retrieved.
+	SecurityScanErrorReason string = "SecurityScanError"
+
+	// DependencyMismatchReason represents the fact that the versions of the
+	// dependencies bundled with the chart of the HelmRelease do not match the
+	// lock file of the chart.
+	DependencyMismatchReason string = "DependencyMismatch"
+
+	// PreflightSucceededReason represents the fact that the target cluster
+	// passed the preflight checks of the Helm upgrade of the HelmRelease.
+	PreflightSucceededReason string = "PreflightSucceeded"
+
+	// PreflightFailedReason represents the fact that the target cluster
+	// failed the preflight checks of the Helm upgrade of the HelmRelease, or
+	// that they could not be performed.
+	PreflightFailedReason string = "PreflightFailed"
+
 	// ArtifactFailedReason represents the fact that the artifact download for the
 	// HelmRelease failed.
 	ArtifactFailedReason string = "ArtifactFailed"
 
+	// AuthenticationFailedReason represents the fact that the source of the
+	// HelmRelease failed to authenticate to fetch the chart, e.g. with expired
+	// registry credentials.
+	AuthenticationFailedReason string = "AuthenticationFailed"
+
 	// DependencyNotReadyReason represents the fact that
 	// one of the dependencies is not ready.
 	DependencyNotReadyReason string = "DependencyNotReady"
+
+	// NewerVersionAvailableReason represents the fact that a newer chart
+	// version is available from the source of the HelmRelease.
+	NewerVersionAvailableReason string = "NewerVersionAvailable"
+
+	// InvalidScheduleReason represents the fact that the reconcile schedule
+	// of the HelmRelease can not be parsed.
+	InvalidScheduleReason string = "InvalidSchedule"
+
+	// InvalidReferencesReason represents the fact that one or more of the
+	// Secrets and ConfigMaps referenced by the HelmRelease do not exist or are
+	// malformed.
+	InvalidReferencesReason string = "InvalidReferences"
+
+	// ValuesTypeMismatchReason represents the fact that one or more of the
+	// values of the HelmRelease have a different type than expected by the
+	// chart, e.g. a string where the chart expects an integer.
+	ValuesTypeMismatchReason string = "ValuesTypeMismatch"
+
+	// WebhookFailedReason represents the fact that a blocking release action
+	// webhook of the HelmRelease could not be resolved, or did not respond
+	// with a 2xx status code in time.
+	WebhookFailedReason string = "WebhookFailed"
+
+	// StorageMigrationFailedReason represents the fact that the records of
+	// the Helm release could not be moved to the storage driver of the
+	// HelmRelease after it changed.
+	StorageMigrationFailedReason string = "StorageMigrationFailed"
 )
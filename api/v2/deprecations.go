@@ -0,0 +1,27 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+// DeprecatedFields is the table of deprecated fields of the HelmRelease API,
+// in any of the served versions, mapped to what should be used instead. A
+// path segment suffixed with "[]" matches every item of the list.
+var DeprecatedFields = map[string]string{
+	"spec.install.skipCRDs":                                "spec.install.crds with value Skip",
+	"spec.chart.spec.valuesFile":                           "spec.chart.spec.valuesFiles",
+	"spec.postRenderers[].kustomize.patchesStrategicMerge": "spec.postRenderers[].kustomize.patches",
+	"spec.postRenderers[].kustomize.patchesJson6902":       "spec.postRenderers[].kustomize.patches",
+}
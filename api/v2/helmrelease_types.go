@@ -17,6 +17,7 @@ limitations under the License.
 package v2
 
 import (
+	"slices"
 	"strings"
 	"time"
 
@@ -61,6 +62,32 @@ type PostRenderer struct {
 	// Kustomization to apply as PostRenderer.
 	// +optional
 	Kustomize *Kustomize `json:"kustomize,omitempty"`
+
+	// ValuesChecksum injects a checksum of the selected values, ConfigMaps
+	// and Secrets as an annotation into the pod templates of the workloads
+	// of the release, to roll out the Pods when the configuration changes.
+	// +optional
+	ValuesChecksum *ValuesChecksum `json:"valuesChecksum,omitempty"`
+}
+
+// ValuesChecksum holds the selection of the configuration of which a
+// checksum is injected into the pod templates of the workloads of a release.
+// When nothing is selected, the checksum is calculated over all values.
+type ValuesChecksum struct {
+	// Values are the dot-separated paths of the values to include in the
+	// checksum, e.g. 'config' or 'database.host'.
+	// +optional
+	Values []string `json:"values,omitempty"`
+
+	// ConfigMaps are the names of the ConfigMaps rendered by the chart of
+	// which the data is included in the checksum.
+	// +optional
+	ConfigMaps []string `json:"configMaps,omitempty"`
+
+	// Secrets are the names of the Secrets rendered by the chart of which
+	// the data is included in the checksum.
+	// +optional
+	Secrets []string `json:"secrets,omitempty"`
 }
 
 // HelmReleaseSpec defines the desired state of a Helm release.
@@ -82,6 +109,13 @@ type HelmReleaseSpec struct {
 	// +required
 	Interval metav1.Duration `json:"interval"`
 
+	// Schedule at which to reconcile the Helm release, in addition to the
+	// Interval. When the next scheduled time comes before the next
+	// reconciliation according to the Interval, the HelmRelease is reconciled
+	// at exactly the scheduled time.
+	// +optional
+	Schedule *ReconcileSchedule `json:"schedule,omitempty"`
+
 	// KubeConfig for reconciling the HelmRelease on a remote cluster.
 	// When used in combination with HelmReleaseSpec.ServiceAccountName,
 	// forces the controller to act on behalf of that Service Account at the
@@ -97,6 +131,34 @@ type HelmReleaseSpec struct {
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
 
+	// SuspendReason is an optional human-readable explanation of why the
+	// reconciliation of this HelmRelease is suspended. It is reflected in the
+	// message of the Suspended condition while Suspend is true.
+	// +kubebuilder:validation:MaxLength=256
+	// +optional
+	SuspendReason string `json:"suspendReason,omitempty"`
+
+	// Pin tells the controller to keep the Helm release at its current
+	// revision. Unlike Suspend, the reconciliation continues to run, including
+	// drift detection and correction and tests, but any action which would
+	// install, upgrade, roll back or uninstall the release is refused.
+	// Defaults to false.
+	// +optional
+	Pin bool `json:"pin,omitempty"`
+
+	// RenderOnly tells the controller to only render the manifest of the Helm
+	// release, without installing or upgrading it. The rendered manifest is
+	// written to a ConfigMap for review, and the Helm storage is left
+	// untouched. Defaults to false.
+	// +optional
+	RenderOnly bool `json:"renderOnly,omitempty"`
+
+	// Migration configures the migration of an existing Helm release, which
+	// was not made by the controller, to the management of the HelmRelease.
+	// Until the release is migrated, the controller only observes it.
+	// +optional
+	Migration *Migration `json:"migration,omitempty"`
+
 	// ReleaseName used for the Helm release. Defaults to a composition of
 	// '[TargetNamespace-]Name'.
 	// +kubebuilder:validation:MinLength=1
@@ -121,6 +183,26 @@ type HelmReleaseSpec struct {
 	// +optional
 	StorageNamespace string `json:"storageNamespace,omitempty"`
 
+	// StorageDriver is the Helm storage driver used to store the releases,
+	// either in Secrets or ConfigMaps in the StorageNamespace.
+	// Defaults to the storage driver configured on the controller. When it
+	// changes, the records of the release are moved to the new storage driver.
+	// +kubebuilder:validation:Enum=secret;configmap
+	// +optional
+	StorageDriver string `json:"storageDriver,omitempty"`
+
+	// StorageLabels are the labels added to the Helm storage Secrets of the
+	// release, in addition to the labels configured on the controller.
+	// The labels set by Helm to query releases can not be overwritten.
+	// +optional
+	StorageLabels map[string]string `json:"storageLabels,omitempty"`
+
+	// StorageAnnotations are the annotations added to the Helm storage
+	// Secrets of the release, in addition to the annotations configured on
+	// the controller.
+	// +optional
+	StorageAnnotations map[string]string `json:"storageAnnotations,omitempty"`
+
 	// DependsOn may contain a meta.NamespacedObjectReference slice with
 	// references to HelmRelease resources that must be ready before this HelmRelease
 	// can be reconciled.
@@ -134,11 +216,39 @@ type HelmReleaseSpec struct {
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
+	// WaitConcurrency is the number of resources of which the readiness is
+	// checked concurrently while waiting for the resources of a Helm action
+	// to become ready. Defaults to '1', which checks them one by one like
+	// Helm does.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	WaitConcurrency int `json:"waitConcurrency,omitempty"`
+
+	// Interruptible allows the wait for the resources of an in-flight install
+	// or upgrade to be interrupted when a new generation of this HelmRelease
+	// is observed, instead of waiting for them to become ready or time out.
+	// The interrupted release is marked as failed, and the new generation is
+	// reconciled right after.
+	// +optional
+	Interruptible bool `json:"interruptible,omitempty"`
+
 	// MaxHistory is the number of revisions saved by Helm for this HelmRelease.
 	// Use '0' for an unlimited number of revisions; defaults to '5'.
 	// +optional
 	MaxHistory *int `json:"maxHistory,omitempty"`
 
+	// History holds the configuration for the retention of the release
+	// snapshots in the status of this HelmRelease, independent of the
+	// MaxHistory of the Helm storage.
+	// +optional
+	History *HistoryRetention `json:"history,omitempty"`
+
+	// HookCleanup holds the configuration for the garbage collection of the
+	// Jobs and Pods created by the hooks of the Helm releases.
+	// +optional
+	HookCleanup *HookCleanup `json:"hookCleanup,omitempty"`
+
 	// The name of the Kubernetes service account to impersonate
 	// when reconciling this HelmRelease.
 	// +kubebuilder:validation:MinLength=1
@@ -187,6 +297,26 @@ type HelmReleaseSpec struct {
 	// +optional
 	Uninstall *Uninstall `json:"uninstall,omitempty"`
 
+	// DeletionPolicy determines what happens to the Helm release when the
+	// HelmRelease is deleted. Valid values are `Delete` or `Orphan`. Default
+	// is `Delete`.
+	//
+	// Delete: the Helm release is uninstalled.
+	//
+	// Orphan: the Helm storage records of the release are removed, but its
+	// resources are left in the cluster.
+	//
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// ValuesFiles is a list of values files bundled in the chart, e.g.
+	// 'values-production.yaml', relative to the root of the chart. They are
+	// merged in the order given, before the ValuesFrom and Values, and work
+	// for charts from a ChartRef as well as from a HelmChartTemplate.
+	// +optional
+	ValuesFiles []string `json:"valuesFiles,omitempty"`
+
 	// ValuesFrom holds references to resources containing Helm values for this HelmRelease,
 	// and information about how they should be merged.
 	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
@@ -199,6 +329,157 @@ type HelmReleaseSpec struct {
 	// of their definition.
 	// +optional
 	PostRenderers []PostRenderer `json:"postRenderers,omitempty"`
+
+	// CommonMetadata specifies the labels and annotations that are applied
+	// to all resources of the Helm release, after the PostRenderers.
+	// +optional
+	CommonMetadata *CommonMetadata `json:"commonMetadata,omitempty"`
+
+	// EventSink specifies an additional HTTP endpoint the controller posts
+	// the events of this HelmRelease to, next to notification-controller.
+	// +optional
+	EventSink *EventSink `json:"eventSink,omitempty"`
+
+	// Hooks specifies the callbacks of the Helm actions performed for this
+	// HelmRelease.
+	// +optional
+	Hooks *Hooks `json:"hooks,omitempty"`
+}
+
+// CommonMetadata defines the common labels and annotations.
+type CommonMetadata struct {
+	// Annotations to be added to the object's metadata.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Labels to be added to the object's metadata.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// EventSinkAddressKey is the key of the EventSink Secret data holding the
+// address of the endpoint.
+const EventSinkAddressKey = "address"
+
+// EventSink defines an HTTP endpoint the events of a HelmRelease are posted
+// to as JSON, in the format notification-controller receives them in.
+type EventSink struct {
+	// SecretRef references a Secret in the same namespace as the HelmRelease,
+	// with the URL of the endpoint in the 'address' key.
+	// +required
+	SecretRef meta.LocalObjectReference `json:"secretRef"`
+}
+
+// Hooks holds the callbacks of the Helm actions performed for a HelmRelease.
+type Hooks struct {
+	// Webhooks are called before and/or after the Helm install, upgrade,
+	// rollback and uninstall actions performed for the HelmRelease.
+	// +optional
+	Webhooks []ActionWebhook `json:"webhooks,omitempty"`
+}
+
+const (
+	// ActionWebhookAddressKey is the key of the ActionWebhook Secret data
+	// holding the address of the endpoint.
+	ActionWebhookAddressKey = "address"
+	// ActionWebhookTokenKey is the key of the ActionWebhook Secret data
+	// holding the key the payload is signed with.
+	ActionWebhookTokenKey = "token"
+)
+
+// WebhookPhase is the phase of a Helm action an ActionWebhook is called in.
+type WebhookPhase string
+
+const (
+	// WebhookPhasePre is the phase before the Helm action is performed.
+	WebhookPhasePre WebhookPhase = "pre"
+	// WebhookPhasePost is the phase after the Helm action has been performed.
+	WebhookPhasePost WebhookPhase = "post"
+)
+
+// ActionWebhook defines an HTTP endpoint which is sent a JSON payload
+// describing a Helm action before and/or after it is performed.
+type ActionWebhook struct {
+	// Name of the webhook, used to identify it in events.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// SecretRef references a Secret in the same namespace as the HelmRelease,
+	// with the URL of the endpoint in the 'address' key. When the Secret has
+	// a 'token' key, the payload is signed with it using HMAC-SHA256, and
+	// the signature is sent in the 'X-Signature' header.
+	// +required
+	SecretRef meta.LocalObjectReference `json:"secretRef"`
+
+	// Actions are the Helm actions the webhook is called for. Defaults to
+	// all of them.
+	// +kubebuilder:validation:items:Enum=install;upgrade;rollback;uninstall
+	// +optional
+	Actions []string `json:"actions,omitempty"`
+
+	// Phases are the phases of the Helm actions the webhook is called in.
+	// Defaults to both.
+	// +kubebuilder:validation:items:Enum=pre;post
+	// +optional
+	Phases []WebhookPhase `json:"phases,omitempty"`
+
+	// Blocking makes the controller wait for the webhook to respond with a
+	// 2xx status code. When a blocking webhook fails in the pre phase, the
+	// Helm action is not performed and retried later. Other webhooks are
+	// called in the background, and their failures are only logged.
+	// +optional
+	Blocking bool `json:"blocking,omitempty"`
+
+	// Timeout is the time to wait for the webhook to respond. Defaults to
+	// '30s'.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// defaultActionWebhookTimeout is the default ActionWebhook.Timeout.
+const defaultActionWebhookTimeout = 30 * time.Second
+
+// GetTimeout returns the configured timeout, or the default.
+func (in ActionWebhook) GetTimeout() time.Duration {
+	if in.Timeout == nil {
+		return defaultActionWebhookTimeout
+	}
+	return in.Timeout.Duration
+}
+
+// actionWebhookActions are the Helm actions an ActionWebhook can be called
+// for.
+var actionWebhookActions = []string{"install", "upgrade", "rollback", "uninstall"}
+
+// Matches returns if the webhook is called in the given phase of the given
+// Helm action.
+func (in ActionWebhook) Matches(action string, phase WebhookPhase) bool {
+	actions := in.Actions
+	if len(actions) == 0 {
+		actions = actionWebhookActions
+	}
+	return slices.Contains(actions, action) && (len(in.Phases) == 0 || slices.Contains(in.Phases, phase))
+}
+
+// ReconcileSchedule defines a cron schedule at which a HelmRelease is
+// reconciled.
+type ReconcileSchedule struct {
+	// Cron is the schedule in the standard five field cron format of
+	// minute, hour, day of month, month and day of week, e.g. "0 2 * * *"
+	// for every day at 02:00. The shorthands @hourly, @daily, @weekly,
+	// @monthly and @yearly are supported as well.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Cron string `json:"cron"`
+
+	// TimeZone is the IANA name of the time zone the Cron schedule is
+	// evaluated in, e.g. "Europe/Amsterdam". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 // DriftDetectionMode represents the modes in which a controller can detect and
@@ -238,6 +519,17 @@ var (
 	DriftDetectionDisabledValue = "disabled"
 )
 
+// Migration defines the migration of an existing Helm release to the
+// management of a HelmRelease.
+type Migration struct {
+	// Migrated hands over the management of the release to the controller.
+	// Until set, the controller records the history of the existing release,
+	// and reports on its drift and health, but does not install, upgrade,
+	// test, roll back, uninstall or correct the release. Defaults to false.
+	// +optional
+	Migrated bool `json:"migrated,omitempty"`
+}
+
 // IgnoreRule defines a rule to selectively disregard specific changes during
 // the drift detection process.
 type IgnoreRule struct {
@@ -442,6 +734,13 @@ type Install struct {
 	// +optional
 	DisableWaitForJobs bool `json:"disableWaitForJobs,omitempty"`
 
+	// WaitStrategies configures the strategy to wait for the resources of a
+	// kind to be ready after a Helm install has been performed. Resources of
+	// kinds without a strategy are awaited by Helm. Has no effect when
+	// DisableWait is set.
+	// +optional
+	WaitStrategies []WaitStrategy `json:"waitStrategies,omitempty"`
+
 	// DisableHooks prevents hooks from running during the Helm install action.
 	// +optional
 	DisableHooks bool `json:"disableHooks,omitempty"`
@@ -491,6 +790,13 @@ type Install struct {
 	// On uninstall, the namespace will not be garbage collected.
 	// +optional
 	CreateNamespace bool `json:"createNamespace,omitempty"`
+
+	// NamespaceMetadata holds the labels and annotations to apply to the
+	// HelmReleaseSpec.TargetNamespace when CreateNamespace is set. They are
+	// applied when the namespace is created, and kept reconciled afterwards.
+	// Other labels and annotations of the namespace are left untouched.
+	// +optional
+	NamespaceMetadata *CommonMetadata `json:"namespaceMetadata,omitempty"`
 }
 
 // GetTimeout returns the configured timeout for the Helm install action,
@@ -589,6 +895,51 @@ const (
 	CreateReplace CRDsPolicy = "CreateReplace"
 )
 
+// WaitStrategyType is the strategy to wait for resources to be ready.
+type WaitStrategyType string
+
+const (
+	// HelmWaitStrategy waits for resources to be ready as Helm does.
+	HelmWaitStrategy WaitStrategyType = "Helm"
+	// KstatusWaitStrategy waits for resources to be ready according to
+	// kstatus, i.e. their status conditions and observed generation.
+	KstatusWaitStrategy WaitStrategyType = "Kstatus"
+	// CELWaitStrategy waits for resources to be ready according to CEL
+	// expressions.
+	CELWaitStrategy WaitStrategyType = "CEL"
+	// SkipWaitStrategy does not wait for resources to be ready.
+	SkipWaitStrategy WaitStrategyType = "Skip"
+)
+
+// WaitStrategy configures the strategy to wait for the resources of a kind
+// to be ready after a Helm action.
+type WaitStrategy struct {
+	// APIVersion of the resources, e.g. 'apps/v1'.
+	// +required
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the resources, e.g. 'Deployment'.
+	// +required
+	Kind string `json:"kind"`
+
+	// Strategy to wait for the resources to be ready.
+	// +kubebuilder:validation:Enum=Helm;Kstatus;CEL;Skip
+	// +required
+	Strategy WaitStrategyType `json:"strategy"`
+
+	// Ready is a CEL expression which evaluates to true when a resource is
+	// ready, e.g. 'self.status.phase == "Running"'. The resource is available
+	// as 'self'. Required by the CEL strategy.
+	// +optional
+	Ready string `json:"ready,omitempty"`
+
+	// Failed is a CEL expression which evaluates to true when a resource has
+	// failed, which stops the waiting. The resource is available as 'self'.
+	// Only used by the CEL strategy.
+	// +optional
+	Failed string `json:"failed,omitempty"`
+}
+
 // Upgrade holds the configuration for Helm upgrade actions for this
 // HelmRelease.
 type Upgrade struct {
@@ -615,6 +966,13 @@ type Upgrade struct {
 	// +optional
 	DisableWaitForJobs bool `json:"disableWaitForJobs,omitempty"`
 
+	// WaitStrategies configures the strategy to wait for the resources of a
+	// kind to be ready after a Helm upgrade has been performed. Resources of
+	// kinds without a strategy are awaited by Helm. Has no effect when
+	// DisableWait is set.
+	// +optional
+	WaitStrategies []WaitStrategy `json:"waitStrategies,omitempty"`
+
 	// DisableHooks prevents hooks from running during the Helm upgrade action.
 	// +optional
 	DisableHooks bool `json:"disableHooks,omitempty"`
@@ -658,8 +1016,183 @@ type Upgrade struct {
 	// +kubebuilder:validation:Enum=Skip;Create;CreateReplace
 	// +optional
 	CRDs CRDsPolicy `json:"crds,omitempty"`
+
+	// Validation configures the validation of the rendered manifests before
+	// the Helm upgrade action is performed. Valid values are `None` or
+	// `DryRun`. Default is `None`.
+	//
+	// None: the manifests are not validated before the upgrade.
+	//
+	// DryRun: a server-side dry-run apply of the manifests is performed, and
+	// the upgrade fails with the errors returned by the Kubernetes API server
+	// (including admission webhooks) without modifying the Helm storage.
+	//
+	// +kubebuilder:validation:Enum=None;DryRun
+	// +optional
+	Validation ValidationPolicy `json:"validation,omitempty"`
+
+	// Canary configures the release of an upgrade as a canary release to a
+	// separate namespace first. The release is only upgraded after the
+	// canary release has remained ready for the analysis window, and is not
+	// upgraded when the analysis fails.
+	// +optional
+	Canary *UpgradeCanary `json:"canary,omitempty"`
+
+	// Preflight configures checks against the target cluster which must
+	// pass before the Helm upgrade action is performed. When a check fails,
+	// the upgrade is not performed and retried with a backoff.
+	// +optional
+	Preflight *UpgradePreflight `json:"preflight,omitempty"`
+
+	// RequireApproval makes the Helm upgrade wait for the RolloutApproved
+	// condition of the HelmRelease to be set to True, for example by a
+	// progressive delivery controller. The chart version and workloads of
+	// the pending upgrade are published in the status as PendingRollout.
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// AllowRecreate allows the Helm upgrade to proceed when it changes
+	// immutable fields of StatefulSets or PersistentVolumeClaims, or removes
+	// PersistentVolumeClaims from the release. By default, such an upgrade
+	// is not performed, as it would either fail midway or recreate the
+	// objects and their storage.
+	// +optional
+	AllowRecreate bool `json:"allowRecreate,omitempty"`
+}
+
+// UpgradePreflight holds the configuration for the preflight checks of a
+// Helm upgrade.
+type UpgradePreflight struct {
+	// RequiredCRDs are the names of the CustomResourceDefinitions which must
+	// be established in the target cluster, e.g.
+	// 'certificates.cert-manager.io'.
+	// +optional
+	RequiredCRDs []string `json:"requiredCRDs,omitempty"`
+
+	// MinKubernetesVersion is the minimum version of the Kubernetes API
+	// server of the target cluster, e.g. '1.28' or '1.28.3'.
+	// +kubebuilder:validation:Pattern="^v?[0-9]+\\.[0-9]+(\\.[0-9]+)?$"
+	// +optional
+	MinKubernetesVersion string `json:"minKubernetesVersion,omitempty"`
+
+	// RequiredStorageClasses are the names of the StorageClasses which must
+	// exist in the target cluster.
+	// +optional
+	RequiredStorageClasses []string `json:"requiredStorageClasses,omitempty"`
+
+	// Checks are custom checks, which evaluate a CEL expression against an
+	// object in the target cluster.
+	// +optional
+	Checks []PreflightCheck `json:"checks,omitempty"`
 }
 
+// PreflightCheck is a custom preflight check, which passes when a CEL
+// expression evaluates to true for an object in the target cluster.
+type PreflightCheck struct {
+	// Name of the check, reported when the check fails.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// Object is a reference to the object in the target cluster the
+	// expression is evaluated against. The check fails when the object does
+	// not exist.
+	// +required
+	Object PreflightObjectReference `json:"object"`
+
+	// Expression is a CEL expression which evaluates to true when the check
+	// passes, e.g. 'self.data.ready == "true"'. The object is available as
+	// 'self'.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Expression string `json:"expression"`
+
+	// Message is reported when the check fails. Defaults to the expression.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// PreflightObjectReference is a reference to an object in the target
+// cluster of a preflight check.
+type PreflightObjectReference struct {
+	// APIVersion of the object, e.g. 'v1'.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the object, e.g. 'ConfigMap'.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the object.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the object. Defaults to the namespace of the release for
+	// namespaced objects.
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// UpgradeCanary holds the configuration for the canary analysis of a Helm
+// upgrade.
+type UpgradeCanary struct {
+	// TargetNamespace is the namespace the canary release is installed to.
+	// It must differ from the namespace of the release.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	TargetNamespace string `json:"targetNamespace"`
+
+	// Window is the duration the canary release must remain ready before the
+	// release is upgraded. Defaults to '5m'.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+// GetWindow returns the configured analysis window, or the default of five
+// minutes.
+func (in UpgradeCanary) GetWindow() metav1.Duration {
+	if in.Window == nil {
+		return metav1.Duration{Duration: 5 * time.Minute}
+	}
+	return *in.Window
+}
+
+// CanaryPhase is the phase of the analysis of a canary release.
+type CanaryPhase string
+
+const (
+	// CanaryPhaseAnalyzing is the CanaryPhase of a canary release which is
+	// being analyzed.
+	CanaryPhaseAnalyzing CanaryPhase = "Analyzing"
+	// CanaryPhaseSucceeded is the CanaryPhase of a canary release which
+	// remained ready for the analysis window.
+	CanaryPhaseSucceeded CanaryPhase = "Succeeded"
+	// CanaryPhaseFailed is the CanaryPhase of a canary release which failed
+	// to be released, or did not remain ready for the analysis window.
+	CanaryPhaseFailed CanaryPhase = "Failed"
+)
+
+// ValidationPolicy defines the validation approach to use for the rendered
+// manifests before a Helm action is performed.
+type ValidationPolicy string
+
+const (
+	// ValidationNone does not validate the manifests.
+	ValidationNone ValidationPolicy = "None"
+	// ValidationDryRun validates the manifests using a server-side dry-run
+	// apply.
+	ValidationDryRun ValidationPolicy = "DryRun"
+)
+
 // GetTimeout returns the configured timeout for the Helm upgrade action, or the
 // given default.
 func (in Upgrade) GetTimeout(defaultTimeout metav1.Duration) metav1.Duration {
@@ -843,6 +1376,13 @@ type Rollback struct {
 	// +optional
 	DisableHooks bool `json:"disableHooks,omitempty"`
 
+	// DisableTestHooks prevents the Helm tests from running for the release
+	// created by a Helm rollback action, even when tests are enabled with
+	// HelmReleaseSpec.Test. This avoids a remediation being blocked by the
+	// test suite which just failed.
+	// +optional
+	DisableTestHooks bool `json:"disableTestHooks,omitempty"`
+
 	// Recreate performs pod restarts for the resource if applicable.
 	// +optional
 	Recreate bool `json:"recreate,omitempty"`
@@ -877,7 +1417,10 @@ type Uninstall struct {
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
-	// DisableHooks prevents hooks from running during the Helm rollback action.
+	// DisableHooks prevents hooks from running during the Helm uninstall
+	// action, both when the HelmRelease is deleted and when the release is
+	// uninstalled as remediation. This prevents a failing pre-delete hook
+	// from blocking the uninstall.
 	// +optional
 	DisableHooks bool `json:"disableHooks,omitempty"`
 
@@ -887,7 +1430,8 @@ type Uninstall struct {
 	KeepHistory bool `json:"keepHistory,omitempty"`
 
 	// DisableWait disables waiting for all the resources to be deleted after
-	// a Helm uninstall is performed.
+	// a Helm uninstall is performed, both when the HelmRelease is deleted and
+	// when the release is uninstalled as remediation.
 	// +optional
 	DisableWait bool `json:"disableWait,omitempty"`
 
@@ -897,6 +1441,73 @@ type Uninstall struct {
 	// +kubebuilder:validation:Enum=background;foreground;orphan
 	// +optional
 	DeletionPropagation *string `json:"deletionPropagation,omitempty"`
+
+	// KeepResources selects the resources of the Helm release which are kept
+	// when a Helm uninstall is performed, as if they were annotated with the
+	// Helm 'helm.sh/resource-policy: keep' annotation.
+	// +optional
+	KeepResources []kustomize.Selector `json:"keepResources,omitempty"`
+
+	// ForceTimeout is the time to wait after the deletion of the HelmRelease
+	// for the Helm uninstall to succeed. Once it has passed, a failing
+	// uninstall is given up on, and the HelmRelease is finalized while the
+	// Helm release and its resources are left behind. By default, the
+	// uninstall is retried until it succeeds.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	ForceTimeout *metav1.Duration `json:"forceTimeout,omitempty"`
+
+	// Retry configures the backoff with which a failing Helm uninstall is
+	// retried after the deletion of the HelmRelease. By default, the
+	// uninstall is retried with the backoff of the controller.
+	// +optional
+	Retry *UninstallRetry `json:"retry,omitempty"`
+}
+
+// UninstallRetry defines the backoff with which a failing Helm uninstall is
+// retried after the deletion of the HelmRelease.
+// +kubebuilder:validation:XValidation:rule="duration(self.interval) >= duration('1s')", message="interval must be at least 1s"
+// +kubebuilder:validation:XValidation:rule="!has(self.maxInterval) || duration(self.maxInterval) >= duration('1s')", message="maxInterval must be at least 1s"
+type UninstallRetry struct {
+	// Interval is the time to wait before the first retry of a failed Helm
+	// uninstall. It doubles with every subsequent failure. Must be at least
+	// '1s'.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +required
+	Interval metav1.Duration `json:"interval"`
+
+	// MaxInterval is the maximum time to wait between two retries of a
+	// failed Helm uninstall. Defaults to '15m', and must be at least '1s'.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	MaxInterval *metav1.Duration `json:"maxInterval,omitempty"`
+}
+
+const (
+	// defaultUninstallRetryMaxInterval is the default
+	// UninstallRetry.MaxInterval.
+	defaultUninstallRetryMaxInterval = 15 * time.Minute
+	// minUninstallRetryInterval is the minimum UninstallRetry.Interval and
+	// UninstallRetry.MaxInterval.
+	minUninstallRetryInterval = time.Second
+)
+
+// GetDelay returns the time to wait before retrying a Helm uninstall which
+// has failed the given number of times. It is never less than a second, to
+// not retry without a delay for an interval which has not been validated.
+func (in UninstallRetry) GetDelay(failures int64) time.Duration {
+	maxInterval := defaultUninstallRetryMaxInterval
+	if in.MaxInterval != nil {
+		maxInterval = max(in.MaxInterval.Duration, minUninstallRetryInterval)
+	}
+	delay := max(in.Interval.Duration, minUninstallRetryInterval)
+	for i := int64(1); i < failures && delay < maxInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, maxInterval)
 }
 
 // GetTimeout returns the configured timeout for the Helm uninstall action, or
@@ -917,6 +1528,18 @@ func (in Uninstall) GetDeletionPropagation() string {
 	return *in.DeletionPropagation
 }
 
+// DeletionPolicy defines what happens to the Helm release when the
+// HelmRelease is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete uninstalls the Helm release.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan removes the Helm storage records of the release,
+	// leaving its resources in the cluster.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
 // ReleaseAction is the action to perform a Helm release.
 type ReleaseAction string
 
@@ -927,6 +1550,56 @@ const (
 	ReleaseActionUpgrade ReleaseAction = "upgrade"
 )
 
+// HistoryRetention holds the configuration for the retention of the release
+// snapshots in the status of a HelmRelease.
+//
+// The latest snapshot, and the snapshot of the previous successful release
+// which is used as rollback target, are always retained.
+type HistoryRetention struct {
+	// MaxSnapshots is the maximum number of snapshots retained in the status
+	// history. When unset, the number of snapshots is not limited beyond
+	// the default truncation of the history.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxSnapshots *int `json:"maxSnapshots,omitempty"`
+
+	// TTL is the duration after which a snapshot is pruned from the status
+	// history, measured from when its release was last deployed.
+	// When unset, snapshots are not pruned based on their age.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// HookCleanup holds the configuration for the garbage collection of the
+// leftovers of Helm hooks, like completed Jobs and test Pods, independent of
+// the hook deletion policies of the chart.
+type HookCleanup struct {
+	// TTL is the duration after which a finished Job or Pod created by a
+	// hook is deleted, measured from when it finished.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +required
+	TTL metav1.Duration `json:"ttl"`
+}
+
+// GetMaxSnapshots returns the configured MaxSnapshots, or 0 if unset.
+func (in HistoryRetention) GetMaxSnapshots() int {
+	if in.MaxSnapshots == nil {
+		return 0
+	}
+	return *in.MaxSnapshots
+}
+
+// GetTTL returns the configured TTL, or 0 if unset.
+func (in HistoryRetention) GetTTL() time.Duration {
+	if in.TTL == nil {
+		return 0
+	}
+	return in.TTL.Duration
+}
+
 // HelmReleaseStatus defines the observed state of a HelmRelease.
 type HelmReleaseStatus struct {
 	// ObservedGeneration is the last observed generation.
@@ -960,6 +1633,11 @@ type HelmReleaseStatus struct {
 	// +optional
 	StorageNamespace string `json:"storageNamespace,omitempty"`
 
+	// StorageDriver is the name of the Helm storage driver the current
+	// release is stored with, e.g. "Secret" or "ConfigMap".
+	// +optional
+	StorageDriver string `json:"storageDriver,omitempty"`
+
 	// History holds the history of Helm releases performed for this HelmRelease
 	// up to the last successfully completed release.
 	// +optional
@@ -1013,6 +1691,12 @@ type HelmReleaseStatus struct {
 	// +optional
 	LastAttemptedConfigDigest string `json:"lastAttemptedConfigDigest,omitempty"`
 
+	// DigestAlgorithm is the canonical algorithm the controller calculated
+	// the digests of the last reconciliation attempt with, e.g. of the
+	// LastAttemptedConfigDigest and the History.
+	// +optional
+	DigestAlgorithm string `json:"digestAlgorithm,omitempty"`
+
 	// LastHandledForceAt holds the value of the most recent force request
 	// value, so a change of the annotation value can be detected.
 	// +optional
@@ -1023,9 +1707,251 @@ type HelmReleaseStatus struct {
 	// +optional
 	LastHandledResetAt string `json:"lastHandledResetAt,omitempty"`
 
+	// LastReleaseAttempt holds the details of the last failed Helm action
+	// performed for this HelmRelease. It is cleared after a successful
+	// install or upgrade.
+	// +optional
+	LastReleaseAttempt *ReleaseAttempt `json:"lastReleaseAttempt,omitempty"`
+
+	// UninstallFailures is the count of the Helm uninstall failures since
+	// the deletion of the HelmRelease.
+	// +optional
+	UninstallFailures int64 `json:"uninstallFailures,omitempty"`
+
+	// UninstallAttempts holds the last failed Helm uninstall attempts since
+	// the deletion of the HelmRelease, with the most recent attempt last.
+	// +optional
+	UninstallAttempts []UninstallAttempt `json:"uninstallAttempts,omitempty"`
+
+	// Inventory contains the list of Kubernetes resource object references
+	// of the current Helm release, excluding hooks.
+	// +optional
+	Inventory *ResourceInventory `json:"inventory,omitempty"`
+
+	// HookInventory contains the list of Kubernetes resource object
+	// references of the Jobs and Pods created by the hooks of the Helm
+	// releases, which are garbage collected when HookCleanup is configured.
+	// +optional
+	HookInventory *ResourceInventory `json:"hookInventory,omitempty"`
+
+	// LastUpgradeDiff holds a summary of the changes made to the objects of
+	// the Helm release by the last successful upgrade.
+	// +optional
+	LastUpgradeDiff *DiffSummary `json:"lastUpgradeDiff,omitempty"`
+
+	// Canary holds the state of the analysis of the canary release of the
+	// last upgrade with Upgrade.Canary configured.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+
+	// PendingRollout holds the Helm upgrade which waits for approval, when
+	// Upgrade.RequireApproval is set.
+	// +optional
+	PendingRollout *PendingRollout `json:"pendingRollout,omitempty"`
+
+	// LastRenderedManifestDigest is the digest of the manifest rendered while
+	// RenderOnly is enabled.
+	// +optional
+	LastRenderedManifestDigest string `json:"lastRenderedManifestDigest,omitempty"`
+
+	// NextReconcileAt is the time at which the controller is expected to
+	// reconcile this HelmRelease next. When the last reconciliation failed,
+	// this is the time of the retry according to the backoff of the
+	// controller.
+	// +optional
+	NextReconcileAt *metav1.Time `json:"nextReconcileAt,omitempty"`
+
 	meta.ReconcileRequestStatus `json:",inline"`
 }
 
+// ReleaseAttempt holds the details of a failed Helm action.
+type ReleaseAttempt struct {
+	// Action is the name of the Helm action which failed, e.g. "upgrade".
+	// +required
+	Action string `json:"action"`
+
+	// Time is when the Helm action failed.
+	// +required
+	Time metav1.Time `json:"time"`
+
+	// Logs holds the last lines of the Helm debug logs of the action as
+	// structured entries, with messages truncated to 4096 bytes in total and
+	// with sensitive values redacted.
+	// +optional
+	Logs []LogEntry `json:"logs,omitempty"`
+}
+
+// UninstallAttempt holds the details of a failed Helm uninstall after the
+// deletion of a HelmRelease.
+type UninstallAttempt struct {
+	// Time is when the Helm uninstall failed.
+	// +required
+	Time metav1.Time `json:"time"`
+
+	// Reason is the reason of the Ready condition after the failure.
+	// +required
+	Reason string `json:"reason"`
+
+	// Message is the error of the Helm uninstall.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// LogEntry is a line of the Helm debug logs of an action.
+type LogEntry struct {
+	// Time is when the message was logged.
+	// +required
+	Time metav1.Time `json:"time"`
+
+	// Level is the level of the message, derived from its contents.
+	// +kubebuilder:validation:Enum=info;warning;error
+	// +required
+	Level string `json:"level"`
+
+	// Component is the Helm component which logged the message, e.g. "kube"
+	// or "storage".
+	// +optional
+	Component string `json:"component,omitempty"`
+
+	// Message is the log message.
+	// +required
+	Message string `json:"message"`
+
+	// Duplicates is the number of times the message was repeated
+	// consecutively.
+	// +optional
+	Duplicates int64 `json:"duplicates,omitempty"`
+}
+
+// CanaryStatus holds the state of the analysis of a canary release.
+type CanaryStatus struct {
+	// Name is the name of the canary release.
+	// +required
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the canary release.
+	// +required
+	Namespace string `json:"namespace"`
+
+	// ChartVersion is the version of the chart of the canary release.
+	// +required
+	ChartVersion string `json:"chartVersion"`
+
+	// ConfigDigest is the digest of the values of the canary release.
+	// +required
+	ConfigDigest string `json:"configDigest"`
+
+	// Phase is the phase of the analysis.
+	// +kubebuilder:validation:Enum=Analyzing;Succeeded;Failed
+	// +required
+	Phase CanaryPhase `json:"phase"`
+
+	// StartedAt is the time at which the analysis started.
+	// +required
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// Message is a human-readable description of the result of the
+	// analysis.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Matches returns true if the canary release was made with the given chart
+// version and values digest.
+func (in *CanaryStatus) Matches(chartVersion, configDigest string) bool {
+	return in != nil && in.ChartVersion == chartVersion && in.ConfigDigest == configDigest
+}
+
+// PendingRollout holds a Helm upgrade which waits for the RolloutApproved
+// condition of the HelmRelease.
+type PendingRollout struct {
+	// ChartName is the name of the chart of the upgrade.
+	// +required
+	ChartName string `json:"chartName"`
+
+	// ChartVersion is the version of the chart of the upgrade.
+	// +required
+	ChartVersion string `json:"chartVersion"`
+
+	// ConfigDigest is the digest of the values of the upgrade.
+	// +required
+	ConfigDigest string `json:"configDigest"`
+
+	// Workloads are the Deployments, StatefulSets and DaemonSets rendered
+	// by the upgrade.
+	// +optional
+	Workloads []ResourceRef `json:"workloads,omitempty"`
+
+	// RequestedAt is the time at which the approval was requested. Only a
+	// RolloutApproved condition which transitioned to True at or after this
+	// time approves the upgrade.
+	// +required
+	RequestedAt metav1.Time `json:"requestedAt"`
+}
+
+// Matches returns true if the pending upgrade is made with the given chart
+// version and values digest.
+func (in *PendingRollout) Matches(chartVersion, configDigest string) bool {
+	return in != nil && in.ChartVersion == chartVersion && in.ConfigDigest == configDigest
+}
+
+// DiffSummary holds a summary of the changes between the objects of two
+// Helm releases.
+type DiffSummary struct {
+	// FromVersion is the version of the Helm release the changes are made
+	// from.
+	// +required
+	FromVersion int `json:"fromVersion"`
+
+	// ToVersion is the version of the Helm release the changes are made to.
+	// +required
+	ToVersion int `json:"toVersion"`
+
+	// Added is the number of objects added.
+	// +optional
+	Added int `json:"added,omitempty"`
+
+	// Modified is the number of objects modified.
+	// +optional
+	Modified int `json:"modified,omitempty"`
+
+	// Removed is the number of objects removed.
+	// +optional
+	Removed int `json:"removed,omitempty"`
+
+	// Changes holds the changed objects, limited to the first 50 objects
+	// ordered by ID.
+	// +optional
+	Changes []ObjectChange `json:"changes,omitempty"`
+}
+
+// ObjectChange describes the change of an object between two Helm releases.
+type ObjectChange struct {
+	// ID is the string representation of the Kubernetes resource object's
+	// metadata, in the format '<namespace>_<name>_<group>_<kind>'.
+	// +required
+	ID string `json:"id"`
+
+	// Action is the change of the object.
+	// +kubebuilder:validation:Enum=added;modified;removed
+	// +required
+	Action string `json:"action"`
+
+	// Fields holds the paths of the fields of a modified object which have
+	// changed, up to two levels deep, e.g. "spec.template".
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+}
+
+const (
+	// ObjectAdded is the ObjectChange.Action of an added object.
+	ObjectAdded = "added"
+	// ObjectModified is the ObjectChange.Action of a modified object.
+	ObjectModified = "modified"
+	// ObjectRemoved is the ObjectChange.Action of a removed object.
+	ObjectRemoved = "removed"
+)
+
 // ClearHistory clears the History.
 func (in *HelmReleaseStatus) ClearHistory() {
 	in.History = nil
@@ -1038,6 +1964,20 @@ func (in *HelmReleaseStatus) ClearFailures() {
 	in.UpgradeFailures = 0
 }
 
+// MaxUninstallAttempts is the maximum number of UninstallAttempts retained
+// in the status.
+const MaxUninstallAttempts = 5
+
+// RecordUninstallAttempt increments the UninstallFailures and records the
+// given attempt, dropping the oldest attempts beyond MaxUninstallAttempts.
+func (in *HelmReleaseStatus) RecordUninstallAttempt(attempt UninstallAttempt) {
+	in.UninstallFailures++
+	in.UninstallAttempts = append(in.UninstallAttempts, attempt)
+	if n := len(in.UninstallAttempts); n > MaxUninstallAttempts {
+		in.UninstallAttempts = in.UninstallAttempts[n-MaxUninstallAttempts:]
+	}
+}
+
 // GetHelmChart returns the namespace and name of the HelmChart.
 func (in HelmReleaseStatus) GetHelmChart() (string, string) {
 	if in.HelmChart == "" {
@@ -1132,6 +2072,15 @@ func (in *HelmRelease) GetUninstall() Uninstall {
 	return *in.Spec.Uninstall
 }
 
+// GetDeletionPolicy returns the configured DeletionPolicy, or
+// DeletionPolicyDelete.
+func (in *HelmRelease) GetDeletionPolicy() DeletionPolicy {
+	if in.Spec.DeletionPolicy == "" {
+		return DeletionPolicyDelete
+	}
+	return in.Spec.DeletionPolicy
+}
+
 // GetActiveRemediation returns the active Remediation configuration for the
 // HelmRelease.
 func (in HelmRelease) GetActiveRemediation() Remediation {
@@ -1204,6 +2153,24 @@ func (in HelmRelease) GetTimeout() metav1.Duration {
 	return *in.Spec.Timeout
 }
 
+// GetWaitConcurrency returns the configured WaitConcurrency, or the default
+// of 1.
+func (in HelmRelease) GetWaitConcurrency() int {
+	if in.Spec.WaitConcurrency < 1 {
+		return 1
+	}
+	return in.Spec.WaitConcurrency
+}
+
+// GetHistory returns the configured HistoryRetention, or an empty
+// HistoryRetention.
+func (in *HelmRelease) GetHistory() HistoryRetention {
+	if in.Spec.History == nil {
+		return HistoryRetention{}
+	}
+	return *in.Spec.History
+}
+
 // GetMaxHistory returns the configured MaxHistory, or the default of 5.
 func (in HelmRelease) GetMaxHistory() int {
 	if in.Spec.MaxHistory == nil {
@@ -1221,6 +2188,12 @@ func (in HelmRelease) UsePersistentClient() bool {
 	return *in.Spec.PersistentClient
 }
 
+// IsObserveOnly returns true if the release of the object is to be observed
+// only, as it has not been migrated yet.
+func (in HelmRelease) IsObserveOnly() bool {
+	return in.Spec.Migration != nil && !in.Spec.Migration.Migrated
+}
+
 // GetDependsOn returns the list of dependencies across-namespaces.
 func (in HelmRelease) GetDependsOn() []meta.NamespacedObjectReference {
 	return in.Spec.DependsOn
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUninstallRetry_GetDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		retry    UninstallRetry
+		failures int64
+		want     time.Duration
+	}{
+		{
+			name:     "first retry",
+			retry:    UninstallRetry{Interval: metav1.Duration{Duration: 30 * time.Second}},
+			failures: 1,
+			want:     30 * time.Second,
+		},
+		{
+			name:     "doubles with every failure",
+			retry:    UninstallRetry{Interval: metav1.Duration{Duration: 30 * time.Second}},
+			failures: 3,
+			want:     2 * time.Minute,
+		},
+		{
+			name:     "capped at default max interval",
+			retry:    UninstallRetry{Interval: metav1.Duration{Duration: 30 * time.Second}},
+			failures: 10,
+			want:     15 * time.Minute,
+		},
+		{
+			name: "capped at max interval",
+			retry: UninstallRetry{
+				Interval:    metav1.Duration{Duration: 30 * time.Second},
+				MaxInterval: &metav1.Duration{Duration: time.Minute},
+			},
+			failures: 10,
+			want:     time.Minute,
+		},
+		{
+			name:     "zero interval",
+			retry:    UninstallRetry{},
+			failures: 3,
+			want:     4 * time.Second,
+		},
+		{
+			name: "zero max interval",
+			retry: UninstallRetry{
+				Interval:    metav1.Duration{Duration: 30 * time.Second},
+				MaxInterval: &metav1.Duration{},
+			},
+			failures: 3,
+			want:     time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.retry.GetDelay(tt.failures); got != tt.want {
+				t.Errorf("GetDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
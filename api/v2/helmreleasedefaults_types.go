@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HelmReleaseDefaultsKind is the kind in string format.
+const HelmReleaseDefaultsKind = "HelmReleaseDefaults"
+
+// HelmReleaseDefaultsSpec defines the defaults for the fields of the
+// HelmReleases in the namespace of the HelmReleaseDefaults, which are
+// applied when the fields are omitted.
+type HelmReleaseDefaultsSpec struct {
+	// Interval is the default of the interval at which to reconcile the Helm
+	// release.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// ServiceAccountName is the default of the name of the Kubernetes service
+	// account to impersonate when reconciling a HelmRelease.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// DriftDetection is the default of the drift detection configuration.
+	// When a HelmRelease configures drift detection without a mode, only the
+	// mode is applied.
+	// +optional
+	DriftDetection *DriftDetection `json:"driftDetection,omitempty"`
+
+	// CommonMetadata is the default of the labels and annotations applied to
+	// all resources of a Helm release. They are merged with the labels and
+	// annotations of a HelmRelease, which take precedence.
+	// +optional
+	CommonMetadata *CommonMetadata `json:"commonMetadata,omitempty"`
+}
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=hrd
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+
+// HelmReleaseDefaults is the Schema for the helmreleasedefaults API. It
+// defines the defaults applied to the HelmReleases in its namespace by the
+// defaulting admission webhook of the controller.
+type HelmReleaseDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HelmReleaseDefaultsSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HelmReleaseDefaultsList contains a list of HelmReleaseDefaults objects.
+type HelmReleaseDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HelmReleaseDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HelmReleaseDefaults{}, &HelmReleaseDefaultsList{})
+}
@@ -104,6 +104,14 @@ type ValuesReference struct {
 	// transient error will still result in a reconciliation failure.
 	// +optional
 	Optional bool `json:"optional,omitempty"`
+
+	// WaitForOwner marks this ValuesReference to wait for the controller owner
+	// of the referent, e.g. an ExternalSecret, to be ready and to have
+	// observed its latest generation, before the values are used. This
+	// prevents a release with values from a referent which has not yet been
+	// updated by its owner.
+	// +optional
+	WaitForOwner bool `json:"waitForOwner,omitempty"`
 }
 
 // GetValuesKey returns the defined ValuesKey, or the default ('values.yaml').
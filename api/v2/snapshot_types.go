@@ -19,6 +19,7 @@ package v2
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -107,6 +108,46 @@ func (in *Snapshots) Truncate(ignoreTests bool) {
 	}
 }
 
+// Prune removes the oldest Snapshots exceeding maxSnapshots, and the
+// Snapshots of which the release was last deployed longer than ttl before
+// now. A maxSnapshots or ttl of zero disables the respective pruning.
+//
+// The Latest Snapshot and the Previous (rollback target) Snapshot are always
+// retained, even if this exceeds maxSnapshots.
+func (in *Snapshots) Prune(maxSnapshots int, ttl time.Duration, now time.Time, ignoreTests bool) {
+	if in.Len() < 2 || (maxSnapshots <= 0 && ttl <= 0) {
+		return
+	}
+
+	latest, previous := in.Latest(), in.Previous(ignoreTests)
+
+	// The number of other Snapshots which can be retained next to the
+	// Latest and Previous Snapshot.
+	available := maxSnapshots - 1
+	if previous != nil {
+		available--
+	}
+
+	retained := make(Snapshots, 0, in.Len())
+	for _, s := range *in {
+		if s == latest || s == previous {
+			retained = append(retained, s)
+			continue
+		}
+		if ttl > 0 && !s.LastDeployed.IsZero() && now.Sub(s.LastDeployed.Time) > ttl {
+			continue
+		}
+		if maxSnapshots > 0 {
+			if available <= 0 {
+				continue
+			}
+			available--
+		}
+		retained = append(retained, s)
+	}
+	*in = retained
+}
+
 // Snapshot captures a point-in-time copy of the status information for a Helm release,
 // as managed by the controller.
 type Snapshot struct {
@@ -162,6 +203,19 @@ type Snapshot struct {
 	// OCIDigest is the digest of the OCI artifact associated with the release.
 	// +optional
 	OCIDigest string `json:"ociDigest,omitempty"`
+	// Notes is the rendered NOTES.txt of the chart of the release, truncated
+	// to 4096 bytes. It is only retained for the latest release.
+	// +optional
+	Notes string `json:"notes,omitempty"`
+	// Images is the sorted list of unique container image references in the
+	// manifest and hooks of the release.
+	// +optional
+	Images []string `json:"images,omitempty"`
+	// Provenance is the metadata of the source the chart of the release
+	// originates from, such as the revision of the source of the HelmChart,
+	// or the OCI annotations of the OCIRepository artifact.
+	// +optional
+	Provenance map[string]string `json:"provenance,omitempty"`
 }
 
 // FullReleaseName returns the full name of the release in the format
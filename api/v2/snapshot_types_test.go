@@ -19,6 +19,9 @@ package v2
 import (
 	"reflect"
 	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestSnapshots_Sort(t *testing.T) {
@@ -296,3 +299,97 @@ func TestSnapshots_Truncate(t *testing.T) {
 		})
 	}
 }
+
+func TestSnapshots_Prune(t *testing.T) {
+	now := time.Now()
+	deployedAgo := func(d time.Duration) metav1.Time {
+		return metav1.NewTime(now.Add(-d))
+	}
+
+	tests := []struct {
+		name         string
+		in           Snapshots
+		maxSnapshots int
+		ttl          time.Duration
+		want         Snapshots
+	}{
+		{
+			name: "disabled",
+			in: Snapshots{
+				{Version: 3, Status: "failed"},
+				{Version: 2, Status: "failed"},
+				{Version: 1, Status: "superseded"},
+			},
+			want: Snapshots{
+				{Version: 3, Status: "failed"},
+				{Version: 2, Status: "failed"},
+				{Version: 1, Status: "superseded"},
+			},
+		},
+		{
+			name: "max snapshots retains latest and previous",
+			in: Snapshots{
+				{Version: 1, Status: "superseded"},
+				{Version: 4, Status: "failed"},
+				{Version: 3, Status: "failed"},
+				{Version: 2, Status: "failed"},
+			},
+			maxSnapshots: 3,
+			want: Snapshots{
+				{Version: 4, Status: "failed"},
+				{Version: 3, Status: "failed"},
+				{Version: 1, Status: "superseded"},
+			},
+		},
+		{
+			name: "max snapshots exceeded by latest and previous",
+			in: Snapshots{
+				{Version: 3, Status: "deployed"},
+				{Version: 2, Status: "failed"},
+				{Version: 1, Status: "superseded"},
+			},
+			maxSnapshots: 1,
+			want: Snapshots{
+				{Version: 3, Status: "deployed"},
+				{Version: 1, Status: "superseded"},
+			},
+		},
+		{
+			name: "max snapshots without previous",
+			in: Snapshots{
+				{Version: 3, Status: "failed"},
+				{Version: 2, Status: "failed"},
+				{Version: 1, Status: "failed"},
+			},
+			maxSnapshots: 2,
+			want: Snapshots{
+				{Version: 3, Status: "failed"},
+				{Version: 2, Status: "failed"},
+			},
+		},
+		{
+			name: "ttl",
+			in: Snapshots{
+				{Version: 4, Status: "failed", LastDeployed: deployedAgo(3 * time.Hour)},
+				{Version: 3, Status: "failed", LastDeployed: deployedAgo(2 * time.Hour)},
+				{Version: 2, Status: "failed", LastDeployed: deployedAgo(30 * time.Minute)},
+				{Version: 1, Status: "superseded", LastDeployed: deployedAgo(4 * time.Hour)},
+			},
+			ttl: time.Hour,
+			want: Snapshots{
+				{Version: 4, Status: "failed", LastDeployed: deployedAgo(3 * time.Hour)},
+				{Version: 2, Status: "failed", LastDeployed: deployedAgo(30 * time.Minute)},
+				{Version: 1, Status: "superseded", LastDeployed: deployedAgo(4 * time.Hour)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.in.Prune(tt.maxSnapshots, tt.ttl, now, false)
+
+			if !reflect.DeepEqual(tt.in, tt.want) {
+				t.Errorf("Prune() got %v, want %v", tt.in, tt.want)
+			}
+		})
+	}
+}
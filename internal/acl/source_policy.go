@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acl
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+)
+
+// AnyNamespace is the key of the SourcePolicy.Namespaces entry which applies
+// to the namespaces without an entry of their own.
+const AnyNamespace = "*"
+
+var (
+	// ChartSourcePolicy is a global policy restricting the chart sources the
+	// HelmReleases in a namespace may reference. When nil, any chart source
+	// may be referenced.
+	ChartSourcePolicy *SourcePolicy
+)
+
+// SourcePolicy restricts the chart sources the HelmReleases in a namespace
+// may reference.
+type SourcePolicy struct {
+	// Namespaces maps the namespace of a HelmRelease to the chart sources
+	// it may reference, as "<kind>/<namespace>/<name>" patterns in the
+	// syntax of path.Match. The AnyNamespace entry applies to namespaces
+	// without an entry of their own. Namespaces without an entry are not
+	// restricted when there is no AnyNamespace entry.
+	Namespaces map[string][]string `json:"namespaces"`
+}
+
+// LoadSourcePolicy reads a SourcePolicy from the YAML file at the given path,
+// and validates its patterns.
+func LoadSourcePolicy(path string) (*SourcePolicy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart source policy: %w", err)
+	}
+
+	p := &SourcePolicy{}
+	if err = yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, fmt.Errorf("failed to decode chart source policy from '%s': %w", path, err)
+	}
+	if err = p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid chart source policy in '%s': %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate returns an error if any of the patterns of the SourcePolicy is
+// invalid.
+func (p *SourcePolicy) Validate() error {
+	for namespace, patterns := range p.Namespaces {
+		for _, pattern := range patterns {
+			if strings.Count(pattern, "/") != 2 {
+				return fmt.Errorf("pattern '%s' for namespace '%s' must be of the form '<kind>/<namespace>/<name>'", pattern, namespace)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("pattern '%s' for namespace '%s': %w", pattern, namespace, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows returns if the HelmReleases in the given namespace may reference the
+// source of the given kind and reference.
+func (p *SourcePolicy) Allows(namespace, kind string, ref types.NamespacedName) bool {
+	if p == nil {
+		return true
+	}
+	patterns, ok := p.Namespaces[namespace]
+	if !ok {
+		if patterns, ok = p.Namespaces[AnyNamespace]; !ok {
+			return true
+		}
+	}
+
+	source := kind + "/" + ref.Namespace + "/" + ref.Name
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsChartSource returns an error if the ChartSourcePolicy does not allow
+// the object to reference the chart source of the given kind and reference.
+func AllowsChartSource(obj client.Object, kind string, ref types.NamespacedName) error {
+	if !ChartSourcePolicy.Allows(obj.GetNamespace(), kind, ref) {
+		return acl.AccessDeniedError(fmt.Sprintf("chart source %s %s is not allowed for namespace '%s' by the chart source policy",
+			kind, ref.String(), obj.GetNamespace(),
+		))
+	}
+	return nil
+}
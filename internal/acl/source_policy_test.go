@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestSourcePolicy_Allows(t *testing.T) {
+	policy := &SourcePolicy{
+		Namespaces: map[string][]string{
+			"team-a": {
+				"HelmRepository/flux-system/*",
+				"OCIRepository/team-a/podinfo",
+			},
+			AnyNamespace: {
+				"OCIRepository/flux-system/*",
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		policy    *SourcePolicy
+		namespace string
+		kind      string
+		ref       types.NamespacedName
+		want      bool
+	}{
+		{
+			name:      "nil policy",
+			namespace: "team-a",
+			kind:      "GitRepository",
+			ref:       types.NamespacedName{Namespace: "team-a", Name: "charts"},
+			want:      true,
+		},
+		{
+			name:      "matches wildcard pattern",
+			policy:    policy,
+			namespace: "team-a",
+			kind:      "HelmRepository",
+			ref:       types.NamespacedName{Namespace: "flux-system", Name: "bitnami"},
+			want:      true,
+		},
+		{
+			name:      "matches exact pattern",
+			policy:    policy,
+			namespace: "team-a",
+			kind:      "OCIRepository",
+			ref:       types.NamespacedName{Namespace: "team-a", Name: "podinfo"},
+			want:      true,
+		},
+		{
+			name:      "does not match namespace patterns",
+			policy:    policy,
+			namespace: "team-a",
+			kind:      "OCIRepository",
+			ref:       types.NamespacedName{Namespace: "flux-system", Name: "podinfo"},
+			want:      false,
+		},
+		{
+			name:      "matches any namespace pattern",
+			policy:    policy,
+			namespace: "team-b",
+			kind:      "OCIRepository",
+			ref:       types.NamespacedName{Namespace: "flux-system", Name: "podinfo"},
+			want:      true,
+		},
+		{
+			name:      "does not match any namespace pattern",
+			policy:    policy,
+			namespace: "team-b",
+			kind:      "HelmRepository",
+			ref:       types.NamespacedName{Namespace: "flux-system", Name: "bitnami"},
+			want:      false,
+		},
+		{
+			name:      "namespace without entry",
+			policy:    &SourcePolicy{Namespaces: map[string][]string{"team-a": nil}},
+			namespace: "team-b",
+			kind:      "HelmRepository",
+			ref:       types.NamespacedName{Namespace: "team-b", Name: "charts"},
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(tt.policy.Allows(tt.namespace, tt.kind, tt.ref)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestAllowsChartSource(t *testing.T) {
+	g := NewWithT(t)
+
+	curPolicy := ChartSourcePolicy
+	ChartSourcePolicy = &SourcePolicy{Namespaces: map[string][]string{AnyNamespace: {"HelmRepository/flux-system/*"}}}
+	t.Cleanup(func() { ChartSourcePolicy = curPolicy })
+
+	obj := &v2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "team-a"}}
+	g.Expect(AllowsChartSource(obj, "HelmRepository", types.NamespacedName{Namespace: "flux-system", Name: "podinfo"})).To(Succeed())
+
+	err := AllowsChartSource(obj, "GitRepository", types.NamespacedName{Namespace: "team-a", Name: "podinfo"})
+	g.Expect(acl.IsAccessDenied(err)).To(BeTrue())
+	g.Expect(err).To(MatchError("chart source GitRepository team-a/podinfo is not allowed for namespace 'team-a' by the chart source policy"))
+}
+
+func TestLoadSourcePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			data: `namespaces:
+  team-a:
+    - HelmRepository/flux-system/*
+  "*":
+    - OCIRepository/flux-system/*
+`,
+		},
+		{
+			name: "invalid pattern form",
+			data: `namespaces:
+  team-a:
+    - HelmRepository/bitnami
+`,
+			wantErr: "must be of the form '<kind>/<namespace>/<name>'",
+		},
+		{
+			name: "invalid pattern syntax",
+			data: `namespaces:
+  team-a:
+    - HelmRepository/flux-system/[
+`,
+			wantErr: "syntax error in pattern",
+		},
+		{
+			name:    "unknown field",
+			data:    `allowed: []`,
+			wantErr: "failed to decode chart source policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			g.Expect(os.WriteFile(path, []byte(tt.data), 0o644)).To(Succeed())
+
+			got, err := LoadSourcePolicy(path)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got.Namespaces).To(HaveLen(2))
+		})
+	}
+}
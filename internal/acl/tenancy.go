@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acl
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+var (
+	// EnforceTenantNamespaces is a global flag that can be used to require
+	// the target and storage namespace of a HelmRelease to equal its own
+	// namespace, unless the namespace is labeled with PrivilegedLabel.
+	EnforceTenantNamespaces = false
+
+	// PrivilegedLabel is the label which exempts the HelmReleases in a
+	// namespace from EnforceTenantNamespaces when set to "true" on the
+	// namespace.
+	PrivilegedLabel = v2.GroupVersion.Group + "/privileged"
+)
+
+// AllowsReleaseNamespaces returns an error if EnforceTenantNamespaces is
+// enabled, and the target or storage namespace of the object differs from
+// its own namespace while the namespace is not labeled with PrivilegedLabel.
+func AllowsReleaseNamespaces(ctx context.Context, c client.Reader, obj *v2.HelmRelease) error {
+	if !EnforceTenantNamespaces {
+		return nil
+	}
+
+	var field, namespace string
+	switch {
+	case obj.GetReleaseNamespace() != obj.GetNamespace():
+		field, namespace = "targetNamespace", obj.GetReleaseNamespace()
+	case obj.GetStorageNamespace() != obj.GetNamespace():
+		field, namespace = "storageNamespace", obj.GetStorageNamespace()
+	default:
+		return nil
+	}
+
+	// Only get the metadata of the namespace, to share the cache of the
+	// Namespace watch of the controller.
+	ns := &metav1.PartialObjectMetadata{}
+	ns.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	if err := c.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, ns); err != nil {
+		return fmt.Errorf("failed to get namespace '%s': %w", obj.GetNamespace(), err)
+	}
+	if ns.GetLabels()[PrivilegedLabel] == "true" {
+		return nil
+	}
+	return acl.AccessDeniedError(fmt.Sprintf("%s '%s' must equal the namespace of the HelmRelease, unless namespace '%s' is labeled with %s=true",
+		field, namespace, obj.GetNamespace(), PrivilegedLabel,
+	))
+}
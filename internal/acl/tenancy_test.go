@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acl
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/runtime/acl"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestAllowsReleaseNamespaces(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "platform",
+			Labels: map[string]string{PrivilegedLabel: "true"},
+		}},
+	).Build()
+
+	tests := []struct {
+		name    string
+		enforce bool
+		obj     *v2.HelmRelease
+		wantErr string
+	}{
+		{
+			name:    "not enforced",
+			enforce: false,
+			obj:     mockRelease("tenant", "other", ""),
+		},
+		{
+			name:    "same namespace",
+			enforce: true,
+			obj:     mockRelease("tenant", "tenant", ""),
+		},
+		{
+			name:    "other target namespace",
+			enforce: true,
+			obj:     mockRelease("tenant", "other", ""),
+			wantErr: "targetNamespace 'other' must equal the namespace of the HelmRelease, unless namespace 'tenant' is labeled with helm.toolkit.fluxcd.io/privileged=true",
+		},
+		{
+			name:    "other storage namespace",
+			enforce: true,
+			obj:     mockRelease("tenant", "", "other"),
+			wantErr: "storageNamespace 'other' must equal the namespace",
+		},
+		{
+			name:    "other target namespace in privileged namespace",
+			enforce: true,
+			obj:     mockRelease("platform", "other", "other"),
+		},
+		{
+			name:    "namespace not found",
+			enforce: true,
+			obj:     mockRelease("missing", "other", ""),
+			wantErr: "failed to get namespace 'missing'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			curEnforce := EnforceTenantNamespaces
+			EnforceTenantNamespaces = tt.enforce
+			t.Cleanup(func() { EnforceTenantNamespaces = curEnforce })
+
+			err := AllowsReleaseNamespaces(context.TODO(), c, tt.obj)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			g.Expect(acl.IsAccessDenied(err)).To(Equal(tt.name != "namespace not found"))
+		})
+	}
+}
+
+func mockRelease(namespace, targetNamespace, storageNamespace string) *v2.HelmRelease {
+	return &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: namespace},
+		Spec: v2.HelmReleaseSpec{
+			TargetNamespace:  targetNamespace,
+			StorageNamespace: storageNamespace,
+		},
+	}
+}
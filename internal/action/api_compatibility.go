@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmpostrender "helm.sh/helm/v3/pkg/postrender"
+
+	"github.com/fluxcd/helm-controller/internal/features"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+)
+
+// withAPICompatibilityCheck returns the given post-renderer combined with a
+// postrender.APICompatibility post-renderer for the cluster of the given
+// config, if the APICompatibilityPreCheck feature is enabled.
+//
+// The APIs served by the cluster are discovered when the post-renderer runs,
+// which is after the CustomResourceDefinitions of the chart have been
+// applied.
+func withAPICompatibilityCheck(config *helmaction.Configuration,
+	renderer helmpostrender.PostRenderer) helmpostrender.PostRenderer {
+	if enabled, _ := features.Enabled(features.APICompatibilityPreCheck); !enabled {
+		return renderer
+	}
+
+	clientSet, err := kubeClientSet(config)
+	if err != nil {
+		return renderer
+	}
+
+	check := postrender.NewAPICompatibility(clientSet.Discovery())
+	if renderer == nil {
+		return check
+	}
+	return postrender.NewCombined(renderer, check)
+}
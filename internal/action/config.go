@@ -17,6 +17,7 @@ limitations under the License.
 package action
 
 import (
+	"errors"
 	"fmt"
 
 	helmaction "helm.sh/helm/v3/pkg/action"
@@ -24,6 +25,7 @@ import (
 	helmstorage "helm.sh/helm/v3/pkg/storage"
 	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"github.com/fluxcd/helm-controller/internal/storage"
 )
@@ -50,6 +52,11 @@ type ConfigFactory struct {
 	Driver helmdriver.Driver
 	// StorageLog is the logger to use for the Helm storage driver.
 	StorageLog helmaction.DebugLog
+	// StorageLabels are the labels added to the Helm storage Secrets.
+	StorageLabels map[string]string
+	// StorageAnnotations are the annotations added to the Helm storage
+	// Secrets.
+	StorageAnnotations map[string]string
 }
 
 // ConfigFactoryOption is a function that configures a ConfigFactory.
@@ -76,10 +83,14 @@ func NewConfigFactory(getter genericclioptions.RESTClientGetter, opts ...ConfigF
 
 // WithStorage configures the ConfigFactory.Driver by constructing a new Helm
 // driver.Driver using the provided driver name and namespace.
-// It supports driver.ConfigMapsDriverName, driver.SecretsDriverName and
-// driver.MemoryDriverName.
-// It returns an error when the driver name is not supported, or the client
-// configuration for the storage fails.
+// It supports driver.ConfigMapsDriverName, driver.SecretsDriverName,
+// driver.SQLDriverName and driver.MemoryDriverName. The SQL driver connects
+// to the database configured by SQLConnectionString. When storage.Encryption
+// is configured, the release payload of the Secrets and ConfigMaps drivers is
+// encrypted.
+// It returns an error when the driver name is not supported, the driver does
+// not support the configured encryption, or the client configuration for
+// the storage fails.
 func WithStorage(driver, namespace string) ConfigFactoryOption {
 	if driver == "" {
 		driver = DefaultStorageDriver
@@ -97,11 +108,31 @@ func WithStorage(driver, namespace string) ConfigFactoryOption {
 				return fmt.Errorf("could not get client set for '%s' storage driver: %w", driver, err)
 			}
 			if driver == helmdriver.ConfigMapsDriverName {
-				f.Driver = helmdriver.NewConfigMaps(clientSet.CoreV1().ConfigMaps(namespace))
+				var configMaps corev1client.ConfigMapInterface = clientSet.CoreV1().ConfigMaps(namespace)
+				if storage.Encryption != nil {
+					configMaps = storage.NewEncryptedConfigMaps(configMaps, storage.Encryption)
+				}
+				f.Driver = helmdriver.NewConfigMaps(configMaps)
 			}
 			if driver == helmdriver.SecretsDriverName {
-				f.Driver = helmdriver.NewSecrets(clientSet.CoreV1().Secrets(namespace))
+				var secrets corev1client.SecretInterface = clientSet.CoreV1().Secrets(namespace)
+				if len(f.StorageLabels) > 0 || len(f.StorageAnnotations) > 0 {
+					secrets = storage.NewMetadataSecrets(secrets, f.StorageLabels, f.StorageAnnotations)
+				}
+				if storage.Encryption != nil {
+					secrets = storage.NewEncryptedSecrets(secrets, storage.Encryption)
+				}
+				f.Driver = helmdriver.NewSecrets(storage.NewSizeGuardedSecrets(secrets))
 			}
+		case helmdriver.SQLDriverName:
+			if storage.Encryption != nil {
+				return fmt.Errorf("storage encryption is not supported by the '%s' storage driver", helmdriver.SQLDriverName)
+			}
+			driver, err := sqlDriver(namespace)
+			if err != nil {
+				return fmt.Errorf("could not configure '%s' storage driver: %w", helmdriver.SQLDriverName, err)
+			}
+			f.Driver = driver
 		case helmdriver.MemoryDriverName:
 			driver := helmdriver.NewMemory()
 			driver.SetNamespace(namespace)
@@ -113,6 +144,21 @@ func WithStorage(driver, namespace string) ConfigFactoryOption {
 	}
 }
 
+// WithStorageMetadata sets the ConfigFactory.StorageLabels and
+// ConfigFactory.StorageAnnotations added to the Helm storage Secrets. As they
+// are applied when the storage driver is constructed, it must be provided
+// before WithStorage.
+func WithStorageMetadata(labels, annotations map[string]string) ConfigFactoryOption {
+	return func(f *ConfigFactory) error {
+		if f.Driver != nil {
+			return errors.New("storage metadata must be configured before the storage driver")
+		}
+		f.StorageLabels = labels
+		f.StorageAnnotations = annotations
+		return nil
+	}
+}
+
 // WithDriver sets the ConfigFactory.Driver.
 func WithDriver(driver helmdriver.Driver) ConfigFactoryOption {
 	return func(f *ConfigFactory) error {
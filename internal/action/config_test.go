@@ -130,6 +130,13 @@ func TestWithStorage(t *testing.T) {
 			factory:    ConfigFactory{},
 			wantErr:    errors.New("no namespace provided for Helm storage driver 'secrets'"),
 		},
+		{
+			name:       "sql without connection string",
+			driverName: helmdriver.SQLDriverName,
+			namespace:  "default",
+			factory:    ConfigFactory{},
+			wantErr:    errors.New("could not configure 'SQL' storage driver: no SQL connection string configured"),
+		},
 		{
 			name:       "invalid driver",
 			driverName: "invalid",
@@ -155,6 +162,39 @@ func TestWithStorage(t *testing.T) {
 			g.Expect(factory.Driver.Name()).To(Equal(tt.wantDriver))
 		})
 	}
+
+	t.Run("sql with storage encryption", func(t *testing.T) {
+		g := NewWithT(t)
+
+		key, err := storage.NewAESKey("key-1", make([]byte, 32))
+		g.Expect(err).ToNot(HaveOccurred())
+		storage.Encryption, err = storage.NewEnvelope(key)
+		g.Expect(err).ToNot(HaveOccurred())
+		t.Cleanup(func() { storage.Encryption = nil })
+
+		factory := ConfigFactory{}
+		err = WithStorage(helmdriver.SQLDriverName, "default")(&factory)
+		g.Expect(err).To(MatchError("storage encryption is not supported by the 'SQL' storage driver"))
+		g.Expect(factory.Driver).To(BeNil())
+	})
+}
+
+func TestWithStorageMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	labels := map[string]string{"backup.example.com/include": "true"}
+	annotations := map[string]string{"example.com/classification": "confidential"}
+
+	factory := &ConfigFactory{
+		KubeClient: helmkube.New(cmdtest.NewTestFactory()),
+	}
+	g.Expect(WithStorageMetadata(labels, annotations)(factory)).To(Succeed())
+	g.Expect(factory.StorageLabels).To(Equal(labels))
+	g.Expect(factory.StorageAnnotations).To(Equal(annotations))
+	g.Expect(WithStorage(helmdriver.SecretsDriverName, "default")(factory)).To(Succeed())
+
+	err := WithStorageMetadata(labels, annotations)(factory)
+	g.Expect(err).To(MatchError("storage metadata must be configured before the storage driver"))
 }
 
 func TestWithDriver(t *testing.T) {
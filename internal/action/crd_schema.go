@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmpostrender "helm.sh/helm/v3/pkg/postrender"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/helm-controller/internal/features"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+)
+
+// withCRDSchemaCheck returns the given post-renderer combined with a
+// postrender.CRDSchema post-renderer for the cluster of the given config, if
+// the CRDSchemaPreCheck feature is enabled.
+//
+// The CustomResourceDefinitions are retrieved when the post-renderer runs,
+// which is after the CustomResourceDefinitions of the chart have been
+// applied.
+func withCRDSchemaCheck(ctx context.Context, config *helmaction.Configuration,
+	renderer helmpostrender.PostRenderer) helmpostrender.PostRenderer {
+	if enabled, _ := features.Enabled(features.CRDSchemaPreCheck); !enabled {
+		return renderer
+	}
+
+	restConfig, err := config.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return renderer
+	}
+	mapper, err := config.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return renderer
+	}
+	client, err := apiextensionsclient.NewForConfig(restConfig)
+	if err != nil {
+		return renderer
+	}
+
+	check := postrender.NewCRDSchema(func(gk schema.GroupKind) (*apiextensionsv1.CustomResourceDefinition, error) {
+		mapping, err := mapper.RESTMapping(gk)
+		if err != nil {
+			if apimeta.IsNoMatchError(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		crd, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx,
+			mapping.Resource.Resource+"."+gk.Group, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return crd, err
+	})
+	if renderer == nil {
+		return check
+	}
+	return postrender.NewCombined(renderer, check)
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	helmaction "helm.sh/helm/v3/pkg/action"
@@ -29,9 +30,12 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	apiruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/discovery"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 )
@@ -41,6 +45,15 @@ const (
 	DefaultCRDPolicy = v2.Create
 )
 
+const (
+	// crdReadyTimeout is the time to wait for applied CustomResourceDefinitions
+	// to be established, and for their APIs to be served.
+	crdReadyTimeout = 60 * time.Second
+	// crdDiscoveryInterval is the interval at which the discovery of the API
+	// server is polled for the APIs of applied CustomResourceDefinitions.
+	crdDiscoveryInterval = time.Second
+)
+
 var accessor = apimeta.NewAccessor()
 
 // crdPolicy returns the CRD policy for the given CRD.
@@ -64,7 +77,7 @@ func (*rootScoped) Name() apimeta.RESTScopeName {
 	return apimeta.RESTScopeNameRoot
 }
 
-func applyCRDs(cfg *helmaction.Configuration, policy v2.CRDsPolicy, chrt *helmchart.Chart, visitorFunc ...resource.VisitorFunc) error {
+func applyCRDs(ctx context.Context, cfg *helmaction.Configuration, policy v2.CRDsPolicy, chrt *helmchart.Chart, visitorFunc ...resource.VisitorFunc) error {
 	if len(chrt.CRDObjects()) == 0 {
 		return nil
 	}
@@ -139,7 +152,7 @@ func applyCRDs(cfg *helmaction.Configuration, policy v2.CRDsPolicy, chrt *helmch
 		// release.
 		original := make(helmkube.ResourceList, 0)
 		for _, r := range allCRDs {
-			if o, err := client.Get(context.TODO(), r.Name, metav1.GetOptions{}); err == nil && o != nil {
+			if o, err := client.Get(ctx, r.Name, metav1.GetOptions{}); err == nil && o != nil {
 				o.GetResourceVersion()
 				original = append(original, &resource.Info{
 					Client: clientSet.ApiextensionsV1().RESTClient(),
@@ -193,11 +206,33 @@ func applyCRDs(cfg *helmaction.Configuration, policy v2.CRDsPolicy, chrt *helmch
 
 	if len(totalItems) > 0 {
 		// Give time for the CRD to be recognized.
-		if err := cfg.KubeClient.Wait(totalItems, 60*time.Second); err != nil {
+		if err := cfg.KubeClient.Wait(totalItems, crdReadyTimeout); err != nil {
 			err = fmt.Errorf("failed to wait for CustomResourceDefinition(s): %w", err)
 			cfg.Log(err.Error())
 			return err
 		}
+
+		// The CRDs may be established before their APIs are served by the
+		// discovery of the API server, in which case the custom resources
+		// of the chart can not be mapped to them yet.
+		dc, err := cfg.RESTClientGetter.ToDiscoveryClient()
+		if err != nil {
+			err = fmt.Errorf("could not create Kubernetes discovery client: %w", err)
+			cfg.Log(err.Error())
+			return err
+		}
+		resources, err := crdResources(totalItems)
+		if err != nil {
+			cfg.Log(err.Error())
+			return err
+		}
+		if err := waitForCRDResources(ctx, dc, resources, crdDiscoveryInterval, crdReadyTimeout); err != nil {
+			cfg.Log(err.Error())
+			return err
+		}
+		// Gather the capabilities of the cluster again when the chart is
+		// rendered, so that the APIs of the CRDs are included.
+		cfg.Capabilities = nil
 		cfg.Log("successfully applied %d CustomResourceDefinition(s)", len(totalItems))
 
 		// Clear the RESTMapper cache, since it will not have the new CRDs.
@@ -214,6 +249,80 @@ func applyCRDs(cfg *helmaction.Configuration, policy v2.CRDsPolicy, chrt *helmch
 	return nil
 }
 
+// crdResources returns the resources of the served versions of the given
+// CustomResourceDefinitions.
+func crdResources(crds []*resource.Info) ([]schema.GroupVersionResource, error) {
+	var resources []schema.GroupVersionResource
+	for _, info := range crds {
+		obj, err := apiruntime.DefaultUnstructuredConverter.ToUnstructured(info.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert CustomResourceDefinition %s: %w", info.Name, err)
+		}
+		group, _, _ := unstructured.NestedString(obj, "spec", "group")
+		plural, _, _ := unstructured.NestedString(obj, "spec", "names", "plural")
+		versions, _, _ := unstructured.NestedSlice(obj, "spec", "versions")
+		for _, v := range versions {
+			version, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(version, "name")
+			if served, _, _ := unstructured.NestedBool(version, "served"); served && name != "" {
+				resources = append(resources, schema.GroupVersionResource{Group: group, Version: name, Resource: plural})
+			}
+		}
+		// CustomResourceDefinitions of apiextensions.k8s.io/v1beta1 may
+		// only define a single version.
+		if version, _, _ := unstructured.NestedString(obj, "spec", "version"); len(versions) == 0 && version != "" {
+			resources = append(resources, schema.GroupVersionResource{Group: group, Version: version, Resource: plural})
+		}
+	}
+	return resources, nil
+}
+
+// waitForCRDResources polls the discovery of the API server at the given
+// interval until it serves all given resources, the timeout expires, or the
+// context is canceled.
+func waitForCRDResources(ctx context.Context, dc discovery.CachedDiscoveryInterface, resources []schema.GroupVersionResource, interval, timeout time.Duration) error {
+	if len(resources) == 0 {
+		return nil
+	}
+
+	var missing []string
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(context.Context) (bool, error) {
+		dc.Invalidate()
+		missing = missing[:0]
+		for _, gvr := range resources {
+			if !servesResource(dc, gvr) {
+				missing = append(missing, gvr.GroupResource().String()+"/"+gvr.Version)
+			}
+		}
+		return len(missing) == 0, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("waiting for APIs of CustomResourceDefinition(s) to be served: %w", ctx.Err())
+		}
+		return fmt.Errorf("APIs of CustomResourceDefinition(s) not served after %s: %s", timeout, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// servesResource returns if the discovery of the API server serves the given
+// resource.
+func servesResource(dc discovery.DiscoveryInterface, gvr schema.GroupVersionResource) bool {
+	list, err := dc.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+	for _, r := range list.APIResources {
+		if r.Name == gvr.Resource {
+			return true
+		}
+	}
+	return false
+}
+
 func setOriginVisitor(group, namespace, name string) resource.VisitorFunc {
 	return func(info *resource.Info, err error) error {
 		if err != nil {
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_crdResources(t *testing.T) {
+	g := NewWithT(t)
+
+	crds := []*resource.Info{
+		{
+			Name: "widgets.example.com",
+			Object: &apiextensionsv1.CustomResourceDefinition{
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Group: "example.com",
+					Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget"},
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+						{Name: "v1", Served: true, Storage: true},
+						{Name: "v1beta1", Served: true},
+						{Name: "v1alpha1", Served: false},
+					},
+				},
+			},
+		},
+		{
+			Name: "gadgets.example.com",
+			Object: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apiextensions.k8s.io/v1beta1",
+				"kind":       "CustomResourceDefinition",
+				"spec": map[string]interface{}{
+					"group":   "example.com",
+					"version": "v1",
+					"names":   map[string]interface{}{"plural": "gadgets", "kind": "Gadget"},
+				},
+			}},
+		},
+	}
+
+	resources, err := crdResources(crds)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resources).To(Equal([]schema.GroupVersionResource{
+		{Group: "example.com", Version: "v1", Resource: "widgets"},
+		{Group: "example.com", Version: "v1beta1", Resource: "widgets"},
+		{Group: "example.com", Version: "v1", Resource: "gadgets"},
+	}))
+}
+
+func Test_waitForCRDResources(t *testing.T) {
+	client := fakeclientset.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget"},
+			},
+		},
+	}
+	dc := memory.NewMemCacheClient(client)
+
+	t.Run("served resources", func(t *testing.T) {
+		g := NewWithT(t)
+
+		g.Expect(waitForCRDResources(context.TODO(), dc, []schema.GroupVersionResource{
+			{Group: "example.com", Version: "v1", Resource: "widgets"},
+		}, 10*time.Millisecond, time.Second)).To(Succeed())
+		g.Expect(waitForCRDResources(context.TODO(), dc, nil, 10*time.Millisecond, time.Second)).To(Succeed())
+	})
+
+	t.Run("resources not served", func(t *testing.T) {
+		g := NewWithT(t)
+
+		err := waitForCRDResources(context.TODO(), dc, []schema.GroupVersionResource{
+			{Group: "example.com", Version: "v1", Resource: "widgets"},
+			{Group: "example.com", Version: "v1", Resource: "gadgets"},
+			{Group: "example.com", Version: "v2", Resource: "widgets"},
+		}, 10*time.Millisecond, 50*time.Millisecond)
+		g.Expect(err).To(MatchError("APIs of CustomResourceDefinition(s) not served after 50ms: " +
+			"gadgets.example.com/v1, widgets.example.com/v2"))
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		err := waitForCRDResources(ctx, dc, []schema.GroupVersionResource{
+			{Group: "example.com", Version: "v1", Resource: "gadgets"},
+		}, 10*time.Millisecond, time.Minute)
+		g.Expect(err).To(MatchError(context.Canceled))
+	})
+}
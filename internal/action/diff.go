@@ -40,12 +40,30 @@ import (
 	ssautil "github.com/fluxcd/pkg/ssa/utils"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/concurrency"
 	"github.com/fluxcd/helm-controller/internal/diff"
 )
 
+// diffLimiter limits the number of concurrent Diff calls, as each of them
+// performs a dry-run apply of all objects of a release.
+var diffLimiter = concurrency.NewLimiter(0)
+
+// SetDiffConcurrency sets the number of concurrent Diff calls. A number of
+// zero or less does not limit them. It is safe to call concurrently with
+// running Diff calls, which are not interrupted.
+func SetDiffConcurrency(n int) {
+	diffLimiter.SetLimit(n)
+}
+
 // Diff returns a jsondiff.DiffSet of the changes between the state of the
 // cluster and the Helm release.Release manifest.
 func Diff(ctx context.Context, config *helmaction.Configuration, rls *helmrelease.Release, fieldOwner string, ignore ...v2.IgnoreRule) (jsondiff.DiffSet, error) {
+	done, err := diffLimiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	// Create a dry-run only client to use solely for diffing.
 	cfg, err := config.RESTClientGetter.ToRESTConfig()
 	if err != nil {
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"reflect"
+	"sort"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+// MaxDiffSummaryChanges is the maximum number of v2.ObjectChange entries in
+// a v2.DiffSummary.
+const MaxDiffSummaryChanges = 50
+
+// SummarizeDiff returns a v2.DiffSummary of the changes between the objects
+// in the manifests of the given releases.
+func SummarizeDiff(config *helmaction.Configuration, from, to release.Observation) (*v2.DiffSummary, error) {
+	mapper, err := config.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return summarizeDiff(mapper, from, to)
+}
+
+func summarizeDiff(mapper apimeta.RESTMapper, from, to release.Observation) (*v2.DiffSummary, error) {
+	fromObjects, err := readReleaseObjects(mapper, from.Manifest, from.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	toObjects, err := readReleaseObjects(mapper, to.Manifest, to.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make(map[string]releaseObject, len(fromObjects))
+	for _, o := range fromObjects {
+		previous[o.id] = o
+	}
+
+	summary := &v2.DiffSummary{
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+	}
+	var changes []v2.ObjectChange
+	for _, o := range toObjects {
+		prev, ok := previous[o.id]
+		delete(previous, o.id)
+		switch {
+		case !ok:
+			summary.Added++
+			changes = append(changes, v2.ObjectChange{ID: o.id, Action: v2.ObjectAdded})
+		case prev.digest != o.digest:
+			summary.Modified++
+			changes = append(changes, v2.ObjectChange{
+				ID:     o.id,
+				Action: v2.ObjectModified,
+				Fields: changedFields(prev.obj.Object, o.obj.Object),
+			})
+		}
+	}
+	for id := range previous {
+		summary.Removed++
+		changes = append(changes, v2.ObjectChange{ID: id, Action: v2.ObjectRemoved})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+	if len(changes) > MaxDiffSummaryChanges {
+		changes = changes[:MaxDiffSummaryChanges]
+	}
+	summary.Changes = changes
+	return summary, nil
+}
+
+// changedFields returns the sorted paths of the fields which differ between
+// the given objects, up to two levels deep.
+func changedFields(from, to map[string]interface{}) []string {
+	var fields []string
+	for _, k := range unionKeys(from, to) {
+		a, b := from[k], to[k]
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		am, aOk := a.(map[string]interface{})
+		bm, bOk := b.(map[string]interface{})
+		if !aOk || !bOk {
+			fields = append(fields, k)
+			continue
+		}
+		for _, kk := range unionKeys(am, bm) {
+			if !reflect.DeepEqual(am[kk], bm[kk]) {
+				fields = append(fields, k+"."+kk)
+			}
+		}
+	}
+	return fields
+}
+
+// unionKeys returns the sorted union of the keys of the given maps.
+func unionKeys(a, b map[string]interface{}) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		seen[k] = struct{}{}
+	}
+	for k := range b {
+		seen[k] = struct{}{}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
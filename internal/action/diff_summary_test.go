@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+func Test_summarizeDiff(t *testing.T) {
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, apimeta.RESTScopeNamespace)
+
+	t.Run("summarizes changes", func(t *testing.T) {
+		g := NewWithT(t)
+
+		from := release.Observation{
+			Version:   1,
+			Namespace: "release",
+			Manifest: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: removed
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    version: "1"
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1
+`,
+		}
+		to := release.Observation{
+			Version:   2,
+			Namespace: "release",
+			Manifest: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: added
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    version: "2"
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:2
+`,
+		}
+
+		got, err := summarizeDiff(mapper, from, to)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(got).To(Equal(&v2.DiffSummary{
+			FromVersion: 1,
+			ToVersion:   2,
+			Added:       1,
+			Modified:    1,
+			Removed:     1,
+			Changes: []v2.ObjectChange{
+				{ID: "release_added__ConfigMap", Action: v2.ObjectAdded},
+				{ID: "release_app_apps_Deployment", Action: v2.ObjectModified, Fields: []string{"metadata.labels", "spec.template"}},
+				{ID: "release_removed__ConfigMap", Action: v2.ObjectRemoved},
+			},
+		}))
+	})
+
+	t.Run("limits changes", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var b strings.Builder
+		for i := 0; i < MaxDiffSummaryChanges+10; i++ {
+			fmt.Fprintf(&b, "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config-%03d\n", i)
+		}
+
+		got, err := summarizeDiff(mapper, release.Observation{Version: 1}, release.Observation{Version: 2, Manifest: b.String()})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(got.Added).To(Equal(MaxDiffSummaryChanges + 10))
+		g.Expect(got.Changes).To(HaveLen(MaxDiffSummaryChanges))
+		g.Expect(got.Changes[0].ID).To(Equal("_config-000__ConfigMap"))
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := summarizeDiff(mapper, release.Observation{Manifest: "invalid: ["}, release.Observation{})
+		g.Expect(err).To(HaveOccurred())
+	})
+}
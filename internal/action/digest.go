@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	apierrutil "k8s.io/apimachinery/pkg/util/errors"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+	intdigest "github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+// MigrateDigests recalculates the digests in the status of the given object
+// which were calculated with another algorithm than the given algorithm.
+//
+// The digests of a v2.Snapshot are only recalculated after the Snapshot has
+// been verified against the release in the Helm storage, and the observed
+// post-renderers digest only when it matches the post-renderers of the
+// current generation. This ensures a digest is never recalculated for data
+// which does not match the original digest.
+//
+// It returns an error for the digests which could not be migrated and are
+// not intdigest.Verifiable, for example because they were calculated with an
+// algorithm which is no longer allowed. Snapshots of releases which are no
+// longer in the Helm storage are ignored.
+func MigrateDigests(config *helmaction.Configuration, obj *v2.HelmRelease, algo digest.Algorithm) error {
+	var errs []error
+	for _, snap := range obj.Status.History {
+		if digest.Digest(snap.Digest).Algorithm() == algo {
+			continue
+		}
+
+		rls, err := VerifySnapshot(config, snap)
+		if err != nil {
+			if errors.Is(err, ErrReleaseDisappeared) {
+				continue
+			}
+			if vErr := intdigest.Verifiable(digest.Digest(snap.Digest)); vErr != nil {
+				errs = append(errs, fmt.Errorf("digest of release %s is not verifiable: %w", snap.FullReleaseName(), vErr))
+			}
+			continue
+		}
+
+		obs := release.ObserveRelease(rls)
+		obs.OCIDigest = snap.OCIDigest
+		obs.Provenance = snap.Provenance
+		snap.Digest = release.Digest(algo, obs).String()
+		snap.ConfigDigest = chartutil.DigestValues(algo, rls.Config).String()
+	}
+
+	if d := obj.Status.ObservedPostRenderersDigest; d != "" && digest.Digest(d).Algorithm() != algo &&
+		obj.Status.ObservedGeneration == obj.Generation && postrender.VerifyDigest(d, obj) {
+		obj.Status.ObservedPostRenderersDigest = postrender.DigestFor(algo, obj).String()
+	}
+
+	return apierrutil.NewAggregate(errs)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestMigrateDigests(t *testing.T) {
+	g := NewWithT(t)
+
+	current := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Version:   2,
+		Status:    helmrelease.StatusDeployed,
+		Namespace: "default",
+	})
+	disappeared := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Version:   1,
+		Status:    helmrelease.StatusSuperseded,
+		Namespace: "default",
+	})
+
+	s := helmstorage.Init(driver.NewMemory())
+	g.Expect(s.Create(current)).To(Succeed())
+
+	obj := &v2.HelmRelease{
+		Spec: v2.HelmReleaseSpec{
+			PostRenderers: []v2.PostRenderer{{Kustomize: &v2.Kustomize{}}},
+		},
+		Status: v2.HelmReleaseStatus{
+			History: v2.Snapshots{
+				release.ObservedToSnapshot(release.ObserveRelease(current)),
+				release.ObservedToSnapshot(release.ObserveRelease(disappeared)),
+			},
+		},
+	}
+	obj.Status.ObservedPostRenderersDigest = postrender.DigestFor(digest.SHA256, obj).String()
+	disappearedDigest := obj.Status.History[1].Digest
+
+	g.Expect(MigrateDigests(&helmaction.Configuration{Releases: s}, obj, digest.SHA512)).To(Succeed())
+
+	g.Expect(obj.Status.History[0].Digest).To(Equal(release.Digest(digest.SHA512, release.ObserveRelease(current)).String()))
+	g.Expect(obj.Status.History[0].ConfigDigest).To(Equal(chartutil.DigestValues(digest.SHA512, current.Config).String()))
+	g.Expect(obj.Status.History[1].Digest).To(Equal(disappearedDigest))
+	g.Expect(obj.Status.ObservedPostRenderersDigest).To(Equal(postrender.DigestFor(digest.SHA512, obj).String()))
+
+	// Migrated snapshots remain verifiable.
+	_, err := VerifySnapshot(&helmaction.Configuration{Releases: s}, obj.Status.History[0])
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Digests which do not match are not migrated, and are reported if
+	// they can not be verified.
+	obj.Status.History[0].Digest = "md5:d41d8cd98f00b204e9800998ecf8427e"
+	err = MigrateDigests(&helmaction.Configuration{Releases: s}, obj, digest.SHA256)
+	g.Expect(err).To(MatchError(ContainSubstring("digest of release default/release.v2 is not verifiable")))
+	g.Expect(obj.Status.History[0].Digest).To(Equal("md5:d41d8cd98f00b204e9800998ecf8427e"))
+}
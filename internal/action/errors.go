@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/fluxcd/helm-controller/internal/podsecurity"
+	"github.com/fluxcd/helm-controller/internal/signature"
+	"github.com/fluxcd/helm-controller/internal/storage"
+)
+
+// MetaErrorCodeKey is the key of the event annotation with the ErrorCode of a
+// failed action, without the API group prefix.
+const MetaErrorCodeKey = "error-code"
+
+// ErrorCode is a machine-readable code for the cause of a failed Helm action,
+// which allows e.g. alerts to be routed based on the kind of failure.
+type ErrorCode string
+
+const (
+	// ErrorCodeChartFetch is the ErrorCode for a failure to fetch or load the
+	// chart artifact of a HelmRelease.
+	ErrorCodeChartFetch ErrorCode = "ChartFetch"
+	// ErrorCodeRender is the ErrorCode for a failure to render the chart
+	// templates, or to build Kubernetes objects from the rendered manifest.
+	ErrorCodeRender ErrorCode = "Render"
+	// ErrorCodeAdmission is the ErrorCode for a request which was denied by
+	// an admission controller of the cluster, or by the admission checks of
+	// the controller itself (e.g. Pod Security or image signatures).
+	ErrorCodeAdmission ErrorCode = "Admission"
+	// ErrorCodeWaitTimeout is the ErrorCode for a timeout while waiting for
+	// the resources or hooks of a release.
+	ErrorCodeWaitTimeout ErrorCode = "WaitTimeout"
+	// ErrorCodeStorage is the ErrorCode for a failure to read or write the
+	// Helm storage.
+	ErrorCodeStorage ErrorCode = "Storage"
+	// ErrorCodeRBAC is the ErrorCode for a request which was denied because
+	// the (impersonated) identity of the controller lacks permissions.
+	ErrorCodeRBAC ErrorCode = "RBAC"
+	// ErrorCodeUnknown is the ErrorCode for a failure which could not be
+	// classified.
+	ErrorCodeUnknown ErrorCode = "Unknown"
+)
+
+var (
+	// admissionErrorMessages are substrings of the messages of errors
+	// returned by admission controllers.
+	admissionErrorMessages = []string{
+		"admission webhook",
+		"denied the request",
+		"violates PodSecurity",
+		"exceeded quota",
+	}
+	// rbacErrorMessages are substrings of the messages of errors returned by
+	// the Kubernetes API server for requests denied by RBAC.
+	rbacErrorMessages = []string{
+		"is forbidden: User",
+		"Unauthorized",
+	}
+	// waitTimeoutErrorMessages are substrings of the messages of errors
+	// returned by Helm when waiting for resources or hooks times out.
+	waitTimeoutErrorMessages = []string{
+		"timed out waiting for the condition",
+		"context deadline exceeded",
+	}
+	// renderErrorMessages are substrings of the messages of errors returned
+	// by Helm when rendering the chart fails.
+	renderErrorMessages = []string{
+		"parse error",
+		"template: ",
+		"error converting YAML to JSON",
+		"unable to build kubernetes objects",
+		"values don't meet the specifications of the schema",
+	}
+	// storageErrorMessages are substrings of the messages of errors returned
+	// by Helm when operating on its storage.
+	storageErrorMessages = []string{
+		"create: failed to create",
+		"update: failed to update",
+		"query: failed to query",
+		"list: failed to list",
+		"delete: failed to delete",
+	}
+)
+
+// ClassifyError returns the ErrorCode for the given error of a Helm action.
+// As Helm does not preserve the type of the errors of the individual
+// Kubernetes API requests it performs, the message of the error is taken into
+// account when the type does not provide a classification. It returns
+// ErrorCodeUnknown if the error can not be classified, or is nil.
+func ClassifyError(err error) ErrorCode {
+	if err == nil {
+		return ErrorCodeUnknown
+	}
+
+	var (
+		violationErr    *podsecurity.ViolationError
+		verificationErr *signature.VerificationError
+		tooLargeErr     *storage.TooLargeError
+	)
+	switch {
+	case errors.As(err, &violationErr), errors.As(err, &verificationErr):
+		return ErrorCodeAdmission
+	case errors.As(err, &tooLargeErr), errors.Is(err, helmdriver.ErrReleaseExists),
+		errors.Is(err, helmdriver.ErrInvalidKey):
+		return ErrorCodeStorage
+	}
+
+	msg := err.Error()
+	switch {
+	case containsAny(msg, admissionErrorMessages):
+		return ErrorCodeAdmission
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err), containsAny(msg, rbacErrorMessages):
+		return ErrorCodeRBAC
+	case errors.Is(err, context.DeadlineExceeded), wait.Interrupted(err), containsAny(msg, waitTimeoutErrorMessages):
+		return ErrorCodeWaitTimeout
+	case containsAny(msg, renderErrorMessages):
+		return ErrorCodeRender
+	case containsAny(msg, storageErrorMessages):
+		return ErrorCodeStorage
+	}
+	return ErrorCodeUnknown
+}
+
+// containsAny returns true if s contains any of the given substrings.
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
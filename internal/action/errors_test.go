@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/helm-controller/internal/podsecurity"
+	"github.com/fluxcd/helm-controller/internal/storage"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: ErrorCodeUnknown,
+		},
+		{
+			name: "unclassified",
+			err:  errors.New("something went wrong"),
+			want: ErrorCodeUnknown,
+		},
+		{
+			name: "pod security violation",
+			err:  fmt.Errorf("pre-check failed: %w", &podsecurity.ViolationError{Level: podsecurity.LevelRestricted}),
+			want: ErrorCodeAdmission,
+		},
+		{
+			name: "admission webhook",
+			err:  errors.New(`admission webhook "validate.kyverno.svc" denied the request: policy violation`),
+			want: ErrorCodeAdmission,
+		},
+		{
+			name: "forbidden status error",
+			err: fmt.Errorf("failed to create resource: %w", apierrors.NewForbidden(
+				schema.GroupResource{Resource: "secrets"}, "podinfo", errors.New("access denied"))),
+			want: ErrorCodeRBAC,
+		},
+		{
+			name: "forbidden message",
+			err:  errors.New(`deployments.apps is forbidden: User "system:serviceaccount:apps:default" cannot create resource "deployments"`),
+			want: ErrorCodeRBAC,
+		},
+		{
+			name: "wait timeout",
+			err:  errors.New("post-install hooks failed: 1 error occurred:\n\t* timed out waiting for the condition"),
+			want: ErrorCodeWaitTimeout,
+		},
+		{
+			name: "context deadline",
+			err:  fmt.Errorf("failed waiting for resources: %w", context.DeadlineExceeded),
+			want: ErrorCodeWaitTimeout,
+		},
+		{
+			name: "render error",
+			err:  errors.New("parse error at (podinfo/templates/service.yaml:4): unexpected EOF"),
+			want: ErrorCodeRender,
+		},
+		{
+			name: "storage too large",
+			err:  fmt.Errorf("create: failed to create: %w", &storage.TooLargeError{Name: "sh.helm.release.v1.podinfo.v1"}),
+			want: ErrorCodeStorage,
+		},
+		{
+			name: "storage driver error",
+			err:  fmt.Errorf("failed to record release: %w", helmdriver.ErrReleaseExists),
+			want: ErrorCodeStorage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ClassifyError(tt.err)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apierrutil "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+var (
+	jobGroupKind = schema.GroupKind{Group: batchv1.GroupName, Kind: "Job"}
+	podGroupKind = schema.GroupKind{Group: corev1.GroupName, Kind: "Pod"}
+)
+
+// CleanupHooks records the Jobs and Pods created by the hooks of the
+// releases with the given name in the Helm storage in the given inventory,
+// and deletes the recorded objects which finished longer than the given TTL
+// ago. It returns the inventory of the recorded objects which remain.
+//
+// The objects are read with the given reader and deleted with the given
+// writer. When either is nil, a client is created for the cluster of the
+// given configuration.
+//
+// Objects are tracked in the inventory, as the releases which created them
+// may have been removed from the Helm storage by the time they expire.
+func CleanupHooks(ctx context.Context, config *helmaction.Configuration, reader client.Reader, writer client.Writer,
+	releaseName string, inv *v2.ResourceInventory, ttl time.Duration) (*v2.ResourceInventory, error) {
+	history, err := config.Releases.History(release.ShortenName(releaseName))
+	if err != nil && !errors.Is(err, helmdriver.ErrReleaseNotFound) {
+		return inv, err
+	}
+	if inv, err = hookInventory(history, inv); err != nil {
+		return inv, err
+	}
+
+	if reader == nil || writer == nil {
+		cfg, err := config.RESTClientGetter.ToRESTConfig()
+		if err != nil {
+			return inv, err
+		}
+		mapper, err := config.RESTClientGetter.ToRESTMapper()
+		if err != nil {
+			return inv, err
+		}
+		c, err := client.New(cfg, client.Options{Mapper: mapper})
+		if err != nil {
+			return inv, err
+		}
+		reader, writer = c, c
+	}
+	return cleanupHooks(ctx, reader, writer, inv, ttl, time.Now())
+}
+
+// hookInventory returns the given inventory extended with the Jobs and Pods
+// created by the hooks of the given releases. Hook objects without a
+// namespace are assigned the namespace of their release.
+func hookInventory(releases []*helmrelease.Release, inv *v2.ResourceInventory) (*v2.ResourceInventory, error) {
+	seen := make(map[string]struct{})
+	result := &v2.ResourceInventory{}
+	if inv != nil {
+		for _, e := range inv.Entries {
+			seen[e.ID] = struct{}{}
+			result.Entries = append(result.Entries, e)
+		}
+	}
+
+	for _, rls := range releases {
+		for _, hook := range rls.Hooks {
+			if hook == nil || (hook.Kind != jobGroupKind.Kind && hook.Kind != podGroupKind.Kind) {
+				continue
+			}
+			objects, err := ssautil.ReadObjects(strings.NewReader(hook.Manifest))
+			if err != nil {
+				return inv, fmt.Errorf("failed to read hook '%s' of release %s: %w", hook.Name, rls.Name, err)
+			}
+			for _, obj := range objects {
+				if gk := obj.GroupVersionKind().GroupKind(); gk != jobGroupKind && gk != podGroupKind {
+					continue
+				}
+				if obj.GetNamespace() == "" {
+					obj.SetNamespace(rls.Namespace)
+				}
+				id := object.UnstructuredToObjMetadata(obj).String()
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				seen[id] = struct{}{}
+				result.Entries = append(result.Entries, v2.ResourceRef{
+					ID:      id,
+					Version: obj.GroupVersionKind().Version,
+				})
+			}
+		}
+	}
+
+	if len(result.Entries) == 0 {
+		return nil, nil
+	}
+	sort.Slice(result.Entries, func(i, j int) bool {
+		return result.Entries[i].ID < result.Entries[j].ID
+	})
+	return result, nil
+}
+
+// cleanupHooks deletes the objects in the given inventory which finished
+// longer than the given TTL before now, and returns the inventory of the
+// objects which remain. Objects which no longer exist, for example because
+// they were deleted by Helm according to their hook deletion policy, are
+// removed from the inventory.
+func cleanupHooks(ctx context.Context, reader client.Reader, writer client.Writer, inv *v2.ResourceInventory,
+	ttl time.Duration, now time.Time) (*v2.ResourceInventory, error) {
+	if inv == nil {
+		return nil, nil
+	}
+
+	var (
+		remaining []v2.ResourceRef
+		errs      []error
+	)
+	for _, e := range inv.Entries {
+		meta, err := object.ParseObjMetadata(e.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid hook inventory entry '%s': %w", e.ID, err))
+			continue
+		}
+
+		var obj client.Object
+		switch meta.GroupKind {
+		case jobGroupKind:
+			obj = &batchv1.Job{}
+		case podGroupKind:
+			obj = &corev1.Pod{}
+		default:
+			continue
+		}
+		if err = reader.Get(ctx, client.ObjectKey{Namespace: meta.Namespace, Name: meta.Name}, obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				errs = append(errs, err)
+				remaining = append(remaining, e)
+			}
+			continue
+		}
+
+		finished, ok := hookFinishedAt(obj)
+		if !ok || finished.Add(ttl).After(now) {
+			remaining = append(remaining, e)
+			continue
+		}
+		if err = writer.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete %s '%s/%s': %w", meta.GroupKind.Kind, meta.Namespace, meta.Name, err))
+			remaining = append(remaining, e)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return nil, apierrutil.NewAggregate(errs)
+	}
+	return &v2.ResourceInventory{Entries: remaining}, apierrutil.NewAggregate(errs)
+}
+
+// hookFinishedAt returns the time the given Job or Pod finished, or false if
+// it has not finished.
+func hookFinishedAt(obj client.Object) (time.Time, bool) {
+	var finished time.Time
+	switch o := obj.(type) {
+	case *batchv1.Job:
+		for _, c := range o.Status.Conditions {
+			if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+				return c.LastTransitionTime.Time, true
+			}
+		}
+		return finished, false
+	case *corev1.Pod:
+		if o.Status.Phase != corev1.PodSucceeded && o.Status.Phase != corev1.PodFailed {
+			return finished, false
+		}
+		for _, statuses := range [][]corev1.ContainerStatus{o.Status.InitContainerStatuses, o.Status.ContainerStatuses} {
+			for _, s := range statuses {
+				if t := s.State.Terminated; t != nil && t.FinishedAt.After(finished) {
+					finished = t.FinishedAt.Time
+				}
+			}
+		}
+		// Fall back to the last transition of the Pod if no container
+		// reported when it finished, e.g. when it was evicted.
+		if finished.IsZero() {
+			for _, c := range o.Status.Conditions {
+				if c.LastTransitionTime.After(finished) {
+					finished = c.LastTransitionTime.Time
+				}
+			}
+		}
+		return finished, !finished.IsZero()
+	default:
+		return finished, false
+	}
+}
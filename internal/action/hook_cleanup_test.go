@@ -0,0 +1,204 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+func TestCleanupHooks(t *testing.T) {
+	g := NewWithT(t)
+
+	// The release name exceeds the maximum length of a Helm release name,
+	// and is stored under its shortened name.
+	releaseName := strings.Repeat("a", 60)
+	store := helmstorage.Init(helmdriver.NewMemory())
+	g.Expect(store.Create(&helmrelease.Release{
+		Name:      release.ShortenName(releaseName),
+		Namespace: "apps",
+		Version:   1,
+		Info:      &helmrelease.Info{Status: helmrelease.StatusDeployed},
+		Hooks: []*helmrelease.Hook{
+			{
+				Name: "migrate",
+				Kind: "Job",
+				Manifest: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate`,
+			},
+		},
+	})).To(Succeed())
+
+	c := fake.NewClientBuilder().WithObjects(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "apps"},
+	}).Build()
+
+	got, err := CleanupHooks(context.TODO(), &helmaction.Configuration{Releases: store}, c, c, releaseName, nil, time.Hour)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.Entries).To(Equal([]v2.ResourceRef{
+		{ID: "apps_migrate_batch_Job", Version: "v1"},
+	}))
+}
+
+func Test_hookInventory(t *testing.T) {
+	g := NewWithT(t)
+
+	releases := []*helmrelease.Release{
+		{
+			Name:      "podinfo",
+			Namespace: "apps",
+			Hooks: []*helmrelease.Hook{
+				{
+					Name: "migrate",
+					Kind: "Job",
+					Manifest: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate`,
+				},
+				{
+					Name: "config",
+					Kind: "ConfigMap",
+					Manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config`,
+				},
+			},
+		},
+		{
+			Name:      "podinfo",
+			Namespace: "apps",
+			Hooks: []*helmrelease.Hook{
+				{
+					Name: "test",
+					Kind: "Pod",
+					Manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: test
+  namespace: tests`,
+				},
+			},
+		},
+	}
+	current := &v2.ResourceInventory{
+		Entries: []v2.ResourceRef{
+			{ID: "apps_old_batch_Job", Version: "v1"},
+			{ID: "apps_migrate_batch_Job", Version: "v1"},
+		},
+	}
+
+	got, err := hookInventory(releases, current)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.Entries).To(Equal([]v2.ResourceRef{
+		{ID: "apps_migrate_batch_Job", Version: "v1"},
+		{ID: "apps_old_batch_Job", Version: "v1"},
+		{ID: "tests_test__Pod", Version: "v1"},
+	}))
+
+	got, err = hookInventory(nil, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(BeNil())
+}
+
+func Test_cleanupHooks(t *testing.T) {
+	now := time.Now()
+	expired := metav1.NewTime(now.Add(-2 * time.Hour))
+	recent := metav1.NewTime(now.Add(-10 * time.Minute))
+
+	objects := []client.Object{
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "expired", Namespace: "apps"},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: expired},
+				},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "recent", Namespace: "apps"},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: recent},
+				},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "apps"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "apps"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: expired}}},
+				},
+			},
+		},
+	}
+
+	g := NewWithT(t)
+
+	c := fake.NewClientBuilder().WithObjects(objects...).Build()
+	got, err := cleanupHooks(context.TODO(), c, c, &v2.ResourceInventory{
+		Entries: []v2.ResourceRef{
+			{ID: "apps_expired_batch_Job", Version: "v1"},
+			{ID: "apps_gone_batch_Job", Version: "v1"},
+			{ID: "apps_recent_batch_Job", Version: "v1"},
+			{ID: "apps_running_batch_Job", Version: "v1"},
+			{ID: "apps_test__Pod", Version: "v1"},
+		},
+	}, time.Hour, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.Entries).To(Equal([]v2.ResourceRef{
+		{ID: "apps_recent_batch_Job", Version: "v1"},
+		{ID: "apps_running_batch_Job", Version: "v1"},
+	}))
+
+	err = c.Get(context.TODO(), client.ObjectKey{Namespace: "apps", Name: "expired"}, &batchv1.Job{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	err = c.Get(context.TODO(), client.ObjectKey{Namespace: "apps", Name: "test"}, &corev1.Pod{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "apps", Name: "recent"}, &batchv1.Job{})).To(Succeed())
+
+	// Objects which have not finished are retained regardless of the TTL.
+	got, err = cleanupHooks(context.TODO(), c, c, got, 0, now.Add(time.Hour))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.Entries).To(Equal([]v2.ResourceRef{
+		{ID: "apps_running_batch_Job", Version: "v1"},
+	}))
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmpostrender "helm.sh/helm/v3/pkg/postrender"
+
+	"github.com/fluxcd/helm-controller/internal/postrender"
+	"github.com/fluxcd/helm-controller/internal/signature"
+)
+
+// withImageVerification returns the given post-renderer combined with a
+// postrender.ImageSignatures post-renderer for the signature.ImageVerifier,
+// if it is configured. The image pull secrets are retrieved with the
+// Kubernetes client of the given config, if available.
+func withImageVerification(ctx context.Context, config *helmaction.Configuration, namespace string,
+	renderer helmpostrender.PostRenderer) helmpostrender.PostRenderer {
+	if signature.ImageVerifier == nil {
+		return renderer
+	}
+	clientSet, err := kubeClientSet(config)
+	if err != nil {
+		clientSet = nil
+	}
+	imageSignatures := postrender.NewImageSignatures(ctx, signature.ImageVerifier, clientSet, namespace)
+	if renderer == nil {
+		return imageSignatures
+	}
+	return postrender.NewCombined(renderer, imageSignatures)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/fluxcd/helm-controller/internal/features"
 	"github.com/fluxcd/helm-controller/internal/postrender"
 	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/tracing"
 )
 
 // InstallOption can be used to modify Helm's action.Install after the instructions
@@ -48,14 +49,33 @@ type InstallOption func(action *helmaction.Install)
 // action result. The caller is expected to listen to this using a
 // storage.ObserveFunc, which provides superior access to Helm storage writes.
 func Install(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease,
-	chrt *helmchart.Chart, vals helmchartutil.Values, opts ...InstallOption) (*helmrelease.Release, error) {
+	chrt *helmchart.Chart, vals helmchartutil.Values, opts ...InstallOption) (rls *helmrelease.Release, err error) {
+	ctx, span, config := startSpan(ctx, "helm install", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if err := withWaitStrategies(config, obj.GetInstall().WaitStrategies); err != nil {
+		return nil, err
+	}
+
+	// Apply the namespace metadata before the Pod Security pre-check, as it
+	// may set the level enforced on the namespace.
+	if err := ApplyNamespaceMetadata(ctx, config, obj); err != nil {
+		return nil, fmt.Errorf("failed to apply namespace metadata: %w", err)
+	}
+
 	install := newInstall(config, obj, opts)
+	install.PostRenderer = withValuesChecksum(obj, vals, install.PostRenderer)
+	install.PostRenderer = withPodSecurityCheck(ctx, config, install.Namespace, install.PostRenderer)
+	install.PostRenderer = withResourceQuotaCheck(ctx, config, install.Namespace, release.ShortenName(obj.GetReleaseName()), install.PostRenderer)
+	install.PostRenderer = withAPICompatibilityCheck(config, install.PostRenderer)
+	install.PostRenderer = withCRDSchemaCheck(ctx, config, install.PostRenderer)
+	install.PostRenderer = withImageVerification(ctx, config, install.Namespace, install.PostRenderer)
 
 	policy, err := crdPolicyOrDefault(obj.GetInstall().CRDs)
 	if err != nil {
 		return nil, err
 	}
-	if err := applyCRDs(config, policy, chrt, setOriginVisitor(v2.GroupVersion.Group, obj.Namespace, obj.Name)); err != nil {
+	if err := applyCRDs(ctx, config, policy, chrt, setOriginVisitor(v2.GroupVersion.Group, obj.Namespace, obj.Name)); err != nil {
 		return nil, fmt.Errorf("failed to apply CustomResourceDefinitions: %w", err)
 	}
 
@@ -67,7 +87,7 @@ func newInstall(config *helmaction.Configuration, obj *v2.HelmRelease, opts []In
 
 	install.ReleaseName = release.ShortenName(obj.GetReleaseName())
 	install.Namespace = obj.GetReleaseNamespace()
-	install.Timeout = obj.GetInstall().GetTimeout(obj.GetTimeout()).Duration
+	install.Timeout = obj.GetInstall().GetTimeout(Timeout(obj)).Duration
 	install.Wait = !obj.GetInstall().DisableWait
 	install.WaitForJobs = !obj.GetInstall().DisableWaitForJobs
 	install.DisableHooks = obj.GetInstall().DisableHooks
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/digest"
+)
+
+// Inventory returns the v2.ResourceInventory of the objects in the manifest
+// of the given Helm release. Objects without a namespace are assigned the
+// namespace of the release if they are namespace scoped, as Helm does when
+// creating them.
+func Inventory(config *helmaction.Configuration, rls *helmrelease.Release) (*v2.ResourceInventory, error) {
+	mapper, err := config.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return inventory(mapper, rls)
+}
+
+func inventory(mapper apimeta.RESTMapper, rls *helmrelease.Release) (*v2.ResourceInventory, error) {
+	objects, err := readReleaseObjects(mapper, rls.Manifest, rls.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &v2.ResourceInventory{Entries: make([]v2.ResourceRef, 0, len(objects))}
+	for _, o := range objects {
+		inv.Entries = append(inv.Entries, v2.ResourceRef{
+			ID:      o.id,
+			Version: o.obj.GroupVersionKind().Version,
+			Digest:  o.digest,
+		})
+	}
+
+	sort.Slice(inv.Entries, func(i, j int) bool {
+		return inv.Entries[i].ID < inv.Entries[j].ID
+	})
+	return inv, nil
+}
+
+// releaseObject is an object read from the manifest of a Helm release.
+type releaseObject struct {
+	// id is the object.ObjMetadata string of the object.
+	id string
+	// digest is the digest of the object as rendered in the manifest.
+	digest string
+	// obj is the object, with the namespace of the release set if it is
+	// namespace scoped and has no namespace.
+	obj *unstructured.Unstructured
+}
+
+// readReleaseObjects reads the objects from the given manifest of a Helm
+// release in the given namespace. Objects without a namespace are assigned
+// the namespace of the release if they are namespace scoped according to the
+// mapper.
+func readReleaseObjects(mapper apimeta.RESTMapper, manifest, namespace string) ([]releaseObject, error) {
+	objects, err := ssautil.ReadObjects(strings.NewReader(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read objects from release manifest: %w", err)
+	}
+
+	result := make([]releaseObject, 0, len(objects))
+	for _, obj := range objects {
+		// Compute the digest before mutating the object.
+		b, err := json.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+
+		if obj.GetNamespace() == "" {
+			gvk := obj.GroupVersionKind()
+			mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine if %s is namespace scoped: %w", gvk.Kind, err)
+			}
+			if mapping.Scope.Name() == apimeta.RESTScopeNameNamespace {
+				obj.SetNamespace(namespace)
+			}
+		}
+
+		result = append(result, releaseObject{
+			id:     object.UnstructuredToObjMetadata(obj).String(),
+			digest: digest.Canonical.FromBytes(b).String(),
+			obj:    obj,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func Test_inventory(t *testing.T) {
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, apimeta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, apimeta.RESTScopeNamespace)
+
+	tests := []struct {
+		name     string
+		manifest string
+		wantIDs  []string
+		wantErr  string
+	}{
+		{
+			name: "objects with and without namespace",
+			manifest: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: other
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns
+`,
+			wantIDs: []string{
+				"_ns__Namespace",
+				"other_app_apps_Deployment",
+				"release_config__ConfigMap",
+			},
+		},
+		{
+			name:     "empty manifest",
+			manifest: "",
+			wantIDs:  []string{},
+		},
+		{
+			name: "unknown kind",
+			manifest: `---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+`,
+			wantErr: "failed to determine if Unknown is namespace scoped",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := inventory(mapper, &helmrelease.Release{
+				Namespace: "release",
+				Manifest:  tt.manifest,
+			})
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			ids := make([]string, 0, len(got.Entries))
+			for _, e := range got.Entries {
+				g.Expect(e.Version).To(Equal("v1"))
+				g.Expect(e.Digest).To(HavePrefix("sha256:"))
+				ids = append(ids, e.ID)
+			}
+			g.Expect(ids).To(Equal(tt.wantIDs))
+		})
+	}
+}
+
+func Test_inventory_digest(t *testing.T) {
+	g := NewWithT(t)
+
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+
+	newRelease := func(value string) *helmrelease.Release {
+		return &helmrelease.Release{
+			Namespace: "release",
+			Manifest: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: ` + value,
+		}
+	}
+
+	a, err := inventory(mapper, newRelease("a"))
+	g.Expect(err).ToNot(HaveOccurred())
+	b, err := inventory(mapper, newRelease("a"))
+	g.Expect(err).ToNot(HaveOccurred())
+	c, err := inventory(mapper, newRelease("b"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(a.Entries[0].Digest).To(Equal(b.Entries[0].Digest))
+	g.Expect(a.Entries[0].Digest).ToNot(Equal(c.Entries[0].Digest))
+}
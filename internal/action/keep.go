@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/pkg/apis/kustomize"
+	"github.com/fluxcd/pkg/ssa/jsondiff"
+)
+
+// keepResources adds the Helm resource policy annotation with the keep
+// policy to the objects in the manifest of the latest release with the given
+// name which match any of the selectors, and updates the release in the Helm
+// storage. This causes a subsequent Helm uninstall to keep the objects.
+//
+// It is a no-op if there are no selectors, no objects match, or the release
+// does not exist.
+func keepResources(config *helmaction.Configuration, releaseName string, selectors []kustomize.Selector) error {
+	if len(selectors) == 0 {
+		return nil
+	}
+
+	rls, err := config.Releases.Last(releaseName)
+	if err != nil {
+		if errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	mapper, err := config.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	manifest, kept, err := keepManifestResources(mapper, rls.Manifest, rls.Namespace, selectors)
+	if err != nil {
+		return err
+	}
+	if kept == 0 {
+		return nil
+	}
+
+	rls.Manifest = manifest
+	if err = config.Releases.Update(rls); err != nil {
+		return fmt.Errorf("failed to mark resources to keep in release: %w", err)
+	}
+	return nil
+}
+
+// keepManifestResources returns the given manifest of a Helm release in the
+// given namespace, with the Helm resource policy annotation with the keep
+// policy added to the objects matching any of the selectors. It returns the
+// number of objects which matched.
+func keepManifestResources(mapper apimeta.RESTMapper, manifest, namespace string, selectors []kustomize.Selector) (string, int, error) {
+	regexes, err := keepSelectorRegexes(selectors)
+	if err != nil {
+		return "", 0, err
+	}
+
+	manifests := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var (
+		b    strings.Builder
+		kept int
+	)
+	for _, k := range keys {
+		content := manifests[k]
+
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(content), &obj.Object); err != nil {
+			return "", 0, fmt.Errorf("failed to read object from release manifest: %w", err)
+		}
+		if len(obj.Object) > 0 && matchesAny(mapper, obj, namespace, regexes) {
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string, 1)
+			}
+			annotations[kube.ResourcePolicyAnno] = kube.KeepPolicy
+			obj.SetAnnotations(annotations)
+
+			y, err := yaml.Marshal(obj.Object)
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to encode object: %w", err)
+			}
+			// Retain the source template comment Helm prefixes the object with.
+			source, _, _ := strings.Cut(content, "\n")
+			if !strings.HasPrefix(source, "# Source: ") {
+				source = ""
+			}
+			content = strings.TrimPrefix(source+"\n"+strings.TrimSuffix(string(y), "\n"), "\n")
+			kept++
+		}
+
+		b.WriteString("---\n")
+		b.WriteString(content)
+		b.WriteString("\n")
+	}
+	return b.String(), kept, nil
+}
+
+// keepSelectorRegexes returns the jsondiff.SelectorRegex for each of the
+// given keep resources selectors.
+func keepSelectorRegexes(selectors []kustomize.Selector) ([]*jsondiff.SelectorRegex, error) {
+	regexes := make([]*jsondiff.SelectorRegex, 0, len(selectors))
+	for _, s := range selectors {
+		sr, err := jsondiff.NewSelectorRegex(&jsondiff.Selector{
+			Group:              s.Group,
+			Version:            s.Version,
+			Kind:               s.Kind,
+			Name:               s.Name,
+			Namespace:          s.Namespace,
+			AnnotationSelector: s.AnnotationSelector,
+			LabelSelector:      s.LabelSelector,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep resources selector: %w", err)
+		}
+		regexes = append(regexes, sr)
+	}
+	return regexes, nil
+}
+
+// matchesAny returns if the object in a Helm release in the given namespace
+// matches any of the selectors. An object without a namespace is matched
+// with the namespace of the release if it is namespace scoped.
+func matchesAny(mapper apimeta.RESTMapper, obj *unstructured.Unstructured, namespace string, selectors []*jsondiff.SelectorRegex) bool {
+	if obj.GetNamespace() == "" {
+		obj = obj.DeepCopy()
+		gvk := obj.GroupVersionKind()
+		if mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil &&
+			mapping.Scope.Name() == apimeta.RESTScopeNameNamespace {
+			obj.SetNamespace(namespace)
+		}
+	}
+
+	for _, s := range selectors {
+		if s.MatchUnstructured(obj) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/pkg/apis/kustomize"
+)
+
+func Test_keepManifestResources(t *testing.T) {
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}, apimeta.RESTScopeNamespace)
+
+	const manifest = `---
+# Source: chart/templates/pvc.yaml
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  labels:
+    app: chart
+---
+# Source: chart/templates/other.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+  namespace: other
+`
+
+	tests := []struct {
+		name      string
+		selectors []kustomize.Selector
+		wantKept  int
+		want      string
+	}{
+		{
+			name: "keeps matching resources",
+			selectors: []kustomize.Selector{
+				{Kind: "PersistentVolumeClaim"},
+				{Kind: "ConfigMap", Namespace: "release", LabelSelector: "app=chart"},
+			},
+			wantKept: 2,
+			want: `---
+# Source: chart/templates/pvc.yaml
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  annotations:
+    helm.sh/resource-policy: keep
+  name: data
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    helm.sh/resource-policy: keep
+  labels:
+    app: chart
+  name: config
+---
+# Source: chart/templates/other.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+  namespace: other
+`,
+		},
+		{
+			name:      "no matching resources",
+			selectors: []kustomize.Selector{{Kind: "Secret"}},
+			wantKept:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, kept, err := keepManifestResources(mapper, manifest, "release", tt.selectors)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(kept).To(Equal(tt.wantKept))
+			if tt.want != "" {
+				g.Expect(got).To(Equal(tt.want))
+			}
+		})
+	}
+
+	t.Run("invalid selector", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, _, err := keepManifestResources(mapper, manifest, "release", []kustomize.Selector{{LabelSelector: "!!"}})
+		g.Expect(err).To(MatchError(ContainSubstring("invalid keep resources selector")))
+	})
+}
@@ -19,6 +19,8 @@ package action
 import (
 	"container/ring"
 	"fmt"
+	"regexp"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +35,25 @@ const DefaultLogBufferSize = 5
 // nowTS can be used to stub out time.Now() in tests.
 var nowTS = time.Now
 
+// redactedValue is the value sensitive values are replaced with by Redact.
+const redactedValue = "***"
+
+var (
+	// sensitiveKeyValueRegex matches the value of a key-value pair of which
+	// the key suggests the value is sensitive, e.g. "password: foo" or
+	// "token=bar".
+	sensitiveKeyValueRegex = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key|credentials?|private[_-]?key)["']?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,;}\]]+)`)
+	// bearerTokenRegex matches a bearer token in an authorization header.
+	bearerTokenRegex = regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9._~+/=-]+`)
+)
+
+// Redact replaces sensitive values in the given string, such as passwords
+// and tokens, with a placeholder.
+func Redact(s string) string {
+	s = sensitiveKeyValueRegex.ReplaceAllString(s, "${1}"+redactedValue)
+	return bearerTokenRegex.ReplaceAllString(s, "${1}"+redactedValue)
+}
+
 // NewDebugLog returns an action.DebugLog that logs to the given logr.Logger.
 func NewDebugLog(log logr.Logger) helmaction.DebugLog {
 	return func(format string, v ...interface{}) {
@@ -40,33 +61,116 @@ func NewDebugLog(log logr.Logger) helmaction.DebugLog {
 	}
 }
 
-// LogBuffer is a ring buffer that logs to a Helm action.DebugLog.
+// LogBuffer is a ring buffer that logs to a Helm action.DebugLog. The
+// buffered messages are recorded as structured LogEntry values.
 type LogBuffer struct {
 	mu     sync.RWMutex
 	log    helmaction.DebugLog
 	buffer *ring.Ring
 }
 
-// logLine is a log message with a timestamp.
-type logLine struct {
-	ts     time.Time
-	lastTS time.Time
-	msg    string
-	count  int64
+// LogLevel is the level of a LogEntry. As Helm does not log with levels, it
+// is derived from the message.
+type LogLevel string
+
+const (
+	// LogLevelInfo is the LogLevel of informational messages.
+	LogLevelInfo LogLevel = "info"
+	// LogLevelWarning is the LogLevel of messages which start with a
+	// warning.
+	LogLevelWarning LogLevel = "warning"
+	// LogLevelError is the LogLevel of messages which report an error or a
+	// failure.
+	LogLevelError LogLevel = "error"
+)
+
+// severity returns the severity of the LogLevel, for comparison with other
+// levels.
+func (l LogLevel) severity() int {
+	switch l {
+	case LogLevelWarning:
+		return 1
+	case LogLevelError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// AtLeast returns true if the LogLevel is at least as severe as the given
+// level.
+func (l LogLevel) AtLeast(level LogLevel) bool {
+	return l.severity() >= level.severity()
 }
 
-// String returns the log line as a string, in the format of:
+// levelForMessage returns the LogLevel for the given message.
+func levelForMessage(msg string) LogLevel {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.HasPrefix(lower, "warning"):
+		return LogLevelWarning
+	case strings.Contains(lower, "error"), strings.Contains(lower, "failed"):
+		return LogLevelError
+	default:
+		return LogLevelInfo
+	}
+}
+
+// helmModulePath is the module path of Helm, used to determine the Helm
+// component which logged a message.
+const helmModulePath = "helm.sh/helm/v3"
+
+// componentForCaller returns the name of the Helm package which called the
+// LogBuffer, e.g. "kube", "storage" or "action". It returns an empty string
+// if the caller is not part of Helm.
+func componentForCaller() string {
+	pcs := make([]uintptr, 8)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if i := strings.Index(frame.File, helmModulePath); i >= 0 {
+			path := frame.File[i+len(helmModulePath):]
+			if j := strings.Index(path, "/pkg/"); j >= 0 {
+				component, _, _ := strings.Cut(path[j+len("/pkg/"):], "/")
+				return component
+			}
+			return ""
+		}
+		if !strings.HasPrefix(frame.Function, "github.com/fluxcd/helm-controller/internal/action.") || !more {
+			return ""
+		}
+	}
+}
+
+// LogEntry is a structured log message of a Helm action.
+type LogEntry struct {
+	// Time is when the message was first logged.
+	Time time.Time
+	// LastTime is when the last consecutive duplicate of the message was
+	// logged.
+	LastTime time.Time
+	// Level is the LogLevel of the message.
+	Level LogLevel
+	// Component is the Helm package which logged the message, e.g. "kube".
+	Component string
+	// Message is the log message.
+	Message string
+	// Duplicates is the number of consecutive duplicates of the message.
+	Duplicates int64
+}
+
+// String returns the log entry as a string, in the format of:
 // '<RFC3339 nano timestamp>: <message>'. But only if the message is not empty.
-func (l *logLine) String() string {
-	if l == nil || l.msg == "" {
+func (l *LogEntry) String() string {
+	if l == nil || l.Message == "" {
 		return ""
 	}
 
-	msg := fmt.Sprintf("%s: %s", l.ts.Format(time.RFC3339Nano), l.msg)
-	if c := l.count; c > 0 {
-		msg += fmt.Sprintf("\n%s: %s", l.lastTS.Format(time.RFC3339Nano), l.msg)
+	msg := fmt.Sprintf("%s: %s", l.Time.Format(time.RFC3339Nano), l.Message)
+	if c := l.Duplicates; c > 0 {
+		msg += fmt.Sprintf("\n%s: %s", l.LastTime.Format(time.RFC3339Nano), l.Message)
 	}
-	if c := l.count - 1; c > 0 {
+	if c := l.Duplicates - 1; c > 0 {
 		var dup = "line"
 		if c > 1 {
 			dup += "s"
@@ -92,21 +196,25 @@ func NewLogBuffer(log helmaction.DebugLog, size int) *LogBuffer {
 // Log adds the log message to the ring buffer before calling the actual log
 // function. It is safe to call this function from multiple goroutines.
 func (l *LogBuffer) Log(format string, v ...interface{}) {
+	component := componentForCaller()
+
 	l.mu.Lock()
 
 	// Filter out duplicate log lines, this happens for example when
 	// Helm is waiting on workloads to become ready.
 	msg := fmt.Sprintf(format, v...)
-	prev, ok := l.buffer.Prev().Value.(*logLine)
-	if ok && prev.msg == msg {
-		prev.count++
-		prev.lastTS = nowTS().UTC()
+	prev, ok := l.buffer.Prev().Value.(*LogEntry)
+	if ok && prev.Message == msg {
+		prev.Duplicates++
+		prev.LastTime = nowTS().UTC()
 		l.buffer.Prev().Value = prev
 	}
-	if !ok || prev.msg != msg {
-		l.buffer.Value = &logLine{
-			ts:  nowTS().UTC(),
-			msg: msg,
+	if !ok || prev.Message != msg {
+		l.buffer.Value = &LogEntry{
+			Time:      nowTS().UTC(),
+			Level:     levelForMessage(msg),
+			Component: component,
+			Message:   msg,
 		}
 		l.buffer = l.buffer.Next()
 	}
@@ -122,8 +230,8 @@ func (l *LogBuffer) Len() (count int) {
 		if s == nil {
 			return
 		}
-		ll, ok := s.(*logLine)
-		if !ok || ll.String() == "" {
+		e, ok := s.(*LogEntry)
+		if !ok || e.String() == "" {
 			return
 		}
 		count++
@@ -139,22 +247,52 @@ func (l *LogBuffer) Reset() {
 	l.mu.Unlock()
 }
 
-// String returns the contents of the buffer as a string.
-func (l *LogBuffer) String() string {
-	var str string
+// Entries returns a copy of the entries in the buffer, from oldest to
+// newest.
+func (l *LogBuffer) Entries() []LogEntry {
+	var entries []LogEntry
 	l.mu.RLock()
 	l.buffer.Do(func(s interface{}) {
-		if s == nil {
-			return
+		if e, ok := s.(*LogEntry); ok && e.Message != "" {
+			entries = append(entries, *e)
 		}
-		ll, ok := s.(*logLine)
-		if !ok {
-			return
+	})
+	l.mu.RUnlock()
+	return entries
+}
+
+// String returns the contents of the buffer as a string.
+func (l *LogBuffer) String() string {
+	return l.StringAtLevel(LogLevelInfo)
+}
+
+// StringAtLevel returns the entries in the buffer with at least the given
+// LogLevel as a string.
+func (l *LogBuffer) StringAtLevel(level LogLevel) string {
+	var str string
+	for _, e := range l.Entries() {
+		if !e.Level.AtLeast(level) {
+			continue
 		}
-		if msg := ll.String(); msg != "" {
+		if msg := e.String(); msg != "" {
 			str += msg + "\n"
 		}
-	})
-	l.mu.RUnlock()
+	}
 	return strings.TrimSpace(str)
 }
+
+// TailEntries returns the newest entries in the buffer with sensitive values
+// redacted, of which the messages do not exceed maxLen bytes in total. The
+// entries are ordered from oldest to newest.
+func (l *LogBuffer) TailEntries(maxLen int) []LogEntry {
+	entries := l.Entries()
+	var size, i int
+	for i = len(entries); i > 0; i-- {
+		e := &entries[i-1]
+		e.Message = Redact(e.Message)
+		if size += len(e.Message); size > maxLen {
+			break
+		}
+	}
+	return entries[i:]
+}
@@ -18,10 +18,13 @@ package action
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
 )
 
 func TestLogBuffer_Log(t *testing.T) {
@@ -134,6 +137,112 @@ func TestLogBuffer_String(t *testing.T) {
 	}
 }
 
+func TestLogBuffer_Entries(t *testing.T) {
+	nowTS = stubNowTS
+
+	l := NewLogBuffer(NewDebugLog(logr.Discard()), 5)
+	for _, v := range []string{"creating 1 resource(s)", "warning: Hook pre-install failed", "Job failed: BackoffLimitExceeded", "Job failed: BackoffLimitExceeded"} {
+		l.Log("%s", v)
+	}
+
+	want := []LogEntry{
+		{Time: stubNowTS(), Level: LogLevelInfo, Message: "creating 1 resource(s)"},
+		{Time: stubNowTS(), Level: LogLevelWarning, Message: "warning: Hook pre-install failed"},
+		{Time: stubNowTS(), LastTime: stubNowTS(), Level: LogLevelError, Message: "Job failed: BackoffLimitExceeded", Duplicates: 1},
+	}
+	if got := l.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() = %v, want %v", got, want)
+	}
+}
+
+func TestLogBuffer_EntriesComponent(t *testing.T) {
+	l := NewLogBuffer(NewDebugLog(logr.Discard()), 5)
+
+	store := helmstorage.Init(helmdriver.NewMemory())
+	store.Log = l.Log
+	_, _ = store.Get("podinfo", 1)
+	l.Log("%s", "not logged by Helm")
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries() returned %d entries, want 2", len(entries))
+	}
+	if got := entries[0].Component; got != "storage" {
+		t.Errorf("Component = %q, want %q", got, "storage")
+	}
+	if got := entries[1].Component; got != "" {
+		t.Errorf("Component = %q, want empty", got)
+	}
+}
+
+func TestLogBuffer_StringAtLevel(t *testing.T) {
+	nowTS = stubNowTS
+	ts := stubNowTS().Format(time.RFC3339Nano)
+
+	l := NewLogBuffer(NewDebugLog(logr.Discard()), 5)
+	for _, v := range []string{"a", "warning: b", "c failed"} {
+		l.Log("%s", v)
+	}
+
+	tests := []struct {
+		level LogLevel
+		want  string
+	}{
+		{level: LogLevelInfo, want: fmt.Sprintf("%[1]s: a\n%[1]s: warning: b\n%[1]s: c failed", ts)},
+		{level: LogLevelWarning, want: fmt.Sprintf("%[1]s: warning: b\n%[1]s: c failed", ts)},
+		{level: LogLevelError, want: fmt.Sprintf("%s: c failed", ts)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.level), func(t *testing.T) {
+			if got := l.StringAtLevel(tt.level); got != tt.want {
+				t.Errorf("StringAtLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogBuffer_TailEntries(t *testing.T) {
+	nowTS = stubNowTS
+
+	l := NewLogBuffer(NewDebugLog(logr.Discard()), 5)
+	for _, v := range []string{"aaaa", "bbbb", "token=cccc"} {
+		l.Log("%s", v)
+	}
+
+	want := []LogEntry{
+		{Time: stubNowTS(), Level: LogLevelInfo, Message: "bbbb"},
+		{Time: stubNowTS(), Level: LogLevelInfo, Message: "token=***"},
+	}
+	if got := l.TailEntries(13); !reflect.DeepEqual(got, want) {
+		t.Errorf("TailEntries() = %v, want %v", got, want)
+	}
+	if got := l.TailEntries(0); len(got) != 0 {
+		t.Errorf("TailEntries() = %v, want empty", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "no sensitive values", in: "creating 3 resource(s)", want: "creating 3 resource(s)"},
+		{name: "password", in: "error: password: hunter2 is invalid", want: "error: password: *** is invalid"},
+		{name: "quoted token", in: `{"token": "abc def", "user": "admin"}`, want: `{"token": ***, "user": "admin"}`},
+		{name: "key value pair", in: "apiKey=abc123,region=eu", want: "apiKey=***,region=eu"},
+		{name: "bearer token", in: "Authorization: Bearer eyJhbGciOi.xyz", want: "Authorization: Bearer ***"},
+		{name: "case insensitive", in: "DB_PASSWORD=secret", want: "DB_PASSWORD=***"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // stubNowTS returns a fixed time for testing purposes.
 func stubNowTS() time.Time {
 	return time.Date(2016, 2, 18, 12, 24, 5, 12345600, time.UTC)
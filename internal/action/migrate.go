@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/fluxcd/helm-controller/internal/apicompat"
+	"github.com/fluxcd/helm-controller/internal/features"
+)
+
+// migrateRemovedAPIs migrates the objects which use an API that was removed
+// from the cluster in the manifest of the deployed release with the given
+// name to the API replacing it, and updates the release in the Helm storage,
+// if the MigrateDeprecatedAPIs feature is enabled. This allows Helm to
+// upgrade a release made before the API was removed from the cluster.
+//
+// It is a no-op if no objects use a removed API, or there is no deployed
+// release.
+func migrateRemovedAPIs(config *helmaction.Configuration, releaseName string) error {
+	if enabled, _ := features.Enabled(features.MigrateDeprecatedAPIs); !enabled {
+		return nil
+	}
+
+	rls, err := config.Releases.Deployed(releaseName)
+	if err != nil {
+		if errors.Is(err, helmdriver.ErrNoDeployedReleases) || errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	clientSet, err := kubeClientSet(config)
+	if err != nil {
+		return err
+	}
+	manifest, migrated, err := apicompat.Migrate(clientSet.Discovery(), rls.Manifest)
+	if err != nil {
+		return err
+	}
+	if len(migrated) == 0 {
+		return nil
+	}
+
+	rls.Manifest = manifest
+	if err = config.Releases.Update(rls); err != nil {
+		return fmt.Errorf("failed to migrate removed APIs in release: %w", err)
+	}
+	for _, m := range migrated {
+		config.Log("migrated removed API of release %s (version %d): %s", rls.Name, rls.Version, m)
+	}
+	return nil
+}
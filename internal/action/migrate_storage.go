@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// MigrateStorage moves the records of the release with the given name from
+// the storage of the from ConfigFactory to the storage of the to
+// ConfigFactory. It returns the number of moved records.
+//
+// All records are copied before any of them is deleted from the previous
+// storage, and records which already exist in the new storage are kept. This
+// allows a migration which failed halfway to be resumed, without ever leaving
+// the release without records.
+func MigrateStorage(from, to *ConfigFactory, releaseName string) (int, error) {
+	src, dst := from.NewStorage(), to.NewStorage()
+
+	history, err := src.History(releaseName)
+	if err != nil {
+		if errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get release records: %w", err)
+	}
+
+	for _, rls := range history {
+		if err = dst.Create(rls); err != nil && !errors.Is(err, helmdriver.ErrReleaseExists) {
+			return 0, fmt.Errorf("failed to copy record of release %s (version %d): %w", rls.Name, rls.Version, err)
+		}
+	}
+	for _, rls := range history {
+		if _, err = src.Delete(rls.Name, rls.Version); err != nil && !errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return 0, fmt.Errorf("failed to delete previous record of release %s (version %d): %w", rls.Name, rls.Version, err)
+		}
+	}
+	return len(history), nil
+}
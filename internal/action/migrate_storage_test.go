@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/fluxcd/helm-controller/internal/kube"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestMigrateStorage(t *testing.T) {
+	newFactories := func(t *testing.T, src, dst helmdriver.Driver) (*ConfigFactory, *ConfigFactory) {
+		t.Helper()
+		from, err := NewConfigFactory(&kube.MemoryRESTClientGetter{}, WithDriver(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		to, err := NewConfigFactory(&kube.MemoryRESTClientGetter{}, WithDriver(dst))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return from, to
+	}
+	newRelease := func(name string, version int, status helmrelease.Status) *helmrelease.Release {
+		return testutil.BuildRelease(&helmrelease.MockReleaseOptions{Name: name, Namespace: "default", Version: version, Status: status})
+	}
+
+	t.Run("moves release records", func(t *testing.T) {
+		g := NewWithT(t)
+
+		src, dst := helmstorage.Init(helmdriver.NewMemory()), helmstorage.Init(helmdriver.NewMemory())
+		for _, rls := range []*helmrelease.Release{
+			newRelease("podinfo", 1, helmrelease.StatusSuperseded),
+			newRelease("podinfo", 2, helmrelease.StatusDeployed),
+			newRelease("other", 1, helmrelease.StatusDeployed),
+		} {
+			g.Expect(src.Create(rls)).To(Succeed())
+		}
+		// A record copied by a previous attempt is kept.
+		g.Expect(dst.Create(newRelease("podinfo", 1, helmrelease.StatusSuperseded))).To(Succeed())
+
+		from, to := newFactories(t, src.Driver, dst.Driver)
+		n, err := MigrateStorage(from, to, "podinfo")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(n).To(Equal(2))
+
+		_, err = src.History("podinfo")
+		g.Expect(err).To(MatchError(helmdriver.ErrReleaseNotFound))
+		_, err = src.Last("other")
+		g.Expect(err).ToNot(HaveOccurred())
+
+		history, err := dst.History("podinfo")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(history).To(HaveLen(2))
+		deployed, err := dst.Deployed("podinfo")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(deployed.Version).To(Equal(2))
+	})
+
+	t.Run("without release records", func(t *testing.T) {
+		g := NewWithT(t)
+
+		from, to := newFactories(t, helmdriver.NewMemory(), helmdriver.NewMemory())
+		n, err := MigrateStorage(from, to, "podinfo")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(n).To(BeZero())
+	})
+}
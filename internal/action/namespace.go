@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// ApplyNamespaceMetadata applies the v2.Install NamespaceMetadata of the
+// given object to the target namespace, if the namespace is created by the
+// Helm install action. When the namespace does not exist yet, it is created
+// with the metadata, so that e.g. the Pod Security admission level is in
+// effect before any of the release resources are created. When it does
+// exist, the labels and annotations of the metadata are (re)applied, while
+// other labels and annotations are left untouched.
+func ApplyNamespaceMetadata(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease) error {
+	install := obj.GetInstall()
+	if !install.CreateNamespace || obj.Spec.TargetNamespace == "" || install.NamespaceMetadata == nil {
+		return nil
+	}
+
+	clientSet, err := kubeClientSet(config)
+	if err != nil {
+		return err
+	}
+	return applyNamespaceMetadata(ctx, clientSet.CoreV1().Namespaces(), obj.GetReleaseNamespace(), *install.NamespaceMetadata)
+}
+
+// applyNamespaceMetadata creates the namespace with the given name and
+// metadata, or merges the metadata into the existing namespace.
+func applyNamespaceMetadata(ctx context.Context, namespaces typedcorev1.NamespaceInterface, name string, metadata v2.CommonMetadata) error {
+	ns, err := namespaces.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get namespace '%s': %w", name, err)
+		}
+		ns = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      maps.Clone(metadata.Labels),
+				Annotations: maps.Clone(metadata.Annotations),
+			},
+		}
+		if _, err := namespaces.Create(ctx, ns, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create namespace '%s': %w", name, err)
+		}
+		return nil
+	}
+
+	_, labelsChanged := mergeMetadata(ns.GetLabels(), metadata.Labels)
+	_, annotationsChanged := mergeMetadata(ns.GetAnnotations(), metadata.Annotations)
+	if !labelsChanged && !annotationsChanged {
+		return nil
+	}
+	patch, err := json.Marshal(metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      metadata.Labels,
+			Annotations: metadata.Annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := namespaces.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch metadata of namespace '%s': %w", name, err)
+	}
+	return nil
+}
+
+// mergeMetadata merges the desired entries into the current map. It returns
+// the merged map, and whether any entry was added or changed.
+func mergeMetadata(current, desired map[string]string) (map[string]string, bool) {
+	var changed bool
+	for k, v := range desired {
+		if cur, ok := current[k]; ok && cur == v {
+			continue
+		}
+		if current == nil {
+			current = make(map[string]string, len(desired))
+		}
+		current[k] = v
+		changed = true
+	}
+	return current, changed
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func Test_applyNamespaceMetadata(t *testing.T) {
+	metadata := v2.CommonMetadata{
+		Labels: map[string]string{
+			"pod-security.kubernetes.io/enforce": "restricted",
+		},
+		Annotations: map[string]string{
+			"owner": "team-a",
+		},
+	}
+
+	t.Run("creates namespace with metadata", func(t *testing.T) {
+		g := NewWithT(t)
+
+		namespaces := fake.NewSimpleClientset().CoreV1().Namespaces()
+		g.Expect(applyNamespaceMetadata(context.TODO(), namespaces, "target", metadata)).To(Succeed())
+
+		ns, err := namespaces.Get(context.TODO(), "target", metav1.GetOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(ns.GetLabels()).To(Equal(metadata.Labels))
+		g.Expect(ns.GetAnnotations()).To(Equal(metadata.Annotations))
+	})
+
+	t.Run("merges metadata into existing namespace", func(t *testing.T) {
+		g := NewWithT(t)
+
+		namespaces := fake.NewSimpleClientset(&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "target",
+				Labels: map[string]string{
+					"pod-security.kubernetes.io/enforce": "privileged",
+					"kubernetes.io/metadata.name":        "target",
+				},
+			},
+		}).CoreV1().Namespaces()
+		g.Expect(applyNamespaceMetadata(context.TODO(), namespaces, "target", metadata)).To(Succeed())
+
+		ns, err := namespaces.Get(context.TODO(), "target", metav1.GetOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(ns.GetLabels()).To(Equal(map[string]string{
+			"pod-security.kubernetes.io/enforce": "restricted",
+			"kubernetes.io/metadata.name":        "target",
+		}))
+		g.Expect(ns.GetAnnotations()).To(Equal(metadata.Annotations))
+	})
+}
+
+func Test_mergeMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	merged, changed := mergeMetadata(nil, map[string]string{"a": "b"})
+	g.Expect(changed).To(BeTrue())
+	g.Expect(merged).To(Equal(map[string]string{"a": "b"}))
+
+	merged, changed = mergeMetadata(map[string]string{"a": "b", "c": "d"}, map[string]string{"a": "b"})
+	g.Expect(changed).To(BeFalse())
+	g.Expect(merged).To(Equal(map[string]string{"a": "b", "c": "d"}))
+}
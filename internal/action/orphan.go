@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/tracing"
+)
+
+// Orphan removes all the Helm storage records of the release with the given
+// name using the provided config, without deleting the resources of the
+// release from the cluster. It is a no-op if the release does not exist.
+func Orphan(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, releaseName string) (err error) {
+	_, span, config := startSpan(ctx, "helm orphan", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	history, err := config.Releases.History(releaseName)
+	if err != nil {
+		if errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	for _, rls := range history {
+		if _, err = config.Releases.Delete(rls.Name, rls.Version); err != nil {
+			return fmt.Errorf("failed to delete release %s (version %d) from storage: %w", rls.Name, rls.Version, err)
+		}
+	}
+	return nil
+}
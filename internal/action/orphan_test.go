@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestOrphan(t *testing.T) {
+	g := NewWithT(t)
+
+	store := helmstorage.Init(helmdriver.NewMemory())
+	for _, rls := range []*helmrelease.Release{
+		testutil.BuildRelease(&helmrelease.MockReleaseOptions{Name: "orphan", Namespace: "default", Version: 1, Status: helmrelease.StatusSuperseded}),
+		testutil.BuildRelease(&helmrelease.MockReleaseOptions{Name: "orphan", Namespace: "default", Version: 2, Status: helmrelease.StatusDeployed}),
+		testutil.BuildRelease(&helmrelease.MockReleaseOptions{Name: "other", Namespace: "default", Version: 1, Status: helmrelease.StatusDeployed}),
+	} {
+		g.Expect(store.Create(rls)).To(Succeed())
+	}
+	config := &helmaction.Configuration{Releases: store}
+
+	g.Expect(Orphan(context.TODO(), config, &v2.HelmRelease{}, "orphan")).To(Succeed())
+
+	_, err := store.History("orphan")
+	g.Expect(err).To(MatchError(helmdriver.ErrReleaseNotFound))
+	_, err = store.Last("other")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Orphaning a release which does not exist is a no-op.
+	g.Expect(Orphan(context.TODO(), config, &v2.HelmRelease{}, "orphan")).To(Succeed())
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	helmpostrender "helm.sh/helm/v3/pkg/postrender"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/fluxcd/helm-controller/internal/features"
+	"github.com/fluxcd/helm-controller/internal/podsecurity"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+)
+
+// withPodSecurityCheck returns the given post-renderer combined with a
+// postrender.PodSecurity post-renderer for the Pod Security Standard enforced
+// on the given namespace, if the PodSecurityPreCheck feature is enabled.
+//
+// The check is best-effort: when the namespace can not be retrieved, for
+// example because it does not exist yet or the client is not allowed to get
+// it, the post-renderer is returned as is and the admission controller
+// remains the authority.
+func withPodSecurityCheck(ctx context.Context, config *helmaction.Configuration, namespace string,
+	renderer helmpostrender.PostRenderer) helmpostrender.PostRenderer {
+	if enabled, _ := features.Enabled(features.PodSecurityPreCheck); !enabled {
+		return renderer
+	}
+
+	clientSet, err := kubeClientSet(config)
+	if err != nil {
+		return renderer
+	}
+	ns, err := clientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return renderer
+	}
+
+	level := podsecurity.LevelForNamespace(ns.GetLabels())
+	if level == podsecurity.LevelPrivileged {
+		return renderer
+	}
+	if renderer == nil {
+		return postrender.NewPodSecurity(level, namespace)
+	}
+	return postrender.NewCombined(renderer, postrender.NewPodSecurity(level, namespace))
+}
+
+// kubeClientSet returns the Kubernetes clientset of the Helm Kubernetes
+// client of the given config.
+func kubeClientSet(config *helmaction.Configuration) (kubernetes.Interface, error) {
+	var client *helmkube.Client
+	switch c := config.KubeClient.(type) {
+	case *helmkube.Client:
+		client = c
+	case *tracingKubeClient:
+		client = c.Client
+	default:
+		return nil, fmt.Errorf("unsupported Kubernetes client %T", config.KubeClient)
+	}
+	return client.Factory.KubernetesClientSet()
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/cel-go/cel"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextension "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/tracing"
+)
+
+// PreflightError is returned by Preflight when the target cluster fails one
+// or more of the preflight checks.
+type PreflightError struct {
+	// Failures are the descriptions of the failed checks.
+	Failures []string
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("preflight checks failed: %s", strings.Join(e.Failures, "; "))
+}
+
+// Preflight performs the preflight checks configured in the upgrade of the
+// given object against the target cluster of the given config. It returns a
+// PreflightError if any of the checks fail, or another error if they could
+// not be performed.
+//
+// It does not modify the Helm storage nor the objects in the cluster.
+func Preflight(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease) (err error) {
+	preflight := obj.GetUpgrade().Preflight
+	if preflight == nil {
+		return nil
+	}
+
+	ctx, span, config := startSpan(ctx, "helm upgrade preflight", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	cfg, err := config.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	crdClient, err := apiextension.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	var (
+		dynamicClient dynamic.Interface
+		mapper        meta.RESTMapper
+	)
+	if len(preflight.Checks) > 0 {
+		if dynamicClient, err = dynamic.NewForConfig(cfg); err != nil {
+			return err
+		}
+		if mapper, err = config.RESTClientGetter.ToRESTMapper(); err != nil {
+			return err
+		}
+	}
+	return preflightChecks(ctx, preflight, obj.GetReleaseNamespace(), kubeClient, crdClient, dynamicClient, mapper)
+}
+
+// preflightChecks performs the given preflight checks using the given
+// clients. The objects of custom checks default to the given namespace.
+func preflightChecks(ctx context.Context, preflight *v2.UpgradePreflight, namespace string, kubeClient kubernetes.Interface,
+	crdClient apiextension.Interface, dynamicClient dynamic.Interface, mapper meta.RESTMapper) error {
+	var failures []string
+
+	if preflight.MinKubernetesVersion != "" {
+		minVersion, err := semver.NewVersion(preflight.MinKubernetesVersion)
+		if err != nil {
+			return fmt.Errorf("invalid minimum Kubernetes version '%s': %w", preflight.MinKubernetesVersion, err)
+		}
+		info, err := kubeClient.Discovery().ServerVersion()
+		if err != nil {
+			return fmt.Errorf("failed to get Kubernetes version: %w", err)
+		}
+		serverVersion, err := semver.NewVersion(info.GitVersion)
+		if err != nil {
+			return fmt.Errorf("failed to parse Kubernetes version '%s': %w", info.GitVersion, err)
+		}
+		// Ignore the pre-release of distributions (e.g. '-eks-1234'), which
+		// would otherwise make the version lower than its release.
+		release := semver.MustParse(fmt.Sprintf("%d.%d.%d",
+			serverVersion.Major(), serverVersion.Minor(), serverVersion.Patch()))
+		if release.LessThan(minVersion) {
+			failures = append(failures, fmt.Sprintf("Kubernetes version %s is lower than the minimum version %s",
+				info.GitVersion, preflight.MinKubernetesVersion))
+		}
+	}
+
+	for _, name := range preflight.RequiredCRDs {
+		crd, err := crdClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				failures = append(failures, fmt.Sprintf("CustomResourceDefinition '%s' not found", name))
+				continue
+			}
+			return fmt.Errorf("failed to get CustomResourceDefinition '%s': %w", name, err)
+		}
+		if !crdEstablished(crd) {
+			failures = append(failures, fmt.Sprintf("CustomResourceDefinition '%s' is not established", name))
+		}
+	}
+
+	for _, name := range preflight.RequiredStorageClasses {
+		if _, err := kubeClient.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				failures = append(failures, fmt.Sprintf("StorageClass '%s' not found", name))
+				continue
+			}
+			return fmt.Errorf("failed to get StorageClass '%s': %w", name, err)
+		}
+	}
+
+	if len(preflight.Checks) > 0 {
+		env, err := cel.NewEnv(cel.Variable("self", cel.DynType))
+		if err != nil {
+			return fmt.Errorf("failed to create CEL environment: %w", err)
+		}
+		for _, check := range preflight.Checks {
+			failure, err := customPreflightCheck(ctx, env, check, namespace, dynamicClient, mapper)
+			if err != nil {
+				return err
+			}
+			if failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &PreflightError{Failures: failures}
+	}
+	return nil
+}
+
+// customPreflightCheck evaluates the expression of the given check against
+// its object. It returns the description of the failure, or an empty string
+// if the check passed.
+func customPreflightCheck(ctx context.Context, env *cel.Env, check v2.PreflightCheck, namespace string,
+	dynamicClient dynamic.Interface, mapper meta.RESTMapper) (string, error) {
+	prg, err := compileBoolExpression(env, check.Expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid expression of preflight check '%s': %w", check.Name, err)
+	}
+
+	ref := check.Object
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return fmt.Sprintf("check '%s' failed: %s %s is not served", check.Name, ref.APIVersion, ref.Kind), nil
+		}
+		return "", fmt.Errorf("failed to get REST mapping of %s %s: %w", ref.APIVersion, ref.Kind, err)
+	}
+
+	key := ref.Name
+	var resource dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if ref.Namespace != "" {
+			namespace = ref.Namespace
+		}
+		resource = dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+		key = namespace + "/" + ref.Name
+	}
+	obj, err := resource.Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("check '%s' failed: %s '%s' not found", check.Name, ref.Kind, key), nil
+		}
+		return "", fmt.Errorf("failed to get %s '%s': %w", ref.Kind, key, err)
+	}
+
+	out, _, err := prg.Eval(map[string]interface{}{"self": obj.Object})
+	if err != nil {
+		return fmt.Sprintf("check '%s' failed: %s", check.Name, err.Error()), nil
+	}
+	if passed, ok := out.Value().(bool); !ok || !passed {
+		msg := check.Message
+		if msg == "" {
+			msg = check.Expression
+		}
+		return fmt.Sprintf("check '%s' failed: %s", check.Name, msg), nil
+	}
+	return "", nil
+}
+
+// crdEstablished returns if the given CustomResourceDefinition has been
+// established by the Kubernetes API server.
+func crdEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established {
+			return cond.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
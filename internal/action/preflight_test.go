@@ -0,0 +1,241 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func Test_preflightChecks(t *testing.T) {
+	established := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "certificates.cert-manager.io"},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{
+				{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue},
+			},
+		},
+	}
+	pending := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuers.cert-manager.io"},
+	}
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+	}
+
+	newClients := func(gitVersion string) (*kubefake.Clientset, *apiextensionfake.Clientset) {
+		kubeClient := kubefake.NewSimpleClientset(storageClass)
+		kubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: gitVersion}
+		return kubeClient, apiextensionfake.NewSimpleClientset(established, pending)
+	}
+
+	tests := []struct {
+		name       string
+		preflight  *v2.UpgradePreflight
+		gitVersion string
+		wantErr    string
+	}{
+		{
+			name: "all checks pass",
+			preflight: &v2.UpgradePreflight{
+				RequiredCRDs:           []string{"certificates.cert-manager.io"},
+				MinKubernetesVersion:   "1.28",
+				RequiredStorageClasses: []string{"fast"},
+			},
+			gitVersion: "v1.28.0-eks-1234",
+		},
+		{
+			name:       "Kubernetes version too low",
+			preflight:  &v2.UpgradePreflight{MinKubernetesVersion: "v1.29.1"},
+			gitVersion: "v1.29.0",
+			wantErr:    "Kubernetes version v1.29.0 is lower than the minimum version v1.29.1",
+		},
+		{
+			name:       "missing CRD",
+			preflight:  &v2.UpgradePreflight{RequiredCRDs: []string{"clusterissuers.cert-manager.io"}},
+			gitVersion: "v1.30.0",
+			wantErr:    "CustomResourceDefinition 'clusterissuers.cert-manager.io' not found",
+		},
+		{
+			name:       "CRD not established",
+			preflight:  &v2.UpgradePreflight{RequiredCRDs: []string{"issuers.cert-manager.io"}},
+			gitVersion: "v1.30.0",
+			wantErr:    "CustomResourceDefinition 'issuers.cert-manager.io' is not established",
+		},
+		{
+			name:       "missing StorageClass",
+			preflight:  &v2.UpgradePreflight{RequiredStorageClasses: []string{"fast", "slow"}},
+			gitVersion: "v1.30.0",
+			wantErr:    "StorageClass 'slow' not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kubeClient, crdClient := newClients(tt.gitVersion)
+			err := preflightChecks(context.TODO(), tt.preflight, "default", kubeClient, crdClient, nil, nil)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			var preflightErr *PreflightError
+			g.Expect(err).To(BeAssignableToTypeOf(preflightErr))
+			g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+		})
+	}
+
+	t.Run("reports all failures", func(t *testing.T) {
+		g := NewWithT(t)
+
+		kubeClient, crdClient := newClients("v1.27.3")
+		err := preflightChecks(context.TODO(), &v2.UpgradePreflight{
+			RequiredCRDs:           []string{"issuers.cert-manager.io"},
+			MinKubernetesVersion:   "1.28",
+			RequiredStorageClasses: []string{"slow"},
+		}, "default", kubeClient, crdClient, nil, nil)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.(*PreflightError).Failures).To(HaveLen(3))
+	})
+}
+
+func Test_preflightChecks_custom(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme,
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "database-status", Namespace: "databases"},
+			Data:       map[string]string{"ready": "true"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "feature-flags", Namespace: "apps"},
+			Data:       map[string]string{"migration": "disabled"},
+		},
+	)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+
+	configMap := func(name, namespace string) v2.PreflightObjectReference {
+		return v2.PreflightObjectReference{APIVersion: "v1", Kind: "ConfigMap", Name: name, Namespace: namespace}
+	}
+
+	tests := []struct {
+		name    string
+		check   v2.PreflightCheck
+		wantErr string
+		invalid bool
+	}{
+		{
+			name: "expression evaluates to true",
+			check: v2.PreflightCheck{
+				Name:       "database",
+				Object:     configMap("database-status", "databases"),
+				Expression: `self.data.ready == "true"`,
+			},
+		},
+		{
+			name: "object defaults to the release namespace",
+			check: v2.PreflightCheck{
+				Name:       "migration",
+				Object:     configMap("feature-flags", ""),
+				Expression: `self.data.migration == "enabled"`,
+				Message:    "migrations are disabled",
+			},
+			wantErr: "check 'migration' failed: migrations are disabled",
+		},
+		{
+			name: "message defaults to the expression",
+			check: v2.PreflightCheck{
+				Name:       "migration",
+				Object:     configMap("feature-flags", "apps"),
+				Expression: `self.data.migration == "enabled"`,
+			},
+			wantErr: `check 'migration' failed: self.data.migration == "enabled"`,
+		},
+		{
+			name: "missing field",
+			check: v2.PreflightCheck{
+				Name:       "database",
+				Object:     configMap("feature-flags", "apps"),
+				Expression: `self.data.ready == "true"`,
+			},
+			wantErr: "check 'database' failed: no such key: ready",
+		},
+		{
+			name: "missing object",
+			check: v2.PreflightCheck{
+				Name:       "database",
+				Object:     configMap("database-status", "apps"),
+				Expression: `self.data.ready == "true"`,
+			},
+			wantErr: "check 'database' failed: ConfigMap 'apps/database-status' not found",
+		},
+		{
+			name: "kind not served",
+			check: v2.PreflightCheck{
+				Name:       "issuer",
+				Object:     v2.PreflightObjectReference{APIVersion: "cert-manager.io/v1", Kind: "ClusterIssuer", Name: "letsencrypt"},
+				Expression: `true`,
+			},
+			wantErr: "check 'issuer' failed: cert-manager.io/v1 ClusterIssuer is not served",
+		},
+		{
+			name: "invalid expression",
+			check: v2.PreflightCheck{
+				Name:       "database",
+				Object:     configMap("database-status", "databases"),
+				Expression: `self.data.ready ==`,
+			},
+			wantErr: "invalid expression of preflight check 'database'",
+			invalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := preflightChecks(context.TODO(), &v2.UpgradePreflight{Checks: []v2.PreflightCheck{tt.check}}, "apps",
+				nil, nil, dynamicClient, mapper)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+			var preflightErr *PreflightError
+			g.Expect(errors.As(err, &preflightErr)).To(Equal(!tt.invalid))
+		})
+	}
+}
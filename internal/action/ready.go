@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+)
+
+// NotReady returns the objects of the given Helm release which are not ready,
+// according to the same checks Helm performs when waiting for a release. The
+// objects are identified as "<Kind>/<namespace>/<name>".
+func NotReady(ctx context.Context, config *helmaction.Configuration, rls *helmrelease.Release) ([]string, error) {
+	resources, err := config.KubeClient.Build(bytes.NewBufferString(rls.Manifest), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build objects of release: %w", err)
+	}
+
+	clientSet, err := config.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	checker := helmkube.NewReadyChecker(clientSet, config.Log, helmkube.PausedAsReady(true), helmkube.CheckJobs(true))
+
+	var notReady []string
+	for _, info := range resources {
+		ready, err := checker.IsReady(ctx, info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check readiness of %s/%s/%s: %w",
+				info.Mapping.GroupVersionKind.Kind, info.Namespace, info.Name, err)
+		}
+		if !ready {
+			notReady = append(notReady, fmt.Sprintf("%s/%s/%s", info.Mapping.GroupVersionKind.Kind, info.Namespace, info.Name))
+		}
+	}
+	return notReady, nil
+}
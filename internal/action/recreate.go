@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmpostrender "helm.sh/helm/v3/pkg/postrender"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+// withRecreateCheck returns the given post-renderer combined with a
+// postrender.Recreate post-renderer for the objects of the deployed release
+// of the given object, unless the upgrade of the object allows objects to be
+// recreated.
+//
+// The check is skipped when there is no deployed release, or its manifest
+// can not be read.
+func withRecreateCheck(config *helmaction.Configuration, obj *v2.HelmRelease,
+	renderer helmpostrender.PostRenderer) helmpostrender.PostRenderer {
+	if obj.GetUpgrade().AllowRecreate {
+		return renderer
+	}
+
+	cur, err := config.Releases.Deployed(release.ShortenName(obj.GetReleaseName()))
+	if err != nil {
+		return renderer
+	}
+	current, err := ssautil.ReadObjects(strings.NewReader(cur.Manifest))
+	if err != nil {
+		return renderer
+	}
+
+	check := postrender.NewRecreate(cur.Namespace, current)
+	if renderer == nil {
+		return check
+	}
+	return postrender.NewCombined(renderer, check)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	intdigest "github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/tracing"
+)
+
+// Render renders the manifest of the Helm release for the given object, chart
+// and values as a Helm install would, including the post-renderers and the
+// hooks of the chart. The data of any Secret in the returned manifest is
+// redacted, while the returned digest is calculated over the manifest before
+// redaction. This allows changes to the data of Secrets to be detected.
+//
+// It does not modify the Helm storage nor the objects in the cluster. Unlike
+// Install, the CRDs of the chart are not applied. The given options are
+// applied after the dry-run configuration, which allows e.g. rendering the
+// manifest without a cluster.
+func Render(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, chrt *helmchart.Chart,
+	vals helmchartutil.Values, opts ...InstallOption) (manifest, digest string, err error) {
+	ctx, span, config := startSpan(ctx, "helm render", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	install := newInstall(config, obj, append([]InstallOption{func(install *helmaction.Install) {
+		// Render the manifests as the actual install would, which includes
+		// interacting with the cluster for e.g. lookup functions.
+		install.DryRun = true
+		install.DryRunOption = "server"
+		// The name of a release which is already installed can be reused
+		// for a dry-run.
+		install.Replace = true
+	}}, opts...))
+	install.PostRenderer = withValuesChecksum(obj, vals, install.PostRenderer)
+	rls, err := install.RunWithContext(ctx, chrt, vals.AsMap())
+	if err != nil {
+		return "", "", err
+	}
+
+	rendered := renderedManifest(rls)
+	if manifest, err = redactSecrets(rendered); err != nil {
+		return "", "", err
+	}
+	return manifest, intdigest.Canonical.FromString(rendered).String(), nil
+}
+
+// SnapshotManifest returns the manifest of the release of the given
+// v2.Snapshot as stored in the Helm storage, followed by the manifests of its
+// hooks, with the data of Secrets redacted. The release is verified to match
+// the snapshot with VerifySnapshot, and an error of its types is returned if
+// it does not.
+//
+// It does not modify the Helm storage.
+func SnapshotManifest(config *helmaction.Configuration, snapshot *v2.Snapshot) (string, error) {
+	rls, err := VerifySnapshot(config, snapshot)
+	if err != nil {
+		return "", err
+	}
+	return redactSecrets(renderedManifest(rls))
+}
+
+// renderedManifest returns the manifest of the given release followed by the
+// manifests of its hooks.
+func renderedManifest(rls *helmrelease.Release) string {
+	var b strings.Builder
+	b.WriteString(rls.Manifest)
+	for _, h := range rls.Hooks {
+		b.WriteString("\n---\n# Source: ")
+		b.WriteString(h.Path)
+		b.WriteString("\n")
+		b.WriteString(h.Manifest)
+	}
+	return strings.TrimPrefix(b.String(), "\n")
+}
+
+// redactSecrets returns the given manifest with the values of the data and
+// stringData fields of any Secret replaced with redactedValue.
+func redactSecrets(manifest string) (string, error) {
+	manifests := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var b strings.Builder
+	for _, k := range keys {
+		content := manifests[k]
+
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(content), &obj); err != nil {
+			return "", fmt.Errorf("failed to read object from rendered manifest: %w", err)
+		}
+		if obj["apiVersion"] == "v1" && obj["kind"] == "Secret" {
+			for _, field := range []string{"data", "stringData"} {
+				data, ok := obj[field].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for key := range data {
+					data[key] = redactedValue
+				}
+			}
+
+			y, err := yaml.Marshal(obj)
+			if err != nil {
+				return "", fmt.Errorf("failed to encode object: %w", err)
+			}
+			// Retain the source template comment Helm prefixes the object with.
+			source, _, _ := strings.Cut(content, "\n")
+			if !strings.HasPrefix(source, "# Source: ") {
+				source = ""
+			}
+			content = strings.TrimPrefix(source+"\n"+strings.TrimSuffix(string(y), "\n"), "\n")
+		}
+
+		b.WriteString("---\n")
+		b.WriteString(content)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestSnapshotManifest(t *testing.T) {
+	g := NewWithT(t)
+
+	rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Version:   1,
+		Status:    helmrelease.StatusDeployed,
+		Namespace: "default",
+	})
+	rls.Manifest = `---
+# Source: chart/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  token: secret`
+	rls.Hooks = nil
+	snapshot := release.ObservedToSnapshot(release.ObserveRelease(rls))
+
+	s := helmstorage.Init(driver.NewMemory())
+	g.Expect(s.Create(rls)).To(Succeed())
+	config := &helmaction.Configuration{Releases: s}
+
+	got, err := SnapshotManifest(config, snapshot)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(Equal(`---
+# Source: chart/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  token: '***'
+`))
+
+	snapshot.Version = 2
+	_, err = SnapshotManifest(config, snapshot)
+	g.Expect(err).To(MatchError(ErrReleaseDisappeared))
+}
+
+func Test_renderedManifest(t *testing.T) {
+	g := NewWithT(t)
+
+	rls := &helmrelease.Release{
+		Manifest: `---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config`,
+		Hooks: []*helmrelease.Hook{
+			{
+				Path: "chart/templates/job.yaml",
+				Manifest: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: hook`,
+			},
+		},
+	}
+
+	g.Expect(renderedManifest(rls)).To(Equal(`---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+# Source: chart/templates/job.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: hook`))
+}
+
+func Test_redactSecrets(t *testing.T) {
+	g := NewWithT(t)
+
+	got, err := redactSecrets(`---
+# Source: chart/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+data:
+  password: c2VjcmV0
+stringData:
+  token: secret
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  password: visible
+`)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(Equal(`---
+# Source: chart/templates/secret.yaml
+apiVersion: v1
+data:
+  password: '***'
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  token: '***'
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  password: visible
+`))
+}
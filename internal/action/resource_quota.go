@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmpostrender "helm.sh/helm/v3/pkg/postrender"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/features"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+	"github.com/fluxcd/helm-controller/internal/quota"
+)
+
+// withResourceQuotaCheck returns the given post-renderer combined with a
+// postrender.ResourceQuota post-renderer for the ResourceQuotas of the given
+// namespace, if the ResourceQuotaPreCheck feature is enabled. The requests
+// of the deployed release with the given name are considered to be replaced
+// by the new release.
+//
+// The check is best-effort: when the ResourceQuotas can not be listed, for
+// example because the client is not allowed to list them, the post-renderer
+// is returned as is and the quota admission controller remains the
+// authority.
+func withResourceQuotaCheck(ctx context.Context, config *helmaction.Configuration, namespace, releaseName string,
+	renderer helmpostrender.PostRenderer) helmpostrender.PostRenderer {
+	if enabled, _ := features.Enabled(features.ResourceQuotaPreCheck); !enabled {
+		return renderer
+	}
+
+	clientSet, err := kubeClientSet(config)
+	if err != nil {
+		return renderer
+	}
+	quotas, err := clientSet.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil || len(quotas.Items) == 0 {
+		return renderer
+	}
+
+	check := postrender.NewResourceQuota(namespace, quotas.Items, deployedRequests(config, namespace, releaseName))
+	if renderer == nil {
+		return check
+	}
+	return postrender.NewCombined(renderer, check)
+}
+
+// deployedRequests returns the compute resources requested by the workloads
+// of the deployed release with the given name in the given namespace, or nil
+// if there is none.
+func deployedRequests(config *helmaction.Configuration, namespace, releaseName string) corev1.ResourceList {
+	if config.Releases == nil {
+		return nil
+	}
+	rls, err := config.Releases.Deployed(releaseName)
+	if err != nil {
+		return nil
+	}
+	objects, err := ssautil.ReadObjects(strings.NewReader(rls.Manifest))
+	if err != nil {
+		return nil
+	}
+	requests, err := quota.Requests(postrender.InNamespace(objects, namespace))
+	if err != nil {
+		return nil
+	}
+	return requests
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package action
 
 import (
+	"context"
+
 	helmaction "helm.sh/helm/v3/pkg/action"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/tracing"
 )
 
 // RollbackOption can be used to modify Helm's action.Rollback after the
@@ -50,7 +53,10 @@ func RollbackDryRun() RollbackOption {
 // expected to be done by the caller. In addition, it does not take note of the
 // action result. The caller is expected to listen to this using a
 // storage.ObserveFunc, which provides superior access to Helm storage writes.
-func Rollback(config *helmaction.Configuration, obj *v2.HelmRelease, releaseName string, opts ...RollbackOption) error {
+func Rollback(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, releaseName string, opts ...RollbackOption) (err error) {
+	_, span, config := startSpan(ctx, "helm rollback", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	rollback := newRollback(config, obj, opts)
 	return rollback.Run(releaseName)
 }
@@ -58,7 +64,7 @@ func Rollback(config *helmaction.Configuration, obj *v2.HelmRelease, releaseName
 func newRollback(config *helmaction.Configuration, obj *v2.HelmRelease, opts []RollbackOption) *helmaction.Rollback {
 	rollback := helmaction.NewRollback(config)
 
-	rollback.Timeout = obj.GetRollback().GetTimeout(obj.GetTimeout()).Duration
+	rollback.Timeout = obj.GetRollback().GetTimeout(Timeout(obj)).Duration
 	rollback.Wait = !obj.GetRollback().DisableWait
 	rollback.WaitForJobs = !obj.GetRollback().DisableWaitForJobs
 	rollback.DisableHooks = obj.GetRollback().DisableHooks
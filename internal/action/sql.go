@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// SQLConnectionString is the PostgreSQL connection string of the database
+// used by the helmdriver.SQLDriverName storage driver. The driver can not be
+// used when it is empty.
+var SQLConnectionString string
+
+var (
+	// sqlDrivers caches the SQL storage driver per namespace, as
+	// constructing a driver opens a new connection pool to the database and
+	// applies the migrations of its tables.
+	sqlDrivers   = make(map[string]*helmdriver.SQL)
+	sqlDriversMu sync.Mutex
+
+	// sqlPingDB is the connection pool used by PingSQLStorage, separate
+	// from the drivers to not require a release namespace.
+	sqlPingDB   *sql.DB
+	sqlPingDBMu sync.Mutex
+)
+
+// storageDriverNames maps the storage driver names accepted by the Helm CLI
+// in HELM_DRIVER to the names of the drivers.
+var storageDriverNames = map[string]string{
+	"secret":     helmdriver.SecretsDriverName,
+	"secrets":    helmdriver.SecretsDriverName,
+	"configmap":  helmdriver.ConfigMapsDriverName,
+	"configmaps": helmdriver.ConfigMapsDriverName,
+	"sql":        helmdriver.SQLDriverName,
+}
+
+// ParseStorageDriver returns the name of the Helm storage driver for the
+// given name, as accepted by the Helm CLI in HELM_DRIVER (e.g. "secret",
+// "configmap" or "sql"). It returns an error if the driver is not supported.
+func ParseStorageDriver(name string) (string, error) {
+	driver, ok := storageDriverNames[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unsupported Helm storage driver '%s'", name)
+	}
+	return driver, nil
+}
+
+// LoadSQLConnectionString reads the PostgreSQL connection string from the
+// file at the given path, e.g. a key of a mounted Secret.
+func LoadSQLConnectionString(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SQL connection string: %w", err)
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return "", fmt.Errorf("empty SQL connection string in '%s'", path)
+	}
+	return s, nil
+}
+
+// sqlDriver returns the SQL storage driver for the given namespace,
+// connecting to the database configured by SQLConnectionString on first use.
+func sqlDriver(namespace string) (*helmdriver.SQL, error) {
+	if SQLConnectionString == "" {
+		return nil, errors.New("no SQL connection string configured")
+	}
+
+	sqlDriversMu.Lock()
+	defer sqlDriversMu.Unlock()
+
+	if driver, ok := sqlDrivers[namespace]; ok {
+		return driver, nil
+	}
+	// The driver is shared between the actions of all releases in the
+	// namespace, which means the log of a single action can not be used.
+	driver, err := helmdriver.NewSQL(SQLConnectionString, func(string, ...interface{}) {}, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SQL database: %w", err)
+	}
+	sqlDrivers[namespace] = driver
+	return driver, nil
+}
+
+// PingSQLStorage verifies the database configured by SQLConnectionString can
+// be reached. It is intended for health checks of the
+// helmdriver.SQLDriverName storage driver.
+func PingSQLStorage(ctx context.Context) error {
+	if SQLConnectionString == "" {
+		return errors.New("no SQL connection string configured")
+	}
+
+	sqlPingDBMu.Lock()
+	if sqlPingDB == nil {
+		// The PostgreSQL driver is registered by the Helm SQL storage driver.
+		db, err := sql.Open("postgres", SQLConnectionString)
+		if err != nil {
+			sqlPingDBMu.Unlock()
+			return fmt.Errorf("failed to open SQL database: %w", err)
+		}
+		db.SetMaxOpenConns(1)
+		sqlPingDB = db
+	}
+	db := sqlPingDB
+	sqlPingDBMu.Unlock()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach SQL database: %w", err)
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	helmrelease "helm.sh/helm/v3/pkg/release"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/tracing"
 )
 
 // TestOption can be used to modify Helm's action.ReleaseTesting after the
@@ -38,7 +39,10 @@ type TestOption func(action *helmaction.ReleaseTesting)
 // expected to be done by the caller. In addition, it does not take note of the
 // action result. The caller is expected to listen to this using a
 // storage.ObserveFunc, which provides superior access to Helm storage writes.
-func Test(_ context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, opts ...TestOption) (*helmrelease.Release, error) {
+func Test(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, opts ...TestOption) (rls *helmrelease.Release, err error) {
+	_, span, config := startSpan(ctx, "helm test", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	test := newTest(config, obj, opts)
 	return test.Run(obj.GetReleaseName())
 }
@@ -47,7 +51,7 @@ func newTest(config *helmaction.Configuration, obj *v2.HelmRelease, opts []TestO
 	test := helmaction.NewReleaseTesting(config)
 
 	test.Namespace = obj.GetReleaseNamespace()
-	test.Timeout = obj.GetTest().GetTimeout(obj.GetTimeout()).Duration
+	test.Timeout = obj.GetTest().GetTimeout(Timeout(obj)).Duration
 
 	filters := make(map[string][]string)
 
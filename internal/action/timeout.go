@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// defaultTimeout is the timeout in nanoseconds of the Helm actions of a
+// HelmRelease which does not configure a timeout. When zero, the default of
+// v2.HelmRelease.GetTimeout is used.
+var defaultTimeout atomic.Int64
+
+// SetDefaultTimeout sets the timeout of the Helm actions of HelmReleases
+// which do not configure a timeout. A timeout of zero or less restores the
+// default of v2.HelmRelease.GetTimeout. It is safe to call concurrently with
+// running actions, which continue with the timeout they started with.
+func SetDefaultTimeout(timeout time.Duration) {
+	defaultTimeout.Store(int64(max(timeout, 0)))
+}
+
+// Timeout returns the timeout of the Helm actions of the given HelmRelease,
+// which is the configured .spec.timeout, or the default set with
+// SetDefaultTimeout.
+func Timeout(obj *v2.HelmRelease) metav1.Duration {
+	if obj.Spec.Timeout == nil {
+		if d := defaultTimeout.Load(); d > 0 {
+			return metav1.Duration{Duration: time.Duration(d)}
+		}
+	}
+	return obj.GetTimeout()
+}
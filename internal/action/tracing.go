@@ -0,0 +1,169 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/interrupt"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/storage"
+	"github.com/fluxcd/helm-controller/internal/tracing"
+)
+
+// startSpan starts a new span for the Helm action with the given name for
+// the object. It returns a copy of the config which records spans for the
+// writes to the Helm storage, the execution of hooks, and waits for
+// resources as children of the new span, and which waits for resources with
+// the wait concurrency of the object.
+func startSpan(ctx context.Context, name string, config *helmaction.Configuration, obj *v2.HelmRelease) (context.Context, trace.Span, *helmaction.Configuration) {
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithAttributes(
+		tracing.ObjectNameKey.String(obj.GetName()),
+		tracing.ObjectNamespaceKey.String(obj.GetNamespace()),
+		tracing.ReleaseNameKey.String(release.ShortenName(obj.GetReleaseName())),
+		tracing.ReleaseNamespaceKey.String(obj.GetReleaseNamespace()),
+	))
+	return ctx, span, traceConfig(ctx, config, obj.GetWaitConcurrency())
+}
+
+// traceConfig returns a shallow copy of the given config, with the Helm
+// storage driver and Kubernetes client wrapped to record spans as children
+// of the span in the given context. The Kubernetes client checks the
+// readiness of up to waitConcurrency resources concurrently while waiting.
+func traceConfig(ctx context.Context, config *helmaction.Configuration, waitConcurrency int) *helmaction.Configuration {
+	if config == nil {
+		return nil
+	}
+
+	traced := *config
+	if config.Releases != nil {
+		releases := *config.Releases
+		releases.Driver = storage.NewTracing(ctx, releases.Driver)
+		traced.Releases = &releases
+	}
+	// Only wrap the Helm client, as Helm performs type assertions on it to
+	// determine its capabilities.
+	if client, ok := config.KubeClient.(*helmkube.Client); ok {
+		traced.KubeClient = &tracingKubeClient{Client: client, ctx: ctx, waitConcurrency: waitConcurrency}
+	}
+	return &traced
+}
+
+// tracingKubeClient is a Helm Kubernetes client which records spans for the
+// execution of hooks, and waits for resources to become ready or deleted.
+// When waitConcurrency is above 1, it checks the readiness of the resources
+// concurrently instead of one by one. The readiness of resources of kinds
+// with a waitStrategy is checked according to the strategy.
+type tracingKubeClient struct {
+	*helmkube.Client
+
+	ctx             context.Context
+	waitConcurrency int
+	waitStrategies  waitStrategies
+}
+
+// WatchUntilReady records a span while watching the resources of a Helm hook
+// until they have completed.
+func (c *tracingKubeClient) WatchUntilReady(resources helmkube.ResourceList, timeout time.Duration) error {
+	return c.trace("helm hook", resources, func() error {
+		return c.Client.WatchUntilReady(resources, timeout)
+	})
+}
+
+// Wait records a span while waiting for the resources to become ready.
+func (c *tracingKubeClient) Wait(resources helmkube.ResourceList, timeout time.Duration) error {
+	return c.trace("helm wait", resources, func() error {
+		return c.wait(resources, timeout, false)
+	})
+}
+
+// WaitWithJobs records a span while waiting for the resources, including
+// Jobs, to become ready.
+func (c *tracingKubeClient) WaitWithJobs(resources helmkube.ResourceList, timeout time.Duration) error {
+	return c.trace("helm wait", resources, func() error {
+		return c.wait(resources, timeout, true)
+	})
+}
+
+// wait waits for the resources of which the readiness is awaited by Helm
+// to become ready, followed by the resources with a waitStrategy, within
+// the given timeout.
+//
+// When the context of the client carries a wait context of an interruptible
+// action, the resources are awaited by a waiter of our own which stops when
+// the action is interrupted. As Helm only waits after the resources have
+// been applied and the release has been recorded, this fails the release
+// without canceling the action itself.
+func (c *tracingKubeClient) wait(resources helmkube.ResourceList, timeout time.Duration, checkJobs bool) error {
+	start := time.Now()
+	resources, custom := c.waitStrategies.split(resources)
+
+	waitCtx, interruptible := interrupt.WaitContext(c.ctx)
+	if !interruptible {
+		waitCtx = context.Background()
+	}
+
+	var err error
+	switch {
+	case len(resources) == 0 && len(custom) > 0:
+	case c.waitConcurrency > 1 || interruptible:
+		err = waitForResources(waitCtx, c.Client, resources, timeout, c.waitConcurrency, checkJobs)
+	case checkJobs:
+		err = c.Client.WaitWithJobs(resources, timeout)
+	default:
+		err = c.Client.Wait(resources, timeout)
+	}
+
+	if err == nil && len(custom) > 0 {
+		c.Client.Log("beginning wait for %d resources with wait strategies", len(custom))
+		ctx, cancel := context.WithTimeout(waitCtx, timeout-time.Since(start))
+		defer cancel()
+		err = pollReady(ctx, c.waitStrategies.isReady, custom, c.waitConcurrency, waitInterval)
+	}
+
+	if err != nil && interrupt.Interrupted(waitCtx) {
+		return fmt.Errorf("wait for resources interrupted: %w", context.Cause(waitCtx))
+	}
+	return err
+}
+
+// WaitForDelete records a span while waiting for the resources to be
+// deleted.
+func (c *tracingKubeClient) WaitForDelete(resources helmkube.ResourceList, timeout time.Duration) error {
+	return c.trace("helm wait for delete", resources, func() error {
+		return c.Client.WaitForDelete(resources, timeout)
+	})
+}
+
+// trace records a span with the given name around the given function.
+func (c *tracingKubeClient) trace(name string, resources helmkube.ResourceList, fn func() error) error {
+	names := make([]string, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, resourceString(r))
+	}
+	_, span := tracing.Tracer().Start(c.ctx, name, trace.WithAttributes(tracing.ResourcesKey.StringSlice(names)))
+	err := fn()
+	tracing.EndSpan(span, err)
+	return err
+}
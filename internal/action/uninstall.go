@@ -18,11 +18,18 @@ package action
 
 import (
 	"context"
+	"sort"
+	"strings"
 
 	helmaction "helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/kube"
 	helmrelease "helm.sh/helm/v3/pkg/release"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+
+	"github.com/fluxcd/pkg/apis/kustomize"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/tracing"
 )
 
 // UninstallOption can be used to modify Helm's action.Uninstall after the
@@ -38,15 +45,68 @@ type UninstallOption func(cfg *helmaction.Uninstall)
 // expected to be done by the caller. In addition, it does not take note of the
 // action result. The caller is expected to listen to this using a
 // storage.ObserveFunc, which provides superior access to Helm storage writes.
-func Uninstall(_ context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, releaseName string, opts ...UninstallOption) (*helmrelease.UninstallReleaseResponse, error) {
+//
+// Before the uninstall, the resources selected by the KeepResources of the
+// uninstall configuration are annotated with the Helm resource policy to keep
+// them in the manifest of the release in storage.
+func Uninstall(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, releaseName string, opts ...UninstallOption) (res *helmrelease.UninstallReleaseResponse, err error) {
+	_, span, config := startSpan(ctx, "helm uninstall", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	// Mark the selected resources to be kept by Helm.
+	if err = keepResources(config, releaseName, obj.GetUninstall().KeepResources); err != nil {
+		return nil, err
+	}
+
 	uninstall := newUninstall(config, obj, opts)
 	return uninstall.Run(releaseName)
 }
 
+// UninstallPreview returns the sorted object.ObjMetadata strings of the
+// objects in the manifest of the latest release with the given name which
+// would be deleted by Uninstall. Objects with the Helm resource policy to keep
+// them, or which are selected by the KeepResources of the uninstall
+// configuration of the object, are excluded.
+func UninstallPreview(config *helmaction.Configuration, obj *v2.HelmRelease, releaseName string) ([]string, error) {
+	rls, err := config.Releases.Last(releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	mapper, err := config.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return uninstallPreview(mapper, rls, obj.GetUninstall().KeepResources)
+}
+
+func uninstallPreview(mapper apimeta.RESTMapper, rls *helmrelease.Release, selectors []kustomize.Selector) ([]string, error) {
+	regexes, err := keepSelectorRegexes(selectors)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := readReleaseObjects(mapper, rls.Manifest, rls.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(objects))
+	for _, o := range objects {
+		policy := strings.ToLower(strings.TrimSpace(o.obj.GetAnnotations()[kube.ResourcePolicyAnno]))
+		if policy == kube.KeepPolicy || matchesAny(mapper, o.obj, rls.Namespace, regexes) {
+			continue
+		}
+		ids = append(ids, o.id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
 func newUninstall(config *helmaction.Configuration, obj *v2.HelmRelease, opts []UninstallOption) *helmaction.Uninstall {
 	uninstall := helmaction.NewUninstall(config)
 
-	uninstall.Timeout = obj.GetUninstall().GetTimeout(obj.GetTimeout()).Duration
+	uninstall.Timeout = obj.GetUninstall().GetTimeout(Timeout(obj)).Duration
 	uninstall.DisableHooks = obj.GetUninstall().DisableHooks
 	uninstall.KeepHistory = obj.GetUninstall().KeepHistory
 	uninstall.Wait = !obj.GetUninstall().DisableWait
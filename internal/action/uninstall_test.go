@@ -22,7 +22,12 @@ import (
 
 	. "github.com/onsi/gomega"
 	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/pkg/apis/kustomize"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 )
@@ -49,6 +54,30 @@ func Test_newUninstall(t *testing.T) {
 		g.Expect(got).ToNot(BeNil())
 		g.Expect(got.Timeout).To(Equal(obj.Spec.Uninstall.Timeout.Duration))
 		g.Expect(got.KeepHistory).To(Equal(obj.Spec.Uninstall.KeepHistory))
+		g.Expect(got.DisableHooks).To(BeFalse())
+		g.Expect(got.Wait).To(BeTrue())
+	})
+
+	t.Run("disable hooks and wait", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "uninstall",
+				Namespace: "uninstall-ns",
+			},
+			Spec: v2.HelmReleaseSpec{
+				Uninstall: &v2.Uninstall{
+					DisableHooks: true,
+					DisableWait:  true,
+				},
+			},
+		}
+
+		got := newUninstall(&helmaction.Configuration{}, obj, nil)
+		g.Expect(got).ToNot(BeNil())
+		g.Expect(got.DisableHooks).To(BeTrue())
+		g.Expect(got.Wait).To(BeFalse())
 	})
 
 	t.Run("timeout fallback", func(t *testing.T) {
@@ -93,3 +122,56 @@ func Test_newUninstall(t *testing.T) {
 		g.Expect(got.DisableHooks).To(BeTrue())
 	})
 }
+
+func Test_uninstallPreview(t *testing.T) {
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}, apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, apimeta.RESTScopeRoot)
+
+	rls := &helmrelease.Release{
+		Namespace: "release",
+		Manifest: `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: other
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  annotations:
+    helm.sh/resource-policy: keep
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kept
+  namespace: other
+`,
+	}
+
+	t.Run("lists resources to delete", func(t *testing.T) {
+		g := NewWithT(t)
+
+		got, err := uninstallPreview(mapper, rls, []kustomize.Selector{{Kind: "ConfigMap", Namespace: "other"}})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(got).To(Equal([]string{
+			"_other__Namespace",
+			"release_config__ConfigMap",
+		}))
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := uninstallPreview(mapper, rls, []kustomize.Selector{{LabelSelector: "!!"}})
+		g.Expect(err).To(MatchError(ContainSubstring("invalid keep resources selector")))
+	})
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/fluxcd/helm-controller/internal/features"
 	"github.com/fluxcd/helm-controller/internal/postrender"
 	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/tracing"
 )
 
 // UpgradeOption can be used to modify Helm's action.Upgrade after the instructions
@@ -48,27 +49,79 @@ type UpgradeOption func(upgrade *helmaction.Upgrade)
 // action result. The caller is expected to listen to this using a
 // storage.ObserveFunc, which provides superior access to Helm storage writes.
 func Upgrade(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, chrt *helmchart.Chart,
-	vals helmchartutil.Values, opts ...UpgradeOption) (*helmrelease.Release, error) {
+	vals helmchartutil.Values, opts ...UpgradeOption) (rls *helmrelease.Release, err error) {
+	ctx, span, config := startSpan(ctx, "helm upgrade", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if err := withWaitStrategies(config, obj.GetUpgrade().WaitStrategies); err != nil {
+		return nil, err
+	}
+
 	upgrade := newUpgrade(config, obj, opts)
+	upgrade.PostRenderer = withValuesChecksum(obj, vals, upgrade.PostRenderer)
+	upgrade.PostRenderer = withPodSecurityCheck(ctx, config, upgrade.Namespace, upgrade.PostRenderer)
+	upgrade.PostRenderer = withResourceQuotaCheck(ctx, config, upgrade.Namespace, release.ShortenName(obj.GetReleaseName()), upgrade.PostRenderer)
+	upgrade.PostRenderer = withAPICompatibilityCheck(config, upgrade.PostRenderer)
+	upgrade.PostRenderer = withCRDSchemaCheck(ctx, config, upgrade.PostRenderer)
+	upgrade.PostRenderer = withRecreateCheck(config, obj, upgrade.PostRenderer)
+	upgrade.PostRenderer = withImageVerification(ctx, config, upgrade.Namespace, upgrade.PostRenderer)
 
 	policy, err := crdPolicyOrDefault(obj.GetUpgrade().CRDs)
 	if err != nil {
 		return nil, err
 	}
-	if err := applyCRDs(config, policy, chrt, setOriginVisitor(v2.GroupVersion.Group, obj.Namespace, obj.Name)); err != nil {
+	if err := applyCRDs(ctx, config, policy, chrt, setOriginVisitor(v2.GroupVersion.Group, obj.Namespace, obj.Name)); err != nil {
 		return nil, fmt.Errorf("failed to apply CustomResourceDefinitions: %w", err)
 	}
+	if err := migrateRemovedAPIs(config, release.ShortenName(obj.GetReleaseName())); err != nil {
+		return nil, err
+	}
 
 	return upgrade.RunWithContext(ctx, release.ShortenName(obj.GetReleaseName()), chrt, vals.AsMap())
 }
 
+// UpgradePreview returns a v2.DiffSummary of the changes between the objects
+// of the deployed release of the given object, and the objects rendered by a
+// server-side dry-run of Upgrade with the given chart and values.
+//
+// It does not modify the Helm storage nor the objects in the cluster.
+func UpgradePreview(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, chrt *helmchart.Chart,
+	vals helmchartutil.Values) (summary *v2.DiffSummary, err error) {
+	ctx, span, config := startSpan(ctx, "helm upgrade preview", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	releaseName := release.ShortenName(obj.GetReleaseName())
+	cur, err := config.Releases.Deployed(releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	upgrade := newUpgrade(config, obj, []UpgradeOption{func(upgrade *helmaction.Upgrade) {
+		// Render the manifests as the actual upgrade would, which includes
+		// interacting with the cluster for e.g. lookup functions.
+		upgrade.DryRun = true
+		upgrade.DryRunOption = "server"
+	}})
+	upgrade.PostRenderer = withValuesChecksum(obj, vals, upgrade.PostRenderer)
+	next, err := upgrade.RunWithContext(ctx, releaseName, chrt, vals.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
+	mapper, err := config.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return summarizeDiff(mapper, release.ObserveRelease(cur), release.ObserveRelease(next))
+}
+
 func newUpgrade(config *helmaction.Configuration, obj *v2.HelmRelease, opts []UpgradeOption) *helmaction.Upgrade {
 	upgrade := helmaction.NewUpgrade(config)
 	upgrade.Namespace = obj.GetReleaseNamespace()
 	upgrade.ResetValues = !obj.GetUpgrade().PreserveValues
 	upgrade.ReuseValues = obj.GetUpgrade().PreserveValues
 	upgrade.MaxHistory = obj.GetMaxHistory()
-	upgrade.Timeout = obj.GetUpgrade().GetTimeout(obj.GetTimeout()).Duration
+	upgrade.Timeout = obj.GetUpgrade().GetTimeout(Timeout(obj)).Duration
 	upgrade.Wait = !obj.GetUpgrade().DisableWait
 	upgrade.WaitForJobs = !obj.GetUpgrade().DisableWaitForJobs
 	upgrade.DisableHooks = obj.GetUpgrade().DisableHooks
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	apierrutil "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/tracing"
+)
+
+// ValidateUpgrade renders the manifests of the Helm upgrade for the given
+// object, chart and values, and validates them by performing a server-side
+// dry-run apply. The returned error contains the errors returned by the
+// Kubernetes API server for the objects which failed to validate, including
+// rejections by admission webhooks.
+//
+// It does not modify the Helm storage nor the objects in the cluster, and
+// can therefore be used to prevent an upgrade from being attempted when it
+// is known to fail.
+func ValidateUpgrade(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, chrt *helmchart.Chart,
+	vals helmchartutil.Values) (err error) {
+	ctx, span, config := startSpan(ctx, "helm upgrade validation", config, obj)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	upgrade := newUpgrade(config, obj, []UpgradeOption{func(upgrade *helmaction.Upgrade) {
+		// Render the manifests as the actual upgrade would, which includes
+		// interacting with the cluster for e.g. lookup functions.
+		upgrade.DryRun = true
+		upgrade.DryRunOption = "server"
+	}})
+	upgrade.PostRenderer = withValuesChecksum(obj, vals, upgrade.PostRenderer)
+	rls, err := upgrade.RunWithContext(ctx, release.ShortenName(obj.GetReleaseName()), chrt, vals.AsMap())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{DryRun: ptr.To(true)})
+	if err != nil {
+		return err
+	}
+	if err = dryRunApply(ctx, c, rls, managedFieldsManager()); err != nil {
+		return fmt.Errorf("server-side dry-run validation failed: %w", err)
+	}
+	return nil
+}
+
+// dryRunApply performs a server-side apply of the objects in the manifest of
+// the given Helm release using the provided client, which is expected to be
+// configured to only perform dry-run requests. Objects of which the kind is
+// not known to the cluster are skipped, as these may be introduced by CRDs
+// which are applied as part of the Helm action.
+func dryRunApply(ctx context.Context, c client.Client, rls *helmrelease.Release, fieldOwner string) error {
+	objects, err := ssautil.ReadObjects(strings.NewReader(rls.Manifest))
+	if err != nil {
+		return fmt.Errorf("failed to read objects from release manifest: %w", err)
+	}
+
+	var errs []error
+	for _, obj := range objects {
+		// Set the Helm metadata on the object which is normally set by Helm
+		// during object creation.
+		setHelmMetadata(obj, rls)
+
+		if obj.GetNamespace() == "" {
+			namespaced, err := apiutil.IsObjectNamespaced(obj, c.Scheme(), c.RESTMapper())
+			if err != nil {
+				if apimeta.IsNoMatchError(err) {
+					continue
+				}
+				errs = append(errs, fmt.Errorf("failed to determine if %s is namespace scoped: %w",
+					obj.GetObjectKind().GroupVersionKind().Kind, err))
+				continue
+			}
+			if namespaced {
+				obj.SetNamespace(rls.Namespace)
+			}
+		}
+
+		if err := c.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+			if apimeta.IsNoMatchError(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s dry-run failed: %w", ssautil.FmtUnstructured(obj), err))
+		}
+	}
+	return apierrutil.NewAggregate(errs)
+}
+
+// managedFieldsManager returns the name of the manager Helm uses for the
+// managed fields of the objects it creates and updates.
+func managedFieldsManager() string {
+	if kube.ManagedFieldsManager != "" {
+		return kube.ManagedFieldsManager
+	}
+	return filepath.Base(os.Args[0])
+}
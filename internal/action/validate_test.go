@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func Test_dryRunApply(t *testing.T) {
+	mapper := apimeta.NewDefaultRESTMapper([]schema.GroupVersion{{Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, apimeta.RESTScopeRoot)
+
+	rls := &helmrelease.Release{
+		Name:      "release",
+		Namespace: "release-ns",
+		Manifest: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: allowed
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: denied
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+`,
+	}
+
+	t.Run("returns errors of rejected objects", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var applied []client.Object
+		c := fake.NewClientBuilder().WithRESTMapper(mapper).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(_ context.Context, _ client.WithWatch, obj client.Object, patch client.Patch, _ ...client.PatchOption) error {
+				g.Expect(patch.Type()).To(Equal(client.Apply.Type()))
+				applied = append(applied, obj)
+				if obj.GetName() == "denied" {
+					return errors.New("admission webhook denied the request")
+				}
+				return nil
+			},
+		}).Build()
+
+		err := dryRunApply(context.TODO(), c, rls, "helm")
+		g.Expect(err).To(MatchError("ConfigMap/release-ns/denied dry-run failed: admission webhook denied the request"))
+
+		g.Expect(applied).To(HaveLen(3))
+		g.Expect(applied[0].GetNamespace()).To(Equal("release-ns"))
+		g.Expect(applied[0].GetAnnotations()).To(HaveKeyWithValue(helmReleaseNameAnnotation, "release"))
+		g.Expect(applied[0].GetLabels()).To(HaveKeyWithValue(appManagedByLabel, appManagedByHelm))
+		g.Expect(applied[2].GetNamespace()).To(BeEmpty())
+	})
+
+	t.Run("succeeds when all objects are accepted", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := fake.NewClientBuilder().WithRESTMapper(mapper).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(context.Context, client.WithWatch, client.Object, client.Patch, ...client.PatchOption) error {
+				return nil
+			},
+		}).Build()
+
+		g.Expect(dryRunApply(context.TODO(), c, rls, "helm")).To(Succeed())
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := fake.NewClientBuilder().WithRESTMapper(mapper).Build()
+		err := dryRunApply(context.TODO(), c, &helmrelease.Release{Manifest: "invalid: ["}, "helm")
+		g.Expect(err).To(MatchError(ContainSubstring("failed to read objects from release manifest")))
+	})
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	helmpostrender "helm.sh/helm/v3/pkg/postrender"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+)
+
+// withValuesChecksum returns the given post-renderer combined with a
+// postrender.ValuesChecksum post-renderer for every v2.ValuesChecksum
+// post-renderer of the given object. These are run after the other
+// post-renderers, so that changes made by e.g. Kustomize patches to the
+// selected ConfigMaps and Secrets are included in the checksum.
+func withValuesChecksum(obj *v2.HelmRelease, vals helmchartutil.Values,
+	renderer helmpostrender.PostRenderer) helmpostrender.PostRenderer {
+	renderers := make([]helmpostrender.PostRenderer, 0, len(obj.Spec.PostRenderers)+1)
+	if renderer != nil {
+		renderers = append(renderers, renderer)
+	}
+	for _, r := range obj.Spec.PostRenderers {
+		if r.ValuesChecksum != nil {
+			renderers = append(renderers, postrender.NewValuesChecksum(*r.ValuesChecksum, vals.AsMap()))
+		}
+	}
+	switch len(renderers) {
+	case 0:
+		return nil
+	case 1:
+		return renderers[0]
+	default:
+		return postrender.NewCombined(renderers...)
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/chartutil"
+	intdigest "github.com/fluxcd/helm-controller/internal/digest"
 	"github.com/fluxcd/helm-controller/internal/release"
 )
 
@@ -120,7 +121,7 @@ func VerifySnapshot(config *helmaction.Configuration, snapshot *v2.Snapshot) (rl
 // verification failure, or nil.
 func VerifyReleaseObject(snapshot *v2.Snapshot, rls *helmrelease.Release) error {
 	relDig, err := digest.Parse(snapshot.Digest)
-	if err != nil {
+	if err != nil || intdigest.Allowed(relDig.Algorithm()) != nil {
 		return ErrReleaseDigest
 	}
 	verifier := relDig.Verifier()
@@ -130,6 +131,7 @@ func VerifyReleaseObject(snapshot *v2.Snapshot, rls *helmrelease.Release) error
 	// unfortunately we have to pass in the OciDigest as is, because helmrelease.Release
 	// does not have a field for it.
 	obs.OCIDigest = snapshot.OCIDigest
+	obs.Provenance = snapshot.Provenance
 
 	if err = obs.Encode(verifier); err != nil {
 		// We are expected to be able to encode valid JSON, error out without a
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// waitInterval is the interval at which the readiness of the resources is
+// checked, equal to the interval of Helm.
+const waitInterval = 2 * time.Second
+
+// waitForResources waits up to the given timeout for the given resources to
+// become ready, checking the readiness of up to concurrency resources at the
+// same time. It considers the same resources ready as Helm does, including
+// Jobs when checkJobs is true. The wait is stopped early when ctx is done.
+func waitForResources(ctx context.Context, client *helmkube.Client, resources helmkube.ResourceList, timeout time.Duration,
+	concurrency int, checkJobs bool) error {
+	clientSet, err := client.Factory.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	checker := helmkube.NewReadyChecker(clientSet, client.Log, helmkube.PausedAsReady(true), helmkube.CheckJobs(checkJobs))
+
+	client.Log("beginning wait for %d resources with timeout of %v and concurrency of %d", len(resources), timeout, concurrency)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return pollReady(ctx, checker.IsReady, resources, concurrency, waitInterval)
+}
+
+// pollReady checks the readiness of the given resources with isReady at
+// the given interval, until all of them are ready in the same round, isReady
+// returns an error, or the context is done. Within a round, the readiness of
+// up to concurrency resources is checked at the same time.
+func pollReady(ctx context.Context, isReady func(context.Context, *resource.Info) (bool, error),
+	resources helmkube.ResourceList, concurrency int, interval time.Duration) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			allReady = true
+			firstErr error
+		)
+		// done returns true once the round can no longer succeed, to stop
+		// checking the remaining resources.
+		done := func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return !allReady || firstErr != nil
+		}
+
+		sem := make(chan struct{}, concurrency)
+		for _, r := range resources {
+			sem <- struct{}{}
+			if ctx.Err() != nil {
+				mu.Lock()
+				allReady = false
+				mu.Unlock()
+			}
+			if done() {
+				<-sem
+				break
+			}
+
+			wg.Add(1)
+			go func(r *resource.Info) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				ready, err := isReady(ctx, r)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if !ready {
+					allReady = false
+				}
+			}(r)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return false, firstErr
+		}
+		return allReady, nil
+	})
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	kstatus "github.com/fluxcd/cli-utils/pkg/kstatus/status"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// waitStrategies are the v2.WaitStrategy of a Helm action, by the group
+// version kind of the resources they apply to.
+type waitStrategies map[schema.GroupVersionKind]*waitStrategy
+
+// waitStrategy is a v2.WaitStrategy with its CEL expressions compiled.
+type waitStrategy struct {
+	strategy v2.WaitStrategyType
+	ready    cel.Program
+	failed   cel.Program
+}
+
+// withWaitStrategies configures the Kubernetes client of the given config
+// (as returned by startSpan) to wait for resources with the given strategies.
+// It returns an error if any of the strategies is invalid.
+func withWaitStrategies(config *helmaction.Configuration, strategies []v2.WaitStrategy) error {
+	if len(strategies) == 0 {
+		return nil
+	}
+	s, err := newWaitStrategies(strategies)
+	if err != nil {
+		return err
+	}
+	if client, ok := config.KubeClient.(*tracingKubeClient); ok {
+		client.waitStrategies = s
+	}
+	return nil
+}
+
+// newWaitStrategies returns the waitStrategies for the given strategies, or
+// an error if any of them is invalid.
+func newWaitStrategies(strategies []v2.WaitStrategy) (waitStrategies, error) {
+	var env *cel.Env
+	result := make(waitStrategies, len(strategies))
+	for _, s := range strategies {
+		if s.APIVersion == "" || s.Kind == "" {
+			return nil, fmt.Errorf("invalid wait strategy for '%s %s': apiVersion and kind are required", s.APIVersion, s.Kind)
+		}
+		gvk := schema.FromAPIVersionAndKind(s.APIVersion, s.Kind)
+		if _, ok := result[gvk]; ok {
+			return nil, fmt.Errorf("invalid wait strategy for '%s %s': duplicate strategy", s.APIVersion, s.Kind)
+		}
+
+		ws := &waitStrategy{strategy: s.Strategy}
+		switch s.Strategy {
+		case v2.HelmWaitStrategy, v2.KstatusWaitStrategy, v2.SkipWaitStrategy:
+		case v2.CELWaitStrategy:
+			if s.Ready == "" {
+				return nil, fmt.Errorf("invalid wait strategy for '%s %s': ready expression is required", s.APIVersion, s.Kind)
+			}
+			var err error
+			if env == nil {
+				if env, err = cel.NewEnv(cel.Variable("self", cel.DynType)); err != nil {
+					return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+				}
+			}
+			if ws.ready, err = compileBoolExpression(env, s.Ready); err != nil {
+				return nil, fmt.Errorf("invalid wait strategy for '%s %s': ready expression: %w", s.APIVersion, s.Kind, err)
+			}
+			if s.Failed != "" {
+				if ws.failed, err = compileBoolExpression(env, s.Failed); err != nil {
+					return nil, fmt.Errorf("invalid wait strategy for '%s %s': failed expression: %w", s.APIVersion, s.Kind, err)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid wait strategy for '%s %s': unknown strategy '%s'", s.APIVersion, s.Kind, s.Strategy)
+		}
+		result[gvk] = ws
+	}
+	return result, nil
+}
+
+// compileBoolExpression compiles the given CEL expression, which must
+// evaluate to a bool.
+func compileBoolExpression(env *cel.Env, expr string) (cel.Program, error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if t := ast.OutputType(); t != cel.BoolType && t != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to a bool, got %s", t)
+	}
+	return env.Program(ast)
+}
+
+// split returns the resources of which the readiness is awaited by Helm,
+// and the resources of which the readiness is awaited with a strategy.
+// Resources with the Skip strategy are left out.
+func (s waitStrategies) split(resources helmkube.ResourceList) (helm, custom helmkube.ResourceList) {
+	if len(s) == 0 {
+		return resources, nil
+	}
+	for _, r := range resources {
+		ws := s.get(r)
+		switch {
+		case ws == nil, ws.strategy == v2.HelmWaitStrategy:
+			helm = append(helm, r)
+		case ws.strategy == v2.SkipWaitStrategy:
+		default:
+			custom = append(custom, r)
+		}
+	}
+	return helm, custom
+}
+
+// get returns the waitStrategy of the given resource, or nil.
+func (s waitStrategies) get(r *resource.Info) *waitStrategy {
+	if r.Mapping != nil {
+		return s[r.Mapping.GroupVersionKind]
+	}
+	if r.Object != nil {
+		return s[r.Object.GetObjectKind().GroupVersionKind()]
+	}
+	return nil
+}
+
+// isReady returns if the current state of the given resource in the cluster
+// is ready according to its waitStrategy. It returns an error if the
+// resource has failed.
+func (s waitStrategies) isReady(_ context.Context, r *resource.Info) (bool, error) {
+	ws := s.get(r)
+	if ws == nil {
+		return true, nil
+	}
+
+	obj, err := resource.NewHelper(r.Client, r.Mapping).Get(r.Namespace, r.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return false, err
+	}
+
+	ready, err := ws.isReady(&unstructured.Unstructured{Object: content})
+	if err != nil {
+		return false, fmt.Errorf("%s %w", resourceString(r), err)
+	}
+	return ready, nil
+}
+
+// isReady returns if the given object is ready according to the strategy.
+// It returns an error if the object has failed.
+func (s *waitStrategy) isReady(obj *unstructured.Unstructured) (bool, error) {
+	switch s.strategy {
+	case v2.KstatusWaitStrategy:
+		res, err := kstatus.Compute(obj)
+		if err != nil {
+			return false, err
+		}
+		switch res.Status {
+		case kstatus.CurrentStatus:
+			return true, nil
+		case kstatus.FailedStatus:
+			return false, fmt.Errorf("has failed: %s", res.Message)
+		}
+		return false, nil
+	case v2.CELWaitStrategy:
+		vars := map[string]interface{}{"self": obj.Object}
+		if s.failed != nil && evalWaitExpression(s.failed, vars) {
+			return false, fmt.Errorf("has failed")
+		}
+		return evalWaitExpression(s.ready, vars), nil
+	}
+	return true, nil
+}
+
+// evalWaitExpression returns the result of the given program. As fields
+// referenced by the expression may not be set (yet), an evaluation error is
+// considered false.
+func evalWaitExpression(prg cel.Program, vars map[string]interface{}) bool {
+	out, _, err := prg.Eval(vars)
+	if err != nil {
+		return false
+	}
+	b, ok := out.Value().(bool)
+	return ok && b
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func Test_newWaitStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategies []v2.WaitStrategy
+		wantErr    string
+	}{
+		{
+			name: "valid strategies",
+			strategies: []v2.WaitStrategy{
+				{APIVersion: "apps/v1", Kind: "Deployment", Strategy: v2.HelmWaitStrategy},
+				{APIVersion: "example.com/v1", Kind: "Widget", Strategy: v2.KstatusWaitStrategy},
+				{APIVersion: "example.com/v1", Kind: "Gadget", Strategy: v2.CELWaitStrategy,
+					Ready: `self.status.phase == "Running"`, Failed: `self.status.phase == "Failed"`},
+				{APIVersion: "batch/v1", Kind: "Job", Strategy: v2.SkipWaitStrategy},
+			},
+		},
+		{
+			name:       "missing kind",
+			strategies: []v2.WaitStrategy{{APIVersion: "apps/v1", Strategy: v2.HelmWaitStrategy}},
+			wantErr:    "apiVersion and kind are required",
+		},
+		{
+			name: "duplicate strategy",
+			strategies: []v2.WaitStrategy{
+				{APIVersion: "apps/v1", Kind: "Deployment", Strategy: v2.HelmWaitStrategy},
+				{APIVersion: "apps/v1", Kind: "Deployment", Strategy: v2.SkipWaitStrategy},
+			},
+			wantErr: "duplicate strategy",
+		},
+		{
+			name:       "unknown strategy",
+			strategies: []v2.WaitStrategy{{APIVersion: "apps/v1", Kind: "Deployment", Strategy: "Eventually"}},
+			wantErr:    "unknown strategy 'Eventually'",
+		},
+		{
+			name:       "CEL strategy without ready expression",
+			strategies: []v2.WaitStrategy{{APIVersion: "example.com/v1", Kind: "Gadget", Strategy: v2.CELWaitStrategy}},
+			wantErr:    "ready expression is required",
+		},
+		{
+			name: "invalid CEL expression",
+			strategies: []v2.WaitStrategy{{APIVersion: "example.com/v1", Kind: "Gadget", Strategy: v2.CELWaitStrategy,
+				Ready: `self.status.phase ==`}},
+			wantErr: "ready expression",
+		},
+		{
+			name: "CEL expression not evaluating to a bool",
+			strategies: []v2.WaitStrategy{{APIVersion: "example.com/v1", Kind: "Gadget", Strategy: v2.CELWaitStrategy,
+				Ready: `"Running"`}},
+			wantErr: "expression must evaluate to a bool",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := newWaitStrategies(tt.strategies)
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(HaveLen(len(tt.strategies)))
+		})
+	}
+}
+
+func Test_waitStrategies_split(t *testing.T) {
+	g := NewWithT(t)
+
+	s, err := newWaitStrategies([]v2.WaitStrategy{
+		{APIVersion: "apps/v1", Kind: "Deployment", Strategy: v2.HelmWaitStrategy},
+		{APIVersion: "example.com/v1", Kind: "Widget", Strategy: v2.KstatusWaitStrategy},
+		{APIVersion: "batch/v1", Kind: "Job", Strategy: v2.SkipWaitStrategy},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	info := func(gvk schema.GroupVersionKind) *resource.Info {
+		return &resource.Info{Name: gvk.Kind, Mapping: &meta.RESTMapping{GroupVersionKind: gvk}}
+	}
+	deployment := info(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	service := info(schema.GroupVersionKind{Version: "v1", Kind: "Service"})
+	widget := info(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"})
+	oldWidget := info(schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"})
+	job := info(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"})
+
+	helm, custom := s.split(helmkube.ResourceList{deployment, service, widget, oldWidget, job})
+	g.Expect(helm).To(Equal(helmkube.ResourceList{deployment, service, oldWidget}))
+	g.Expect(custom).To(Equal(helmkube.ResourceList{widget}))
+
+	helm, custom = waitStrategies(nil).split(helmkube.ResourceList{deployment, job})
+	g.Expect(helm).To(Equal(helmkube.ResourceList{deployment, job}))
+	g.Expect(custom).To(BeEmpty())
+}
+
+func Test_waitStrategy_isReady(t *testing.T) {
+	s, err := newWaitStrategies([]v2.WaitStrategy{
+		{APIVersion: "example.com/v1", Kind: "Widget", Strategy: v2.KstatusWaitStrategy},
+		{APIVersion: "example.com/v1", Kind: "Gadget", Strategy: v2.CELWaitStrategy,
+			Ready: `self.status.phase == "Running"`, Failed: `self.status.phase == "Failed"`},
+	})
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+	widget := s[schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}]
+	gadget := s[schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"}]
+
+	object := func(kind string, status map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": "test", "generation": int64(1)},
+		}}
+		if status != nil {
+			obj.Object["status"] = status
+		}
+		return obj
+	}
+	condition := func(typ, status string) map[string]interface{} {
+		return map[string]interface{}{
+			"observedGeneration": int64(1),
+			"conditions": []interface{}{
+				map[string]interface{}{"type": typ, "status": status, "message": "test"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		strategy  *waitStrategy
+		obj       *unstructured.Unstructured
+		wantReady bool
+		wantErr   string
+	}{
+		{
+			name:      "kstatus ready",
+			strategy:  widget,
+			obj:       object("Widget", condition("Ready", "True")),
+			wantReady: true,
+		},
+		{
+			name:     "kstatus in progress",
+			strategy: widget,
+			obj:      object("Widget", condition("Reconciling", "True")),
+		},
+		{
+			name:     "kstatus failed",
+			strategy: widget,
+			obj:      object("Widget", condition("Stalled", "True")),
+			wantErr:  "has failed",
+		},
+		{
+			name:      "CEL ready",
+			strategy:  gadget,
+			obj:       object("Gadget", map[string]interface{}{"phase": "Running"}),
+			wantReady: true,
+		},
+		{
+			name:     "CEL not ready",
+			strategy: gadget,
+			obj:      object("Gadget", map[string]interface{}{"phase": "Pending"}),
+		},
+		{
+			name:     "CEL without status",
+			strategy: gadget,
+			obj:      object("Gadget", nil),
+		},
+		{
+			name:     "CEL failed",
+			strategy: gadget,
+			obj:      object("Gadget", map[string]interface{}{"phase": "Failed"}),
+			wantErr:  "has failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ready, err := tt.strategy.isReady(tt.obj)
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(ready).To(Equal(tt.wantReady))
+		})
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/scheme"
+	restfake "k8s.io/client-go/rest/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/interrupt"
+)
+
+func Test_pollReady(t *testing.T) {
+	resources := make(helmkube.ResourceList, 10)
+	for i := range resources {
+		resources[i] = &resource.Info{Name: fmt.Sprintf("resource-%d", i)}
+	}
+
+	t.Run("checks resources concurrently", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var (
+			mu           sync.Mutex
+			active, peak int
+			checked      = map[string]int{}
+		)
+		isReady := func(_ context.Context, r *resource.Info) (bool, error) {
+			mu.Lock()
+			active++
+			if active > peak {
+				peak = active
+			}
+			checked[r.Name]++
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+			return true, nil
+		}
+
+		err := pollReady(context.Background(), isReady, resources, 4, time.Millisecond)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(peak).To(BeNumerically(">", 1))
+		g.Expect(peak).To(BeNumerically("<=", 4))
+		g.Expect(checked).To(HaveLen(len(resources)))
+	})
+
+	t.Run("waits until all resources are ready", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var (
+			mu     sync.Mutex
+			rounds = map[string]int{}
+		)
+		isReady := func(_ context.Context, r *resource.Info) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			rounds[r.Name]++
+			return r.Name != "resource-5" || rounds[r.Name] >= 3, nil
+		}
+
+		err := pollReady(context.Background(), isReady, resources, 3, time.Millisecond)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(rounds["resource-5"]).To(Equal(3))
+	})
+
+	t.Run("returns readiness error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		isReady := func(_ context.Context, r *resource.Info) (bool, error) {
+			if r.Name == "resource-2" {
+				return false, errors.New("boom")
+			}
+			return true, nil
+		}
+
+		err := pollReady(context.Background(), isReady, resources, 5, time.Millisecond)
+		g.Expect(err).To(MatchError("boom"))
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		isReady := func(_ context.Context, r *resource.Info) (bool, error) {
+			return r.Name != "resource-9", nil
+		}
+
+		err := pollReady(ctx, isReady, resources, 2, time.Millisecond)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(ctx.Err()).To(HaveOccurred())
+	})
+}
+
+func Test_tracingKubeClient_wait(t *testing.T) {
+	strategies, err := newWaitStrategies([]v2.WaitStrategy{
+		{APIVersion: "example.com/v1", Kind: "Widget", Strategy: v2.KstatusWaitStrategy},
+	})
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+
+	// The Widget is never found, and thus never becomes ready.
+	widget := &resource.Info{
+		Name:      "test",
+		Namespace: "default",
+		Mapping: &meta.RESTMapping{
+			GroupVersionKind: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"},
+			Resource:         schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"},
+			Scope:            meta.RESTScopeNamespace,
+		},
+		Client: &restfake.RESTClient{
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+			Client: restfake.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Header:     http.Header{"Content-Type": []string{"text/plain"}},
+					Body:       io.NopCloser(strings.NewReader("not found")),
+				}, nil
+			}),
+		},
+	}
+
+	t.Run("times out", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := &tracingKubeClient{Client: helmkube.New(nil), ctx: context.TODO(), waitStrategies: strategies}
+		err := c.wait(helmkube.ResourceList{widget}, 50*time.Millisecond, false)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(errors.Is(err, interrupt.ErrSuperseded)).To(BeFalse())
+	})
+
+	t.Run("stops when interrupted", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := interrupt.NewTracker()
+		waitCtx, done := tracker.Track(context.TODO(), "default/test", 1)
+		defer done()
+
+		ctx := interrupt.WithWaitContext(context.TODO(), waitCtx)
+		c := &tracingKubeClient{Client: helmkube.New(nil), ctx: ctx, waitStrategies: strategies}
+
+		time.AfterFunc(50*time.Millisecond, func() { tracker.Interrupt("default/test", 2) })
+		start := time.Now()
+		err := c.wait(helmkube.ResourceList{widget}, time.Minute, false)
+		g.Expect(err).To(MatchError("wait for resources interrupted: superseded by a newer generation"))
+		g.Expect(errors.Is(err, interrupt.ErrSuperseded)).To(BeTrue())
+		g.Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+		g.Expect(ctx.Err()).ToNot(HaveOccurred())
+	})
+}
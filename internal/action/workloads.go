@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/digest"
+)
+
+// workloadGroupKinds are the kinds of the objects which are reported as
+// workloads by RenderWorkloads.
+var workloadGroupKinds = []schema.GroupKind{
+	{Group: appsv1.GroupName, Kind: "Deployment"},
+	{Group: appsv1.GroupName, Kind: "StatefulSet"},
+	{Group: appsv1.GroupName, Kind: "DaemonSet"},
+}
+
+// RenderWorkloads renders the manifest of the Helm release for the given
+// object, chart and values with Render, and returns the references to the
+// Deployments, StatefulSets and DaemonSets in it. The digest of a reference
+// is calculated as for the Inventory, which allows the workloads changed by
+// an upgrade to be determined.
+func RenderWorkloads(ctx context.Context, config *helmaction.Configuration, obj *v2.HelmRelease, chrt *helmchart.Chart,
+	vals helmchartutil.Values) ([]v2.ResourceRef, error) {
+	manifest, _, err := Render(ctx, config, obj, chrt, vals)
+	if err != nil {
+		return nil, err
+	}
+	return workloads(manifest, obj.GetReleaseNamespace())
+}
+
+// workloads returns the references to the workloads in the given manifest.
+// Workloads without a namespace are assigned the given namespace.
+func workloads(manifest, namespace string) ([]v2.ResourceRef, error) {
+	objects, err := ssautil.ReadObjects(strings.NewReader(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read objects from rendered manifest: %w", err)
+	}
+
+	var refs []v2.ResourceRef
+	for _, obj := range objects {
+		if !slices.Contains(workloadGroupKinds, obj.GroupVersionKind().GroupKind()) {
+			continue
+		}
+		// Compute the digest before mutating the object.
+		b, err := json.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		refs = append(refs, v2.ResourceRef{
+			ID:      object.UnstructuredToObjMetadata(obj).String(),
+			Version: obj.GroupVersionKind().Version,
+			Digest:  digest.Canonical.FromBytes(b).String(),
+		})
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].ID < refs[j].ID
+	})
+	return refs, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_workloads(t *testing.T) {
+	g := NewWithT(t)
+
+	manifest := `---
+# Source: podinfo/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: podinfo
+---
+# Source: podinfo/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+---
+# Source: podinfo/templates/redis.yaml
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: redis
+  namespace: cache
+`
+
+	got, err := workloads(manifest, "apps")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(HaveLen(2))
+	g.Expect(got[0].ID).To(Equal("apps_podinfo_apps_Deployment"))
+	g.Expect(got[0].Version).To(Equal("v1"))
+	g.Expect(got[0].Digest).To(HavePrefix("sha256:"))
+	g.Expect(got[1].ID).To(Equal("cache_redis_apps_StatefulSet"))
+
+	got, err = workloads("", "apps")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(BeEmpty())
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package actionhook calls the release action webhooks of a HelmRelease
+// before and after the Helm actions performed for it, so that external
+// change-management systems can record or gate deployments.
+package actionhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/audit"
+	"github.com/fluxcd/helm-controller/internal/outbound"
+)
+
+// SignatureHeader is the header the HMAC-SHA256 signature of the payload is
+// sent in, formatted as "sha256=<hex>".
+const SignatureHeader = "X-Signature"
+
+// Payload is the JSON payload sent to a webhook.
+type Payload struct {
+	// Phase is the phase of the Helm action the webhook is called in.
+	Phase v2.WebhookPhase `json:"phase"`
+	// Action is the name of the Helm action, e.g. "install" or "upgrade".
+	Action string `json:"action"`
+	// Time is when the webhook is called.
+	Time time.Time `json:"time"`
+	// ReconcileID is the ID of the reconciliation the Helm action is
+	// performed in.
+	ReconcileID string `json:"reconcileID,omitempty"`
+	// Result is the result of the Helm action in the post phase, either
+	// audit.ResultSuccess or audit.ResultFailure.
+	Result string `json:"result,omitempty"`
+	// Error is the error message of a failed Helm action.
+	Error string `json:"error,omitempty"`
+	// Object is the HelmRelease the Helm action is performed for.
+	Object audit.Object `json:"object"`
+	// Release is the Helm release the Helm action is performed on.
+	Release audit.Release `json:"release"`
+}
+
+// Webhooks are the v2.ActionWebhook of a HelmRelease. The Secret of a
+// webhook is only read when it is called, so that an invalid Secret only
+// affects the Helm actions the webhook is configured for.
+type Webhooks struct {
+	reader     client.Reader
+	httpClient *outbound.Client
+	namespace  string
+	webhooks   []v2.ActionWebhook
+}
+
+// New returns the Webhooks of the given object, which get their Secrets
+// using the given reader and are called using the given outbound.Client. It
+// returns nil if the object has no webhooks, or the client is nil.
+func New(reader client.Reader, httpClient *outbound.Client, obj *v2.HelmRelease) *Webhooks {
+	if httpClient == nil || obj.Spec.Hooks == nil || len(obj.Spec.Hooks.Webhooks) == 0 {
+		return nil
+	}
+	return &Webhooks{
+		reader:     reader,
+		httpClient: httpClient,
+		namespace:  obj.GetNamespace(),
+		webhooks:   obj.Spec.Hooks.Webhooks,
+	}
+}
+
+// Validate returns an error if the Secret of any of the webhooks can not be
+// read, or does not contain a valid address.
+func (w *Webhooks) Validate(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+	var errs []error
+	for _, hook := range w.webhooks {
+		if _, _, err := w.resolve(ctx, hook); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Call sends the payload to the webhooks matching its action and phase.
+// Blocking webhooks are called in order, and the errors of the ones which
+// fail are returned. Other webhooks are called in the background, and their
+// errors are logged.
+func (w *Webhooks) Call(ctx context.Context, payload Payload) error {
+	if w == nil {
+		return nil
+	}
+
+	var body []byte
+	var errs []error
+	for _, hook := range w.webhooks {
+		if !hook.Matches(payload.Action, payload.Phase) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(payload); err != nil {
+				return fmt.Errorf("failed to encode webhook payload: %w", err)
+			}
+		}
+
+		if !hook.Blocking {
+			go func(hook v2.ActionWebhook) {
+				if err := w.send(context.WithoutCancel(ctx), hook, body); err != nil {
+					ctrl.LoggerFrom(ctx).Error(err, "release action webhook failed",
+						"action", payload.Action, "phase", payload.Phase)
+				}
+			}(hook)
+			continue
+		}
+		errs = append(errs, w.send(ctx, hook, body))
+	}
+	return errors.Join(errs...)
+}
+
+// resolve returns the address and token in the Secret of the given webhook.
+func (w *Webhooks) resolve(ctx context.Context, hook v2.ActionWebhook) (string, []byte, error) {
+	key := types.NamespacedName{Namespace: w.namespace, Name: hook.SecretRef.Name}
+	secret := &corev1.Secret{}
+	if err := w.reader.Get(ctx, key, secret); err != nil {
+		return "", nil, fmt.Errorf("failed to get Secret '%s' of webhook '%s': %w", key, hook.Name, err)
+	}
+	address := strings.TrimSpace(string(secret.Data[v2.ActionWebhookAddressKey]))
+	if err := w.httpClient.ValidateAddress(address); err != nil {
+		return "", nil, fmt.Errorf("invalid '%s' in Secret '%s' of webhook '%s': %w",
+			v2.ActionWebhookAddressKey, key, hook.Name, err)
+	}
+	return address, secret.Data[v2.ActionWebhookTokenKey], nil
+}
+
+// send posts the body to the address in the Secret of the webhook, signed
+// with its token, and returns an error if it does not respond with a 2xx
+// status code within its timeout.
+func (w *Webhooks) send(ctx context.Context, hook v2.ActionWebhook, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, hook.GetTimeout())
+	defer cancel()
+
+	address, token, err := w.resolve(ctx, hook)
+	if err != nil {
+		return err
+	}
+	var header http.Header
+	if len(token) > 0 {
+		header = http.Header{SignatureHeader: []string{Sign(token, body)}}
+	}
+	if err = w.httpClient.Post(ctx, address, body, header); err != nil {
+		return fmt.Errorf("webhook '%s' failed: %w", hook.Name, err)
+	}
+	return nil
+}
+
+// Sign returns the value of the SignatureHeader for the given body, signed
+// with the given token.
+func Sign(token, body []byte) string {
+	mac := hmac.New(sha256.New, token)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
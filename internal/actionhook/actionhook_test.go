@@ -0,0 +1,280 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/audit"
+	"github.com/fluxcd/helm-controller/internal/outbound"
+)
+
+func newClient(t *testing.T) *outbound.Client {
+	t.Helper()
+	c, err := outbound.NewClient(outbound.Options{AllowedHosts: []string{"example.com", "127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func newObj(secrets ...string) *v2.HelmRelease {
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "default"},
+	}
+	if len(secrets) > 0 {
+		obj.Spec.Hooks = &v2.Hooks{}
+	}
+	for _, s := range secrets {
+		obj.Spec.Hooks.Webhooks = append(obj.Spec.Hooks.Webhooks, v2.ActionWebhook{
+			Name:      "hook-" + s,
+			SecretRef: meta.LocalObjectReference{Name: s},
+		})
+	}
+	return obj
+}
+
+func TestNew(t *testing.T) {
+	g := NewWithT(t)
+
+	reader := fake.NewClientBuilder().Build()
+	g.Expect(New(reader, newClient(t), newObj())).To(BeNil())
+	g.Expect(New(reader, nil, newObj("valid"))).To(BeNil())
+	g.Expect(New(reader, newClient(t), newObj("valid"))).ToNot(BeNil())
+}
+
+func TestWebhooks_Validate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "valid", Namespace: "default"},
+			Data: map[string][]byte{
+				v2.ActionWebhookAddressKey: []byte("https://example.com/hook"),
+				v2.ActionWebhookTokenKey:   []byte("secret"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "default"},
+			Data:       map[string][]byte{v2.ActionWebhookAddressKey: []byte("example.com")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "disallowed", Namespace: "default"},
+			Data:       map[string][]byte{v2.ActionWebhookAddressKey: []byte("http://169.254.169.254/latest")},
+		},
+	).Build()
+
+	t.Run("without webhooks", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(New(reader, newClient(t), newObj()).Validate(context.TODO())).To(Succeed())
+	})
+
+	t.Run("with valid Secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		webhooks := New(reader, newClient(t), newObj("valid"))
+		g.Expect(webhooks.Validate(context.TODO())).To(Succeed())
+
+		address, token, err := webhooks.resolve(context.TODO(), webhooks.webhooks[0])
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(address).To(Equal("https://example.com/hook"))
+		g.Expect(token).To(Equal([]byte("secret")))
+	})
+
+	t.Run("with missing Secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		err := New(reader, newClient(t), newObj("valid", "missing")).Validate(context.TODO())
+		g.Expect(err).To(MatchError(ContainSubstring("failed to get Secret 'default/missing' of webhook 'hook-missing'")))
+	})
+
+	t.Run("with invalid address", func(t *testing.T) {
+		g := NewWithT(t)
+
+		err := New(reader, newClient(t), newObj("invalid")).Validate(context.TODO())
+		g.Expect(err).To(MatchError("invalid 'address' in Secret 'default/invalid' of webhook 'hook-invalid': must be an HTTP(S) URL"))
+	})
+
+	t.Run("with disallowed address", func(t *testing.T) {
+		g := NewWithT(t)
+
+		err := New(reader, newClient(t), newObj("disallowed")).Validate(context.TODO())
+		g.Expect(err).To(MatchError("invalid 'address' in Secret 'default/disallowed' of webhook 'hook-disallowed': host '169.254.169.254' is not allowed"))
+	})
+}
+
+func TestWebhooks_Call(t *testing.T) {
+	type request struct {
+		payload   Payload
+		signature string
+		valid     bool
+	}
+	received := make(chan request, 10)
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p Payload
+		_ = json.Unmarshal(body, &p)
+		sig := r.Header.Get(SignatureHeader)
+		w.WriteHeader(int(status.Load()))
+		received <- request{payload: p, signature: sig, valid: sig == Sign([]byte("token"), body)}
+	}))
+	t.Cleanup(server.Close)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	t.Cleanup(slow.Close)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "signed", Namespace: "default"},
+			Data: map[string][]byte{
+				v2.ActionWebhookAddressKey: []byte(server.URL),
+				v2.ActionWebhookTokenKey:   []byte("token"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "unsigned", Namespace: "default"},
+			Data:       map[string][]byte{v2.ActionWebhookAddressKey: []byte(server.URL)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "slow", Namespace: "default"},
+			Data:       map[string][]byte{v2.ActionWebhookAddressKey: []byte(slow.URL)},
+		},
+	).Build()
+
+	newWebhooks := func(hooks ...v2.ActionWebhook) *Webhooks {
+		obj := newObj()
+		obj.Spec.Hooks = &v2.Hooks{Webhooks: hooks}
+		return New(reader, newClient(t), obj)
+	}
+	secretRef := func(name string) meta.LocalObjectReference {
+		return meta.LocalObjectReference{Name: name}
+	}
+
+	payload := Payload{
+		Phase:   v2.WebhookPhasePre,
+		Action:  "upgrade",
+		Time:    time.Now(),
+		Object:  audit.Object{Name: "release", Namespace: "default"},
+		Release: audit.Release{Name: "release", Namespace: "default", Version: 2},
+	}
+
+	t.Run("calls matching blocking webhooks", func(t *testing.T) {
+		g := NewWithT(t)
+
+		webhooks := newWebhooks(
+			v2.ActionWebhook{Name: "signed", SecretRef: secretRef("signed"), Blocking: true},
+			v2.ActionWebhook{Name: "install", SecretRef: secretRef("unsigned"), Blocking: true, Actions: []string{"install"}},
+			v2.ActionWebhook{Name: "post", SecretRef: secretRef("unsigned"), Blocking: true, Phases: []v2.WebhookPhase{v2.WebhookPhasePost}},
+		)
+		g.Expect(webhooks.Call(context.TODO(), payload)).To(Succeed())
+
+		var req request
+		g.Expect(received).To(Receive(&req))
+		g.Expect(req.valid).To(BeTrue())
+		g.Expect(req.signature).To(HavePrefix("sha256="))
+		g.Expect(req.payload.Action).To(Equal("upgrade"))
+		g.Expect(req.payload.Phase).To(Equal(v2.WebhookPhasePre))
+		g.Expect(req.payload.Release.Version).To(Equal(2))
+		g.Expect(received).ToNot(Receive())
+	})
+
+	t.Run("does not call webhooks for other actions", func(t *testing.T) {
+		g := NewWithT(t)
+
+		webhooks := newWebhooks(v2.ActionWebhook{Name: "all", SecretRef: secretRef("unsigned"), Blocking: true})
+		test := payload
+		test.Action = "test"
+		g.Expect(webhooks.Call(context.TODO(), test)).To(Succeed())
+		g.Expect(received).ToNot(Receive())
+	})
+
+	t.Run("does not read Secrets of webhooks for other actions", func(t *testing.T) {
+		g := NewWithT(t)
+
+		webhooks := newWebhooks(v2.ActionWebhook{Name: "install", SecretRef: secretRef("missing"), Blocking: true,
+			Actions: []string{"install"}})
+		g.Expect(webhooks.Call(context.TODO(), payload)).To(Succeed())
+	})
+
+	t.Run("returns error of blocking webhook with missing Secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		webhooks := newWebhooks(v2.ActionWebhook{Name: "gate", SecretRef: secretRef("missing"), Blocking: true})
+		g.Expect(webhooks.Call(context.TODO(), payload)).To(MatchError(ContainSubstring(
+			"failed to get Secret 'default/missing' of webhook 'gate'")))
+	})
+
+	t.Run("returns error of failed blocking webhook", func(t *testing.T) {
+		g := NewWithT(t)
+		status.Store(http.StatusForbidden)
+		t.Cleanup(func() { status.Store(http.StatusOK) })
+
+		webhooks := newWebhooks(v2.ActionWebhook{Name: "gate", SecretRef: secretRef("unsigned"), Blocking: true})
+		g.Expect(webhooks.Call(context.TODO(), payload)).To(MatchError("webhook 'gate' failed: unexpected status code 403"))
+		g.Expect(received).To(Receive())
+	})
+
+	t.Run("ignores failed non-blocking webhook", func(t *testing.T) {
+		g := NewWithT(t)
+		status.Store(http.StatusInternalServerError)
+		t.Cleanup(func() { status.Store(http.StatusOK) })
+
+		webhooks := newWebhooks(v2.ActionWebhook{Name: "record", SecretRef: secretRef("unsigned")})
+		g.Expect(webhooks.Call(context.TODO(), payload)).To(Succeed())
+		g.Eventually(received, time.Second).Should(Receive())
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		g := NewWithT(t)
+
+		webhooks := newWebhooks(v2.ActionWebhook{
+			Name:      "slow",
+			SecretRef: secretRef("slow"),
+			Blocking:  true,
+			Timeout:   &metav1.Duration{Duration: 10 * time.Millisecond},
+		})
+		g.Expect(webhooks.Call(context.TODO(), payload)).To(MatchError(ContainSubstring("webhook 'slow' failed: context deadline exceeded")))
+	})
+
+	t.Run("nil Webhooks", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var webhooks *Webhooks
+		g.Expect(webhooks.Call(context.TODO(), payload)).To(Succeed())
+		g.Expect(webhooks.Validate(context.TODO())).To(Succeed())
+	})
+}
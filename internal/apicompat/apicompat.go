@@ -0,0 +1,222 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apicompat validates the API versions of rendered objects against
+// the APIs served by a Kubernetes cluster, to detect objects using APIs which
+// have been removed from (or are unknown to) the cluster before any of them
+// is applied.
+package apicompat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+// Removal describes the removal of an API version from Kubernetes.
+type Removal struct {
+	// Version is the Kubernetes version the API version was removed in.
+	Version string
+	// Replacement is the API version replacing the removed version.
+	Replacement string
+}
+
+// removals are the API versions of the built-in kinds which were removed from
+// Kubernetes, by group version and kind.
+var removals = map[schema.GroupVersionKind]Removal{
+	{Group: "extensions", Version: "v1beta1", Kind: "DaemonSet"}:                                        {"v1.16", "apps/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}:                                       {"v1.16", "apps/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "ReplicaSet"}:                                       {"v1.16", "apps/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "NetworkPolicy"}:                                    {"v1.16", "networking.k8s.io/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "PodSecurityPolicy"}:                                {"v1.16", "policy/v1beta1"},
+	{Group: "apps", Version: "v1beta1", Kind: "Deployment"}:                                             {"v1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta1", Kind: "StatefulSet"}:                                            {"v1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "DaemonSet"}:                                              {"v1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "Deployment"}:                                             {"v1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "ReplicaSet"}:                                             {"v1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "StatefulSet"}:                                            {"v1.16", "apps/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}:                                          {"v1.22", "networking.k8s.io/v1"},
+	{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"}:                                   {"v1.22", "networking.k8s.io/v1"},
+	{Group: "networking.k8s.io", Version: "v1beta1", Kind: "IngressClass"}:                              {"v1.22", "networking.k8s.io/v1"},
+	{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"}:               {"v1.22", "apiextensions.k8s.io/v1"},
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "MutatingWebhookConfiguration"}:   {"v1.22", "admissionregistration.k8s.io/v1"},
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "ValidatingWebhookConfiguration"}: {"v1.22", "admissionregistration.k8s.io/v1"},
+	{Group: "apiregistration.k8s.io", Version: "v1beta1", Kind: "APIService"}:                           {"v1.22", "apiregistration.k8s.io/v1"},
+	{Group: "certificates.k8s.io", Version: "v1beta1", Kind: "CertificateSigningRequest"}:               {"v1.22", "certificates.k8s.io/v1"},
+	{Group: "coordination.k8s.io", Version: "v1beta1", Kind: "Lease"}:                                   {"v1.22", "coordination.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "ClusterRole"}:                       {"v1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "ClusterRoleBinding"}:                {"v1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "Role"}:                              {"v1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "RoleBinding"}:                       {"v1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "scheduling.k8s.io", Version: "v1beta1", Kind: "PriorityClass"}:                             {"v1.22", "scheduling.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSIDriver"}:                                    {"v1.22", "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSINode"}:                                      {"v1.22", "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "StorageClass"}:                                 {"v1.22", "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "VolumeAttachment"}:                             {"v1.22", "storage.k8s.io/v1"},
+	{Group: "batch", Version: "v1beta1", Kind: "CronJob"}:                                               {"v1.25", "batch/v1"},
+	{Group: "discovery.k8s.io", Version: "v1beta1", Kind: "EndpointSlice"}:                              {"v1.25", "discovery.k8s.io/v1"},
+	{Group: "events.k8s.io", Version: "v1beta1", Kind: "Event"}:                                         {"v1.25", "events.k8s.io/v1"},
+	{Group: "autoscaling", Version: "v2beta1", Kind: "HorizontalPodAutoscaler"}:                         {"v1.25", "autoscaling/v2"},
+	{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"}:                                  {"v1.25", "policy/v1"},
+	{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}:                                    {"v1.25", ""},
+	{Group: "node.k8s.io", Version: "v1beta1", Kind: "RuntimeClass"}:                                    {"v1.25", "node.k8s.io/v1"},
+	{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"}:                         {"v1.26", "autoscaling/v2"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Kind: "FlowSchema"}:                     {"v1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Kind: "PriorityLevelConfiguration"}:     {"v1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSIStorageCapacity"}:                           {"v1.27", "storage.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Kind: "FlowSchema"}:                     {"v1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Kind: "PriorityLevelConfiguration"}:     {"v1.29", "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// IncompatibleError is returned by Check when rendered objects use API
+// versions which are not served by the cluster.
+type IncompatibleError struct {
+	// Unavailable are the descriptions of the objects using an API version
+	// which is not served by the cluster.
+	Unavailable []string
+}
+
+// Error returns an error string containing the unavailable APIs.
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("rendered objects use APIs which are not served by the cluster: %s",
+		strings.Join(e.Unavailable, "; "))
+}
+
+// Check returns an IncompatibleError if any of the given objects use an API
+// version which is not served by the cluster according to the given
+// discovery client. The kinds defined by CustomResourceDefinitions in the
+// objects are considered to be served, as they become available when the
+// objects are applied.
+func Check(client discovery.DiscoveryInterface, objects []*unstructured.Unstructured) error {
+	defined := definedKinds(objects)
+	served := newServedKinds(client)
+
+	var unavailable []string
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		if _, ok := defined[gvk]; ok {
+			continue
+		}
+		ok, err := served.has(gvk)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			unavailable = append(unavailable, describe(obj))
+		}
+	}
+
+	if len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		return &IncompatibleError{Unavailable: unavailable}
+	}
+	return nil
+}
+
+// RemovalFor returns the Removal of the given group version and kind, and
+// false if it is not a known removal.
+func RemovalFor(gvk schema.GroupVersionKind) (Removal, bool) {
+	r, ok := removals[gvk]
+	return r, ok
+}
+
+// describe returns a description of the object with the unavailable API,
+// including the Kubernetes version it was removed in if known.
+func describe(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	desc := fmt.Sprintf("%s %s", gvk.GroupVersion().String(), ssautil.FmtUnstructured(obj))
+	r, ok := RemovalFor(gvk)
+	if !ok {
+		return desc + ": unknown API"
+	}
+	desc = fmt.Sprintf("%s: API removed in Kubernetes %s", desc, r.Version)
+	if r.Replacement != "" {
+		desc += fmt.Sprintf(", use %s", r.Replacement)
+	}
+	return desc
+}
+
+// servedKinds discovers the kinds served by a cluster, caching the
+// resources of every group version it discovered.
+type servedKinds struct {
+	client    discovery.DiscoveryInterface
+	resources map[schema.GroupVersion]*metav1.APIResourceList
+}
+
+func newServedKinds(client discovery.DiscoveryInterface) *servedKinds {
+	return &servedKinds{
+		client:    client,
+		resources: make(map[schema.GroupVersion]*metav1.APIResourceList),
+	}
+}
+
+// has returns true if the cluster serves the given group version kind.
+func (s *servedKinds) has(gvk schema.GroupVersionKind) (bool, error) {
+	resources, ok := s.resources[gvk.GroupVersion()]
+	if !ok {
+		var err error
+		resources, err = s.client.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return false, fmt.Errorf("failed to discover resources of %s: %w", gvk.GroupVersion(), err)
+			}
+			resources = nil
+		}
+		s.resources[gvk.GroupVersion()] = resources
+	}
+	if resources == nil {
+		return false, nil
+	}
+	for _, r := range resources.APIResources {
+		// Ignore subresources, e.g. deployments/scale.
+		if r.Kind == gvk.Kind && !strings.Contains(r.Name, "/") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// definedKinds returns the group version kinds defined by the
+// CustomResourceDefinitions in the given objects.
+func definedKinds(objects []*unstructured.Unstructured) map[schema.GroupVersionKind]struct{} {
+	defined := make(map[schema.GroupVersionKind]struct{})
+	for _, obj := range objects {
+		if gvk := obj.GroupVersionKind(); gvk.Group != "apiextensions.k8s.io" || gvk.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		versions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "versions")
+		for _, v := range versions {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, ok := m["name"].(string); ok {
+				defined[schema.GroupVersionKind{Group: group, Version: name, Kind: kind}] = struct{}{}
+			}
+		}
+	}
+	return defined
+}
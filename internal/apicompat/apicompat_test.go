@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicompat
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+func TestCheck(t *testing.T) {
+	client := fakeclientset.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap"},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment"},
+				{Name: "deployments/scale", Kind: "Scale"},
+			},
+		},
+		{
+			GroupVersion: "flowcontrol.apiserver.k8s.io/v1beta3",
+			APIResources: []metav1.APIResource{
+				{Name: "flowschemas", Kind: "FlowSchema"},
+			},
+		},
+		{
+			GroupVersion: "apiextensions.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		manifest    string
+		unavailable []string
+	}{
+		{
+			name: "served APIs",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo`,
+		},
+		{
+			name: "removed APIs",
+			manifest: `apiVersion: flowcontrol.apiserver.k8s.io/v1beta2
+kind: FlowSchema
+metadata:
+  name: podinfo
+---
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: podinfo`,
+			unavailable: []string{
+				"flowcontrol.apiserver.k8s.io/v1beta2 FlowSchema/podinfo: API removed in Kubernetes v1.29, use flowcontrol.apiserver.k8s.io/v1",
+				"policy/v1beta1 PodSecurityPolicy/podinfo: API removed in Kubernetes v1.25",
+			},
+		},
+		{
+			name: "unknown kind of served group version",
+			manifest: `apiVersion: apps/v1
+kind: Scale
+metadata:
+  name: podinfo`,
+			unavailable: []string{
+				"apps/v1 Scale/podinfo: unknown API",
+			},
+		},
+		{
+			name: "kind defined by chart",
+			manifest: `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: podinfos.example.com
+spec:
+  group: example.com
+  names:
+    kind: Podinfo
+  versions:
+  - name: v1
+---
+apiVersion: example.com/v1
+kind: Podinfo
+metadata:
+  name: podinfo
+---
+apiVersion: example.com/v2
+kind: Podinfo
+metadata:
+  name: podinfo-v2`,
+			unavailable: []string{
+				"example.com/v2 Podinfo/podinfo-v2: unknown API",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objects, err := ssautil.ReadObjects(strings.NewReader(tt.manifest))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = Check(client, objects)
+			if len(tt.unavailable) == 0 {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(BeAssignableToTypeOf(&IncompatibleError{}))
+			g.Expect(err.(*IncompatibleError).Unavailable).To(Equal(tt.unavailable))
+		})
+	}
+}
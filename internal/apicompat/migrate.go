@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicompat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/yaml"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+// manifestSeparator matches the separators between the documents of a Helm
+// release manifest.
+var manifestSeparator = regexp.MustCompile(`(?m)^---\n`)
+
+// Migrate returns the given Helm release manifest with the objects which use
+// a removed API that is not served by the cluster of the given discovery
+// client rewritten to the API replacing it, or removed from the manifest if
+// there is no replacement. It returns a description of every migrated
+// object, which is empty when the manifest was not modified.
+//
+// This allows Helm to build the objects of a release made before the API was
+// removed from the cluster, which it otherwise refuses to upgrade.
+func Migrate(client discovery.DiscoveryInterface, manifest string) (string, []string, error) {
+	served := newServedKinds(client)
+
+	var (
+		docs     = manifestSeparator.Split(manifest, -1)
+		result   = make([]string, 0, len(docs))
+		migrated []string
+	)
+	for _, doc := range docs {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil || obj.Object == nil {
+			result = append(result, doc)
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		r, ok := RemovalFor(gvk)
+		if !ok {
+			result = append(result, doc)
+			continue
+		}
+		ok, err := served.has(gvk)
+		if err != nil {
+			return manifest, nil, err
+		}
+		if ok {
+			result = append(result, doc)
+			continue
+		}
+
+		desc := fmt.Sprintf("%s %s", gvk.GroupVersion().String(), ssautil.FmtUnstructured(obj))
+		if r.Replacement == "" {
+			migrated = append(migrated, desc+" removed")
+			continue
+		}
+		apiVersion := regexp.MustCompile(`(?m)^apiVersion:[ \t]*["']?` + regexp.QuoteMeta(gvk.GroupVersion().String()) + `["']?[ \t]*$`)
+		result = append(result, apiVersion.ReplaceAllLiteralString(doc, "apiVersion: "+r.Replacement))
+		migrated = append(migrated, desc+" migrated to "+r.Replacement)
+	}
+
+	if len(migrated) == 0 {
+		return manifest, nil, nil
+	}
+	return strings.Join(result, "---\n"), migrated, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicompat
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMigrate(t *testing.T) {
+	client := fakeclientset.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "batch/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "cronjobs", Kind: "CronJob"},
+			},
+		},
+		{
+			GroupVersion: "autoscaling/v2beta2",
+			APIResources: []metav1.APIResource{
+				{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler"},
+			},
+		},
+	}
+
+	const manifest = `---
+# Source: chart/templates/cronjob.yaml
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: apps
+---
+# Source: chart/templates/hpa.yaml
+apiVersion: autoscaling/v2beta2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: podinfo
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: podinfo
+---
+# Source: chart/templates/psp.yaml
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: podinfo
+`
+
+	t.Run("migrates removed APIs", func(t *testing.T) {
+		g := NewWithT(t)
+
+		got, migrated, err := Migrate(client, manifest)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(migrated).To(Equal([]string{
+			"batch/v1beta1 CronJob/apps/backup migrated to batch/v1",
+			"policy/v1beta1 PodSecurityPolicy/podinfo removed",
+		}))
+		// The HorizontalPodAutoscaler API is still served by the cluster.
+		g.Expect(got).To(Equal(`---
+# Source: chart/templates/cronjob.yaml
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: apps
+---
+# Source: chart/templates/hpa.yaml
+apiVersion: autoscaling/v2beta2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: podinfo
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: podinfo
+`))
+	})
+
+	t.Run("no removed APIs", func(t *testing.T) {
+		g := NewWithT(t)
+
+		const current = `---
+# Source: chart/templates/cronjob.yaml
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+`
+		got, migrated, err := Migrate(client, current)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(migrated).To(BeEmpty())
+		g.Expect(got).To(Equal(current))
+	})
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit provides an audit log of the Helm actions performed by the
+// controller, written as JSON records to a file and/or an HTTP endpoint.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	flagLogPath    = "audit-log-path"
+	flagWebhookURL = "audit-webhook-url"
+)
+
+const (
+	// ResultSuccess is the Record.Result of a successful Helm action.
+	ResultSuccess = "success"
+	// ResultFailure is the Record.Result of a failed Helm action.
+	ResultFailure = "failure"
+)
+
+// Record is the audit record of a Helm action performed by the controller.
+type Record struct {
+	// Time is when the Helm action completed.
+	Time time.Time `json:"time"`
+	// ReconcileID is the ID of the reconciliation the Helm action was
+	// performed in.
+	ReconcileID string `json:"reconcileID,omitempty"`
+	// Action is the name of the Helm action, e.g. "install" or "upgrade".
+	Action string `json:"action"`
+	// Result is the result of the Helm action, either ResultSuccess or
+	// ResultFailure.
+	Result string `json:"result"`
+	// Error is the error message of a failed Helm action.
+	Error string `json:"error,omitempty"`
+	// Duration is the duration of the Helm action.
+	Duration string `json:"duration"`
+	// Object is the HelmRelease object the Helm action was performed for.
+	Object Object `json:"object"`
+	// ServiceAccount is the name of the ServiceAccount impersonated to
+	// perform the Helm action, if any.
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// KubeConfig is the name of the Secret with the KubeConfig used to
+	// perform the Helm action on a remote cluster, if any.
+	KubeConfig string `json:"kubeConfig,omitempty"`
+	// Release is the Helm release the Helm action was performed on.
+	Release Release `json:"release"`
+}
+
+// Object identifies a HelmRelease object.
+type Object struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	UID        string `json:"uid,omitempty"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+// Release describes a Helm release.
+type Release struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	Version      int    `json:"version,omitempty"`
+	ChartName    string `json:"chartName,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty"`
+	ValuesDigest string `json:"valuesDigest,omitempty"`
+}
+
+// Sink writes audit records.
+type Sink interface {
+	Write(ctx context.Context, rec Record) error
+}
+
+// Options contains the configuration options for the audit log.
+type Options struct {
+	// LogPath is the path of the file to append audit records to. The
+	// records are written to stdout when set to "-".
+	LogPath string
+	// WebhookURL is the URL of the HTTP endpoint to POST audit records to.
+	WebhookURL string
+}
+
+// BindFlags will parse the given pflag.FlagSet for audit option flags and
+// set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.LogPath, flagLogPath, "",
+		"The path of the file to append an audit record of each Helm action to, or '-' for stdout.")
+	fs.StringVar(&o.WebhookURL, flagWebhookURL, "",
+		"The URL of the HTTP endpoint to POST an audit record of each Helm action to.")
+}
+
+var (
+	sink   Sink
+	sinkMu sync.RWMutex
+)
+
+// Setup configures the audit Sink according to the Options. It returns a
+// function which releases the resources of the Sink, which should be called
+// before the program exits.
+//
+// When neither a LogPath nor a WebhookURL is configured, no audit records
+// are written.
+func Setup(opts Options) (func() error, error) {
+	var (
+		sinks   multiSink
+		closers []io.Closer
+	)
+
+	switch opts.LogPath {
+	case "":
+	case "-":
+		sinks = append(sinks, NewWriterSink(os.Stdout))
+	default:
+		f, err := os.OpenFile(opts.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log file: %w", err)
+		}
+		sinks = append(sinks, NewWriterSink(f))
+		closers = append(closers, f)
+	}
+
+	if opts.WebhookURL != "" {
+		sinks = append(sinks, NewWebhookSink(opts.WebhookURL, http.DefaultClient))
+	}
+
+	if len(sinks) > 0 {
+		SetSink(sinks)
+	}
+
+	return func() error {
+		var errs []error
+		for _, c := range closers {
+			errs = append(errs, c.Close())
+		}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// SetSink sets the Sink used by Log.
+func SetSink(s Sink) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sink = s
+}
+
+// Log writes the record to the configured Sink. It is a no-op if no Sink
+// has been configured.
+func Log(ctx context.Context, rec Record) error {
+	sinkMu.RLock()
+	defer sinkMu.RUnlock()
+	if sink == nil {
+		return nil
+	}
+	return sink.Write(ctx, rec)
+}
+
+// WriterSink writes audit records as JSON lines to an io.Writer.
+type WriterSink struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewWriterSink returns a new WriterSink writing to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write writes the record as a single line of JSON.
+func (s *WriterSink) Write(_ context.Context, rec Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(b)
+	return err
+}
+
+// WebhookSink POSTs audit records as JSON to an HTTP endpoint.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink returns a new WebhookSink posting to the given URL using
+// the given client.
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	return &WebhookSink{url: url, client: client}
+}
+
+// Write POSTs the record to the endpoint, and returns an error if the
+// endpoint does not respond with a 2xx status code.
+func (s *WebhookSink) Write(ctx context.Context, rec Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post audit record: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to post audit record: unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// multiSink writes audit records to multiple Sinks.
+type multiSink []Sink
+
+// Write writes the record to all Sinks, returning the joined errors.
+func (m multiSink) Write(ctx context.Context, rec Record) error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.Write(ctx, rec))
+	}
+	return errors.Join(errs...)
+}
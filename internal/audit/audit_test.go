@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWriterSink_Write(t *testing.T) {
+	g := NewWithT(t)
+
+	var buf bytes.Buffer
+	s := NewWriterSink(&buf)
+
+	g.Expect(s.Write(context.TODO(), Record{Action: "install", Result: ResultSuccess})).To(Succeed())
+	g.Expect(s.Write(context.TODO(), Record{Action: "upgrade", Result: ResultFailure, Error: "timeout"})).To(Succeed())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	g.Expect(lines).To(HaveLen(2))
+
+	var got Record
+	g.Expect(json.Unmarshal([]byte(lines[1]), &got)).To(Succeed())
+	g.Expect(got.Action).To(Equal("upgrade"))
+	g.Expect(got.Result).To(Equal(ResultFailure))
+	g.Expect(got.Error).To(Equal("timeout"))
+}
+
+func TestWebhookSink_Write(t *testing.T) {
+	t.Run("posts record", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var got Record
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(r.Method).To(Equal(http.MethodPost))
+			g.Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			g.Expect(json.NewDecoder(r.Body).Decode(&got)).To(Succeed())
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer srv.Close()
+
+		s := NewWebhookSink(srv.URL, srv.Client())
+		g.Expect(s.Write(context.TODO(), Record{Action: "rollback", Object: Object{Name: "podinfo"}})).To(Succeed())
+		g.Expect(got.Action).To(Equal("rollback"))
+		g.Expect(got.Object.Name).To(Equal("podinfo"))
+	})
+
+	t.Run("returns error on unexpected status code", func(t *testing.T) {
+		g := NewWithT(t)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		s := NewWebhookSink(srv.URL, srv.Client())
+		g.Expect(s.Write(context.TODO(), Record{})).To(MatchError(ContainSubstring("unexpected status code 500")))
+	})
+}
+
+func TestSetup(t *testing.T) {
+	t.Cleanup(func() { SetSink(nil) })
+
+	t.Run("without sinks", func(t *testing.T) {
+		g := NewWithT(t)
+
+		closeFn, err := Setup(Options{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(Log(context.TODO(), Record{Action: "install"})).To(Succeed())
+		g.Expect(closeFn()).To(Succeed())
+	})
+
+	t.Run("with log file", func(t *testing.T) {
+		g := NewWithT(t)
+
+		path := filepath.Join(t.TempDir(), "audit.log")
+		closeFn, err := Setup(Options{LogPath: path})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		g.Expect(Log(context.TODO(), Record{Action: "install"})).To(Succeed())
+		g.Expect(Log(context.TODO(), Record{Action: "test"})).To(Succeed())
+		g.Expect(closeFn()).To(Succeed())
+
+		b, err := os.ReadFile(path)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(strings.Count(string(b), "\n")).To(Equal(2))
+	})
+
+	t.Run("with invalid log file", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := Setup(Options{LogPath: filepath.Join(t.TempDir(), "missing", "audit.log")})
+		g.Expect(err).To(MatchError(ContainSubstring("failed to open audit log file")))
+	})
+}
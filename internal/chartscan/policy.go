@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chartscan checks the vulnerability attestations attached to OCI
+// chart artifacts against a policy.
+package chartscan
+
+import (
+	"crypto"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/helm-controller/internal/signature"
+)
+
+// Severity is the severity of a vulnerability.
+type Severity string
+
+const (
+	SeverityUnknown  Severity = "UNKNOWN"
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+// Severities are the known severities, from the lowest to the highest.
+var Severities = []Severity{SeverityUnknown, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// ParseSeverity returns the Severity for the given case-insensitive name.
+// Unknown names result in SeverityUnknown.
+func ParseSeverity(name string) Severity {
+	s := Severity(strings.ToUpper(strings.TrimSpace(name)))
+	for _, known := range Severities {
+		if s == known {
+			return s
+		}
+	}
+	return SeverityUnknown
+}
+
+// rank returns the position of the Severity in Severities.
+func (s Severity) rank() int {
+	for i, known := range Severities {
+		if s == known {
+			return i
+		}
+	}
+	return 0
+}
+
+// Policy configures the requirements on the vulnerability attestations of
+// the charts of a repository.
+type Policy struct {
+	// Charts are the rules for the charts to check. The first rule with a
+	// pattern matching the repository of a chart applies. Charts without a
+	// matching rule are not checked.
+	Charts []ChartRule `json:"charts"`
+}
+
+// ChartRule configures the requirements on the vulnerability attestations
+// of the charts of the repositories matching a pattern.
+type ChartRule struct {
+	// Pattern is matched against the repository of a chart (e.g.
+	// "ghcr.io/stefanprodan/charts/podinfo"), in the syntax of path.Match.
+	Pattern string `json:"pattern"`
+	// RequireAttestation fails the check of a chart without a vulnerability
+	// attestation.
+	RequireAttestation bool `json:"requireAttestation,omitempty"`
+	// BlockSeverity fails the check of a chart with vulnerabilities of this
+	// severity or higher. When empty, vulnerabilities do not fail the check.
+	BlockSeverity Severity `json:"blockSeverity,omitempty"`
+	// Ignore are the IDs of vulnerabilities which are ignored, e.g.
+	// "CVE-2024-1234".
+	Ignore []string `json:"ignore,omitempty"`
+	// Enforce blocks the installation and upgrade of a chart which fails the
+	// check. Otherwise, the failure is only reported.
+	Enforce bool `json:"enforce,omitempty"`
+	// PublicKeys are the PEM-encoded public keys of the rule. Only
+	// attestations signed with any of the keys are taken into account.
+	PublicKeys []string `json:"publicKeys"`
+
+	keys []crypto.PublicKey
+}
+
+// LoadPolicy reads a Policy from the YAML file at the given path, and
+// validates its rules.
+func LoadPolicy(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart vulnerability policy: %w", err)
+	}
+
+	p := &Policy{}
+	if err = yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, fmt.Errorf("failed to decode chart vulnerability policy from '%s': %w", path, err)
+	}
+	if err = p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid chart vulnerability policy in '%s': %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate returns an error if any of the rules of the Policy has an invalid
+// pattern, severity or public key. It parses the public keys of the rules.
+func (p *Policy) Validate() error {
+	for i := range p.Charts {
+		rule := &p.Charts[i]
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule %d must have a pattern", i)
+		}
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("pattern '%s' of rule %d: %w", rule.Pattern, i, err)
+		}
+		if rule.BlockSeverity != "" {
+			severity := ParseSeverity(string(rule.BlockSeverity))
+			if !strings.EqualFold(string(severity), string(rule.BlockSeverity)) {
+				return fmt.Errorf("invalid block severity '%s' of rule '%s'", rule.BlockSeverity, rule.Pattern)
+			}
+			rule.BlockSeverity = severity
+		}
+		if len(rule.PublicKeys) == 0 {
+			return fmt.Errorf("rule '%s' must have at least one public key", rule.Pattern)
+		}
+		rule.keys = make([]crypto.PublicKey, 0, len(rule.PublicKeys))
+		for j, k := range rule.PublicKeys {
+			key, err := signature.ParsePublicKey([]byte(k))
+			if err != nil {
+				return fmt.Errorf("public key %d of rule '%s': %w", j, rule.Pattern, err)
+			}
+			rule.keys = append(rule.keys, key)
+		}
+	}
+	return nil
+}
+
+// rule returns the first rule matching the given repository, or nil.
+func (p *Policy) rule(repository string) *ChartRule {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Charts {
+		if ok, _ := path.Match(p.Charts[i].Pattern, repository); ok {
+			return &p.Charts[i]
+		}
+	}
+	return nil
+}
+
+// ignores returns if the vulnerability with the given ID is ignored by the
+// rule.
+func (r *ChartRule) ignores(id string) bool {
+	for _, ignored := range r.Ignore {
+		if strings.EqualFold(ignored, id) {
+			return true
+		}
+	}
+	return false
+}
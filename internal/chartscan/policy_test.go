@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartscan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	_, pub := generateKey(t)
+	publicKeys := "    publicKeys:\n    - |\n      " + strings.ReplaceAll(strings.TrimSpace(pub), "\n", "\n      ") + "\n"
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			data: `charts:
+  - pattern: ghcr.io/stefanprodan/charts/*
+    requireAttestation: true
+    blockSeverity: high
+    ignore:
+    - CVE-2024-1234
+    enforce: true
+` + publicKeys,
+		},
+		{
+			name: "missing public keys",
+			data: `charts:
+  - pattern: ghcr.io/stefanprodan/charts/*
+`,
+			wantErr: "must have at least one public key",
+		},
+		{
+			name: "invalid public key",
+			data: `charts:
+  - pattern: ghcr.io/stefanprodan/charts/*
+    publicKeys:
+    - invalid
+`,
+			wantErr: "public key 0 of rule",
+		},
+		{
+			name: "invalid pattern",
+			data: `charts:
+  - pattern: ghcr.io/[
+`,
+			wantErr: "syntax error in pattern",
+		},
+		{
+			name: "invalid severity",
+			data: `charts:
+  - pattern: ghcr.io/stefanprodan/charts/*
+    blockSeverity: severe
+`,
+			wantErr: "invalid block severity 'severe'",
+		},
+		{
+			name:    "unknown field",
+			data:    `images: []`,
+			wantErr: "failed to decode chart vulnerability policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			g.Expect(os.WriteFile(path, []byte(tt.data), 0o600)).To(Succeed())
+
+			got, err := LoadPolicy(path)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got.Charts).To(HaveLen(1))
+			g.Expect(got.Charts[0].BlockSeverity).To(Equal(SeverityHigh))
+		})
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ParseSeverity("critical")).To(Equal(SeverityCritical))
+	g.Expect(ParseSeverity(" Medium ")).To(Equal(SeverityMedium))
+	g.Expect(ParseSeverity("negligible")).To(Equal(SeverityUnknown))
+}
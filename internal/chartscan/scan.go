@@ -0,0 +1,407 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartscan
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference/docker"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/fluxcd/helm-controller/internal/signature"
+)
+
+const (
+	// dsseMediaType is the media type of a layer containing a DSSE envelope
+	// with an in-toto statement, as attached by e.g. cosign attest.
+	dsseMediaType = "application/vnd.dsse.envelope.v1+json"
+	// inTotoMediaType is the artifact type of an in-toto attestation
+	// attached as an OCI referrer.
+	inTotoMediaType = "application/vnd.in-toto+json"
+	// vulnPredicateType is the in-toto predicate type of a cosign
+	// vulnerability attestation.
+	vulnPredicateType = "https://cosign.sigstore.dev/attestation/vuln/v1"
+	// maxFetchSize is the maximum size of an attestation manifest or layer.
+	maxFetchSize = 16 << 20
+)
+
+// ChartScanner is a global Scanner for the chart artifacts of HelmReleases.
+// When nil, charts are not scanned.
+var ChartScanner *Scanner
+
+// Vulnerability is a vulnerability reported by a vulnerability attestation.
+type Vulnerability struct {
+	ID       string
+	Severity Severity
+}
+
+// Report is the result of the check of the vulnerability attestations of a
+// chart against the rule of the Policy matching its repository.
+type Report struct {
+	// Reference is the reference of the chart, with the digest it resolved
+	// to.
+	Reference string
+	// Attested is true if the chart has a vulnerability attestation.
+	Attested bool
+	// Vulnerabilities are the number of vulnerabilities per severity,
+	// excluding the ignored vulnerabilities.
+	Vulnerabilities map[Severity]int
+	// Violations are the reasons the chart failed the check, if any.
+	Violations []string
+	// Enforced is true if the chart must not be installed or upgraded when
+	// it failed the check.
+	Enforced bool
+}
+
+// Passed returns if the chart passed the check.
+func (r *Report) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// Blocked returns if the chart must not be installed or upgraded.
+func (r *Report) Blocked() bool {
+	return r.Enforced && !r.Passed()
+}
+
+// Summary returns a human-readable summary of the Report.
+func (r *Report) Summary() string {
+	if !r.Passed() {
+		return strings.Join(r.Violations, "; ")
+	}
+	if !r.Attested {
+		return "no vulnerability attestation found"
+	}
+	var counts []string
+	for i := len(Severities) - 1; i >= 0; i-- {
+		if n := r.Vulnerabilities[Severities[i]]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, strings.ToLower(string(Severities[i]))))
+		}
+	}
+	if len(counts) == 0 {
+		return "no vulnerabilities found"
+	}
+	return "vulnerabilities found: " + strings.Join(counts, ", ")
+}
+
+// Scanner checks the vulnerability attestations of charts against a Policy.
+type Scanner struct {
+	policy   *Policy
+	resolver remotes.Resolver
+}
+
+// NewScanner returns a new Scanner for the given Policy, which retrieves
+// charts and their attestations with the given resolver.
+func NewScanner(policy *Policy, resolver remotes.Resolver) *Scanner {
+	return &Scanner{
+		policy:   policy,
+		resolver: resolver,
+	}
+}
+
+// Enforces returns if the rule of the Policy matching the repository of the
+// chart with the given OCI reference enforces the check.
+func (s *Scanner) Enforces(ref string) bool {
+	named, err := docker.ParseDockerRef(ref)
+	if err != nil {
+		return false
+	}
+	rule := s.policy.rule(named.Name())
+	return rule != nil && rule.Enforce
+}
+
+// Scan checks the vulnerability attestations of the chart with the given OCI
+// reference (e.g. "ghcr.io/stefanprodan/charts/podinfo:6.5.4") against the
+// rule of the Policy matching its repository. It returns nil if no rule
+// matches, or an error if the chart or its attestations could not be
+// retrieved.
+//
+// Attestations are looked up with the referrers tag schema of the OCI
+// distribution specification, and the attestation tag of cosign.
+func (s *Scanner) Scan(ctx context.Context, ref string) (*Report, error) {
+	named, err := docker.ParseDockerRef(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chart reference '%s': %w", ref, err)
+	}
+	rule := s.policy.rule(named.Name())
+	if rule == nil {
+		return nil, nil
+	}
+
+	_, desc, err := s.resolver.Resolve(ctx, named.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve chart '%s': %w", named, err)
+	}
+
+	vulns, attested, err := s.vulnerabilities(ctx, named.Name(), desc.Digest, rule.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Reference:       named.Name() + "@" + desc.Digest.String(),
+		Attested:        attested,
+		Vulnerabilities: make(map[Severity]int),
+		Enforced:        rule.Enforce,
+	}
+	var blocking []string
+	for _, v := range vulns {
+		if rule.ignores(v.ID) {
+			continue
+		}
+		report.Vulnerabilities[v.Severity]++
+		if rule.BlockSeverity != "" && v.Severity.rank() >= rule.BlockSeverity.rank() {
+			blocking = append(blocking, v.ID)
+		}
+	}
+	if rule.RequireAttestation && !attested {
+		report.Violations = append(report.Violations, "no vulnerability attestation found")
+	}
+	if len(blocking) > 0 {
+		sort.Strings(blocking)
+		report.Violations = append(report.Violations, fmt.Sprintf("%d vulnerabilities of severity %s or higher: %s",
+			len(blocking), rule.BlockSeverity, strings.Join(blocking, ", ")))
+	}
+	return report, nil
+}
+
+// vulnerabilities returns the unique vulnerabilities of the vulnerability
+// attestations for the given digest in the given repository which are signed
+// with any of the given keys, and if any such attestation was found.
+func (s *Scanner) vulnerabilities(ctx context.Context, repository string, dig digest.Digest, keys []crypto.PublicKey) ([]Vulnerability, bool, error) {
+	var envelopes [][]byte
+
+	// The referrers tag schema lists the referrers of a manifest in an
+	// index tagged with its digest.
+	referrersRef := fmt.Sprintf("%s:%s-%s", repository, dig.Algorithm(), dig.Encoded())
+	index, fetcher, err := s.fetchTag(ctx, referrersRef)
+	if err != nil {
+		return nil, false, err
+	}
+	if index != nil {
+		var idx ocispec.Index
+		if err = json.Unmarshal(index, &idx); err != nil {
+			return nil, false, fmt.Errorf("failed to decode referrers '%s': %w", referrersRef, err)
+		}
+		for _, m := range idx.Manifests {
+			if m.ArtifactType != inTotoMediaType && m.ArtifactType != dsseMediaType {
+				continue
+			}
+			b, err := fetch(ctx, fetcher, m)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to fetch referrer '%s': %w", m.Digest, err)
+			}
+			layers, err := s.attestationLayers(ctx, fetcher, b, true)
+			if err != nil {
+				return nil, false, err
+			}
+			envelopes = append(envelopes, layers...)
+		}
+	}
+
+	// Cosign stores the attestations of a manifest in a manifest tagged
+	// with its digest.
+	attRef := fmt.Sprintf("%s:%s-%s.att", repository, dig.Algorithm(), dig.Encoded())
+	manifest, fetcher, err := s.fetchTag(ctx, attRef)
+	if err != nil {
+		return nil, false, err
+	}
+	if manifest != nil {
+		layers, err := s.attestationLayers(ctx, fetcher, manifest, false)
+		if err != nil {
+			return nil, false, err
+		}
+		envelopes = append(envelopes, layers...)
+	}
+
+	seen := make(map[string]struct{})
+	var vulns []Vulnerability
+	var attested bool
+	for _, e := range envelopes {
+		found, ok := parseVulnAttestation(e, dig, keys)
+		if !ok {
+			continue
+		}
+		attested = true
+		for _, v := range found {
+			if _, ok := seen[v.ID]; ok {
+				continue
+			}
+			seen[v.ID] = struct{}{}
+			vulns = append(vulns, v)
+		}
+	}
+	return vulns, attested, nil
+}
+
+// fetchTag returns the content of the manifest with the given reference,
+// and a fetcher for its repository. It returns nil content if the reference
+// does not exist.
+func (s *Scanner) fetchTag(ctx context.Context, ref string) ([]byte, remotes.Fetcher, error) {
+	_, desc, err := s.resolver.Resolve(ctx, ref)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to resolve attestations '%s': %w", ref, err)
+	}
+	fetcher, err := s.resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch attestations '%s': %w", ref, err)
+	}
+	b, err := fetch(ctx, fetcher, desc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch attestations '%s': %w", ref, err)
+	}
+	return b, fetcher, nil
+}
+
+// attestationLayers returns the content of the DSSE envelope layers of the
+// given manifest. When all is true, all layers are returned regardless of
+// their media type, as the artifact type of a referrer already identifies
+// its layers as attestations.
+func (s *Scanner) attestationLayers(ctx context.Context, fetcher remotes.Fetcher, b []byte, all bool) ([][]byte, error) {
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode attestation manifest: %w", err)
+	}
+	var layers [][]byte
+	for _, layer := range manifest.Layers {
+		if !all && layer.MediaType != dsseMediaType {
+			continue
+		}
+		l, err := fetch(ctx, fetcher, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch attestation '%s': %w", layer.Digest, err)
+		}
+		layers = append(layers, l)
+	}
+	return layers, nil
+}
+
+// parseVulnAttestation returns the vulnerabilities of the given DSSE
+// envelope, if it is signed with any of the given keys and contains a cosign
+// vulnerability attestation for the given digest with a Trivy scan result.
+func parseVulnAttestation(b []byte, dig digest.Digest, keys []crypto.PublicKey) ([]Vulnerability, bool) {
+	var envelope struct {
+		PayloadType string `json:"payloadType"`
+		Payload     string `json:"payload"`
+		Signatures  []struct {
+			Sig string `json:"sig"`
+		} `json:"signatures"`
+	}
+	if err := json.Unmarshal(b, &envelope); err != nil || envelope.PayloadType != inTotoMediaType {
+		return nil, false
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, false
+	}
+
+	// The signatures of a DSSE envelope are made over the pre-authentication
+	// encoding of its payload type and payload.
+	pae := dssePAE(envelope.PayloadType, payload)
+	var signed bool
+	for _, s := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err == nil && signature.VerifySignature(keys, pae, sig) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return nil, false
+	}
+
+	var statement struct {
+		PredicateType string `json:"predicateType"`
+		Subject       []struct {
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+		Predicate struct {
+			Scanner struct {
+				Result struct {
+					Results []struct {
+						Vulnerabilities []struct {
+							VulnerabilityID string `json:"VulnerabilityID"`
+							Severity        string `json:"Severity"`
+						} `json:"Vulnerabilities"`
+					} `json:"Results"`
+				} `json:"result"`
+			} `json:"scanner"`
+		} `json:"predicate"`
+	}
+	if err = json.Unmarshal(payload, &statement); err != nil || statement.PredicateType != vulnPredicateType {
+		return nil, false
+	}
+
+	var subject bool
+	for _, s := range statement.Subject {
+		if s.Digest[dig.Algorithm().String()] == dig.Encoded() {
+			subject = true
+			break
+		}
+	}
+	if !subject {
+		return nil, false
+	}
+
+	var vulns []Vulnerability
+	for _, r := range statement.Predicate.Scanner.Result.Results {
+		for _, v := range r.Vulnerabilities {
+			vulns = append(vulns, Vulnerability{ID: v.VulnerabilityID, Severity: ParseSeverity(v.Severity)})
+		}
+	}
+	return vulns, true
+}
+
+// dssePAE returns the DSSE pre-authentication encoding of the given payload
+// type and payload.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// fetch returns the content of the given descriptor, after verifying its
+// digest.
+func fetch(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	if desc.Size > maxFetchSize {
+		return nil, fmt.Errorf("size %d exceeds the maximum of %d bytes", desc.Size, maxFetchSize)
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(io.LimitReader(rc, maxFetchSize))
+	if err != nil {
+		return nil, err
+	}
+	if d := digest.FromBytes(b); d != desc.Digest {
+		return nil, fmt.Errorf("digest mismatch: expected %s, got %s", desc.Digest, d)
+	}
+	return b, nil
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const chartRepository = "ghcr.io/stefanprodan/charts/podinfo"
+
+// fakeResolver is a remotes.Resolver serving manifests and blobs from
+// memory.
+type fakeResolver struct {
+	refs  map[string]ocispec.Descriptor
+	blobs map[digest.Digest][]byte
+}
+
+func newFakeResolver() *fakeResolver {
+	return &fakeResolver{
+		refs:  make(map[string]ocispec.Descriptor),
+		blobs: make(map[digest.Digest][]byte),
+	}
+}
+
+func (r *fakeResolver) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	desc, ok := r.refs[ref]
+	if !ok {
+		return "", ocispec.Descriptor{}, fmt.Errorf("%s: %w", ref, errdefs.ErrNotFound)
+	}
+	return ref, desc, nil
+}
+
+func (r *fakeResolver) Fetcher(_ context.Context, _ string) (remotes.Fetcher, error) {
+	return remotes.FetcherFunc(func(_ context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		b, ok := r.blobs[desc.Digest]
+		if !ok {
+			return nil, errdefs.ErrNotFound
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}), nil
+}
+
+func (r *fakeResolver) Pusher(_ context.Context, _ string) (remotes.Pusher, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeResolver) add(ref, mediaType string, b []byte) ocispec.Descriptor {
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(b),
+		Size:      int64(len(b)),
+	}
+	r.blobs[desc.Digest] = b
+	if ref != "" {
+		r.refs[ref] = desc
+	}
+	return desc
+}
+
+// vulnEnvelope returns a DSSE envelope with a cosign vulnerability
+// attestation for the given digest, reporting the given vulnerabilities. The
+// envelope is signed with the given key, unless it is nil.
+func vulnEnvelope(t *testing.T, key *ecdsa.PrivateKey, d digest.Digest, vulns map[string]string) []byte {
+	type vuln struct {
+		VulnerabilityID string
+		Severity        string
+	}
+	var vs []vuln
+	for id, severity := range vulns {
+		vs = append(vs, vuln{VulnerabilityID: id, Severity: severity})
+	}
+	statement := map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": vulnPredicateType,
+		"subject": []map[string]interface{}{
+			{"name": chartRepository, "digest": map[string]string{d.Algorithm().String(): d.Encoded()}},
+		},
+		"predicate": map[string]interface{}{
+			"scanner": map[string]interface{}{
+				"uri": "pkg:github/aquasecurity/trivy",
+				"result": map[string]interface{}{
+					"Results": []map[string]interface{}{{"Vulnerabilities": vs}},
+				},
+			},
+		},
+	}
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatures := []interface{}{}
+	if key != nil {
+		h := sha256.Sum256(dssePAE(inTotoMediaType, payload))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signatures = append(signatures, map[string]string{"sig": base64.StdEncoding.EncodeToString(sig)})
+	}
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payloadType": inTotoMediaType,
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+		"signatures":  signatures,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+func generateKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+}
+
+// pushChart adds a chart with the given tag, and returns its digest.
+func (r *fakeResolver) pushChart(tag string) digest.Digest {
+	ref := chartRepository + ":" + tag
+	return r.add(ref, ocispec.MediaTypeImageManifest, []byte(`{"schemaVersion":2,"ref":"`+ref+`"}`)).Digest
+}
+
+// attestCosign attaches the envelope with the cosign attestation tag.
+func (r *fakeResolver) attestCosign(d digest.Digest, envelope []byte) {
+	layer := r.add("", dsseMediaType, envelope)
+	manifest, _ := json.Marshal(ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest, Layers: []ocispec.Descriptor{layer}})
+	r.add(fmt.Sprintf("%s:%s-%s.att", chartRepository, d.Algorithm(), d.Encoded()), ocispec.MediaTypeImageManifest, manifest)
+}
+
+// attestReferrer attaches the envelope with the referrers tag schema.
+func (r *fakeResolver) attestReferrer(d digest.Digest, envelope []byte) {
+	layer := r.add("", dsseMediaType, envelope)
+	manifest, _ := json.Marshal(ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest, Layers: []ocispec.Descriptor{layer}})
+	desc := r.add("", ocispec.MediaTypeImageManifest, manifest)
+	desc.ArtifactType = inTotoMediaType
+	index, _ := json.Marshal(ocispec.Index{MediaType: ocispec.MediaTypeImageIndex, Manifests: []ocispec.Descriptor{desc}})
+	r.add(fmt.Sprintf("%s:%s-%s", chartRepository, d.Algorithm(), d.Encoded()), ocispec.MediaTypeImageIndex, index)
+}
+
+func TestScanner_Scan(t *testing.T) {
+	key, pub := generateKey(t)
+	otherKey, _ := generateKey(t)
+
+	tests := []struct {
+		name           string
+		rule           ChartRule
+		attest         func(t *testing.T, r *fakeResolver, d digest.Digest)
+		wantNil        bool
+		wantAttested   bool
+		wantVulns      map[Severity]int
+		wantViolations []string
+		wantBlocked    bool
+	}{
+		{
+			name:    "no matching rule",
+			rule:    ChartRule{Pattern: "docker.io/*"},
+			wantNil: true,
+		},
+		{
+			name:      "not attested",
+			rule:      ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*"},
+			wantVulns: map[Severity]int{},
+		},
+		{
+			name:           "attestation required",
+			rule:           ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*", RequireAttestation: true, Enforce: true},
+			wantVulns:      map[Severity]int{},
+			wantViolations: []string{"no vulnerability attestation found"},
+			wantBlocked:    true,
+		},
+		{
+			name: "cosign attestation below block severity",
+			rule: ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*", BlockSeverity: SeverityHigh, Enforce: true},
+			attest: func(t *testing.T, r *fakeResolver, d digest.Digest) {
+				r.attestCosign(d, vulnEnvelope(t, key, d, map[string]string{"CVE-2024-0001": "MEDIUM", "CVE-2024-0002": "LOW"}))
+			},
+			wantAttested: true,
+			wantVulns:    map[Severity]int{SeverityMedium: 1, SeverityLow: 1},
+		},
+		{
+			name: "referrer attestation with blocking vulnerabilities",
+			rule: ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*", BlockSeverity: SeverityHigh},
+			attest: func(t *testing.T, r *fakeResolver, d digest.Digest) {
+				r.attestReferrer(d, vulnEnvelope(t, key, d, map[string]string{"CVE-2024-0001": "CRITICAL", "CVE-2024-0002": "HIGH"}))
+			},
+			wantAttested:   true,
+			wantVulns:      map[Severity]int{SeverityCritical: 1, SeverityHigh: 1},
+			wantViolations: []string{"2 vulnerabilities of severity HIGH or higher: CVE-2024-0001, CVE-2024-0002"},
+		},
+		{
+			name: "ignored vulnerabilities",
+			rule: ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*", BlockSeverity: SeverityHigh, Ignore: []string{"cve-2024-0001"}},
+			attest: func(t *testing.T, r *fakeResolver, d digest.Digest) {
+				r.attestCosign(d, vulnEnvelope(t, key, d, map[string]string{"CVE-2024-0001": "CRITICAL"}))
+			},
+			wantAttested: true,
+			wantVulns:    map[Severity]int{},
+		},
+		{
+			name: "attestation for other digest",
+			rule: ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*", RequireAttestation: true},
+			attest: func(t *testing.T, r *fakeResolver, d digest.Digest) {
+				r.attestCosign(d, vulnEnvelope(t, key, digest.FromString("other"), map[string]string{"CVE-2024-0001": "CRITICAL"}))
+			},
+			wantVulns:      map[Severity]int{},
+			wantViolations: []string{"no vulnerability attestation found"},
+		},
+		{
+			name: "unsigned attestation",
+			rule: ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*", RequireAttestation: true},
+			attest: func(t *testing.T, r *fakeResolver, d digest.Digest) {
+				r.attestCosign(d, vulnEnvelope(t, nil, d, map[string]string{}))
+			},
+			wantVulns:      map[Severity]int{},
+			wantViolations: []string{"no vulnerability attestation found"},
+		},
+		{
+			name: "attestation signed with other key",
+			rule: ChartRule{Pattern: "ghcr.io/stefanprodan/charts/*", RequireAttestation: true},
+			attest: func(t *testing.T, r *fakeResolver, d digest.Digest) {
+				r.attestReferrer(d, vulnEnvelope(t, otherKey, d, map[string]string{}))
+			},
+			wantVulns:      map[Severity]int{},
+			wantViolations: []string{"no vulnerability attestation found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := newFakeResolver()
+			d := r.pushChart("6.5.4")
+			if tt.attest != nil {
+				tt.attest(t, r, d)
+			}
+
+			rule := tt.rule
+			rule.PublicKeys = []string{pub}
+			policy := &Policy{Charts: []ChartRule{rule}}
+			g.Expect(policy.Validate()).To(Succeed())
+
+			report, err := NewScanner(policy, r).Scan(context.TODO(), chartRepository+":6.5.4")
+			g.Expect(err).ToNot(HaveOccurred())
+			if tt.wantNil {
+				g.Expect(report).To(BeNil())
+				return
+			}
+			g.Expect(report).ToNot(BeNil())
+			g.Expect(report.Reference).To(Equal(chartRepository + "@" + d.String()))
+			g.Expect(report.Attested).To(Equal(tt.wantAttested))
+			g.Expect(report.Vulnerabilities).To(Equal(tt.wantVulns))
+			g.Expect(report.Violations).To(Equal(tt.wantViolations))
+			g.Expect(report.Passed()).To(Equal(len(tt.wantViolations) == 0))
+			g.Expect(report.Blocked()).To(Equal(tt.wantBlocked))
+		})
+	}
+}
+
+func TestScanner_Scan_resolveError(t *testing.T) {
+	g := NewWithT(t)
+
+	_, pub := generateKey(t)
+	policy := &Policy{Charts: []ChartRule{{Pattern: "ghcr.io/stefanprodan/charts/*", PublicKeys: []string{pub}}}}
+	g.Expect(policy.Validate()).To(Succeed())
+	_, err := NewScanner(policy, newFakeResolver()).Scan(context.TODO(), chartRepository+":6.5.4")
+	g.Expect(err).To(MatchError(ContainSubstring("failed to resolve chart")))
+}
+
+func TestReport_Summary(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect((&Report{}).Summary()).To(Equal("no vulnerability attestation found"))
+	g.Expect((&Report{Attested: true}).Summary()).To(Equal("no vulnerabilities found"))
+	g.Expect((&Report{Attested: true, Vulnerabilities: map[Severity]int{SeverityHigh: 2, SeverityLow: 1}}).Summary()).
+		To(Equal("vulnerabilities found: 2 high, 1 low"))
+	g.Expect((&Report{Violations: []string{"a", "b"}}).Summary()).To(Equal("a; b"))
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"sigs.k8s.io/yaml"
+)
+
+// ChangesAnnotation is the chart annotation Artifact Hub uses for the changes
+// introduced by the version of the chart.
+const ChangesAnnotation = "artifacthub.io/changes"
+
+// Changes returns the changes introduced by the version of the given chart,
+// as described by the ChangesAnnotation of the chart. When the chart does not
+// have the annotation, the changes are taken from the section of the version
+// in a CHANGELOG file at the root of the chart. It returns nil if the changes
+// can not be determined.
+func Changes(chrt *helmchart.Chart) []string {
+	if chrt == nil || chrt.Metadata == nil {
+		return nil
+	}
+	if changes, ok := chrt.Metadata.Annotations[ChangesAnnotation]; ok {
+		return annotationChanges(changes)
+	}
+	for _, f := range chrt.Files {
+		if path.Dir(f.Name) == "." && strings.HasPrefix(strings.ToUpper(f.Name), "CHANGELOG") {
+			return changelogChanges(string(f.Data), chrt.Metadata.Version)
+		}
+	}
+	return nil
+}
+
+// annotationChanges returns the changes in the given value of the
+// ChangesAnnotation, which is either a YAML list of descriptions, or a YAML
+// list of objects with a kind and description.
+func annotationChanges(annotation string) []string {
+	var entries []interface{}
+	if err := yaml.Unmarshal([]byte(annotation), &entries); err != nil {
+		return nil
+	}
+	var changes []string
+	for _, e := range entries {
+		switch v := e.(type) {
+		case string:
+			changes = append(changes, strings.TrimSpace(v))
+		case map[string]interface{}:
+			desc, _ := v["description"].(string)
+			if desc = strings.TrimSpace(desc); desc == "" {
+				continue
+			}
+			if kind, _ := v["kind"].(string); kind != "" {
+				desc = fmt.Sprintf("%s: %s", kind, desc)
+			}
+			changes = append(changes, desc)
+		}
+	}
+	return changes
+}
+
+// changelogChanges returns the lines of the section of the given version in
+// the given Markdown changelog, without list markers. The section starts at
+// the first heading containing the version, and ends at the next heading of
+// the same or a higher level.
+func changelogChanges(changelog, version string) []string {
+	if version == "" {
+		return nil
+	}
+	var (
+		changes []string
+		level   int
+	)
+	for _, line := range strings.Split(changelog, "\n") {
+		line = strings.TrimSpace(line)
+		if l := headingLevel(line); l > 0 {
+			if level > 0 && l <= level {
+				break
+			}
+			if level == 0 && strings.Contains(line, version) {
+				level = l
+			}
+			continue
+		}
+		if level == 0 || line == "" {
+			continue
+		}
+		for _, marker := range []string{"- ", "* ", "+ "} {
+			line = strings.TrimPrefix(line, marker)
+		}
+		changes = append(changes, line)
+	}
+	return changes
+}
+
+// headingLevel returns the level of the given Markdown ATX heading, or zero
+// if the line is not a heading.
+func headingLevel(line string) int {
+	l := len(line) - len(strings.TrimLeft(line, "#"))
+	if l == 0 || l > 6 || (len(line) > l && line[l] != ' ') {
+		return 0
+	}
+	return l
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestChanges(t *testing.T) {
+	const changelog = `# Changelog
+
+## [6.5.0] - 2024-02-01
+
+### Added
+
+- Support for topology spread constraints
+* Ingress class name
+
+## 6.4.1
+
+- Fix probes
+`
+
+	tests := []struct {
+		name  string
+		chart *helmchart.Chart
+		want  []string
+	}{
+		{
+			name: "annotation with descriptions",
+			chart: &helmchart.Chart{Metadata: &helmchart.Metadata{
+				Version: "6.5.0",
+				Annotations: map[string]string{
+					ChangesAnnotation: "- Support for topology spread constraints\n- Ingress class name\n",
+				},
+			}},
+			want: []string{"Support for topology spread constraints", "Ingress class name"},
+		},
+		{
+			name: "annotation with kinds",
+			chart: &helmchart.Chart{Metadata: &helmchart.Metadata{
+				Version: "6.5.0",
+				Annotations: map[string]string{
+					ChangesAnnotation: `- kind: added
+  description: Support for topology spread constraints
+  links:
+  - name: GitHub PR
+    url: https://github.com/stefanprodan/podinfo/pull/1
+- kind: fixed
+  description: Probes
+- kind: changed
+`,
+				},
+			}},
+			want: []string{"added: Support for topology spread constraints", "fixed: Probes"},
+		},
+		{
+			name: "annotation takes precedence",
+			chart: &helmchart.Chart{
+				Metadata: &helmchart.Metadata{
+					Version:     "6.5.0",
+					Annotations: map[string]string{ChangesAnnotation: "- Ingress class name"},
+				},
+				Files: []*helmchart.File{{Name: "CHANGELOG.md", Data: []byte(changelog)}},
+			},
+			want: []string{"Ingress class name"},
+		},
+		{
+			name: "changelog section",
+			chart: &helmchart.Chart{
+				Metadata: &helmchart.Metadata{Version: "6.5.0"},
+				Files:    []*helmchart.File{{Name: "CHANGELOG.md", Data: []byte(changelog)}},
+			},
+			want: []string{"Support for topology spread constraints", "Ingress class name"},
+		},
+		{
+			name: "changelog without version",
+			chart: &helmchart.Chart{
+				Metadata: &helmchart.Metadata{Version: "7.0.0"},
+				Files:    []*helmchart.File{{Name: "CHANGELOG.md", Data: []byte(changelog)}},
+			},
+		},
+		{
+			name: "changelog in subdirectory",
+			chart: &helmchart.Chart{
+				Metadata: &helmchart.Metadata{Version: "6.5.0"},
+				Files:    []*helmchart.File{{Name: "docs/CHANGELOG.md", Data: []byte(changelog)}},
+			},
+		},
+		{
+			name:  "no changes",
+			chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Version: "6.5.0"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(Changes(tt.chart)).To(Equal(tt.want))
+		})
+	}
+}
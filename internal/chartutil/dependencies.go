@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// DependencyMismatch describes a difference between the lock file of a chart
+// and the dependencies declared in, or bundled with, the chart.
+type DependencyMismatch struct {
+	// Chart is the full path of the chart in the chart tree, e.g.
+	// "parent/charts/redis".
+	Chart string
+	// Message describes the mismatch.
+	Message string
+}
+
+// String returns a description of the mismatch, e.g.
+// `parent: dependency 'redis' is locked at version 17.3.7 in Chart.lock,
+// but version 17.3.8 is bundled`.
+func (m DependencyMismatch) String() string {
+	return m.Chart + ": " + m.Message
+}
+
+// DependencyMismatches compares the lock file of the chart and of its
+// subcharts with the dependencies they declare and bundle, and returns the
+// mismatches. This catches charts which were repackaged with other versions
+// of their dependencies than the locked ones. As a lock file records the
+// versions of the dependencies but not digests of their contents, a bundled
+// dependency of which the contents were changed without changing its version
+// is not detected.
+//
+// For every chart with a lock file, the digest of the lock file must match
+// the dependencies declared in the chart, as computed by Helm when the lock
+// file is written, and every locked dependency must be bundled with the
+// chart at the locked version. The digest can not be verified for charts of
+// which the dependencies use a repository alias, as Helm computes it after
+// resolving the aliases using the repository configuration of the packager.
+// Charts without a lock file, and bundled subcharts without a lock entry,
+// are not compared.
+func DependencyMismatches(chrt *helmchart.Chart) []DependencyMismatch {
+	var mismatches []DependencyMismatch
+	dependencyMismatches(chrt, &mismatches)
+	return mismatches
+}
+
+func dependencyMismatches(chrt *helmchart.Chart, mismatches *[]DependencyMismatch) {
+	if chrt.Lock != nil && chrt.Metadata != nil {
+		lockFile := "Chart.lock"
+		if chrt.Metadata.APIVersion == helmchart.APIVersionV1 {
+			lockFile = "requirements.lock"
+		}
+		add := func(format string, a ...interface{}) {
+			*mismatches = append(*mismatches, DependencyMismatch{
+				Chart:   chrt.ChartFullPath(),
+				Message: fmt.Sprintf(format, a...),
+			})
+		}
+
+		if !lockDigestMatches(chrt) {
+			add("%s is out of sync with the dependencies of the chart", lockFile)
+		}
+
+		bundled := make(map[string][]string)
+		for _, dep := range chrt.Dependencies() {
+			if dep.Metadata != nil {
+				bundled[dep.Name()] = append(bundled[dep.Name()], dep.Metadata.Version)
+			}
+		}
+		for _, lock := range chrt.Lock.Dependencies {
+			versions, ok := bundled[lock.Name]
+			switch {
+			case !ok:
+				add("dependency '%s' is locked at version %s in %s, but is not bundled", lock.Name, lock.Version, lockFile)
+			case !containsVersion(versions, lock.Version):
+				add("dependency '%s' is locked at version %s in %s, but version %s is bundled",
+					lock.Name, lock.Version, lockFile, strings.Join(versions, ", "))
+			}
+		}
+	}
+
+	for _, dep := range chrt.Dependencies() {
+		dependencyMismatches(dep, mismatches)
+	}
+}
+
+// lockDigestMatches returns if the digest of the lock file of the chart
+// matches the dependencies declared in the chart, or can not be verified.
+func lockDigestMatches(chrt *helmchart.Chart) bool {
+	for _, dep := range chrt.Metadata.Dependencies {
+		if strings.HasPrefix(dep.Repository, "@") || strings.HasPrefix(dep.Repository, "alias:") {
+			return true
+		}
+	}
+
+	// Equal to the digest Helm computes when writing the lock file.
+	if d, err := lockDigest([2][]*helmchart.Dependency{chrt.Metadata.Dependencies, chrt.Lock.Dependencies}); err == nil && d == chrt.Lock.Digest {
+		return true
+	}
+	// Helm v2 computed the digest of the requirements only, which is still
+	// accepted by Helm for charts with API version v1.
+	if chrt.Metadata.APIVersion == helmchart.APIVersionV1 {
+		if d, err := lockDigest(map[string][]*helmchart.Dependency{"dependencies": chrt.Metadata.Dependencies}); err == nil && d == chrt.Lock.Digest {
+			return true
+		}
+	}
+	return false
+}
+
+// lockDigest returns the digest of the JSON encoding of v, in the format of
+// the digest of a lock file.
+func lockDigest(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// containsVersion returns if versions contains the given version, comparing
+// them as semantic versions if possible.
+func containsVersion(versions []string, version string) bool {
+	want, err := semver.NewVersion(version)
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+		if err != nil {
+			continue
+		}
+		if got, err := semver.NewVersion(v); err == nil && got.Equal(want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func TestDependencyMismatches(t *testing.T) {
+	// The Chart.lock of the parent chart was written by Helm.
+	load := func(t *testing.T) *helmchart.Chart {
+		t.Helper()
+		chrt, err := loader.Load("testdata/dependencies/parent")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chrt
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(chrt *helmchart.Chart)
+		want   []string
+	}{
+		{
+			name:   "locked dependencies are bundled",
+			mutate: func(chrt *helmchart.Chart) {},
+		},
+		{
+			name:   "without lock file",
+			mutate: func(chrt *helmchart.Chart) { chrt.Lock = nil },
+		},
+		{
+			name: "dependencies changed after locking",
+			mutate: func(chrt *helmchart.Chart) {
+				chrt.Metadata.Dependencies[0].Version = ">=0.2.0"
+			},
+			want: []string{"parent: Chart.lock is out of sync with the dependencies of the chart"},
+		},
+		{
+			name: "dependencies with repository alias",
+			mutate: func(chrt *helmchart.Chart) {
+				chrt.Metadata.Dependencies[0].Repository = "@internal"
+			},
+		},
+		{
+			name: "bundled dependency with other version",
+			mutate: func(chrt *helmchart.Chart) {
+				chrt.Dependencies()[0].Metadata.Version = "0.1.1"
+			},
+			want: []string{"parent: dependency 'child' is locked at version 0.1.0 in Chart.lock, but version 0.1.1 is bundled"},
+		},
+		{
+			name: "bundled dependency with equal semantic version",
+			mutate: func(chrt *helmchart.Chart) {
+				chrt.Dependencies()[0].Metadata.Version = "v0.1.0"
+			},
+		},
+		{
+			name: "locked dependency not bundled",
+			mutate: func(chrt *helmchart.Chart) {
+				chrt.SetDependencies()
+			},
+			want: []string{"parent: dependency 'child' is locked at version 0.1.0 in Chart.lock, but is not bundled"},
+		},
+		{
+			name: "mismatch in lock file of subchart",
+			mutate: func(chrt *helmchart.Chart) {
+				chrt.Dependencies()[0].Metadata.APIVersion = helmchart.APIVersionV1
+				chrt.Dependencies()[0].Lock = &helmchart.Lock{
+					Digest:       "sha256:invalid",
+					Dependencies: []*helmchart.Dependency{{Name: "redis", Version: "17.3.7"}},
+				}
+			},
+			want: []string{
+				"parent/charts/child: requirements.lock is out of sync with the dependencies of the chart",
+				"parent/charts/child: dependency 'redis' is locked at version 17.3.7 in requirements.lock, but is not bundled",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			chrt := load(t)
+			tt.mutate(chrt)
+
+			var got []string
+			for _, m := range DependencyMismatches(chrt) {
+				got = append(got, m.String())
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
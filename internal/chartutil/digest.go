@@ -20,6 +20,7 @@ import (
 	"github.com/opencontainers/go-digest"
 	"helm.sh/helm/v3/pkg/chartutil"
 
+	intdigest "github.com/fluxcd/helm-controller/internal/digest"
 	intyaml "github.com/fluxcd/helm-controller/internal/yaml"
 )
 
@@ -36,8 +37,10 @@ func DigestValues(algo digest.Algorithm, values chartutil.Values) digest.Digest
 }
 
 // VerifyValues verifies the digest of the values against the provided digest.
+// It returns false if the algorithm of the digest is not allowed by
+// digest.Allowed.
 func VerifyValues(digest digest.Digest, values chartutil.Values) bool {
-	if digest.Validate() != nil {
+	if digest.Validate() != nil || intdigest.Allowed(digest.Algorithm()) != nil {
 		return false
 	}
 
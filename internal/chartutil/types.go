@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/fluxcd/pkg/runtime/transform"
+)
+
+// The kinds of values, as named by JSON schema.
+const (
+	kindString  = "string"
+	kindInteger = "integer"
+	kindNumber  = "number"
+	kindBoolean = "boolean"
+	kindObject  = "object"
+	kindArray   = "array"
+)
+
+// TypeMismatch describes a value of which the type differs from the type
+// expected by the chart, e.g. the string "3" where the chart expects an
+// integer. It does not hold the value itself, as values may be composed from
+// Secrets.
+type TypeMismatch struct {
+	// Path is the dot notation path of the value.
+	Path string
+	// Got is the kind of the value.
+	Got string
+	// Want are the kinds expected by the chart.
+	Want []string
+}
+
+// String returns a description of the mismatch, e.g.
+// `replicaCount: got string, chart expects integer`.
+func (m TypeMismatch) String() string {
+	return fmt.Sprintf("%s: got %s, chart expects %s", m.Path, m.Got, strings.Join(m.Want, " or "))
+}
+
+// TypeMismatches compares the kinds of the given values to the kinds expected
+// by the chart, and returns the mismatches sorted by path.
+//
+// The expected kind of a value is taken from the JSON schema of the chart if
+// it declares a type for the value, and otherwise from the default value of
+// the chart. Values of subcharts are compared to the schema and default
+// values of the subchart. Values without a counterpart in the chart, and null
+// values, are not compared. As charts commonly do not distinguish between
+// integer and floating point defaults, the kinds "integer" and "number" only
+// mismatch when the schema requires an integer.
+//
+// Helm does not coerce values: a string "3" passed where the chart expects
+// an integer, or a string "false" where it expects a boolean (which is
+// truthy), is passed to the templates as is. This silently breaks charts
+// which are not protected by a schema.
+func TypeMismatches(chrt *helmchart.Chart, values chartutil.Values) []TypeMismatch {
+	var result []TypeMismatch
+	compareChart(chrt, nil, values, "", &result)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// compareChart compares the values of the given chart, with the defaults of
+// the parent chart for the chart merged over its own defaults.
+func compareChart(chrt *helmchart.Chart, parentDefaults, values map[string]interface{}, path string, result *[]TypeMismatch) {
+	defaults := transform.MergeMaps(chrt.Values, parentDefaults)
+
+	subcharts := make(map[string]*helmchart.Chart)
+	for _, dep := range chrt.Dependencies() {
+		subcharts[dep.Name()] = dep
+	}
+	if chrt.Metadata != nil {
+		for _, dep := range chrt.Metadata.Dependencies {
+			if c, ok := subcharts[dep.Name]; ok && dep.Alias != "" {
+				subcharts[dep.Alias] = c
+			}
+		}
+	}
+
+	compareValues(defaults, schemaOf(chrt), values, path, subcharts, result)
+}
+
+// compareValues compares the kinds of the values to the kinds expected by the
+// schema node and defaults at the given path. Values of which the key is
+// in subcharts are compared to the subchart instead.
+func compareValues(defaults, schema, values map[string]interface{}, path string,
+	subcharts map[string]*helmchart.Chart, result *[]TypeMismatch) {
+	for k, v := range values {
+		if v == nil {
+			continue
+		}
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+
+		if sub, ok := subcharts[k]; ok {
+			if m, ok := v.(map[string]interface{}); ok {
+				d, _ := defaults[k].(map[string]interface{})
+				compareChart(sub, d, m, p, result)
+			}
+			continue
+		}
+
+		childSchema := schemaProperty(schema, k)
+		got := kindOf(v)
+		want, strict := schemaTypes(childSchema), true
+		if len(want) == 0 && defaults[k] != nil {
+			want, strict = []string{kindOf(defaults[k])}, false
+		}
+		if len(want) > 0 && !kindMatches(got, want, strict) {
+			*result = append(*result, TypeMismatch{Path: p, Got: got, Want: want})
+			continue
+		}
+
+		if m, ok := v.(map[string]interface{}); ok {
+			d, _ := defaults[k].(map[string]interface{})
+			compareValues(d, childSchema, m, p, nil, result)
+		}
+	}
+}
+
+// kindMatches returns if the kind is one of the wanted kinds. Unless strict,
+// the kinds "integer" and "number" match each other.
+func kindMatches(got string, want []string, strict bool) bool {
+	for _, w := range want {
+		switch {
+		case w == got:
+			return true
+		case w == kindNumber && got == kindInteger:
+			return true
+		case !strict && w == kindInteger && got == kindNumber:
+			return true
+		}
+	}
+	return false
+}
+
+// kindOf returns the JSON schema kind of the value. Whole numbers are
+// reported as "integer", regardless of their Go type.
+func kindOf(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return kindString
+	case bool:
+		return kindBoolean
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return kindInteger
+	case float32:
+		return kindOf(float64(n))
+	case float64:
+		if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return kindInteger
+		}
+		return kindNumber
+	case json.Number:
+		if _, err := n.Int64(); err == nil {
+			return kindInteger
+		}
+		return kindNumber
+	case map[string]interface{}:
+		return kindObject
+	case []interface{}:
+		return kindArray
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// schemaOf returns the JSON schema of the chart, or nil if the chart does not
+// have a (valid) schema.
+func schemaOf(chrt *helmchart.Chart) map[string]interface{} {
+	if len(chrt.Schema) == 0 {
+		return nil
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(chrt.Schema, &schema); err != nil {
+		return nil
+	}
+	return schema
+}
+
+// schemaProperty returns the schema node of the property with the given name
+// of the schema node, or nil.
+func schemaProperty(schema map[string]interface{}, name string) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	property, _ := properties[name].(map[string]interface{})
+	return property
+}
+
+// schemaTypes returns the types declared by the schema node, excluding
+// "null".
+func schemaTypes(schema map[string]interface{}) []string {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = append(types, t)
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	result := types[:0]
+	for _, t := range types {
+		if t != "null" {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestTypeMismatches(t *testing.T) {
+	newChart := func() *helmchart.Chart {
+		chrt := &helmchart.Chart{
+			Metadata: &helmchart.Metadata{
+				Name: "parent",
+				Dependencies: []*helmchart.Dependency{
+					{Name: "redis", Alias: "cache"},
+				},
+			},
+			Values: map[string]interface{}{
+				"replicaCount": float64(1),
+				"ratio":        0.5,
+				"enabled":      true,
+				"image": map[string]interface{}{
+					"tag":        "",
+					"pullPolicy": "IfNotPresent",
+				},
+				"resources": map[string]interface{}{},
+				"extraArgs": []interface{}{},
+				"nothing":   nil,
+			},
+			Schema: []byte(`{
+  "properties": {
+    "port": {"type": "integer"},
+    "annotations": {"type": ["object", "null"]}
+  }
+}`),
+		}
+		chrt.AddDependency(&helmchart.Chart{
+			Metadata: &helmchart.Metadata{Name: "redis"},
+			Values: map[string]interface{}{
+				"persistence": true,
+			},
+		})
+		return chrt
+	}
+
+	tests := []struct {
+		name   string
+		values chartutil.Values
+		want   []string
+	}{
+		{
+			name: "matching values",
+			values: chartutil.Values{
+				"replicaCount": int64(3),
+				"ratio":        float64(1),
+				"enabled":      false,
+				"image":        map[string]interface{}{"tag": "6.5.4"},
+				"port":         float64(8080),
+				"annotations":  nil,
+				"unknown":      "value",
+				"nothing":      "value",
+				"cache":        map[string]interface{}{"persistence": false},
+			},
+		},
+		{
+			name: "mismatching values",
+			values: chartutil.Values{
+				"replicaCount": "3",
+				"enabled":      "false",
+				"image":        map[string]interface{}{"tag": 1.10},
+				"resources":    "none",
+				"extraArgs":    map[string]interface{}{},
+				"port":         8080.5,
+				"annotations":  "a=b",
+				"cache":        map[string]interface{}{"persistence": "yes"},
+			},
+			want: []string{
+				`annotations: got string, chart expects object`,
+				`cache.persistence: got string, chart expects boolean`,
+				`enabled: got string, chart expects boolean`,
+				`extraArgs: got object, chart expects array`,
+				`image.tag: got number, chart expects string`,
+				`port: got number, chart expects integer`,
+				`replicaCount: got string, chart expects integer`,
+				`resources: got string, chart expects object`,
+			},
+		},
+		{
+			name:   "no values",
+			values: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var got []string
+			for _, m := range TypeMismatches(newChart(), tt.values) {
+				got = append(got, m.String())
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
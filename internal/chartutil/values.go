@@ -20,16 +20,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 
+	helmchart "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/strvals"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	kubeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	kstatus "github.com/fluxcd/cli-utils/pkg/kstatus/status"
 	"github.com/fluxcd/pkg/runtime/transform"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
@@ -54,6 +59,9 @@ var (
 	// ErrValueMerge signals a single value could not be merged into the
 	// values.
 	ErrValueMerge = errors.New("failed to merge value")
+	// ErrOwnerNotReady signals the controller owner of the referenced values
+	// resource is not ready.
+	ErrOwnerNotReady = errors.New("owner not ready")
 	// ErrUnknown signals the reason an error occurred is unknown.
 	ErrUnknown = errors.New("unknown error")
 )
@@ -147,11 +155,36 @@ const (
 	kindSecret    = "Secret"
 )
 
+// ownerReady returns an error if the controller owner of the given object is
+// not ready, or has not observed its latest generation, according to
+// kstatus. It returns nil if the object does not have a controller owner.
+func ownerReady(ctx context.Context, client kubeclient.Reader, obj kubeclient.Object) error {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		return nil
+	}
+
+	owner := &unstructured.Unstructured{}
+	owner.SetAPIVersion(ref.APIVersion)
+	owner.SetKind(ref.Kind)
+	if err := client.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: ref.Name}, owner); err != nil {
+		return fmt.Errorf("failed to get owner %s '%s': %w", ref.Kind, ref.Name, err)
+	}
+	res, err := kstatus.Compute(owner)
+	if err != nil {
+		return fmt.Errorf("failed to compute status of owner %s '%s': %w", ref.Kind, ref.Name, err)
+	}
+	if res.Status != kstatus.CurrentStatus {
+		return fmt.Errorf("owner %s '%s' is not ready: %s", ref.Kind, ref.Name, res.Message)
+	}
+	return nil
+}
+
 // ChartValuesFromReferences attempts to construct new chart values by resolving
 // the provided references using the client, merging them in the order given.
 // If provided, the values map is merged in last. Overwriting values from
 // references. It returns the merged values, or an ErrValuesReference error.
-func ChartValuesFromReferences(ctx context.Context, client kubeclient.Client, namespace string,
+func ChartValuesFromReferences(ctx context.Context, client kubeclient.Reader, namespace string,
 	values map[string]interface{}, refs ...v2.ValuesReference) (chartutil.Values, error) {
 
 	log := ctrl.LoggerFrom(ctx)
@@ -203,6 +236,12 @@ func ChartValuesFromReferences(ctx context.Context, client kubeclient.Client, na
 				return nil, NewErrValuesReference(namespacedName, ref, ErrResourceNotFound, nil)
 			}
 
+			if ref.WaitForOwner {
+				if err := ownerReady(ctx, client, resource); err != nil {
+					return nil, NewErrValuesReference(namespacedName, ref, ErrOwnerNotReady, err)
+				}
+			}
+
 			switch typedRes := resource.(type) {
 			case *corev1.Secret:
 				data, ok := typedRes.Data[ref.GetValuesKey()]
@@ -252,6 +291,44 @@ func ChartValuesFromReferences(ctx context.Context, client kubeclient.Client, na
 	return transform.MergeMaps(result, values), nil
 }
 
+// ChartValuesFromFiles returns the values of the given values files bundled
+// in the chart, merged in the order given. The default values file of the
+// chart is taken from the values of the chart. It returns an error if a file
+// is not bundled in the chart, or can not be read.
+func ChartValuesFromFiles(chrt *helmchart.Chart, files []string) (chartutil.Values, error) {
+	result := chartutil.Values{}
+	for _, name := range files {
+		name = path.Clean(strings.TrimPrefix(name, "/"))
+
+		var values map[string]interface{}
+		if name == chartutil.ValuesfileName {
+			values = chrt.Values
+		} else {
+			data, ok := chartFile(chrt, name)
+			if !ok {
+				return nil, fmt.Errorf("values file '%s' not found in chart", name)
+			}
+			var err error
+			if values, err = chartutil.ReadValues(data); err != nil {
+				return nil, fmt.Errorf("failed to read values file '%s' from chart: %w", name, err)
+			}
+		}
+		result = transform.MergeMaps(result, values)
+	}
+	return result, nil
+}
+
+// chartFile returns the data of the file with the given name in the files
+// of the chart.
+func chartFile(chrt *helmchart.Chart, name string) ([]byte, bool) {
+	for _, f := range chrt.Files {
+		if f.Name == name {
+			return f.Data, true
+		}
+	}
+	return nil, false
+}
+
 // ReplacePathValue replaces the value at the dot notation path with the given
 // value using Helm's string value parser using strvals.ParseInto. Single or
 // double-quoted values are merged using strvals.ParseIntoString.
@@ -22,10 +22,13 @@ import (
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
+	helmchart "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
@@ -213,6 +216,31 @@ other: values
 			},
 			wantErr: true,
 		},
+		{
+			name: "values reference waiting for missing owner",
+			resources: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "values",
+						OwnerReferences: []metav1.OwnerReference{{
+							APIVersion: "external-secrets.io/v1beta1",
+							Kind:       "ExternalSecret",
+							Name:       "values",
+							Controller: ptr.To(true),
+						}},
+					},
+					Data: map[string][]byte{"values.yaml": []byte("flat: value")},
+				},
+			},
+			references: []v2.ValuesReference{
+				{
+					Kind:         kindSecret,
+					Name:         "values",
+					WaitForOwner: true,
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "unsupported values reference kind",
 			references: []v2.ValuesReference{
@@ -266,6 +294,73 @@ invalid`,
 
 // This tests compatability with the formats described in:
 // https://helm.sh/docs/intro/using_helm/#the-format-and-limitations-of---set
+func TestChartValuesFromFiles(t *testing.T) {
+	chrt := &helmchart.Chart{
+		Values: map[string]interface{}{
+			"replicas": 1,
+			"image":    map[string]interface{}{"tag": "latest", "pullPolicy": "Always"},
+		},
+		Files: []*helmchart.File{
+			{Name: "values-production.yaml", Data: []byte("replicas: 3\nimage:\n  tag: v1.0.0\n")},
+			{Name: "profiles/values-ha.yaml", Data: []byte("replicas: 5\nha: true\n")},
+			{Name: "values-invalid.yaml", Data: []byte("replicas: [")},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		files   []string
+		want    chartutil.Values
+		wantErr string
+	}{
+		{
+			name:  "no files",
+			files: nil,
+			want:  chartutil.Values{},
+		},
+		{
+			name:  "merges files in order",
+			files: []string{"values.yaml", "values-production.yaml", "/profiles/values-ha.yaml"},
+			want: chartutil.Values{
+				"replicas": float64(5),
+				"ha":       true,
+				"image":    map[string]interface{}{"tag": "v1.0.0", "pullPolicy": "Always"},
+			},
+		},
+		{
+			name:    "file not found",
+			files:   []string{"values-staging.yaml"},
+			wantErr: "values file 'values-staging.yaml' not found in chart",
+		},
+		{
+			name:    "invalid file",
+			files:   []string{"values-invalid.yaml"},
+			wantErr: "failed to read values file 'values-invalid.yaml' from chart",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := ChartValuesFromFiles(chrt, tt.files)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+
+	t.Run("does not modify chart values", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := ChartValuesFromFiles(chrt, []string{"values.yaml", "values-production.yaml"})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(chrt.Values["image"]).To(HaveKeyWithValue("tag", "latest"))
+	})
+}
+
 func TestReplacePathValue(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -394,3 +489,82 @@ func testScheme() *runtime.Scheme {
 	_ = v2.AddToScheme(scheme)
 	return scheme
 }
+
+func Test_ownerReady(t *testing.T) {
+	newOwner := func(generation, observedGeneration int64, ready metav1.ConditionStatus) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "external-secrets.io/v1beta1",
+			"kind":       "ExternalSecret",
+			"metadata": map[string]interface{}{
+				"name":       "values",
+				"generation": generation,
+			},
+			"status": map[string]interface{}{
+				"observedGeneration": observedGeneration,
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":   "Ready",
+						"status": string(ready),
+					},
+				},
+			},
+		}}
+	}
+	secret := mockSecret("values", nil)
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "external-secrets.io/v1beta1",
+		Kind:       "ExternalSecret",
+		Name:       "values",
+		Controller: ptr.To(true),
+	}}
+
+	tests := []struct {
+		name    string
+		obj     *corev1.Secret
+		owner   *unstructured.Unstructured
+		wantErr string
+	}{
+		{
+			name: "without owner",
+			obj:  mockSecret("values", nil),
+		},
+		{
+			name:  "ready owner",
+			obj:   secret,
+			owner: newOwner(2, 2, metav1.ConditionTrue),
+		},
+		{
+			name:    "owner which has not observed its generation",
+			obj:     secret,
+			owner:   newOwner(2, 1, metav1.ConditionTrue),
+			wantErr: "owner ExternalSecret 'values' is not ready",
+		},
+		{
+			name:    "owner which is not ready",
+			obj:     secret,
+			owner:   newOwner(2, 2, metav1.ConditionFalse),
+			wantErr: "owner ExternalSecret 'values' is not ready",
+		},
+		{
+			name:    "missing owner",
+			obj:     secret,
+			wantErr: "failed to get owner ExternalSecret 'values'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithScheme(testScheme())
+			if tt.owner != nil {
+				c.WithObjects(tt.owner)
+			}
+			err := ownerReady(context.TODO(), c.Build(), tt.obj)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
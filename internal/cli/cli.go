@@ -0,0 +1,37 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cli provides the subcommands of the controller binary, which run
+// a single task instead of the controller.
+package cli
+
+import (
+	"context"
+	"io"
+)
+
+// Command is a subcommand of the controller binary. It is run with the
+// arguments following the name of the subcommand, and writes its output to
+// stdout.
+type Command func(ctx context.Context, args []string, stdout io.Writer) error
+
+// Commands contains the subcommands of the controller binary by name.
+var Commands = map[string]Command{
+	"convert": Convert,
+	"export":  Export,
+	"import":  Import,
+	"render":  Render,
+}
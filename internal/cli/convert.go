@@ -0,0 +1,291 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+)
+
+// helmChartMediaType is the media type of the layer containing the chart in
+// an OCI artifact.
+const helmChartMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+// convertOptions contains the options for converting a Helm release to
+// HelmRelease manifests.
+type convertOptions struct {
+	// repositoryURL is the URL of the Helm repository the chart of the
+	// release is available from. An "oci://" URL results in an
+	// OCIRepository, any other URL in a HelmRepository.
+	repositoryURL string
+	// sourceName is the name of the HelmRepository.
+	sourceName string
+	// namespace is the namespace of the emitted objects. Defaults to the
+	// namespace of the release.
+	namespace string
+	// interval is the reconciliation interval of the emitted objects.
+	interval time.Duration
+	// valuesSecret writes the values of the release to a Secret referenced
+	// by the HelmRelease, instead of inlining them.
+	valuesSecret bool
+}
+
+// Convert reads the deployed Helm releases from the Helm storage in a
+// namespace of the cluster, and writes the HelmRelease and source manifests
+// which would take over the management of the releases to stdout.
+//
+// As a Helm release does not record the repository its chart originates
+// from, the URL of the repository is provided with a flag. The values of the
+// releases are inlined in the HelmReleases, or written to Secrets.
+func Convert(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		opts     convertOptions
+		releases []string
+	)
+
+	kubeConfig := genericclioptions.NewConfigFlags(false)
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	kubeConfig.AddFlags(fs)
+	fs.StringVar(&opts.repositoryURL, "repository-url", "",
+		"The URL of the Helm repository the charts of the releases are available from. An 'oci://' URL results in OCIRepositories.")
+	fs.StringVar(&opts.sourceName, "source-name", "charts",
+		"The name of the emitted HelmRepository.")
+	fs.StringVar(&opts.namespace, "export-namespace", "",
+		"The namespace of the emitted objects. Defaults to the namespace of the releases.")
+	fs.DurationVar(&opts.interval, "interval", 10*time.Minute,
+		"The reconciliation interval of the emitted objects.")
+	fs.BoolVar(&opts.valuesSecret, "values-secret", false,
+		"Write the values of the releases to Secrets referenced by the HelmReleases, instead of inlining them.")
+	fs.StringSliceVar(&releases, "release", nil,
+		"The name of a release to convert. Can be repeated. Defaults to all deployed releases in the namespace.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if opts.repositoryURL == "" {
+		return errors.New("--repository-url is required")
+	}
+
+	namespace, _, err := kubeConfig.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return fmt.Errorf("failed to determine namespace: %w", err)
+	}
+	cfg, err := action.NewConfigFactory(kubeConfig, action.WithStorage(action.DefaultStorageDriver, namespace))
+	if err != nil {
+		return err
+	}
+	deployed, err := cfg.NewStorage().ListDeployed()
+	if err != nil {
+		return fmt.Errorf("failed to list deployed releases in namespace '%s': %w", namespace, err)
+	}
+	sort.Slice(deployed, func(i, j int) bool { return deployed[i].Name < deployed[j].Name })
+
+	var (
+		n    int
+		seen = make(map[string]struct{})
+	)
+	for _, rls := range deployed {
+		if len(releases) > 0 && !slices.Contains(releases, rls.Name) {
+			continue
+		}
+		objs, err := convertRelease(rls, opts)
+		if err != nil {
+			return fmt.Errorf("failed to convert release '%s/%s': %w", rls.Namespace, rls.Name, err)
+		}
+		// The HelmRepository is shared by the releases, and only written once.
+		objs = slices.DeleteFunc(objs, func(obj client.Object) bool {
+			key := obj.GetObjectKind().GroupVersionKind().Kind + "/" + client.ObjectKeyFromObject(obj).String()
+			_, ok := seen[key]
+			seen[key] = struct{}{}
+			return ok
+		})
+		if err = writeObjects(stdout, objs); err != nil {
+			return err
+		}
+		n++
+	}
+	if n == 0 {
+		return fmt.Errorf("no deployed releases to convert in namespace '%s'", namespace)
+	}
+	return nil
+}
+
+// convertRelease returns the HelmRelease, the source object for its chart,
+// and optionally the Secret with its values, for the given release.
+func convertRelease(rls *helmrelease.Release, opts convertOptions) ([]client.Object, error) {
+	if rls.Chart == nil || rls.Chart.Metadata == nil {
+		return nil, errors.New("release has no chart metadata")
+	}
+
+	namespace := opts.namespace
+	if namespace == "" {
+		namespace = rls.Namespace
+	}
+	interval := metav1.Duration{Duration: opts.interval}
+
+	obj := &v2.HelmRelease{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v2.GroupVersion.String(),
+			Kind:       v2.HelmReleaseKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rls.Name,
+			Namespace: namespace,
+		},
+		Spec: v2.HelmReleaseSpec{
+			Interval:    interval,
+			ReleaseName: rls.Name,
+		},
+	}
+	// Target the existing release when the objects are emitted to another
+	// namespace, so that the controller takes over the release instead of
+	// installing a new one.
+	if namespace != rls.Namespace {
+		obj.Spec.TargetNamespace = rls.Namespace
+		obj.Spec.StorageNamespace = rls.Namespace
+	}
+
+	var objs []client.Object
+	if strings.HasPrefix(opts.repositoryURL, "oci://") {
+		repository := &sourcev1beta2.OCIRepository{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: sourcev1beta2.GroupVersion.String(),
+				Kind:       sourcev1beta2.OCIRepositoryKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      rls.Name,
+				Namespace: namespace,
+			},
+			Spec: sourcev1beta2.OCIRepositorySpec{
+				URL:       strings.TrimSuffix(opts.repositoryURL, "/") + "/" + rls.Chart.Name(),
+				Reference: &sourcev1beta2.OCIRepositoryRef{Tag: rls.Chart.Metadata.Version},
+				LayerSelector: &sourcev1beta2.OCILayerSelector{
+					MediaType: helmChartMediaType,
+					Operation: sourcev1beta2.OCILayerCopy,
+				},
+				Interval: interval,
+			},
+		}
+		obj.Spec.ChartRef = &v2.CrossNamespaceSourceReference{
+			Kind: sourcev1beta2.OCIRepositoryKind,
+			Name: repository.Name,
+		}
+		objs = append(objs, repository)
+	} else {
+		repository := &sourcev1.HelmRepository{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: sourcev1.GroupVersion.String(),
+				Kind:       sourcev1.HelmRepositoryKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      opts.sourceName,
+				Namespace: namespace,
+			},
+			Spec: sourcev1.HelmRepositorySpec{
+				URL:      opts.repositoryURL,
+				Interval: interval,
+			},
+		}
+		obj.Spec.Chart = &v2.HelmChartTemplate{
+			Spec: v2.HelmChartTemplateSpec{
+				Chart:   rls.Chart.Name(),
+				Version: rls.Chart.Metadata.Version,
+				SourceRef: v2.CrossNamespaceObjectReference{
+					Kind: sourcev1.HelmRepositoryKind,
+					Name: repository.Name,
+				},
+			},
+		}
+		objs = append(objs, repository)
+	}
+
+	if len(rls.Config) > 0 {
+		if opts.valuesSecret {
+			values, err := yaml.Marshal(rls.Config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode values: %w", err)
+			}
+			secret := &corev1.Secret{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: corev1.SchemeGroupVersion.String(),
+					Kind:       "Secret",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      rls.Name + "-values",
+					Namespace: namespace,
+				},
+				StringData: map[string]string{"values.yaml": string(values)},
+			}
+			obj.Spec.ValuesFrom = []v2.ValuesReference{{Kind: "Secret", Name: secret.Name}}
+			objs = append(objs, secret)
+		} else {
+			values, err := json.Marshal(rls.Config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode values: %w", err)
+			}
+			obj.Spec.Values = &apiextensionsv1.JSON{Raw: values}
+		}
+	}
+
+	return append(objs, obj), nil
+}
+
+// writeObjects writes the given objects as YAML documents to w, omitting
+// their status and creation timestamp.
+func writeObjects(w io.Writer, objs []client.Object) error {
+	for _, obj := range objs {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return fmt.Errorf("failed to convert object: %w", err)
+		}
+		delete(u, "status")
+		if metadata, ok := u["metadata"].(map[string]interface{}); ok {
+			delete(metadata, "creationTimestamp")
+		}
+
+		b, err := yaml.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("failed to encode object: %w", err)
+		}
+		if _, err = fmt.Fprintf(w, "---\n%s", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
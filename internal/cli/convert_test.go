@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func Test_convertRelease(t *testing.T) {
+	rls := &helmrelease.Release{
+		Name:      "podinfo",
+		Namespace: "apps",
+		Chart:     testutil.BuildChart(testutil.ChartWithName("podinfo"), testutil.ChartWithVersion("6.5.3")),
+		Config:    map[string]interface{}{"replicas": 2},
+	}
+
+	tests := []struct {
+		name string
+		opts convertOptions
+		want string
+	}{
+		{
+			name: "HelmRepository with inlined values",
+			opts: convertOptions{
+				repositoryURL: "https://stefanprodan.github.io/podinfo",
+				sourceName:    "podinfo",
+				interval:      10 * time.Minute,
+			},
+			want: `---
+apiVersion: source.toolkit.fluxcd.io/v1
+kind: HelmRepository
+metadata:
+  name: podinfo
+  namespace: apps
+spec:
+  interval: 10m0s
+  url: https://stefanprodan.github.io/podinfo
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: podinfo
+  namespace: apps
+spec:
+  chart:
+    spec:
+      chart: podinfo
+      sourceRef:
+        kind: HelmRepository
+        name: podinfo
+      version: 6.5.3
+  interval: 10m0s
+  releaseName: podinfo
+  values:
+    replicas: 2
+`,
+		},
+		{
+			name: "OCIRepository with values Secret in other namespace",
+			opts: convertOptions{
+				repositoryURL: "oci://ghcr.io/stefanprodan/charts/",
+				namespace:     "flux-system",
+				interval:      time.Hour,
+				valuesSecret:  true,
+			},
+			want: `---
+apiVersion: source.toolkit.fluxcd.io/v1beta2
+kind: OCIRepository
+metadata:
+  name: podinfo
+  namespace: flux-system
+spec:
+  interval: 1h0m0s
+  layerSelector:
+    mediaType: application/vnd.cncf.helm.chart.content.v1.tar+gzip
+    operation: copy
+  ref:
+    tag: 6.5.3
+  url: oci://ghcr.io/stefanprodan/charts/podinfo
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: podinfo-values
+  namespace: flux-system
+stringData:
+  values.yaml: |
+    replicas: 2
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: podinfo
+  namespace: flux-system
+spec:
+  chartRef:
+    kind: OCIRepository
+    name: podinfo
+  interval: 1h0m0s
+  releaseName: podinfo
+  storageNamespace: apps
+  targetNamespace: apps
+  valuesFrom:
+  - kind: Secret
+    name: podinfo-values
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objs, err := convertRelease(rls, tt.opts)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			var out bytes.Buffer
+			g.Expect(writeObjects(&out, objs)).To(Succeed())
+			g.Expect(out.String()).To(Equal(tt.want))
+		})
+	}
+
+	t.Run("release without chart", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := convertRelease(&helmrelease.Release{Name: "podinfo"}, convertOptions{})
+		g.Expect(err).To(MatchError("release has no chart metadata"))
+	})
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmloader "helm.sh/helm/v3/pkg/chart/loader"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/pkg/runtime/transform"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+)
+
+// Render renders the manifest the controller would apply for a HelmRelease,
+// without access to a cluster. The HelmRelease is read from a file, the chart
+// from a local chart archive or directory, and the ConfigMaps and Secrets
+// referenced in the values references of the HelmRelease from files.
+//
+// The values are composed, and the post renderers applied, the same way as
+// the controller does. The data of any Secret in the manifest is redacted.
+func Render(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		objPath     string
+		chartPath   string
+		valuesFrom  []string
+		kubeVersion string
+		apiVersions []string
+		includeCRDs bool
+	)
+
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.StringVarP(&objPath, "helmrelease", "f", "",
+		"The path to the file containing the HelmRelease to render.")
+	fs.StringVar(&chartPath, "chart", "",
+		"The path to the chart archive or directory to render.")
+	fs.StringSliceVar(&valuesFrom, "values-from", nil,
+		"The path to a file containing the ConfigMaps and Secrets referenced in the values references of the HelmRelease. Can be repeated.")
+	fs.StringVar(&kubeVersion, "kube-version", "",
+		"The Kubernetes version used for Capabilities.KubeVersion.")
+	fs.StringSliceVarP(&apiVersions, "api-versions", "a", nil,
+		"The Kubernetes API versions used for Capabilities.APIVersions. Can be repeated.")
+	fs.BoolVar(&includeCRDs, "include-crds", false,
+		"Include the CRDs of the chart in the rendered manifest.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if objPath == "" || chartPath == "" {
+		return errors.New("both --helmrelease and --chart are required")
+	}
+
+	obj, err := readHelmRelease(objPath)
+	if err != nil {
+		return err
+	}
+
+	chrt, err := helmloader.Load(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load chart from '%s': %w", chartPath, err)
+	}
+
+	c, err := newValuesReader(obj.Namespace, valuesFrom)
+	if err != nil {
+		return err
+	}
+	values, err := chartutil.ChartValuesFromReferences(ctx, c, obj.Namespace, obj.GetValues(), obj.Spec.ValuesFrom...)
+	if err != nil {
+		return err
+	}
+	if len(obj.Spec.ValuesFiles) > 0 {
+		fileValues, err := chartutil.ChartValuesFromFiles(chrt, obj.Spec.ValuesFiles)
+		if err != nil {
+			return err
+		}
+		values = transform.MergeMaps(fileValues, values)
+	}
+
+	var capabilities *helmchartutil.KubeVersion
+	if kubeVersion != "" {
+		if capabilities, err = helmchartutil.ParseKubeVersion(kubeVersion); err != nil {
+			return fmt.Errorf("invalid Kubernetes version '%s': %w", kubeVersion, err)
+		}
+	}
+
+	config := &helmaction.Configuration{Log: func(string, ...interface{}) {}}
+	manifest, _, err := action.Render(ctx, config, obj, chrt, values, func(install *helmaction.Install) {
+		install.ClientOnly = true
+		install.DryRunOption = "client"
+		install.KubeVersion = capabilities
+		install.APIVersions = apiVersions
+		install.IncludeCRDs = includeCRDs
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	_, err = io.WriteString(stdout, manifest)
+	return err
+}
+
+// readHelmRelease reads the HelmRelease from the file at the given path. The
+// namespace of the HelmRelease defaults to "default".
+func readHelmRelease(path string) (*v2.HelmRelease, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HelmRelease: %w", err)
+	}
+
+	obj := &v2.HelmRelease{}
+	if err = yaml.UnmarshalStrict(b, obj); err != nil {
+		return nil, fmt.Errorf("failed to decode HelmRelease from '%s': %w", path, err)
+	}
+	if obj.Kind != v2.HelmReleaseKind || obj.APIVersion != v2.GroupVersion.String() {
+		return nil, fmt.Errorf("'%s' does not contain a %s %s", path, v2.GroupVersion.String(), v2.HelmReleaseKind)
+	}
+	if obj.Namespace == "" {
+		obj.Namespace = "default"
+	}
+	return obj, nil
+}
+
+// newValuesReader returns a valuesReader serving the ConfigMaps and Secrets
+// read from the files at the given paths. Objects without a namespace are
+// placed in the given namespace, and the string data of Secrets is merged
+// into their data as the API server would.
+func newValuesReader(namespace string, paths []string) (valuesReader, error) {
+	objs := make(valuesReader)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values references: %w", err)
+		}
+		objects, err := ssautil.ReadObjects(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode values references from '%s': %w", path, err)
+		}
+
+		for _, u := range objects {
+			var obj client.Object
+			switch u.GetObjectKind().GroupVersionKind() {
+			case corev1.SchemeGroupVersion.WithKind("ConfigMap"):
+				obj = &corev1.ConfigMap{}
+			case corev1.SchemeGroupVersion.WithKind("Secret"):
+				obj = &corev1.Secret{}
+			default:
+				return nil, fmt.Errorf("unsupported object '%s' in '%s': must be a ConfigMap or Secret", ssautil.FmtUnstructured(u), path)
+			}
+			if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+				return nil, fmt.Errorf("failed to decode '%s' from '%s': %w", ssautil.FmtUnstructured(u), path, err)
+			}
+			if secret, ok := obj.(*corev1.Secret); ok && len(secret.StringData) > 0 {
+				if secret.Data == nil {
+					secret.Data = make(map[string][]byte, len(secret.StringData))
+				}
+				for k, v := range secret.StringData {
+					secret.Data[k] = []byte(v)
+				}
+				secret.StringData = nil
+			}
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(namespace)
+			}
+			objs[valuesKey{kind: u.GetKind(), key: client.ObjectKeyFromObject(obj)}] = obj
+		}
+	}
+	return objs, nil
+}
+
+// valuesKey is the key of an object served by a valuesReader.
+type valuesKey struct {
+	kind string
+	key  client.ObjectKey
+}
+
+// valuesReader is a client.Reader serving the ConfigMaps and Secrets of
+// values references read from files.
+type valuesReader map[valuesKey]client.Object
+
+// Get copies the ConfigMap or Secret with the given key into the given
+// object, or returns a NotFound error if there is none.
+func (r valuesReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		stored, ok := r[valuesKey{kind: "ConfigMap", key: key}]
+		if !ok {
+			return apierrors.NewNotFound(corev1.Resource("configmaps"), key.Name)
+		}
+		stored.(*corev1.ConfigMap).DeepCopyInto(obj)
+	case *corev1.Secret:
+		stored, ok := r[valuesKey{kind: "Secret", key: key}]
+		if !ok {
+			return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+		}
+		stored.(*corev1.Secret).DeepCopyInto(obj)
+	default:
+		return fmt.Errorf("unsupported object type %T: must be a ConfigMap or Secret", obj)
+	}
+	return nil
+}
+
+// List is not supported, as values references are read by name.
+func (r valuesReader) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return errors.New("listing values references is not supported")
+}
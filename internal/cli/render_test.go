@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+
+	chrt := testutil.BuildChart()
+	chrt.Templates = append(chrt.Templates, &helmchart.File{
+		Name: "templates/values",
+		Data: []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: values
+data:
+  replicas: "{{ .Values.replicas }}"
+  image: "{{ .Values.image }}"
+`),
+	})
+	chartPath, err := testutil.SaveChart(chrt, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objPath := writeFile(t, dir, "helmrelease.yaml", `apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: podinfo
+  namespace: apps
+spec:
+  interval: 5m
+  chart:
+    spec:
+      chart: hello
+      sourceRef:
+        kind: HelmRepository
+        name: podinfo
+  values:
+    replicas: 2
+  valuesFrom:
+    - kind: Secret
+      name: values
+  postRenderers:
+    - kustomize:
+        patches:
+          - target:
+              kind: ConfigMap
+              name: values
+            patch: |
+              - op: add
+                path: /data/patched
+                value: "true"
+`)
+	valuesPath := writeFile(t, dir, "values.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: values
+stringData:
+  values.yaml: |
+    replicas: 1
+    image: podinfo:6.0.0
+`)
+
+	t.Run("renders HelmRelease", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var out bytes.Buffer
+		err := Render(context.TODO(), []string{"-f", objPath, "--chart", chartPath, "--values-from", valuesPath}, &out)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(out.String()).To(ContainSubstring(`  image: podinfo:6.0.0
+  patched: "true"
+  replicas: "2"`))
+		g.Expect(out.String()).To(ContainSubstring("helm.toolkit.fluxcd.io/name: podinfo"))
+		g.Expect(out.String()).To(ContainSubstring("namespace: apps"))
+	})
+
+	t.Run("fails on missing values reference", func(t *testing.T) {
+		g := NewWithT(t)
+
+		err := Render(context.TODO(), []string{"-f", objPath, "--chart", chartPath}, &bytes.Buffer{})
+		g.Expect(err).To(MatchError(ContainSubstring("could not resolve Secret chart values reference 'apps/values'")))
+	})
+
+	t.Run("fails on unsupported values reference object", func(t *testing.T) {
+		g := NewWithT(t)
+
+		path := writeFile(t, dir, "invalid.yaml", `apiVersion: v1
+kind: Service
+metadata:
+  name: values
+`)
+		err := Render(context.TODO(), []string{"-f", objPath, "--chart", chartPath, "--values-from", path}, &bytes.Buffer{})
+		g.Expect(err).To(MatchError(ContainSubstring("must be a ConfigMap or Secret")))
+	})
+
+	t.Run("requires HelmRelease and chart", func(t *testing.T) {
+		g := NewWithT(t)
+
+		err := Render(context.TODO(), []string{"-f", objPath}, &bytes.Buffer{})
+		g.Expect(err).To(MatchError("both --helmrelease and --chart are required"))
+	})
+}
+
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+// storageFunc returns the Helm storage for the given driver and namespace.
+type storageFunc func(driver, namespace string) (*helmstorage.Storage, error)
+
+// snapshot is the archived state of a HelmRelease: the object including its
+// status, and the records of its release in the Helm storage.
+type snapshot struct {
+	HelmRelease *v2.HelmRelease        `json:"helmRelease"`
+	Releases    []*helmrelease.Release `json:"releases,omitempty"`
+}
+
+// Export writes a gzipped tar archive with a snapshot of the HelmReleases in
+// a namespace of the cluster to stdout. A snapshot contains the HelmRelease
+// including its status history, and the records of its release in the Helm
+// storage.
+//
+// The archive can be imported with Import, to restore the HelmReleases in a
+// rebuilt cluster without installing their releases again.
+func Export(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		names         []string
+		allNamespaces bool
+	)
+
+	kubeConfig := genericclioptions.NewConfigFlags(false)
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	kubeConfig.AddFlags(fs)
+	fs.StringSliceVar(&names, "helmrelease", nil,
+		"The name of a HelmRelease to export. Can be repeated. Defaults to all HelmReleases in the namespace.")
+	fs.BoolVarP(&allNamespaces, "all-namespaces", "A", false,
+		"Export the HelmReleases in all namespaces.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := newClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+	var opts []client.ListOption
+	if !allNamespaces {
+		namespace, _, err := kubeConfig.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			return fmt.Errorf("failed to determine namespace: %w", err)
+		}
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	list := &v2.HelmReleaseList{}
+	if err = c.List(ctx, list, opts...); err != nil {
+		return fmt.Errorf("failed to list HelmReleases: %w", err)
+	}
+
+	var objs []*v2.HelmRelease
+	for i := range list.Items {
+		if len(names) > 0 && !slices.Contains(names, list.Items[i].Name) {
+			continue
+		}
+		objs = append(objs, &list.Items[i])
+	}
+	if len(objs) == 0 {
+		return errors.New("no HelmReleases to export")
+	}
+	return exportSnapshots(stdout, objs, newStorageFunc(kubeConfig), time.Now())
+}
+
+// Import restores the HelmReleases from an archive written by Export.
+//
+// The records of the releases are created in the Helm storage, unless a
+// record of the same release version exists. HelmReleases which do not exist
+// are created, and the status history is restored for HelmReleases without a
+// history. The HelmReleases are expected to not be reconciled by the
+// controller while they are imported.
+func Import(ctx context.Context, args []string, stdout io.Writer) error {
+	var archivePath string
+
+	kubeConfig := genericclioptions.NewConfigFlags(false)
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	kubeConfig.AddFlags(fs)
+	fs.StringVar(&archivePath, "archive", "",
+		"The path to the archive written by the export subcommand.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if archivePath == "" {
+		return errors.New("--archive is required")
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer f.Close()
+	snapshots, err := readSnapshots(f)
+	if err != nil {
+		return fmt.Errorf("failed to read archive '%s': %w", archivePath, err)
+	}
+
+	c, err := newClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+	newStorage := newStorageFunc(kubeConfig)
+	for _, snap := range snapshots {
+		if err = importSnapshot(ctx, c, newStorage, snap, stdout); err != nil {
+			return fmt.Errorf("failed to import HelmRelease '%s': %w", client.ObjectKeyFromObject(snap.HelmRelease), err)
+		}
+	}
+	return nil
+}
+
+// newClient returns a client for the cluster configured with the given
+// flags, which is able to handle HelmReleases.
+func newClient(kubeConfig *genericclioptions.ConfigFlags) (client.Client, error) {
+	cfg, err := kubeConfig.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	scheme := runtime.NewScheme()
+	if err = v2.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// newStorageFunc returns a storageFunc for the cluster configured with the
+// given flags.
+func newStorageFunc(kubeConfig *genericclioptions.ConfigFlags) storageFunc {
+	return func(driver, namespace string) (*helmstorage.Storage, error) {
+		cfg, err := action.NewConfigFactory(kubeConfig, action.WithStorage(driver, namespace))
+		if err != nil {
+			return nil, err
+		}
+		return cfg.NewStorage(), nil
+	}
+}
+
+// storageOf returns the Helm storage driver and namespace of the release of
+// the given HelmRelease, preferring the ones recorded in its status.
+func storageOf(obj *v2.HelmRelease) (string, string) {
+	driver, namespace := obj.Status.StorageDriver, obj.Status.StorageNamespace
+	if driver == "" {
+		driver = obj.Spec.StorageDriver
+	}
+	if namespace == "" {
+		namespace = obj.GetStorageNamespace()
+	}
+	return driver, namespace
+}
+
+// exportSnapshots writes a snapshot of each of the given HelmReleases to w,
+// as JSON files named after their namespace and name in a gzipped tar
+// archive.
+func exportSnapshots(w io.Writer, objs []*v2.HelmRelease, newStorage storageFunc, modTime time.Time) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, obj := range objs {
+		driver, namespace := storageOf(obj)
+		s, err := newStorage(driver, namespace)
+		if err != nil {
+			return err
+		}
+		releases, err := s.History(release.ShortenName(obj.GetReleaseName()))
+		if err != nil && !errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return fmt.Errorf("failed to read release history of HelmRelease '%s': %w", client.ObjectKeyFromObject(obj), err)
+		}
+		slices.SortFunc(releases, func(a, b *helmrelease.Release) int { return a.Version - b.Version })
+
+		obj = obj.DeepCopy()
+		obj.APIVersion = v2.GroupVersion.String()
+		obj.Kind = v2.HelmReleaseKind
+		obj.ManagedFields = nil
+
+		b, err := json.Marshal(snapshot{HelmRelease: obj, Releases: releases})
+		if err != nil {
+			return fmt.Errorf("failed to encode snapshot of HelmRelease '%s': %w", client.ObjectKeyFromObject(obj), err)
+		}
+		if err = tw.WriteHeader(&tar.Header{
+			Name:    path.Join(obj.Namespace, obj.Name+".json"),
+			Mode:    0o600,
+			Size:    int64(len(b)),
+			ModTime: modTime,
+		}); err != nil {
+			return err
+		}
+		if _, err = tw.Write(b); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// readSnapshots reads the snapshots from a gzipped tar archive written by
+// exportSnapshots.
+func readSnapshots(r io.Reader) ([]snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	var snapshots []snapshot
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		var snap snapshot
+		if err = json.NewDecoder(tr).Decode(&snap); err != nil {
+			return nil, fmt.Errorf("failed to decode '%s': %w", hdr.Name, err)
+		}
+		if snap.HelmRelease == nil || snap.HelmRelease.Name == "" || snap.HelmRelease.Namespace == "" {
+			return nil, fmt.Errorf("'%s' does not contain a HelmRelease", hdr.Name)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, nil
+}
+
+// importSnapshot restores the given snapshot, and writes a summary of the
+// changes to w.
+func importSnapshot(ctx context.Context, c client.Client, newStorage storageFunc, snap snapshot, w io.Writer) error {
+	driver, namespace := storageOf(snap.HelmRelease)
+	s, err := newStorage(driver, namespace)
+	if err != nil {
+		return err
+	}
+	var created int
+	for _, rls := range snap.Releases {
+		if _, err = s.Get(rls.Name, rls.Version); err == nil {
+			continue
+		} else if !errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return fmt.Errorf("failed to read release '%s' (version %d): %w", rls.Name, rls.Version, err)
+		}
+		if err = s.Create(rls); err != nil {
+			return fmt.Errorf("failed to create release '%s' (version %d): %w", rls.Name, rls.Version, err)
+		}
+		created++
+	}
+
+	key := client.ObjectKeyFromObject(snap.HelmRelease)
+	obj := &v2.HelmRelease{}
+	if err = c.Get(ctx, key, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		obj = snap.HelmRelease.DeepCopy()
+		obj.ObjectMeta = metav1.ObjectMeta{
+			Name:        obj.Name,
+			Namespace:   obj.Namespace,
+			Labels:      obj.Labels,
+			Annotations: obj.Annotations,
+		}
+		obj.Status = v2.HelmReleaseStatus{}
+		if err = c.Create(ctx, obj); err != nil {
+			return err
+		}
+	}
+
+	restored := len(obj.Status.History) == 0 && len(snap.HelmRelease.Status.History) > 0
+	if restored {
+		obj.Status.History = snap.HelmRelease.Status.History
+		obj.Status.StorageNamespace = snap.HelmRelease.Status.StorageNamespace
+		obj.Status.StorageDriver = snap.HelmRelease.Status.StorageDriver
+		obj.Status.DigestAlgorithm = snap.HelmRelease.Status.DigestAlgorithm
+		if err = c.Status().Update(ctx, obj); err != nil {
+			return fmt.Errorf("failed to restore status: %w", err)
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "HelmRelease '%s' imported: %d of %d release(s) created, status history restored: %t\n",
+		key, created, len(snap.Releases), restored)
+	return err
+}
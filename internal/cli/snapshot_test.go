@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestExportImportSnapshots(t *testing.T) {
+	g := NewWithT(t)
+
+	rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      "podinfo",
+		Namespace: "apps",
+		Version:   2,
+		Status:    helmrelease.StatusDeployed,
+	})
+	prev := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      "podinfo",
+		Namespace: "apps",
+		Version:   1,
+		Status:    helmrelease.StatusSuperseded,
+	})
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "podinfo",
+			Namespace:       "apps",
+			ResourceVersion: "42",
+			Labels:          map[string]string{"app": "podinfo"},
+		},
+		Spec: v2.HelmReleaseSpec{
+			Interval: metav1.Duration{Duration: time.Minute},
+		},
+		Status: v2.HelmReleaseStatus{
+			StorageNamespace: "apps",
+			History: v2.Snapshots{
+				release.ObservedToSnapshot(release.ObserveRelease(rls)),
+				release.ObservedToSnapshot(release.ObserveRelease(prev)),
+			},
+		},
+	}
+
+	source := helmstorage.Init(helmdriver.NewMemory())
+	g.Expect(source.Create(prev)).To(Succeed())
+	g.Expect(source.Create(rls)).To(Succeed())
+
+	var archive bytes.Buffer
+	g.Expect(exportSnapshots(&archive, []*v2.HelmRelease{obj}, func(driver, namespace string) (*helmstorage.Storage, error) {
+		g.Expect(namespace).To(Equal("apps"))
+		return source, nil
+	}, time.Now())).To(Succeed())
+
+	snapshots, err := readSnapshots(&archive)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].HelmRelease.Kind).To(Equal(v2.HelmReleaseKind))
+	g.Expect(snapshots[0].Releases).To(HaveLen(2))
+	g.Expect(snapshots[0].Releases[0].Version).To(Equal(1))
+
+	// Import into a rebuilt cluster in which the HelmRelease does not exist.
+	scheme := runtime.NewScheme()
+	g.Expect(v2.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v2.HelmRelease{}).Build()
+	target := helmstorage.Init(helmdriver.NewMemory())
+	newStorage := func(string, string) (*helmstorage.Storage, error) { return target, nil }
+
+	var out bytes.Buffer
+	g.Expect(importSnapshot(context.TODO(), c, newStorage, snapshots[0], &out)).To(Succeed())
+	g.Expect(out.String()).To(Equal("HelmRelease 'apps/podinfo' imported: 2 of 2 release(s) created, status history restored: true\n"))
+
+	got := &v2.HelmRelease{}
+	g.Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(obj), got)).To(Succeed())
+	g.Expect(got.Labels).To(Equal(obj.Labels))
+	g.Expect(got.Spec).To(Equal(obj.Spec))
+	g.Expect(got.Status.History).To(HaveLen(2))
+	g.Expect(got.Status.History.Latest().Digest).To(Equal(obj.Status.History.Latest().Digest))
+
+	// The restored history matches the releases in the Helm storage.
+	deployed, err := target.Deployed("podinfo")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(release.ObservedToSnapshot(release.ObserveRelease(deployed)).Digest).To(Equal(got.Status.History.Latest().Digest))
+
+	// Importing again leaves the existing releases and history untouched.
+	out.Reset()
+	g.Expect(importSnapshot(context.TODO(), c, newStorage, snapshots[0], &out)).To(Succeed())
+	g.Expect(out.String()).To(Equal("HelmRelease 'apps/podinfo' imported: 0 of 2 release(s) created, status history restored: false\n"))
+}
+
+func TestExportSnapshots_longReleaseName(t *testing.T) {
+	g := NewWithT(t)
+
+	// The release name exceeds the maximum length of a Helm release name,
+	// and is stored under its shortened name.
+	releaseName := strings.Repeat("a", 60)
+	rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      release.ShortenName(releaseName),
+		Namespace: "apps",
+		Version:   1,
+		Status:    helmrelease.StatusDeployed,
+	})
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "podinfo",
+			Namespace: "apps",
+		},
+		Spec: v2.HelmReleaseSpec{
+			ReleaseName: releaseName,
+		},
+	}
+
+	source := helmstorage.Init(helmdriver.NewMemory())
+	g.Expect(source.Create(rls)).To(Succeed())
+
+	var archive bytes.Buffer
+	g.Expect(exportSnapshots(&archive, []*v2.HelmRelease{obj}, func(string, string) (*helmstorage.Storage, error) {
+		return source, nil
+	}, time.Now())).To(Succeed())
+
+	snapshots, err := readSnapshots(&archive)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].Releases).To(HaveLen(1))
+	g.Expect(snapshots[0].Releases[0].Name).To(Equal(release.ShortenName(releaseName)))
+}
+
+func Test_readSnapshots(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := readSnapshots(bytes.NewBufferString("invalid"))
+	g.Expect(err).To(HaveOccurred())
+
+	var archive bytes.Buffer
+	g.Expect(exportSnapshots(&archive, nil, nil, time.Now())).To(Succeed())
+	snapshots, err := readSnapshots(&archive)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(BeEmpty())
+}
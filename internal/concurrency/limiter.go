@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package concurrency provides a limiter for the number of concurrent
+// operations, of which the limit can be changed at runtime.
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// Limiter limits the number of concurrent operations. Its limit can be
+// changed while operations are active: lowering it does not interrupt
+// active operations, but delays new ones until the number of active
+// operations is below the new limit.
+type Limiter struct {
+	mu     sync.Mutex
+	limit  int
+	active int
+	// changed is closed and replaced when an operation is released or the
+	// limit is changed, to wake up the waiting operations.
+	changed chan struct{}
+}
+
+// NewLimiter returns a new Limiter with the given limit. A limit of zero or
+// less does not limit the number of concurrent operations.
+func NewLimiter(limit int) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		changed: make(chan struct{}),
+	}
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// SetLimit sets the limit. A limit of zero or less does not limit the number
+// of concurrent operations.
+func (l *Limiter) SetLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.notify()
+}
+
+// Acquire waits until the operation may start, or the context is canceled.
+// The returned function must be called to release the operation when it has
+// finished.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	for {
+		l.mu.Lock()
+		if l.limit <= 0 || l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(l.release) }, nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.notify()
+}
+
+// notify wakes up the waiting operations. It must be called with the lock
+// held.
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("limits concurrent operations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		l := NewLimiter(1)
+		release, err := l.Acquire(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		acquired := make(chan func())
+		go func() {
+			r, _ := l.Acquire(context.TODO())
+			acquired <- r
+		}()
+		g.Consistently(acquired, 100*time.Millisecond).ShouldNot(Receive())
+
+		release()
+		// Releasing twice is a no-op.
+		release()
+		var r func()
+		g.Eventually(acquired, time.Second).Should(Receive(&r))
+		r()
+	})
+
+	t.Run("raising limit starts waiting operations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		l := NewLimiter(1)
+		_, err := l.Acquire(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		acquired := make(chan func())
+		go func() {
+			r, _ := l.Acquire(context.TODO())
+			acquired <- r
+		}()
+		g.Consistently(acquired, 100*time.Millisecond).ShouldNot(Receive())
+
+		l.SetLimit(2)
+		g.Eventually(acquired, time.Second).Should(Receive())
+	})
+
+	t.Run("lowering limit delays new operations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		l := NewLimiter(2)
+		release, err := l.Acquire(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		l.SetLimit(1)
+		ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cancel()
+		_, err = l.Acquire(ctx)
+		g.Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		release()
+		_, err = l.Acquire(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		g := NewWithT(t)
+
+		l := NewLimiter(0)
+		for i := 0; i < 10; i++ {
+			_, err := l.Acquire(context.TODO())
+			g.Expect(err).ToNot(HaveOccurred())
+		}
+	})
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config provides the configuration file of the controller, which
+// is reloaded without restarting the controller when it changes.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/helm-controller/internal/features"
+)
+
+// Config is the configuration of the controller which can be changed at
+// runtime. Fields which are not set fall back to the value of their
+// command-line flag.
+type Config struct {
+	// Concurrent is the number of concurrent HelmRelease reconciles. It can
+	// not exceed the maximum set at startup.
+	Concurrent *int `json:"concurrent,omitempty"`
+	// ConcurrentDriftDetection is the number of concurrent drift
+	// detections. When zero, they are only limited by Concurrent.
+	ConcurrentDriftDetection *int `json:"concurrentDriftDetection,omitempty"`
+	// DefaultTimeout is the timeout of the Helm actions of HelmReleases
+	// which do not configure a timeout.
+	DefaultTimeout *metav1.Duration `json:"defaultTimeout,omitempty"`
+	// FeatureGates are the states of the feature gates which can be
+	// changed at runtime, overriding their state set at startup.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// EventsDedupInterval is the interval during which identical warning
+	// events for a HelmRelease are only recorded once.
+	EventsDedupInterval *metav1.Duration `json:"eventsDedupInterval,omitempty"`
+	// RegistryMirrors maps the domain of a container registry to the domain
+	// of the mirror the images are retrieved from for image signature
+	// verification.
+	RegistryMirrors map[string]string `json:"registryMirrors,omitempty"`
+	// Freeze holds all mutating Helm actions while set, e.g. during a change
+	// freeze around cluster maintenance.
+	Freeze *Freeze `json:"freeze,omitempty"`
+}
+
+// Freeze is a cluster-wide change freeze.
+type Freeze struct {
+	// Reason is the reason of the freeze, which is included in the Frozen
+	// condition of the held HelmReleases.
+	Reason string `json:"reason,omitempty"`
+	// Until is the time at which the freeze is lifted automatically.
+	Until *metav1.Time `json:"until,omitempty"`
+}
+
+// Load reads a Config from the YAML file at the given path, and validates
+// it.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read controller configuration: %w", err)
+	}
+	return decode(path, b)
+}
+
+// decode decodes and validates a Config read from the given path.
+func decode(path string, b []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("failed to decode controller configuration from '%s': %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid controller configuration in '%s': %w", path, err)
+	}
+	return c, nil
+}
+
+// Validate returns an error if any of the fields of the Config is invalid.
+func (c *Config) Validate() error {
+	if c.Concurrent != nil && *c.Concurrent < 1 {
+		return fmt.Errorf("concurrent '%d' must be greater than 0", *c.Concurrent)
+	}
+	if c.ConcurrentDriftDetection != nil && *c.ConcurrentDriftDetection < 0 {
+		return fmt.Errorf("concurrentDriftDetection '%d' must not be negative", *c.ConcurrentDriftDetection)
+	}
+	if c.DefaultTimeout != nil && c.DefaultTimeout.Duration <= 0 {
+		return fmt.Errorf("defaultTimeout '%s' must be greater than 0", c.DefaultTimeout.Duration)
+	}
+	if c.EventsDedupInterval != nil && c.EventsDedupInterval.Duration < 0 {
+		return fmt.Errorf("eventsDedupInterval '%s' must not be negative", c.EventsDedupInterval.Duration)
+	}
+	if err := features.ValidateReloadable(c.FeatureGates); err != nil {
+		return err
+	}
+	for registry, mirror := range c.RegistryMirrors {
+		if registry == "" || strings.Contains(registry, "/") {
+			return fmt.Errorf("registry mirror for '%s' must be keyed by a registry domain", registry)
+		}
+		if mirror == "" || strings.Contains(mirror, "/") {
+			return fmt.Errorf("registry mirror '%s' for '%s' must be a registry domain", mirror, registry)
+		}
+	}
+	return nil
+}
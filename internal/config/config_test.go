@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/helm-controller/internal/features"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "valid config",
+			config: `concurrent: 10
+concurrentDriftDetection: 2
+defaultTimeout: 10m
+featureGates:
+  AllowDNSLookups: true
+eventsDedupInterval: 1h
+registryMirrors:
+  docker.io: mirror.example.com
+freeze:
+  reason: cluster upgrade
+  until: "2024-05-06T10:00:00Z"
+`,
+		},
+		{
+			name:   "empty config",
+			config: "",
+		},
+		{
+			name:    "unknown field",
+			config:  "unknown: true",
+			wantErr: "failed to decode controller configuration",
+		},
+		{
+			name:    "invalid concurrent",
+			config:  "concurrent: 0",
+			wantErr: "concurrent '0' must be greater than 0",
+		},
+		{
+			name:    "negative concurrent drift detection",
+			config:  "concurrentDriftDetection: -1",
+			wantErr: "concurrentDriftDetection '-1' must not be negative",
+		},
+		{
+			name:    "invalid default timeout",
+			config:  "defaultTimeout: 0s",
+			wantErr: "defaultTimeout '0s' must be greater than 0",
+		},
+		{
+			name:    "negative events dedup interval",
+			config:  "eventsDedupInterval: -1s",
+			wantErr: "eventsDedupInterval '-1s' must not be negative",
+		},
+		{
+			name:    "feature gate not reloadable",
+			config:  "featureGates:\n  " + features.CacheSecretsAndConfigMaps + ": true",
+			wantErr: "can not be changed at runtime",
+		},
+		{
+			name:    "invalid registry mirror",
+			config:  "registryMirrors:\n  docker.io: mirror.example.com/docker",
+			wantErr: "must be a registry domain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			g.Expect(os.WriteFile(path, []byte(tt.config), 0o644)).To(Succeed())
+
+			_, err := Load(path)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	g.Expect(os.WriteFile(path, []byte("concurrent: 1"), 0o644)).To(Succeed())
+
+	applied := make(chan *Config, 10)
+	w := NewWatcher(path, func(c *Config) { applied <- c }, logr.Discard())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = w.Start(ctx)
+	}()
+
+	var c *Config
+	g.Eventually(applied, time.Second).Should(Receive(&c))
+	g.Expect(c.Concurrent).To(HaveValue(Equal(1)))
+
+	// An invalid config is not applied.
+	g.Expect(os.WriteFile(path, []byte("concurrent: 0"), 0o644)).To(Succeed())
+	g.Consistently(applied, 500*time.Millisecond).ShouldNot(Receive())
+
+	g.Expect(os.WriteFile(path, []byte("concurrent: 2"), 0o644)).To(Succeed())
+	g.Eventually(applied, time.Second).Should(Receive(&c))
+	g.Expect(c.Concurrent).To(HaveValue(Equal(2)))
+
+	// A SIGHUP applies the config, even when unchanged.
+	g.Expect(syscall.Kill(os.Getpid(), syscall.SIGHUP)).To(Succeed())
+	g.Eventually(applied, time.Second).Should(Receive(&c))
+	g.Expect(c.Concurrent).To(HaveValue(Equal(2)))
+}
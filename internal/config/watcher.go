@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+)
+
+// reloadDelay is the delay after the last change of the configuration file
+// before it is reloaded, to not apply a partially written file.
+const reloadDelay = 100 * time.Millisecond
+
+// Watcher watches the configuration file of the controller, and applies the
+// Config when the file changes, or when the process receives a SIGHUP. An
+// invalid Config is not applied, leaving the previously applied Config in
+// effect.
+type Watcher struct {
+	path   string
+	apply  func(*Config)
+	logger logr.Logger
+
+	last []byte
+}
+
+// NewWatcher returns a Watcher which applies the Config from the file at the
+// given path with the given func when the file changes.
+func NewWatcher(path string, apply func(*Config), logger logr.Logger) *Watcher {
+	return &Watcher{
+		path:   path,
+		apply:  apply,
+		logger: logger,
+	}
+}
+
+// Start applies the Config, and watches the configuration file until the
+// context is canceled. It implements manager.Runnable.
+func (w *Watcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch controller configuration: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the directory instead of the file, as a mounted ConfigMap is
+	// updated by atomically replacing a symlink in the directory.
+	if err = watcher.Add(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("failed to watch controller configuration: %w", err)
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	w.reload(false)
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			timer.Reset(reloadDelay)
+		case <-timer.C:
+			w.reload(false)
+		case <-hangup:
+			w.reload(true)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Error(err, "failed to watch controller configuration")
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, to apply
+// the Config on all replicas of the controller.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// reload applies the Config if the file changed since it was last applied,
+// was not applied before, or if forced.
+func (w *Watcher) reload(force bool) {
+	b, err := os.ReadFile(w.path)
+	if err != nil {
+		w.logger.Error(err, "failed to read controller configuration")
+		return
+	}
+	if !force && w.last != nil && bytes.Equal(b, w.last) {
+		return
+	}
+	c, err := decode(w.path, b)
+	if err != nil {
+		w.logger.Error(err, "ignoring controller configuration")
+		return
+	}
+	w.last = b
+	w.apply(c)
+	w.logger.Info("applied controller configuration")
+}
@@ -20,24 +20,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	helmaction "helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	apierrutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/rest"
 	kuberecorder "k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
@@ -55,32 +63,54 @@ import (
 	"github.com/fluxcd/pkg/runtime/object"
 	"github.com/fluxcd/pkg/runtime/patch"
 	"github.com/fluxcd/pkg/runtime/predicates"
+	"github.com/fluxcd/pkg/runtime/transform"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	intacl "github.com/fluxcd/helm-controller/internal/acl"
 	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/actionhook"
+	"github.com/fluxcd/helm-controller/internal/chartscan"
 	"github.com/fluxcd/helm-controller/internal/chartutil"
+	"github.com/fluxcd/helm-controller/internal/concurrency"
 	"github.com/fluxcd/helm-controller/internal/digest"
 	interrors "github.com/fluxcd/helm-controller/internal/errors"
 	"github.com/fluxcd/helm-controller/internal/features"
+	"github.com/fluxcd/helm-controller/internal/freeze"
+	"github.com/fluxcd/helm-controller/internal/health"
+	"github.com/fluxcd/helm-controller/internal/interrupt"
 	"github.com/fluxcd/helm-controller/internal/kube"
 	"github.com/fluxcd/helm-controller/internal/loader"
+	intlogger "github.com/fluxcd/helm-controller/internal/logger"
+	"github.com/fluxcd/helm-controller/internal/metrics"
+	"github.com/fluxcd/helm-controller/internal/outbound"
 	"github.com/fluxcd/helm-controller/internal/postrender"
 	intpredicates "github.com/fluxcd/helm-controller/internal/predicates"
+	"github.com/fluxcd/helm-controller/internal/preview"
 	intreconcile "github.com/fluxcd/helm-controller/internal/reconcile"
 	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/rollout"
+	"github.com/fluxcd/helm-controller/internal/schedule"
+	"github.com/fluxcd/helm-controller/internal/signature"
+	"github.com/fluxcd/helm-controller/internal/tracing"
 )
 
 // +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases/finalizers,verbs=get;create;update;patch;delete
-// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=helmcharts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=helmcharts,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=helmcharts/status,verbs=get
-// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=ocirepositories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=helmrepositories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=ocirepositories,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=ocirepositories/status,verbs=get
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get
+// +kubebuilder:rbac:groups="",resources=serviceaccounts/token,verbs=create
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 // HelmReleaseReconciler reconciles a HelmRelease object.
 type HelmReleaseReconciler struct {
@@ -88,52 +118,106 @@ type HelmReleaseReconciler struct {
 	kuberecorder.EventRecorder
 	helper.Metrics
 
+	// APIReader reads objects directly from the API server, for objects
+	// which are not watched by the controller.
+	APIReader client.Reader
+
 	GetClusterConfig func() (*rest.Config, error)
 	ClientOpts       runtimeClient.Options
 	KubeConfigOpts   runtimeClient.KubeConfigOptions
 
 	FieldManager          string
 	DefaultServiceAccount string
-
+	// ServiceAccountTokens issues the tokens used to authenticate as the
+	// service account of a HelmRelease targeting the cluster of the
+	// controller. When nil, the service account is impersonated.
+	ServiceAccountTokens *kube.TokenCache
+	// StorageDriver is the name of the Helm storage driver used to store
+	// the releases. When empty, action.DefaultStorageDriver is used.
+	StorageDriver string
+	// StorageLabels and StorageAnnotations are added to the Helm storage
+	// Secrets of all releases, and can be extended per HelmRelease.
+	StorageLabels      map[string]string
+	StorageAnnotations map[string]string
+	// ActionWebhookClient calls the release action webhooks of the
+	// HelmReleases. When nil, the webhooks are not called.
+	ActionWebhookClient *outbound.Client
+
+	rateLimiter          ratelimiter.RateLimiter
+	retryDelay           helper.RateLimiterOptions
 	requeueDependency    time.Duration
 	artifactFetchRetries int
+	drainTimeout         time.Duration
+	concurrency          *concurrency.Limiter
+	rolloutGroups        *rollout.Groups
+	artifactHost         *health.ArtifactHost
+	interrupts           *interrupt.Tracker
+	interruptedPolicy    intreconcile.InterruptedReleasePolicy
 }
 
 type HelmReleaseReconcilerOptions struct {
 	HTTPRetry                 int
 	DependencyRequeueInterval time.Duration
+	DrainTimeout              time.Duration
 	RateLimiter               ratelimiter.RateLimiter
+	// RateLimiterOptions are the options the RateLimiter has been
+	// configured with, used to determine the time of the next retry of a
+	// failed reconciliation.
+	RateLimiterOptions helper.RateLimiterOptions
+	// Concurrency limits the number of concurrent reconciles below the
+	// number of workers of the controller, and allows changing it at
+	// runtime. When nil, it is only limited by the number of workers.
+	Concurrency *concurrency.Limiter
+	// RolloutGroups coordinates the upgrades of the HelmReleases labeled
+	// with v2.RolloutGroupLabel. When nil, the label is ignored.
+	RolloutGroups *rollout.Groups
+	// ArtifactHost records the host of the chart artifacts for the
+	// readiness check of the controller. When nil, it is not recorded.
+	ArtifactHost *health.ArtifactHost
+	// InterruptedReleasePolicy is the policy for the remediation of a
+	// release left in a pending state by an interrupted action, e.g. due to
+	// a crash of the controller.
+	InterruptedReleasePolicy intreconcile.InterruptedReleasePolicy
 }
 
 var (
 	errWaitForDependency = errors.New("must wait for dependency")
 	errWaitForChart      = errors.New("must wait for chart")
+	errRetryUninstall    = errors.New("must retry uninstall")
 )
 
 func (r *HelmReleaseReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, opts HelmReleaseReconcilerOptions) error {
-	// Index the HelmRelease by the Source reference they point to.
-	if err := mgr.GetFieldIndexer().IndexField(ctx, &v2.HelmRelease{}, v2.SourceIndexKey,
-		func(o client.Object) []string {
-			obj := o.(*v2.HelmRelease)
-			namespacedName, err := getNamespacedName(obj)
-			if err != nil {
-				return nil
-			}
-			return []string{
-				namespacedName.String(),
-			}
-		},
-	); err != nil {
+	// Index the HelmRelease by the kind and name of the Source they point to.
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v2.HelmRelease{}, v2.SourceIndexKey, indexBySource); err != nil {
+		return err
+	}
+	// Index the sources by the name of the Secret with their credentials.
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &sourcev1beta2.OCIRepository{}, secretRefIndexKey, indexOCIRepositoryBySecretRef); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &sourcev1.HelmRepository{}, secretRefIndexKey, indexHelmRepositoryBySecretRef); err != nil {
 		return err
 	}
 
 	r.requeueDependency = opts.DependencyRequeueInterval
 	r.artifactFetchRetries = opts.HTTPRetry
-
-	return ctrl.NewControllerManagedBy(mgr).
+	r.drainTimeout = opts.DrainTimeout
+	r.rateLimiter = opts.RateLimiter
+	r.retryDelay = opts.RateLimiterOptions
+	r.concurrency = opts.Concurrency
+	r.rolloutGroups = opts.RolloutGroups
+	r.artifactHost = opts.ArtifactHost
+	r.interrupts = interrupt.NewTracker()
+	r.interruptedPolicy = opts.InterruptedReleasePolicy
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&v2.HelmRelease{}, builder.WithPredicates(
 			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}),
 		)).
+		Watches(
+			&v2.HelmRelease{},
+			handler.Funcs{UpdateFunc: r.interruptObsoleteAction},
+		).
 		Watches(
 			&sourcev1.HelmChart{},
 			handler.EnqueueRequestsFromMapFunc(r.requestsForHelmChartChange),
@@ -144,22 +228,62 @@ func (r *HelmReleaseReconciler) SetupWithManager(ctx context.Context, mgr ctrl.M
 			handler.EnqueueRequestsFromMapFunc(r.requestsForOCIRrepositoryChange),
 			builder.WithPredicates(intpredicates.SourceRevisionChangePredicate{}),
 		).
-		WithOptions(controller.Options{
-			RateLimiter: opts.RateLimiter,
-		}).
-		Complete(r)
+		WatchesMetadata(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForPullSecretChange),
+			builder.WithPredicates(pullSecretPredicate, predicate.ResourceVersionChangedPredicate{}),
+		)
+
+	// Requeue the HelmReleases denied to release to other namespaces when
+	// their namespace is (un)labeled as privileged.
+	if intacl.EnforceTenantNamespaces {
+		b = b.WatchesMetadata(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForNamespaceChange),
+			builder.WithPredicates(privilegedNamespacePredicate),
+		)
+	}
+
+	return b.WithOptions(controller.Options{
+		RateLimiter: opts.RateLimiter,
+	}).Complete(r)
 }
 
 func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, retErr error) {
+	if r.concurrency != nil {
+		done, err := r.concurrency.Acquire(ctx)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		defer done()
+	}
+
 	start := time.Now()
 	log := ctrl.LoggerFrom(ctx)
 
+	ctx, span := tracing.Tracer().Start(ctx, "reconcile", trace.WithAttributes(
+		tracing.ObjectNameKey.String(req.Name),
+		tracing.ObjectNamespaceKey.String(req.Namespace),
+	))
+	defer func() { tracing.EndSpan(span, retErr) }()
+
 	// Fetch the HelmRelease
 	obj := &v2.HelmRelease{}
 	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			metrics.DeleteReleaseInfo(req.Name, req.Namespace)
+			metrics.DeleteSuspendInfo(req.Name, req.Namespace)
+			metrics.DeleteChartSecurityScan(req.Name, req.Namespace)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Raise the log verbosity for this object if requested.
+	if v2.IsDebugEnabled(obj) {
+		log = intlogger.Verbose(log, logger.DebugLevel)
+		ctx = ctrl.LoggerInto(ctx, log)
+	}
+
 	if !isValidChartRef(obj) {
 		return ctrl.Result{}, reconcile.TerminalError(fmt.Errorf("invalid Chart reference"))
 	}
@@ -187,7 +311,27 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		// However, not returning an error will cause the patch helper to
 		// patch the observed generation, which we do not want. So we ignore
 		// these errors here after patching.
-		retErr = interrors.Ignore(retErr, errWaitForDependency, errWaitForChart)
+		retErr = interrors.Ignore(retErr, errWaitForDependency, errWaitForChart,
+			intreconcile.ErrWaitForRolloutGroup, intreconcile.ErrWaitForCanary, intreconcile.ErrWaitForApproval,
+			intreconcile.ErrFrozen, errRetryUninstall)
+
+		// In accordance with kstatus, indicate the object is being retried
+		// after a failure which does not require intervention.
+		if retErr != nil && !errors.Is(retErr, reconcile.TerminalError(nil)) && conditions.IsReconciling(obj) {
+			conditions.MarkReconciling(obj, meta.ProgressingWithRetryReason, "%s", conditions.GetMessage(obj, meta.ReadyCondition))
+		}
+
+		// Reconcile at the next time of the schedule, if it comes first.
+		result = requeueForSchedule(obj, result, time.Now())
+
+		// Record when the object is expected to be reconciled next.
+		obj.Status.NextReconcileAt = r.nextReconcileAt(req, result, retErr)
+
+		// Compact the status after all events have been recorded, as the
+		// events then contain the full messages.
+		if compact, _ := features.Enabled(features.CompactStatus); compact {
+			intreconcile.CompactStatus(obj)
+		}
 
 		if err := patchHelper.Patch(ctx, obj, patchOpts...); err != nil {
 			if !obj.DeletionTimestamp.IsZero() {
@@ -206,6 +350,10 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 		// Record the duration of the reconciliation.
 		r.Metrics.RecordDuration(ctx, obj, start)
+
+		// Record the information about the latest release.
+		metrics.RecordReleaseInfo(obj)
+		metrics.RecordSuspendInfo(obj)
 	}()
 
 	// Examine if the object is under deletion.
@@ -225,11 +373,41 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Return early if the object is suspended.
 	if obj.Spec.Suspend {
 		log.Info("reconciliation is suspended for this object")
+		markSuspended(obj)
+
+		// Continue to report the availability of upgrades, as the source
+		// is still reconciled while the object is suspended.
+		if source, err := r.getSource(ctx, obj); err == nil {
+			observeUpgradeAvailable(obj, source)
+		}
 		return ctrl.Result{}, nil
 	}
+	conditions.Delete(obj, v2.SuspendedCondition)
+
+	// Confirm the reconcile schedule is valid.
+	if _, err := schedule.ForHelmRelease(obj); err != nil {
+		conditions.MarkStalled(obj, v2.InvalidScheduleReason, "%s", err.Error())
+		conditions.MarkFalse(obj, meta.ReadyCondition, v2.InvalidScheduleReason, "%s", err.Error())
+		conditions.Delete(obj, meta.ReconcilingCondition)
+		r.Eventf(obj, corev1.EventTypeWarning, v2.InvalidScheduleReason, "%s", err.Error())
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	}
+
+	// Confirm the object is allowed to release to its target and storage
+	// namespace. A change of the privileged label of the namespace requeues
+	// the object, see requestsForNamespaceChange.
+	if err := intacl.AllowsReleaseNamespaces(ctx, r.Client, obj); err != nil {
+		if acl.IsAccessDenied(err) {
+			return r.accessDenied(obj, err)
+		}
+		return ctrl.Result{}, err
+	}
 
 	// Reconcile the HelmChart template.
 	if err := r.reconcileChartTemplate(ctx, obj); err != nil {
+		if acl.IsAccessDenied(err) {
+			return r.accessDenied(obj, err)
+		}
 		return ctrl.Result{}, err
 	}
 
@@ -239,12 +417,17 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelper *patch.SerialPatcher, obj *v2.HelmRelease) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
-	// Mark the resource as under reconciliation.
+	// Mark the resource as under reconciliation. Any Stalled condition is
+	// removed, as it is determined again during the reconciliation.
 	conditions.MarkReconciling(obj, meta.ProgressingReason, "Fulfilling prerequisites")
+	conditions.Delete(obj, meta.StalledCondition)
 	if err := patchHelper.Patch(ctx, obj, patch.WithOwnedConditions{Conditions: intreconcile.OwnedConditions}, patch.WithFieldOwner(r.FieldManager)); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Surface invalid references before any action is attempted.
+	r.validateReferences(ctx, obj)
+
 	// Confirm dependencies are Ready before proceeding.
 	if c := len(obj.Spec.DependsOn); c > 0 {
 		log.Info(fmt.Sprintf("checking %d dependencies", c))
@@ -272,15 +455,7 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 	source, err := r.getSource(ctx, obj)
 	if err != nil {
 		if acl.IsAccessDenied(err) {
-			conditions.MarkStalled(obj, aclv1.AccessDeniedReason, err.Error())
-			conditions.MarkFalse(obj, meta.ReadyCondition, aclv1.AccessDeniedReason, err.Error())
-			conditions.Delete(obj, meta.ReconcilingCondition)
-			r.Eventf(obj, corev1.EventTypeWarning, aclv1.AccessDeniedReason, err.Error())
-
-			// Recovering from this is not possible without a restart of the
-			// controller or a change of spec, both triggering a new
-			// reconciliation.
-			return ctrl.Result{}, reconcile.TerminalError(err)
+			return r.accessDenied(obj, err)
 		}
 
 		msg := fmt.Sprintf("could not get Source object: %s", err.Error())
@@ -295,16 +470,28 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 	// Check if the source is ready.
 	if ready, msg := isSourceReady(source); !ready {
 		log.Info(msg)
-		conditions.MarkFalse(obj, meta.ReadyCondition, "SourceNotReady", msg)
+		reason := "SourceNotReady"
+		if isSourceAuthenticationFailed(source) {
+			reason = v2.AuthenticationFailedReason
+			// Let the source retry with any rotated credentials.
+			if err := r.requestSourceReconcile(ctx, source); err != nil {
+				log.Error(err, "failed to request reconciliation of source")
+			}
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, msg)
 		// Do not requeue immediately, when the artifact is created
 		// the watcher should trigger a reconciliation.
 		return jitter.JitteredRequeueInterval(ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}), errWaitForChart
 	}
 	// Remove any stale corresponding Ready=False condition with Unknown.
-	if conditions.HasAnyReason(obj, meta.ReadyCondition, "SourceNotReady") {
+	if conditions.HasAnyReason(obj, meta.ReadyCondition, "SourceNotReady", v2.AuthenticationFailedReason) {
 		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
 	}
 
+	// Report the availability of an upgrade once the release has been
+	// reconciled, or when the reconciliation is blocked.
+	defer observeUpgradeAvailable(obj, source)
+
 	// Compose values based from the spec and references.
 	values, err := chartutil.ChartValuesFromReferences(ctx, r.Client, obj.Namespace, obj.GetValues(), obj.Spec.ValuesFrom...)
 	if err != nil {
@@ -318,7 +505,20 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 	}
 
 	// Load chart from artifact.
-	loadedChart, err := loader.SecureLoadChartFromURL(loader.NewRetryableHTTPClient(ctx, r.artifactFetchRetries), source.GetArtifact().URL, source.GetArtifact().Digest)
+	loadCtx, span := tracing.Tracer().Start(ctx, "load chart", trace.WithAttributes(
+		tracing.ArtifactRevisionKey.String(source.GetArtifact().Revision),
+	))
+	if artifactURL, err := loader.ArtifactURL(source.GetArtifact().URL); err == nil {
+		r.artifactHost.Observe(artifactURL)
+	}
+	loadedChart, err := loader.SecureLoadChartFromURL(loader.NewRetryableHTTPClient(loadCtx, r.artifactFetchRetries), source.GetArtifact().URL, source.GetArtifact().Digest)
+	if loadedChart != nil && loadedChart.Metadata != nil {
+		span.SetAttributes(
+			tracing.ChartNameKey.String(loadedChart.Metadata.Name),
+			tracing.ChartVersionKey.String(loadedChart.Metadata.Version),
+		)
+	}
+	tracing.EndSpan(span, err)
 	if err != nil {
 		if errors.Is(err, loader.ErrFileNotFound) {
 			msg := fmt.Sprintf("Source not ready: artifact not found. Retrying in %s", r.requeueDependency.String())
@@ -328,7 +528,9 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 		}
 
 		conditions.MarkFalse(obj, meta.ReadyCondition, v2.ArtifactFailedReason, fmt.Sprintf("Could not load chart: %s", err.Error()))
-		r.Eventf(obj, corev1.EventTypeWarning, v2.ArtifactFailedReason, err.Error())
+		r.AnnotatedEventf(obj, map[string]string{
+			v2.GroupVersion.Group + "/" + action.MetaErrorCodeKey: string(action.ErrorCodeChartFetch),
+		}, corev1.EventTypeWarning, v2.ArtifactFailedReason, err.Error())
 		return ctrl.Result{}, err
 	}
 	// Remove any stale corresponding Ready=False condition with Unknown.
@@ -336,6 +538,40 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
 	}
 
+	// Verify the bundled dependencies of the chart, if enabled.
+	if err := verifyChartDependencies(loadedChart); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, v2.DependencyMismatchReason, "%s", err.Error())
+		r.Eventf(obj, corev1.EventTypeWarning, v2.DependencyMismatchReason, "%s", err.Error())
+		return ctrl.Result{}, err
+	}
+	// Remove any stale corresponding Ready=False condition with Unknown.
+	if conditions.HasAnyReason(obj, meta.ReadyCondition, v2.DependencyMismatchReason) {
+		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
+	}
+
+	// Merge the values files bundled in the chart before the values from the
+	// spec and references.
+	if len(obj.Spec.ValuesFiles) > 0 {
+		fileValues, err := chartutil.ChartValuesFromFiles(loadedChart, obj.Spec.ValuesFiles)
+		if err != nil {
+			conditions.MarkFalse(obj, meta.ReadyCondition, "ValuesError", "%s", err.Error())
+			r.Eventf(obj, corev1.EventTypeWarning, "ValuesError", "%s", err.Error())
+			return ctrl.Result{}, err
+		}
+		values = transform.MergeMaps(fileValues, values)
+	}
+	r.checkValuesTypes(obj, loadedChart, values)
+
+	// Check the vulnerability attestations of the chart, if configured.
+	if err := r.scanChart(ctx, obj, source, loadedChart.Metadata.Version); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, v2.SecurityScanFailedReason, err.Error())
+		return ctrl.Result{}, err
+	}
+	// Remove any stale corresponding Ready=False condition with Unknown.
+	if conditions.HasAnyReason(obj, meta.ReadyCondition, v2.SecurityScanFailedReason) {
+		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
+	}
+
 	ociDigest, err := mutateChartWithSourceRevision(loadedChart, source)
 	if err != nil {
 		conditions.MarkFalse(obj, meta.ReadyCondition, "ChartMutateError", err.Error())
@@ -353,6 +589,15 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
 	}
 
+	// In render-only mode, only render the manifest of the release for
+	// review without touching the Helm storage.
+	if obj.Spec.RenderOnly {
+		return r.reconcileRender(ctx, getter, obj, loadedChart, values)
+	}
+	if err = intreconcile.DeleteRenderedManifest(ctx, r.Client, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Keep feature flagged code paths separate from the main reconciliation
 	// logic to ensure easy removal when the feature flag is removed.
 	if ok, _ := features.Enabled(features.AdoptLegacyReleases); ok {
@@ -369,7 +614,50 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 	// If the release target configuration has changed, we need to uninstall the
 	// previous release target first. If we did not do this, the installation would
 	// fail due to resources already existing.
-	if reason, changed := action.ReleaseTargetChanged(obj, loadedChart.Name()); changed {
+	reason, changed := action.ReleaseTargetChanged(obj, loadedChart.Name())
+	if !changed && obj.Status.History.Latest() != nil && r.releaseStorageDriver(obj) != r.storageDriver(obj) {
+		if obj.IsObserveOnly() {
+			reason, changed = "storage driver", true
+		} else {
+			// Move the records of the release to the new storage driver,
+			// and never uninstall the release, as its records in the
+			// previous storage are kept intact when this fails.
+			if err = r.migrateStorage(ctx, getter, obj); err != nil {
+				conditions.MarkFalse(obj, meta.ReadyCondition, v2.StorageMigrationFailedReason, "%s", err.Error())
+				r.Eventf(obj, corev1.EventTypeWarning, v2.StorageMigrationFailedReason, "%s", err.Error())
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true}, nil
+		}
+	}
+	if changed && obj.IsObserveOnly() {
+		// A release which has not been migrated yet must not be
+		// uninstalled, observe the new release target instead.
+		log.Info(fmt.Sprintf("release target configuration changed (%s): observing new target of release which has not been migrated", reason))
+		obj.Status.ClearHistory()
+		obj.Status.ClearFailures()
+		changed = false
+	}
+	if changed {
+		// A pinned release must not be uninstalled, wait for the release
+		// target configuration to be reverted, or the release to be
+		// unpinned.
+		if obj.Spec.Pin {
+			log.Info(fmt.Sprintf("release target configuration changed (%s): refusing to uninstall pinned release", reason))
+			conditions.MarkTrue(obj, v2.PinnedCondition, v2.ReleasePinnedReason,
+				"Release %s is pinned to its current revision, refused to uninstall it after release target configuration changed (%s)",
+				obj.Status.History.Latest().FullReleaseName(), reason)
+			return jitter.JitteredRequeueInterval(ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}), nil
+		}
+
+		// Hold the uninstall during a cluster-wide change freeze.
+		if f := freeze.Active(time.Now()); f != nil {
+			log.Info(fmt.Sprintf("release target configuration changed (%s): change freeze active, holding uninstall", reason))
+			conditions.MarkTrue(obj, v2.FrozenCondition, v2.ChangeFreezeReason,
+				"%s, holding uninstall of release after release target configuration changed (%s)", f, reason)
+			return ctrl.Result{RequeueAfter: r.requeueDependency}, intreconcile.ErrFrozen
+		}
+
 		log.Info(fmt.Sprintf("release target configuration changed (%s): running uninstall for current release", reason))
 		if err = r.reconcileUninstall(ctx, getter, obj); err != nil && !errors.Is(err, intreconcile.ErrNoLatest) {
 			return ctrl.Result{}, err
@@ -377,16 +665,22 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 		obj.Status.ClearHistory()
 		obj.Status.ClearFailures()
 		obj.Status.StorageNamespace = ""
+		obj.Status.StorageDriver = ""
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// Set current storage namespace.
+	// Set current storage namespace and driver.
 	obj.Status.StorageNamespace = obj.GetStorageNamespace()
+	obj.Status.StorageDriver = r.storageDriver(obj)
 
 	// Reset the failure count if the chart or values have changed.
 	if reason, ok := action.MustResetFailures(obj, loadedChart.Metadata, values); ok {
 		log.V(logger.DebugLevel).Info(fmt.Sprintf("resetting failure count (%s)", reason))
 		obj.Status.ClearFailures()
+		// Allow a failed canary analysis to be retried.
+		if obj.Status.Canary != nil && obj.Status.Canary.Phase == v2.CanaryPhaseFailed {
+			obj.Status.Canary = nil
+		}
 	}
 
 	// Set last attempt values.
@@ -399,7 +693,8 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 
 	// Construct config factory for any further Helm actions.
 	cfg, err := action.NewConfigFactory(getter,
-		action.WithStorage(action.DefaultStorageDriver, obj.Status.StorageNamespace),
+		action.WithStorageMetadata(r.storageMetadata(obj)),
+		action.WithStorage(r.storageDriver(obj), obj.Status.StorageNamespace),
 		action.WithStorageLog(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))),
 	)
 	if err != nil {
@@ -411,16 +706,35 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
 	}
 
+	// Migrate the digests in the status if the configured algorithm changed.
+	if obj.Status.DigestAlgorithm != digest.Canonical.String() {
+		if err = action.MigrateDigests(cfg.Build(nil), obj, digest.Canonical); err != nil {
+			log.Error(err, "failed to migrate digests", "algorithm", digest.Canonical.String())
+		}
+		obj.Status.DigestAlgorithm = digest.Canonical.String()
+	}
+
 	// Off we go!
-	if err = intreconcile.NewAtomicRelease(patchHelper, cfg, r.EventRecorder, r.FieldManager).Reconcile(ctx, &intreconcile.Request{
-		Object: obj,
-		Chart:  loadedChart,
-		Values: values,
+	if err = intreconcile.NewAtomicRelease(patchHelper, cfg, r.EventRecorder, r.FieldManager,
+		intreconcile.WithDrainTimeout(r.drainTimeout),
+		intreconcile.WithRolloutGroups(r.rolloutGroups),
+		intreconcile.WithInterrupts(r.interrupts),
+		intreconcile.WithInterruptedReleasePolicy(r.interruptedPolicy),
+		r.hookClientOption(obj)).Reconcile(ctx, &intreconcile.Request{
+		Object:     obj,
+		Chart:      loadedChart,
+		Values:     values,
+		Provenance: sourceProvenance(source),
+		Webhooks:   actionhook.New(r.Client, r.ActionWebhookClient, obj),
 	}); err != nil {
 		if errors.Is(err, intreconcile.ErrMustRequeue) {
 			return ctrl.Result{Requeue: true}, nil
 		}
-		if interrors.IsOneOf(err, intreconcile.ErrExceededMaxRetries, intreconcile.ErrMissingRollbackTarget) {
+		if interrors.IsOneOf(err, intreconcile.ErrWaitForRolloutGroup, intreconcile.ErrWaitForCanary, intreconcile.ErrWaitForApproval,
+			intreconcile.ErrFrozen) {
+			return ctrl.Result{RequeueAfter: r.requeueDependency}, err
+		}
+		if interrors.IsOneOf(err, intreconcile.ErrExceededMaxRetries, intreconcile.ErrMissingRollbackTarget, intreconcile.ErrCanaryFailed) {
 			err = reconcile.TerminalError(err)
 		}
 		return ctrl.Result{}, err
@@ -428,14 +742,151 @@ func (r *HelmReleaseReconciler) reconcileRelease(ctx context.Context, patchHelpe
 	return jitter.JitteredRequeueInterval(ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}), nil
 }
 
+// reconcileRender renders the manifest of the Helm release of the object for
+// the given chart and values, and writes it to a ConfigMap for review.
+func (r *HelmReleaseReconciler) reconcileRender(ctx context.Context, getter genericclioptions.RESTClientGetter,
+	obj *v2.HelmRelease, chrt *chart.Chart, values helmchartutil.Values) (ctrl.Result, error) {
+	cfg, err := action.NewConfigFactory(getter,
+		action.WithStorage(r.storageDriver(obj), obj.GetStorageNamespace()),
+		action.WithStorageLog(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))),
+	)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, "FactoryError", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if err = intreconcile.NewRender(r.Client, cfg, r.EventRecorder).Reconcile(ctx, &intreconcile.Request{
+		Object: obj,
+		Chart:  chrt,
+		Values: values,
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	conditions.Delete(obj, meta.ReconcilingCondition)
+	return jitter.JitteredRequeueInterval(ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}), nil
+}
+
+// Preview renders the manifest of the release of the given object with the
+// chart and values the next reconciliation would use, and summarizes the
+// changes to the deployed release as computed by a server-side dry-run of an
+// upgrade. It implements preview.Previewer.
+//
+// It does not modify the object, the Helm storage nor the objects in the
+// cluster.
+func (r *HelmReleaseReconciler) Preview(ctx context.Context, obj *v2.HelmRelease) (*preview.Result, error) {
+	source, err := r.getSource(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("could not get Source object: %w", err)
+	}
+	if ready, msg := isSourceReady(source); !ready {
+		return nil, errors.New(msg)
+	}
+
+	values, err := chartutil.ChartValuesFromReferences(ctx, r.Client, obj.Namespace, obj.GetValues(), obj.Spec.ValuesFrom...)
+	if err != nil {
+		return nil, err
+	}
+	loadedChart, err := loader.SecureLoadChartFromURL(loader.NewRetryableHTTPClient(ctx, r.artifactFetchRetries),
+		source.GetArtifact().URL, source.GetArtifact().Digest)
+	if err != nil {
+		return nil, fmt.Errorf("could not load chart: %w", err)
+	}
+	if len(obj.Spec.ValuesFiles) > 0 {
+		fileValues, err := chartutil.ChartValuesFromFiles(loadedChart, obj.Spec.ValuesFiles)
+		if err != nil {
+			return nil, err
+		}
+		values = transform.MergeMaps(fileValues, values)
+	}
+	if _, err = mutateChartWithSourceRevision(loadedChart, source); err != nil {
+		return nil, err
+	}
+
+	log := action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))
+	cfg, err := r.buildConfigFactory(ctx, obj, log)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &preview.Result{
+		Object: client.ObjectKeyFromObject(obj).String(),
+		Chart:  loadedChart.Name() + "@" + loadedChart.Metadata.Version,
+		Action: preview.ActionInstall,
+	}
+	if result.Manifest, _, err = action.Render(ctx, cfg.Build(log), obj, loadedChart, values); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+	diff, err := action.UpgradePreview(ctx, cfg.Build(log), obj, loadedChart, values)
+	switch {
+	case err == nil:
+		result.Action = preview.ActionUpgrade
+		result.Diff = diff
+	case errors.Is(err, helmdriver.ErrNoDeployedReleases), errors.Is(err, helmdriver.ErrReleaseNotFound):
+	default:
+		return nil, fmt.Errorf("failed to preview upgrade: %w", err)
+	}
+	return result, nil
+}
+
+// Manifest returns the manifest of the release of the given snapshot in the
+// history of the given object as stored in the Helm storage, with the data of
+// Secrets redacted. It implements manifest.Getter.
+//
+// It does not modify the object nor the Helm storage.
+func (r *HelmReleaseReconciler) Manifest(ctx context.Context, obj *v2.HelmRelease, snapshot *v2.Snapshot) (string, error) {
+	log := action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))
+	cfg, err := r.buildConfigFactory(ctx, obj, log)
+	if err != nil {
+		return "", err
+	}
+	return action.SnapshotManifest(cfg.Build(log), snapshot)
+}
+
+// buildConfigFactory returns an action.ConfigFactory for the release of the
+// given object, for use outside of its reconciliation. The storage namespace
+// of the last release takes precedence over the one in the spec.
+func (r *HelmReleaseReconciler) buildConfigFactory(ctx context.Context, obj *v2.HelmRelease,
+	log helmaction.DebugLog) (*action.ConfigFactory, error) {
+	getter, err := r.buildRESTClientGetter(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	storageNamespace := obj.Status.StorageNamespace
+	if storageNamespace == "" {
+		storageNamespace = obj.GetStorageNamespace()
+	}
+	return action.NewConfigFactory(getter,
+		action.WithStorage(r.releaseStorageDriver(obj), storageNamespace),
+		action.WithStorageLog(log),
+	)
+}
+
 // reconcileDelete deletes the v1beta2.HelmChart of the v2.HelmRelease,
 // and uninstalls the Helm release if the resource has not been suspended.
 func (r *HelmReleaseReconciler) reconcileDelete(ctx context.Context, obj *v2.HelmRelease) (ctrl.Result, error) {
 	// Only uninstall the release and delete the HelmChart resource if the
 	// resource is not suspended.
 	if !obj.Spec.Suspend {
+		// Hold the uninstall during a cluster-wide change freeze.
+		if f := freeze.Active(time.Now()); f != nil && !obj.DeletionTimestamp.IsZero() && obj.Status.StorageNamespace != "" {
+			ctrl.LoggerFrom(ctx).Info("change freeze active: holding uninstall of deleted release")
+			conditions.MarkTrue(obj, v2.FrozenCondition, v2.ChangeFreezeReason, "%s, holding uninstall of deleted release", f)
+			return ctrl.Result{RequeueAfter: r.requeueDependency}, intreconcile.ErrFrozen
+		}
+
 		if err := r.reconcileReleaseDeletion(ctx, obj); err != nil {
-			return ctrl.Result{}, err
+			now := time.Now()
+			recordUninstallAttempt(obj, err, now)
+			if !uninstallForceTimeoutPassed(obj, now) {
+				if retry := obj.GetUninstall().Retry; retry != nil {
+					delay := uninstallRetryDelay(obj, *retry, now)
+					ctrl.LoggerFrom(ctx).Error(err, "Helm uninstall failed, retrying after backoff",
+						"failures", obj.Status.UninstallFailures, "after", delay.String())
+					return ctrl.Result{RequeueAfter: delay}, errRetryUninstall
+				}
+				return ctrl.Result{}, err
+			}
+			r.forceReleaseDeletion(ctx, obj, err)
 		}
 
 		if err := r.reconcileChartTemplate(ctx, obj); err != nil {
@@ -444,6 +895,18 @@ func (r *HelmReleaseReconciler) reconcileDelete(ctx context.Context, obj *v2.Hel
 	}
 
 	if !obj.DeletionTimestamp.IsZero() {
+		// Allow the next member of the rollout group to upgrade.
+		if r.rolloutGroups != nil {
+			r.rolloutGroups.Release(obj.GetNamespace() + "/" + obj.GetName())
+		}
+
+		// Drop the token of the service account the object authenticated
+		// as. Other objects using the same service account request a new
+		// one.
+		if r.ServiceAccountTokens != nil {
+			r.ServiceAccountTokens.Evict(obj.Spec.ServiceAccountName, obj.GetNamespace())
+		}
+
 		// Remove our finalizer from the list.
 		controllerutil.RemoveFinalizer(obj, v2.HelmReleaseFinalizer)
 
@@ -454,6 +917,89 @@ func (r *HelmReleaseReconciler) reconcileDelete(ctx context.Context, obj *v2.Hel
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// uninstallForceTimeoutPassed returns if the configured uninstall force
+// timeout has passed since the deletion of the object at the given time.
+func uninstallForceTimeoutPassed(obj *v2.HelmRelease, now time.Time) bool {
+	timeout := obj.GetUninstall().ForceTimeout
+	if timeout == nil || obj.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return now.After(obj.DeletionTimestamp.Add(timeout.Duration))
+}
+
+// recordUninstallAttempt records the failure of the Helm uninstall of the
+// deleted object with the given error at the given time in the status.
+func recordUninstallAttempt(obj *v2.HelmRelease, err error, now time.Time) {
+	reason := conditions.GetReason(obj, meta.ReadyCondition)
+	if reason == "" || !conditions.IsFalse(obj, meta.ReadyCondition) {
+		reason = v2.UninstallFailedReason
+	}
+	obj.Status.RecordUninstallAttempt(v2.UninstallAttempt{
+		Time:    metav1.NewTime(now),
+		Reason:  reason,
+		Message: err.Error(),
+	})
+}
+
+// uninstallRetryDelay returns the time to wait before retrying the failed
+// Helm uninstall of the deleted object according to the given retry
+// configuration. The delay does not exceed the time until the uninstall
+// force timeout passes, so the object is finalized in time.
+func uninstallRetryDelay(obj *v2.HelmRelease, retry v2.UninstallRetry, now time.Time) time.Duration {
+	delay := retry.GetDelay(obj.Status.UninstallFailures)
+	if timeout := obj.GetUninstall().ForceTimeout; timeout != nil && !obj.DeletionTimestamp.IsZero() {
+		if until := obj.DeletionTimestamp.Add(timeout.Duration).Sub(now); until < delay {
+			// Retry just after the timeout has passed.
+			delay = max(until, 0) + time.Second
+		}
+	}
+	return delay
+}
+
+// maxLeftBehindResources is the maximum number of resources listed in the
+// event emitted by forceReleaseDeletion.
+const maxLeftBehindResources = 20
+
+// forceReleaseDeletion gives up on the uninstallation of the Helm release of
+// the object after it failed with the given error, orphaning the Helm release
+// and its resources. It emits a warning event listing the resources of the
+// release inventory which are left behind, and truncates the current release
+// details in the status.
+func (r *HelmReleaseReconciler) forceReleaseDeletion(ctx context.Context, obj *v2.HelmRelease, err error) {
+	releaseName := obj.GetReleaseNamespace() + "/" + obj.GetReleaseName()
+	if cur := obj.Status.History.Latest(); cur != nil {
+		releaseName = cur.FullReleaseName()
+	}
+
+	var resources string
+	switch {
+	case obj.Status.Inventory == nil:
+		resources = "resources are unknown"
+	case len(obj.Status.Inventory.Entries) == 0:
+		resources = "no resources"
+	default:
+		ids := make([]string, 0, maxLeftBehindResources)
+		for i, e := range obj.Status.Inventory.Entries {
+			if i == maxLeftBehindResources {
+				ids = append(ids, fmt.Sprintf("and %d more", len(obj.Status.Inventory.Entries)-i))
+				break
+			}
+			ids = append(ids, e.ID)
+		}
+		resources = fmt.Sprintf("%d resources: %s", len(obj.Status.Inventory.Entries), strings.Join(ids, ", "))
+	}
+
+	msg := fmt.Sprintf("Helm uninstall for release %s did not succeed within %s after deletion, "+
+		"orphaning the Helm release with %s. Last error: %s", releaseName,
+		obj.GetUninstall().ForceTimeout.Duration.String(), resources, err.Error())
+	ctrl.LoggerFrom(ctx).Info(msg)
+	r.Event(obj, corev1.EventTypeWarning, v2.UninstallForcedReason, msg)
+
+	obj.Status.ClearHistory()
+	obj.Status.StorageNamespace = ""
+	obj.Status.Inventory = nil
+}
+
 // handleReleaseDeletion handles the deletion of a HelmRelease resource.
 //
 // Before uninstalling the release, it will check if the current configuration
@@ -480,6 +1026,20 @@ func (r *HelmReleaseReconciler) reconcileReleaseDeletion(ctx context.Context, ob
 		return nil
 	}
 
+	// A release which has not been migrated yet is not owned by the
+	// HelmRelease, and must be left in place.
+	if obj.IsObserveOnly() {
+		ctrl.LoggerFrom(ctx).Info("skipping Helm release uninstallation: release has not been migrated")
+		return nil
+	}
+
+	// A pinned release must not be uninstalled, and is left in place
+	// along with its storage records.
+	if obj.Spec.Pin {
+		ctrl.LoggerFrom(ctx).Info("skipping Helm release uninstallation: release is pinned")
+		return nil
+	}
+
 	// Build client getter.
 	getter, err := r.buildRESTClientGetter(ctx, obj)
 	if err != nil {
@@ -531,6 +1091,19 @@ func (r *HelmReleaseReconciler) reconcileReleaseDeletion(ctx context.Context, ob
 		}
 	}
 
+	// Orphan the release instead of uninstalling it, if configured.
+	if obj.GetDeletionPolicy() == v2.DeletionPolicyOrphan {
+		if err = r.reconcileOrphan(ctx, getter, obj); err != nil {
+			return err
+		}
+
+		// Truncate the current release details in the status.
+		obj.Status.ClearHistory()
+		obj.Status.StorageNamespace = ""
+
+		return nil
+	}
+
 	// Attempt to uninstall the release.
 	if err = r.reconcileUninstall(ctx, getter, obj); err != nil && !errors.Is(err, intreconcile.ErrNoLatest) {
 		return err
@@ -546,6 +1119,19 @@ func (r *HelmReleaseReconciler) reconcileReleaseDeletion(ctx context.Context, ob
 	return nil
 }
 
+// accessDenied marks the object as stalled because of the given access denied
+// error, and returns a terminal error.
+func (r *HelmReleaseReconciler) accessDenied(obj *v2.HelmRelease, err error) (ctrl.Result, error) {
+	conditions.MarkStalled(obj, aclv1.AccessDeniedReason, err.Error())
+	conditions.MarkFalse(obj, meta.ReadyCondition, aclv1.AccessDeniedReason, err.Error())
+	conditions.Delete(obj, meta.ReconcilingCondition)
+	r.Eventf(obj, corev1.EventTypeWarning, aclv1.AccessDeniedReason, err.Error())
+
+	// Recovering from this is not possible without a restart of the
+	// controller or a change of spec, both triggering a new reconciliation.
+	return ctrl.Result{}, reconcile.TerminalError(err)
+}
+
 // reconcileChartTemplate reconciles the HelmChart template from the HelmRelease.
 // Effectively, this means that the HelmChart resource is created, updated or
 // deleted based on the state of the HelmRelease.
@@ -558,7 +1144,8 @@ func (r *HelmReleaseReconciler) reconcileChartTemplate(ctx context.Context, obj
 func (r *HelmReleaseReconciler) reconcileUninstall(ctx context.Context, getter genericclioptions.RESTClientGetter, obj *v2.HelmRelease) error {
 	// Construct config factory for current release.
 	cfg, err := action.NewConfigFactory(getter,
-		action.WithStorage(action.DefaultStorageDriver, obj.Status.StorageNamespace),
+		action.WithStorageMetadata(r.storageMetadata(obj)),
+		action.WithStorage(r.releaseStorageDriver(obj), obj.Status.StorageNamespace),
 		action.WithStorageLog(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))),
 	)
 	if err != nil {
@@ -566,8 +1153,154 @@ func (r *HelmReleaseReconciler) reconcileUninstall(ctx context.Context, getter g
 		return err
 	}
 
-	// Run uninstall.
-	return intreconcile.NewUninstall(cfg, r.EventRecorder).Reconcile(ctx, &intreconcile.Request{Object: obj})
+	// The Secrets of the release action webhooks may have been deleted
+	// along with the namespace, which must not block the uninstall.
+	webhooks := actionhook.New(r.Client, r.ActionWebhookClient, obj)
+	if err = webhooks.Validate(ctx); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "skipping release action webhooks of uninstall")
+		webhooks = nil
+	}
+	req := &intreconcile.Request{Object: obj, Webhooks: webhooks}
+	uninstall := intreconcile.NewUninstall(cfg, r.EventRecorder)
+
+	// Run uninstall, gated by the release action webhooks if there is a
+	// release to uninstall.
+	hasRelease := obj.Status.History.Latest() != nil
+	if hasRelease {
+		if err = intreconcile.CallWebhooks(ctx, r.EventRecorder, uninstall, req, v2.WebhookPhasePre, nil); err != nil {
+			conditions.MarkFalse(obj, meta.ReadyCondition, v2.WebhookFailedReason, "%s", err.Error())
+			return err
+		}
+	}
+	err = uninstall.Reconcile(ctx, req)
+	if hasRelease {
+		_ = intreconcile.CallWebhooks(ctx, r.EventRecorder, uninstall, req, v2.WebhookPhasePost, err)
+	}
+
+	// Remove the canary release of an analysis in progress.
+	if cErr := intreconcile.RemoveCanary(ctx, cfg, obj); cErr != nil {
+		ctrl.LoggerFrom(ctx).Error(cErr, "failed to uninstall canary release")
+	}
+	return err
+}
+
+// reconcileOrphan removes the Helm storage records of the latest release of
+// the object, leaving the resources of the release in the cluster.
+func (r *HelmReleaseReconciler) reconcileOrphan(ctx context.Context, getter genericclioptions.RESTClientGetter, obj *v2.HelmRelease) error {
+	cur := obj.Status.History.Latest()
+	if cur == nil {
+		ctrl.LoggerFrom(ctx).Info("skipping orphaning of Helm release: no latest release")
+		return nil
+	}
+
+	// Construct config factory for current release.
+	cfg, err := action.NewConfigFactory(getter,
+		action.WithStorage(r.releaseStorageDriver(obj), obj.Status.StorageNamespace),
+		action.WithStorageLog(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))),
+	)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, "ConfigFactoryErr", err.Error())
+		return err
+	}
+
+	if err = action.Orphan(ctx, cfg.Build(nil), obj, cur.Name); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, v2.UninstallFailedReason,
+			"failed to orphan release %s: %s", cur.FullReleaseName(), err.Error())
+		return err
+	}
+
+	msg := fmt.Sprintf("Orphaned Helm release %s, leaving its resources in the cluster", cur.FullReleaseName())
+	ctrl.LoggerFrom(ctx).Info(msg)
+	r.Event(obj, corev1.EventTypeNormal, v2.ReleaseOrphanedReason, msg)
+	return nil
+}
+
+// migrateStorage moves the records of the current release of the object from
+// the storage driver it is stored with to the storage driver configured for
+// the object, after the latter changed. This happens when the storage driver
+// of the object, or the default storage driver of the controller, changes.
+func (r *HelmReleaseReconciler) migrateStorage(ctx context.Context, getter genericclioptions.RESTClientGetter, obj *v2.HelmRelease) error {
+	from, to := r.releaseStorageDriver(obj), r.storageDriver(obj)
+
+	storageLog := action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))
+	src, err := action.NewConfigFactory(getter,
+		action.WithStorage(from, obj.Status.StorageNamespace),
+		action.WithStorageLog(storageLog),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move release records from '%s' to '%s' storage driver: %w", from, to, err)
+	}
+	dst, err := action.NewConfigFactory(getter,
+		action.WithStorageMetadata(r.storageMetadata(obj)),
+		action.WithStorage(to, obj.Status.StorageNamespace),
+		action.WithStorageLog(storageLog),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move release records from '%s' to '%s' storage driver: %w", from, to, err)
+	}
+
+	n, err := action.MigrateStorage(src, dst, obj.Status.History.Latest().Name)
+	if err != nil {
+		return fmt.Errorf("failed to move release records from '%s' to '%s' storage driver: %w", from, to, err)
+	}
+	ctrl.LoggerFrom(ctx).Info(fmt.Sprintf("moved %d record(s) of release %s from '%s' to '%s' storage driver",
+		n, obj.Status.History.Latest().FullReleaseName(), from, to))
+	obj.Status.StorageDriver = to
+	return nil
+}
+
+// storageDriver returns the name of the Helm storage driver configured for
+// the object, falling back to the storage driver of the controller.
+func (r *HelmReleaseReconciler) storageDriver(obj *v2.HelmRelease) string {
+	if obj.Spec.StorageDriver != "" {
+		// The value is validated by the CRD, an invalid value results in
+		// the default of the controller.
+		if driver, err := action.ParseStorageDriver(obj.Spec.StorageDriver); err == nil {
+			return driver
+		}
+	}
+	if r.StorageDriver != "" {
+		return r.StorageDriver
+	}
+	return action.DefaultStorageDriver
+}
+
+// storageMetadata returns the labels and annotations added to the Helm
+// storage Secrets of the object, composed of the StorageLabels and
+// StorageAnnotations of the controller and the object.
+func (r *HelmReleaseReconciler) storageMetadata(obj *v2.HelmRelease) (labels, annotations map[string]string) {
+	return mergeStringMaps(r.StorageLabels, obj.Spec.StorageLabels),
+		mergeStringMaps(r.StorageAnnotations, obj.Spec.StorageAnnotations)
+}
+
+// mergeStringMaps returns a new map with the entries of all given maps, with
+// the entries of later maps taking precedence. It returns nil if all maps
+// are empty.
+func mergeStringMaps(maps ...map[string]string) map[string]string {
+	var merged map[string]string
+	for _, m := range maps {
+		for k, v := range m {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// releaseStorageDriver returns the name of the Helm storage driver the
+// current release of the object is stored with. For releases made before
+// the driver was recorded in the status, this is the storage driver of the
+// controller.
+func (r *HelmReleaseReconciler) releaseStorageDriver(obj *v2.HelmRelease) string {
+	if obj.Status.StorageDriver != "" {
+		return obj.Status.StorageDriver
+	}
+	if r.StorageDriver != "" {
+		return r.StorageDriver
+	}
+	return action.DefaultStorageDriver
 }
 
 // checkDependencies checks if the dependencies of the given v2.HelmRelease
@@ -618,7 +1351,7 @@ func (r *HelmReleaseReconciler) adoptLegacyRelease(ctx context.Context, getter g
 
 	// Construct config factory for current release.
 	cfg, err := action.NewConfigFactory(getter,
-		action.WithStorage(action.DefaultStorageDriver, storageNamespace),
+		action.WithStorage(r.storageDriver(obj), storageNamespace),
 		action.WithStorageLog(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.TraceLevel))),
 	)
 	if err != nil {
@@ -659,34 +1392,39 @@ func (*HelmReleaseReconciler) adoptPostRenderersStatus(obj *v2.HelmRelease) {
 
 	// if we have a reconciled object with PostRenderers not reflected in the
 	// status, we need to update the status.
-	if obj.Spec.PostRenderers != nil && obj.Status.ObservedPostRenderersDigest == "" {
-		obj.Status.ObservedPostRenderersDigest = postrender.Digest(digest.Canonical, obj.Spec.PostRenderers).String()
+	if postrender.HasPostRenderers(obj) && obj.Status.ObservedPostRenderersDigest == "" {
+		obj.Status.ObservedPostRenderersDigest = postrender.DigestFor(digest.Canonical, obj).String()
+	}
+}
+
+// hookClientOption returns the option to clean up the hooks of the Helm
+// release of the object with the client of the reconciler, if the object
+// targets the cluster of the controller without a service account.
+// Otherwise, a client is created for the target cluster on every cleanup.
+func (r *HelmReleaseReconciler) hookClientOption(obj *v2.HelmRelease) intreconcile.AtomicReleaseOption {
+	if r.APIReader == nil || obj.Spec.KubeConfig != nil ||
+		obj.Spec.ServiceAccountName != "" || kube.DefaultServiceAccountName != "" {
+		return intreconcile.WithHookClient(nil, nil)
 	}
+	return intreconcile.WithHookClient(r.APIReader, r.Client)
 }
 
 func (r *HelmReleaseReconciler) buildRESTClientGetter(ctx context.Context, obj *v2.HelmRelease) (genericclioptions.RESTClientGetter, error) {
 	opts := []kube.Option{
 		kube.WithNamespace(obj.GetReleaseNamespace()),
 		kube.WithClientOptions(r.ClientOpts),
-		// When ServiceAccountName is empty, it will fall back to the configured
-		// default. If this is not configured either, this option will result in
-		// a no-op.
-		kube.WithImpersonate(obj.Spec.ServiceAccountName, obj.GetNamespace()),
 		kube.WithPersistent(obj.UsePersistentClient()),
 	}
+
+	// When ServiceAccountName is empty, the impersonation and token options
+	// fall back to the configured default. If this is not configured either,
+	// they result in a no-op.
 	if obj.Spec.KubeConfig != nil {
-		secretName := types.NamespacedName{
-			Namespace: obj.GetNamespace(),
-			Name:      obj.Spec.KubeConfig.SecretRef.Name,
-		}
-		var secret corev1.Secret
-		if err := r.Get(ctx, secretName, &secret); err != nil {
-			return nil, fmt.Errorf("could not get KubeConfig secret '%s': %w", secretName, err)
-		}
-		kubeConfig, err := kube.ConfigFromSecret(&secret, obj.Spec.KubeConfig.SecretRef.Key, r.KubeConfigOpts)
+		kubeConfig, err := r.getKubeConfig(ctx, obj)
 		if err != nil {
 			return nil, err
 		}
+		opts = append(opts, kube.WithImpersonate(obj.Spec.ServiceAccountName, obj.GetNamespace()))
 		return kube.NewMemoryRESTClientGetter(kubeConfig, opts...), nil
 	}
 
@@ -694,9 +1432,159 @@ func (r *HelmReleaseReconciler) buildRESTClientGetter(ctx context.Context, obj *
 	if err != nil {
 		return nil, fmt.Errorf("could not get in-cluster REST config: %w", err)
 	}
+	if r.ServiceAccountTokens != nil {
+		opts = append(opts, kube.WithServiceAccountToken(r.ServiceAccountTokens, obj.Spec.ServiceAccountName, obj.GetNamespace()))
+	} else {
+		opts = append(opts, kube.WithImpersonate(obj.Spec.ServiceAccountName, obj.GetNamespace()))
+	}
 	return kube.NewMemoryRESTClientGetter(cfg, opts...), nil
 }
 
+// getKubeConfig returns the REST config from the KubeConfig Secret
+// referenced by the object.
+func (r *HelmReleaseReconciler) getKubeConfig(ctx context.Context, obj *v2.HelmRelease) (*rest.Config, error) {
+	secretName := types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.Spec.KubeConfig.SecretRef.Name,
+	}
+	var secret corev1.Secret
+	if err := r.Get(ctx, secretName, &secret); err != nil {
+		return nil, fmt.Errorf("could not get KubeConfig secret '%s': %w", secretName, err)
+	}
+	return kube.ConfigFromSecret(&secret, obj.Spec.KubeConfig.SecretRef.Key, r.KubeConfigOpts)
+}
+
+// validateReferences validates that the KubeConfig Secret, the values
+// references and the image pull secrets of the ServiceAccount of the object
+// exist and are well-formed, and marks
+// ReferencesValid=False with the problems found. The condition is removed
+// when the object does not have any references.
+//
+// It does not block the reconciliation, as the references are resolved again
+// when they are used. This allows problems to be observed while the release
+// is otherwise healthy, before they cause the next action attempt to fail.
+func (r *HelmReleaseReconciler) validateReferences(ctx context.Context, obj *v2.HelmRelease) {
+	if obj.Spec.KubeConfig == nil && len(obj.Spec.ValuesFrom) == 0 && obj.Spec.Hooks == nil && obj.Spec.ServiceAccountName == "" {
+		conditions.Delete(obj, v2.ReferencesValidCondition)
+		return
+	}
+
+	var errs []error
+	if obj.Spec.KubeConfig != nil {
+		if _, err := r.getKubeConfig(ctx, obj); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, ref := range obj.Spec.ValuesFrom {
+		if _, err := chartutil.ChartValuesFromReferences(ctx, r.Client, obj.Namespace, nil, ref); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := actionhook.New(r.Client, r.ActionWebhookClient, obj).Validate(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, r.validateImagePullSecrets(ctx, obj)...)
+
+	if len(errs) > 0 {
+		msg := apierrutil.NewAggregate(errs).Error()
+		conditions.MarkFalse(obj, v2.ReferencesValidCondition, v2.InvalidReferencesReason, "%s", msg)
+		ctrl.LoggerFrom(ctx).Info("invalid references: " + msg)
+		return
+	}
+	conditions.MarkTrue(obj, v2.ReferencesValidCondition, meta.SucceededReason, "All references are valid")
+}
+
+// validateImagePullSecrets validates that the image pull secrets of the
+// ServiceAccount the release is applied with exist and are well-formed. The
+// ServiceAccount is only validated when it is set on the object and in the
+// cluster of the controller, i.e. without a KubeConfig.
+//
+// The ServiceAccount is read with the APIReader when set, to not cache all
+// the ServiceAccounts in the cluster.
+func (r *HelmReleaseReconciler) validateImagePullSecrets(ctx context.Context, obj *v2.HelmRelease) []error {
+	if obj.Spec.ServiceAccountName == "" || obj.Spec.KubeConfig != nil {
+		return nil
+	}
+
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	var sa corev1.ServiceAccount
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.Spec.ServiceAccountName}, &sa); err != nil {
+		return []error{fmt.Errorf("could not get ServiceAccount '%s/%s': %w", obj.GetNamespace(), obj.Spec.ServiceAccountName, err)}
+	}
+
+	var errs []error
+	for _, ref := range sa.ImagePullSecrets {
+		var secret corev1.Secret
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: sa.Namespace, Name: ref.Name}, &secret); err != nil {
+			errs = append(errs, fmt.Errorf("could not get image pull secret '%s/%s' of ServiceAccount '%s': %w",
+				sa.Namespace, ref.Name, sa.Name, err))
+			continue
+		}
+		if err := signature.ValidatePullSecret(secret); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// checkValuesTypes compares the types of the composed values to the types
+// expected by the chart, and marks ValuesTypesValid=False with the mismatches
+// found. A Warning event is emitted when the mismatches change. The condition
+// is removed when no values are composed.
+//
+// It does not block the reconciliation, as a mismatch may be intended by a
+// chart which does not declare the types of its values.
+func (r *HelmReleaseReconciler) checkValuesTypes(obj *v2.HelmRelease, chrt *chart.Chart, values helmchartutil.Values) {
+	if len(values) == 0 {
+		conditions.Delete(obj, v2.ValuesTypesValidCondition)
+		return
+	}
+
+	mismatches := chartutil.TypeMismatches(chrt, values)
+	if len(mismatches) == 0 {
+		conditions.MarkTrue(obj, v2.ValuesTypesValidCondition, meta.SucceededReason, "All values match the types expected by the chart")
+		return
+	}
+
+	const maxMismatches = 10
+	var msgs []string
+	for i, m := range mismatches {
+		if i == maxMismatches {
+			msgs = append(msgs, fmt.Sprintf("and %d more", len(mismatches)-maxMismatches))
+			break
+		}
+		msgs = append(msgs, m.String())
+	}
+	msg := "Values do not match the types expected by the chart: " + strings.Join(msgs, "; ")
+
+	if !conditions.IsFalse(obj, v2.ValuesTypesValidCondition) || conditions.GetMessage(obj, v2.ValuesTypesValidCondition) != msg {
+		r.Eventf(obj, corev1.EventTypeWarning, v2.ValuesTypeMismatchReason, "%s", msg)
+	}
+	conditions.MarkFalse(obj, v2.ValuesTypesValidCondition, v2.ValuesTypeMismatchReason, "%s", msg)
+}
+
+// verifyChartDependencies returns an error listing the mismatches between the
+// versions in the lock files of the chart and the dependencies bundled with
+// it, if the VerifyChartDependencies feature is enabled.
+func verifyChartDependencies(chrt *chart.Chart) error {
+	if enabled, _ := features.Enabled(features.VerifyChartDependencies); !enabled {
+		return nil
+	}
+
+	mismatches := chartutil.DependencyMismatches(chrt)
+	if len(mismatches) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(mismatches))
+	for _, m := range mismatches {
+		msgs = append(msgs, m.String())
+	}
+	return fmt.Errorf("bundled chart dependency versions do not match lock file: %s", strings.Join(msgs, "; "))
+}
+
 // getSource returns the source object containing the HelmChart, either by
 // using the chartRef in the spec, or by looking up the HelmChart
 // referenced in the status object.
@@ -725,6 +1613,11 @@ func (r *HelmReleaseReconciler) getSource(ctx context.Context, obj *v2.HelmRelea
 	if err := r.Client.Get(ctx, chartRef, &hc); err != nil {
 		return nil, err
 	}
+
+	sourceRef := types.NamespacedName{Namespace: hc.Namespace, Name: hc.Spec.SourceRef.Name}
+	if err := intacl.AllowsChartSource(obj, hc.Spec.SourceRef.Kind, sourceRef); err != nil {
+		return nil, err
+	}
 	return &hc, nil
 }
 
@@ -738,6 +1631,9 @@ func (r *HelmReleaseReconciler) getSourceFromOCIRef(ctx context.Context, obj *v2
 	if err := intacl.AllowsAccessTo(obj, sourcev1beta2.OCIRepositoryKind, ociRepoRef); err != nil {
 		return nil, err
 	}
+	if err := intacl.AllowsChartSource(obj, sourcev1beta2.OCIRepositoryKind, ociRepoRef); err != nil {
+		return nil, err
+	}
 
 	or := sourcev1beta2.OCIRepository{}
 	if err := r.Client.Get(ctx, ociRepoRef, &or); err != nil {
@@ -746,6 +1642,59 @@ func (r *HelmReleaseReconciler) getSourceFromOCIRef(ctx context.Context, obj *v2
 	return &or, nil
 }
 
+// nextReconcileAt returns the time at which the object of the request is
+// expected to be reconciled next, given the result of the reconciliation.
+// It returns nil if the object will not be requeued.
+func (r *HelmReleaseReconciler) nextReconcileAt(req ctrl.Request, result ctrl.Result, err error) *metav1.Time {
+	var delay time.Duration
+	switch {
+	case errors.Is(err, reconcile.TerminalError(nil)):
+		return nil
+	case err != nil, result.RequeueAfter <= 0 && result.Requeue:
+		delay = r.nextRetryDelay(req)
+	case result.RequeueAfter > 0:
+		delay = result.RequeueAfter
+	default:
+		return nil
+	}
+	next := metav1.NewTime(time.Now().Add(delay).Truncate(time.Second))
+	return &next
+}
+
+// requeueForSchedule returns the given result with the RequeueAfter shortened
+// to the time until the next time of the v2.ReconcileSchedule of the object,
+// if it comes before the requeue according to the interval. Results which do
+// not requeue after a delay are returned as is.
+func requeueForSchedule(obj *v2.HelmRelease, result ctrl.Result, now time.Time) ctrl.Result {
+	if result.RequeueAfter <= 0 {
+		return result
+	}
+	cron, err := schedule.ForHelmRelease(obj)
+	if err != nil || cron == nil {
+		return result
+	}
+	if until := cron.Until(now); until > 0 && until < result.RequeueAfter {
+		result.RequeueAfter = until
+	}
+	return result
+}
+
+// nextRetryDelay returns the delay before the rate limiter retries the
+// failed reconciliation of the object of the request. This mirrors the
+// exponential backoff of the rate limiter, based on the number of failures
+// it has recorded for the request so far.
+func (r *HelmReleaseReconciler) nextRetryDelay(req ctrl.Request) time.Duration {
+	var failures int
+	if r.rateLimiter != nil {
+		failures = r.rateLimiter.NumRequeues(req)
+	}
+	backoff := float64(r.retryDelay.MinRetryDelay) * math.Pow(2, float64(failures))
+	if backoff > float64(r.retryDelay.MaxRetryDelay) {
+		return r.retryDelay.MaxRetryDelay
+	}
+	return time.Duration(backoff)
+}
+
 // waitForHistoryCacheSync returns a function that can be used to wait for the
 // cache backing the Kubernetes client to be in sync with the current state of
 // the v2.HelmRelease.
@@ -778,7 +1727,7 @@ func (r *HelmReleaseReconciler) requestsForHelmChartChange(ctx context.Context,
 
 	var list v2.HelmReleaseList
 	if err := r.List(ctx, &list, client.MatchingFields{
-		v2.SourceIndexKey: client.ObjectKeyFromObject(hc).String(),
+		v2.SourceIndexKey: sourceIndexValue(sourcev1.HelmChartKind, client.ObjectKeyFromObject(hc)),
 	}); err != nil {
 		ctrl.LoggerFrom(ctx).Error(err, "failed to list HelmReleases for HelmChart change")
 		return nil
@@ -796,6 +1745,21 @@ func (r *HelmReleaseReconciler) requestsForHelmChartChange(ctx context.Context,
 	return reqs
 }
 
+// interruptObsoleteAction interrupts the in-flight install or upgrade of an
+// interruptible HelmRelease when a new generation of it is observed. The
+// object is not enqueued, as this is already done by the watch of the
+// HelmRelease itself.
+func (r *HelmReleaseReconciler) interruptObsoleteAction(ctx context.Context, e event.UpdateEvent, _ workqueue.RateLimitingInterface) {
+	obj, ok := e.ObjectNew.(*v2.HelmRelease)
+	if !ok || e.ObjectOld == nil || obj.GetGeneration() <= e.ObjectOld.GetGeneration() {
+		return
+	}
+	if r.interrupts.Interrupt(intreconcile.InterruptKey(obj), obj.GetGeneration()) {
+		ctrl.LoggerFrom(ctx).Info(fmt.Sprintf("interrupting in-flight action of HelmRelease %s for generation %d",
+			client.ObjectKeyFromObject(obj), obj.GetGeneration()))
+	}
+}
+
 func (r *HelmReleaseReconciler) requestsForOCIRrepositoryChange(ctx context.Context, o client.Object) []reconcile.Request {
 	or, ok := o.(*sourcev1beta2.OCIRepository)
 	if !ok {
@@ -810,23 +1774,23 @@ func (r *HelmReleaseReconciler) requestsForOCIRrepositoryChange(ctx context.Cont
 
 	var list v2.HelmReleaseList
 	if err := r.List(ctx, &list, client.MatchingFields{
-		v2.SourceIndexKey: client.ObjectKeyFromObject(or).String(),
+		v2.SourceIndexKey: sourceIndexValue(sourcev1beta2.OCIRepositoryKind, client.ObjectKeyFromObject(or)),
 	}); err != nil {
 		ctrl.LoggerFrom(ctx).Error(err, "failed to list HelmReleases for OCIRepository change")
 		return nil
 	}
 
+	// If we cannot retrieve the artifact digest, we have no requests to make.
+	digest := extractDigest(or.GetArtifact().Revision)
+	if digest == "" {
+		ctrl.LoggerFrom(ctx).Error(fmt.Errorf("wrong digest for %T", or), "failed to get requests for OCIRepository change")
+		return nil
+	}
+
 	var reqs []reconcile.Request
 	for i, hr := range list.Items {
-		// If the HelmRelease is ready and the digest of the artifact equals to the
-		// last attempted revision digest, we should not make a request for this HelmRelease,
-		// likewise if we cannot retrieve the artifact digest.
-		digest := extractDigest(or.GetArtifact().Revision)
-		if digest == "" {
-			ctrl.LoggerFrom(ctx).Error(fmt.Errorf("wrong digest for %T", or), "failed to get requests for OCIRepository change")
-			continue
-		}
-
+		// If the digest of the artifact equals to the last attempted revision
+		// digest, we should not make a request for this HelmRelease.
 		if digest == hr.Status.LastAttemptedRevisionDigest {
 			continue
 		}
@@ -836,6 +1800,157 @@ func (r *HelmReleaseReconciler) requestsForOCIRrepositoryChange(ctx context.Cont
 	return reqs
 }
 
+// requestsForNamespaceChange enqueues the HelmReleases in the given
+// Namespace of which the target or storage namespace differs from the
+// Namespace, as the intacl.PrivilegedLabel of the Namespace determines
+// whether they are allowed to release.
+func (r *HelmReleaseReconciler) requestsForNamespaceChange(ctx context.Context, o client.Object) []reconcile.Request {
+	var list v2.HelmReleaseList
+	if err := r.List(ctx, &list, client.InNamespace(o.GetName())); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list HelmReleases for Namespace change")
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for i, hr := range list.Items {
+		if hr.GetReleaseNamespace() != hr.GetNamespace() || hr.GetStorageNamespace() != hr.GetNamespace() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+	return reqs
+}
+
+// requestsForPullSecretChange returns the requests for the HelmReleases of
+// which the source failed to authenticate with the credentials in the given
+// Secret, to let their source retry with the rotated credentials.
+func (r *HelmReleaseReconciler) requestsForPullSecretChange(ctx context.Context, o client.Object) []reconcile.Request {
+	var sources []string
+
+	var ociRepos sourcev1beta2.OCIRepositoryList
+	if err := r.List(ctx, &ociRepos, client.InNamespace(o.GetNamespace()),
+		client.MatchingFields{secretRefIndexKey: o.GetName()}); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list OCIRepositories for Secret change")
+		return nil
+	}
+	for i := range ociRepos.Items {
+		sources = append(sources, sourceIndexValue(sourcev1beta2.OCIRepositoryKind, client.ObjectKeyFromObject(&ociRepos.Items[i])))
+	}
+
+	var helmRepos sourcev1.HelmRepositoryList
+	if err := r.List(ctx, &helmRepos, client.InNamespace(o.GetNamespace()),
+		client.MatchingFields{secretRefIndexKey: o.GetName()}); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list HelmRepositories for Secret change")
+		return nil
+	}
+	repos := make(map[string]struct{})
+	for _, repo := range helmRepos.Items {
+		repos[repo.Name] = struct{}{}
+	}
+	if len(repos) > 0 {
+		var charts sourcev1.HelmChartList
+		if err := r.List(ctx, &charts, client.InNamespace(o.GetNamespace())); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to list HelmCharts for Secret change")
+			return nil
+		}
+		for i, hc := range charts.Items {
+			if _, ok := repos[hc.Spec.SourceRef.Name]; ok && hc.Spec.SourceRef.Kind == sourcev1.HelmRepositoryKind {
+				sources = append(sources, sourceIndexValue(sourcev1.HelmChartKind, client.ObjectKeyFromObject(&charts.Items[i])))
+			}
+		}
+	}
+
+	var reqs []reconcile.Request
+	for _, source := range sources {
+		var list v2.HelmReleaseList
+		if err := r.List(ctx, &list, client.MatchingFields{v2.SourceIndexKey: source}); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to list HelmReleases for Secret change")
+			return nil
+		}
+		for i := range list.Items {
+			if conditions.GetReason(&list.Items[i], meta.ReadyCondition) == v2.AuthenticationFailedReason {
+				reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+			}
+		}
+	}
+	return reqs
+}
+
+// pullSecretRef returns the reference to the Secret with the credentials the
+// given source fetches the chart with, or nil if it does not have one.
+func (r *HelmReleaseReconciler) pullSecretRef(ctx context.Context, source sourcev1.Source) (*types.NamespacedName, error) {
+	switch s := source.(type) {
+	case *sourcev1beta2.OCIRepository:
+		if s.Spec.SecretRef == nil {
+			return nil, nil
+		}
+		return &types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.SecretRef.Name}, nil
+	case *sourcev1.HelmChart:
+		if s.Spec.SourceRef.Kind != sourcev1.HelmRepositoryKind {
+			return nil, nil
+		}
+		var repo sourcev1.HelmRepository
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.SourceRef.Name}, &repo); err != nil {
+			return nil, fmt.Errorf("failed to get HelmRepository of chart: %w", err)
+		}
+		if repo.Spec.SecretRef == nil {
+			return nil, nil
+		}
+		return &types.NamespacedName{Namespace: repo.Namespace, Name: repo.Spec.SecretRef.Name}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// requestSourceReconcile requests the reconciliation of the given source,
+// once per revision of the Secret with the credentials it fetches the chart
+// with. This lets the source retry to fetch the chart after the credentials
+// have been rotated, instead of at its next interval.
+func (r *HelmReleaseReconciler) requestSourceReconcile(ctx context.Context, source sourcev1.Source) error {
+	ref, err := r.pullSecretRef(ctx, source)
+	if err != nil || ref == nil {
+		return err
+	}
+	secret := &metav1.PartialObjectMetadata{}
+	secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	if err = r.Client.Get(ctx, *ref, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	obj, ok := source.(client.Object)
+	if !ok {
+		return nil
+	}
+	requestedAt := "secret/" + secret.GetResourceVersion()
+	if obj.GetAnnotations()[meta.ReconcileRequestAnnotation] == requestedAt {
+		return nil
+	}
+	p := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations[meta.ReconcileRequestAnnotation] = requestedAt
+	obj.SetAnnotations(annotations)
+	if err = r.Client.Patch(ctx, obj, p); err != nil {
+		return fmt.Errorf("failed to request reconciliation of %s '%s/%s': %w",
+			obj.GetObjectKind().GroupVersionKind().Kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	ctrl.LoggerFrom(ctx).Info(fmt.Sprintf("requested reconciliation of source '%s/%s' with credentials of Secret '%s' at revision %s",
+		obj.GetNamespace(), obj.GetName(), ref.Name, secret.GetResourceVersion()))
+	return nil
+}
+
+// isSourceAuthenticationFailed returns if the given source failed to
+// authenticate to fetch the chart.
+func isSourceAuthenticationFailed(obj sourcev1.Source) bool {
+	o, ok := obj.(conditions.Getter)
+	if !ok {
+		return false
+	}
+	return conditions.GetReason(o, meta.ReadyCondition) == sourcev1.AuthenticationFailedReason ||
+		conditions.GetReason(o, sourcev1.FetchFailedCondition) == sourcev1.AuthenticationFailedReason
+}
+
 func isSourceReady(obj sourcev1.Source) (bool, string) {
 	if o, ok := obj.(conditions.Getter); ok {
 		return isReady(o, obj.GetArtifact())
@@ -895,6 +2010,74 @@ func getNamespacedName(obj *v2.HelmRelease) (types.NamespacedName, error) {
 	return namespacedName, nil
 }
 
+// indexBySource returns the v2.SourceIndexKey index values of the given
+// HelmRelease.
+func indexBySource(o client.Object) []string {
+	obj := o.(*v2.HelmRelease)
+	kind := sourcev1.HelmChartKind
+	if obj.HasChartRef() {
+		kind = obj.Spec.ChartRef.Kind
+	}
+	namespacedName, err := getNamespacedName(obj)
+	if err != nil {
+		return nil
+	}
+	return []string{
+		sourceIndexValue(kind, namespacedName),
+	}
+}
+
+// secretRefIndexKey is the key used for indexing sources based on the name
+// of the Secret with their credentials.
+const secretRefIndexKey = ".spec.secretRef.name"
+
+// indexOCIRepositoryBySecretRef indexes an OCIRepository by the name of the
+// Secret with its credentials.
+func indexOCIRepositoryBySecretRef(o client.Object) []string {
+	obj := o.(*sourcev1beta2.OCIRepository)
+	if obj.Spec.SecretRef == nil {
+		return nil
+	}
+	return []string{obj.Spec.SecretRef.Name}
+}
+
+// indexHelmRepositoryBySecretRef indexes a HelmRepository by the name of the
+// Secret with its credentials.
+func indexHelmRepositoryBySecretRef(o client.Object) []string {
+	obj := o.(*sourcev1.HelmRepository)
+	if obj.Spec.SecretRef == nil {
+		return nil
+	}
+	return []string{obj.Spec.SecretRef.Name}
+}
+
+// privilegedNamespacePredicate filters the update events of the Namespaces
+// of which the intacl.PrivilegedLabel changed.
+var privilegedNamespacePredicate = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if e.ObjectOld == nil || e.ObjectNew == nil {
+			return false
+		}
+		return e.ObjectOld.GetLabels()[intacl.PrivilegedLabel] != e.ObjectNew.GetLabels()[intacl.PrivilegedLabel]
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// pullSecretPredicate filters the events of the Secrets which are labeled
+// with v2.PullSecretWatchLabel.
+var pullSecretPredicate = predicate.NewPredicateFuncs(func(o client.Object) bool {
+	return o.GetLabels()[v2.PullSecretWatchLabel] == "true"
+})
+
+// sourceIndexValue returns the value of the v2.SourceIndexKey index for a
+// Source of the given kind and name. The kind is included, as a HelmChart and
+// OCIRepository in the same namespace can have the same name.
+func sourceIndexValue(kind string, namespacedName types.NamespacedName) string {
+	return kind + "/" + namespacedName.String()
+}
+
 func mutateChartWithSourceRevision(chart *chart.Chart, source sourcev1.Source) (string, error) {
 	// If the source is an OCIRepository, we can try to mutate the chart version
 	// with the artifact revision. The revision is either a <tag>@<digest> or
@@ -951,6 +2134,198 @@ func mutateChartWithSourceRevision(chart *chart.Chart, source sourcev1.Source) (
 	return ociDigest, nil
 }
 
+const (
+	// provenanceSourceKey is the provenance key of the source the chart
+	// originates from, in the format of "<kind>/<namespace>/<name>".
+	provenanceSourceKey = "source"
+	// provenanceRevisionKey is the provenance key of the revision of the
+	// source the chart originates from.
+	provenanceRevisionKey = "source-revision"
+	// ociAnnotationPrefix is the prefix of the OCI annotations of an
+	// artifact which are recorded as provenance.
+	ociAnnotationPrefix = "org.opencontainers.image."
+)
+
+// sourceProvenance returns the provenance of the chart of the given source.
+// For a HelmChart, this is the source of the HelmChart and the revision of
+// its artifact (e.g. the Git commit). For an OCIRepository, this is the
+// OCIRepository and the revision of its artifact. The OCI annotations of the
+// artifact, such as the revision and authors recorded by the publisher, are
+// included as is.
+func sourceProvenance(source sourcev1.Source) map[string]string {
+	provenance := make(map[string]string)
+	switch obj := source.(type) {
+	case *sourcev1.HelmChart:
+		provenance[provenanceSourceKey] = fmt.Sprintf("%s/%s/%s", obj.Spec.SourceRef.Kind, obj.GetNamespace(), obj.Spec.SourceRef.Name)
+		// The revision of a HelmRepository is the digest of its index,
+		// which does not identify the origin of the chart.
+		if rev := obj.Status.ObservedSourceArtifactRevision; rev != "" && obj.Spec.SourceRef.Kind != sourcev1.HelmRepositoryKind {
+			provenance[provenanceRevisionKey] = rev
+		}
+	case *sourcev1beta2.OCIRepository:
+		provenance[provenanceSourceKey] = fmt.Sprintf("%s/%s/%s", sourcev1beta2.OCIRepositoryKind, obj.GetNamespace(), obj.GetName())
+		if artifact := obj.GetArtifact(); artifact != nil {
+			provenance[provenanceRevisionKey] = artifact.Revision
+		}
+	default:
+		return nil
+	}
+	if artifact := source.GetArtifact(); artifact != nil {
+		for k, v := range artifact.Metadata {
+			if strings.HasPrefix(k, ociAnnotationPrefix) {
+				provenance[k] = v
+			}
+		}
+	}
+	return provenance
+}
+
+// markSuspended marks Suspended=True on the object, with the suspend reason
+// of the object as message if set.
+func markSuspended(obj *v2.HelmRelease) {
+	msg := "Reconciliation is suspended"
+	if obj.Spec.SuspendReason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, obj.Spec.SuspendReason)
+	}
+	conditions.MarkTrue(obj, v2.SuspendedCondition, v2.ReconciliationSuspendedReason, "%s", msg)
+}
+
+// scanChart checks the vulnerability attestations of the chart of the given
+// source against the policy of chartscan.ChartScanner, if configured, and
+// records the result in the SecurityScanPassed condition and the metrics.
+// It returns an error if the chart must not be installed or upgraded
+// according to the policy.
+func (r *HelmReleaseReconciler) scanChart(ctx context.Context, obj *v2.HelmRelease, source sourcev1.Source, chartVersion string) error {
+	if chartscan.ChartScanner == nil {
+		conditions.Delete(obj, v2.SecurityScanPassedCondition)
+		metrics.DeleteChartSecurityScan(obj.GetName(), obj.GetNamespace())
+		return nil
+	}
+	ref, err := r.chartOCIReference(ctx, source, chartVersion)
+	if err != nil {
+		return err
+	}
+	if ref == "" {
+		conditions.Delete(obj, v2.SecurityScanPassedCondition)
+		metrics.DeleteChartSecurityScan(obj.GetName(), obj.GetNamespace())
+		return nil
+	}
+
+	report, err := chartscan.ChartScanner.Scan(ctx, ref)
+	if err != nil {
+		conditions.MarkUnknown(obj, v2.SecurityScanPassedCondition, v2.SecurityScanErrorReason, "%s", err.Error())
+		metrics.DeleteChartSecurityScan(obj.GetName(), obj.GetNamespace())
+		if chartscan.ChartScanner.Enforces(ref) {
+			return fmt.Errorf("failed to check vulnerability attestations of chart: %w", err)
+		}
+		ctrl.LoggerFrom(ctx).Error(err, "failed to check vulnerability attestations of chart")
+		return nil
+	}
+	if report == nil {
+		conditions.Delete(obj, v2.SecurityScanPassedCondition)
+		metrics.DeleteChartSecurityScan(obj.GetName(), obj.GetNamespace())
+		return nil
+	}
+
+	vulnerabilities := make(map[string]int, len(report.Vulnerabilities))
+	for severity, n := range report.Vulnerabilities {
+		vulnerabilities[string(severity)] = n
+	}
+	metrics.RecordChartSecurityScan(obj.GetName(), obj.GetNamespace(), report.Passed(), vulnerabilities)
+
+	if report.Passed() {
+		conditions.MarkTrue(obj, v2.SecurityScanPassedCondition, v2.SecurityScanSucceededReason,
+			"Chart %s: %s", report.Reference, report.Summary())
+		return nil
+	}
+	msg := fmt.Sprintf("Chart %s failed security scan: %s", report.Reference, report.Summary())
+	conditions.MarkFalse(obj, v2.SecurityScanPassedCondition, v2.SecurityScanFailedReason, "%s", msg)
+	r.Eventf(obj, corev1.EventTypeWarning, v2.SecurityScanFailedReason, "%s", msg)
+	if report.Blocked() {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// chartOCIReference returns the OCI reference of the chart of the given
+// source with the given version, or an empty string if the chart is not
+// stored in an OCI registry.
+func (r *HelmReleaseReconciler) chartOCIReference(ctx context.Context, source sourcev1.Source, chartVersion string) (string, error) {
+	switch s := source.(type) {
+	case *sourcev1beta2.OCIRepository:
+		// The revision is either a <tag>@<digest> or just a digest.
+		revision := s.GetArtifact().Revision
+		if _, d, found := strings.Cut(revision, "@"); found {
+			revision = d
+		}
+		return strings.TrimPrefix(s.Spec.URL, "oci://") + "@" + revision, nil
+	case *sourcev1.HelmChart:
+		if s.Spec.SourceRef.Kind != sourcev1.HelmRepositoryKind {
+			return "", nil
+		}
+		var repo sourcev1.HelmRepository
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.SourceRef.Name}, &repo); err != nil {
+			return "", fmt.Errorf("failed to get HelmRepository of chart: %w", err)
+		}
+		if repo.Spec.Type != sourcev1.HelmRepositoryTypeOCI {
+			return "", nil
+		}
+		// Helm replaces the '+' of a chart version with '_' in OCI tags.
+		return fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(strings.TrimPrefix(repo.Spec.URL, "oci://"), "/"),
+			s.Spec.Chart, strings.ReplaceAll(chartVersion, "+", "_")), nil
+	default:
+		return "", nil
+	}
+}
+
+// observeUpgradeAvailable marks UpgradeAvailable=True on the object when the
+// source advertises a newer chart version than the version of the latest
+// release. Otherwise, it removes the condition.
+func observeUpgradeAvailable(obj *v2.HelmRelease, source sourcev1.Source) {
+	cur := obj.Status.History.Latest()
+	candidate := advertisedChartVersion(source)
+	if cur == nil || candidate == nil {
+		conditions.Delete(obj, v2.UpgradeAvailableCondition)
+		return
+	}
+
+	deployed, err := semver.NewVersion(cur.ChartVersion)
+	if err != nil || !candidate.GreaterThan(deployed) {
+		conditions.Delete(obj, v2.UpgradeAvailableCondition)
+		return
+	}
+
+	conditions.MarkTrue(obj, v2.UpgradeAvailableCondition, v2.NewerVersionAvailableReason,
+		"Chart version %s is available for release %s with chart %s", candidate.Original(),
+		cur.FullReleaseName(), cur.VersionedChartName())
+}
+
+// advertisedChartVersion returns the chart version of the artifact of the
+// source, or nil if it can not be determined. For an OCIRepository, this is
+// the tag of the artifact revision.
+func advertisedChartVersion(source sourcev1.Source) *semver.Version {
+	artifact := source.GetArtifact()
+	if artifact == nil {
+		return nil
+	}
+
+	revision := artifact.Revision
+	if _, ok := source.(*sourcev1beta2.OCIRepository); ok {
+		tag, _, found := strings.Cut(revision, "@")
+		if !found {
+			// The revision is just a digest.
+			return nil
+		}
+		revision = tag
+	}
+
+	ver, err := semver.NewVersion(revision)
+	if err != nil {
+		return nil
+	}
+	return ver
+}
+
 func extractDigestSubString(revision string) (string, error) {
 	var sha string
 	// expects a revision in the <algorithm>:<digest> format
@@ -27,6 +27,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/opencontainers/go-digest"
 	"helm.sh/helm/v3/pkg/chart"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	helmstorage "helm.sh/helm/v3/pkg/storage"
 	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
@@ -39,9 +40,11 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/yaml"
 
@@ -49,6 +52,7 @@ import (
 	aclv1 "github.com/fluxcd/pkg/apis/acl"
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/conditions"
+	helper "github.com/fluxcd/pkg/runtime/controller"
 	feathelper "github.com/fluxcd/pkg/runtime/features"
 	"github.com/fluxcd/pkg/runtime/patch"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
@@ -241,6 +245,90 @@ func TestHelmReleaseReconciler_reconcileRelease(t *testing.T) {
 		}))
 	})
 
+	t.Run("reports source authentication failure", func(t *testing.T) {
+		g := NewWithT(t)
+
+		repo := &sourcev1.HelmRepository{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "repo",
+				Namespace: "mock",
+			},
+			Spec: sourcev1.HelmRepositorySpec{
+				Type:      sourcev1.HelmRepositoryTypeOCI,
+				SecretRef: &meta.LocalObjectReference{Name: "registry"},
+			},
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registry",
+				Namespace: "mock",
+			},
+		}
+		chart := &sourcev1.HelmChart{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: sourcev1.GroupVersion.String(),
+				Kind:       sourcev1.HelmChartKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "chart",
+				Namespace:  "mock",
+				Generation: 1,
+			},
+			Spec: sourcev1.HelmChartSpec{
+				SourceRef: sourcev1.LocalHelmChartSourceReference{
+					Kind: sourcev1.HelmRepositoryKind,
+					Name: "repo",
+				},
+			},
+			Status: sourcev1.HelmChartStatus{
+				ObservedGeneration: 1,
+				Conditions: []metav1.Condition{
+					{
+						Type:    meta.ReadyCondition,
+						Status:  metav1.ConditionFalse,
+						Reason:  sourcev1.AuthenticationFailedReason,
+						Message: "failed to login to OCI registry",
+					},
+				},
+			},
+		}
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "release",
+				Namespace: "mock",
+			},
+			Spec: v2.HelmReleaseSpec{
+				Interval: metav1.Duration{Duration: 1 * time.Second},
+			},
+			Status: v2.HelmReleaseStatus{
+				HelmChart: "mock/chart",
+			},
+		}
+
+		r := &HelmReleaseReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(NewTestScheme()).
+				WithStatusSubresource(&v2.HelmRelease{}).
+				WithObjects(repo, secret, chart, obj).
+				Build(),
+		}
+
+		_, err := r.reconcileRelease(context.TODO(), patch.NewSerialPatcher(obj, r.Client), obj)
+		g.Expect(err).To(Equal(errWaitForChart))
+
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(meta.ReconcilingCondition, meta.ProgressingReason, ""),
+			*conditions.FalseCondition(meta.ReadyCondition, v2.AuthenticationFailedReason, "HelmChart 'mock/chart' is not ready"),
+		}))
+
+		// The reconciliation of the HelmChart has been requested for the
+		// current revision of the Secret.
+		g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+		g.Expect(r.Client.Get(context.TODO(), client.ObjectKeyFromObject(chart), chart)).To(Succeed())
+		g.Expect(chart.GetAnnotations()).To(HaveKeyWithValue(meta.ReconcileRequestAnnotation, "secret/"+secret.ResourceVersion))
+	})
+
 	t.Run("waits for HelmChart ObservedGeneration to equal Generation", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -585,6 +673,151 @@ func TestHelmReleaseReconciler_reconcileRelease(t *testing.T) {
 		g.Expect(obj.Status.StorageNamespace).To(BeEmpty())
 	})
 
+	t.Run("moves release records if storage driver has changed", func(t *testing.T) {
+		g := NewWithT(t)
+
+		chartMock := testutil.BuildChart()
+		chartArtifact, err := testutil.SaveChartAsArtifact(chartMock, digest.SHA256, testServer.URL(), testServer.Root())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		chart := &sourcev1.HelmChart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "chart",
+				Namespace:  "mock",
+				Generation: 1,
+			},
+			Status: sourcev1.HelmChartStatus{
+				ObservedGeneration: 1,
+				Artifact:           chartArtifact,
+				Conditions: []metav1.Condition{
+					{
+						Type:   meta.ReadyCondition,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		}
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "release",
+				Namespace: "mock",
+			},
+			Spec: v2.HelmReleaseSpec{
+				StorageDriver: "configmap",
+			},
+			Status: v2.HelmReleaseStatus{
+				History: v2.Snapshots{
+					{
+						Name:      "mock",
+						Namespace: "mock",
+					},
+				},
+				HelmChart:        "mock/chart",
+				StorageNamespace: "mock",
+				StorageDriver:    helmdriver.SecretsDriverName,
+			},
+		}
+
+		c := fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithStatusSubresource(&v2.HelmRelease{}).
+			WithObjects(chart, obj).
+			Build()
+
+		r := &HelmReleaseReconciler{
+			Client:           c,
+			GetClusterConfig: GetTestClusterConfig,
+			EventRecorder:    record.NewFakeRecorder(32),
+		}
+
+		res, err := r.reconcileRelease(context.TODO(), patch.NewSerialPatcher(obj, c), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(res.Requeue).To(BeTrue())
+
+		// Verify the release is not uninstalled, and the new storage driver
+		// is recorded.
+		g.Expect(obj.Status.Conditions).To(BeEmpty())
+		g.Expect(obj.Status.History).To(HaveLen(1))
+		g.Expect(obj.Status.StorageNamespace).To(Equal("mock"))
+		g.Expect(obj.Status.StorageDriver).To(Equal(helmdriver.ConfigMapsDriverName))
+	})
+
+	t.Run("stalls if release records can not be moved to new storage driver", func(t *testing.T) {
+		g := NewWithT(t)
+
+		chartMock := testutil.BuildChart()
+		chartArtifact, err := testutil.SaveChartAsArtifact(chartMock, digest.SHA256, testServer.URL(), testServer.Root())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		chart := &sourcev1.HelmChart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "chart",
+				Namespace:  "mock",
+				Generation: 1,
+			},
+			Status: sourcev1.HelmChartStatus{
+				ObservedGeneration: 1,
+				Artifact:           chartArtifact,
+				Conditions: []metav1.Condition{
+					{
+						Type:   meta.ReadyCondition,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		}
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "release",
+				Namespace: "mock",
+			},
+			Spec: v2.HelmReleaseSpec{
+				StorageDriver: "configmap",
+			},
+			Status: v2.HelmReleaseStatus{
+				History: v2.Snapshots{
+					{
+						Name:      "mock",
+						Namespace: "mock",
+					},
+				},
+				HelmChart:        "mock/chart",
+				StorageNamespace: "mock",
+				StorageDriver:    helmdriver.SQLDriverName,
+			},
+		}
+
+		c := fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithStatusSubresource(&v2.HelmRelease{}).
+			WithObjects(chart, obj).
+			Build()
+
+		// The SQL storage driver can not be configured without a
+		// connection string.
+		r := &HelmReleaseReconciler{
+			StorageDriver:    helmdriver.SQLDriverName,
+			Client:           c,
+			GetClusterConfig: GetTestClusterConfig,
+			EventRecorder:    record.NewFakeRecorder(32),
+		}
+
+		_, err = r.reconcileRelease(context.TODO(), patch.NewSerialPatcher(obj, c), obj)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeFalse())
+
+		g.Expect(conditions.IsStalled(obj)).To(BeFalse())
+		g.Expect(conditions.GetReason(obj, meta.ReadyCondition)).To(Equal(v2.StorageMigrationFailedReason))
+		g.Expect(conditions.GetMessage(obj, meta.ReadyCondition)).To(HavePrefix(
+			"failed to move release records from 'sql' to 'configmap' storage driver"))
+
+		// Verify the release is not uninstalled.
+		g.Expect(obj.Status.History).To(HaveLen(1))
+		g.Expect(obj.Status.StorageDriver).To(Equal(helmdriver.SQLDriverName))
+	})
+
 	t.Run("resets failure counts on configuration change", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -1434,6 +1667,48 @@ func TestHelmReleaseReconciler_reconcileReleaseFromOCIRepositorySource(t *testin
 		}))
 	})
 
+	t.Run("handles chart source policy error for ChartRef", func(t *testing.T) {
+		g := NewWithT(t)
+
+		curPolicy := intacl.ChartSourcePolicy
+		intacl.ChartSourcePolicy = &intacl.SourcePolicy{
+			Namespaces: map[string][]string{"mock": {"OCIRepository/flux-system/*"}},
+		}
+		t.Cleanup(func() { intacl.ChartSourcePolicy = curPolicy })
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "release",
+				Namespace: "mock",
+			},
+			Spec: v2.HelmReleaseSpec{
+				ChartRef: &v2.CrossNamespaceSourceReference{
+					Kind: sourcev1beta2.OCIRepositoryKind,
+					Name: "ocirepo",
+				},
+			},
+		}
+
+		r := &HelmReleaseReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(NewTestScheme()).
+				WithStatusSubresource(&v2.HelmRelease{}).
+				WithObjects(obj).
+				Build(),
+			EventRecorder: record.NewFakeRecorder(32),
+		}
+
+		res, err := r.reconcileRelease(context.TODO(), patch.NewSerialPatcher(obj, r.Client), obj)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeTrue())
+		g.Expect(res.IsZero()).To(BeTrue())
+
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(meta.StalledCondition, acl.AccessDeniedReason, "chart source OCIRepository mock/ocirepo is not allowed"),
+			*conditions.FalseCondition(meta.ReadyCondition, acl.AccessDeniedReason, "chart source OCIRepository mock/ocirepo is not allowed"),
+		}))
+	})
+
 	t.Run("waits for ChartRef to have an Artifact", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -2161,24 +2436,89 @@ func TestHelmReleaseReconciler_reconcileDelete(t *testing.T) {
 		}).Should(Succeed())
 	})
 
-	t.Run("removes finalizer for suspended resource with DeletionTimestamp", func(t *testing.T) {
+	t.Run("does not uninstall pinned Helm release", func(t *testing.T) {
 		g := NewWithT(t)
 
+		// Create a test namespace for storing the Helm release mock.
+		ns, err := testEnv.CreateNamespace(context.TODO(), "reconcile-delete-pinned")
+		g.Expect(err).ToNot(HaveOccurred())
+		t.Cleanup(func() {
+			_ = testEnv.Delete(context.TODO(), ns)
+		})
+
+		// Create a test Helm release storage mock.
+		rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+			Name:      "reconcile-delete-pinned",
+			Namespace: ns.Name,
+			Version:   1,
+			Chart:     testutil.BuildChart(testutil.ChartWithTestHook()),
+			Status:    helmrelease.StatusDeployed,
+		})
+
 		obj := &v2.HelmRelease{
 			ObjectMeta: metav1.ObjectMeta{
-				Finalizers:        []string{v2.HelmReleaseFinalizer, "other-finalizer"},
+				Name:              "reconcile-delete-pinned",
+				Namespace:         ns.Name,
+				Finalizers:        []string{v2.HelmReleaseFinalizer},
 				DeletionTimestamp: &metav1.Time{Time: time.Now()},
 			},
 			Spec: v2.HelmReleaseSpec{
-				Suspend: true,
+				Pin: true,
+			},
+			Status: v2.HelmReleaseStatus{
+				StorageNamespace: ns.Name,
+				History: v2.Snapshots{
+					release.ObservedToSnapshot(release.ObserveRelease(rls)),
+				},
 			},
 		}
 
-		res, err := (&HelmReleaseReconciler{}).reconcileDelete(context.TODO(), obj)
+		r := &HelmReleaseReconciler{
+			Client:           testEnv.Client,
+			GetClusterConfig: GetTestClusterConfig,
+			EventRecorder:    record.NewFakeRecorder(32),
+		}
+
+		// Store the Helm release mock in the test namespace.
+		getter, err := r.buildRESTClientGetter(context.TODO(), obj)
 		g.Expect(err).ToNot(HaveOccurred())
-		g.Expect(res.IsZero()).To(BeTrue())
 
-		g.Expect(obj.GetFinalizers()).To(ConsistOf("other-finalizer"))
+		cfg, err := action.NewConfigFactory(getter, action.WithStorage(helmdriver.SecretsDriverName, obj.Status.StorageNamespace))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		store := helmstorage.Init(cfg.Driver)
+		g.Expect(store.Create(rls)).To(Succeed())
+
+		// Reconcile the deletion of the object.
+		res, err := r.reconcileDelete(context.TODO(), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(res.IsZero()).To(BeTrue())
+		g.Expect(obj.Finalizers).To(BeEmpty())
+
+		// Verify Helm release has been left in place.
+		history, err := store.History(rls.Name)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(history).To(HaveLen(1))
+	})
+
+	t.Run("removes finalizer for suspended resource with DeletionTimestamp", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Finalizers:        []string{v2.HelmReleaseFinalizer, "other-finalizer"},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+			Spec: v2.HelmReleaseSpec{
+				Suspend: true,
+			},
+		}
+
+		res, err := (&HelmReleaseReconciler{}).reconcileDelete(context.TODO(), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(res.IsZero()).To(BeTrue())
+
+		g.Expect(obj.GetFinalizers()).To(ConsistOf("other-finalizer"))
 	})
 
 	t.Run("does not remove finalizer when DeletionTimestamp is not set", func(t *testing.T) {
@@ -2201,6 +2541,164 @@ func TestHelmReleaseReconciler_reconcileDelete(t *testing.T) {
 	})
 }
 
+func TestHelmReleaseReconciler_reconcileDelete_forceTimeout(t *testing.T) {
+	newObj := func(deletedAt time.Time) *v2.HelmRelease {
+		return &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "force-delete",
+				Namespace:         "default",
+				Finalizers:        []string{v2.HelmReleaseFinalizer},
+				DeletionTimestamp: &metav1.Time{Time: deletedAt},
+			},
+			Spec: v2.HelmReleaseSpec{
+				Uninstall: &v2.Uninstall{
+					ForceTimeout: &metav1.Duration{Duration: time.Minute},
+				},
+			},
+			Status: v2.HelmReleaseStatus{
+				StorageNamespace: "default",
+				Inventory: &v2.ResourceInventory{
+					Entries: []v2.ResourceRef{
+						{ID: "default_config__ConfigMap", Version: "v1"},
+						{ID: "default_app_apps_Deployment", Version: "v1"},
+					},
+				},
+			},
+		}
+	}
+
+	newReconciler := func(recorder *record.FakeRecorder) *HelmReleaseReconciler {
+		return &HelmReleaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(NewTestScheme()).Build(),
+			GetClusterConfig: func() (*rest.Config, error) {
+				return nil, errors.New("cluster unreachable")
+			},
+			EventRecorder: recorder,
+		}
+	}
+
+	t.Run("retries uninstall before force timeout", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := newObj(time.Now())
+		_, err := newReconciler(record.NewFakeRecorder(32)).reconcileDelete(context.TODO(), obj)
+		g.Expect(err).To(MatchError(ContainSubstring("cluster unreachable")))
+		g.Expect(obj.Finalizers).To(ContainElement(v2.HelmReleaseFinalizer))
+		g.Expect(obj.Status.StorageNamespace).To(Equal("default"))
+		g.Expect(obj.Status.UninstallFailures).To(Equal(int64(1)))
+	})
+
+	t.Run("orphans release after force timeout", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := record.NewFakeRecorder(32)
+		obj := newObj(time.Now().Add(-2 * time.Minute))
+		_, err := newReconciler(recorder).reconcileDelete(context.TODO(), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(obj.Finalizers).ToNot(ContainElement(v2.HelmReleaseFinalizer))
+		g.Expect(obj.Status.StorageNamespace).To(BeEmpty())
+		g.Expect(obj.Status.Inventory).To(BeNil())
+
+		g.Expect(recorder.Events).To(Receive(Equal(corev1.EventTypeWarning + " " + v2.UninstallForcedReason + " " +
+			"Helm uninstall for release default/force-delete did not succeed within 1m0s after deletion, " +
+			"orphaning the Helm release with 2 resources: default_config__ConfigMap, default_app_apps_Deployment. " +
+			"Last error: could not get in-cluster REST config: cluster unreachable")))
+	})
+}
+
+func TestHelmReleaseReconciler_reconcileDelete_retry(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "retry-delete",
+			Namespace:         "default",
+			Finalizers:        []string{v2.HelmReleaseFinalizer},
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+		},
+		Spec: v2.HelmReleaseSpec{
+			Uninstall: &v2.Uninstall{
+				Retry: &v2.UninstallRetry{
+					Interval:    metav1.Duration{Duration: 10 * time.Second},
+					MaxInterval: &metav1.Duration{Duration: 30 * time.Second},
+				},
+			},
+		},
+		Status: v2.HelmReleaseStatus{
+			StorageNamespace: "default",
+		},
+	}
+	r := &HelmReleaseReconciler{
+		Client: fake.NewClientBuilder().WithScheme(NewTestScheme()).Build(),
+		GetClusterConfig: func() (*rest.Config, error) {
+			return nil, errors.New("cluster unreachable")
+		},
+		EventRecorder: record.NewFakeRecorder(32),
+	}
+
+	var delays []time.Duration
+	for i := 0; i < v2.MaxUninstallAttempts+2; i++ {
+		res, err := r.reconcileDelete(context.TODO(), obj)
+		g.Expect(err).To(MatchError(errRetryUninstall))
+		delays = append(delays, res.RequeueAfter)
+	}
+	g.Expect(delays).To(HaveExactElements(10*time.Second, 20*time.Second, 30*time.Second,
+		30*time.Second, 30*time.Second, 30*time.Second, 30*time.Second))
+	g.Expect(obj.Finalizers).To(ContainElement(v2.HelmReleaseFinalizer))
+
+	g.Expect(obj.Status.UninstallFailures).To(Equal(int64(v2.MaxUninstallAttempts + 2)))
+	g.Expect(obj.Status.UninstallAttempts).To(HaveLen(v2.MaxUninstallAttempts))
+	for _, a := range obj.Status.UninstallAttempts {
+		g.Expect(a.Reason).To(Equal(v2.UninstallFailedReason))
+		g.Expect(a.Message).To(ContainSubstring("cluster unreachable"))
+		g.Expect(a.Time.IsZero()).To(BeFalse())
+	}
+}
+
+func Test_uninstallRetryDelay(t *testing.T) {
+	now := time.Now()
+	retry := v2.UninstallRetry{Interval: metav1.Duration{Duration: time.Minute}}
+
+	tests := []struct {
+		name         string
+		failures     int64
+		forceTimeout *metav1.Duration
+		want         time.Duration
+	}{
+		{name: "first retry", failures: 1, want: time.Minute},
+		{name: "exponential backoff", failures: 3, want: 4 * time.Minute},
+		{name: "default max interval", failures: 10, want: 15 * time.Minute},
+		{
+			name:         "retry after force timeout",
+			failures:     3,
+			forceTimeout: &metav1.Duration{Duration: 2 * time.Minute},
+			want:         time.Minute + time.Second,
+		},
+		{
+			name:         "force timeout after delay",
+			failures:     1,
+			forceTimeout: &metav1.Duration{Duration: time.Hour},
+			want:         time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			obj := &v2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: now.Add(-time.Minute)},
+				},
+				Spec: v2.HelmReleaseSpec{
+					Uninstall: &v2.Uninstall{ForceTimeout: tt.forceTimeout},
+				},
+				Status: v2.HelmReleaseStatus{UninstallFailures: tt.failures},
+			}
+			g.Expect(uninstallRetryDelay(obj, retry, now)).To(Equal(tt.want))
+		})
+	}
+}
+
 func TestHelmReleaseReconciler_reconcileReleaseDeletion(t *testing.T) {
 	t.Run("uninstalls Helm release", func(t *testing.T) {
 		g := NewWithT(t)
@@ -2264,6 +2762,138 @@ func TestHelmReleaseReconciler_reconcileReleaseDeletion(t *testing.T) {
 		g.Expect(err).To(MatchError(helmdriver.ErrReleaseNotFound))
 	})
 
+	t.Run("orphans Helm release with Orphan deletion policy", func(t *testing.T) {
+		g := NewWithT(t)
+
+		// Create a test namespace for storing the Helm release mock.
+		ns, err := testEnv.CreateNamespace(context.TODO(), "reconcile-release-deletion")
+		g.Expect(err).ToNot(HaveOccurred())
+		t.Cleanup(func() {
+			_ = testEnv.Delete(context.TODO(), ns)
+		})
+
+		// Create a test Helm release storage mock.
+		rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+			Name:      "reconcile-delete",
+			Namespace: ns.Name,
+			Version:   1,
+			Chart:     testutil.BuildChart(testutil.ChartWithTestHook()),
+			Status:    helmrelease.StatusDeployed,
+		})
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "reconcile-delete",
+				Namespace:         ns.Name,
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+			Spec: v2.HelmReleaseSpec{
+				DeletionPolicy: v2.DeletionPolicyOrphan,
+			},
+			Status: v2.HelmReleaseStatus{
+				StorageNamespace: ns.Name,
+				History: v2.Snapshots{
+					release.ObservedToSnapshot(release.ObserveRelease(rls)),
+				},
+			},
+		}
+
+		recorder := record.NewFakeRecorder(32)
+		r := &HelmReleaseReconciler{
+			Client:           testEnv.Client,
+			GetClusterConfig: GetTestClusterConfig,
+			EventRecorder:    recorder,
+		}
+
+		// Store the Helm release mock in the test namespace.
+		getter, err := r.buildRESTClientGetter(context.TODO(), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		cfg, err := action.NewConfigFactory(getter, action.WithStorage(helmdriver.SecretsDriverName, obj.Status.StorageNamespace))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		store := helmstorage.Init(cfg.Driver)
+		g.Expect(store.Create(rls)).To(Succeed())
+
+		// Reconcile the actual deletion of the Helm release.
+		err = r.reconcileReleaseDeletion(context.TODO(), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		// Verify status of Helm release has been updated.
+		g.Expect(obj.Status.StorageNamespace).To(BeEmpty())
+		g.Expect(obj.Status.History).To(BeNil())
+
+		// Verify Helm release has been removed from storage, without
+		// uninstalling it.
+		_, err = store.History(rls.Name)
+		g.Expect(err).To(MatchError(helmdriver.ErrReleaseNotFound))
+		g.Expect(recorder.Events).To(Receive(ContainSubstring(v2.ReleaseOrphanedReason)))
+	})
+
+	t.Run("skip uninstalling pinned Helm release", func(t *testing.T) {
+		g := NewWithT(t)
+
+		// Create a test namespace for storing the Helm release mock.
+		ns, err := testEnv.CreateNamespace(context.TODO(), "reconcile-release-deletion")
+		g.Expect(err).ToNot(HaveOccurred())
+		t.Cleanup(func() {
+			_ = testEnv.Delete(context.TODO(), ns)
+		})
+
+		// Create a test Helm release storage mock.
+		rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+			Name:      "reconcile-delete",
+			Namespace: ns.Name,
+			Version:   1,
+			Chart:     testutil.BuildChart(testutil.ChartWithTestHook()),
+			Status:    helmrelease.StatusDeployed,
+		})
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "reconcile-delete",
+				Namespace:         ns.Name,
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+			Spec: v2.HelmReleaseSpec{
+				Pin:            true,
+				DeletionPolicy: v2.DeletionPolicyOrphan,
+			},
+			Status: v2.HelmReleaseStatus{
+				StorageNamespace: ns.Name,
+				History: v2.Snapshots{
+					release.ObservedToSnapshot(release.ObserveRelease(rls)),
+				},
+			},
+		}
+
+		r := &HelmReleaseReconciler{
+			Client:           testEnv.Client,
+			GetClusterConfig: GetTestClusterConfig,
+			EventRecorder:    record.NewFakeRecorder(32),
+		}
+
+		// Store the Helm release mock in the test namespace.
+		getter, err := r.buildRESTClientGetter(context.TODO(), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		cfg, err := action.NewConfigFactory(getter, action.WithStorage(helmdriver.SecretsDriverName, obj.Status.StorageNamespace))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		store := helmstorage.Init(cfg.Driver)
+		g.Expect(store.Create(rls)).To(Succeed())
+
+		// Reconcile the deletion of the Helm release.
+		err = r.reconcileReleaseDeletion(context.TODO(), obj)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		// Verify Helm release and its storage records have been left in
+		// place.
+		history, err := store.History(rls.Name)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(history).To(HaveLen(1))
+	})
+
 	t.Run("skip uninstalling Helm release when KubeConfig Secret is missing", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -3135,29 +3765,233 @@ users:
 	}
 }
 
-func TestHelmReleaseReconciler_getHelmChart(t *testing.T) {
-	g := NewWithT(t)
+func TestHelmReleaseReconciler_validateReferences(t *testing.T) {
+	const namespace = "some-namespace"
 
-	chart := &sourcev1.HelmChart{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: "some-namespace",
-			Name:      "some-chart-name",
+	kubeConfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig", Namespace: namespace},
+		Data: map[string][]byte{
+			kube.DefaultKubeConfigSecretKey: []byte(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://1.2.3.4
+  name: development
+contexts:
+- context:
+    cluster: development
+  name: development
+current-context: development
+`),
+		},
+	}
+	valuesConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "values", Namespace: namespace},
+		Data:       map[string]string{"values.yaml": "foo: bar"},
+	}
+	invalidValuesConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid-values", Namespace: namespace},
+		Data:       map[string]string{"values.yaml": "foo: [bar"},
+	}
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: namespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"ghcr.io":{"username":"user","password":"pass"}}}`),
+		},
+	}
+	invalidPullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid-pull-secret", Namespace: namespace},
+		Type:       corev1.SecretTypeOpaque,
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta:       metav1.ObjectMeta{Name: "deployer", Namespace: namespace},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "pull-secret"}},
+	}
+	invalidServiceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid-deployer", Namespace: namespace},
+		ImagePullSecrets: []corev1.LocalObjectReference{
+			{Name: "pull-secret"},
+			{Name: "missing"},
+			{Name: "invalid-pull-secret"},
 		},
 	}
 
 	tests := []struct {
-		name            string
-		rel             *v2.HelmRelease
-		chart           *sourcev1.HelmChart
-		expectChart     bool
-		wantErr         bool
-		disallowCrossNS bool
+		name       string
+		spec       v2.HelmReleaseSpec
+		conditions []metav1.Condition
+		want       []metav1.Condition
 	}{
 		{
-			name: "retrieves HelmChart object from Status",
-			rel: &v2.HelmRelease{
-				Status: v2.HelmReleaseStatus{
-					HelmChart: "some-namespace/some-chart-name",
+			name: "valid references",
+			spec: v2.HelmReleaseSpec{
+				KubeConfig: &meta.KubeConfigReference{
+					SecretRef: meta.SecretKeyReference{Name: "kubeconfig"},
+				},
+				ValuesFrom: []v2.ValuesReference{
+					{Kind: "ConfigMap", Name: "values"},
+					{Kind: "Secret", Name: "missing", Optional: true},
+				},
+				ServiceAccountName: "deployer",
+			},
+			want: []metav1.Condition{
+				*conditions.TrueCondition(v2.ReferencesValidCondition, meta.SucceededReason, "All references are valid"),
+			},
+		},
+		{
+			name: "invalid references",
+			spec: v2.HelmReleaseSpec{
+				KubeConfig: &meta.KubeConfigReference{
+					SecretRef: meta.SecretKeyReference{Name: "kubeconfig", Key: "invalid-key"},
+				},
+				ValuesFrom: []v2.ValuesReference{
+					{Kind: "ConfigMap", Name: "values"},
+					{Kind: "Secret", Name: "missing"},
+					{Kind: "ConfigMap", Name: "invalid-values"},
+				},
+			},
+			want: []metav1.Condition{
+				*conditions.FalseCondition(v2.ReferencesValidCondition, v2.InvalidReferencesReason,
+					"[KubeConfig secret 'some-namespace/kubeconfig' does not contain a 'invalid-key' key with data, "+
+						"could not resolve Secret chart values reference 'some-namespace/missing' with key 'values.yaml': "+
+						"secrets \"missing\" not found, "+
+						"could not resolve ConfigMap chart values reference 'some-namespace/invalid-values' with key 'values.yaml': "+
+						"error converting YAML to JSON: yaml: line 1: did not find expected ',' or ']']"),
+			},
+		},
+		{
+			name: "invalid image pull secrets",
+			spec: v2.HelmReleaseSpec{
+				ServiceAccountName: "invalid-deployer",
+			},
+			want: []metav1.Condition{
+				*conditions.FalseCondition(v2.ReferencesValidCondition, v2.InvalidReferencesReason,
+					"[could not get image pull secret 'some-namespace/missing' of ServiceAccount 'invalid-deployer': "+
+						"secrets \"missing\" not found, "+
+						"image pull secret 'some-namespace/invalid-pull-secret' has unsupported type 'Opaque']"),
+			},
+		},
+		{
+			name: "missing ServiceAccount",
+			spec: v2.HelmReleaseSpec{
+				ServiceAccountName: "missing",
+			},
+			want: []metav1.Condition{
+				*conditions.FalseCondition(v2.ReferencesValidCondition, v2.InvalidReferencesReason,
+					"could not get ServiceAccount 'some-namespace/missing': serviceaccounts \"missing\" not found"),
+			},
+		},
+		{
+			name: "no references",
+			conditions: []metav1.Condition{
+				*conditions.TrueCondition(v2.ReferencesValidCondition, meta.SucceededReason, "All references are valid"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// The ServiceAccounts are only known to the APIReader, as they
+			// are not cached.
+			r := &HelmReleaseReconciler{
+				Client: fake.NewClientBuilder().
+					WithObjects(kubeConfigSecret, valuesConfigMap, invalidValuesConfigMap,
+						pullSecret, invalidPullSecret).
+					Build(),
+				APIReader: fake.NewClientBuilder().
+					WithObjects(serviceAccount, invalidServiceAccount).
+					Build(),
+			}
+
+			obj := &v2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: namespace},
+				Spec:       tt.spec,
+				Status:     v2.HelmReleaseStatus{Conditions: tt.conditions},
+			}
+			r.validateReferences(context.TODO(), obj)
+			g.Expect(obj.Status.Conditions).To(conditions.MatchConditions(tt.want))
+		})
+	}
+}
+
+func TestHelmReleaseReconciler_checkValuesTypes(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(32)
+	r := &HelmReleaseReconciler{EventRecorder: recorder}
+
+	chrt := testutil.BuildChart()
+	chrt.Values = map[string]interface{}{"replicaCount": float64(1)}
+	obj := &v2.HelmRelease{}
+
+	r.checkValuesTypes(obj, chrt, helmchartutil.Values{"replicaCount": "3"})
+	msg := `Values do not match the types expected by the chart: replicaCount: got string, chart expects integer`
+	g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+		*conditions.FalseCondition(v2.ValuesTypesValidCondition, v2.ValuesTypeMismatchReason, msg),
+	}))
+	g.Expect(recorder.Events).To(Receive(Equal(corev1.EventTypeWarning + " " + v2.ValuesTypeMismatchReason + " " + msg)))
+
+	// The event is not repeated for the same mismatches.
+	r.checkValuesTypes(obj, chrt, helmchartutil.Values{"replicaCount": "3"})
+	g.Expect(recorder.Events).ToNot(Receive())
+
+	r.checkValuesTypes(obj, chrt, helmchartutil.Values{"replicaCount": 3})
+	g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+		*conditions.TrueCondition(v2.ValuesTypesValidCondition, meta.SucceededReason, "All values match the types expected by the chart"),
+	}))
+
+	r.checkValuesTypes(obj, chrt, nil)
+	g.Expect(obj.Status.Conditions).To(BeEmpty())
+}
+
+func Test_verifyChartDependencies(t *testing.T) {
+	g := NewWithT(t)
+
+	chrt := testutil.BuildChart()
+	chrt.Lock = &chart.Lock{
+		Digest:       "sha256:invalid",
+		Dependencies: []*chart.Dependency{{Name: "redis", Version: "17.3.7"}},
+	}
+
+	// The verification is disabled by default.
+	g.Expect(verifyChartDependencies(chrt)).To(Succeed())
+
+	g.Expect(features.SetReloadable(map[string]bool{features.VerifyChartDependencies: true})).To(Succeed())
+	t.Cleanup(func() { _ = features.SetReloadable(nil) })
+
+	g.Expect(verifyChartDependencies(chrt)).To(MatchError("bundled chart dependency versions do not match lock file: " +
+		"hello: requirements.lock is out of sync with the dependencies of the chart; " +
+		"hello: dependency 'redis' is locked at version 17.3.7 in requirements.lock, but is not bundled"))
+
+	chrt.Lock = nil
+	g.Expect(verifyChartDependencies(chrt)).To(Succeed())
+}
+
+func TestHelmReleaseReconciler_getHelmChart(t *testing.T) {
+	g := NewWithT(t)
+
+	chart := &sourcev1.HelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "some-namespace",
+			Name:      "some-chart-name",
+		},
+	}
+
+	tests := []struct {
+		name            string
+		rel             *v2.HelmRelease
+		chart           *sourcev1.HelmChart
+		expectChart     bool
+		wantErr         bool
+		disallowCrossNS bool
+	}{
+		{
+			name: "retrieves HelmChart object from Status",
+			rel: &v2.HelmRelease{
+				Status: v2.HelmReleaseStatus{
+					HelmChart: "some-namespace/some-chart-name",
 				},
 			},
 			chart:       chart,
@@ -3693,6 +4527,203 @@ func Test_isOCIRepositoryReady(t *testing.T) {
 	}
 }
 
+func TestHelmReleaseReconciler_requestsForOCIRrepositoryChange(t *testing.T) {
+	g := NewWithT(t)
+
+	repo := &sourcev1beta2.OCIRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "podinfo",
+			Namespace: "default",
+		},
+		Status: sourcev1beta2.OCIRepositoryStatus{
+			Artifact: &sourcev1.Artifact{
+				Revision: "6.6.1@sha256:9933f58f8bf459eb199d59ebc8a05683f3944e1242d9f5467d99aa2cf08a5370",
+			},
+		},
+	}
+
+	newRelease := func(name, kind, digest string) *v2.HelmRelease {
+		return &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v2.HelmReleaseSpec{
+				ChartRef: &v2.CrossNamespaceSourceReference{
+					Kind: kind,
+					Name: "podinfo",
+				},
+			},
+			Status: v2.HelmReleaseStatus{
+				LastAttemptedRevisionDigest: digest,
+			},
+		}
+	}
+
+	r := &HelmReleaseReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithIndex(&v2.HelmRelease{}, v2.SourceIndexKey, indexBySource).
+			WithObjects(
+				newRelease("changed", sourcev1beta2.OCIRepositoryKind, "sha256:0cc9a8446c95009ef382f5eade883a67c257f77d50f84e78ecef2aac9428d1e5"),
+				newRelease("unchanged", sourcev1beta2.OCIRepositoryKind, "sha256:9933f58f8bf459eb199d59ebc8a05683f3944e1242d9f5467d99aa2cf08a5370"),
+				newRelease("helmchart", sourcev1.HelmChartKind, ""),
+			).
+			Build(),
+	}
+
+	g.Expect(r.requestsForOCIRrepositoryChange(context.TODO(), repo)).To(Equal([]reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "default", Name: "changed"}},
+	}))
+}
+
+func TestHelmReleaseReconciler_requestsForPullSecretChange(t *testing.T) {
+	g := NewWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry",
+			Namespace: "default",
+		},
+	}
+	ociRepo := &sourcev1beta2.OCIRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "podinfo",
+			Namespace: "default",
+		},
+		Spec: sourcev1beta2.OCIRepositorySpec{
+			SecretRef: &meta.LocalObjectReference{Name: "registry"},
+		},
+	}
+	helmRepo := &sourcev1.HelmRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "charts",
+			Namespace: "default",
+		},
+		Spec: sourcev1.HelmRepositorySpec{
+			Type:      sourcev1.HelmRepositoryTypeOCI,
+			SecretRef: &meta.LocalObjectReference{Name: "registry"},
+		},
+	}
+	otherRepo := &sourcev1beta2.OCIRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other",
+			Namespace: "default",
+		},
+		Spec: sourcev1beta2.OCIRepositorySpec{
+			SecretRef: &meta.LocalObjectReference{Name: "other"},
+		},
+	}
+	helmChart := &sourcev1.HelmChart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "default-chart",
+			Namespace: "default",
+		},
+		Spec: sourcev1.HelmChartSpec{
+			SourceRef: sourcev1.LocalHelmChartSourceReference{
+				Kind: sourcev1.HelmRepositoryKind,
+				Name: "charts",
+			},
+		},
+	}
+
+	newRelease := func(name, kind, source, reason string) *v2.HelmRelease {
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v2.HelmReleaseSpec{
+				ChartRef: &v2.CrossNamespaceSourceReference{
+					Kind: kind,
+					Name: source,
+				},
+			},
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, "not ready")
+		return obj
+	}
+
+	r := &HelmReleaseReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithIndex(&v2.HelmRelease{}, v2.SourceIndexKey, indexBySource).
+			WithIndex(&sourcev1beta2.OCIRepository{}, secretRefIndexKey, indexOCIRepositoryBySecretRef).
+			WithIndex(&sourcev1.HelmRepository{}, secretRefIndexKey, indexHelmRepositoryBySecretRef).
+			WithObjects(
+				ociRepo, helmRepo, helmChart, otherRepo,
+				newRelease("oci", sourcev1beta2.OCIRepositoryKind, "podinfo", v2.AuthenticationFailedReason),
+				newRelease("chart", sourcev1.HelmChartKind, "default-chart", v2.AuthenticationFailedReason),
+				newRelease("other", sourcev1beta2.OCIRepositoryKind, "podinfo", "SourceNotReady"),
+				newRelease("unrelated", sourcev1beta2.OCIRepositoryKind, "other", v2.AuthenticationFailedReason),
+			).
+			Build(),
+	}
+
+	g.Expect(r.requestsForPullSecretChange(context.TODO(), secret)).To(ConsistOf(
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "oci"}},
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "chart"}},
+	))
+}
+
+func TestHelmReleaseReconciler_requestsForNamespaceChange(t *testing.T) {
+	g := NewWithT(t)
+
+	newRelease := func(name, namespace string, spec v2.HelmReleaseSpec) *v2.HelmRelease {
+		return &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       spec,
+		}
+	}
+
+	r := &HelmReleaseReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithObjects(
+				newRelease("target", "tenant", v2.HelmReleaseSpec{TargetNamespace: "other"}),
+				newRelease("storage", "tenant", v2.HelmReleaseSpec{StorageNamespace: "other"}),
+				newRelease("own", "tenant", v2.HelmReleaseSpec{TargetNamespace: "tenant"}),
+				newRelease("other-namespace", "other", v2.HelmReleaseSpec{TargetNamespace: "tenant"}),
+			).
+			Build(),
+	}
+
+	ns := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}
+	g.Expect(r.requestsForNamespaceChange(context.TODO(), ns)).To(ConsistOf(
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "tenant", Name: "target"}},
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "tenant", Name: "storage"}},
+	))
+}
+
+func Test_privilegedNamespacePredicate(t *testing.T) {
+	g := NewWithT(t)
+
+	newNamespace := func(labels map[string]string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "tenant", Labels: labels}}
+	}
+	unlabeled := newNamespace(nil)
+	privileged := newNamespace(map[string]string{intacl.PrivilegedLabel: "true"})
+	other := newNamespace(map[string]string{"team": "a"})
+
+	g.Expect(privilegedNamespacePredicate.Update(event.UpdateEvent{ObjectOld: unlabeled, ObjectNew: privileged})).To(BeTrue())
+	g.Expect(privilegedNamespacePredicate.Update(event.UpdateEvent{ObjectOld: privileged, ObjectNew: unlabeled})).To(BeTrue())
+	g.Expect(privilegedNamespacePredicate.Update(event.UpdateEvent{ObjectOld: unlabeled, ObjectNew: other})).To(BeFalse())
+	g.Expect(privilegedNamespacePredicate.Create(event.CreateEvent{Object: privileged})).To(BeFalse())
+}
+
+func Test_pullSecretPredicate(t *testing.T) {
+	g := NewWithT(t)
+
+	secret := &metav1.PartialObjectMetadata{}
+	g.Expect(pullSecretPredicate.Generic(event.GenericEvent{Object: secret})).To(BeFalse())
+
+	secret.SetLabels(map[string]string{v2.PullSecretWatchLabel: "false"})
+	g.Expect(pullSecretPredicate.Generic(event.GenericEvent{Object: secret})).To(BeFalse())
+
+	secret.SetLabels(map[string]string{v2.PullSecretWatchLabel: "true"})
+	g.Expect(pullSecretPredicate.Generic(event.GenericEvent{Object: secret})).To(BeTrue())
+}
+
 func Test_TryMutateChartWithSourceRevision(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -3760,3 +4791,411 @@ func Test_TryMutateChartWithSourceRevision(t *testing.T) {
 	}
 
 }
+
+func TestHelmReleaseReconciler_storageDriver(t *testing.T) {
+	tests := []struct {
+		name              string
+		controllerDriver  string
+		spec              string
+		status            string
+		wantDriver        string
+		wantReleaseDriver string
+	}{
+		{
+			name:              "defaults",
+			wantDriver:        helmdriver.SecretsDriverName,
+			wantReleaseDriver: helmdriver.SecretsDriverName,
+		},
+		{
+			name:              "controller driver",
+			controllerDriver:  helmdriver.SQLDriverName,
+			wantDriver:        helmdriver.SQLDriverName,
+			wantReleaseDriver: helmdriver.SQLDriverName,
+		},
+		{
+			name:              "spec driver",
+			controllerDriver:  helmdriver.SQLDriverName,
+			spec:              "configmap",
+			wantDriver:        helmdriver.ConfigMapsDriverName,
+			wantReleaseDriver: helmdriver.SQLDriverName,
+		},
+		{
+			name:              "recorded driver",
+			spec:              "configmap",
+			status:            helmdriver.SecretsDriverName,
+			wantDriver:        helmdriver.ConfigMapsDriverName,
+			wantReleaseDriver: helmdriver.SecretsDriverName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &HelmReleaseReconciler{StorageDriver: tt.controllerDriver}
+			obj := &v2.HelmRelease{
+				Spec:   v2.HelmReleaseSpec{StorageDriver: tt.spec},
+				Status: v2.HelmReleaseStatus{StorageDriver: tt.status},
+			}
+			g.Expect(r.storageDriver(obj)).To(Equal(tt.wantDriver))
+			g.Expect(r.releaseStorageDriver(obj)).To(Equal(tt.wantReleaseDriver))
+		})
+	}
+}
+
+func TestHelmReleaseReconciler_storageMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	r := &HelmReleaseReconciler{
+		StorageLabels:      map[string]string{"backup": "true", "tier": "default"},
+		StorageAnnotations: map[string]string{"team": "platform"},
+	}
+	obj := &v2.HelmRelease{
+		Spec: v2.HelmReleaseSpec{
+			StorageLabels: map[string]string{"tier": "critical"},
+		},
+	}
+
+	labels, annotations := r.storageMetadata(obj)
+	g.Expect(labels).To(Equal(map[string]string{"backup": "true", "tier": "critical"}))
+	g.Expect(annotations).To(Equal(map[string]string{"team": "platform"}))
+	// The maps of the controller are not modified.
+	g.Expect(r.StorageLabels).To(HaveKeyWithValue("tier", "default"))
+
+	labels, annotations = (&HelmReleaseReconciler{}).storageMetadata(&v2.HelmRelease{})
+	g.Expect(labels).To(BeNil())
+	g.Expect(annotations).To(BeNil())
+}
+
+func Test_sourceProvenance(t *testing.T) {
+	tests := []struct {
+		name   string
+		source sourcev1.Source
+		want   map[string]string
+	}{
+		{
+			name: "HelmChart from GitRepository",
+			source: &sourcev1.HelmChart{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "flux-system", Name: "apps-podinfo"},
+				Spec: sourcev1.HelmChartSpec{
+					SourceRef: sourcev1.LocalHelmChartSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "apps"},
+				},
+				Status: sourcev1.HelmChartStatus{
+					ObservedSourceArtifactRevision: "main@sha1:b3d45a7b48e1cd0d2cd9d4b4ad6bd26a14bb8d4e",
+					Artifact:                       &sourcev1.Artifact{Revision: "6.5.3"},
+				},
+			},
+			want: map[string]string{
+				"source":          "GitRepository/flux-system/apps",
+				"source-revision": "main@sha1:b3d45a7b48e1cd0d2cd9d4b4ad6bd26a14bb8d4e",
+			},
+		},
+		{
+			name: "HelmChart from HelmRepository",
+			source: &sourcev1.HelmChart{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "flux-system", Name: "apps-podinfo"},
+				Spec: sourcev1.HelmChartSpec{
+					SourceRef: sourcev1.LocalHelmChartSourceReference{Kind: sourcev1.HelmRepositoryKind, Name: "podinfo"},
+				},
+				Status: sourcev1.HelmChartStatus{
+					ObservedSourceArtifactRevision: "sha256:9933f58f8bf459eb199d59ebc8a05683f3944e1242d9f5467d99aa2cf08a5370",
+				},
+			},
+			want: map[string]string{
+				"source": "HelmRepository/flux-system/podinfo",
+			},
+		},
+		{
+			name: "OCIRepository with annotations",
+			source: &sourcev1beta2.OCIRepository{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "podinfo"},
+				Status: sourcev1beta2.OCIRepositoryStatus{
+					Artifact: &sourcev1.Artifact{
+						Revision: "6.5.3@sha256:9933f58f8bf459eb199d59ebc8a05683f3944e1242d9f5467d99aa2cf08a5370",
+						Metadata: map[string]string{
+							"org.opencontainers.image.revision": "6.5.3@sha1:b3d45a7b48e1cd0d2cd9d4b4ad6bd26a14bb8d4e",
+							"org.opencontainers.image.authors":  "stefanprodan",
+							"other":                             "ignored",
+						},
+					},
+				},
+			},
+			want: map[string]string{
+				"source":                            "OCIRepository/apps/podinfo",
+				"source-revision":                   "6.5.3@sha256:9933f58f8bf459eb199d59ebc8a05683f3944e1242d9f5467d99aa2cf08a5370",
+				"org.opencontainers.image.revision": "6.5.3@sha1:b3d45a7b48e1cd0d2cd9d4b4ad6bd26a14bb8d4e",
+				"org.opencontainers.image.authors":  "stefanprodan",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(sourceProvenance(tt.source)).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_markSuspended(t *testing.T) {
+	t.Run("without reason", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{Spec: v2.HelmReleaseSpec{Suspend: true}}
+		markSuspended(obj)
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(v2.SuspendedCondition, v2.ReconciliationSuspendedReason, "Reconciliation is suspended"),
+		}))
+	})
+
+	t.Run("with reason", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{Spec: v2.HelmReleaseSpec{Suspend: true, SuspendReason: "database maintenance (100%)"}}
+		markSuspended(obj)
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(v2.SuspendedCondition, v2.ReconciliationSuspendedReason,
+				"%s", "Reconciliation is suspended: database maintenance (100%)"),
+		}))
+	})
+}
+
+func Test_observeUpgradeAvailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  v2.Snapshots
+		source   sourcev1.Source
+		wantTrue bool
+		wantMsg  string
+	}{
+		{
+			name: "newer HelmChart version",
+			history: v2.Snapshots{
+				{Name: "release", Namespace: "default", Version: 1, ChartName: "podinfo", ChartVersion: "6.0.0"},
+			},
+			source: &sourcev1.HelmChart{
+				Status: sourcev1.HelmChartStatus{
+					Artifact: &sourcev1.Artifact{Revision: "6.1.0"},
+				},
+			},
+			wantTrue: true,
+			wantMsg:  "Chart version 6.1.0 is available for release default/release.v1 with chart podinfo@6.0.0",
+		},
+		{
+			name: "same HelmChart version with build metadata",
+			history: v2.Snapshots{
+				{Name: "release", Namespace: "default", Version: 1, ChartName: "podinfo", ChartVersion: "6.0.0+abcdef"},
+			},
+			source: &sourcev1.HelmChart{
+				Status: sourcev1.HelmChartStatus{
+					Artifact: &sourcev1.Artifact{Revision: "6.0.0+123456"},
+				},
+			},
+		},
+		{
+			name: "older HelmChart version",
+			history: v2.Snapshots{
+				{Name: "release", Namespace: "default", Version: 1, ChartName: "podinfo", ChartVersion: "6.1.0"},
+			},
+			source: &sourcev1.HelmChart{
+				Status: sourcev1.HelmChartStatus{
+					Artifact: &sourcev1.Artifact{Revision: "6.0.0"},
+				},
+			},
+		},
+		{
+			name: "newer OCIRepository tag",
+			history: v2.Snapshots{
+				{Name: "release", Namespace: "default", Version: 1, ChartName: "podinfo", ChartVersion: "6.0.0+9933f58f8bf4"},
+			},
+			source: &sourcev1beta2.OCIRepository{
+				Status: sourcev1beta2.OCIRepositoryStatus{
+					Artifact: &sourcev1.Artifact{
+						Revision: "6.1.0@sha256:9933f58f8bf459eb199d59ebc8a05683f3944e1242d9f5467d99aa2cf08a5370",
+					},
+				},
+			},
+			wantTrue: true,
+			wantMsg:  "Chart version 6.1.0 is available for release default/release.v1 with chart podinfo@6.0.0+9933f58f8bf4",
+		},
+		{
+			name: "OCIRepository digest without tag",
+			history: v2.Snapshots{
+				{Name: "release", Namespace: "default", Version: 1, ChartName: "podinfo", ChartVersion: "6.0.0"},
+			},
+			source: &sourcev1beta2.OCIRepository{
+				Status: sourcev1beta2.OCIRepositoryStatus{
+					Artifact: &sourcev1.Artifact{
+						Revision: "sha256:9933f58f8bf459eb199d59ebc8a05683f3944e1242d9f5467d99aa2cf08a5370",
+					},
+				},
+			},
+		},
+		{
+			name: "without release",
+			source: &sourcev1.HelmChart{
+				Status: sourcev1.HelmChartStatus{
+					Artifact: &sourcev1.Artifact{Revision: "6.1.0"},
+				},
+			},
+		},
+		{
+			name: "without artifact",
+			history: v2.Snapshots{
+				{Name: "release", Namespace: "default", Version: 1, ChartName: "podinfo", ChartVersion: "6.0.0"},
+			},
+			source: &sourcev1.HelmChart{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			obj := &v2.HelmRelease{
+				Status: v2.HelmReleaseStatus{
+					History: tt.history,
+					Conditions: []metav1.Condition{
+						*conditions.TrueCondition(v2.UpgradeAvailableCondition, v2.NewerVersionAvailableReason, "stale"),
+					},
+				},
+			}
+
+			observeUpgradeAvailable(obj, tt.source)
+
+			if !tt.wantTrue {
+				g.Expect(conditions.Has(obj, v2.UpgradeAvailableCondition)).To(BeFalse())
+				return
+			}
+			g.Expect(conditions.IsTrue(obj, v2.UpgradeAvailableCondition)).To(BeTrue())
+			g.Expect(conditions.GetReason(obj, v2.UpgradeAvailableCondition)).To(Equal(v2.NewerVersionAvailableReason))
+			g.Expect(conditions.GetMessage(obj, v2.UpgradeAvailableCondition)).To(Equal(tt.wantMsg))
+		})
+	}
+}
+
+func TestHelmReleaseReconciler_nextReconcileAt(t *testing.T) {
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "release"}}
+
+	newReconciler := func(failures int) *HelmReleaseReconciler {
+		rl := workqueue.NewItemExponentialFailureRateLimiter(5*time.Second, time.Minute)
+		for i := 0; i < failures; i++ {
+			rl.When(req)
+		}
+		return &HelmReleaseReconciler{
+			rateLimiter: rl,
+			retryDelay: helper.RateLimiterOptions{
+				MinRetryDelay: 5 * time.Second,
+				MaxRetryDelay: time.Minute,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		failures int
+		result   reconcile.Result
+		err      error
+		want     time.Duration
+		wantNil  bool
+	}{
+		{
+			name:   "requeue after interval",
+			result: reconcile.Result{RequeueAfter: 10 * time.Minute},
+			want:   10 * time.Minute,
+		},
+		{
+			name: "first retry after error",
+			err:  errors.New("failed"),
+			want: 5 * time.Second,
+		},
+		{
+			name:     "backoff after repeated errors",
+			failures: 2,
+			err:      errors.New("failed"),
+			want:     20 * time.Second,
+		},
+		{
+			name:     "backoff is capped",
+			failures: 10,
+			result:   reconcile.Result{Requeue: true},
+			want:     time.Minute,
+		},
+		{
+			name:    "terminal error",
+			err:     reconcile.TerminalError(errors.New("failed")),
+			wantNil: true,
+		},
+		{
+			name:    "not requeued",
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			before := time.Now().Truncate(time.Second)
+			got := newReconciler(tt.failures).nextReconcileAt(req, tt.result, tt.err)
+			if tt.wantNil {
+				g.Expect(got).To(BeNil())
+				return
+			}
+			g.Expect(got).ToNot(BeNil())
+			g.Expect(got.Time).To(BeTemporally("~", before.Add(tt.want), time.Second))
+		})
+	}
+}
+
+func Test_requeueForSchedule(t *testing.T) {
+	now := time.Date(2024, 5, 7, 1, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule *v2.ReconcileSchedule
+		result   reconcile.Result
+		want     reconcile.Result
+	}{
+		{
+			name:   "no schedule",
+			result: reconcile.Result{RequeueAfter: time.Hour},
+			want:   reconcile.Result{RequeueAfter: time.Hour},
+		},
+		{
+			name:     "schedule before interval",
+			schedule: &v2.ReconcileSchedule{Cron: "0 2 * * *"},
+			result:   reconcile.Result{RequeueAfter: time.Hour},
+			want:     reconcile.Result{RequeueAfter: 30 * time.Minute},
+		},
+		{
+			name:     "schedule after interval",
+			schedule: &v2.ReconcileSchedule{Cron: "0 2 * * *"},
+			result:   reconcile.Result{RequeueAfter: 10 * time.Minute},
+			want:     reconcile.Result{RequeueAfter: 10 * time.Minute},
+		},
+		{
+			name:     "schedule in time zone",
+			schedule: &v2.ReconcileSchedule{Cron: "0 4 * * *", TimeZone: "Europe/Amsterdam"},
+			result:   reconcile.Result{RequeueAfter: time.Hour},
+			want:     reconcile.Result{RequeueAfter: 30 * time.Minute},
+		},
+		{
+			name:     "not requeued",
+			schedule: &v2.ReconcileSchedule{Cron: "0 2 * * *"},
+			result:   reconcile.Result{},
+			want:     reconcile.Result{},
+		},
+		{
+			name:     "invalid schedule",
+			schedule: &v2.ReconcileSchedule{Cron: "invalid"},
+			result:   reconcile.Result{RequeueAfter: time.Hour},
+			want:     reconcile.Result{RequeueAfter: time.Hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			obj := &v2.HelmRelease{Spec: v2.HelmReleaseSpec{Schedule: tt.schedule}}
+			g.Expect(requeueForSchedule(obj, tt.result, now)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crdschema validates rendered custom resources against the OpenAPI
+// schemas of their CustomResourceDefinitions, to report the precise location
+// of invalid fields before any of the objects is applied.
+//
+// The validation is best-effort: it does not take defaulting, pruning of
+// unknown fields or CEL validation rules into account, which are left to the
+// API server.
+package crdschema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+// InvalidError is returned by Check when rendered custom resources do not
+// match the schemas of their CustomResourceDefinitions.
+type InvalidError struct {
+	// Invalid are the descriptions of the invalid fields, prefixed with the
+	// object they belong to.
+	Invalid []string
+}
+
+// Error returns an error string containing the invalid fields.
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("rendered custom resources do not match the schemas of their CustomResourceDefinitions: %s",
+		strings.Join(e.Invalid, "; "))
+}
+
+// GetFunc returns the CustomResourceDefinition of the given group kind from
+// the cluster, or nil if the cluster does not have one.
+type GetFunc func(gk schema.GroupKind) (*apiextensionsv1.CustomResourceDefinition, error)
+
+// Check returns an InvalidError if any of the given objects does not match
+// the schema of the version of its CustomResourceDefinition. The
+// CustomResourceDefinitions in the objects take precedence over the ones
+// returned by the given GetFunc, as they are applied before the objects.
+// Objects of kinds without a CustomResourceDefinition, or of versions
+// without a schema, are not validated.
+func Check(get GetFunc, objects []*unstructured.Unstructured) error {
+	crds, err := definedCRDs(objects)
+	if err != nil {
+		return err
+	}
+	schemas := make(map[schema.GroupVersionKind]*versionSchema)
+
+	var invalid []string
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		// The group of a CustomResourceDefinition must contain a dot.
+		if !strings.Contains(gvk.Group, ".") {
+			continue
+		}
+
+		crd, ok := crds[gvk.GroupKind()]
+		if !ok {
+			if crd, err = get(gvk.GroupKind()); err != nil {
+				return fmt.Errorf("failed to get CustomResourceDefinition of %s: %w", gvk.GroupKind(), err)
+			}
+			crds[gvk.GroupKind()] = crd
+		}
+		if crd == nil {
+			continue
+		}
+
+		s, ok := schemas[gvk]
+		if !ok {
+			if s, err = schemaFor(crd, gvk.Version); err != nil {
+				return err
+			}
+			schemas[gvk] = s
+		}
+		if s == nil {
+			continue
+		}
+
+		for _, msg := range s.validate(obj) {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", ssautil.FmtUnstructured(obj), msg))
+		}
+	}
+
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return &InvalidError{Invalid: invalid}
+	}
+	return nil
+}
+
+// versionSchema is the schema of a version of a CustomResourceDefinition.
+type versionSchema struct {
+	validator validation.SchemaValidator
+	// status is true if the status of the version is a subresource, which
+	// the API server ignores on create and update.
+	status bool
+}
+
+// validate returns the descriptions of the fields of the object which do not
+// match the schema. The metadata of the object is validated by the API
+// server itself, and is not taken into account. The values of the fields
+// are not included in the descriptions, as they may be sensitive.
+func (s *versionSchema) validate(obj *unstructured.Unstructured) []string {
+	content := make(map[string]interface{}, len(obj.Object))
+	for k, v := range obj.Object {
+		if k == "metadata" || (k == "status" && s.status) {
+			continue
+		}
+		content[k] = v
+	}
+
+	var msgs []string
+	for _, e := range validation.ValidateCustomResource(nil, content, s.validator) {
+		msg := fmt.Sprintf("%s: %s", e.Field, e.Type)
+		if e.Detail != "" {
+			msg += ": " + e.Detail
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// schemaFor returns the schema of the given version of the
+// CustomResourceDefinition, or nil if the version does not exist or does not
+// have a schema.
+func schemaFor(crd *apiextensionsv1.CustomResourceDefinition, version string) (*versionSchema, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Name != version || v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+		props := &apiextensions.JSONSchemaProps{}
+		if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(v.Schema.OpenAPIV3Schema, props, nil); err != nil {
+			return nil, fmt.Errorf("failed to convert schema of CustomResourceDefinition '%s': %w", crd.Name, err)
+		}
+		validator, _, err := validation.NewSchemaValidator(props)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build validator for schema of CustomResourceDefinition '%s': %w", crd.Name, err)
+		}
+		return &versionSchema{
+			validator: validator,
+			status:    v.Subresources != nil && v.Subresources.Status != nil,
+		}, nil
+	}
+	return nil, nil
+}
+
+// definedCRDs returns the CustomResourceDefinitions in the given objects, by
+// the group kind they define.
+func definedCRDs(objects []*unstructured.Unstructured) (map[schema.GroupKind]*apiextensionsv1.CustomResourceDefinition, error) {
+	crds := make(map[schema.GroupKind]*apiextensionsv1.CustomResourceDefinition)
+	for _, obj := range objects {
+		if gvk := obj.GroupVersionKind(); gvk.Group != apiextensionsv1.GroupName || gvk.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+		crds[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = crd
+	}
+	return crds, nil
+}
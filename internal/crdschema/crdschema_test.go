@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+func TestCheck(t *testing.T) {
+	clusterCRD := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Widget"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name: "v1",
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"spec": {
+									Type:     "object",
+									Required: []string{"size"},
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"size": {Type: "integer"},
+										"port": {XIntOrString: true},
+										"parts": {
+											Type: "array",
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+												Schema: &apiextensionsv1.JSONSchemaProps{
+													Type: "object",
+													Properties: map[string]apiextensionsv1.JSONSchemaProps{
+														"mode": {Type: "string", Enum: []apiextensionsv1.JSON{{Raw: []byte(`"fast"`)}}},
+													},
+												},
+											},
+										},
+									},
+								},
+								"status": {Type: "object"},
+							},
+						},
+					},
+					Subresources: &apiextensionsv1.CustomResourceSubresources{
+						Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					},
+				},
+				{Name: "v1alpha1"},
+			},
+		},
+	}
+	get := func(gk schema.GroupKind) (*apiextensionsv1.CustomResourceDefinition, error) {
+		if gk == (schema.GroupKind{Group: "example.com", Kind: "Widget"}) {
+			return clusterCRD, nil
+		}
+		return nil, nil
+	}
+
+	tests := []struct {
+		name     string
+		manifest string
+		invalid  []string
+	}{
+		{
+			name: "valid custom resources",
+			manifest: `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: valid
+  namespace: apps
+spec:
+  size: 3
+  port: http
+  parts:
+  - mode: fast
+status: invalid
+---
+apiVersion: example.com/v1alpha1
+kind: Widget
+metadata:
+  name: unvalidated
+spec:
+  size: three
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+spec:
+  replicas: three`,
+		},
+		{
+			name: "invalid custom resources",
+			manifest: `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: invalid
+  namespace: apps
+spec:
+  size: "3"
+  parts:
+  - mode: slow
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: missing
+  namespace: apps
+spec: {}`,
+			invalid: []string{
+				`Widget/apps/invalid: spec.parts[0].mode: Unsupported value: supported values: "fast"`,
+				`Widget/apps/invalid: spec.size: Invalid value: spec.size in body must be of type integer: "string"`,
+				`Widget/apps/missing: spec.size: Required value`,
+			},
+		},
+		{
+			name: "CustomResourceDefinition in objects",
+			manifest: `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gadget
+    plural: gadgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              enabled:
+                type: boolean
+---
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: gadget
+  namespace: apps
+spec:
+  enabled: "yes"`,
+			invalid: []string{
+				`Gadget/apps/gadget: spec.enabled: Invalid value: spec.enabled in body must be of type boolean: "string"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objects, err := ssautil.ReadObjects(strings.NewReader(tt.manifest))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = Check(get, objects)
+			if len(tt.invalid) == 0 {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			var invalidErr *InvalidError
+			g.Expect(errors.As(err, &invalidErr)).To(BeTrue())
+			g.Expect(invalidErr.Invalid).To(Equal(tt.invalid))
+		})
+	}
+
+	t.Run("get error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		objects, err := ssautil.ReadObjects(strings.NewReader(`apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget`))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		err = Check(func(schema.GroupKind) (*apiextensionsv1.CustomResourceDefinition, error) {
+			return nil, errors.New("forbidden")
+		}, objects)
+		g.Expect(err).To(MatchError("failed to get CustomResourceDefinition of Widget.example.com: forbidden"))
+	})
+}
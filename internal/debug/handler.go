@@ -0,0 +1,305 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debug provides an HTTP endpoint which dumps the view of the
+// controller on a HelmRelease, for troubleshooting releases of which the
+// status does not explain the behavior.
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+	"github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/httpauth"
+	"github.com/fluxcd/helm-controller/internal/postrender"
+)
+
+const (
+	// Path is the path the Handler is served at, followed by
+	// "<namespace>/<name>" of the HelmRelease.
+	Path = "/debug/helmreleases/"
+
+	// Subresource is the subresource of HelmReleases a user must be allowed
+	// to get to retrieve the view of the controller on a HelmRelease.
+	Subresource = "debug"
+)
+
+// redactedValue is the value redacted chart values are replaced with.
+const redactedValue = "***"
+
+// sensitiveKeyRegex matches the keys of chart values which are redacted
+// regardless of their origin.
+var sensitiveKeyRegex = regexp.MustCompile(`(?i)password|passwd|secret|token|api[_-]?key|credentials?|private[_-]?key`)
+
+// View is the view of the controller on a HelmRelease.
+type View struct {
+	// Object is the namespace and name of the HelmRelease.
+	Object string `json:"object"`
+	// Generation is the generation of the HelmRelease.
+	Generation int64 `json:"generation"`
+	// ObservedGeneration is the last generation of the HelmRelease which was
+	// reconciled.
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// Chart is the resolved reference of the chart of the HelmRelease.
+	Chart Chart `json:"chart"`
+	// Values are the composed chart values of the HelmRelease, with the
+	// values originating from Secrets and sensitive values redacted.
+	Values map[string]interface{} `json:"values,omitempty"`
+	// ValuesError is the error returned while composing the chart values.
+	ValuesError string `json:"valuesError,omitempty"`
+	// Digests are the digests computed from the current spec, next to the
+	// digests observed by the last reconciliation.
+	Digests Digests `json:"digests"`
+	// History are the snapshots of the releases of the HelmRelease.
+	History v2.Snapshots `json:"history,omitempty"`
+	// LastReleaseAttempt holds the details, including the tail of the Helm
+	// logs, of the last failed Helm action.
+	LastReleaseAttempt *v2.ReleaseAttempt `json:"lastReleaseAttempt,omitempty"`
+	// PendingActions are the reasons for which the next reconciliation is
+	// expected to act on the release. Empty when the release is expected to
+	// be in-sync.
+	PendingActions []string `json:"pendingActions,omitempty"`
+}
+
+// Chart is the resolved reference of the chart of a HelmRelease.
+type Chart struct {
+	// Ref is the kind, namespace and name of the source object providing the
+	// chart artifact.
+	Ref string `json:"ref,omitempty"`
+	// LastAttemptedRevision is the revision of the chart artifact of the
+	// last release attempt.
+	LastAttemptedRevision string `json:"lastAttemptedRevision,omitempty"`
+	// LastAttemptedRevisionDigest is the digest of the chart artifact of the
+	// last release attempt.
+	LastAttemptedRevisionDigest string `json:"lastAttemptedRevisionDigest,omitempty"`
+}
+
+// Digests are the digests of a HelmRelease.
+type Digests struct {
+	// Values is the digest of the composed chart values.
+	Values string `json:"values,omitempty"`
+	// LastAttemptedValues is the digest of the chart values of the last
+	// release attempt.
+	LastAttemptedValues string `json:"lastAttemptedValues,omitempty"`
+	// PostRenderers is the digest of the post renderers.
+	PostRenderers string `json:"postRenderers,omitempty"`
+	// ObservedPostRenderers is the digest of the post renderers of the last
+	// reconciliation.
+	ObservedPostRenderers string `json:"observedPostRenderers,omitempty"`
+}
+
+// Handler serves the View of a HelmRelease as JSON at
+// Path<namespace>/<name>, to users which are allowed to get the Subresource
+// of the HelmRelease.
+type Handler struct {
+	client client.Client
+}
+
+// NewHandler returns a new Handler which reads the HelmRelease and its values
+// references, and reviews the tokens and access of users, with the given
+// client.
+func NewHandler(c client.Client) *Handler {
+	return &Handler{client: c}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, Path), "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		http.Error(w, fmt.Sprintf("expected path %s<namespace>/<name>", Path), http.StatusBadRequest)
+		return
+	}
+
+	user, err := httpauth.Authenticate(h.client, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err = httpauth.Authorize(r.Context(), h.client, user, namespace, name, Subresource); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	obj := &v2.HelmRelease{}
+	if err := h.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		code := http.StatusInternalServerError
+		if apierrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	view := View{
+		Object:             client.ObjectKeyFromObject(obj).String(),
+		Generation:         obj.Generation,
+		ObservedGeneration: obj.Status.ObservedGeneration,
+		Chart: Chart{
+			Ref:                         chartRef(obj),
+			LastAttemptedRevision:       obj.Status.LastAttemptedRevision,
+			LastAttemptedRevisionDigest: obj.Status.LastAttemptedRevisionDigest,
+		},
+		Digests: Digests{
+			LastAttemptedValues:   obj.Status.LastAttemptedConfigDigest,
+			PostRenderers:         postrender.DigestFor(digest.AlgorithmOf(obj.Status.ObservedPostRenderersDigest), obj).String(),
+			ObservedPostRenderers: obj.Status.ObservedPostRenderersDigest,
+		},
+		History:            obj.Status.History,
+		LastReleaseAttempt: obj.Status.LastReleaseAttempt,
+	}
+
+	values, err := chartutil.ChartValuesFromReferences(r.Context(), h.client, obj.Namespace, obj.GetValues(), obj.Spec.ValuesFrom...)
+	if err == nil {
+		// The values files bundled in the chart are not known without
+		// loading the chart, which makes the digest incomparable.
+		// The digest is calculated with the algorithm of the last attempted
+		// values, which may have been configured previously.
+		if len(obj.Spec.ValuesFiles) == 0 {
+			view.Digests.Values = chartutil.DigestValues(digest.AlgorithmOf(obj.Status.LastAttemptedConfigDigest), values).String()
+		}
+		var secretValues helmchartutil.Values
+		if secretValues, err = chartutil.ChartValuesFromReferences(r.Context(), h.client, obj.Namespace, nil, secretRefs(obj.Spec.ValuesFrom)...); err == nil {
+			view.Values = redactValues(values, secretValues)
+		}
+	}
+	if err != nil {
+		view.ValuesError = err.Error()
+	}
+	view.PendingActions = pendingActions(obj, view.Digests)
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(view)
+}
+
+// chartRef returns the reference of the source object providing the chart
+// artifact of the given HelmRelease.
+func chartRef(obj *v2.HelmRelease) string {
+	if ref := obj.Spec.ChartRef; ref != nil {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = obj.Namespace
+		}
+		return fmt.Sprintf("%s/%s/%s", ref.Kind, namespace, ref.Name)
+	}
+	if obj.Status.HelmChart != "" {
+		return "HelmChart/" + obj.Status.HelmChart
+	}
+	return ""
+}
+
+// secretRefs returns the references to Secrets from the given values
+// references.
+func secretRefs(refs []v2.ValuesReference) []v2.ValuesReference {
+	var secrets []v2.ValuesReference
+	for _, ref := range refs {
+		if ref.Kind == "Secret" {
+			secrets = append(secrets, ref)
+		}
+	}
+	return secrets
+}
+
+// redactValues returns a copy of the given values, with the values which are
+// set in secretValues, or of which the key matches sensitiveKeyRegex,
+// replaced with redactedValue. The value of a name/value pair, such as an
+// environment variable, is redacted if the name matches sensitiveKeyRegex.
+func redactValues(values, secretValues map[string]interface{}) map[string]interface{} {
+	name, _ := values["name"].(string)
+	sensitiveName := sensitiveKeyRegex.MatchString(name)
+
+	redacted := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		secretValue, fromSecret := secretValues[k]
+		redact := fromSecret || sensitiveKeyRegex.MatchString(k) || (k == "value" && sensitiveName)
+		redacted[k] = redactValue(v, secretValue, redact)
+	}
+	return redacted
+}
+
+// redactValue returns a copy of the given value, descending into maps and
+// lists, with the scalar values replaced with redactedValue if redact is true.
+// The elements of a list are matched by index against the elements of
+// secretValue.
+func redactValue(v, secretValue interface{}, redact bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		secretNested, _ := secretValue.(map[string]interface{})
+		return redactValues(v, secretNested)
+	case []interface{}:
+		secretList, _ := secretValue.([]interface{})
+		list := make([]interface{}, len(v))
+		for i, e := range v {
+			var secretElem interface{}
+			if i < len(secretList) {
+				secretElem = secretList[i]
+			}
+			list[i] = redactValue(e, secretElem, redact)
+		}
+		return list
+	}
+	if redact {
+		return redactedValue
+	}
+	return v
+}
+
+// pendingActions returns the reasons for which the next reconciliation of the
+// given HelmRelease is expected to act on the release, based on the given
+// digests computed from its current spec.
+func pendingActions(obj *v2.HelmRelease, digests Digests) []string {
+	if obj.Spec.Suspend {
+		return nil
+	}
+
+	var pending []string
+	if v2.ShouldHandleResetRequest(obj) {
+		pending = append(pending, "reset of the remediation retries requested")
+	}
+	if v2.ShouldHandleForceRequest(obj) {
+		pending = append(pending, "forced release requested")
+	}
+	if obj.Generation != obj.Status.ObservedGeneration {
+		pending = append(pending, fmt.Sprintf("generation %d not yet reconciled", obj.Generation))
+	}
+	if digests.Values != "" && digests.LastAttemptedValues != "" && digests.Values != digests.LastAttemptedValues {
+		pending = append(pending, "values changed since the last release attempt")
+	}
+	if digests.ObservedPostRenderers != "" && digests.PostRenderers != digests.ObservedPostRenderers {
+		pending = append(pending, "post renderers changed since the last reconciliation")
+	}
+	if len(obj.Status.History) == 0 {
+		pending = append(pending, "no release made by the controller")
+	}
+	return pending
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v2.AddToScheme(scheme)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "podinfo",
+			Namespace:  "apps",
+			Generation: 2,
+		},
+		Spec: v2.HelmReleaseSpec{
+			ChartRef: &v2.CrossNamespaceSourceReference{
+				Kind: "OCIRepository",
+				Name: "podinfo",
+			},
+			Values: &apiextensionsv1.JSON{Raw: []byte(`{"replicas":2,"auth":{"apiKey":"inline"}}`)},
+			ValuesFrom: []v2.ValuesReference{
+				{Kind: "Secret", Name: "values"},
+			},
+		},
+		Status: v2.HelmReleaseStatus{
+			ObservedGeneration:        1,
+			LastAttemptedConfigDigest: "sha256:old",
+			History: v2.Snapshots{
+				{Name: "podinfo", Namespace: "apps", Version: 1},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "values", Namespace: "apps"},
+		Data: map[string][]byte{
+			"values.yaml": []byte("image:\n  tag: 6.0.0\n  repository: podinfo\n"),
+		},
+	}
+	// The token "valid" authenticates the user "alice", who is allowed to
+	// get the debug view of HelmReleases in the "apps" namespace.
+	newClient := func(objs ...client.Object) client.Client {
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				switch review := obj.(type) {
+				case *authenticationv1.TokenReview:
+					if review.Spec.Token == "valid" {
+						review.Status.Authenticated = true
+						review.Status.User = authenticationv1.UserInfo{Username: "alice"}
+					}
+				case *authorizationv1.SubjectAccessReview:
+					attrs := review.Spec.ResourceAttributes
+					review.Status.Allowed = review.Spec.User == "alice" && attrs.Namespace == "apps" &&
+						attrs.Resource == "helmreleases" && attrs.Subresource == Subresource && attrs.Verb == "get"
+				default:
+					return c.Create(ctx, obj, opts...)
+				}
+				return nil
+			},
+		}).Build()
+	}
+	newRequest := func(path string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer valid")
+		return req
+	}
+	h := NewHandler(newClient(obj, secret))
+
+	t.Run("dumps view of HelmRelease", func(t *testing.T) {
+		g := NewWithT(t)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest(Path+"apps/podinfo"))
+		g.Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var view View
+		g.Expect(json.Unmarshal(rec.Body.Bytes(), &view)).To(Succeed())
+		g.Expect(view.Object).To(Equal("apps/podinfo"))
+		g.Expect(view.Chart.Ref).To(Equal("OCIRepository/apps/podinfo"))
+		g.Expect(view.ValuesError).To(BeEmpty())
+		g.Expect(view.Values).To(Equal(map[string]interface{}{
+			"replicas": float64(2),
+			"auth":     map[string]interface{}{"apiKey": redactedValue},
+			"image":    map[string]interface{}{"tag": redactedValue, "repository": redactedValue},
+		}))
+		g.Expect(view.Digests.Values).ToNot(BeEmpty())
+		g.Expect(view.History).To(HaveLen(1))
+		g.Expect(view.PendingActions).To(ConsistOf(
+			"generation 2 not yet reconciled",
+			"values changed since the last release attempt",
+		))
+	})
+
+	t.Run("reports values error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := obj.DeepCopy()
+		obj.Name = "missing"
+		obj.ResourceVersion = ""
+		obj.Spec.ValuesFrom = []v2.ValuesReference{{Kind: "ConfigMap", Name: "missing"}}
+		h := NewHandler(newClient(obj))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest(Path+"apps/missing"))
+		g.Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var view View
+		g.Expect(json.Unmarshal(rec.Body.Bytes(), &view)).To(Succeed())
+		g.Expect(view.Values).To(BeNil())
+		g.Expect(view.ValuesError).To(ContainSubstring("could not resolve ConfigMap chart values reference"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		g := NewWithT(t)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest(Path+"apps/other"))
+		g.Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		g := NewWithT(t)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path+"apps/podinfo", nil))
+		g.Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		g.Expect(rec.Body.String()).ToNot(ContainSubstring("podinfo"))
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		g := NewWithT(t)
+
+		other := obj.DeepCopy()
+		other.Namespace = "other"
+		other.ResourceVersion = ""
+		h := NewHandler(newClient(other))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest(Path+"other/podinfo"))
+		g.Expect(rec.Code).To(Equal(http.StatusForbidden))
+		g.Expect(rec.Body.String()).To(ContainSubstring("user 'alice' is not allowed to get helmreleases/debug"))
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		g := NewWithT(t)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest(Path+"apps"))
+		g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+}
+
+func Test_redactValues(t *testing.T) {
+	g := NewWithT(t)
+
+	values := map[string]interface{}{
+		"replicas": float64(2),
+		"env": []interface{}{
+			map[string]interface{}{"name": "LOG_LEVEL", "value": "debug"},
+			map[string]interface{}{"name": "DB_PASSWORD", "value": "inline"},
+		},
+		"tokens": []interface{}{"a", "b"},
+		"hosts":  []interface{}{"example.com", map[string]interface{}{"user": "admin"}},
+	}
+	secretValues := map[string]interface{}{
+		"hosts": []interface{}{"example.com", map[string]interface{}{"user": "admin"}},
+	}
+
+	g.Expect(redactValues(values, secretValues)).To(Equal(map[string]interface{}{
+		"replicas": float64(2),
+		"env": []interface{}{
+			map[string]interface{}{"name": "LOG_LEVEL", "value": "debug"},
+			map[string]interface{}{"name": "DB_PASSWORD", "value": redactedValue},
+		},
+		"tokens": []interface{}{redactedValue, redactedValue},
+		"hosts":  []interface{}{redactedValue, map[string]interface{}{"user": redactedValue}},
+	}))
+}
@@ -0,0 +1,27 @@
+//go:build !fips
+
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package digest
+
+import (
+	_ "github.com/opencontainers/go-digest/blake3"
+)
+
+// fipsBuild is false as the controller is not built with the "fips" build
+// tag, which makes BLAKE3 available for the verification of digests.
+const fipsBuild = false
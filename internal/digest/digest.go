@@ -21,9 +21,9 @@ import (
 	_ "crypto/sha256"
 	_ "crypto/sha512"
 	"fmt"
+	"slices"
 
 	"github.com/opencontainers/go-digest"
-	_ "github.com/opencontainers/go-digest/blake3"
 )
 
 const (
@@ -34,6 +34,15 @@ var (
 	// Canonical is the primary digest algorithm used to calculate checksums
 	// for e.g. Helm release objects and config values.
 	Canonical = digest.SHA256
+
+	// FIPS restricts the digest algorithms which can be configured and
+	// verified to FIPSAlgorithms. It is enabled by default when the
+	// controller is built with the "fips" build tag.
+	FIPS = fipsBuild
+
+	// FIPSAlgorithms are the FIPS-approved digest algorithms allowed when
+	// FIPS is enabled.
+	FIPSAlgorithms = []digest.Algorithm{digest.SHA256, digest.SHA384, digest.SHA512}
 )
 
 func init() {
@@ -48,5 +57,39 @@ func AlgorithmForName(name string) (digest.Algorithm, error) {
 	if !a.Available() {
 		return "", fmt.Errorf("%w: %s", digest.ErrDigestUnsupported, name)
 	}
+	if err := Allowed(a); err != nil {
+		return "", err
+	}
 	return a, nil
 }
+
+// Allowed returns an error of type digest.ErrDigestUnsupported if FIPS is
+// enabled and the given algorithm is not one of FIPSAlgorithms. It is used to
+// guard the code paths verifying digests, so that data is not trusted based
+// on a digest calculated with an algorithm which is not FIPS-approved.
+func Allowed(algo digest.Algorithm) error {
+	if FIPS && !slices.Contains(FIPSAlgorithms, algo) {
+		return fmt.Errorf("%w: %s is not a FIPS-approved algorithm", digest.ErrDigestUnsupported, algo)
+	}
+	return nil
+}
+
+// Verifiable returns an error if the given digest can not be verified,
+// because it is invalid, its algorithm is unavailable, or its algorithm is
+// not Allowed.
+func Verifiable(d digest.Digest) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	return Allowed(d.Algorithm())
+}
+
+// AlgorithmOf returns the algorithm of the given digest if it is Verifiable,
+// or Canonical otherwise. It allows a digest to be compared with a digest
+// calculated with a previously configured algorithm.
+func AlgorithmOf(d string) digest.Algorithm {
+	if dig := digest.Digest(d); Verifiable(dig) == nil {
+		return dig.Algorithm()
+	}
+	return Canonical
+}
@@ -69,3 +69,70 @@ func TestAlgorithmForName(t *testing.T) {
 		})
 	}
 }
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		fips    bool
+		algo    digest.Algorithm
+		wantErr bool
+	}{
+		{name: "sha256", fips: true, algo: digest.SHA256},
+		{name: "sha384", fips: true, algo: digest.SHA384},
+		{name: "sha512", fips: true, algo: digest.SHA512},
+		{name: "blake3", fips: true, algo: digest.BLAKE3, wantErr: true},
+		{name: "sha1", fips: true, algo: SHA1, wantErr: true},
+		{name: "blake3 without FIPS", fips: false, algo: digest.BLAKE3},
+		{name: "sha1 without FIPS", fips: false, algo: SHA1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			curFIPS := FIPS
+			FIPS = tt.fips
+			t.Cleanup(func() { FIPS = curFIPS })
+
+			err := Allowed(tt.algo)
+			if tt.wantErr {
+				g.Expect(errors.Is(err, digest.ErrDigestUnsupported)).To(BeTrue())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+
+	t.Run("AlgorithmForName rejects algorithms which are not allowed", func(t *testing.T) {
+		g := NewWithT(t)
+
+		curFIPS := FIPS
+		FIPS = true
+		t.Cleanup(func() { FIPS = curFIPS })
+
+		_, err := AlgorithmForName("sha1")
+		g.Expect(err).To(MatchError("unsupported digest algorithm: sha1 is not a FIPS-approved algorithm"))
+	})
+}
+
+func TestVerifiable(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(Verifiable(digest.SHA512.FromString("values"))).To(Succeed())
+	g.Expect(Verifiable(SHA1.FromString("values"))).To(Succeed())
+	g.Expect(Verifiable("")).ToNot(Succeed())
+	g.Expect(Verifiable("md5:d41d8cd98f00b204e9800998ecf8427e")).ToNot(Succeed())
+
+	curFIPS := FIPS
+	FIPS = true
+	t.Cleanup(func() { FIPS = curFIPS })
+	g.Expect(Verifiable(SHA1.FromString("values"))).To(MatchError(ContainSubstring("not a FIPS-approved algorithm")))
+}
+
+func TestAlgorithmOf(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(AlgorithmOf(digest.SHA512.FromString("values").String())).To(Equal(digest.SHA512))
+	g.Expect(AlgorithmOf(digest.SHA256.FromString("values").String())).To(Equal(digest.SHA256))
+	g.Expect(AlgorithmOf("")).To(Equal(Canonical))
+	g.Expect(AlgorithmOf("invalid")).To(Equal(Canonical))
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events provides recorders which filter and annotate the events
+// recorded by the controller.
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	kuberecorder "k8s.io/client-go/tools/record"
+
+	"github.com/fluxcd/helm-controller/internal/digest"
+)
+
+// Filter is a kuberecorder.EventRecorder which de-duplicates warning events.
+// A warning event with the same reason and message as a previous warning
+// event for the same object is dropped, until the configured interval has
+// passed since the previous event was recorded.
+//
+// This prevents a storm of identical alerts from being sent during a
+// prolonged failure, while changes in the failure are still reported
+// immediately. Events of other types are always recorded.
+type Filter struct {
+	kuberecorder.EventRecorder
+
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewFilter returns a new Filter which records the events which are not
+// filtered using the given recorder. An interval of zero or less disables
+// the filter.
+func NewFilter(recorder kuberecorder.EventRecorder, interval time.Duration) *Filter {
+	return &Filter{
+		EventRecorder: recorder,
+		interval:      interval,
+		now:           time.Now,
+		seen:          make(map[string]time.Time),
+	}
+}
+
+// SetInterval sets the interval after which an identical warning event is
+// recorded again. An interval of zero or less disables the filter.
+func (f *Filter) SetInterval(interval time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.interval = interval
+}
+
+// Event records the event for the object unless it is filtered.
+func (f *Filter) Event(object runtime.Object, eventtype, reason, message string) {
+	if f.filter(object, eventtype, reason, message) {
+		return
+	}
+	f.EventRecorder.Event(object, eventtype, reason, message)
+}
+
+// Eventf records the event for the object unless it is filtered.
+func (f *Filter) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if f.filter(object, eventtype, reason, message) {
+		return
+	}
+	f.EventRecorder.Event(object, eventtype, reason, message)
+}
+
+// AnnotatedEventf records the event for the object unless it is filtered.
+func (f *Filter) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if f.filter(object, eventtype, reason, message) {
+		return
+	}
+	f.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+}
+
+// filter returns true if the event should be dropped. If not, it records
+// the event as seen.
+func (f *Filter) filter(object runtime.Object, eventtype, reason, message string) bool {
+	if eventtype != corev1.EventTypeWarning {
+		return false
+	}
+
+	objMeta, err := apimeta.Accessor(object)
+	if err != nil {
+		return false
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s/%s", objMeta.GetUID(), objMeta.GetNamespace(), objMeta.GetName(),
+		reason, digest.Canonical.FromString(message).Encoded())
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.interval <= 0 {
+		return false
+	}
+
+	now := f.now()
+	f.prune(now)
+
+	if last, ok := f.seen[key]; ok && now.Sub(last) < f.interval {
+		return true
+	}
+	f.seen[key] = now
+	return false
+}
+
+// prune removes the entries which have expired, at most once per interval.
+func (f *Filter) prune(now time.Time) {
+	if now.Sub(f.lastPrune) < f.interval {
+		return
+	}
+	for k, last := range f.seen {
+		if now.Sub(last) >= f.interval {
+			delete(f.seen, k)
+		}
+	}
+	f.lastPrune = now
+}
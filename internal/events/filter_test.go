@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kuberecorder "k8s.io/client-go/tools/record"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestFilter(t *testing.T) {
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "release",
+			Namespace: "default",
+			UID:       "uid",
+		},
+	}
+	other := obj.DeepCopy()
+	other.Name = "other"
+	other.UID = "other-uid"
+
+	t.Run("drops identical warning events within interval", func(t *testing.T) {
+		g := NewWithT(t)
+
+		now := time.Now()
+		recorder := kuberecorder.NewFakeRecorder(10)
+		f := NewFilter(recorder, time.Minute)
+		f.now = func() time.Time { return now }
+
+		f.Eventf(obj, corev1.EventTypeWarning, "InstallFailed", "install failed: %s", "timeout")
+		f.AnnotatedEventf(obj, nil, corev1.EventTypeWarning, "InstallFailed", "install failed: %s", "timeout")
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed: timeout")
+		g.Expect(recorder.Events).To(HaveLen(1))
+
+		// A different message, reason or object is recorded.
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed: other")
+		f.Event(obj, corev1.EventTypeWarning, "UpgradeFailed", "install failed: timeout")
+		f.Event(other, corev1.EventTypeWarning, "InstallFailed", "install failed: timeout")
+		g.Expect(recorder.Events).To(HaveLen(4))
+
+		// After the interval, the event is recorded again.
+		now = now.Add(time.Minute)
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed: timeout")
+		g.Expect(recorder.Events).To(HaveLen(5))
+		g.Expect(<-recorder.Events).To(Equal("Warning InstallFailed install failed: timeout"))
+	})
+
+	t.Run("records normal events", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := kuberecorder.NewFakeRecorder(10)
+		f := NewFilter(recorder, time.Minute)
+
+		f.Event(obj, corev1.EventTypeNormal, "InstallSucceeded", "install succeeded")
+		f.Event(obj, corev1.EventTypeNormal, "InstallSucceeded", "install succeeded")
+		g.Expect(recorder.Events).To(HaveLen(2))
+	})
+
+	t.Run("disabled with zero interval", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := kuberecorder.NewFakeRecorder(10)
+		f := NewFilter(recorder, 0)
+
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		g.Expect(recorder.Events).To(HaveLen(2))
+	})
+
+	t.Run("changes interval at runtime", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := kuberecorder.NewFakeRecorder(10)
+		f := NewFilter(recorder, 0)
+
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		g.Expect(recorder.Events).To(HaveLen(2))
+
+		f.SetInterval(time.Minute)
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		g.Expect(recorder.Events).To(HaveLen(3))
+	})
+
+	t.Run("prunes expired entries", func(t *testing.T) {
+		g := NewWithT(t)
+
+		now := time.Now()
+		f := NewFilter(kuberecorder.NewFakeRecorder(10), time.Minute)
+		f.now = func() time.Time { return now }
+
+		f.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		f.Event(other, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		g.Expect(f.seen).To(HaveLen(2))
+
+		now = now.Add(2 * time.Minute)
+		f.Event(obj, corev1.EventTypeWarning, "UpgradeFailed", "upgrade failed")
+		g.Expect(f.seen).To(HaveLen(1))
+	})
+}
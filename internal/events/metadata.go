@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"fmt"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kuberecorder "k8s.io/client-go/tools/record"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+const (
+	// MetaSeverityKey is the key for the severity of the event.
+	MetaSeverityKey = "severity"
+	// MetaAppVersionKey is the key for the app version of the chart of the
+	// latest release.
+	MetaAppVersionKey = "app-version"
+	// MetaTargetClusterKey is the key for the identity of the cluster the
+	// release is made to.
+	MetaTargetClusterKey = "target-cluster"
+	// MetaRevisionDigestKey is the key for the digest of the last attempted
+	// source revision.
+	MetaRevisionDigestKey = "revision-digest"
+
+	// InClusterTarget is the target cluster identity of a HelmRelease
+	// without a KubeConfig reference.
+	InClusterTarget = "in-cluster"
+)
+
+// Metadata is a kuberecorder.EventRecorder which adds structured metadata
+// about the HelmRelease to the annotations of every event, so that
+// notification-controller can route and template events without matching
+// on the message.
+//
+// Annotations set by the caller take precedence over the added metadata.
+type Metadata struct {
+	kuberecorder.EventRecorder
+}
+
+// NewMetadata returns a new Metadata which records the events with the
+// added metadata using the given recorder.
+func NewMetadata(recorder kuberecorder.EventRecorder) *Metadata {
+	return &Metadata{EventRecorder: recorder}
+}
+
+// Event records the event for the object with the added metadata.
+func (m *Metadata) Event(object runtime.Object, eventtype, reason, message string) {
+	m.EventRecorder.AnnotatedEventf(object, metadataFor(object, nil, eventtype), eventtype, reason, "%s", message)
+}
+
+// Eventf records the event for the object with the added metadata.
+func (m *Metadata) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	m.EventRecorder.AnnotatedEventf(object, metadataFor(object, nil, eventtype), eventtype, reason, "%s", fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf records the event for the object with the given
+// annotations and the added metadata.
+func (m *Metadata) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	m.EventRecorder.AnnotatedEventf(object, metadataFor(object, annotations, eventtype), eventtype, reason, "%s", fmt.Sprintf(messageFmt, args...))
+}
+
+// metadataFor returns a copy of the given annotations with the metadata
+// for the object and event type added. Objects other than a HelmRelease
+// only receive the severity.
+func metadataFor(object runtime.Object, annotations map[string]string, eventtype string) map[string]string {
+	m := make(map[string]string, len(annotations)+4)
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		key = v2.GroupVersion.Group + "/" + key
+		if _, ok := annotations[key]; !ok {
+			m[key] = value
+		}
+	}
+
+	add(MetaSeverityKey, severity(eventtype))
+	if obj, ok := object.(*v2.HelmRelease); ok {
+		if latest := obj.Status.History.Latest(); latest != nil {
+			add(MetaAppVersionKey, latest.AppVersion)
+		}
+		add(MetaTargetClusterKey, targetCluster(obj))
+		add(MetaRevisionDigestKey, obj.Status.LastAttemptedRevisionDigest)
+	}
+
+	for k, v := range annotations {
+		m[k] = v
+	}
+	return m
+}
+
+// severity returns the severity notification-controller assigns to an
+// event of the given type.
+func severity(eventtype string) string {
+	switch eventtype {
+	case corev1.EventTypeWarning:
+		return eventv1.EventSeverityError
+	case eventv1.EventTypeTrace:
+		return eventv1.EventSeverityTrace
+	default:
+		return eventv1.EventSeverityInfo
+	}
+}
+
+// targetCluster returns the identity of the cluster the release of the
+// given HelmRelease is made to. For a remote cluster, this is the
+// namespaced name of the KubeConfig Secret.
+func targetCluster(obj *v2.HelmRelease) string {
+	if obj.Spec.KubeConfig == nil {
+		return InClusterTarget
+	}
+	return obj.GetNamespace() + "/" + obj.Spec.KubeConfig.SecretRef.Name
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kuberecorder "k8s.io/client-go/tools/record"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// annotationsRecorder records the annotations of the recorded events.
+type annotationsRecorder struct {
+	kuberecorder.FakeRecorder
+	annotations []map[string]string
+}
+
+func (r *annotationsRecorder) AnnotatedEventf(_ runtime.Object, annotations map[string]string, _, _, _ string, _ ...interface{}) {
+	r.annotations = append(r.annotations, annotations)
+}
+
+func TestMetadata(t *testing.T) {
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "release",
+			Namespace: "default",
+		},
+		Status: v2.HelmReleaseStatus{
+			LastAttemptedRevisionDigest: "sha256:abc",
+			History: v2.Snapshots{
+				{Version: 1, AppVersion: "1.0.0"},
+				{Version: 2, AppVersion: "2.0.0"},
+			},
+		},
+	}
+
+	t.Run("adds metadata to events", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := &annotationsRecorder{}
+		m := NewMetadata(recorder)
+
+		m.Event(obj, corev1.EventTypeWarning, "InstallFailed", "install failed")
+		m.Eventf(obj, corev1.EventTypeNormal, "InstallSucceeded", "install %s", "succeeded")
+		g.Expect(recorder.annotations).To(Equal([]map[string]string{
+			{
+				"helm.toolkit.fluxcd.io/severity":        "error",
+				"helm.toolkit.fluxcd.io/app-version":     "2.0.0",
+				"helm.toolkit.fluxcd.io/target-cluster":  InClusterTarget,
+				"helm.toolkit.fluxcd.io/revision-digest": "sha256:abc",
+			},
+			{
+				"helm.toolkit.fluxcd.io/severity":        "info",
+				"helm.toolkit.fluxcd.io/app-version":     "2.0.0",
+				"helm.toolkit.fluxcd.io/target-cluster":  InClusterTarget,
+				"helm.toolkit.fluxcd.io/revision-digest": "sha256:abc",
+			},
+		}))
+	})
+
+	t.Run("retains given annotations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := &annotationsRecorder{}
+		m := NewMetadata(recorder)
+
+		annotations := map[string]string{
+			"helm.toolkit.fluxcd.io/app-version": "1.0.0",
+			"helm.toolkit.fluxcd.io/revision":    "1.0.0",
+		}
+		m.AnnotatedEventf(obj, annotations, corev1.EventTypeNormal, "UpgradeSucceeded", "upgrade succeeded")
+		g.Expect(recorder.annotations).To(HaveLen(1))
+		g.Expect(recorder.annotations[0]).To(HaveKeyWithValue("helm.toolkit.fluxcd.io/app-version", "1.0.0"))
+		g.Expect(recorder.annotations[0]).To(HaveKeyWithValue("helm.toolkit.fluxcd.io/revision", "1.0.0"))
+		g.Expect(recorder.annotations[0]).To(HaveKeyWithValue("helm.toolkit.fluxcd.io/severity", "info"))
+		g.Expect(annotations).To(HaveLen(2))
+	})
+
+	t.Run("remote target cluster", func(t *testing.T) {
+		g := NewWithT(t)
+
+		remote := obj.DeepCopy()
+		remote.Spec.KubeConfig = &meta.KubeConfigReference{
+			SecretRef: meta.SecretKeyReference{Name: "kubeconfig"},
+		}
+		remote.Status = v2.HelmReleaseStatus{}
+
+		recorder := &annotationsRecorder{}
+		NewMetadata(recorder).Event(remote, corev1.EventTypeNormal, "InstallSucceeded", "install succeeded")
+		g.Expect(recorder.annotations).To(Equal([]map[string]string{{
+			"helm.toolkit.fluxcd.io/severity":       "info",
+			"helm.toolkit.fluxcd.io/target-cluster": "default/kubeconfig",
+		}}))
+	})
+}
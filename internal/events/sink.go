@@ -0,0 +1,231 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kuberecorder "k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/outbound"
+)
+
+const (
+	// sinkTimeout is the timeout for posting an event to an EventSink,
+	// including getting the Secret with its address.
+	sinkTimeout = 10 * time.Second
+	// sinkQueueSize is the number of events which can be queued to be
+	// posted. Events are dropped while the queue is full.
+	sinkQueueSize = 1000
+	// sinkWorkers is the number of workers posting the queued events.
+	sinkWorkers = 4
+)
+
+// Sink is a kuberecorder.EventRecorder which posts the events of a
+// HelmRelease with a v2.EventSink to the address in the referenced Secret,
+// in addition to recording them using the wrapped recorder. This allows the
+// raw events of specific releases to be sent to other systems, without
+// setting up notification-controller.
+//
+// The events are queued, and posted by a fixed number of workers once the
+// Sink has been started, in the same format as they are sent to
+// notification-controller. An event is dropped when the queue is full, and a
+// failure to post an event is logged, and not retried. Trace events are not
+// posted. The address must be allowed by the outbound.Client.
+type Sink struct {
+	kuberecorder.EventRecorder
+
+	client     client.Reader
+	httpClient *outbound.Client
+	controller string
+	hostname   string
+	queue      chan sinkEvent
+	log        logr.Logger
+}
+
+// sinkEvent is an event queued to be posted to the address in the Secret.
+type sinkEvent struct {
+	secret types.NamespacedName
+	event  eventv1.Event
+}
+
+// NewSink returns a new Sink which records the events using the given
+// recorder, gets the Secrets of the v2.EventSink using the given client,
+// and posts the events using the given outbound.Client. The controller name
+// is reported as the controller of the posted events.
+func NewSink(recorder kuberecorder.EventRecorder, reader client.Reader, httpClient *outbound.Client,
+	controller string, log logr.Logger) *Sink {
+	hostname, _ := os.Hostname()
+	return &Sink{
+		EventRecorder: recorder,
+		client:        reader,
+		httpClient:    httpClient,
+		controller:    controller,
+		hostname:      hostname,
+		queue:         make(chan sinkEvent, sinkQueueSize),
+		log:           log,
+	}
+}
+
+// Start posts the queued events until the context is cancelled. It
+// implements manager.Runnable.
+func (s *Sink) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < sinkWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e := <-s.queue:
+					s.postQueued(ctx, e)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, to post the
+// events of every replica.
+func (s *Sink) NeedLeaderElection() bool {
+	return false
+}
+
+// Event records the event for the object, and posts it to the EventSink of
+// the object.
+func (s *Sink) Event(object runtime.Object, eventtype, reason, message string) {
+	s.EventRecorder.Event(object, eventtype, reason, message)
+	s.post(object, nil, eventtype, reason, message)
+}
+
+// Eventf records the event for the object, and posts it to the EventSink of
+// the object.
+func (s *Sink) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	s.EventRecorder.Event(object, eventtype, reason, message)
+	s.post(object, nil, eventtype, reason, message)
+}
+
+// AnnotatedEventf records the event for the object with the given
+// annotations, and posts it to the EventSink of the object.
+func (s *Sink) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	s.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	s.post(object, annotations, eventtype, reason, message)
+}
+
+// post queues the event to be posted if the object is a HelmRelease with an
+// EventSink. The event is dropped if the queue is full.
+func (s *Sink) post(object runtime.Object, annotations map[string]string, eventtype, reason, message string) {
+	obj, ok := object.(*v2.HelmRelease)
+	if !ok || obj.Spec.EventSink == nil {
+		return
+	}
+	severity := severity(eventtype)
+	if severity == eventv1.EventSeverityTrace {
+		return
+	}
+
+	e := sinkEvent{
+		secret: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.Spec.EventSink.SecretRef.Name},
+		event: eventv1.Event{
+			InvolvedObject: corev1.ObjectReference{
+				Kind:            v2.HelmReleaseKind,
+				APIVersion:      v2.GroupVersion.String(),
+				Namespace:       obj.GetNamespace(),
+				Name:            obj.GetName(),
+				UID:             obj.GetUID(),
+				ResourceVersion: obj.GetResourceVersion(),
+			},
+			Severity:            severity,
+			Timestamp:           metav1.Now(),
+			Message:             message,
+			Reason:              reason,
+			Metadata:            annotations,
+			ReportingController: s.controller,
+			ReportingInstance:   s.hostname,
+		},
+	}
+	select {
+	case s.queue <- e:
+	default:
+		s.log.Info("event sink queue is full, dropping event",
+			"name", obj.GetName(), "namespace", obj.GetNamespace(), "reason", reason)
+	}
+}
+
+// postQueued posts the queued event, and logs any error.
+func (s *Sink) postQueued(ctx context.Context, e sinkEvent) {
+	ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+	defer cancel()
+	if err := s.send(ctx, e.secret, e.event); err != nil {
+		s.log.Error(err, "unable to post event to event sink",
+			"name", e.event.InvolvedObject.Name, "namespace", e.event.InvolvedObject.Namespace, "reason", e.event.Reason)
+	}
+}
+
+// send posts the event as JSON to the address in the given Secret, and
+// returns an error if the endpoint does not respond with a 2xx status code.
+func (s *Sink) send(ctx context.Context, secret types.NamespacedName, event eventv1.Event) error {
+	address, err := s.address(ctx, secret)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if err = s.httpClient.Post(ctx, address, body, nil); err != nil {
+		return fmt.Errorf("failed to post event: %w", err)
+	}
+	return nil
+}
+
+// address returns the address of the endpoint in the given Secret.
+func (s *Sink) address(ctx context.Context, secret types.NamespacedName) (string, error) {
+	obj := &corev1.Secret{}
+	if err := s.client.Get(ctx, secret, obj); err != nil {
+		return "", fmt.Errorf("failed to get event sink Secret '%s': %w", secret, err)
+	}
+	address := strings.TrimSpace(string(obj.Data[v2.EventSinkAddressKey]))
+	if address == "" {
+		return "", fmt.Errorf("event sink Secret '%s' has no '%s' key", secret, v2.EventSinkAddressKey)
+	}
+	if err := s.httpClient.ValidateAddress(address); err != nil {
+		return "", fmt.Errorf("event sink Secret '%s' has an invalid address: %w", secret, err)
+	}
+	return address, nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kuberecorder "k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/outbound"
+)
+
+func TestSink(t *testing.T) {
+	received := make(chan eventv1.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event eventv1.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "default"},
+		Data:       map[string][]byte{v2.EventSinkAddressKey: []byte(server.URL)},
+	}).Build()
+
+	httpClient, err := outbound.NewClient(outbound.Options{AllowedHosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "release",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: v2.HelmReleaseSpec{
+			EventSink: &v2.EventSink{SecretRef: meta.LocalObjectReference{Name: "sink"}},
+		},
+	}
+
+	t.Run("posts events of HelmRelease with event sink", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := kuberecorder.NewFakeRecorder(10)
+		s := NewSink(recorder, reader, httpClient, "helm-controller", logr.Discard())
+		go s.Start(ctx)
+
+		s.AnnotatedEventf(obj, map[string]string{"helm.toolkit.fluxcd.io/severity": "error"},
+			corev1.EventTypeWarning, "UpgradeFailed", "upgrade failed: %s", "timeout")
+		g.Expect(recorder.Events).To(Receive(HavePrefix("Warning UpgradeFailed upgrade failed: timeout")))
+
+		var event eventv1.Event
+		g.Eventually(received, time.Second).Should(Receive(&event))
+		g.Expect(event.InvolvedObject.Kind).To(Equal(v2.HelmReleaseKind))
+		g.Expect(event.InvolvedObject.APIVersion).To(Equal(v2.GroupVersion.String()))
+		g.Expect(event.InvolvedObject.Namespace).To(Equal("default"))
+		g.Expect(event.InvolvedObject.Name).To(Equal("release"))
+		g.Expect(event.InvolvedObject.UID).To(Equal(types.UID("uid")))
+		g.Expect(event.Severity).To(Equal(eventv1.EventSeverityError))
+		g.Expect(event.Reason).To(Equal("UpgradeFailed"))
+		g.Expect(event.Message).To(Equal("upgrade failed: timeout"))
+		g.Expect(event.Metadata).To(HaveKeyWithValue("helm.toolkit.fluxcd.io/severity", "error"))
+		g.Expect(event.ReportingController).To(Equal("helm-controller"))
+	})
+
+	t.Run("does not post trace events", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := kuberecorder.NewFakeRecorder(10)
+		s := NewSink(recorder, reader, httpClient, "helm-controller", logr.Discard())
+		go s.Start(ctx)
+
+		s.Eventf(obj, eventv1.EventTypeTrace, "HelmChartDeleted", "deleted HelmChart")
+		s.Event(obj, corev1.EventTypeNormal, "InstallSucceeded", "install succeeded")
+		g.Expect(recorder.Events).To(HaveLen(2))
+
+		var event eventv1.Event
+		g.Eventually(received, time.Second).Should(Receive(&event))
+		g.Expect(event.Reason).To(Equal("InstallSucceeded"))
+		g.Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	t.Run("does not post events of objects without event sink", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := kuberecorder.NewFakeRecorder(10)
+		s := NewSink(recorder, reader, httpClient, "helm-controller", logr.Discard())
+		go s.Start(ctx)
+
+		other := obj.DeepCopy()
+		other.Spec.EventSink = nil
+		s.Event(other, corev1.EventTypeNormal, "InstallSucceeded", "install succeeded")
+		s.Event(&corev1.ConfigMap{}, corev1.EventTypeNormal, "Created", "created")
+		g.Expect(recorder.Events).To(HaveLen(2))
+		g.Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	t.Run("send errors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		s := NewSink(kuberecorder.NewFakeRecorder(10), fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "default"},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "default"},
+				Data:       map[string][]byte{v2.EventSinkAddressKey: []byte("ftp://example.com")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "disallowed", Namespace: "default"},
+				Data:       map[string][]byte{v2.EventSinkAddressKey: []byte("http://169.254.169.254/latest")},
+			},
+		).Build(), httpClient, "helm-controller", logr.Discard())
+
+		err := s.send(context.TODO(), types.NamespacedName{Namespace: "default", Name: "missing"}, eventv1.Event{})
+		g.Expect(err).To(MatchError(ContainSubstring("failed to get event sink Secret 'default/missing'")))
+
+		err = s.send(context.TODO(), types.NamespacedName{Namespace: "default", Name: "empty"}, eventv1.Event{})
+		g.Expect(err).To(MatchError("event sink Secret 'default/empty' has no 'address' key"))
+
+		err = s.send(context.TODO(), types.NamespacedName{Namespace: "default", Name: "invalid"}, eventv1.Event{})
+		g.Expect(err).To(MatchError("event sink Secret 'default/invalid' has an invalid address: must be an HTTP(S) URL"))
+
+		err = s.send(context.TODO(), types.NamespacedName{Namespace: "default", Name: "disallowed"}, eventv1.Event{})
+		g.Expect(err).To(MatchError("event sink Secret 'default/disallowed' has an invalid address: host '169.254.169.254' is not allowed"))
+	})
+
+	t.Run("drops events while queue is full", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := kuberecorder.NewFakeRecorder(sinkQueueSize + 1)
+		s := NewSink(recorder, reader, httpClient, "helm-controller", logr.Discard())
+
+		// The Sink is not started, so the queued events are not posted.
+		for i := 0; i < sinkQueueSize+1; i++ {
+			s.Event(obj, corev1.EventTypeNormal, "InstallSucceeded", "install succeeded")
+		}
+		g.Expect(recorder.Events).To(HaveLen(sinkQueueSize + 1))
+		g.Expect(s.queue).To(HaveLen(sinkQueueSize))
+	})
+}
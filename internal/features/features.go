@@ -18,7 +18,12 @@ limitations under the License.
 // helm-controller supports, and their default states.
 package features
 
-import feathelper "github.com/fluxcd/pkg/runtime/features"
+import (
+	"fmt"
+	"sync"
+
+	feathelper "github.com/fluxcd/pkg/runtime/features"
+)
 
 const (
 	// CacheSecretsAndConfigMaps configures the caching of Secrets and ConfigMaps
@@ -58,6 +63,87 @@ const (
 	// without the need to upgrade the Helm release. But it can be disabled to
 	// avoid potential abuse of the adoption mechanism.
 	AdoptLegacyReleases = "AdoptLegacyReleases"
+
+	// GarbageCollectStorage enables the periodic garbage collection of Helm
+	// storage Secrets created by the controller which do not belong to the
+	// release of any HelmRelease anymore. This is disabled by default.
+	GarbageCollectStorage = "GarbageCollectStorage"
+
+	// DebugEndpoint enables the debug endpoint on the metrics server, which
+	// dumps the view of the controller on a HelmRelease, including its
+	// (redacted) composed values, for authenticated and authorized users.
+	// This is disabled by default.
+	DebugEndpoint = "DebugEndpoint"
+
+	// PreviewEndpoint enables the preview endpoint on the metrics server,
+	// which renders the manifest of the release of a HelmRelease and
+	// summarizes the changes to the deployed release, for authenticated and
+	// authorized users. This is disabled by default.
+	PreviewEndpoint = "PreviewEndpoint"
+
+	// ManifestEndpoint enables the manifest endpoint on the metrics server,
+	// which returns the manifest stored for a release in the history of a
+	// HelmRelease, for authenticated and authorized users. This is disabled
+	// by default.
+	ManifestEndpoint = "ManifestEndpoint"
+
+	// PodSecurityPreCheck enables the evaluation of the rendered Pod specs
+	// against the Pod Security Standard enforced on the namespace of the
+	// release, before an install or upgrade is performed. This is disabled
+	// by default.
+	PodSecurityPreCheck = "PodSecurityPreCheck"
+
+	// ResourceQuotaPreCheck enables the estimation of the compute resources
+	// requested by the rendered workloads, and their comparison with the
+	// ResourceQuotas of the namespace of the release, before an install or
+	// upgrade is performed. This is disabled by default.
+	ResourceQuotaPreCheck = "ResourceQuotaPreCheck"
+
+	// APICompatibilityPreCheck enables the validation of the API versions of
+	// the rendered objects against the APIs served by the target cluster,
+	// before an install or upgrade is performed. This is disabled by
+	// default.
+	APICompatibilityPreCheck = "APICompatibilityPreCheck"
+
+	// CRDSchemaPreCheck enables the validation of the rendered custom
+	// resources against the schemas of their CustomResourceDefinitions,
+	// before an install or upgrade is performed. This is disabled by
+	// default.
+	CRDSchemaPreCheck = "CRDSchemaPreCheck"
+
+	// MigrateDeprecatedAPIs enables the migration of objects using APIs
+	// which were removed from the cluster in the manifest of the deployed
+	// release in the Helm storage to the APIs replacing them, before an
+	// upgrade is performed. This is disabled by default.
+	MigrateDeprecatedAPIs = "MigrateDeprecatedAPIs"
+
+	// CompactStatus enables the compaction of the HelmRelease status, by
+	// truncating condition messages and trimming the history to the
+	// Snapshots required for remediation. This reduces the size of objects
+	// in etcd for large installations, while the full messages remain
+	// available via events. This is disabled by default.
+	CompactStatus = "CompactStatus"
+
+	// UpgradePreview enables the publication of a summary of the changes a
+	// Helm upgrade will make to the objects of the release, as an event
+	// emitted before the upgrade is performed. This is disabled by default.
+	UpgradePreview = "UpgradePreview"
+
+	// VerifyChartDependencies enables the verification of the versions of
+	// the dependencies bundled with the chart against the lock file of the
+	// chart, before the release is rendered. This is disabled by default.
+	VerifyChartDependencies = "VerifyChartDependencies"
+
+	// EventSinks enables the posting of the events of HelmReleases to the
+	// address in the Secret referenced by their event sink, restricted to
+	// the hosts allowed by the operator. This is disabled by default.
+	EventSinks = "EventSinks"
+
+	// ActionWebhooks enables the calling of the release action webhooks of
+	// HelmReleases before and after the Helm actions performed for them,
+	// restricted to the hosts allowed by the operator. This is disabled by
+	// default.
+	ActionWebhooks = "ActionWebhooks"
 )
 
 var features = map[string]bool{
@@ -79,8 +165,72 @@ var features = map[string]bool{
 	// AdoptLegacyReleases
 	// opt-out from v0.37
 	AdoptLegacyReleases: true,
+	// GarbageCollectStorage
+	// opt-in from v1.1
+	GarbageCollectStorage: false,
+	// DebugEndpoint
+	// opt-in from v1.1
+	DebugEndpoint: false,
+	// PreviewEndpoint
+	// opt-in from v1.1
+	PreviewEndpoint: false,
+	// ManifestEndpoint
+	// opt-in from v1.1
+	ManifestEndpoint: false,
+	// PodSecurityPreCheck
+	// opt-in from v1.1
+	PodSecurityPreCheck: false,
+	// ResourceQuotaPreCheck
+	// opt-in from v1.1
+	ResourceQuotaPreCheck: false,
+	// APICompatibilityPreCheck
+	// opt-in from v1.1
+	APICompatibilityPreCheck: false,
+	// CRDSchemaPreCheck
+	// opt-in from v1.1
+	CRDSchemaPreCheck: false,
+	// MigrateDeprecatedAPIs
+	// opt-in from v1.1
+	MigrateDeprecatedAPIs: false,
+	// CompactStatus
+	// opt-in from v1.1
+	CompactStatus: false,
+	// UpgradePreview
+	// opt-in from v1.1
+	UpgradePreview: false,
+	// VerifyChartDependencies
+	// opt-in from v1.1
+	VerifyChartDependencies: false,
+	// EventSinks
+	// opt-in from v1.1
+	EventSinks: false,
+	// ActionWebhooks
+	// opt-in from v1.1
+	ActionWebhooks: false,
+}
+
+// reloadable are the feature gates which are evaluated for every
+// reconciliation, and can therefore be changed at runtime with SetReloadable.
+var reloadable = map[string]struct{}{
+	AllowDNSLookups:          {},
+	AdoptLegacyReleases:      {},
+	PodSecurityPreCheck:      {},
+	ResourceQuotaPreCheck:    {},
+	APICompatibilityPreCheck: {},
+	CRDSchemaPreCheck:        {},
+	MigrateDeprecatedAPIs:    {},
+	CompactStatus:            {},
+	UpgradePreview:           {},
+	VerifyChartDependencies:  {},
 }
 
+var (
+	// overrides are the states of reloadable feature gates set at runtime,
+	// which take precedence over the states set at startup.
+	overrides   map[string]bool
+	overridesMu sync.RWMutex
+)
+
 // FeatureGates contains a list of all supported feature gates and
 // their default values.
 func FeatureGates() map[string]bool {
@@ -93,9 +243,48 @@ func FeatureGates() map[string]bool {
 // pkg/runtime/features, so callers won't need to import
 // both packages for checking whether a feature is enabled.
 func Enabled(feature string) (bool, error) {
+	overridesMu.RLock()
+	enabled, ok := overrides[feature]
+	overridesMu.RUnlock()
+	if ok {
+		return enabled, nil
+	}
 	return feathelper.Enabled(feature)
 }
 
+// ValidateReloadable returns an error if any of the given feature gates can
+// not be changed at runtime.
+func ValidateReloadable(gates map[string]bool) error {
+	for feature := range gates {
+		if _, ok := features[feature]; !ok {
+			return fmt.Errorf("feature-gate '%s' not supported", feature)
+		}
+		if _, ok := reloadable[feature]; !ok {
+			return fmt.Errorf("feature-gate '%s' can not be changed at runtime", feature)
+		}
+	}
+	return nil
+}
+
+// SetReloadable sets the states of the given reloadable feature gates,
+// replacing the states set by a previous call. Feature gates which are not
+// given fall back to their state set at startup. It returns an error if any
+// of the feature gates can not be changed at runtime.
+func SetReloadable(gates map[string]bool) error {
+	if err := ValidateReloadable(gates); err != nil {
+		return err
+	}
+	m := make(map[string]bool, len(gates))
+	for feature, enabled := range gates {
+		m[feature] = enabled
+	}
+
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	overrides = m
+	return nil
+}
+
 // Disable disables the specified feature. If the feature is not
 // present, it's a no-op.
 func Disable(feature string) {
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package freeze holds the cluster-wide change freeze of the controller,
+// during which no mutating Helm actions are run, e.g. around cluster
+// maintenance.
+package freeze
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Freeze is a cluster-wide change freeze.
+type Freeze struct {
+	// Reason is the reason of the freeze, e.g. the maintenance it is for.
+	Reason string
+	// Until is the time at which the freeze is lifted. When zero, the freeze
+	// lasts until it is lifted with Set.
+	Until time.Time
+}
+
+// String returns a message describing the freeze.
+func (f *Freeze) String() string {
+	msg := "Cluster change freeze is active"
+	if !f.Until.IsZero() {
+		msg += fmt.Sprintf(" until %s", f.Until.UTC().Format(time.RFC3339))
+	}
+	if f.Reason != "" {
+		msg += ": " + f.Reason
+	}
+	return msg
+}
+
+// current is the Freeze set with Set.
+var current atomic.Pointer[Freeze]
+
+// Set sets the current Freeze, or lifts it when nil. It is safe to call
+// concurrently with Active.
+func Set(f *Freeze) {
+	current.Store(f)
+}
+
+// Active returns the Freeze which is active at the given time, or nil.
+func Active(now time.Time) *Freeze {
+	f := current.Load()
+	if f == nil || (!f.Until.IsZero() && !now.Before(f.Until)) {
+		return nil
+	}
+	return f
+}
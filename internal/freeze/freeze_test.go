@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package freeze
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestActive(t *testing.T) {
+	g := NewWithT(t)
+	t.Cleanup(func() { Set(nil) })
+
+	now := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	g.Expect(Active(now)).To(BeNil())
+
+	Set(&Freeze{})
+	g.Expect(Active(now)).ToNot(BeNil())
+	g.Expect(Active(now).String()).To(Equal("Cluster change freeze is active"))
+
+	Set(&Freeze{Reason: "cluster upgrade", Until: now.Add(time.Hour)})
+	g.Expect(Active(now).String()).To(Equal("Cluster change freeze is active until 2024-05-06T11:00:00Z: cluster upgrade"))
+	g.Expect(Active(now.Add(time.Hour))).To(BeNil())
+
+	Set(nil)
+	g.Expect(Active(now)).To(BeNil())
+}
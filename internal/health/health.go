@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health provides readiness checks for the subsystems the
+// controller depends on, which report the controller as degraded when one
+// of them can not be reached.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const (
+	flagDependencyChecks       = "readiness-dependency-checks"
+	flagDependencyCheckTimeout = "readiness-dependency-check-timeout"
+
+	// DiscoveryCheck is the name of the check of the Kubernetes API
+	// discovery.
+	DiscoveryCheck = "discovery"
+	// StorageCheck is the name of the check of the Helm storage backend.
+	StorageCheck = "helm-storage"
+	// ArtifactHostCheck is the name of the check of the host serving the
+	// chart artifacts.
+	ArtifactHostCheck = "artifact-host"
+)
+
+// Options configures the readiness checks of the dependencies.
+type Options struct {
+	// DependencyChecks enables the readiness checks of the dependencies.
+	DependencyChecks bool
+	// Timeout is the time a single check is allowed to take.
+	Timeout time.Duration
+}
+
+// BindFlags will parse the given pflag.FlagSet for the readiness check
+// flags and set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.DependencyChecks, flagDependencyChecks, false,
+		"Report the controller as not ready when the Kubernetes API discovery, the Helm storage backend or the chart artifact host can not be reached.")
+	fs.DurationVar(&o.Timeout, flagDependencyCheckTimeout, 5*time.Second,
+		"The time a single readiness check of a dependency is allowed to take.")
+}
+
+// Registerer registers named readiness checks, e.g. a ctrl.Manager.
+type Registerer interface {
+	AddReadyzCheck(name string, check healthz.Checker) error
+}
+
+// SetupChecks registers the given checks as readiness checks, when enabled
+// in the Options. Each check is limited to the configured timeout, and can
+// be queried individually at '/readyz/<name>'.
+func SetupChecks(r Registerer, opts Options, checks map[string]healthz.Checker) error {
+	if !opts.DependencyChecks {
+		return nil
+	}
+	for name, check := range checks {
+		if err := r.AddReadyzCheck(name, withTimeout(check, opts.Timeout)); err != nil {
+			return fmt.Errorf("unable to add '%s' readiness check: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// withTimeout returns the given check with the context of the request
+// limited to the given timeout.
+func withTimeout(check healthz.Checker, timeout time.Duration) healthz.Checker {
+	if timeout <= 0 {
+		return check
+	}
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		return check(req.WithContext(ctx))
+	}
+}
+
+// Discovery returns a check which fails when the API groups can not be
+// discovered from the Kubernetes API server.
+func Discovery(client discovery.ServerGroupsInterface) healthz.Checker {
+	return func(_ *http.Request) error {
+		if _, err := client.ServerGroups(); err != nil {
+			return fmt.Errorf("failed to discover API groups: %w", err)
+		}
+		return nil
+	}
+}
+
+// Ping returns a check which fails when the given function returns an error,
+// e.g. to check the Helm storage backend can be reached.
+func Ping(ping func(ctx context.Context) error) healthz.Checker {
+	return func(req *http.Request) error {
+		return ping(req.Context())
+	}
+}
+
+// ArtifactHost tracks the host serving the chart artifacts, as observed from
+// the artifact URLs the controller downloads charts from. The zero value is
+// ready to use.
+type ArtifactHost struct {
+	mu   sync.RWMutex
+	host string
+}
+
+// NewArtifactHost returns a new ArtifactHost.
+func NewArtifactHost() *ArtifactHost {
+	return &ArtifactHost{}
+}
+
+// Observe records the host of the given artifact URL. Invalid URLs are
+// ignored.
+func (a *ArtifactHost) Observe(artifactURL string) {
+	if a == nil {
+		return
+	}
+	u, err := url.Parse(artifactURL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.host = host
+}
+
+// Host returns the last observed host, or an empty string.
+func (a *ArtifactHost) Host() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.host
+}
+
+// Check fails when a TCP connection can not be established to the last
+// observed host. It succeeds when no host has been observed yet.
+func (a *ArtifactHost) Check(req *http.Request) error {
+	host := a.Host()
+	if host == "" {
+		return nil
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(req.Context(), "tcp", host)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return fmt.Errorf("artifact host '%s' did not respond in time", host)
+		}
+		return fmt.Errorf("artifact host '%s' is unreachable: %w", host, err)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+type fakeRegisterer map[string]healthz.Checker
+
+func (f fakeRegisterer) AddReadyzCheck(name string, check healthz.Checker) error {
+	f[name] = check
+	return nil
+}
+
+func TestSetupChecks(t *testing.T) {
+	checks := map[string]healthz.Checker{
+		DiscoveryCheck: healthz.Ping,
+		StorageCheck:   healthz.Ping,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := fakeRegisterer{}
+		g.Expect(SetupChecks(r, Options{}, checks)).To(Succeed())
+		g.Expect(r).To(BeEmpty())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := fakeRegisterer{}
+		g.Expect(SetupChecks(r, Options{DependencyChecks: true}, checks)).To(Succeed())
+		g.Expect(r).To(HaveKey(DiscoveryCheck))
+		g.Expect(r).To(HaveKey(StorageCheck))
+	})
+}
+
+func TestPing(t *testing.T) {
+	g := NewWithT(t)
+
+	check := withTimeout(Ping(func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("database unreachable")
+	}), 10*time.Millisecond)
+	g.Expect(check(httptest.NewRequest(http.MethodGet, "/readyz", nil))).To(MatchError("database unreachable"))
+}
+
+func TestArtifactHost(t *testing.T) {
+	t.Run("ready without observed host", func(t *testing.T) {
+		g := NewWithT(t)
+
+		a := NewArtifactHost()
+		g.Expect(a.Check(httptest.NewRequest(http.MethodGet, "/readyz", nil))).To(Succeed())
+	})
+
+	t.Run("reachable host", func(t *testing.T) {
+		g := NewWithT(t)
+
+		srv := httptest.NewServer(http.NotFoundHandler())
+		t.Cleanup(srv.Close)
+
+		a := NewArtifactHost()
+		a.Observe(srv.URL + "/helmchart/default/podinfo/podinfo-6.5.4.tgz")
+		g.Expect(a.Host()).To(Equal(srv.Listener.Addr().String()))
+		g.Expect(a.Check(httptest.NewRequest(http.MethodGet, "/readyz", nil))).To(Succeed())
+	})
+
+	t.Run("unreachable host", func(t *testing.T) {
+		g := NewWithT(t)
+
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		a := NewArtifactHost()
+		a.Observe(srv.URL + "/helmchart/default/podinfo/podinfo-6.5.4.tgz")
+		g.Expect(a.Check(httptest.NewRequest(http.MethodGet, "/readyz", nil))).To(MatchError(ContainSubstring("is unreachable")))
+	})
+
+	t.Run("default port", func(t *testing.T) {
+		g := NewWithT(t)
+
+		a := NewArtifactHost()
+		a.Observe("http://source-controller.flux-system.svc/helmchart/podinfo.tgz")
+		g.Expect(a.Host()).To(Equal("source-controller.flux-system.svc:80"))
+		a.Observe("https://source-controller.flux-system.svc/helmchart/podinfo.tgz")
+		g.Expect(a.Host()).To(Equal("source-controller.flux-system.svc:443"))
+	})
+
+	t.Run("nil is ignored", func(t *testing.T) {
+		var a *ArtifactHost
+		a.Observe("http://source-controller.flux-system.svc")
+	})
+}
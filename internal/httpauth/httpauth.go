@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpauth authenticates and authorizes the requests to the HTTP
+// endpoints of the controller against the Kubernetes API server, for
+// endpoints which expose data of a HelmRelease that its readers may not be
+// allowed to see. As the requests carry bearer tokens, the endpoints are
+// served over TLS by a dedicated server.
+package httpauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+const (
+	flagPort    = "endpoints-port"
+	flagCertDir = "endpoints-cert-dir"
+)
+
+// Options contains the configuration options for the server of the
+// authenticated endpoints.
+type Options struct {
+	// Port is the port the server listens on. The server is disabled when
+	// set to 0.
+	Port int
+	// CertDir is the directory containing the TLS certificate and key of the
+	// server.
+	CertDir string
+}
+
+// BindFlags will parse the given pflag.FlagSet for endpoint server option
+// flags and set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.Port, flagPort, 0,
+		"The port the HTTPS server of the authenticated HelmRelease endpoints listens on. Required when any of the endpoints is enabled.")
+	fs.StringVar(&o.CertDir, flagCertDir, "",
+		"The directory containing the TLS certificate (tls.crt) and key (tls.key) of the HTTPS server of the authenticated HelmRelease endpoints.")
+}
+
+// Enabled returns if the server of the authenticated endpoints is enabled.
+func (o Options) Enabled() bool {
+	return o.Port != 0
+}
+
+// Server returns a server serving the given handlers by path over TLS,
+// configured according to the Options. The certificate and key are reloaded
+// when they change.
+func (o Options) Server(handlers map[string]http.Handler) ctrlwebhook.Server {
+	server := ctrlwebhook.NewServer(ctrlwebhook.Options{
+		Port:    o.Port,
+		CertDir: o.CertDir,
+	})
+	for path, h := range handlers {
+		server.Register(path, h)
+	}
+	return server
+}
+
+// Authenticate returns the user of the bearer token of the given request, as
+// verified with a TokenReview created with the given client.
+func Authenticate(c client.Client, r *http.Request) (authenticationv1.UserInfo, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return authenticationv1.UserInfo{}, errors.New("missing bearer token")
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := c.Create(r.Context(), review); err != nil {
+		return authenticationv1.UserInfo{}, fmt.Errorf("failed to review token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		msg := "invalid bearer token"
+		if review.Status.Error != "" {
+			msg += ": " + review.Status.Error
+		}
+		return authenticationv1.UserInfo{}, errors.New(msg)
+	}
+	return review.Status.User, nil
+}
+
+// Authorize returns an error if the given user is not allowed to get the
+// given subresource of the HelmRelease with the given namespace and name, as
+// verified with a SubjectAccessReview created with the given client.
+func Authorize(ctx context.Context, c client.Client, user authenticationv1.UserInfo,
+	namespace, name, subresource string) error {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        "get",
+				Group:       v2.GroupVersion.Group,
+				Resource:    "helmreleases",
+				Subresource: subresource,
+				Name:        name,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			Extra:  extra,
+			UID:    user.UID,
+		},
+	}
+	if err := c.Create(ctx, review); err != nil {
+		return fmt.Errorf("failed to review access: %w", err)
+	}
+	if !review.Status.Allowed {
+		return fmt.Errorf("user '%s' is not allowed to get %s/%s of HelmRelease '%s/%s'",
+			user.Username, "helmreleases", subresource, namespace, name)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestAuthenticateAndAuthorize(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	// The token "valid" authenticates the user "alice", who is allowed to
+	// get the "manifest" subresource of HelmReleases in the "apps"
+	// namespace.
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "valid" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "alice"}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				attrs := review.Spec.ResourceAttributes
+				review.Status.Allowed = review.Spec.User == "alice" && attrs.Namespace == "apps" &&
+					attrs.Resource == "helmreleases" && attrs.Subresource == "manifest" && attrs.Verb == "get"
+			default:
+				return c.Create(ctx, obj, opts...)
+			}
+			return nil
+		},
+	}).Build()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := Authenticate(c, req)
+	g.Expect(err).To(MatchError("missing bearer token"))
+
+	req.Header.Set("Authorization", "Bearer invalid")
+	_, err = Authenticate(c, req)
+	g.Expect(err).To(MatchError("invalid bearer token"))
+
+	req.Header.Set("Authorization", "Bearer valid")
+	user, err := Authenticate(c, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(user.Username).To(Equal("alice"))
+
+	g.Expect(Authorize(context.TODO(), c, user, "apps", "podinfo", "manifest")).To(Succeed())
+	g.Expect(Authorize(context.TODO(), c, user, "apps", "podinfo", "preview")).To(
+		MatchError("user 'alice' is not allowed to get helmreleases/preview of HelmRelease 'apps/podinfo'"))
+	g.Expect(Authorize(context.TODO(), c, user, "other", "podinfo", "manifest")).ToNot(Succeed())
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package interrupt tracks the in-flight Helm actions of HelmReleases, to
+// allow an action for an obsolete generation of a HelmRelease to be
+// interrupted once a newer generation is observed.
+package interrupt
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSuperseded is the cause of the cancellation of the wait context of an
+// interrupted action.
+var ErrSuperseded = errors.New("superseded by a newer generation")
+
+// Tracker tracks the in-flight actions by object key. At most one action is
+// tracked per key, which matches the guarantee of the controller that an
+// object is not reconciled concurrently.
+//
+// The state is kept in memory, and is not shared between controller
+// instances.
+type Tracker struct {
+	mu      sync.Mutex
+	actions map[string]*action
+}
+
+// action is an in-flight action for a generation of an object.
+type action struct {
+	generation int64
+	cancel     context.CancelCauseFunc
+}
+
+// NewTracker returns a new Tracker without any tracked actions.
+func NewTracker() *Tracker {
+	return &Tracker{
+		actions: make(map[string]*action),
+	}
+}
+
+// Track returns a copy of ctx for an action for the given generation of the
+// object with the given key. The returned context is canceled with
+// ErrSuperseded when Interrupt is called for the key with a newer
+// generation.
+//
+// Calling the returned context.CancelFunc stops tracking the action and
+// releases the resources associated with the context, and should be done as
+// soon as the action completes.
+func (t *Tracker) Track(ctx context.Context, key string, generation int64) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	a := &action{generation: generation, cancel: cancel}
+
+	t.mu.Lock()
+	t.actions[key] = a
+	t.mu.Unlock()
+
+	return ctx, func() {
+		t.mu.Lock()
+		if t.actions[key] == a {
+			delete(t.actions, key)
+		}
+		t.mu.Unlock()
+		cancel(context.Canceled)
+	}
+}
+
+// Interrupt cancels the in-flight action of the object with the given key if
+// it was started for a generation older than the given generation. It
+// returns true if an action was interrupted.
+func (t *Tracker) Interrupt(key string, generation int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a, ok := t.actions[key]
+	if !ok || a.generation >= generation {
+		return false
+	}
+	delete(t.actions, key)
+	a.cancel(ErrSuperseded)
+	return true
+}
+
+// Interrupted returns true if the given context returned by Track was
+// canceled by Interrupt.
+func Interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrSuperseded)
+}
+
+// waitContextKey is the key of the wait context in a context.Context.
+type waitContextKey struct{}
+
+// WithWaitContext returns a copy of ctx which carries the given context
+// returned by Track, to interrupt the wait for the resources of the action
+// once they have been applied. The context of the action itself must never
+// be canceled by an interruption, as Helm continues to apply a release in
+// the background when it is.
+func WithWaitContext(ctx, waitCtx context.Context) context.Context {
+	return context.WithValue(ctx, waitContextKey{}, waitCtx)
+}
+
+// WaitContext returns the context carried by ctx using WithWaitContext, and
+// false if it does not carry one.
+func WaitContext(ctx context.Context) (context.Context, bool) {
+	waitCtx, ok := ctx.Value(waitContextKey{}).(context.Context)
+	return waitCtx, ok
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interrupt
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTracker(t *testing.T) {
+	t.Run("interrupts action for older generation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := NewTracker()
+		ctx, done := tracker.Track(context.TODO(), "flux-system/podinfo", 2)
+		defer done()
+
+		// The same or an older generation does not interrupt the action.
+		g.Expect(tracker.Interrupt("flux-system/podinfo", 2)).To(BeFalse())
+		g.Expect(tracker.Interrupt("flux-system/podinfo", 1)).To(BeFalse())
+		g.Expect(ctx.Err()).ToNot(HaveOccurred())
+
+		// Other objects are not affected.
+		g.Expect(tracker.Interrupt("flux-system/other", 3)).To(BeFalse())
+		g.Expect(ctx.Err()).ToNot(HaveOccurred())
+
+		g.Expect(tracker.Interrupt("flux-system/podinfo", 3)).To(BeTrue())
+		g.Expect(ctx.Err()).To(MatchError(context.Canceled))
+		g.Expect(Interrupted(ctx)).To(BeTrue())
+
+		// The action is no longer tracked once interrupted.
+		g.Expect(tracker.Interrupt("flux-system/podinfo", 4)).To(BeFalse())
+	})
+
+	t.Run("stops tracking completed action", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := NewTracker()
+		ctx, done := tracker.Track(context.TODO(), "flux-system/podinfo", 1)
+		done()
+
+		g.Expect(ctx.Err()).To(MatchError(context.Canceled))
+		g.Expect(Interrupted(ctx)).To(BeFalse())
+		g.Expect(tracker.Interrupt("flux-system/podinfo", 2)).To(BeFalse())
+		g.Expect(tracker.actions).To(BeEmpty())
+	})
+}
+
+func TestWaitContext(t *testing.T) {
+	g := NewWithT(t)
+
+	_, ok := WaitContext(context.TODO())
+	g.Expect(ok).To(BeFalse())
+
+	tracker := NewTracker()
+	waitCtx, done := tracker.Track(context.TODO(), "flux-system/podinfo", 1)
+	defer done()
+
+	ctx := WithWaitContext(context.TODO(), waitCtx)
+	got, ok := WaitContext(ctx)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(got).To(Equal(waitCtx))
+
+	// Interrupting the action only cancels the wait context.
+	g.Expect(tracker.Interrupt("flux-system/podinfo", 2)).To(BeTrue())
+	g.Expect(Interrupted(got)).To(BeTrue())
+	g.Expect(ctx.Err()).ToNot(HaveOccurred())
+}
@@ -34,14 +34,19 @@ const userNameFormat = "system:serviceaccount:%s:%s"
 // given, or the DefaultServiceAccountName as a fallback if set. It returns
 // the configured impersonation username, or an empty string.
 func SetImpersonationConfig(cfg *rest.Config, namespace, serviceAccount string) string {
-	name := DefaultServiceAccountName
-	if serviceAccount != "" {
-		name = serviceAccount
-	}
-	if name != "" && namespace != "" {
+	if name := serviceAccountName(serviceAccount); name != "" && namespace != "" {
 		username := fmt.Sprintf(userNameFormat, namespace, name)
 		cfg.Impersonate = rest.ImpersonationConfig{UserName: username}
 		return username
 	}
 	return ""
 }
+
+// serviceAccountName returns the provided service account name if given, or
+// the DefaultServiceAccountName.
+func serviceAccountName(serviceAccount string) string {
+	if serviceAccount != "" {
+		return serviceAccount
+	}
+	return DefaultServiceAccountName
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PodSpec returns the Pod spec of the given object, or nil if the object is
+// not a Pod or a built-in workload with a Pod template.
+func PodSpec(obj *unstructured.Unstructured) (*corev1.PodSpec, error) {
+	fields := podSpecFields(obj)
+	if fields == nil {
+		return nil, nil
+	}
+
+	m, ok, err := unstructured.NestedMap(obj.Object, fields...)
+	if err != nil || !ok {
+		return nil, err
+	}
+	spec := &corev1.PodSpec{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(m, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// podSpecFields returns the path of the Pod spec of the given object, or nil
+// if the object is not a Pod or a built-in workload with a Pod template.
+func podSpecFields(obj *unstructured.Unstructured) []string {
+	switch gvk := obj.GroupVersionKind(); {
+	case gvk.Group == "" && gvk.Kind == "Pod":
+		return []string{"spec"}
+	case gvk.Group == "" && gvk.Kind == "ReplicationController",
+		gvk.Group == "apps" && slices.Contains([]string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet"}, gvk.Kind),
+		gvk.Group == "batch" && gvk.Kind == "Job":
+		return []string{"spec", "template", "spec"}
+	case gvk.Group == "batch" && gvk.Kind == "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+}
+
+// MapImages replaces the image of every container of the Pod spec of the
+// given object with the result of the given function. Objects which are
+// not a Pod or a built-in workload with a Pod template are not modified.
+func MapImages(obj *unstructured.Unstructured, mapping func(image string) string) error {
+	fields := podSpecFields(obj)
+	if fields == nil {
+		return nil
+	}
+
+	for _, key := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		path := append(slices.Clone(fields), key)
+		containers, ok, err := unstructured.NestedSlice(obj.Object, path...)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := container["image"].(string); ok {
+				container["image"] = mapping(image)
+			}
+		}
+		if err = unstructured.SetNestedSlice(obj.Object, containers, path...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
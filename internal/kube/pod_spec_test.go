@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMapImages(t *testing.T) {
+	g := NewWithT(t)
+
+	cronJob := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "CronJob",
+		"spec": map[string]interface{}{
+			"jobTemplate": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"initContainers": []interface{}{
+								map[string]interface{}{"name": "init", "image": "busybox:1.36"},
+							},
+							"containers": []interface{}{
+								map[string]interface{}{"name": "app", "image": "nginx:1.25"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+	configMap := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"data":       map[string]interface{}{"image": "nginx:1.25"},
+	}}
+
+	pin := func(image string) string { return image + "@sha256:0" }
+	g.Expect(MapImages(cronJob, pin)).To(Succeed())
+	g.Expect(MapImages(configMap, pin)).To(Succeed())
+
+	spec, err := PodSpec(cronJob)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(spec.InitContainers[0].Image).To(Equal("busybox:1.36@sha256:0"))
+	g.Expect(spec.Containers[0].Image).To(Equal("nginx:1.25@sha256:0"))
+	g.Expect(configMap.Object["data"]).To(HaveKeyWithValue("image", "nginx:1.25"))
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultTokenExpiration is the default requested lifetime of the tokens
+// issued by a TokenCache.
+const DefaultTokenExpiration = time.Hour
+
+// tokenRequestTimeout is the timeout of a TokenRequest.
+const tokenRequestTimeout = 30 * time.Second
+
+// TokenCache issues short-lived, audience-bound tokens for service accounts
+// with the TokenRequest API. The tokens are cached, and renewed when 80% of
+// their lifetime has passed. Expired tokens are evicted from the cache when
+// a new token is issued.
+type TokenCache struct {
+	client     corev1client.ServiceAccountsGetter
+	audiences  []string
+	expiration time.Duration
+
+	// tokens holds the cached tokens, and is guarded by mu, which is never
+	// held while requesting a token.
+	tokens map[types.NamespacedName]*cachedToken
+	mu     sync.Mutex
+	// requests deduplicates the concurrent requests for a token of the
+	// same service account.
+	requests singleflight.Group
+
+	// now returns the current time, and can be overridden in tests.
+	now func() time.Time
+}
+
+// cachedToken is a token issued by a TokenCache.
+type cachedToken struct {
+	token     string
+	renewAt   time.Time
+	expiresAt time.Time
+}
+
+// NewTokenCache returns a new TokenCache issuing tokens with the given
+// client, for the given audiences and with the given expiration. When no
+// audiences are given, the tokens are bound to the audiences of the API
+// server. When the expiration is zero, DefaultTokenExpiration is used.
+func NewTokenCache(client corev1client.ServiceAccountsGetter, audiences []string, expiration time.Duration) *TokenCache {
+	if expiration == 0 {
+		expiration = DefaultTokenExpiration
+	}
+	return &TokenCache{
+		client:     client,
+		audiences:  audiences,
+		expiration: expiration,
+		tokens:     make(map[types.NamespacedName]*cachedToken),
+		now:        time.Now,
+	}
+}
+
+// Token returns a token for the service account with the given namespace and
+// name, from the cache or by requesting a new one. Concurrent calls for the
+// same service account share a single request, which is not canceled when
+// the context of a caller is.
+func (c *TokenCache) Token(ctx context.Context, namespace, name string) (string, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+
+	c.mu.Lock()
+	t, ok := c.tokens[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(t.renewAt) {
+		return t.token, nil
+	}
+
+	ch := c.requests.DoChan(key.String(), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenRequestTimeout)
+		defer cancel()
+		return c.request(ctx, key)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Evict removes the token of the service account with the given name in the
+// given namespace from the cache, e.g. when the last object authenticating
+// as it is deleted. Like WithServiceAccountToken, it falls back to
+// DefaultServiceAccountName when no service account name is given.
+func (c *TokenCache) Evict(serviceAccount, namespace string) {
+	name := serviceAccountName(serviceAccount)
+	if name == "" || namespace == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, types.NamespacedName{Namespace: namespace, Name: name})
+}
+
+// request requests a new token for the service account with the given key,
+// and caches it.
+func (c *TokenCache) request(ctx context.Context, key types.NamespacedName) (string, error) {
+	now := c.now()
+	seconds := int64(c.expiration.Seconds())
+	tr, err := c.client.ServiceAccounts(key.Namespace).CreateToken(ctx, key.Name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         c.audiences,
+			ExpirationSeconds: &seconds,
+		},
+	}, metav1.CreateOptions{})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		delete(c.tokens, key)
+		return "", fmt.Errorf("failed to request token for service account '%s': %w", key.String(), err)
+	}
+
+	// The API server may issue a token with a different lifetime than
+	// requested.
+	lifetime := tr.Status.ExpirationTimestamp.Sub(now)
+	c.tokens[key] = &cachedToken{
+		token:     tr.Status.Token,
+		renewAt:   now.Add(lifetime * 8 / 10),
+		expiresAt: tr.Status.ExpirationTimestamp.Time,
+	}
+	for k, t := range c.tokens {
+		if !now.Before(t.expiresAt) {
+			delete(c.tokens, k)
+		}
+	}
+	return tr.Status.Token, nil
+}
+
+// WithServiceAccountToken configures the REST client to authenticate as the
+// service account in the given namespace with a token issued by the given
+// TokenCache, instead of impersonating it with the credentials of the REST
+// config. Like WithImpersonate, it falls back to DefaultServiceAccountName
+// when no service account name is given, and is a no-op when neither is set.
+//
+// The token is retrieved from the cache on every request, so that a
+// persistent client keeps working when the token is renewed.
+func WithServiceAccountToken(tokens *TokenCache, serviceAccount, namespace string) Option {
+	return func(c *MemoryRESTClientGetter) {
+		name := serviceAccountName(serviceAccount)
+		if name == "" || namespace == "" {
+			return
+		}
+
+		// Strip the credentials of the controller, as the API server would
+		// otherwise authenticate the request with e.g. its client
+		// certificate instead of the token.
+		cfg := rest.AnonymousClientConfig(c.cfg)
+		cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &tokenRoundTripper{
+				tokens:    tokens,
+				namespace: namespace,
+				name:      name,
+				rt:        rt,
+			}
+		})
+		c.cfg = cfg
+	}
+}
+
+// tokenRoundTripper sets a service account token from a TokenCache as the
+// bearer token of requests.
+type tokenRoundTripper struct {
+	tokens    *TokenCache
+	namespace string
+	name      string
+	rt        http.RoundTripper
+}
+
+func (t *tokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.Token(req.Context(), t.namespace, t.name)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.rt.RoundTrip(req)
+}
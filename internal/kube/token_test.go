@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeTokenClientSet returns a fake client set issuing tokens named after
+// the service account and the number of issued tokens, expiring after the
+// requested expiration.
+func fakeTokenClientSet(now func() time.Time) (*fake.Clientset, *[]*authenticationv1.TokenRequest) {
+	var requests []*authenticationv1.TokenRequest
+	clientSet := fake.NewSimpleClientset()
+	clientSet.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		create := action.(k8stesting.CreateActionImpl)
+		tr := create.GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		requests = append(requests, tr)
+		tr.Status = authenticationv1.TokenRequestStatus{
+			Token:               create.GetNamespace() + "/" + create.Name + "-" + string(rune('0'+len(requests))),
+			ExpirationTimestamp: metav1.NewTime(now().Add(time.Duration(*tr.Spec.ExpirationSeconds) * time.Second)),
+		}
+		return true, tr, nil
+	})
+	return clientSet, &requests
+}
+
+func TestTokenCache_Token(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	clock := func() time.Time { return now }
+	clientSet, requests := fakeTokenClientSet(clock)
+
+	c := NewTokenCache(clientSet.CoreV1(), []string{"https://kubernetes.default.svc"}, 10*time.Minute)
+	c.now = clock
+
+	token, err := c.Token(context.TODO(), "apps", "deployer")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal("apps/deployer-1"))
+	g.Expect(*requests).To(HaveLen(1))
+	g.Expect((*requests)[0].Spec.Audiences).To(Equal([]string{"https://kubernetes.default.svc"}))
+	g.Expect(*(*requests)[0].Spec.ExpirationSeconds).To(Equal(int64(600)))
+
+	// Returns the cached token.
+	now = now.Add(7 * time.Minute)
+	token, err = c.Token(context.TODO(), "apps", "deployer")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal("apps/deployer-1"))
+	g.Expect(*requests).To(HaveLen(1))
+
+	// Issues a token per service account.
+	token, err = c.Token(context.TODO(), "apps", "other")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal("apps/other-2"))
+
+	// Renews the token after 80% of its lifetime.
+	now = now.Add(time.Minute)
+	token, err = c.Token(context.TODO(), "apps", "deployer")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal("apps/deployer-3"))
+}
+
+func TestTokenCache_Token_concurrent(t *testing.T) {
+	g := NewWithT(t)
+
+	clientSet, requests := fakeTokenClientSet(time.Now)
+	// Block the requests for the token of the deployer service account.
+	block := make(chan struct{})
+	clientSet.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "token" && action.(k8stesting.CreateActionImpl).Name == "deployer" {
+			<-block
+		}
+		return false, nil, nil
+	})
+	c := NewTokenCache(clientSet.CoreV1(), nil, 0)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 3)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = c.Token(context.TODO(), "apps", "deployer")
+		}(i)
+	}
+
+	// Tokens of other service accounts are issued while the request for
+	// the deployer service account is in progress.
+	g.Eventually(func() (string, error) {
+		return c.Token(context.TODO(), "apps", "other")
+	}, time.Second).Should(HavePrefix("apps/other-"))
+
+	// A caller stops waiting when its context is done.
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := c.Token(ctx, "apps", "deployer")
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	close(block)
+	wg.Wait()
+	g.Expect(tokens[0]).To(HavePrefix("apps/deployer-"))
+	g.Expect(tokens).To(HaveEach(tokens[0]))
+	g.Expect(*requests).To(HaveLen(2))
+}
+
+func TestTokenCache_eviction(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	clock := func() time.Time { return now }
+	clientSet, requests := fakeTokenClientSet(clock)
+
+	c := NewTokenCache(clientSet.CoreV1(), nil, 10*time.Minute)
+	c.now = clock
+
+	_, err := c.Token(context.TODO(), "apps", "deployer")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = c.Token(context.TODO(), "apps", "other")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.tokens).To(HaveLen(2))
+
+	// Evicts the token of a service account.
+	c.Evict("other", "apps")
+	g.Expect(c.tokens).To(HaveLen(1))
+	token, err := c.Token(context.TODO(), "apps", "other")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal("apps/other-3"))
+	g.Expect(*requests).To(HaveLen(3))
+
+	// Evicts expired tokens when a new token is issued.
+	now = now.Add(11 * time.Minute)
+	_, err = c.Token(context.TODO(), "apps", "new")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.tokens).To(HaveLen(1))
+	g.Expect(c.tokens).To(HaveKey(types.NamespacedName{Namespace: "apps", Name: "new"}))
+}
+
+func TestWithServiceAccountToken(t *testing.T) {
+	t.Run("authenticates with token", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var header http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+
+		clientSet, _ := fakeTokenClientSet(time.Now)
+		tokens := NewTokenCache(clientSet.CoreV1(), nil, 0)
+
+		cfg := &rest.Config{
+			Host:        server.URL,
+			BearerToken: "controller-token",
+			QPS:         20,
+		}
+		getter := NewMemoryRESTClientGetter(cfg, WithServiceAccountToken(tokens, "deployer", "apps"))
+
+		restCfg, err := getter.ToRESTConfig()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(restCfg.BearerToken).To(BeEmpty())
+		g.Expect(restCfg.Impersonate.UserName).To(BeEmpty())
+		g.Expect(restCfg.QPS).To(Equal(float32(20)))
+
+		httpClient, err := rest.HTTPClientFor(restCfg)
+		g.Expect(err).ToNot(HaveOccurred())
+		resp, err := httpClient.Get(server.URL)
+		g.Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		g.Expect(header.Get("Authorization")).To(Equal("Bearer apps/deployer-1"))
+		g.Expect(header.Get("Impersonate-User")).To(BeEmpty())
+	})
+
+	t.Run("falls back to default service account", func(t *testing.T) {
+		g := NewWithT(t)
+
+		curDefault := DefaultServiceAccountName
+		t.Cleanup(func() { DefaultServiceAccountName = curDefault })
+
+		cfg := &rest.Config{BearerToken: "controller-token"}
+		DefaultServiceAccountName = ""
+		getter := NewMemoryRESTClientGetter(cfg, WithServiceAccountToken(nil, "", "apps"))
+		restCfg, _ := getter.ToRESTConfig()
+		g.Expect(restCfg.BearerToken).To(Equal("controller-token"))
+
+		DefaultServiceAccountName = "default"
+		getter = NewMemoryRESTClientGetter(cfg, WithServiceAccountToken(nil, "", "apps"))
+		restCfg, _ = getter.ToRESTConfig()
+		g.Expect(restCfg.BearerToken).To(BeEmpty())
+		g.Expect(restCfg.WrapTransport).ToNot(BeNil())
+	})
+}
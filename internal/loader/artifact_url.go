@@ -29,9 +29,10 @@ import (
 
 	"github.com/hashicorp/go-retryablehttp"
 	digestlib "github.com/opencontainers/go-digest"
-	_ "github.com/opencontainers/go-digest/blake3"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+
+	intdigest "github.com/fluxcd/helm-controller/internal/digest"
 )
 
 const (
@@ -39,6 +40,11 @@ const (
 	// used to override the hostname of the source-controller from which
 	// the chart is usually downloaded.
 	envSourceControllerLocalhost = "SOURCE_CONTROLLER_LOCALHOST"
+
+	// maxPreallocSize is the maximum size of the buffer allocated upfront for
+	// a chart artifact based on the Content-Length of the response. Larger
+	// artifacts grow the buffer while being read.
+	maxPreallocSize = 64 << 20
 )
 
 var (
@@ -53,8 +59,12 @@ var (
 // using the provided client. The retrieved data is verified against the given
 // digest before loading the chart. It returns the loaded chart.Chart, or an
 // error. The error may be of type ErrIntegrity if the integrity check fails.
+//
+// The artifact is streamed into memory while its digest is computed, and
+// never written to disk. This allows the controller to run with a read-only
+// root filesystem, without any temporary files to clean up.
 func SecureLoadChartFromURL(client *retryablehttp.Client, URL, digest string) (*chart.Chart, error) {
-	URL, err := overwriteHostname(URL, os.Getenv(envSourceControllerLocalhost))
+	URL, err := ArtifactURL(URL)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +87,9 @@ func SecureLoadChartFromURL(client *retryablehttp.Client, URL, digest string) (*
 	}
 
 	var c bytes.Buffer
+	if resp.ContentLength > 0 && resp.ContentLength <= maxPreallocSize {
+		c.Grow(int(resp.ContentLength))
+	}
 	if err := copyAndVerify(digest, resp.Body, &c); err != nil {
 		_ = resp.Body.Close()
 		return nil, err
@@ -88,6 +101,13 @@ func SecureLoadChartFromURL(client *retryablehttp.Client, URL, digest string) (*
 	return loader.LoadArchive(&c)
 }
 
+// ArtifactURL returns the URL the artifact with the given URL is downloaded
+// from, which has the hostname of the source-controller overridden when
+// configured.
+func ArtifactURL(URL string) (string, error) {
+	return overwriteHostname(URL, os.Getenv(envSourceControllerLocalhost))
+}
+
 // copyAndVerify copies the contents of reader to writer, and verifies the
 // integrity of the data using the given digest. It returns an error if the
 // integrity check fails.
@@ -96,6 +116,9 @@ func copyAndVerify(digest string, reader io.Reader, writer io.Writer) error {
 	if err != nil {
 		return fmt.Errorf("failed to parse digest '%s': %w", digest, err)
 	}
+	if err = intdigest.Allowed(dig.Algorithm()); err != nil {
+		return fmt.Errorf("failed to verify digest '%s': %w", digest, err)
+	}
 
 	verifier := dig.Verifier()
 	mw := io.MultiWriter(verifier, writer)
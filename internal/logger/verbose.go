@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logger provides helpers for raising the verbosity of a single
+// logr.Logger, independent of the log level configured for the controller.
+package logger
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// Verbose returns a logr.Logger derived from log which logs the messages up
+// to and including the given verbosity level as info messages. This allows
+// the debug logs of a single object to be logged, without lowering the log
+// level of the whole controller.
+func Verbose(log logr.Logger, level int) logr.Logger {
+	sink := log.GetSink()
+	if sink == nil {
+		return log
+	}
+	if v, ok := sink.(*verboseSink); ok {
+		sink = v.sink
+	}
+	return log.WithSink(&verboseSink{sink: sink, level: level})
+}
+
+// verboseSink is a logr.LogSink which promotes the messages up to and
+// including level to info messages of the wrapped logr.LogSink.
+type verboseSink struct {
+	sink  logr.LogSink
+	level int
+}
+
+// Init implements logr.LogSink.
+func (s *verboseSink) Init(info logr.RuntimeInfo) {
+	s.sink.Init(info)
+}
+
+// Enabled implements logr.LogSink.
+func (s *verboseSink) Enabled(level int) bool {
+	return s.sink.Enabled(s.promote(level))
+}
+
+// Info implements logr.LogSink.
+func (s *verboseSink) Info(level int, msg string, keysAndValues ...any) {
+	s.sink.Info(s.promote(level), msg, keysAndValues...)
+}
+
+// Error implements logr.LogSink.
+func (s *verboseSink) Error(err error, msg string, keysAndValues ...any) {
+	s.sink.Error(err, msg, keysAndValues...)
+}
+
+// WithValues implements logr.LogSink.
+func (s *verboseSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &verboseSink{sink: s.sink.WithValues(keysAndValues...), level: s.level}
+}
+
+// WithName implements logr.LogSink.
+func (s *verboseSink) WithName(name string) logr.LogSink {
+	return &verboseSink{sink: s.sink.WithName(name), level: s.level}
+}
+
+// WithCallDepth implements logr.CallDepthLogSink, to ensure the caller
+// information of the wrapped logr.LogSink is correct.
+func (s *verboseSink) WithCallDepth(depth int) logr.LogSink {
+	if cd, ok := s.sink.(logr.CallDepthLogSink); ok {
+		return &verboseSink{sink: cd.WithCallDepth(depth), level: s.level}
+	}
+	return s
+}
+
+// promote returns the level the message should be passed to the wrapped
+// logr.LogSink with.
+func (s *verboseSink) promote(level int) int {
+	if level <= s.level {
+		return 0
+	}
+	return level
+}
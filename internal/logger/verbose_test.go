@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/gomega"
+)
+
+func TestVerbose(t *testing.T) {
+	g := NewWithT(t)
+
+	var lines []string
+	log := funcr.New(func(_, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: 0})
+
+	log.V(1).Info("hidden")
+	g.Expect(lines).To(BeEmpty())
+
+	verbose := Verbose(log, 1)
+	verbose.V(1).Info("debug")
+	verbose.V(2).Info("trace")
+	verbose.WithValues("key", "value").WithName("name").V(1).Info("with values")
+	g.Expect(lines).To(HaveLen(2))
+	g.Expect(lines[0]).To(ContainSubstring(`"msg"="debug"`))
+	g.Expect(lines[1]).To(ContainSubstring(`"key"="value"`))
+
+	// Wrapping a verbose logger replaces the level.
+	lines = nil
+	Verbose(verbose, 2).V(2).Info("trace")
+	g.Expect(lines).To(HaveLen(1))
+
+	// A discard logger is returned as-is.
+	g.Expect(Verbose(logr.Discard(), 1)).To(Equal(logr.Discard()))
+}
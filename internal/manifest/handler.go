@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifest provides an authenticated HTTP endpoint which returns the
+// manifest stored for a release in the history of a HelmRelease, to retrieve
+// what was applied without access to the Helm storage.
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/httpauth"
+)
+
+const (
+	// Path is the path the Handler is served at, followed by
+	// "<namespace>/<name>/<version>" of the HelmRelease and the version of
+	// the release in its history.
+	Path = "/manifests/helmreleases/"
+
+	// Subresource is the subresource of HelmReleases a user must be allowed
+	// to get to retrieve the manifests of a HelmRelease.
+	Subresource = "manifest"
+)
+
+// Result is the manifest of a release in the history of a HelmRelease.
+type Result struct {
+	// Object is the namespace and name of the HelmRelease.
+	Object string `json:"object"`
+	// Release is the namespace, name and version of the release.
+	Release string `json:"release"`
+	// Chart is the name and version of the chart of the release.
+	Chart string `json:"chart"`
+	// Digest is the digest of the release, as recorded in the history.
+	Digest string `json:"digest"`
+	// Manifest is the manifest of the release followed by the manifests of
+	// its hooks, with the data of Secrets redacted.
+	Manifest string `json:"manifest"`
+}
+
+// Getter gets the manifest of a release of a HelmRelease.
+type Getter interface {
+	// Manifest returns the manifest of the release of the given snapshot in
+	// the history of the given HelmRelease, as stored in the Helm storage.
+	// It must not modify the Helm storage.
+	Manifest(ctx context.Context, obj *v2.HelmRelease, snapshot *v2.Snapshot) (string, error)
+}
+
+// Handler serves the Result of a Getter for a release of a HelmRelease as
+// JSON at Path<namespace>/<name>/<version>.
+//
+// Requests must carry a bearer token of a user which is allowed to get the
+// Subresource of the HelmRelease, as verified with a TokenReview and a
+// SubjectAccessReview.
+type Handler struct {
+	client client.Client
+
+	mu     sync.RWMutex
+	getter Getter
+}
+
+// NewHandler returns a new Handler which authenticates requests and reads
+// the HelmRelease with the given client. The Handler responds with
+// http.StatusServiceUnavailable until a Getter is set with SetGetter, which
+// allows it to be registered before the controller is set up.
+func NewHandler(c client.Client) *Handler {
+	return &Handler{client: c}
+}
+
+// SetGetter sets the Getter the Handler gets manifests with.
+func (h *Handler) SetGetter(getter Getter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.getter = getter
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, Path), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, fmt.Sprintf("expected path %s<namespace>/<name>/<version>", Path), http.StatusBadRequest)
+		return
+	}
+	namespace, name := parts[0], parts[1]
+	version, err := strconv.Atoi(parts[2])
+	if err != nil || version < 1 {
+		http.Error(w, fmt.Sprintf("invalid release version '%s'", parts[2]), http.StatusBadRequest)
+		return
+	}
+
+	user, err := httpauth.Authenticate(h.client, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err = httpauth.Authorize(r.Context(), h.client, user, namespace, name, Subresource); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	obj := &v2.HelmRelease{}
+	if err = h.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		code := http.StatusInternalServerError
+		if apierrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	var snapshot *v2.Snapshot
+	for _, s := range obj.Status.History {
+		if s.Version == version {
+			snapshot = s
+			break
+		}
+	}
+	if snapshot == nil {
+		http.Error(w, fmt.Sprintf("release version %d is not in the history of HelmRelease '%s/%s'",
+			version, namespace, name), http.StatusNotFound)
+		return
+	}
+
+	h.mu.RLock()
+	getter := h.getter
+	h.mu.RUnlock()
+	if getter == nil {
+		http.Error(w, "controller is not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	manifest, err := getter.Manifest(r.Context(), obj, snapshot)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get manifest of release: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(Result{
+		Object:   client.ObjectKeyFromObject(obj).String(),
+		Release:  snapshot.FullReleaseName(),
+		Chart:    snapshot.VersionedChartName(),
+		Digest:   snapshot.Digest,
+		Manifest: manifest,
+	})
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// getterFunc is a Getter which calls the function.
+type getterFunc func(ctx context.Context, obj *v2.HelmRelease, snapshot *v2.Snapshot) (string, error)
+
+func (f getterFunc) Manifest(ctx context.Context, obj *v2.HelmRelease, snapshot *v2.Snapshot) (string, error) {
+	return f(ctx, obj, snapshot)
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v2.AddToScheme(scheme)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+		Status: v2.HelmReleaseStatus{
+			History: v2.Snapshots{
+				{Name: "podinfo", Namespace: "apps", Version: 2, ChartName: "podinfo", ChartVersion: "6.5.1", Digest: "sha256:2"},
+				{Name: "podinfo", Namespace: "apps", Version: 1, ChartName: "podinfo", ChartVersion: "6.5.0", Digest: "sha256:1"},
+			},
+		},
+	}
+
+	// The token "valid" authenticates the user "alice", who is allowed to
+	// get the manifests of HelmReleases in the "apps" namespace.
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "valid" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "alice"}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				attrs := review.Spec.ResourceAttributes
+				review.Status.Allowed = review.Spec.User == "alice" && attrs.Namespace == "apps" &&
+					attrs.Resource == "helmreleases" && attrs.Subresource == Subresource && attrs.Verb == "get"
+			default:
+				return c.Create(ctx, obj, opts...)
+			}
+			return nil
+		},
+	}).Build()
+
+	getter := getterFunc(func(_ context.Context, _ *v2.HelmRelease, snapshot *v2.Snapshot) (string, error) {
+		if snapshot.Version == 2 {
+			return "", errors.New("release disappeared from storage")
+		}
+		return "---\napiVersion: v1\nkind: ConfigMap\n", nil
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		getter   Getter
+		wantCode int
+	}{
+		{
+			name:     "missing token",
+			path:     Path + "apps/podinfo/1",
+			getter:   getter,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "forbidden namespace",
+			path:     Path + "other/podinfo/1",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "not found",
+			path:     Path + "apps/missing/1",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "version not in history",
+			path:     Path + "apps/podinfo/3",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "invalid path",
+			path:     Path + "apps/podinfo",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid version",
+			path:     Path + "apps/podinfo/latest",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodPost,
+			path:     Path + "apps/podinfo/1",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "getter not set",
+			path:     Path + "apps/podinfo/1",
+			token:    "valid",
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "getter error",
+			path:     Path + "apps/podinfo/2",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "manifest",
+			path:     Path + "apps/podinfo/1",
+			token:    "valid",
+			getter:   getter,
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			h := NewHandler(c)
+			if tt.getter != nil {
+				h.SetGetter(tt.getter)
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			g.Expect(rec.Code).To(Equal(tt.wantCode), rec.Body.String())
+
+			if tt.wantCode == http.StatusOK {
+				result := &Result{}
+				g.Expect(json.Unmarshal(rec.Body.Bytes(), result)).To(Succeed())
+				g.Expect(*result).To(Equal(Result{
+					Object:   "apps/podinfo",
+					Release:  "apps/podinfo.v1",
+					Chart:    "podinfo@6.5.0",
+					Digest:   "sha256:1",
+					Manifest: "---\napiVersion: v1\nkind: ConfigMap\n",
+				}))
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// ShardLabel is the label used to assign a HelmRelease to a controller
+// shard.
+const ShardLabel = "sharding.fluxcd.io/key"
+
+// shardCollectTimeout is the timeout for listing the HelmRelease objects
+// while collecting the ShardObjects metric.
+const shardCollectTimeout = 5 * time.Second
+
+var shardObjectsDesc = prometheus.NewDesc(
+	"helm_controller_shard_objects",
+	"The number of HelmRelease objects watched by the controller, labeled by shard.",
+	[]string{"shard"}, nil,
+)
+
+// ShardCollector is a prometheus.Collector which reports the number of
+// HelmRelease objects watched by the controller per value of the ShardLabel.
+// Objects without the label are reported with an empty shard value.
+type ShardCollector struct {
+	reader client.Reader
+}
+
+// NewShardCollector returns a new ShardCollector which lists the HelmRelease
+// objects using the given reader, which should be backed by the cache of the
+// controller.
+func NewShardCollector(reader client.Reader) *ShardCollector {
+	return &ShardCollector{reader: reader}
+}
+
+// Describe implements prometheus.Collector.
+func (c *ShardCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- shardObjectsDesc
+}
+
+// Collect implements prometheus.Collector. No metrics are reported if the
+// HelmRelease objects can not be listed.
+func (c *ShardCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), shardCollectTimeout)
+	defer cancel()
+
+	var list v2.HelmReleaseList
+	if err := c.reader.List(ctx, &list); err != nil {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, obj := range list.Items {
+		counts[obj.GetLabels()[ShardLabel]]++
+	}
+	for shard, n := range counts {
+		ch <- prometheus.MustNewConstMetric(shardObjectsDesc, prometheus.GaugeValue, float64(n), shard)
+	}
+}
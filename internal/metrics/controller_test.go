@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestShardCollector(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(v2.AddToScheme(scheme)).To(Succeed())
+
+	newObj := func(name, shard string) *v2.HelmRelease {
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		}
+		if shard != "" {
+			obj.Labels = map[string]string{ShardLabel: shard}
+		}
+		return obj
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newObj("a", "shard1"),
+		newObj("b", "shard1"),
+		newObj("c", "shard2"),
+		newObj("d", ""),
+	).Build()
+
+	g.Expect(testutil.CollectAndCompare(NewShardCollector(c), strings.NewReader(`
+# HELP helm_controller_shard_objects The number of HelmRelease objects watched by the controller, labeled by shard.
+# TYPE helm_controller_shard_objects gauge
+helm_controller_shard_objects{shard=""} 1
+helm_controller_shard_objects{shard="shard1"} 2
+helm_controller_shard_objects{shard="shard2"} 1
+`))).To(Succeed())
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics provides the Prometheus metrics specific to the Helm
+// operations of the controller, registered in the controller-runtime metrics
+// registry.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crtlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+const (
+	// ResultSuccess is the result label value of a successful Helm action.
+	ResultSuccess = "success"
+	// ResultFailure is the result label value of a failed Helm action.
+	ResultFailure = "failure"
+
+	// InClusterTarget is the target cluster label value of a release made
+	// to the cluster the controller runs in.
+	InClusterTarget = "in-cluster"
+)
+
+var (
+	// ActionDuration is the histogram of the duration of Helm actions,
+	// labeled by the action and its result.
+	ActionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "helm_action_duration_seconds",
+			Help: "The duration in seconds of a Helm action performed by the controller.",
+			// Use a histogram with 12 count buckets between 100ms - 1hour.
+			Buckets: prometheus.ExponentialBucketsRange(0.1, 3600, 12),
+		},
+		[]string{"action", "result"},
+	)
+
+	// ReleaseInfo is the gauge with information about the latest Helm
+	// release of a HelmRelease object, which is always set to 1.
+	ReleaseInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helm_release_info",
+			Help: "Information about the latest Helm release of a HelmRelease.",
+		},
+		[]string{"name", "namespace", "release_name", "release_namespace",
+			"chart_name", "chart_version", "app_version", "revision", "target_cluster"},
+	)
+
+	// SuspendInfo is the gauge with the reason of the suspension of a
+	// suspended HelmRelease object, which is always set to 1.
+	SuspendInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helm_release_suspend_info",
+			Help: "The reason of the suspension of a suspended HelmRelease.",
+		},
+		[]string{"name", "namespace", "reason"},
+	)
+
+	// ChartVulnerabilities is the gauge with the number of vulnerabilities
+	// per severity reported by the vulnerability attestations of the chart
+	// of a HelmRelease object.
+	ChartVulnerabilities = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helm_release_chart_vulnerabilities",
+			Help: "The number of vulnerabilities per severity reported for the chart of a HelmRelease.",
+		},
+		[]string{"name", "namespace", "severity"},
+	)
+
+	// ChartSecurityScan is the gauge with the result of the check of the
+	// vulnerability attestations of the chart of a HelmRelease object, which
+	// is 1 when it passed and 0 when it failed.
+	ChartSecurityScan = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helm_release_chart_security_scan_passed",
+			Help: "Whether the chart of a HelmRelease passed the check of its vulnerability attestations.",
+		},
+		[]string{"name", "namespace"},
+	)
+
+	// StorageHealthy is the gauge with the result of the last check of the
+	// Helm storage backend of the controller, which is 1 when the controller
+	// could write, read and delete a release record and 0 when it could not.
+	StorageHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "helm_storage_healthy",
+			Help: "Whether the controller could write, read and delete a release in the Helm storage backend.",
+		},
+		[]string{"driver"},
+	)
+)
+
+func init() {
+	crtlmetrics.Registry.MustRegister(ActionDuration, ReleaseInfo, SuspendInfo,
+		ChartVulnerabilities, ChartSecurityScan, StorageHealthy)
+}
+
+// ObserveActionDuration records the duration since the given start time of
+// the Helm action with the given name, with the result determined by err.
+func ObserveActionDuration(action string, start time.Time, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	ActionDuration.WithLabelValues(action, result).Observe(time.Since(start).Seconds())
+}
+
+// RecordReleaseInfo records the information about the latest Helm release
+// of the given object, replacing any previously recorded information. When
+// the object has no release, or is being deleted, the information is removed.
+func RecordReleaseInfo(obj *v2.HelmRelease) {
+	DeleteReleaseInfo(obj.GetName(), obj.GetNamespace())
+
+	cur := obj.Status.History.Latest()
+	if cur == nil || !obj.GetDeletionTimestamp().IsZero() {
+		return
+	}
+	ReleaseInfo.WithLabelValues(obj.GetName(), obj.GetNamespace(), cur.Name, cur.Namespace,
+		cur.ChartName, cur.ChartVersion, cur.AppVersion, strconv.Itoa(cur.Version), targetCluster(obj)).Set(1)
+}
+
+// DeleteReleaseInfo removes the information recorded for the HelmRelease
+// object with the given name and namespace.
+func DeleteReleaseInfo(name, namespace string) {
+	ReleaseInfo.DeletePartialMatch(prometheus.Labels{"name": name, "namespace": namespace})
+}
+
+// RecordSuspendInfo records the reason of the suspension of the given object,
+// replacing any previously recorded reason. When the object is not suspended,
+// or is being deleted, the reason is removed.
+func RecordSuspendInfo(obj *v2.HelmRelease) {
+	DeleteSuspendInfo(obj.GetName(), obj.GetNamespace())
+
+	if !obj.Spec.Suspend || !obj.GetDeletionTimestamp().IsZero() {
+		return
+	}
+	SuspendInfo.WithLabelValues(obj.GetName(), obj.GetNamespace(), obj.Spec.SuspendReason).Set(1)
+}
+
+// DeleteSuspendInfo removes the suspension reason recorded for the
+// HelmRelease object with the given name and namespace.
+func DeleteSuspendInfo(name, namespace string) {
+	SuspendInfo.DeletePartialMatch(prometheus.Labels{"name": name, "namespace": namespace})
+}
+
+// RecordChartSecurityScan records the result of the check of the
+// vulnerability attestations of the chart of the HelmRelease object with the
+// given name and namespace, and the number of vulnerabilities per severity.
+func RecordChartSecurityScan(name, namespace string, passed bool, vulnerabilities map[string]int) {
+	DeleteChartSecurityScan(name, namespace)
+
+	result := 0.0
+	if passed {
+		result = 1
+	}
+	ChartSecurityScan.WithLabelValues(name, namespace).Set(result)
+	for severity, n := range vulnerabilities {
+		ChartVulnerabilities.WithLabelValues(name, namespace, severity).Set(float64(n))
+	}
+}
+
+// DeleteChartSecurityScan removes the result of the check of the
+// vulnerability attestations recorded for the HelmRelease object with the
+// given name and namespace.
+func DeleteChartSecurityScan(name, namespace string) {
+	ChartSecurityScan.DeletePartialMatch(prometheus.Labels{"name": name, "namespace": namespace})
+	ChartVulnerabilities.DeletePartialMatch(prometheus.Labels{"name": name, "namespace": namespace})
+}
+
+// RecordStorageHealth records the result of a check of the Helm storage
+// backend with the given driver name, which failed when err is not nil.
+func RecordStorageHealth(driver string, err error) {
+	healthy := 1.0
+	if err != nil {
+		healthy = 0
+	}
+	StorageHealthy.WithLabelValues(driver).Set(healthy)
+}
+
+// targetCluster returns the name of the KubeConfig Secret used to target a
+// remote cluster, or InClusterTarget.
+func targetCluster(obj *v2.HelmRelease) string {
+	if obj.Spec.KubeConfig != nil {
+		return obj.Spec.KubeConfig.SecretRef.Name
+	}
+	return InClusterTarget
+}
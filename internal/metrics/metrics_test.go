@@ -0,0 +1,199 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestObserveActionDuration(t *testing.T) {
+	g := NewWithT(t)
+
+	ActionDuration.Reset()
+	t.Cleanup(ActionDuration.Reset)
+
+	start := time.Now().Add(-2 * time.Second)
+	ObserveActionDuration("install", start, nil)
+	ObserveActionDuration("install", start, errors.New("failed"))
+	ObserveActionDuration("install", start, errors.New("failed"))
+	ObserveActionDuration("upgrade", start, nil)
+
+	g.Expect(testutil.CollectAndCount(ActionDuration)).To(Equal(3))
+
+	for _, tt := range []struct {
+		action string
+		result string
+		count  uint64
+	}{
+		{action: "install", result: ResultSuccess, count: 1},
+		{action: "install", result: ResultFailure, count: 2},
+		{action: "upgrade", result: ResultSuccess, count: 1},
+	} {
+		m := &dto.Metric{}
+		g.Expect(ActionDuration.WithLabelValues(tt.action, tt.result).(prometheus.Histogram).Write(m)).To(Succeed())
+		g.Expect(m.GetHistogram().GetSampleCount()).To(Equal(tt.count))
+		g.Expect(m.GetHistogram().GetSampleSum()).To(BeNumerically(">=", 2*float64(tt.count)))
+	}
+}
+
+func TestRecordReleaseInfo(t *testing.T) {
+	g := NewWithT(t)
+
+	ReleaseInfo.Reset()
+	t.Cleanup(ReleaseInfo.Reset)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "release",
+			Namespace: "default",
+		},
+		Status: v2.HelmReleaseStatus{
+			History: v2.Snapshots{
+				{
+					Name:         "release",
+					Namespace:    "target",
+					Version:      1,
+					ChartName:    "podinfo",
+					ChartVersion: "6.0.0",
+					AppVersion:   "6.0.0",
+				},
+			},
+		},
+	}
+
+	RecordReleaseInfo(obj)
+	g.Expect(testutil.CollectAndCount(ReleaseInfo)).To(Equal(1))
+	g.Expect(testutil.ToFloat64(ReleaseInfo.WithLabelValues("release", "default", "release", "target",
+		"podinfo", "6.0.0", "6.0.0", "1", InClusterTarget))).To(Equal(float64(1)))
+
+	// A new release replaces the previous information.
+	obj.Spec.KubeConfig = &meta.KubeConfigReference{SecretRef: meta.SecretKeyReference{Name: "remote"}}
+	obj.Status.History = append(v2.Snapshots{{
+		Name:         "release",
+		Namespace:    "target",
+		Version:      2,
+		ChartName:    "podinfo",
+		ChartVersion: "6.1.0",
+		AppVersion:   "6.1.0",
+	}}, obj.Status.History...)
+
+	RecordReleaseInfo(obj)
+	g.Expect(testutil.CollectAndCount(ReleaseInfo)).To(Equal(1))
+	g.Expect(testutil.ToFloat64(ReleaseInfo.WithLabelValues("release", "default", "release", "target",
+		"podinfo", "6.1.0", "6.1.0", "2", "remote"))).To(Equal(float64(1)))
+
+	// Information of other objects is retained.
+	other := obj.DeepCopy()
+	other.Name = "other"
+	RecordReleaseInfo(other)
+	g.Expect(testutil.CollectAndCount(ReleaseInfo)).To(Equal(2))
+
+	// Deletion of the object removes the information.
+	now := metav1.Now()
+	obj.DeletionTimestamp = &now
+	RecordReleaseInfo(obj)
+	g.Expect(testutil.CollectAndCount(ReleaseInfo)).To(Equal(1))
+
+	DeleteReleaseInfo(other.Name, other.Namespace)
+	g.Expect(testutil.CollectAndCount(ReleaseInfo)).To(Equal(0))
+}
+
+func TestRecordSuspendInfo(t *testing.T) {
+	g := NewWithT(t)
+
+	SuspendInfo.Reset()
+	t.Cleanup(SuspendInfo.Reset)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "release",
+			Namespace: "default",
+		},
+	}
+
+	// An object which is not suspended has no information.
+	RecordSuspendInfo(obj)
+	g.Expect(testutil.CollectAndCount(SuspendInfo)).To(Equal(0))
+
+	obj.Spec.Suspend = true
+	RecordSuspendInfo(obj)
+	g.Expect(testutil.ToFloat64(SuspendInfo.WithLabelValues("release", "default", ""))).To(Equal(float64(1)))
+
+	// A new reason replaces the previous information.
+	obj.Spec.SuspendReason = "maintenance"
+	RecordSuspendInfo(obj)
+	g.Expect(testutil.CollectAndCount(SuspendInfo)).To(Equal(1))
+	g.Expect(testutil.ToFloat64(SuspendInfo.WithLabelValues("release", "default", "maintenance"))).To(Equal(float64(1)))
+
+	// Resuming the object removes the information.
+	obj.Spec.Suspend = false
+	RecordSuspendInfo(obj)
+	g.Expect(testutil.CollectAndCount(SuspendInfo)).To(Equal(0))
+
+	obj.Spec.Suspend = true
+	RecordSuspendInfo(obj)
+	DeleteSuspendInfo(obj.Name, obj.Namespace)
+	g.Expect(testutil.CollectAndCount(SuspendInfo)).To(Equal(0))
+}
+
+func TestRecordChartSecurityScan(t *testing.T) {
+	g := NewWithT(t)
+
+	ChartSecurityScan.Reset()
+	ChartVulnerabilities.Reset()
+	t.Cleanup(ChartSecurityScan.Reset)
+	t.Cleanup(ChartVulnerabilities.Reset)
+
+	RecordChartSecurityScan("release", "default", false, map[string]int{"CRITICAL": 1, "LOW": 3})
+	g.Expect(testutil.ToFloat64(ChartSecurityScan.WithLabelValues("release", "default"))).To(Equal(float64(0)))
+	g.Expect(testutil.ToFloat64(ChartVulnerabilities.WithLabelValues("release", "default", "CRITICAL"))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(ChartVulnerabilities.WithLabelValues("release", "default", "LOW"))).To(Equal(float64(3)))
+
+	// A new result replaces the previous result.
+	RecordChartSecurityScan("release", "default", true, map[string]int{"LOW": 1})
+	g.Expect(testutil.ToFloat64(ChartSecurityScan.WithLabelValues("release", "default"))).To(Equal(float64(1)))
+	g.Expect(testutil.CollectAndCount(ChartVulnerabilities)).To(Equal(1))
+
+	DeleteChartSecurityScan("release", "default")
+	g.Expect(testutil.CollectAndCount(ChartSecurityScan)).To(Equal(0))
+	g.Expect(testutil.CollectAndCount(ChartVulnerabilities)).To(Equal(0))
+}
+
+func TestRecordStorageHealth(t *testing.T) {
+	g := NewWithT(t)
+
+	StorageHealthy.Reset()
+	t.Cleanup(StorageHealthy.Reset)
+
+	RecordStorageHealth("Secret", errors.New("forbidden"))
+	g.Expect(testutil.ToFloat64(StorageHealthy.WithLabelValues("Secret"))).To(Equal(float64(0)))
+
+	RecordStorageHealth("Secret", nil)
+	g.Expect(testutil.ToFloat64(StorageHealthy.WithLabelValues("Secret"))).To(Equal(float64(1)))
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package outbound posts payloads to the HTTP(S) addresses configured by
+// tenants in Secrets, restricted to the hosts allowed by the operator.
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	flagAllowedHosts = "outbound-allowed-hosts"
+	flagTimeout      = "outbound-timeout"
+)
+
+// DefaultTimeout is the default timeout of a request.
+const DefaultTimeout = time.Minute
+
+// Options contains the configuration options for the requests to the
+// addresses configured by tenants.
+type Options struct {
+	// AllowedHosts are the patterns of the hosts requests may be sent to,
+	// in the syntax of path.Match. They are matched against the host of an
+	// address, with and without its port. When empty, no requests are sent.
+	AllowedHosts []string
+	// Timeout is the timeout of a request, which caps any shorter timeout
+	// set on the context of the request.
+	Timeout time.Duration
+}
+
+// BindFlags will parse the given pflag.FlagSet for outbound option flags and
+// set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringSliceVar(&o.AllowedHosts, flagAllowedHosts, nil,
+		"The patterns of the hosts the event sinks and release action webhooks of HelmReleases may send requests to, "+
+			"e.g. 'hooks.example.com' or '*.example.com:8443'. When empty, no requests are sent.")
+	fs.DurationVar(&o.Timeout, flagTimeout, DefaultTimeout,
+		"The maximum time to wait for the response to a request of the event sinks and release action webhooks of HelmReleases.")
+}
+
+// Client posts payloads to addresses with an allowed host.
+type Client struct {
+	httpClient   *http.Client
+	allowedHosts []string
+}
+
+// NewClient returns a new Client configured with the given Options. It
+// returns an error if any of the allowed host patterns is invalid.
+func NewClient(opts Options) (*Client, error) {
+	for _, p := range opts.AllowedHosts {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowed host pattern '%s': %w", p, err)
+		}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: timeout,
+			// Redirects are not followed, as they could lead to a host
+			// which is not allowed.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowedHosts: opts.AllowedHosts,
+	}, nil
+}
+
+// ValidateAddress returns an error if the given address is not an HTTP(S)
+// URL, or its host is not allowed.
+func (c *Client) ValidateAddress(address string) error {
+	u, err := url.ParseRequestURI(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an HTTP(S) URL")
+	}
+	if !c.allowed(u) {
+		return fmt.Errorf("host '%s' is not allowed", u.Host)
+	}
+	return nil
+}
+
+// allowed returns if the host of the given URL matches any of the allowed
+// host patterns.
+func (c *Client) allowed(u *url.URL) bool {
+	host, hostname := strings.ToLower(u.Host), strings.ToLower(u.Hostname())
+	for _, p := range c.allowedHosts {
+		p = strings.ToLower(p)
+		if ok, _ := path.Match(p, host); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, hostname); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Post posts the JSON body with the given additional headers to the given
+// address, and returns an error if the address is not allowed, or the
+// endpoint does not respond with a 2xx status code.
+func (c *Client) Post(ctx context.Context, address string, body []byte, header http.Header) error {
+	if err := c.ValidateAddress(address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The address may contain credentials, which must not be reported.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outbound
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNewClient(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewClient(Options{AllowedHosts: []string{"hooks.example.com", "[invalid"}})
+	g.Expect(err).To(MatchError(ContainSubstring("invalid allowed host pattern '[invalid'")))
+}
+
+func TestClient_ValidateAddress(t *testing.T) {
+	c, err := NewClient(Options{AllowedHosts: []string{"hooks.example.com", "*.internal.example.com:8443"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address string
+		wantErr string
+	}{
+		{address: "https://hooks.example.com/events"},
+		{address: "http://HOOKS.example.com:8080/events"},
+		{address: "https://ci.internal.example.com:8443/hook"},
+		{address: "https://ci.internal.example.com/hook", wantErr: "host 'ci.internal.example.com' is not allowed"},
+		{address: "http://169.254.169.254/latest/meta-data", wantErr: "host '169.254.169.254' is not allowed"},
+		{address: "ftp://hooks.example.com", wantErr: "must be an HTTP(S) URL"},
+		{address: "hooks.example.com", wantErr: "must be an HTTP(S) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := c.ValidateAddress(tt.address)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestClient_ValidateAddress_noAllowedHosts(t *testing.T) {
+	g := NewWithT(t)
+
+	c, err := NewClient(Options{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.ValidateAddress("https://hooks.example.com")).To(MatchError("host 'hooks.example.com' is not allowed"))
+}
+
+func TestClient_Post(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Test") != "value" || string(b) != "{}" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(Options{AllowedHosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"X-Test": []string{"value"}}
+
+	t.Run("posts body", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(c.Post(context.TODO(), server.URL+"/ok", []byte("{}"), header)).To(Succeed())
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(c.Post(context.TODO(), server.URL+"/redirect", []byte("{}"), header)).
+			To(MatchError("unexpected status code 307"))
+	})
+
+	t.Run("error status code", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(c.Post(context.TODO(), server.URL+"/error", []byte("{}"), header)).
+			To(MatchError("unexpected status code 500"))
+	})
+
+	t.Run("host not allowed", func(t *testing.T) {
+		g := NewWithT(t)
+
+		other, err := NewClient(Options{AllowedHosts: []string{"hooks.example.com"}})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(other.Post(context.TODO(), server.URL+"/ok", []byte("{}"), header)).
+			To(MatchError(ContainSubstring("is not allowed")))
+	})
+}
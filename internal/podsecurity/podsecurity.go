@@ -0,0 +1,269 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podsecurity evaluates the Pod specs of rendered objects against
+// the Pod Security Standards, to detect Pods which would be rejected by the
+// Pod Security admission controller before they are applied.
+//
+// It implements the controls of the Baseline and Restricted policies which
+// can be evaluated from a Pod spec. It does not take the exemptions of the
+// admission controller configuration into account.
+package podsecurity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/kube"
+)
+
+// EnforceLabel is the namespace label configuring the Pod Security Standard
+// enforced by the Pod Security admission controller.
+const EnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// Level is a Pod Security Standard policy level.
+type Level string
+
+const (
+	// LevelPrivileged is the unrestricted policy.
+	LevelPrivileged Level = "privileged"
+	// LevelBaseline is the minimally restrictive policy, which prevents
+	// known privilege escalations.
+	LevelBaseline Level = "baseline"
+	// LevelRestricted is the heavily restricted policy, following Pod
+	// hardening best practices.
+	LevelRestricted Level = "restricted"
+)
+
+// LevelForNamespace returns the Level enforced for a namespace with the
+// given labels. It returns LevelPrivileged if no (valid) level is enforced.
+func LevelForNamespace(labels map[string]string) Level {
+	switch level := Level(labels[EnforceLabel]); level {
+	case LevelBaseline, LevelRestricted:
+		return level
+	default:
+		return LevelPrivileged
+	}
+}
+
+// ViolationError is returned by Check when objects violate the enforced
+// Level.
+type ViolationError struct {
+	// Level is the violated Level.
+	Level Level
+	// Namespace is the namespace enforcing the Level.
+	Namespace string
+	// Violations are the violations per object.
+	Violations []string
+}
+
+// Error returns an error string containing the violations.
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("Pods would violate PodSecurity %q enforced on namespace '%s': %s",
+		string(e.Level)+":latest", e.Namespace, strings.Join(e.Violations, "; "))
+}
+
+var (
+	// baselineCapabilities are the capabilities which may be added under
+	// the Baseline policy.
+	baselineCapabilities = []corev1.Capability{
+		"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD", "NET_BIND_SERVICE",
+		"SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+	}
+	// safeSysctls are the sysctls which may be set under the Baseline
+	// policy.
+	safeSysctls = []string{
+		"kernel.shm_rmid_forced", "net.ipv4.ip_local_port_range", "net.ipv4.ip_unprivileged_port_start",
+		"net.ipv4.tcp_syncookies", "net.ipv4.ping_group_range", "net.ipv4.ip_local_reserved_ports",
+		"net.ipv4.tcp_keepalive_time", "net.ipv4.tcp_fin_timeout", "net.ipv4.tcp_keepalive_intvl",
+		"net.ipv4.tcp_keepalive_probes",
+	}
+)
+
+// Check evaluates the Pod specs of the given objects against the given Level
+// for the given namespace. It returns a ViolationError listing the violations
+// of all objects. Objects without a Pod spec are ignored.
+func Check(level Level, namespace string, objects []*unstructured.Unstructured) error {
+	if level != LevelBaseline && level != LevelRestricted {
+		return nil
+	}
+
+	var violations []string
+	for _, obj := range objects {
+		spec, err := kube.PodSpec(obj)
+		if err != nil {
+			return fmt.Errorf("failed to read Pod spec of %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+		if spec == nil {
+			continue
+		}
+		if v := checkPodSpec(level, spec); len(v) > 0 {
+			violations = append(violations, fmt.Sprintf("%s: %s", ssautil.FmtUnstructured(obj), strings.Join(v, ", ")))
+		}
+	}
+	if len(violations) > 0 {
+		return &ViolationError{Level: level, Namespace: namespace, Violations: violations}
+	}
+	return nil
+}
+
+// checkPodSpec returns the violations of the given Pod spec against the
+// given Level.
+func checkPodSpec(level Level, spec *corev1.PodSpec) []string {
+	var violations []string
+	add := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+
+	podSC := spec.SecurityContext
+	if podSC == nil {
+		podSC = &corev1.PodSecurityContext{}
+	}
+
+	// Baseline controls.
+	if spec.HostNetwork || spec.HostPID || spec.HostIPC {
+		add("host namespaces (hostNetwork=%t, hostPID=%t, hostIPC=%t)", spec.HostNetwork, spec.HostPID, spec.HostIPC)
+	}
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil {
+			add("hostPath volume %q", v.Name)
+		}
+	}
+	if podSC.SeccompProfile != nil && podSC.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		add("pod seccompProfile type %q", corev1.SeccompProfileTypeUnconfined)
+	}
+	if podSC.WindowsOptions != nil && podSC.WindowsOptions.HostProcess != nil && *podSC.WindowsOptions.HostProcess {
+		add("pod windowsOptions.hostProcess=true")
+	}
+	for _, s := range podSC.Sysctls {
+		if !slices.Contains(safeSysctls, s.Name) {
+			add("forbidden sysctl %q", s.Name)
+		}
+	}
+
+	containers := make([]corev1.Container, 0, len(spec.InitContainers)+len(spec.Containers)+len(spec.EphemeralContainers))
+	containers = append(containers, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, c := range spec.EphemeralContainers {
+		containers = append(containers, corev1.Container(c.EphemeralContainerCommon))
+	}
+
+	for _, c := range containers {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			add("container %q must not be privileged", c.Name)
+		}
+		for _, p := range c.Ports {
+			if p.HostPort != 0 {
+				add("container %q must not use hostPort %d", c.Name, p.HostPort)
+			}
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if !slices.Contains(baselineCapabilities, capability) {
+					add("container %q must not add capability %q", c.Name, capability)
+				}
+			}
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			add("container %q must not set procMount %q", c.Name, *sc.ProcMount)
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			add("container %q must not set seccompProfile type %q", c.Name, corev1.SeccompProfileTypeUnconfined)
+		}
+		if sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+			add("container %q must not set windowsOptions.hostProcess=true", c.Name)
+		}
+	}
+
+	if level != LevelRestricted {
+		return violations
+	}
+
+	// Restricted controls.
+	for _, v := range spec.Volumes {
+		if !restrictedVolume(v) {
+			add("volume %q uses a restricted volume type", v.Name)
+		}
+	}
+	for _, c := range containers {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			add("container %q must set securityContext.allowPrivilegeEscalation=false", c.Name)
+		}
+		if !runAsNonRoot(podSC, sc) {
+			add("container %q must set securityContext.runAsNonRoot=true", c.Name)
+		}
+		if (sc.RunAsUser != nil && *sc.RunAsUser == 0) || (sc.RunAsUser == nil && podSC.RunAsUser != nil && *podSC.RunAsUser == 0) {
+			add("container %q must not set runAsUser=0", c.Name)
+		}
+		if !seccompRestricted(podSC, sc) {
+			add("container %q must set securityContext.seccompProfile.type to %q or %q", c.Name,
+				corev1.SeccompProfileTypeRuntimeDefault, corev1.SeccompProfileTypeLocalhost)
+		}
+		if sc.Capabilities == nil || !slices.Contains(sc.Capabilities.Drop, "ALL") {
+			add("container %q must set securityContext.capabilities.drop=[\"ALL\"]", c.Name)
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" {
+					add("container %q must only add capability \"NET_BIND_SERVICE\"", c.Name)
+					break
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// restrictedVolume returns if the given volume is of a type allowed under
+// the Restricted policy.
+func restrictedVolume(v corev1.Volume) bool {
+	return v.ConfigMap != nil || v.CSI != nil || v.DownwardAPI != nil || v.EmptyDir != nil ||
+		v.Ephemeral != nil || v.PersistentVolumeClaim != nil || v.Projected != nil || v.Secret != nil
+}
+
+// runAsNonRoot returns if a container with the given security contexts is
+// required to run as a non-root user.
+func runAsNonRoot(podSC *corev1.PodSecurityContext, sc *corev1.SecurityContext) bool {
+	if sc.RunAsNonRoot != nil {
+		return *sc.RunAsNonRoot
+	}
+	return podSC.RunAsNonRoot != nil && *podSC.RunAsNonRoot
+}
+
+// seccompRestricted returns if a container with the given security contexts
+// has a seccomp profile allowed under the Restricted policy.
+func seccompRestricted(podSC *corev1.PodSecurityContext, sc *corev1.SecurityContext) bool {
+	profile := sc.SeccompProfile
+	if profile == nil {
+		profile = podSC.SeccompProfile
+	}
+	return profile != nil &&
+		(profile.Type == corev1.SeccompProfileTypeRuntimeDefault || profile.Type == corev1.SeccompProfileTypeLocalhost)
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podsecurity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+const restrictedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+        seccompProfile:
+          type: RuntimeDefault
+      containers:
+      - name: podinfo
+        securityContext:
+          allowPrivilegeEscalation: false
+          capabilities:
+            drop: ["ALL"]
+`
+
+const privilegedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+spec:
+  template:
+    spec:
+      hostNetwork: true
+      containers:
+      - name: podinfo
+        securityContext:
+          privileged: true
+`
+
+const defaultCronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+`
+
+const configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: podinfo
+data:
+  hostNetwork: "true"
+`
+
+func TestLevelForNamespace(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(LevelForNamespace(nil)).To(Equal(LevelPrivileged))
+	g.Expect(LevelForNamespace(map[string]string{EnforceLabel: "baseline"})).To(Equal(LevelBaseline))
+	g.Expect(LevelForNamespace(map[string]string{EnforceLabel: "restricted"})).To(Equal(LevelRestricted))
+	g.Expect(LevelForNamespace(map[string]string{EnforceLabel: "invalid"})).To(Equal(LevelPrivileged))
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     Level
+		manifests string
+		wantErr   []string
+	}{
+		{
+			name:      "privileged level",
+			level:     LevelPrivileged,
+			manifests: privilegedDeployment,
+		},
+		{
+			name:      "baseline violations",
+			level:     LevelBaseline,
+			manifests: privilegedDeployment,
+			wantErr: []string{
+				`Deployment/podinfo: host namespaces (hostNetwork=true, hostPID=false, hostIPC=false)`,
+				`container "podinfo" must not be privileged`,
+			},
+		},
+		{
+			name:      "baseline allows default Pod spec",
+			level:     LevelBaseline,
+			manifests: defaultCronJob,
+		},
+		{
+			name:      "restricted violations",
+			level:     LevelRestricted,
+			manifests: defaultCronJob,
+			wantErr: []string{
+				`CronJob/backup: container "backup" must set securityContext.allowPrivilegeEscalation=false`,
+				`container "backup" must set securityContext.runAsNonRoot=true`,
+				`container "backup" must set securityContext.seccompProfile.type to "RuntimeDefault" or "Localhost"`,
+				`container "backup" must set securityContext.capabilities.drop=["ALL"]`,
+			},
+		},
+		{
+			name:      "restricted allows hardened Pod spec",
+			level:     LevelRestricted,
+			manifests: restrictedDeployment,
+		},
+		{
+			name:      "ignores objects without Pod spec",
+			level:     LevelRestricted,
+			manifests: configMap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objects, err := ssautil.ReadObjects(strings.NewReader(tt.manifests))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = Check(tt.level, "apps", objects)
+			if len(tt.wantErr) == 0 {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+
+			var violationErr *ViolationError
+			g.Expect(errors.As(err, &violationErr)).To(BeTrue())
+			g.Expect(violationErr.Level).To(Equal(tt.level))
+			g.Expect(violationErr.Namespace).To(Equal("apps"))
+			for _, want := range tt.wantErr {
+				g.Expect(err.Error()).To(ContainSubstring(want))
+			}
+		})
+	}
+
+	t.Run("lists violations of all objects", func(t *testing.T) {
+		g := NewWithT(t)
+
+		objects, err := ssautil.ReadObjects(strings.NewReader(privilegedDeployment + "---\n" + defaultCronJob))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		err = Check(LevelRestricted, "apps", objects)
+		var violationErr *ViolationError
+		g.Expect(errors.As(err, &violationErr)).To(BeTrue())
+		g.Expect(violationErr.Violations).To(HaveLen(2))
+		g.Expect(err.Error()).To(HavePrefix(`Pods would violate PodSecurity "restricted:latest" enforced on namespace 'apps': `))
+	})
+
+	t.Run("invalid Pod spec", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": "podinfo"},
+			"spec":       map[string]interface{}{"hostNetwork": "yes"},
+		}}
+		err := Check(LevelBaseline, "apps", []*unstructured.Unstructured{obj})
+		g.Expect(err).To(MatchError(ContainSubstring("failed to read Pod spec of Pod/podinfo")))
+	})
+}
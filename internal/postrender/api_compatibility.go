@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"errors"
+
+	"k8s.io/client-go/discovery"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/apicompat"
+)
+
+// NewAPICompatibility returns an APICompatibility post-renderer which checks
+// the API versions of the rendered objects against the APIs served by the
+// cluster of the given discovery client.
+func NewAPICompatibility(client discovery.DiscoveryInterface) *APICompatibility {
+	return &APICompatibility{client: client}
+}
+
+// APICompatibility is a Helm post-renderer which returns an
+// apicompat.IncompatibleError when rendered objects use API versions which
+// are not served by the cluster. It does not modify the rendered manifests.
+//
+// The check is best-effort: when the APIs served by the cluster can not be
+// discovered, the rendered manifests are returned as is.
+type APICompatibility struct {
+	client discovery.DiscoveryInterface
+}
+
+func (k *APICompatibility) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	objects, err := ssautil.ReadObjects(bytes.NewReader(renderedManifests.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	var incompatibleErr *apicompat.IncompatibleError
+	if err := apicompat.Check(k.client, objects); errors.As(err, &incompatibleErr) {
+		return nil, err
+	}
+	return renderedManifests, nil
+}
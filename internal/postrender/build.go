@@ -23,6 +23,7 @@ import (
 	helmpostrender "helm.sh/helm/v3/pkg/postrender"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
+	intdigest "github.com/fluxcd/helm-controller/internal/digest"
 )
 
 // BuildPostRenderers creates the post-renderer instances from a HelmRelease
@@ -40,6 +41,9 @@ func BuildPostRenderers(rel *v2.HelmRelease) helmpostrender.PostRenderer {
 			})
 		}
 	}
+	if rel.Spec.CommonMetadata != nil {
+		renderers = append(renderers, NewCommonMetadata(*rel.Spec.CommonMetadata))
+	}
 	renderers = append(renderers, NewOriginLabels(v2.GroupVersion.Group, rel.Namespace, rel.Name))
 	if len(renderers) == 0 {
 		return nil
@@ -47,6 +51,42 @@ func BuildPostRenderers(rel *v2.HelmRelease) helmpostrender.PostRenderer {
 	return NewCombined(renderers...)
 }
 
+// HasPostRenderers returns true if the HelmRelease has post-renderers or
+// common metadata, which modify the rendered manifests.
+func HasPostRenderers(rel *v2.HelmRelease) bool {
+	return rel.Spec.PostRenderers != nil || rel.Spec.CommonMetadata != nil
+}
+
+// DigestFor returns the digest of the post-renderers of the HelmRelease. The
+// common metadata is included when set, to detect changes to it.
+func DigestFor(algo digest.Algorithm, rel *v2.HelmRelease) digest.Digest {
+	if rel.Spec.CommonMetadata == nil {
+		return Digest(algo, rel.Spec.PostRenderers)
+	}
+	digester := algo.Digester()
+	enc := json.NewEncoder(digester.Hash())
+	if err := enc.Encode(struct {
+		PostRenderers  []v2.PostRenderer  `json:"postRenderers"`
+		CommonMetadata *v2.CommonMetadata `json:"commonMetadata"`
+	}{rel.Spec.PostRenderers, rel.Spec.CommonMetadata}); err != nil {
+		return ""
+	}
+	return digester.Digest()
+}
+
+// VerifyDigest returns true if the given digest matches the digest of the
+// post-renderers of the HelmRelease, calculated with the algorithm of the
+// given digest. This allows a digest calculated with a previously configured
+// algorithm to be compared. It returns false if the digest is not
+// intdigest.Verifiable.
+func VerifyDigest(d string, rel *v2.HelmRelease) bool {
+	dig := digest.Digest(d)
+	if intdigest.Verifiable(dig) != nil {
+		return false
+	}
+	return DigestFor(dig.Algorithm(), rel) == dig
+}
+
 func Digest(algo digest.Algorithm, postrenders []v2.PostRenderer) digest.Digest {
 	digester := algo.Digester()
 	enc := json.NewEncoder(digester.Hash())
@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestVerifyDigest(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v2.HelmRelease{
+		Spec: v2.HelmReleaseSpec{
+			PostRenderers: []v2.PostRenderer{{Kustomize: &v2.Kustomize{}}},
+		},
+	}
+	g.Expect(VerifyDigest(DigestFor(digest.SHA256, obj).String(), obj)).To(BeTrue())
+	g.Expect(VerifyDigest(DigestFor(digest.SHA512, obj).String(), obj)).To(BeTrue())
+	g.Expect(VerifyDigest(digest.SHA256.FromString("other").String(), obj)).To(BeFalse())
+	g.Expect(VerifyDigest("", obj)).To(BeFalse())
+}
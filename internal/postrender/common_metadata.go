@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+
+	"sigs.k8s.io/kustomize/api/builtins"
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// NewCommonMetadata returns a CommonMetadata post-renderer which sets the
+// labels and annotations of the given v2.CommonMetadata on all objects.
+func NewCommonMetadata(metadata v2.CommonMetadata) *CommonMetadata {
+	return &CommonMetadata{
+		labels:      metadata.Labels,
+		annotations: metadata.Annotations,
+	}
+}
+
+// CommonMetadata is a post-renderer which sets labels and annotations on all
+// objects, overwriting existing values.
+type CommonMetadata struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+func (k *CommonMetadata) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	resFactory := provider.NewDefaultDepProvider().GetResourceFactory()
+	resMapFactory := resmap.NewFactory(resFactory)
+
+	resMap, err := resMapFactory.NewResMapFromBytes(renderedManifests.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(k.labels) > 0 {
+		labelTransformer := builtins.LabelTransformerPlugin{
+			Labels: k.labels,
+			FieldSpecs: []kustypes.FieldSpec{
+				{Path: "metadata/labels", CreateIfNotPresent: true},
+			},
+		}
+		if err := labelTransformer.Transform(resMap); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(k.annotations) > 0 {
+		annotationsTransformer := builtins.AnnotationsTransformerPlugin{
+			Annotations: k.annotations,
+			FieldSpecs: []kustypes.FieldSpec{
+				{Path: "metadata/annotations", CreateIfNotPresent: true},
+			},
+		}
+		if err := annotationsTransformer.Transform(resMap); err != nil {
+			return nil, err
+		}
+	}
+
+	yaml, err := resMap.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBuffer(yaml), nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func Test_CommonMetadata_Run(t *testing.T) {
+	g := NewWithT(t)
+
+	k := NewCommonMetadata(v2.CommonMetadata{
+		Labels:      map[string]string{"team": "a", "existing": "overwritten"},
+		Annotations: map[string]string{"owner": "team-a"},
+	})
+	got, err := k.Run(bytes.NewBufferString(mixedResourceMock))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.String()).To(Equal(`apiVersion: v1
+kind: Pod
+metadata:
+  annotations:
+    owner: team-a
+  labels:
+    existing: overwritten
+    team: a
+  name: pod-without-labels
+---
+apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    owner: team-a
+  labels:
+    existing: overwritten
+    team: a
+  name: service-with-labels
+`))
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"errors"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/crdschema"
+)
+
+// NewCRDSchema returns a CRDSchema post-renderer which validates the rendered
+// custom resources against the schemas of the CustomResourceDefinitions
+// returned by the given crdschema.GetFunc.
+func NewCRDSchema(get crdschema.GetFunc) *CRDSchema {
+	return &CRDSchema{get: get}
+}
+
+// CRDSchema is a Helm post-renderer which returns a crdschema.InvalidError
+// when rendered custom resources do not match the schemas of their
+// CustomResourceDefinitions. It does not modify the rendered manifests.
+//
+// The check is best-effort: when the CustomResourceDefinitions can not be
+// retrieved, the rendered manifests are returned as is.
+type CRDSchema struct {
+	get crdschema.GetFunc
+}
+
+func (k *CRDSchema) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	objects, err := ssautil.ReadObjects(bytes.NewReader(renderedManifests.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	var invalidErr *crdschema.InvalidError
+	if err := crdschema.Check(k.get, objects); errors.As(err, &invalidErr) {
+		return nil, err
+	}
+	return renderedManifests, nil
+}
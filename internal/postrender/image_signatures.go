@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/kube"
+	"github.com/fluxcd/helm-controller/internal/signature"
+)
+
+// NewImageSignatures returns an ImageSignatures post-renderer which verifies
+// the images with the given verifier, authenticating with the image pull
+// secrets retrieved with the given client. Objects without a namespace are
+// taken to be in the given namespace of the release.
+func NewImageSignatures(ctx context.Context, verifier *signature.Verifier, client kubernetes.Interface, namespace string) *ImageSignatures {
+	return &ImageSignatures{
+		ctx:       ctx,
+		verifier:  verifier,
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+// ImageSignatures is a Helm post-renderer which returns a
+// signature.VerificationError when the container images of the Pod specs of
+// the rendered objects do not have a valid signature. The verified images
+// are pinned to their verified digest, so the kubelet pulls the image which
+// was verified even if the tag is moved afterwards.
+//
+// The registries are authenticated to with the image pull secrets of the
+// Pod specs, and of the ServiceAccounts they run as, in addition to the
+// credentials of the verifier.
+type ImageSignatures struct {
+	// ctx is the context of the Helm action the post-renderer is
+	// configured for, as the post-renderer interface does not accept one.
+	ctx       context.Context
+	verifier  *signature.Verifier
+	client    kubernetes.Interface
+	namespace string
+}
+
+func (k *ImageSignatures) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	objects, err := ssautil.ReadObjects(bytes.NewReader(renderedManifests.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	images, err := signature.Images(objects)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := k.pullSecrets(objects)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := k.verifier.WithPullSecrets(secrets)
+	if err != nil {
+		return nil, err
+	}
+	digests, err := verifier.Verify(k.ctx, images)
+	if err != nil {
+		return nil, err
+	}
+
+	var pinned bool
+	for _, obj := range objects {
+		err = kube.MapImages(obj, func(image string) string {
+			d, ok := digests[image]
+			if !ok || strings.Contains(image, "@") {
+				return image
+			}
+			pinned = true
+			return image + "@" + d.String()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to pin images of %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+	}
+	if !pinned {
+		return renderedManifests, nil
+	}
+
+	yaml, err := ssautil.ObjectsToYAML(objects)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBufferString(yaml), nil
+}
+
+// pullSecrets returns the image pull secrets of the Pod specs of the given
+// objects, and of the ServiceAccounts they run as. Secrets and
+// ServiceAccounts are taken from the rendered objects, or else retrieved
+// from the cluster. The ones which do not exist, or which may not be read,
+// are ignored, as the registry may not require authentication.
+func (k *ImageSignatures) pullSecrets(objects []*unstructured.Unstructured) ([]corev1.Secret, error) {
+	var (
+		names []types.NamespacedName
+		seen  = make(map[types.NamespacedName]struct{})
+	)
+	add := func(namespace string, refs []corev1.LocalObjectReference) {
+		for _, ref := range refs {
+			name := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+			if _, ok := seen[name]; !ok && ref.Name != "" {
+				seen[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, obj := range objects {
+		spec, err := kube.PodSpec(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Pod spec of %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+		if spec == nil {
+			continue
+		}
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = k.namespace
+		}
+		add(namespace, spec.ImagePullSecrets)
+
+		serviceAccountName := spec.ServiceAccountName
+		if serviceAccountName == "" {
+			serviceAccountName = "default"
+		}
+		sa, err := k.serviceAccount(objects, types.NamespacedName{Namespace: namespace, Name: serviceAccountName})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ServiceAccount '%s/%s': %w", namespace, serviceAccountName, err)
+		}
+		if sa != nil {
+			add(namespace, sa.ImagePullSecrets)
+		}
+	}
+
+	var secrets []corev1.Secret
+	for _, name := range names {
+		secret, err := k.secret(objects, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image pull secret '%s': %w", name, err)
+		}
+		if secret != nil {
+			secrets = append(secrets, *secret)
+		}
+	}
+	return secrets, nil
+}
+
+// serviceAccount returns the ServiceAccount with the given name from the
+// rendered objects, or else from the cluster. It returns nil if it does not
+// exist, or may not be read.
+func (k *ImageSignatures) serviceAccount(objects []*unstructured.Unstructured, name types.NamespacedName) (*corev1.ServiceAccount, error) {
+	sa := &corev1.ServiceAccount{}
+	if obj := k.rendered(objects, "ServiceAccount", name); obj != nil {
+		return sa, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, sa)
+	}
+	if k.client == nil {
+		return nil, nil
+	}
+	sa, err := k.client.CoreV1().ServiceAccounts(name.Namespace).Get(k.ctx, name.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+		return nil, nil
+	}
+	return sa, err
+}
+
+// secret returns the Secret with the given name from the rendered objects,
+// or else from the cluster. It returns nil if it does not exist, or may not
+// be read.
+func (k *ImageSignatures) secret(objects []*unstructured.Unstructured, name types.NamespacedName) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if obj := k.rendered(objects, "Secret", name); obj != nil {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, secret); err != nil {
+			return nil, err
+		}
+		secret.Namespace = name.Namespace
+		for key, value := range secret.StringData {
+			if secret.Data == nil {
+				secret.Data = make(map[string][]byte)
+			}
+			secret.Data[key] = []byte(value)
+		}
+		return secret, nil
+	}
+	if k.client == nil {
+		return nil, nil
+	}
+	secret, err := k.client.CoreV1().Secrets(name.Namespace).Get(k.ctx, name.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// rendered returns the core object of the given kind with the given name
+// from the rendered objects, or nil.
+func (k *ImageSignatures) rendered(objects []*unstructured.Unstructured, kind string, name types.NamespacedName) *unstructured.Unstructured {
+	for _, obj := range objects {
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = k.namespace
+		}
+		if obj.GroupVersionKind() == corev1.SchemeGroupVersion.WithKind(kind) && namespace == name.Namespace && obj.GetName() == name.Name {
+			return obj
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+func TestImageSignatures_pullSecrets(t *testing.T) {
+	g := NewWithT(t)
+
+	objects, err := ssautil.ReadObjects(strings.NewReader(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+      imagePullSecrets:
+      - name: rendered
+      - name: missing
+      containers:
+      - name: app
+        image: registry.example.com/app:1.0.0
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: rendered
+type: kubernetes.io/dockerconfigjson
+stringData:
+  .dockerconfigjson: '{"auths":{}}'
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: job
+  namespace: other
+spec:
+  template:
+    spec:
+      containers:
+      - name: job
+        image: registry.example.com/job:1.0.0
+`))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	client := fake.NewSimpleClientset(
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "service-account"}, {Name: "rendered"}},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "other"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "service-account"}},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "service-account", Namespace: "default"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "service-account", Namespace: "other"}},
+	)
+
+	secrets, err := NewImageSignatures(context.TODO(), nil, client, "default").pullSecrets(objects)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var names []string
+	for _, s := range secrets {
+		names = append(names, s.Namespace+"/"+s.Name)
+	}
+	g.Expect(names).To(Equal([]string{"default/rendered", "default/service-account", "other/service-account"}))
+	g.Expect(secrets[0].Data).To(HaveKeyWithValue(corev1.DockerConfigJsonKey, []byte(`{"auths":{}}`)))
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/podsecurity"
+)
+
+// NewPodSecurity returns a PodSecurity post-renderer which checks the objects
+// in the given namespace against the given level.
+func NewPodSecurity(level podsecurity.Level, namespace string) *PodSecurity {
+	return &PodSecurity{
+		level:     level,
+		namespace: namespace,
+	}
+}
+
+// PodSecurity is a Helm post-renderer which returns a
+// podsecurity.ViolationError when the Pod specs of the rendered objects in
+// the namespace violate the Pod Security Standard level enforced on it. It
+// does not modify the rendered manifests.
+type PodSecurity struct {
+	level     podsecurity.Level
+	namespace string
+}
+
+func (k *PodSecurity) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	objects, err := ssautil.ReadObjects(bytes.NewReader(renderedManifests.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects without a namespace are created in the namespace of the
+	// release, objects in other namespaces are subject to the level
+	// enforced on their own namespace.
+	if err := podsecurity.Check(k.level, k.namespace, InNamespace(objects, k.namespace)); err != nil {
+		return nil, err
+	}
+	return renderedManifests, nil
+}
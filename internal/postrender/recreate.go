@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/recreate"
+)
+
+// NewRecreate returns a Recreate post-renderer which compares the rendered
+// manifests with the given current objects of the release in the given
+// namespace.
+func NewRecreate(namespace string, current []*unstructured.Unstructured) *Recreate {
+	return &Recreate{namespace: namespace, current: current}
+}
+
+// Recreate is a Helm post-renderer which returns a recreate.RequiredError
+// when the rendered manifests would require StatefulSets or
+// PersistentVolumeClaims of the current objects to be recreated. It does not
+// modify the rendered manifests.
+type Recreate struct {
+	namespace string
+	current   []*unstructured.Unstructured
+}
+
+func (k *Recreate) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	objects, err := ssautil.ReadObjects(bytes.NewReader(renderedManifests.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	if err := recreate.Check(k.namespace, k.current, objects); err != nil {
+		return nil, err
+	}
+	return renderedManifests, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/quota"
+)
+
+// NewResourceQuota returns a ResourceQuota post-renderer which checks the
+// requests of the objects in the given namespace against the given quotas,
+// replacing the given previous requests of the release.
+func NewResourceQuota(namespace string, quotas []corev1.ResourceQuota, previous corev1.ResourceList) *ResourceQuota {
+	return &ResourceQuota{
+		namespace: namespace,
+		quotas:    quotas,
+		previous:  previous,
+	}
+}
+
+// ResourceQuota is a Helm post-renderer which returns a quota.ExceededError
+// when the compute resources requested by the rendered workloads in the
+// namespace would exceed the ResourceQuotas of the namespace. It does not
+// modify the rendered manifests.
+type ResourceQuota struct {
+	namespace string
+	quotas    []corev1.ResourceQuota
+	previous  corev1.ResourceList
+}
+
+func (k *ResourceQuota) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	objects, err := ssautil.ReadObjects(bytes.NewReader(renderedManifests.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := quota.Requests(InNamespace(objects, k.namespace))
+	if err != nil {
+		return nil, err
+	}
+	if err := quota.Check(k.namespace, k.quotas, requests, k.previous); err != nil {
+		return nil, err
+	}
+	return renderedManifests, nil
+}
+
+// InNamespace returns the objects which are created in the given namespace
+// of a release: the objects without a namespace, and the objects in the
+// namespace.
+func InNamespace(objects []*unstructured.Unstructured, namespace string) []*unstructured.Unstructured {
+	inNamespace := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if ns := obj.GetNamespace(); ns == "" || ns == namespace {
+			inNamespace = append(inNamespace, obj)
+		}
+	}
+	return inNamespace
+}
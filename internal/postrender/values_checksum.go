@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"sigs.k8s.io/kustomize/api/builtins"
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// podTemplateFieldSpecs are the paths of the annotations of the pod templates
+// of the workload kinds.
+var podTemplateFieldSpecs = []kustypes.FieldSpec{
+	{Gvk: resid.Gvk{Group: "apps", Kind: "Deployment"}, Path: "spec/template/metadata/annotations", CreateIfNotPresent: true},
+	{Gvk: resid.Gvk{Group: "apps", Kind: "StatefulSet"}, Path: "spec/template/metadata/annotations", CreateIfNotPresent: true},
+	{Gvk: resid.Gvk{Group: "apps", Kind: "DaemonSet"}, Path: "spec/template/metadata/annotations", CreateIfNotPresent: true},
+	{Gvk: resid.Gvk{Group: "apps", Kind: "ReplicaSet"}, Path: "spec/template/metadata/annotations", CreateIfNotPresent: true},
+	{Gvk: resid.Gvk{Group: "batch", Kind: "Job"}, Path: "spec/template/metadata/annotations", CreateIfNotPresent: true},
+	{Gvk: resid.Gvk{Group: "batch", Kind: "CronJob"}, Path: "spec/jobTemplate/spec/template/metadata/annotations", CreateIfNotPresent: true},
+}
+
+// NewValuesChecksum returns a ValuesChecksum post-renderer for the given
+// v2.ValuesChecksum configuration and the values of the release.
+func NewValuesChecksum(spec v2.ValuesChecksum, values map[string]interface{}) *ValuesChecksum {
+	return &ValuesChecksum{
+		values:     values,
+		paths:      spec.Values,
+		configMaps: spec.ConfigMaps,
+		secrets:    spec.Secrets,
+	}
+}
+
+// ValuesChecksum is a post-renderer which sets the v2.ValuesChecksumAnnotation
+// on the pod templates of all workloads, with a checksum of the selected
+// values and of the data of the selected ConfigMaps and Secrets in the
+// rendered manifests. When nothing is selected, the checksum is calculated
+// over all values.
+type ValuesChecksum struct {
+	values     map[string]interface{}
+	paths      []string
+	configMaps []string
+	secrets    []string
+}
+
+func (k *ValuesChecksum) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
+	resFactory := provider.NewDefaultDepProvider().GetResourceFactory()
+	resMapFactory := resmap.NewFactory(resFactory)
+
+	resMap, err := resMapFactory.NewResMapFromBytes(renderedManifests.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := k.checksum(resMap)
+	if err != nil {
+		return nil, err
+	}
+
+	annotationsTransformer := builtins.AnnotationsTransformerPlugin{
+		Annotations: map[string]string{v2.ValuesChecksumAnnotation: checksum},
+		FieldSpecs:  podTemplateFieldSpecs,
+	}
+	if err := annotationsTransformer.Transform(resMap); err != nil {
+		return nil, err
+	}
+
+	yaml, err := resMap.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBuffer(yaml), nil
+}
+
+// checksum returns the checksum of the selected values, ConfigMaps and
+// Secrets. It returns an error if a selected ConfigMap or Secret is not part
+// of the rendered manifests.
+func (k *ValuesChecksum) checksum(resMap resmap.ResMap) (string, error) {
+	selected := struct {
+		Values     map[string]interface{} `json:"values,omitempty"`
+		ConfigMaps map[string]interface{} `json:"configMaps,omitempty"`
+		Secrets    map[string]interface{} `json:"secrets,omitempty"`
+	}{}
+
+	if len(k.paths) == 0 && len(k.configMaps) == 0 && len(k.secrets) == 0 {
+		selected.Values = map[string]interface{}{"": k.values}
+	}
+	if len(k.paths) > 0 {
+		selected.Values = make(map[string]interface{}, len(k.paths))
+		for _, path := range k.paths {
+			selected.Values[path] = lookupValue(k.values, path)
+		}
+	}
+
+	var err error
+	if selected.ConfigMaps, err = selectData(resMap, "ConfigMap", k.configMaps); err != nil {
+		return "", err
+	}
+	if selected.Secrets, err = selectData(resMap, "Secret", k.secrets); err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(selected)
+	if err != nil {
+		return "", err
+	}
+	return digest.SHA256.FromBytes(b).Encoded(), nil
+}
+
+// selectData returns the data of the objects of the given kind with the given
+// names in the resource map, keyed by name.
+func selectData(resMap resmap.ResMap, kind string, names []string) (map[string]interface{}, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	data := make(map[string]interface{}, len(names))
+	for _, res := range resMap.Resources() {
+		if res.GetKind() != kind {
+			continue
+		}
+		m, err := res.Map()
+		if err != nil {
+			return nil, err
+		}
+		data[res.GetName()] = map[string]interface{}{
+			"data":       m["data"],
+			"binaryData": m["binaryData"],
+			"stringData": m["stringData"],
+		}
+	}
+
+	selected := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		d, ok := data[name]
+		if !ok {
+			return nil, fmt.Errorf("%s '%s' selected for values checksum not found in rendered manifests", kind, name)
+		}
+		selected[name] = d
+	}
+	return selected, nil
+}
+
+// lookupValue returns the value at the given dot-separated path, or nil if
+// the path does not exist.
+func lookupValue(values map[string]interface{}, path string) interface{} {
+	var cur interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if cur, ok = m[key]; !ok {
+			return nil
+		}
+	}
+	return cur
+}
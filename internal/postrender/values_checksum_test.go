@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+const workloadsMock = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - image: app
+        name: app
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cron
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: cron
+            name: cron
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`
+
+func Test_ValuesChecksum_Run(t *testing.T) {
+	g := NewWithT(t)
+
+	k := NewValuesChecksum(v2.ValuesChecksum{}, map[string]interface{}{"replicas": 1})
+	got, err := k.Run(bytes.NewBufferString(workloadsMock))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	resMap, err := resmap.NewFactory(provider.NewDefaultDepProvider().GetResourceFactory()).
+		NewResMapFromBytes(got.Bytes())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var checksums []string
+	for _, res := range resMap.Resources() {
+		var path []string
+		switch res.GetKind() {
+		case "Deployment":
+			path = []string{"spec", "template", "metadata", "annotations", v2.ValuesChecksumAnnotation}
+		case "CronJob":
+			path = []string{"spec", "jobTemplate", "spec", "template", "metadata", "annotations", v2.ValuesChecksumAnnotation}
+		default:
+			g.Expect(res.GetAnnotations()).ToNot(HaveKey(v2.ValuesChecksumAnnotation))
+			continue
+		}
+		node, err := res.Pipe(kyaml.Lookup(path...))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(node).ToNot(BeNil())
+		checksums = append(checksums, node.YNode().Value)
+	}
+	g.Expect(checksums).To(HaveLen(2))
+	g.Expect(checksums[0]).ToNot(BeEmpty())
+	g.Expect(checksums[0]).To(Equal(checksums[1]))
+}
+
+func Test_ValuesChecksum_checksum(t *testing.T) {
+	values := map[string]interface{}{
+		"replicas": 1,
+		"config": map[string]interface{}{
+			"level": "info",
+		},
+	}
+	otherValues := map[string]interface{}{
+		"replicas": 2,
+		"config": map[string]interface{}{
+			"level": "info",
+		},
+	}
+
+	resMap := func(t *testing.T, manifest string) resmap.ResMap {
+		m, err := resmap.NewFactory(provider.NewDefaultDepProvider().GetResourceFactory()).
+			NewResMapFromBytes([]byte(manifest))
+		NewWithT(t).Expect(err).ToNot(HaveOccurred())
+		return m
+	}
+
+	t.Run("all values when nothing is selected", func(t *testing.T) {
+		g := NewWithT(t)
+
+		a, err := NewValuesChecksum(v2.ValuesChecksum{}, values).checksum(resMap(t, workloadsMock))
+		g.Expect(err).ToNot(HaveOccurred())
+		b, err := NewValuesChecksum(v2.ValuesChecksum{}, otherValues).checksum(resMap(t, workloadsMock))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(a).ToNot(Equal(b))
+	})
+
+	t.Run("only selected values", func(t *testing.T) {
+		g := NewWithT(t)
+
+		spec := v2.ValuesChecksum{Values: []string{"config.level"}}
+		a, err := NewValuesChecksum(spec, values).checksum(resMap(t, workloadsMock))
+		g.Expect(err).ToNot(HaveOccurred())
+		b, err := NewValuesChecksum(spec, otherValues).checksum(resMap(t, workloadsMock))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(a).To(Equal(b))
+	})
+
+	t.Run("data of selected ConfigMaps", func(t *testing.T) {
+		g := NewWithT(t)
+
+		spec := v2.ValuesChecksum{ConfigMaps: []string{"app-config"}}
+		a, err := NewValuesChecksum(spec, values).checksum(resMap(t, workloadsMock))
+		g.Expect(err).ToNot(HaveOccurred())
+		b, err := NewValuesChecksum(spec, otherValues).checksum(resMap(t, workloadsMock))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(a).To(Equal(b))
+
+		changed := bytes.Replace([]byte(workloadsMock), []byte("key: value"), []byte("key: changed"), 1)
+		c, err := NewValuesChecksum(spec, values).checksum(resMap(t, string(changed)))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(a).ToNot(Equal(c))
+	})
+
+	t.Run("error for missing selected Secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		spec := v2.ValuesChecksum{Secrets: []string{"app-secret"}}
+		_, err := NewValuesChecksum(spec, values).checksum(resMap(t, workloadsMock))
+		g.Expect(err).To(MatchError(ContainSubstring("Secret 'app-secret'")))
+	})
+}
+
+func Test_lookupValue(t *testing.T) {
+	g := NewWithT(t)
+
+	values := map[string]interface{}{
+		"config": map[string]interface{}{"level": "info"},
+	}
+	g.Expect(lookupValue(values, "config.level")).To(Equal("info"))
+	g.Expect(lookupValue(values, "config")).To(Equal(values["config"]))
+	g.Expect(lookupValue(values, "config.level.missing")).To(BeNil())
+	g.Expect(lookupValue(values, "missing")).To(BeNil())
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package preview provides an authenticated HTTP endpoint which previews the
+// release the controller would make for a HelmRelease, to show what will
+// change before it is reconciled.
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/httpauth"
+)
+
+const (
+	// Path is the path the Handler is served at, followed by
+	// "<namespace>/<name>" of the HelmRelease.
+	Path = "/preview/helmreleases/"
+
+	// Subresource is the subresource of HelmReleases a user must be allowed
+	// to get to preview a HelmRelease.
+	Subresource = "preview"
+)
+
+const (
+	// ActionInstall is the action of a Result for a HelmRelease without a
+	// deployed release.
+	ActionInstall = "install"
+	// ActionUpgrade is the action of a Result for a HelmRelease with a
+	// deployed release.
+	ActionUpgrade = "upgrade"
+)
+
+// Result is the preview of the release of a HelmRelease.
+type Result struct {
+	// Object is the namespace and name of the HelmRelease.
+	Object string `json:"object"`
+	// Chart is the name and version of the chart of the release.
+	Chart string `json:"chart"`
+	// Action is the Helm action which would make the release, either
+	// ActionInstall or ActionUpgrade.
+	Action string `json:"action"`
+	// Manifest is the rendered manifest of the release, with the data of
+	// Secrets redacted.
+	Manifest string `json:"manifest"`
+	// Diff summarizes the changes to the objects of the deployed release,
+	// as computed by a server-side dry-run of the upgrade. It is nil for
+	// ActionInstall.
+	Diff *v2.DiffSummary `json:"diff,omitempty"`
+}
+
+// Previewer previews the release of a HelmRelease.
+type Previewer interface {
+	// Preview returns the preview of the release the controller would make
+	// for the given HelmRelease. It must not modify the Helm storage nor the
+	// objects in the cluster.
+	Preview(ctx context.Context, obj *v2.HelmRelease) (*Result, error)
+}
+
+// Handler serves the Result of a Previewer for a HelmRelease as JSON at
+// Path<namespace>/<name>.
+//
+// Requests must carry a bearer token of a user which is allowed to get the
+// Subresource of the HelmRelease, as verified with a TokenReview and a
+// SubjectAccessReview.
+type Handler struct {
+	client client.Client
+
+	mu        sync.RWMutex
+	previewer Previewer
+}
+
+// NewHandler returns a new Handler which authenticates requests and reads
+// the HelmRelease with the given client. The Handler responds with
+// http.StatusServiceUnavailable until a Previewer is set with SetPreviewer,
+// which allows it to be registered before the controller is set up.
+func NewHandler(c client.Client) *Handler {
+	return &Handler{client: c}
+}
+
+// SetPreviewer sets the Previewer the Handler previews HelmReleases with.
+func (h *Handler) SetPreviewer(previewer Previewer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.previewer = previewer
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, Path), "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		http.Error(w, fmt.Sprintf("expected path %s<namespace>/<name>", Path), http.StatusBadRequest)
+		return
+	}
+
+	user, err := httpauth.Authenticate(h.client, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err = httpauth.Authorize(r.Context(), h.client, user, namespace, name, Subresource); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	obj := &v2.HelmRelease{}
+	if err = h.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		code := http.StatusInternalServerError
+		if apierrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	h.mu.RLock()
+	previewer := h.previewer
+	h.mu.RUnlock()
+	if previewer == nil {
+		http.Error(w, "controller is not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := previewer.Preview(r.Context(), obj)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to preview HelmRelease: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(result)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// previewFunc is a Previewer which calls the function.
+type previewFunc func(ctx context.Context, obj *v2.HelmRelease) (*Result, error)
+
+func (f previewFunc) Preview(ctx context.Context, obj *v2.HelmRelease) (*Result, error) {
+	return f(ctx, obj)
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v2.AddToScheme(scheme)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+	}
+
+	// The token "valid" authenticates the user "alice", who is allowed to
+	// preview HelmReleases in the "apps" namespace.
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "valid" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "alice"}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				attrs := review.Spec.ResourceAttributes
+				review.Status.Allowed = review.Spec.User == "alice" && attrs.Namespace == "apps" &&
+					attrs.Resource == "helmreleases" && attrs.Subresource == Subresource && attrs.Verb == "get"
+			default:
+				return c.Create(ctx, obj, opts...)
+			}
+			return nil
+		},
+	}).Build()
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		token     string
+		previewer Previewer
+		wantCode  int
+	}{
+		{
+			name:     "missing token",
+			path:     Path + "apps/podinfo",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "invalid token",
+			path:     Path + "apps/podinfo",
+			token:    "invalid",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "forbidden namespace",
+			path:     Path + "other/podinfo",
+			token:    "valid",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "not found",
+			path:     Path + "apps/missing",
+			token:    "valid",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "invalid path",
+			path:     Path + "apps",
+			token:    "valid",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodPost,
+			path:     Path + "apps/podinfo",
+			token:    "valid",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "previewer not set",
+			path:     Path + "apps/podinfo",
+			token:    "valid",
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:  "preview",
+			path:  Path + "apps/podinfo",
+			token: "valid",
+			previewer: previewFunc(func(_ context.Context, obj *v2.HelmRelease) (*Result, error) {
+				return &Result{
+					Object: client.ObjectKeyFromObject(obj).String(),
+					Action: ActionUpgrade,
+					Diff:   &v2.DiffSummary{FromVersion: 1, ToVersion: 2, Modified: 1},
+				}, nil
+			}),
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			h := NewHandler(c)
+			if tt.previewer != nil {
+				h.SetPreviewer(tt.previewer)
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			g.Expect(rec.Code).To(Equal(tt.wantCode), rec.Body.String())
+
+			if tt.wantCode == http.StatusOK {
+				result := &Result{}
+				g.Expect(json.Unmarshal(rec.Body.Bytes(), result)).To(Succeed())
+				g.Expect(result.Object).To(Equal("apps/podinfo"))
+				g.Expect(result.Diff.Modified).To(Equal(1))
+			}
+		})
+	}
+}
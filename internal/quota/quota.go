@@ -0,0 +1,221 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota estimates the compute resources requested by the workloads
+// of rendered objects, to detect releases which would exceed the
+// ResourceQuotas of their namespace before they are applied.
+//
+// The estimation is best-effort: it does not take autoscalers, the surge of
+// rolling updates, Pod overhead or LimitRange defaults into account.
+package quota
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	"github.com/fluxcd/helm-controller/internal/kube"
+)
+
+// checkedResources are the ResourceQuota resources which are checked.
+var checkedResources = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+	corev1.ResourceRequestsCPU,
+	corev1.ResourceRequestsMemory,
+	corev1.ResourceLimitsCPU,
+	corev1.ResourceLimitsMemory,
+	corev1.ResourcePods,
+}
+
+// ExceededError is returned by Check when the requests of a release would
+// exceed a ResourceQuota.
+type ExceededError struct {
+	// Namespace is the namespace of the ResourceQuotas.
+	Namespace string
+	// Exceeded are the exceeded resources per ResourceQuota.
+	Exceeded []string
+}
+
+// Error returns an error string containing the exceeded resources.
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("release would exceed ResourceQuota in namespace '%s': %s",
+		e.Namespace, strings.Join(e.Exceeded, "; "))
+}
+
+// Requests returns the compute resources requested by the workloads in the
+// given objects, multiplied by their replicas, by ResourceQuota resource name.
+// DaemonSets and CronJobs are ignored, as the number of their Pods can not be
+// determined from their spec.
+func Requests(objects []*unstructured.Unstructured) (corev1.ResourceList, error) {
+	total := corev1.ResourceList{}
+	for _, obj := range objects {
+		replicas, ok, err := podReplicas(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read replicas of %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+		if !ok || replicas == 0 {
+			continue
+		}
+		spec, err := kube.PodSpec(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Pod spec of %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+		if spec == nil {
+			continue
+		}
+
+		for name, q := range podRequests(spec) {
+			q.Mul(replicas)
+			add(total, name, q)
+		}
+		add(total, corev1.ResourcePods, *resource.NewQuantity(replicas, resource.DecimalSI))
+	}
+	return total, nil
+}
+
+// Check returns an ExceededError if the given requests of a release exceed
+// the hard limits of any of the given ResourceQuotas, in addition to their
+// current usage minus the given previous requests of the release which are
+// replaced. ResourceQuotas with scopes are ignored, as the Pods they apply
+// to can not be determined.
+func Check(namespace string, quotas []corev1.ResourceQuota, requests, previous corev1.ResourceList) error {
+	var exceeded []string
+	for _, q := range quotas {
+		if len(q.Spec.Scopes) > 0 || q.Spec.ScopeSelector != nil {
+			continue
+		}
+		for _, name := range checkedResources {
+			hard, ok := q.Status.Hard[name]
+			if !ok {
+				if hard, ok = q.Spec.Hard[name]; !ok {
+					continue
+				}
+			}
+			requested, ok := requests[name]
+			if !ok {
+				continue
+			}
+
+			want := q.Status.Used[name].DeepCopy()
+			want.Add(requested)
+			if p, ok := previous[name]; ok {
+				want.Sub(p)
+			}
+			if want.Cmp(hard) > 0 {
+				exceeded = append(exceeded, fmt.Sprintf("'%s' %s: %s would exceed hard limit %s",
+					q.Name, name, want.String(), hard.String()))
+			}
+		}
+	}
+	if len(exceeded) > 0 {
+		sort.Strings(exceeded)
+		return &ExceededError{Namespace: namespace, Exceeded: exceeded}
+	}
+	return nil
+}
+
+// podReplicas returns the number of Pods of the given workload object, and
+// false if it can not be determined from its spec.
+func podReplicas(obj *unstructured.Unstructured) (int64, bool, error) {
+	gvk := obj.GroupVersionKind()
+	switch {
+	case gvk.Group == "" && gvk.Kind == "Pod":
+		return 1, true, nil
+	case gvk.Group == "" && gvk.Kind == "ReplicationController",
+		gvk.Group == "apps" && (gvk.Kind == "Deployment" || gvk.Kind == "StatefulSet" || gvk.Kind == "ReplicaSet"):
+		return nestedInt64(obj, 1, "spec", "replicas")
+	case gvk.Group == "batch" && gvk.Kind == "Job":
+		return nestedInt64(obj, 1, "spec", "parallelism")
+	default:
+		return 0, false, nil
+	}
+}
+
+// nestedInt64 returns the integer at the given field path of the object, or
+// the given default if it is not set.
+func nestedInt64(obj *unstructured.Unstructured, def int64, fields ...string) (int64, bool, error) {
+	v, ok, err := unstructured.NestedFieldNoCopy(obj.Object, fields...)
+	if err != nil || !ok || v == nil {
+		return def, err == nil, err
+	}
+	switch n := v.(type) {
+	case int64:
+		return n, true, nil
+	case float64:
+		return int64(n), true, nil
+	default:
+		return 0, false, fmt.Errorf("%s is of type %T, expected integer", strings.Join(fields, "."), v)
+	}
+}
+
+// podRequests returns the effective requests and limits of a Pod with the
+// given spec by ResourceQuota resource name: the sum of its containers, or
+// the highest of its init containers if that is higher.
+func podRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		for name, q := range c.Resources.Requests {
+			add(requests, name, q)
+		}
+		for name, q := range c.Resources.Limits {
+			add(limits, name, q)
+		}
+	}
+	for _, c := range spec.InitContainers {
+		maxOf(requests, c.Resources.Requests)
+		maxOf(limits, c.Resources.Limits)
+	}
+
+	result := corev1.ResourceList{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if q, ok := requests[name]; ok {
+			result[name] = q.DeepCopy()
+			result[corev1.ResourceName("requests."+string(name))] = q.DeepCopy()
+		}
+		if q, ok := limits[name]; ok {
+			result[corev1.ResourceName("limits."+string(name))] = q.DeepCopy()
+		}
+	}
+	return result
+}
+
+// add adds the given quantity to the resource with the given name in the
+// list.
+func add(list corev1.ResourceList, name corev1.ResourceName, q resource.Quantity) {
+	if cur, ok := list[name]; ok {
+		cur.Add(q)
+		list[name] = cur
+		return
+	}
+	list[name] = q.DeepCopy()
+}
+
+// maxOf sets the resources in the list to the quantity in other, if that is
+// higher.
+func maxOf(list, other corev1.ResourceList) {
+	for name, q := range other {
+		if cur, ok := list[name]; !ok || q.Cmp(cur) > 0 {
+			list[name] = q.DeepCopy()
+		}
+	}
+}
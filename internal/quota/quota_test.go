@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+const manifests = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+  template:
+    spec:
+      initContainers:
+        - name: migrate
+          resources:
+            requests:
+              cpu: "1"
+      containers:
+        - name: app
+          resources:
+            requests:
+              cpu: 250m
+              memory: 128Mi
+            limits:
+              memory: 256Mi
+        - name: sidecar
+          resources:
+            requests:
+              cpu: 50m
+              memory: 32Mi
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: job
+spec:
+  template:
+    spec:
+      containers:
+        - name: job
+          resources:
+            requests:
+              memory: 64Mi
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      containers:
+        - name: agent
+          resources:
+            requests:
+              cpu: "2"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`
+
+func TestRequests(t *testing.T) {
+	g := NewWithT(t)
+
+	objects, err := ssautil.ReadObjects(strings.NewReader(manifests))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	got, err := Requests(objects)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	expect := map[corev1.ResourceName]string{
+		// The init container requests more CPU than the containers.
+		corev1.ResourceCPU:            "3",
+		corev1.ResourceRequestsCPU:    "3",
+		corev1.ResourceMemory:         "544Mi",
+		corev1.ResourceRequestsMemory: "544Mi",
+		corev1.ResourceLimitsMemory:   "768Mi",
+		corev1.ResourcePods:           "4",
+	}
+	g.Expect(got).To(HaveLen(len(expect)))
+	for name, q := range expect {
+		g.Expect(got).To(HaveKey(name))
+		v := got[name]
+		g.Expect(v.Cmp(resource.MustParse(q))).To(BeZero(), "%s: %s != %s", name, v.String(), q)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	quota := corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("4"),
+				corev1.ResourceRequestsMemory: resource.MustParse("1Gi"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				corev1.ResourceRequestsMemory: resource.MustParse("512Mi"),
+			},
+		},
+	}
+	scoped := corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "best-effort"},
+		Spec: corev1.ResourceQuotaSpec{
+			Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+		},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("0")},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		requests corev1.ResourceList
+		previous corev1.ResourceList
+		wantErr  string
+	}{
+		{
+			name: "within quota",
+			requests: corev1.ResourceList{
+				corev1.ResourceRequestsCPU: resource.MustParse("2"),
+				corev1.ResourcePods:        resource.MustParse("10"),
+			},
+		},
+		{
+			name: "exceeds quota",
+			requests: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("2500m"),
+				corev1.ResourceRequestsMemory: resource.MustParse("256Mi"),
+			},
+			wantErr: "'compute' requests.cpu: 4500m would exceed hard limit 4",
+		},
+		{
+			name: "previous requests are replaced",
+			requests: corev1.ResourceList{
+				corev1.ResourceRequestsCPU: resource.MustParse("2500m"),
+			},
+			previous: corev1.ResourceList{
+				corev1.ResourceRequestsCPU: resource.MustParse("1"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := Check("default", []corev1.ResourceQuota{quota, scoped}, tt.requests, tt.previous)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(BeAssignableToTypeOf(&ExceededError{}))
+			g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+			g.Expect(err.Error()).ToNot(ContainSubstring("requests.memory"))
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+	"github.com/fluxcd/helm-controller/internal/digest"
+)
+
+const (
+	// fmtApprovalPending is the message format for an upgrade which waits
+	// for approval.
+	fmtApprovalPending = "Upgrade to chart %s@%s waits for approval"
+)
+
+// awaitApproval returns nil if the next action may run with respect to the
+// approval required by the object. Only upgrades require approval.
+//
+// When the chart version and values of the Request differ from the
+// v2.PendingRollout of the object, the workloads of the upgrade are rendered
+// and published as the new PendingRollout, and any RolloutApproved condition
+// of a previous upgrade is removed. The upgrade then waits with
+// ErrWaitForApproval, until the RolloutApproved condition transitioned to
+// True after the PendingRollout was requested.
+func (r *AtomicRelease) awaitApproval(ctx context.Context, req *Request, next ActionReconciler) error {
+	if _, ok := next.(*Upgrade); !ok || !req.Object.GetUpgrade().RequireApproval {
+		return nil
+	}
+
+	chartVersion := req.Chart.Metadata.Version
+	configDigest := chartutil.DigestValues(digest.Canonical, req.Values).String()
+	pending := req.Object.Status.PendingRollout
+	if !pending.Matches(chartVersion, configDigest) {
+		// Publish the pending upgrade without workloads if they can not be
+		// rendered, as the upgrade itself reports the failure to render.
+		workloads, err := action.RenderWorkloads(ctx, r.configFactory.Build(nil), req.Object, req.Chart, req.Values)
+		if err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to render workloads of pending upgrade")
+		}
+
+		pending = &v2.PendingRollout{
+			ChartName:    req.Chart.Name(),
+			ChartVersion: chartVersion,
+			ConfigDigest: configDigest,
+			Workloads:    workloads,
+			RequestedAt:  metav1.Now().Rfc3339Copy(),
+		}
+		req.Object.Status.PendingRollout = pending
+		conditions.Delete(req.Object, v2.RolloutApprovedCondition)
+
+		r.eventRecorder.AnnotatedEventf(req.Object, eventMeta(chartVersion, configDigest),
+			corev1.EventTypeNormal, v2.ApprovalPendingReason, fmtApprovalPending, req.Chart.Name(), chartVersion)
+	}
+
+	approval := conditions.Get(req.Object, v2.RolloutApprovedCondition)
+	if approval != nil && approval.Status == metav1.ConditionTrue && !approval.LastTransitionTime.Before(&pending.RequestedAt) {
+		return nil
+	}
+
+	msg := fmt.Sprintf(fmtApprovalPending, req.Chart.Name(), chartVersion)
+	if approval != nil && approval.Status == metav1.ConditionFalse && approval.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, approval.Message)
+	}
+	markReconciling(req.Object, v2.ApprovalPendingReason, "%s", msg)
+	return ErrWaitForApproval
+}
+
+// withdrawApproval removes the v2.PendingRollout and the RolloutApproved
+// condition of the object, once no upgrade is pending.
+func withdrawApproval(obj *v2.HelmRelease) {
+	if obj.Status.PendingRollout == nil {
+		return
+	}
+	obj.Status.PendingRollout = nil
+	conditions.Delete(obj, v2.RolloutApprovedCondition)
+}
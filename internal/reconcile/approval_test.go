@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/kube"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestAtomicRelease_awaitApproval(t *testing.T) {
+	g := NewWithT(t)
+
+	cfg, err := action.NewConfigFactory(&kube.MemoryRESTClientGetter{},
+		action.WithStorage(helmdriver.MemoryDriverName, mockReleaseNamespace),
+	)
+	g.Expect(err).ToNot(HaveOccurred())
+	r := &AtomicRelease{configFactory: cfg, eventRecorder: testutil.NewFakeRecorder(10, false)}
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      mockReleaseName,
+			Namespace: mockReleaseNamespace,
+		},
+		Spec: v2.HelmReleaseSpec{
+			Upgrade: &v2.Upgrade{RequireApproval: true},
+		},
+	}
+	req := &Request{Object: obj, Chart: testutil.BuildChart(), Values: map[string]interface{}{"foo": "bar"}}
+
+	// Other actions do not require approval.
+	g.Expect(r.awaitApproval(context.TODO(), req, &Install{})).To(Succeed())
+	g.Expect(obj.Status.PendingRollout).To(BeNil())
+
+	// A stale approval of a previous upgrade is withdrawn.
+	conditions.MarkTrue(obj, v2.RolloutApprovedCondition, "Approved", "approved")
+	g.Expect(r.awaitApproval(context.TODO(), req, &Upgrade{})).To(MatchError(ErrWaitForApproval))
+	pending := obj.Status.PendingRollout
+	g.Expect(pending).ToNot(BeNil())
+	g.Expect(pending.ChartVersion).To(Equal(req.Chart.Metadata.Version))
+	g.Expect(conditions.Has(obj, v2.RolloutApprovedCondition)).To(BeFalse())
+	g.Expect(conditions.GetReason(obj, "Reconciling")).To(Equal(v2.ApprovalPendingReason))
+
+	// A rejection is reported in the message.
+	conditions.MarkFalse(obj, v2.RolloutApprovedCondition, "Rejected", "error rate too high")
+	g.Expect(r.awaitApproval(context.TODO(), req, &Upgrade{})).To(MatchError(ErrWaitForApproval))
+	g.Expect(conditions.GetMessage(obj, "Reconciling")).To(HaveSuffix(": error rate too high"))
+	g.Expect(obj.Status.PendingRollout).To(Equal(pending))
+
+	// An approval which transitioned before the request does not approve.
+	apimeta.SetStatusCondition(&obj.Status.Conditions, metav1.Condition{
+		Type:               v2.RolloutApprovedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Approved",
+		LastTransitionTime: metav1.NewTime(pending.RequestedAt.Add(-time.Minute)),
+	})
+	g.Expect(r.awaitApproval(context.TODO(), req, &Upgrade{})).To(MatchError(ErrWaitForApproval))
+
+	// An approval after the request approves the upgrade.
+	conditions.Delete(obj, v2.RolloutApprovedCondition)
+	conditions.MarkTrue(obj, v2.RolloutApprovedCondition, "Approved", "approved")
+	g.Expect(r.awaitApproval(context.TODO(), req, &Upgrade{})).To(Succeed())
+
+	// A change of the values requires a new approval.
+	req.Values = map[string]interface{}{"foo": "baz"}
+	g.Expect(r.awaitApproval(context.TODO(), req, &Upgrade{})).To(MatchError(ErrWaitForApproval))
+	g.Expect(obj.Status.PendingRollout.Matches(pending.ChartVersion, pending.ConfigDigest)).To(BeFalse())
+
+	withdrawApproval(obj)
+	g.Expect(obj.Status.PendingRollout).To(BeNil())
+	g.Expect(conditions.Has(obj, v2.RolloutApprovedCondition)).To(BeFalse())
+}
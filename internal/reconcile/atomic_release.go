@@ -23,11 +23,14 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"helm.sh/helm/v3/pkg/kube"
+	helmrelease "helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/conditions"
@@ -40,7 +43,11 @@ import (
 	"github.com/fluxcd/helm-controller/internal/diff"
 	"github.com/fluxcd/helm-controller/internal/digest"
 	interrors "github.com/fluxcd/helm-controller/internal/errors"
+	"github.com/fluxcd/helm-controller/internal/freeze"
+	"github.com/fluxcd/helm-controller/internal/interrupt"
 	"github.com/fluxcd/helm-controller/internal/postrender"
+	"github.com/fluxcd/helm-controller/internal/rollout"
+	"github.com/fluxcd/helm-controller/internal/tracing"
 )
 
 // OwnedConditions is a list of Condition types owned by the HelmRelease object.
@@ -48,6 +55,16 @@ var OwnedConditions = []string{
 	v2.ReleasedCondition,
 	v2.RemediatedCondition,
 	v2.TestSuccessCondition,
+	v2.UpgradeAvailableCondition,
+	v2.ReferencesValidCondition,
+	v2.ValuesTypesValidCondition,
+	v2.FrozenCondition,
+	v2.MigratedCondition,
+	v2.PinnedCondition,
+	v2.SuspendedCondition,
+	v2.RolloutGroupCondition,
+	v2.SecurityScanPassedCondition,
+	v2.PreflightPassedCondition,
 	meta.ReconcilingCondition,
 	meta.ReadyCondition,
 	meta.StalledCondition,
@@ -72,6 +89,27 @@ var (
 	// ErrUnknownRemediationStrategy is returned when the remediation strategy
 	// is unknown.
 	ErrUnknownRemediationStrategy = errors.New("unknown remediation strategy")
+
+	// ErrWaitForRolloutGroup is returned when the upgrade of the release must
+	// wait for other members of the rollout group of the object to complete
+	// their upgrade.
+	ErrWaitForRolloutGroup = errors.New("must wait for rollout group")
+
+	// ErrWaitForCanary is returned when the upgrade of the release must wait
+	// for the analysis of its canary release to complete.
+	ErrWaitForCanary = errors.New("must wait for canary analysis")
+
+	// ErrCanaryFailed is returned when the release is not upgraded because
+	// the analysis of its canary release failed.
+	ErrCanaryFailed = errors.New("canary analysis failed")
+
+	// ErrWaitForApproval is returned when the upgrade of the release must
+	// wait for the RolloutApproved condition of the object.
+	ErrWaitForApproval = errors.New("must wait for rollout approval")
+
+	// ErrFrozen is returned when the next action must wait for the
+	// cluster-wide change freeze to be lifted.
+	ErrFrozen = errors.New("must wait for change freeze to be lifted")
 )
 
 // AtomicRelease is an ActionReconciler which implements an atomic release
@@ -95,6 +133,49 @@ var (
 // The status conditions are summarized into a Ready condition when no actions
 // to be run remain, to ensure any transient error is cleared.
 //
+// While a cluster-wide change freeze is active (see freeze.Set), no action is
+// run. The state of the release is still determined, and the object is
+// marked with Frozen=True and ErrFrozen is returned when an action is held.
+//
+// When the object has a canary configured for upgrades, an upgrade is only
+// run after the analysis of its canary release succeeded. While the analysis
+// is in progress ErrWaitForCanary is returned, and ErrCanaryFailed after it
+// failed. For more information, refer to analyzeCanary.
+//
+// When the object has Upgrade.RequireApproval set, an upgrade is only run
+// after the RolloutApproved condition has been set to True for the pending
+// upgrade published in the status. Until then, ErrWaitForApproval is
+// returned. For more information, refer to awaitApproval.
+//
+// When configured with rollout groups using WithRolloutGroups, an upgrade of
+// a member of a rollout group is only run when the group allows it, otherwise
+// ErrWaitForRolloutGroup is returned. The member holds its slot in the group
+// until its release is ready.
+//
+// When configured with an interrupt.Tracker using WithInterrupts, the wait
+// for the resources of an install or upgrade of an object with Interruptible
+// set is stopped when the Tracker interrupts it for a newer generation of the
+// object. The action itself is never canceled, as the resources have been
+// applied and the release has been recorded before Helm waits for them. The
+// interrupted release is marked as failed by Helm, the attempt does not count
+// towards the remediation retries, and ErrMustRequeue is returned to
+// reconcile the newer generation.
+//
+// When configured with InterruptedReleaseRollback using
+// WithInterruptedReleasePolicy, a release which is unlocked from a pending
+// state left behind by an interrupted action, e.g. due to a crash of the
+// controller, is remediated right away: by rolling back to the previous
+// release, or by uninstalling it if there is none. Otherwise, it is
+// remediated according to the remediation strategy of the object.
+//
+// When the object has a Migration which has not been migrated, no action is
+// run. The existing release is observed instead, and the object is marked
+// with Migrated=False. For more information, refer to observe.
+//
+// When the context is canceled, no new actions are started and the status is
+// patched to persist the last observation. An in-flight action is allowed to
+// complete within the drain timeout configured using WithDrainTimeout.
+//
 // Any returned error other than ErrExceededMaxRetries should be retried by the
 // caller as soon as possible, preferably with a backoff strategy. In case of
 // ErrMustRequeue, it is advised to requeue the object outside the interval
@@ -113,18 +194,112 @@ type AtomicRelease struct {
 	eventRecorder record.EventRecorder
 	strategy      releaseStrategy
 	fieldManager  string
+	drainTimeout  time.Duration
+	rolloutGroups *rollout.Groups
+	interrupts    *interrupt.Tracker
+	hookReader    client.Reader
+	hookWriter    client.Writer
+
+	interruptedPolicy InterruptedReleasePolicy
+	// unlocked is set when the release has been unlocked from a pending
+	// state, and must be remediated according to the interruptedPolicy.
+	unlocked bool
+}
+
+// InterruptedReleasePolicy is the policy for the remediation of a release
+// left in a pending state by an interrupted Helm action.
+type InterruptedReleasePolicy string
+
+const (
+	// InterruptedReleaseUnlock marks the release as failed, and remediates
+	// it according to the remediation strategy of the object.
+	InterruptedReleaseUnlock InterruptedReleasePolicy = "unlock"
+	// InterruptedReleaseRollback marks the release as failed, and rolls it
+	// back to the previous release, or uninstalls it if there is none.
+	InterruptedReleaseRollback InterruptedReleasePolicy = "rollback"
+)
+
+// ParseInterruptedReleasePolicy returns the InterruptedReleasePolicy for the
+// given name, or an error if it is unknown.
+func ParseInterruptedReleasePolicy(name string) (InterruptedReleasePolicy, error) {
+	switch policy := InterruptedReleasePolicy(name); policy {
+	case InterruptedReleaseUnlock, InterruptedReleaseRollback:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid interrupted release policy '%s': must be one of '%s' or '%s'",
+			name, InterruptedReleaseUnlock, InterruptedReleaseRollback)
+	}
+}
+
+// AtomicReleaseOption is a function that configures an AtomicRelease.
+type AtomicReleaseOption func(*AtomicRelease)
+
+// WithDrainTimeout configures the duration an in-flight action is allowed to
+// continue to run after the context given to Reconcile has been canceled,
+// for example due to the controller shutting down. No new actions are
+// started once the context has been canceled.
+//
+// When not configured, or configured with a zero duration, the in-flight
+// action is canceled together with the context.
+func WithDrainTimeout(timeout time.Duration) AtomicReleaseOption {
+	return func(r *AtomicRelease) {
+		r.drainTimeout = timeout
+	}
+}
+
+// WithRolloutGroups configures the rollout.Groups which coordinate the
+// upgrades of the objects labeled with v2.RolloutGroupLabel. When not
+// configured, the label is ignored.
+func WithRolloutGroups(groups *rollout.Groups) AtomicReleaseOption {
+	return func(r *AtomicRelease) {
+		r.rolloutGroups = groups
+	}
+}
+
+// WithInterrupts configures the interrupt.Tracker which allows the install
+// or upgrade of an object with Interruptible set to be interrupted by a
+// newer generation of the object. When not configured, actions are never
+// interrupted.
+func WithInterrupts(tracker *interrupt.Tracker) AtomicReleaseOption {
+	return func(r *AtomicRelease) {
+		r.interrupts = tracker
+	}
+}
+
+// WithInterruptedReleasePolicy configures the InterruptedReleasePolicy for
+// a release left in a pending state by an interrupted action. When not
+// configured, InterruptedReleaseUnlock is used.
+func WithInterruptedReleasePolicy(policy InterruptedReleasePolicy) AtomicReleaseOption {
+	return func(r *AtomicRelease) {
+		r.interruptedPolicy = policy
+	}
+}
+
+// WithHookClient configures the client.Reader and client.Writer used to get
+// and delete the hook objects collected according to the HookCleanup of the
+// object. When not configured, a client is created for the cluster of the
+// Helm configuration on every cleanup.
+func WithHookClient(reader client.Reader, writer client.Writer) AtomicReleaseOption {
+	return func(r *AtomicRelease) {
+		r.hookReader = reader
+		r.hookWriter = writer
+	}
 }
 
 // NewAtomicRelease returns a new AtomicRelease reconciler configured with the
 // provided values.
-func NewAtomicRelease(patchHelper *patch.SerialPatcher, cfg *action.ConfigFactory, recorder record.EventRecorder, fieldManager string) *AtomicRelease {
-	return &AtomicRelease{
+func NewAtomicRelease(patchHelper *patch.SerialPatcher, cfg *action.ConfigFactory, recorder record.EventRecorder, fieldManager string, opts ...AtomicReleaseOption) *AtomicRelease {
+	r := &AtomicRelease{
 		patchHelper:   patchHelper,
 		eventRecorder: recorder,
 		configFactory: cfg,
 		strategy:      &cleanReleaseStrategy{},
 		fieldManager:  fieldManager,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // releaseStrategy defines the continue-stop behavior of the reconcile loop.
@@ -165,25 +340,48 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 		previous ReconcilerTypeSet
 		next     ActionReconciler
 	)
+
+	// Run the actions with a context which outlives the cancellation of ctx
+	// by the drain timeout. This allows an in-flight action to complete when
+	// e.g. the controller is shutting down, while the cancellation of ctx
+	// prevents any new action from being started.
+	actionCtx, cancelAction := drainContext(ctx, r.drainTimeout)
+	defer cancelAction()
+
+	// Release any slot held in a rollout group the object is no longer a
+	// member of, or after its release has become ready.
+	r.releaseRolloutGroup(req.Object)
+
+	defer func() {
+		if errors.Is(ctx.Err(), context.Canceled) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			// If the context is canceled, we still need to persist any
+			// last observation before returning. If the patch fails, we
+			// log the error and return the original error.
+			if err := r.patchHelper.Patch(ctx, req.Object, patch.WithOwnedConditions{Conditions: OwnedConditions}, patch.WithFieldOwner(r.fieldManager)); err != nil {
+				log.Error(err, "failed to patch HelmRelease after context cancellation")
+			}
+			cancel()
+		}
+	}()
+
+	// Until an existing release is migrated, only observe it.
+	if req.Object.IsObserveOnly() {
+		return r.observe(ctx, req)
+	}
+	conditions.Delete(req.Object, v2.MigratedCondition)
+
 	for {
 		select {
 		case <-ctx.Done():
-			if errors.Is(ctx.Err(), context.Canceled) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				// If the context is canceled, we still need to persist any
-				// last observation before returning. If the patch fails, we
-				// log the error and return the original context cancellation
-				// error.
-				if err := r.patchHelper.Patch(ctx, req.Object, patch.WithOwnedConditions{Conditions: OwnedConditions}, patch.WithFieldOwner(r.fieldManager)); err != nil {
-					log.Error(err, "failed to patch HelmRelease after context cancellation")
-				}
-				cancel()
-			}
 			return fmt.Errorf("atomic release canceled: %w", ctx.Err())
 		default:
 			// Determine the current state of the Helm release.
 			log.V(logger.DebugLevel).Info("determining current state of Helm release")
-			state, err := DetermineReleaseState(ctx, r.configFactory, req)
+			stateCtx, span := tracing.Tracer().Start(ctx, "determine release state")
+			state, err := DetermineReleaseState(stateCtx, r.configFactory, req)
+			span.SetAttributes(tracing.ReleaseStateKey.String(state.Status.String()))
+			tracing.EndSpan(span, err)
 			if err != nil {
 				conditions.MarkFalse(req.Object, meta.ReadyCondition, "StateError", fmt.Sprintf("Could not determine release state: %s", err.Error()))
 				return fmt.Errorf("cannot determine release state: %w", err)
@@ -193,17 +391,68 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 			log.V(logger.DebugLevel).Info("determining next Helm action based on current state")
 			if next, err = r.actionForState(ctx, req, state); err != nil {
 				if errors.Is(err, ErrExceededMaxRetries) {
-					conditions.MarkStalled(req.Object, "RetriesExceeded", "Failed to %s after %d attempt(s)",
+					markStalled(req.Object, "RetriesExceeded", "Failed to %s after %d attempt(s)",
 						req.Object.Status.LastAttemptedReleaseAction, req.Object.GetActiveRemediation().GetFailureCount(req.Object))
 					return err
 				}
 				if errors.Is(err, ErrMissingRollbackTarget) {
-					conditions.MarkStalled(req.Object, "MissingRollbackTarget", "Failed to perform remediation: %s", err.Error())
+					markStalled(req.Object, "MissingRollbackTarget", "Failed to perform remediation: %s", err.Error())
 					return err
 				}
 				return err
 			}
 
+			// If the release is pinned, refuse to run any action which
+			// would result in a new release revision or remove the
+			// release.
+			if req.Object.Spec.Pin {
+				var refused string
+				if next != nil && (next.Type() == ReconcilerTypeRelease || next.Type() == ReconcilerTypeRemediate) {
+					log.Info(fmt.Sprintf("release pinned: refusing to run '%s' action", next.Name()))
+					refused, next = next.Name(), nil
+				}
+				if next == nil {
+					markPinned(req.Object, refused)
+				}
+			} else {
+				conditions.Delete(req.Object, v2.PinnedCondition)
+			}
+
+			// During a cluster-wide change freeze, hold any action while
+			// continuing to observe the release.
+			if f := freeze.Active(time.Now()); f != nil && next != nil {
+				log.Info(fmt.Sprintf("change freeze active: holding '%s' action", next.Name()))
+				msg := fmt.Sprintf("%s, holding '%s' action", f, next.Name())
+				conditions.MarkTrue(req.Object, v2.FrozenCondition, v2.ChangeFreezeReason, "%s", msg)
+				markReconciling(req.Object, v2.ChangeFreezeReason, "%s", msg)
+				return ErrFrozen
+			}
+			conditions.Delete(req.Object, v2.FrozenCondition)
+
+			// If a canary is configured, only upgrade after the analysis of
+			// the canary release succeeded.
+			if next != nil {
+				if err = r.analyzeCanary(ctx, req, next); err != nil {
+					return err
+				}
+			}
+
+			// If approval is required, only upgrade after the pending
+			// upgrade has been approved.
+			if next != nil {
+				if err = r.awaitApproval(ctx, req, next); err != nil {
+					return err
+				}
+			}
+
+			// If the object is a member of a rollout group, only upgrade
+			// when the group allows it.
+			if next != nil && !r.acquireRolloutGroup(req.Object, next) {
+				log.Info(fmt.Sprintf("waiting for rollout group '%s' before running '%s' action",
+					v2.GetRolloutGroup(req.Object), next.Name()))
+				return ErrWaitForRolloutGroup
+			}
+
 			// If there is no next action, we are done.
 			if next == nil {
 				conditions.Delete(req.Object, meta.ReconcilingCondition)
@@ -212,12 +461,37 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 				// written to Ready.
 				summarize(req)
 
+				// Allow the next member of the rollout group to upgrade once
+				// the release is ready.
+				r.releaseRolloutGroup(req.Object)
+
+				// Prune any expired snapshots from the history.
+				pruneHistory(req.Object)
+
+				// Withdraw the approval of the completed upgrade.
+				withdrawApproval(req.Object)
+
+				// Record the objects of the current release.
+				if err := r.recordInventory(req); err != nil {
+					log.Error(err, "failed to record inventory of Helm release")
+				}
+
+				// Garbage collect the leftovers of the hooks of the release.
+				if err := r.cleanupHooks(ctx, req); err != nil {
+					log.Error(err, "failed to clean up hooks of Helm release")
+				}
+
+				// Keep the metadata of the target namespace reconciled.
+				if err := action.ApplyNamespaceMetadata(ctx, r.configFactory.Build(nil), req.Object); err != nil {
+					log.Error(err, "failed to apply metadata to target namespace")
+				}
+
 				// remove stale post-renderers digest on successful reconciliation.
 				if conditions.IsReady(req.Object) {
 					req.Object.Status.ObservedPostRenderersDigest = ""
-					if req.Object.Spec.PostRenderers != nil {
+					if postrender.HasPostRenderers(req.Object) {
 						// Update the post-renderers digest if the post-renderers exist.
-						req.Object.Status.ObservedPostRenderersDigest = postrender.Digest(digest.Canonical, req.Object.Spec.PostRenderers).String()
+						req.Object.Status.ObservedPostRenderersDigest = postrender.DigestFor(digest.Canonical, req.Object).String()
 					}
 				}
 
@@ -231,7 +505,7 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 				)
 
 				if remediation := req.Object.GetActiveRemediation(); remediation == nil || !remediation.RetriesExhausted(req.Object) {
-					conditions.MarkReconciling(req.Object, meta.ProgressingWithRetryReason, conditions.GetMessage(req.Object, meta.ReadyCondition))
+					markReconciling(req.Object, meta.ProgressingWithRetryReason, conditions.GetMessage(req.Object, meta.ReadyCondition))
 					return ErrMustRequeue
 				}
 
@@ -243,7 +517,7 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 			// This to show continuous progress, as Helm actions can be long-running.
 			reconcilingMsg := fmt.Sprintf("Running '%s' action with timeout of %s",
 				next.Name(), timeoutForAction(next, req.Object).String())
-			conditions.MarkReconciling(req.Object, meta.ProgressingReason, reconcilingMsg)
+			markReconciling(req.Object, meta.ProgressingReason, reconcilingMsg)
 
 			// If the next action is a release action, we can mark the release
 			// as progressing in terms of readiness as well. Doing this for any
@@ -258,15 +532,55 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 				return err
 			}
 
+			// Call the release action webhooks, of which the blocking ones
+			// gate the action.
+			if err = CallWebhooks(actionCtx, r.eventRecorder, next, req, v2.WebhookPhasePre, nil); err != nil {
+				conditions.MarkFalse(req.Object, meta.ReadyCondition, v2.WebhookFailedReason, "%s", err.Error())
+				return err
+			}
+
 			// Run the action sub-reconciler.
 			log.Info(fmt.Sprintf("running '%s' action with timeout of %s", next.Name(), timeoutForAction(next, req.Object).String()))
-			if err = next.Reconcile(ctx, req); err != nil {
+			runCtx, stopTracking := r.trackAction(actionCtx, req.Object, next)
+			failures := failureCounts(req.Object)
+			spanCtx, span := tracing.Tracer().Start(runCtx, next.Name(), trace.WithAttributes(
+				tracing.ReconcilerTypeKey.String(string(next.Type())),
+			))
+			err = next.Reconcile(spanCtx, req)
+			tracing.EndSpan(span, err)
+			interrupted := stopTracking()
+
+			// Prune the history of the object after it has been updated by
+			// the action.
+			pruneHistory(req.Object)
+
+			// Report the result of the action to the release action
+			// webhooks. A failed action does not necessarily return an
+			// error, but always counts as a failure.
+			actionErr := err
+			if actionErr == nil && req.Object.Status.Failures > failures.failures {
+				actionErr = errors.New(conditions.GetMessage(req.Object, meta.ReadyCondition))
+			}
+			_ = CallWebhooks(ctx, r.eventRecorder, next, req, v2.WebhookPhasePost, actionErr)
+
+			if err != nil {
 				if conditions.IsReady(req.Object) {
 					conditions.MarkFalse(req.Object, meta.ReadyCondition, "ReconcileError", err.Error())
 				}
 				return err
 			}
 
+			// If the action was interrupted by a newer generation of the
+			// object, stop to reconcile the newer generation instead of
+			// remediating the obsolete release.
+			if interrupted {
+				log.Info(fmt.Sprintf("'%s' action interrupted by a newer generation of the object", next.Name()))
+				failures.restore(req.Object)
+				markReconciling(req.Object, meta.ProgressingWithRetryReason,
+					fmt.Sprintf("'%s' action interrupted by a newer generation of the object", next.Name()))
+				return ErrMustRequeue
+			}
+
 			// If we must stop after running the action, we are done for now...
 			if r.strategy.MustStop(next.Type(), previous) {
 				log.V(logger.DebugLevel).Info(fmt.Sprintf(
@@ -275,13 +589,13 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 
 				remediation := req.Object.GetActiveRemediation()
 				if remediation == nil || !remediation.RetriesExhausted(req.Object) {
-					conditions.MarkReconciling(req.Object, meta.ProgressingWithRetryReason, conditions.GetMessage(req.Object, meta.ReadyCondition))
+					markReconciling(req.Object, meta.ProgressingWithRetryReason, conditions.GetMessage(req.Object, meta.ReadyCondition))
 					return ErrMustRequeue
 				}
 				// Check if retries have exhausted after remediation for early
 				// stall condition detection.
 				if remediation != nil && remediation.RetriesExhausted(req.Object) {
-					conditions.MarkStalled(req.Object, "RetriesExceeded", "Failed to %s after %d attempt(s)",
+					markStalled(req.Object, "RetriesExceeded", "Failed to %s after %d attempt(s)",
 						req.Object.Status.LastAttemptedReleaseAction, req.Object.GetActiveRemediation().GetFailureCount(req.Object))
 					return ErrExceededMaxRetries
 				}
@@ -293,6 +607,12 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 			// Append the type to the set of action types we have performed.
 			previous = append(previous, next.Type())
 
+			// If the context was canceled while the action was running, we
+			// are done for now. The observation is persisted before returning.
+			if ctx.Err() != nil {
+				continue
+			}
+
 			// Patch the release to reflect progress.
 			if err = r.patchHelper.Patch(ctx, req.Object, patch.WithOwnedConditions{Conditions: OwnedConditions}, patch.WithFieldOwner(r.fieldManager)); err != nil {
 				return err
@@ -301,6 +621,115 @@ func (r *AtomicRelease) Reconcile(ctx context.Context, req *Request) error {
 	}
 }
 
+// trackAction returns a copy of ctx for the next action which carries a wait
+// context tracked by the interrupt.Tracker of the AtomicRelease, if the next
+// action is an install or upgrade of an object with Interruptible set. Other
+// actions, like remediations, are never interrupted. An interruption only
+// cancels the wait context, which stops the wait for the resources of the
+// release, and never the returned context itself.
+//
+// The returned function must be called when the action completes, and
+// returns true if the action was interrupted.
+func (r *AtomicRelease) trackAction(ctx context.Context, obj *v2.HelmRelease, next ActionReconciler) (context.Context, func() bool) {
+	if r.interrupts == nil || !obj.Spec.Interruptible {
+		return ctx, func() bool { return false }
+	}
+	switch next.(type) {
+	case *Install, *Upgrade:
+		waitCtx, cancel := r.interrupts.Track(ctx, InterruptKey(obj), obj.GetGeneration())
+		return interrupt.WithWaitContext(ctx, waitCtx), func() bool {
+			interrupted := interrupt.Interrupted(waitCtx)
+			cancel()
+			return interrupted
+		}
+	default:
+		return ctx, func() bool { return false }
+	}
+}
+
+// InterruptKey returns the key of the object in an interrupt.Tracker.
+func InterruptKey(obj *v2.HelmRelease) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// releaseFailures holds the failure counts of an object.
+type releaseFailures struct {
+	failures, install, upgrade int64
+}
+
+// failureCounts returns the current failure counts of the object.
+func failureCounts(obj *v2.HelmRelease) releaseFailures {
+	return releaseFailures{
+		failures: obj.Status.Failures,
+		install:  obj.Status.InstallFailures,
+		upgrade:  obj.Status.UpgradeFailures,
+	}
+}
+
+// restore sets the failure counts of the object to the recorded counts.
+func (f releaseFailures) restore(obj *v2.HelmRelease) {
+	obj.Status.Failures = f.failures
+	obj.Status.InstallFailures = f.install
+	obj.Status.UpgradeFailures = f.upgrade
+}
+
+// markPinned marks the given object with Pinned=True. If refused is not
+// empty, the message notes the release action which was refused.
+func markPinned(obj *v2.HelmRelease, refused string) {
+	target := obj.GetReleaseNamespace() + "/" + obj.GetReleaseName()
+	if cur := obj.Status.History.Latest(); cur != nil {
+		target = cur.FullReleaseName()
+	}
+
+	msg := fmt.Sprintf("Release %s is pinned to its current revision", target)
+	if refused != "" {
+		msg += fmt.Sprintf(", refused to run '%s' action", refused)
+	}
+	conditions.MarkTrue(obj, v2.PinnedCondition, v2.ReleasePinnedReason, msg)
+}
+
+// acquireRolloutGroup returns true if the next action may run with respect
+// to the rollout group of the object. Only upgrades are coordinated, for
+// which the object is marked with the progress of the group.
+func (r *AtomicRelease) acquireRolloutGroup(obj *v2.HelmRelease, next ActionReconciler) bool {
+	group := v2.GetRolloutGroup(obj)
+	if _, ok := next.(*Upgrade); !ok || r.rolloutGroups == nil || group == "" {
+		return true
+	}
+
+	ok, others := r.rolloutGroups.TryAcquire(group, rolloutGroupMember(obj))
+	if !ok {
+		msg := fmt.Sprintf("Waiting for %s in rollout group '%s' to complete their upgrade",
+			strings.Join(others, ", "), group)
+		conditions.MarkFalse(obj, v2.RolloutGroupCondition, v2.RolloutWaitingReason, "%s", msg)
+		markReconciling(obj, v2.RolloutWaitingReason, "%s", msg)
+		return false
+	}
+
+	msg := fmt.Sprintf("Upgrading as %d of %d allowed member(s) of rollout group '%s'",
+		len(others)+1, r.rolloutGroups.Limit(), group)
+	conditions.MarkTrue(obj, v2.RolloutGroupCondition, v2.RolloutProgressingReason, "%s", msg)
+	return true
+}
+
+// releaseRolloutGroup releases the slot of the object in its rollout group
+// when its release is ready, or when it is no longer a member of a group.
+func (r *AtomicRelease) releaseRolloutGroup(obj *v2.HelmRelease) {
+	if r.rolloutGroups == nil {
+		return
+	}
+	if v2.GetRolloutGroup(obj) == "" || conditions.IsReady(obj) {
+		r.rolloutGroups.Release(rolloutGroupMember(obj))
+		conditions.Delete(obj, v2.RolloutGroupCondition)
+	}
+}
+
+// rolloutGroupMember returns the name of the object as a member of a rollout
+// group.
+func rolloutGroupMember(obj *v2.HelmRelease) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
 // actionForState determines the next action to run based on the current state.
 func (r *AtomicRelease) actionForState(ctx context.Context, req *Request, state ReleaseState) (ActionReconciler, error) {
 	log := ctrl.LoggerFrom(ctx)
@@ -346,6 +775,7 @@ func (r *AtomicRelease) actionForState(ctx context.Context, req *Request, state
 		return nil, nil
 	case ReleaseStatusLocked:
 		log.Info(msgWithReason("release locked", state.Reason))
+		r.unlocked = r.interruptedPolicy == InterruptedReleaseRollback
 		return NewUnlock(r.configFactory, r.eventRecorder), nil
 	case ReleaseStatusAbsent:
 		log.Info(msgWithReason("release not installed", state.Reason))
@@ -429,6 +859,15 @@ func (r *AtomicRelease) actionForState(ctx context.Context, req *Request, state
 	case ReleaseStatusFailed:
 		log.Info(msgWithReason("release is in a failed state", state.Reason))
 
+		// Remediate a release unlocked from an interrupted action right
+		// away, regardless of the remediation strategy.
+		if r.unlocked {
+			r.unlocked = false
+			if next := r.remediateInterrupted(ctx, req); next != nil {
+				return next, nil
+			}
+		}
+
 		remediation := req.Object.GetActiveRemediation()
 
 		// If there is no active remediation strategy, we can only attempt to
@@ -498,6 +937,84 @@ func (r *AtomicRelease) actionForState(ctx context.Context, req *Request, state
 	}
 }
 
+// remediateInterrupted returns the ActionReconciler which remediates the
+// latest release of the Request.Object after it has been unlocked from a
+// pending state: a RollbackRemediation if the previous release can be
+// verified, or an UninstallRemediation if there is no previous release.
+// It returns nil if the previous release can not be verified, to remediate
+// the release according to the remediation strategy of the object.
+func (r *AtomicRelease) remediateInterrupted(ctx context.Context, req *Request) ActionReconciler {
+	log := ctrl.LoggerFrom(ctx)
+
+	prev := req.Object.Status.History.Previous(req.Object.GetUpgrade().GetRemediation().MustIgnoreTestFailures(req.Object.GetTest().IgnoreFailures))
+	if prev == nil {
+		log.Info("uninstalling release of interrupted action: no previous release to roll back to")
+		return NewUninstallRemediation(r.configFactory, r.eventRecorder)
+	}
+	if _, err := action.VerifySnapshot(r.configFactory.Build(nil), prev); err != nil {
+		log.Info(msgWithReason("unable to verify previous release in storage to roll back interrupted action to", err.Error()))
+		return nil
+	}
+	log.Info(fmt.Sprintf("rolling back release of interrupted action to previous release %s", prev.FullReleaseName()))
+	return NewRollbackRemediation(r.configFactory, r.eventRecorder)
+}
+
+// recordInventory records the inventory of the objects of the current Helm
+// release in storage on the object. To not read the release from storage on
+// every reconciliation, the inventory is only recorded again when the digest
+// of the latest snapshot differs from the one it was recorded for. The
+// inventory is removed if there is no current release.
+func (r *AtomicRelease) recordInventory(req *Request) error {
+	cur := req.Object.Status.History.Latest()
+	if cur == nil || cur.Status == helmrelease.StatusUninstalled.String() {
+		req.Object.Status.Inventory = nil
+		return nil
+	}
+	if inv := req.Object.Status.Inventory; inv != nil && inv.ReleaseDigest == cur.Digest {
+		return nil
+	}
+
+	cfg := r.configFactory.Build(nil)
+	rls, err := action.LastRelease(cfg, req.Object.GetReleaseName())
+	if err != nil {
+		if errors.Is(err, action.ErrReleaseNotFound) {
+			req.Object.Status.Inventory = nil
+			return nil
+		}
+		return err
+	}
+	if rls.Info != nil && rls.Info.Status == helmrelease.StatusUninstalled {
+		req.Object.Status.Inventory = nil
+		return nil
+	}
+
+	inv, err := action.Inventory(cfg, rls)
+	if err != nil {
+		return err
+	}
+	if cur.Targets(rls.Name, rls.Namespace, rls.Version) {
+		inv.ReleaseDigest = cur.Digest
+	}
+	req.Object.Status.Inventory = inv
+	return nil
+}
+
+// cleanupHooks garbage collects the Jobs and Pods created by the hooks of the
+// Helm release which finished longer than the TTL of the HookCleanup of the
+// object ago, and records the remaining objects on the object. The hook
+// inventory is removed if no HookCleanup is configured.
+func (r *AtomicRelease) cleanupHooks(ctx context.Context, req *Request) error {
+	if req.Object.Spec.HookCleanup == nil {
+		req.Object.Status.HookInventory = nil
+		return nil
+	}
+
+	inv, err := action.CleanupHooks(ctx, r.configFactory.Build(nil), r.hookReader, r.hookWriter,
+		req.Object.GetReleaseName(), req.Object.Status.HookInventory, req.Object.Spec.HookCleanup.TTL.Duration)
+	req.Object.Status.HookInventory = inv
+	return err
+}
+
 func (r *AtomicRelease) Name() string {
 	return "atomic-release"
 }
@@ -522,23 +1039,43 @@ func inStringSlice(ss []string, str string) (pos int, ok bool) {
 	return -1, false
 }
 
-func timeoutForAction(action ActionReconciler, obj *v2.HelmRelease) time.Duration {
-	switch action.(type) {
+func timeoutForAction(reconciler ActionReconciler, obj *v2.HelmRelease) time.Duration {
+	switch reconciler.(type) {
 	case *Install:
-		return obj.GetInstall().GetTimeout(obj.GetTimeout()).Duration
+		return obj.GetInstall().GetTimeout(action.Timeout(obj)).Duration
 	case *Upgrade:
-		return obj.GetUpgrade().GetTimeout(obj.GetTimeout()).Duration
+		return obj.GetUpgrade().GetTimeout(action.Timeout(obj)).Duration
 	case *Test:
-		return obj.GetTest().GetTimeout(obj.GetTimeout()).Duration
+		return obj.GetTest().GetTimeout(action.Timeout(obj)).Duration
 	case *RollbackRemediation:
-		return obj.GetRollback().GetTimeout(obj.GetTimeout()).Duration
+		return obj.GetRollback().GetTimeout(action.Timeout(obj)).Duration
 	case *UninstallRemediation:
-		return obj.GetUninstall().GetTimeout(obj.GetTimeout()).Duration
+		return obj.GetUninstall().GetTimeout(action.Timeout(obj)).Duration
 	default:
-		return obj.GetTimeout().Duration
+		return action.Timeout(obj).Duration
 	}
 }
 
+// markStalled marks the object as Stalled with the given reason and message.
+// In accordance with kstatus, the Reconciling condition is removed as the
+// object can not make progress without intervention, and the Ready condition
+// is marked False if it is not already.
+func markStalled(obj *v2.HelmRelease, reason, messageFormat string, messageArgs ...interface{}) {
+	conditions.MarkStalled(obj, reason, messageFormat, messageArgs...)
+	conditions.Delete(obj, meta.ReconcilingCondition)
+	if !conditions.IsFalse(obj, meta.ReadyCondition) {
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, messageFormat, messageArgs...)
+	}
+}
+
+// markReconciling marks the object as Reconciling with the given reason and
+// message. In accordance with kstatus, any Stalled condition is removed as
+// the object is making progress.
+func markReconciling(obj *v2.HelmRelease, reason, messageFormat string, messageArgs ...interface{}) {
+	conditions.MarkReconciling(obj, reason, messageFormat, messageArgs...)
+	conditions.Delete(obj, meta.StalledCondition)
+}
+
 // replaceCondition replaces existing target condition with replacement
 // condition, if present, for the given values, retaining the
 // LastTransitionTime.
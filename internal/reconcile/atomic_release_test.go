@@ -44,9 +44,12 @@ import (
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/action"
 	"github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/freeze"
+	"github.com/fluxcd/helm-controller/internal/interrupt"
 	"github.com/fluxcd/helm-controller/internal/kube"
 	"github.com/fluxcd/helm-controller/internal/postrender"
 	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/rollout"
 	"github.com/fluxcd/helm-controller/internal/testutil"
 )
 
@@ -1837,6 +1840,94 @@ func TestAtomicRelease_actionForState(t *testing.T) {
 	}
 }
 
+func TestAtomicRelease_actionForState_interruptedRelease(t *testing.T) {
+	prev := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      mockReleaseName,
+		Namespace: mockReleaseNamespace,
+		Version:   1,
+		Status:    helmrelease.StatusSuperseded,
+		Chart:     testutil.BuildChart(),
+	})
+	cur := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      mockReleaseName,
+		Namespace: mockReleaseNamespace,
+		Version:   2,
+		Status:    helmrelease.StatusFailed,
+		Chart:     testutil.BuildChart(),
+	})
+
+	tests := []struct {
+		name     string
+		policy   InterruptedReleasePolicy
+		releases []*helmrelease.Release
+		want     ActionReconciler
+	}{
+		{
+			name:     "unlock policy remediates according to the object",
+			policy:   InterruptedReleaseUnlock,
+			releases: []*helmrelease.Release{prev, cur},
+			want:     &Upgrade{},
+		},
+		{
+			name:     "rollback policy rolls back to previous release",
+			policy:   InterruptedReleaseRollback,
+			releases: []*helmrelease.Release{prev, cur},
+			want:     &RollbackRemediation{},
+		},
+		{
+			name:     "rollback policy uninstalls release without previous release",
+			policy:   InterruptedReleaseRollback,
+			releases: []*helmrelease.Release{cur},
+			want:     &UninstallRemediation{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			obj := &v2.HelmRelease{
+				Spec: v2.HelmReleaseSpec{
+					ReleaseName:      mockReleaseName,
+					TargetNamespace:  mockReleaseNamespace,
+					StorageNamespace: mockReleaseNamespace,
+				},
+			}
+			for i := len(tt.releases) - 1; i >= 0; i-- {
+				obj.Status.History = append(obj.Status.History, release.ObservedToSnapshot(release.ObserveRelease(tt.releases[i])))
+			}
+
+			cfg, err := action.NewConfigFactory(&kube.MemoryRESTClientGetter{},
+				action.WithStorage(helmdriver.MemoryDriverName, mockReleaseNamespace),
+			)
+			g.Expect(err).ToNot(HaveOccurred())
+			store := helmstorage.Init(cfg.Driver)
+			for _, rls := range tt.releases {
+				g.Expect(store.Create(rls)).To(Succeed())
+			}
+
+			r := &AtomicRelease{configFactory: cfg, eventRecorder: testutil.NewFakeRecorder(1, false), interruptedPolicy: tt.policy}
+			got, err := r.actionForState(context.TODO(), &Request{Object: obj}, ReleaseState{Status: ReleaseStatusLocked})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(BeAssignableToTypeOf(&Unlock{}))
+
+			got, err = r.actionForState(context.TODO(), &Request{Object: obj}, ReleaseState{Status: ReleaseStatusFailed})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(BeAssignableToTypeOf(tt.want))
+		})
+	}
+}
+
+func TestParseInterruptedReleasePolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	policy, err := ParseInterruptedReleasePolicy("rollback")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(policy).To(Equal(InterruptedReleaseRollback))
+
+	_, err = ParseInterruptedReleasePolicy("retry")
+	g.Expect(err).To(MatchError(ContainSubstring("invalid interrupted release policy 'retry'")))
+}
+
 func Test_replaceCondition(t *testing.T) {
 	g := NewWithT(t)
 	timestamp, err := time.Parse(time.UnixDate, "Wed Feb 25 11:06:39 GMT 2015")
@@ -1950,3 +2041,325 @@ func Test_replaceCondition(t *testing.T) {
 		})
 	}
 }
+
+func Test_markStalled(t *testing.T) {
+	t.Run("removes reconciling and marks not ready", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{}
+		conditions.MarkReconciling(obj, meta.ProgressingReason, "Running 'upgrade' action")
+		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "Running 'upgrade' action")
+
+		markStalled(obj, "RetriesExceeded", "Failed to %s after %d attempt(s)", "upgrade", 3)
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(meta.StalledCondition, "RetriesExceeded", "Failed to upgrade after 3 attempt(s)"),
+			*conditions.FalseCondition(meta.ReadyCondition, "RetriesExceeded", "Failed to upgrade after 3 attempt(s)"),
+		}))
+	})
+
+	t.Run("retains failed ready condition", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{}
+		conditions.MarkFalse(obj, meta.ReadyCondition, v2.UpgradeFailedReason, "upgrade failed")
+
+		markStalled(obj, "RetriesExceeded", "Failed to upgrade after 3 attempt(s)")
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(meta.StalledCondition, "RetriesExceeded", "Failed to upgrade after 3 attempt(s)"),
+			*conditions.FalseCondition(meta.ReadyCondition, v2.UpgradeFailedReason, "upgrade failed"),
+		}))
+	})
+}
+
+func Test_markReconciling(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v2.HelmRelease{}
+	conditions.MarkStalled(obj, "RetriesExceeded", "Failed to upgrade after 3 attempt(s)")
+
+	markReconciling(obj, meta.ProgressingReason, "Running '%s' action", "upgrade")
+	g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+		*conditions.TrueCondition(meta.ReconcilingCondition, meta.ProgressingReason, "Running 'upgrade' action"),
+	}))
+}
+
+func Test_markPinned(t *testing.T) {
+	t.Run("without current release", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+		}
+
+		markPinned(obj, "install")
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(v2.PinnedCondition, v2.ReleasePinnedReason,
+				"Release apps/podinfo is pinned to its current revision, refused to run 'install' action"),
+		}))
+	})
+
+	t.Run("with current release", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+			Status: v2.HelmReleaseStatus{
+				History: v2.Snapshots{
+					{Name: "podinfo", Namespace: "apps", Version: 3},
+				},
+			},
+		}
+		markPinned(obj, "upgrade")
+
+		markPinned(obj, "")
+		g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(v2.PinnedCondition, v2.ReleasePinnedReason,
+				"Release apps/podinfo.v3 is pinned to its current revision"),
+		}))
+	})
+}
+
+func TestAtomicRelease_rolloutGroup(t *testing.T) {
+	newObj := func(name string) *v2.HelmRelease {
+		return &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "apps",
+				Labels:    map[string]string{v2.RolloutGroupLabel: "regions"},
+			},
+		}
+	}
+
+	t.Run("waits for active members", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &AtomicRelease{rolloutGroups: rollout.NewGroups(1)}
+		eu, us := newObj("eu"), newObj("us")
+
+		g.Expect(r.acquireRolloutGroup(eu, &Upgrade{})).To(BeTrue())
+		g.Expect(eu.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.TrueCondition(v2.RolloutGroupCondition, v2.RolloutProgressingReason,
+				"Upgrading as 1 of 1 allowed member(s) of rollout group 'regions'"),
+		}))
+
+		g.Expect(r.acquireRolloutGroup(us, &Upgrade{})).To(BeFalse())
+		g.Expect(us.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+			*conditions.FalseCondition(v2.RolloutGroupCondition, v2.RolloutWaitingReason,
+				"Waiting for apps/eu in rollout group 'regions' to complete their upgrade"),
+			*conditions.TrueCondition(meta.ReconcilingCondition, v2.RolloutWaitingReason,
+				"Waiting for apps/eu in rollout group 'regions' to complete their upgrade"),
+		}))
+
+		// Other actions are not coordinated.
+		g.Expect(r.acquireRolloutGroup(us, &Install{})).To(BeTrue())
+	})
+
+	t.Run("holds slot until ready", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &AtomicRelease{rolloutGroups: rollout.NewGroups(1)}
+		eu, us := newObj("eu"), newObj("us")
+
+		g.Expect(r.acquireRolloutGroup(eu, &Upgrade{})).To(BeTrue())
+
+		conditions.MarkFalse(eu, meta.ReadyCondition, v2.UpgradeFailedReason, "upgrade failed")
+		r.releaseRolloutGroup(eu)
+		g.Expect(conditions.Has(eu, v2.RolloutGroupCondition)).To(BeTrue())
+		g.Expect(r.acquireRolloutGroup(us, &Upgrade{})).To(BeFalse())
+
+		conditions.MarkTrue(eu, meta.ReadyCondition, v2.UpgradeSucceededReason, "upgrade succeeded")
+		r.releaseRolloutGroup(eu)
+		g.Expect(conditions.Has(eu, v2.RolloutGroupCondition)).To(BeFalse())
+		g.Expect(r.acquireRolloutGroup(us, &Upgrade{})).To(BeTrue())
+	})
+
+	t.Run("releases slot when leaving group", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &AtomicRelease{rolloutGroups: rollout.NewGroups(1)}
+		eu, us := newObj("eu"), newObj("us")
+
+		g.Expect(r.acquireRolloutGroup(eu, &Upgrade{})).To(BeTrue())
+		eu.Labels = nil
+		r.releaseRolloutGroup(eu)
+		g.Expect(r.acquireRolloutGroup(us, &Upgrade{})).To(BeTrue())
+	})
+
+	t.Run("ignores label without rollout groups", func(t *testing.T) {
+		g := NewWithT(t)
+
+		r := &AtomicRelease{}
+		obj := newObj("eu")
+		g.Expect(r.acquireRolloutGroup(obj, &Upgrade{})).To(BeTrue())
+		g.Expect(obj.Status.Conditions).To(BeEmpty())
+	})
+}
+
+func TestAtomicRelease_recordInventory(t *testing.T) {
+	newObj := func(status helmrelease.Status, inv *v2.ResourceInventory) *v2.HelmRelease {
+		return &v2.HelmRelease{
+			Spec: v2.HelmReleaseSpec{
+				ReleaseName:      mockReleaseName,
+				TargetNamespace:  mockReleaseNamespace,
+				StorageNamespace: mockReleaseNamespace,
+			},
+			Status: v2.HelmReleaseStatus{
+				History: v2.Snapshots{{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+					Version:   1,
+					Status:    status.String(),
+					Digest:    "sha256:1111",
+				}},
+				Inventory: inv,
+			},
+		}
+	}
+
+	cfg, err := action.NewConfigFactory(&kube.MemoryRESTClientGetter{},
+		action.WithStorage(helmdriver.MemoryDriverName, mockReleaseNamespace),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &AtomicRelease{configFactory: cfg}
+
+	t.Run("keeps inventory of unchanged release", func(t *testing.T) {
+		g := NewWithT(t)
+
+		inv := &v2.ResourceInventory{
+			Entries:       []v2.ResourceRef{{ID: "default_podinfo_apps_Deployment", Version: "v1"}},
+			ReleaseDigest: "sha256:1111",
+		}
+		obj := newObj(helmrelease.StatusDeployed, inv.DeepCopy())
+		g.Expect(r.recordInventory(&Request{Object: obj})).To(Succeed())
+		g.Expect(obj.Status.Inventory).To(Equal(inv))
+	})
+
+	t.Run("reads changed release from storage", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := newObj(helmrelease.StatusDeployed, &v2.ResourceInventory{
+			Entries:       []v2.ResourceRef{{ID: "default_podinfo_apps_Deployment", Version: "v1"}},
+			ReleaseDigest: "sha256:0000",
+		})
+		g.Expect(r.recordInventory(&Request{Object: obj})).To(Succeed())
+		g.Expect(obj.Status.Inventory).To(BeNil())
+	})
+
+	t.Run("removes inventory of uninstalled release", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := newObj(helmrelease.StatusUninstalled, &v2.ResourceInventory{ReleaseDigest: "sha256:1111"})
+		g.Expect(r.recordInventory(&Request{Object: obj})).To(Succeed())
+		g.Expect(obj.Status.Inventory).To(BeNil())
+	})
+}
+
+func TestAtomicRelease_trackAction(t *testing.T) {
+	newObj := func(interruptible bool) *v2.HelmRelease {
+		return &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "podinfo",
+				Namespace:  "apps",
+				Generation: 2,
+			},
+			Spec: v2.HelmReleaseSpec{
+				Interruptible: interruptible,
+			},
+		}
+	}
+
+	t.Run("tracks install and upgrade", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := interrupt.NewTracker()
+		r := &AtomicRelease{interrupts: tracker}
+		obj := newObj(true)
+
+		for _, next := range []ActionReconciler{&Install{}, &Upgrade{}} {
+			ctx, done := r.trackAction(context.TODO(), obj, next)
+			waitCtx, ok := interrupt.WaitContext(ctx)
+			g.Expect(ok).To(BeTrue())
+			g.Expect(tracker.Interrupt(InterruptKey(obj), 3)).To(BeTrue())
+			g.Expect(interrupt.Interrupted(waitCtx)).To(BeTrue())
+			// Only the wait is interrupted, never the action itself.
+			g.Expect(ctx.Err()).ToNot(HaveOccurred())
+			g.Expect(done()).To(BeTrue())
+		}
+	})
+
+	t.Run("does not track other actions", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := interrupt.NewTracker()
+		r := &AtomicRelease{interrupts: tracker}
+		obj := newObj(true)
+
+		for _, next := range []ActionReconciler{&RollbackRemediation{}, &UninstallRemediation{}, &Test{}, &Unlock{}} {
+			ctx, done := r.trackAction(context.TODO(), obj, next)
+			_, ok := interrupt.WaitContext(ctx)
+			g.Expect(ok).To(BeFalse())
+			g.Expect(tracker.Interrupt(InterruptKey(obj), 3)).To(BeFalse())
+			g.Expect(done()).To(BeFalse())
+		}
+	})
+
+	t.Run("does not track without interruptible", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := interrupt.NewTracker()
+		r := &AtomicRelease{interrupts: tracker}
+		obj := newObj(false)
+
+		ctx, done := r.trackAction(context.TODO(), obj, &Upgrade{})
+		_, ok := interrupt.WaitContext(ctx)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(tracker.Interrupt(InterruptKey(obj), 3)).To(BeFalse())
+		g.Expect(done()).To(BeFalse())
+	})
+
+	t.Run("restores failure counts", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := newObj(true)
+		obj.Status.Failures = 1
+		obj.Status.UpgradeFailures = 1
+
+		failures := failureCounts(obj)
+		obj.Status.Failures++
+		obj.Status.UpgradeFailures++
+		failures.restore(obj)
+		g.Expect(obj.Status.Failures).To(Equal(int64(1)))
+		g.Expect(obj.Status.UpgradeFailures).To(Equal(int64(1)))
+		g.Expect(obj.Status.InstallFailures).To(BeZero())
+	})
+}
+
+func TestAtomicRelease_Reconcile_freeze(t *testing.T) {
+	g := NewWithT(t)
+	t.Cleanup(func() { freeze.Set(nil) })
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      mockReleaseName,
+			Namespace: mockReleaseNamespace,
+		},
+	}
+	cfg, err := action.NewConfigFactory(&kube.MemoryRESTClientGetter{},
+		action.WithStorage(helmdriver.MemoryDriverName, mockReleaseNamespace),
+	)
+	g.Expect(err).ToNot(HaveOccurred())
+	req := &Request{Object: obj, Chart: testutil.BuildChart()}
+
+	freeze.Set(&freeze.Freeze{Reason: "cluster upgrade"})
+	r := NewAtomicRelease(nil, cfg, record.NewFakeRecorder(10), testFieldManager)
+	g.Expect(r.Reconcile(context.TODO(), req)).To(MatchError(ErrFrozen))
+
+	msg := "Cluster change freeze is active: cluster upgrade, holding 'install' action"
+	g.Expect(obj.Status.Conditions).To(conditions.MatchConditions([]metav1.Condition{
+		*conditions.TrueCondition(v2.FrozenCondition, v2.ChangeFreezeReason, msg),
+		*conditions.TrueCondition(meta.ReconcilingCondition, v2.ChangeFreezeReason, msg),
+	}))
+	g.Expect(obj.Status.History).To(BeEmpty())
+}
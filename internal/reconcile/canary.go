@@ -0,0 +1,273 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/runtime/patch"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+	"github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+const (
+	// canaryReleaseSuffix is appended to the name of the release to compose
+	// the name of its canary release.
+	canaryReleaseSuffix = "-canary"
+
+	// fmtCanaryAnalysis is the message format for a canary release which is
+	// being analyzed.
+	fmtCanaryAnalysis = "Analyzing canary release %s/%s with chart %s@%s: ready for %s of %s"
+	// fmtCanarySuccess is the message format for a canary release which
+	// remained ready for the analysis window.
+	fmtCanarySuccess = "Canary release %s/%s with chart %s@%s remained ready for %s"
+	// fmtCanaryReleaseFailure is the message format for a canary release
+	// which failed to be released.
+	fmtCanaryReleaseFailure = "Canary release %s/%s with chart %s@%s failed: %s"
+	// fmtCanaryNotReady is the message format for a canary release of which
+	// objects became not ready during the analysis.
+	fmtCanaryNotReady = "Canary release %s/%s with chart %s@%s failed analysis: not ready: %s"
+)
+
+var (
+	// canaryOfNameLabel and canaryOfNamespaceLabel are the labels of a
+	// canary release, set to the name and namespace of the object which
+	// installed it. A release without them is never uninstalled as a
+	// canary release.
+	canaryOfNameLabel      = v2.GroupVersion.Group + "/canary-of-name"
+	canaryOfNamespaceLabel = v2.GroupVersion.Group + "/canary-of-namespace"
+)
+
+// analyzeCanary returns nil if the next action may run with respect to the
+// canary analysis configured on the object. Only upgrades are analyzed.
+//
+// When the object has not been analyzed for the chart version and values of
+// the Request, the chart is released as a canary release to the target
+// namespace of the canary configuration. The analysis then checks the canary
+// release to be ready on every reconciliation, and returns
+// ErrWaitForCanary until it has been ready for the analysis window. After
+// this, the canary release is uninstalled and the upgrade may run.
+//
+// When the canary release fails or becomes not ready, it is uninstalled and
+// ErrCanaryFailed is returned until the chart version or values change.
+func (r *AtomicRelease) analyzeCanary(ctx context.Context, req *Request, next ActionReconciler) error {
+	canary := req.Object.GetUpgrade().Canary
+	if _, ok := next.(*Upgrade); !ok || canary == nil {
+		return nil
+	}
+
+	chartVersion := req.Chart.Metadata.Version
+	configDigest := chartutil.DigestValues(digest.Canonical, req.Values).String()
+	if !req.Object.Status.Canary.Matches(chartVersion, configDigest) {
+		// Mark the object as reconciling before the canary release is
+		// made, as it waits for the release to become ready.
+		markReconciling(req.Object, v2.CanaryAnalysisReason, "Releasing canary with chart %s@%s to namespace %s",
+			req.Chart.Name(), chartVersion, canary.TargetNamespace)
+		if err := r.patchHelper.Patch(ctx, req.Object, patch.WithOwnedConditions{Conditions: OwnedConditions}, patch.WithFieldOwner(r.fieldManager)); err != nil {
+			return err
+		}
+		r.releaseCanary(ctx, req, canary.TargetNamespace, chartVersion, configDigest)
+	}
+
+	status := req.Object.Status.Canary
+	if status.Phase == v2.CanaryPhaseAnalyzing {
+		logBuf := newLogBuffer(ctx, req.Object)
+		cfg := r.canaryConfig(logBuf, status.Namespace)
+
+		rls, err := cfg.Releases.Last(status.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get canary release: %w", err)
+		}
+		notReady, err := action.NotReady(ctx, cfg, rls)
+		if err != nil {
+			return fmt.Errorf("failed to analyze canary release: %w", err)
+		}
+
+		window := canary.GetWindow().Duration
+		elapsed := time.Since(status.StartedAt.Time).Round(time.Second)
+		switch {
+		case len(notReady) > 0:
+			r.failCanary(ctx, req, cfg, nil, fmt.Sprintf(fmtCanaryNotReady, status.Namespace, status.Name,
+				req.Chart.Name(), chartVersion, strings.Join(notReady, ", ")))
+		case elapsed < window:
+			markReconciling(req.Object, v2.CanaryAnalysisReason, fmtCanaryAnalysis, status.Namespace, status.Name,
+				req.Chart.Name(), chartVersion, elapsed, window)
+			return ErrWaitForCanary
+		default:
+			if err := removeCanary(ctx, cfg, req.Object, status.Name, status.Namespace); err != nil {
+				return fmt.Errorf("failed to uninstall canary release after analysis: %w", err)
+			}
+			status.Phase = v2.CanaryPhaseSucceeded
+			status.Message = fmt.Sprintf(fmtCanarySuccess, status.Namespace, status.Name,
+				req.Chart.Name(), chartVersion, window)
+			r.eventRecorder.AnnotatedEventf(req.Object, eventMeta(chartVersion, configDigest),
+				corev1.EventTypeNormal, v2.CanarySucceededReason, status.Message)
+		}
+	}
+
+	if status.Phase == v2.CanaryPhaseFailed {
+		markStalled(req.Object, v2.CanaryFailedReason, "%s", status.Message)
+		return ErrCanaryFailed
+	}
+	return nil
+}
+
+// releaseCanary installs the chart and values of the Request as the canary
+// release of the object to the given namespace, replacing any previous
+// canary release. It records the v2.CanaryStatus on the object, with the
+// analysis started or failed.
+func (r *AtomicRelease) releaseCanary(ctx context.Context, req *Request, namespace, chartVersion, configDigest string) {
+	name := release.ShortenName(req.Object.GetReleaseName() + canaryReleaseSuffix)
+	logBuf := newLogBuffer(ctx, req.Object)
+	cfg := r.canaryConfig(logBuf, namespace)
+
+	req.Object.Status.Canary = &v2.CanaryStatus{
+		Name:         name,
+		Namespace:    namespace,
+		ChartVersion: chartVersion,
+		ConfigDigest: configDigest,
+		Phase:        v2.CanaryPhaseAnalyzing,
+		StartedAt:    metav1.Now(),
+	}
+
+	err := removeCanary(ctx, cfg, req.Object, name, namespace)
+	if err == nil {
+		_, err = action.Install(ctx, cfg, canaryObject(req.Object, name, namespace), req.Chart, req.Values,
+			func(install *helmaction.Install) {
+				install.Labels = canaryLabels(req.Object)
+			})
+	}
+	if err != nil {
+		r.failCanary(ctx, req, cfg, logBuf, fmt.Sprintf(fmtCanaryReleaseFailure, namespace, name,
+			req.Chart.Name(), chartVersion, strings.TrimSpace(err.Error())))
+		return
+	}
+	// Start the analysis window once the canary release is ready.
+	req.Object.Status.Canary.StartedAt = metav1.Now()
+}
+
+// failCanary marks the canary analysis of the object as failed with the given
+// message, and uninstalls the canary release. The warning event includes the
+// logs of the given buffer, if any.
+func (r *AtomicRelease) failCanary(ctx context.Context, req *Request, cfg *helmaction.Configuration,
+	logBuf *action.LogBuffer, msg string) {
+	status := req.Object.Status.Canary
+	status.Phase = v2.CanaryPhaseFailed
+	status.Message = msg
+
+	if err := removeCanary(ctx, cfg, req.Object, status.Name, status.Namespace); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to uninstall failed canary release")
+	}
+
+	r.eventRecorder.AnnotatedEventf(req.Object, eventMeta(status.ChartVersion, status.ConfigDigest),
+		corev1.EventTypeWarning, v2.CanaryFailedReason, eventMessageWithLog(msg, logBuf))
+}
+
+// canaryConfig returns a Helm action configuration for the canary release in
+// the given namespace.
+func (r *AtomicRelease) canaryConfig(logBuf *action.LogBuffer, namespace string) *helmaction.Configuration {
+	// As a logger is provided, Build returns a new Kubernetes client which
+	// can safely be configured with the namespace of the canary release.
+	cfg := r.configFactory.Build(logBuf.Log)
+	if client, ok := cfg.KubeClient.(*helmkube.Client); ok {
+		client.Namespace = namespace
+	}
+	return cfg
+}
+
+// RemoveCanary uninstalls the canary release recorded in the status of the
+// given object, if any. It is intended to clean up the canary release of an
+// analysis in progress when the release of the object is uninstalled.
+func RemoveCanary(ctx context.Context, cfg *action.ConfigFactory, obj *v2.HelmRelease) error {
+	status := obj.Status.Canary
+	if status == nil || status.Phase != v2.CanaryPhaseAnalyzing {
+		return nil
+	}
+
+	config := cfg.Build(newLogBuffer(ctx, obj).Log)
+	if client, ok := config.KubeClient.(*helmkube.Client); ok {
+		client.Namespace = status.Namespace
+	}
+	if err := removeCanary(ctx, config, obj, status.Name, status.Namespace); err != nil {
+		return err
+	}
+	obj.Status.Canary = nil
+	return nil
+}
+
+// removeCanary uninstalls the canary release with the given name and
+// namespace, if it exists. It refuses to uninstall a release which is not
+// labeled as the canary release of the object.
+func removeCanary(ctx context.Context, cfg *helmaction.Configuration, obj *v2.HelmRelease, name, namespace string) error {
+	rls, err := cfg.Releases.Last(name)
+	if err != nil {
+		if errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !isCanaryOf(rls.Labels, obj) {
+		return fmt.Errorf("refusing to uninstall release %s/%s: not a canary release of %s/%s",
+			namespace, name, obj.GetNamespace(), obj.GetName())
+	}
+	_, err = action.Uninstall(ctx, cfg, canaryObject(obj, name, namespace), name)
+	return err
+}
+
+// canaryLabels returns the labels of the canary release of the given object.
+func canaryLabels(obj *v2.HelmRelease) map[string]string {
+	return map[string]string{
+		canaryOfNameLabel:      obj.GetName(),
+		canaryOfNamespaceLabel: obj.GetNamespace(),
+	}
+}
+
+// isCanaryOf returns if the given release labels mark the release as the
+// canary release of the given object.
+func isCanaryOf(labels map[string]string, obj *v2.HelmRelease) bool {
+	return labels[canaryOfNameLabel] == obj.GetName() && labels[canaryOfNamespaceLabel] == obj.GetNamespace()
+}
+
+// canaryObject returns a copy of the given object which targets the canary
+// release with the given name and namespace, to configure the Helm actions
+// for the canary release.
+func canaryObject(obj *v2.HelmRelease, name, namespace string) *v2.HelmRelease {
+	canary := obj.DeepCopy()
+	canary.Spec.ReleaseName = name
+	canary.Spec.TargetNamespace = namespace
+	// Do not keep the history of canary releases, which would prevent the
+	// next canary release from being installed.
+	if canary.Spec.Uninstall != nil {
+		canary.Spec.Uninstall.KeepHistory = false
+	}
+	return canary
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/runtime/patch"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestAtomicRelease_analyzeCanary(t *testing.T) {
+	tests := []struct {
+		name        string
+		chart       *helmchart.Chart
+		window      time.Duration
+		next        ActionReconciler
+		wantErr     error
+		wantPhase   v2.CanaryPhase
+		wantCanary  bool
+		wantReason  string
+		wantStalled bool
+	}{
+		{
+			name:       "waits for analysis window",
+			chart:      testutil.BuildChart(),
+			window:     time.Hour,
+			next:       &Upgrade{},
+			wantErr:    ErrWaitForCanary,
+			wantPhase:  v2.CanaryPhaseAnalyzing,
+			wantCanary: true,
+			wantReason: v2.CanaryAnalysisReason,
+		},
+		{
+			name:      "succeeds after analysis window",
+			chart:     testutil.BuildChart(),
+			next:      &Upgrade{},
+			wantPhase: v2.CanaryPhaseSucceeded,
+		},
+		{
+			name:        "fails when canary release fails",
+			chart:       testutil.BuildChart(testutil.ChartWithFailingHook()),
+			next:        &Upgrade{},
+			wantErr:     ErrCanaryFailed,
+			wantPhase:   v2.CanaryPhaseFailed,
+			wantReason:  v2.CanaryFailedReason,
+			wantStalled: true,
+		},
+		{
+			name:  "ignores other actions",
+			chart: testutil.BuildChart(),
+			next:  &Install{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			namedNS, err := testEnv.CreateNamespace(context.TODO(), mockReleaseNamespace)
+			g.Expect(err).NotTo(HaveOccurred())
+			t.Cleanup(func() {
+				_ = testEnv.Delete(context.TODO(), namedNS)
+			})
+			canaryNS, err := testEnv.CreateNamespace(context.TODO(), "canary")
+			g.Expect(err).NotTo(HaveOccurred())
+			t.Cleanup(func() {
+				_ = testEnv.Delete(context.TODO(), canaryNS)
+			})
+			releaseNamespace := namedNS.Name
+
+			obj := &v2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      mockReleaseName,
+					Namespace: releaseNamespace,
+				},
+				Spec: v2.HelmReleaseSpec{
+					ReleaseName:      mockReleaseName,
+					TargetNamespace:  releaseNamespace,
+					StorageNamespace: releaseNamespace,
+					Timeout:          &metav1.Duration{Duration: 100 * time.Millisecond},
+					Upgrade: &v2.Upgrade{
+						Canary: &v2.UpgradeCanary{
+							TargetNamespace: canaryNS.Name,
+							Window:          &metav1.Duration{Duration: tt.window},
+						},
+					},
+				},
+			}
+
+			getter, err := RESTClientGetterFromManager(testEnv.Manager, obj.GetReleaseNamespace())
+			g.Expect(err).ToNot(HaveOccurred())
+
+			cfg, err := action.NewConfigFactory(getter,
+				action.WithStorage(action.DefaultStorageDriver, obj.GetStorageNamespace()),
+			)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			client := fake.NewClientBuilder().
+				WithScheme(testEnv.Scheme()).
+				WithObjects(obj).
+				WithStatusSubresource(&v2.HelmRelease{}).
+				Build()
+			recorder := new(record.FakeRecorder)
+
+			r := NewAtomicRelease(patch.NewSerialPatcher(obj, client), cfg, recorder, testFieldManager)
+			err = r.analyzeCanary(context.TODO(), &Request{Object: obj, Chart: tt.chart}, tt.next)
+			if tt.wantErr != nil {
+				g.Expect(err).To(MatchError(tt.wantErr))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+
+			if tt.wantPhase == "" {
+				g.Expect(obj.Status.Canary).To(BeNil())
+				return
+			}
+			g.Expect(obj.Status.Canary).ToNot(BeNil())
+			g.Expect(obj.Status.Canary.Phase).To(Equal(tt.wantPhase))
+			g.Expect(obj.Status.Canary.Name).To(Equal(mockReleaseName + canaryReleaseSuffix))
+			g.Expect(obj.Status.Canary.Namespace).To(Equal(canaryNS.Name))
+			g.Expect(obj.Status.Canary.ChartVersion).To(Equal(tt.chart.Metadata.Version))
+
+			if tt.wantReason != "" {
+				g.Expect(conditions.GetReason(obj, meta.ReconcilingCondition) == tt.wantReason ||
+					conditions.GetReason(obj, meta.StalledCondition) == tt.wantReason).To(BeTrue())
+			}
+			g.Expect(conditions.IsStalled(obj)).To(Equal(tt.wantStalled))
+
+			// The canary release only remains during the analysis.
+			_, err = helmstorage.Init(cfg.Driver).Last(obj.Status.Canary.Name)
+			if tt.wantCanary {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(HaveOccurred())
+			}
+		})
+	}
+}
+
+func Test_canaryObject(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+		Spec: v2.HelmReleaseSpec{
+			TargetNamespace: "production",
+			Uninstall:       &v2.Uninstall{KeepHistory: true},
+		},
+	}
+
+	got := canaryObject(obj, "production-podinfo-canary", "canary")
+	g.Expect(got.GetReleaseName()).To(Equal("production-podinfo-canary"))
+	g.Expect(got.GetReleaseNamespace()).To(Equal("canary"))
+	g.Expect(got.GetUninstall().KeepHistory).To(BeFalse())
+
+	// The object itself is not modified.
+	g.Expect(obj.GetReleaseName()).To(Equal("production-podinfo"))
+	g.Expect(obj.GetUninstall().KeepHistory).To(BeTrue())
+}
+
+func Test_removeCanary(t *testing.T) {
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+	}
+
+	t.Run("refuses to uninstall release without canary labels", func(t *testing.T) {
+		g := NewWithT(t)
+
+		rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+			Name:      "podinfo-canary",
+			Namespace: "canary",
+			Version:   1,
+			Chart:     testutil.BuildChart(),
+			Status:    helmrelease.StatusDeployed,
+		})
+		cfg := &helmaction.Configuration{Releases: helmstorage.Init(helmdriver.NewMemory())}
+		g.Expect(cfg.Releases.Create(rls)).To(Succeed())
+
+		err := removeCanary(context.TODO(), cfg, obj, rls.Name, rls.Namespace)
+		g.Expect(err).To(MatchError(ContainSubstring("not a canary release of apps/podinfo")))
+
+		_, err = cfg.Releases.Last(rls.Name)
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("refuses to uninstall canary release of other object", func(t *testing.T) {
+		g := NewWithT(t)
+
+		rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+			Name:      "podinfo-canary",
+			Namespace: "canary",
+			Version:   1,
+			Chart:     testutil.BuildChart(),
+			Status:    helmrelease.StatusDeployed,
+		})
+		rls.Labels = canaryLabels(&v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "other"},
+		})
+		cfg := &helmaction.Configuration{Releases: helmstorage.Init(helmdriver.NewMemory())}
+		g.Expect(cfg.Releases.Create(rls)).To(Succeed())
+
+		err := removeCanary(context.TODO(), cfg, obj, rls.Name, rls.Namespace)
+		g.Expect(err).To(HaveOccurred())
+
+		_, err = cfg.Releases.Last(rls.Name)
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("ignores missing release", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cfg := &helmaction.Configuration{Releases: helmstorage.Init(helmdriver.NewMemory())}
+		g.Expect(removeCanary(context.TODO(), cfg, obj, "podinfo-canary", "canary")).To(Succeed())
+	})
+}
+
+func Test_isCanaryOf(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+	}
+	g.Expect(isCanaryOf(canaryLabels(obj), obj)).To(BeTrue())
+	g.Expect(isCanaryOf(nil, obj)).To(BeFalse())
+	g.Expect(isCanaryOf(map[string]string{canaryOfNameLabel: "podinfo"}, obj)).To(BeFalse())
+}
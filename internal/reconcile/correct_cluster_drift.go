@@ -63,7 +63,7 @@ func (r *CorrectClusterDrift) Reconcile(ctx context.Context, req *Request) error
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, req.Object.GetTimeout().Duration)
+	ctx, cancel := context.WithTimeout(ctx, action.Timeout(req.Object).Duration)
 	defer cancel()
 
 	// Update condition to reflect the current status.
@@ -105,11 +105,11 @@ func (r *CorrectClusterDrift) report(obj *v2.HelmRelease, changeSet *ssa.ChangeS
 		}
 
 		r.eventRecorder.AnnotatedEventf(obj, eventMeta(cur.ChartVersion, cur.ConfigDigest,
-			addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)), corev1.EventTypeWarning,
+			addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)), corev1.EventTypeWarning,
 			"DriftCorrectionFailed", sb.String())
 	case changeSet != nil && len(changeSet.Entries) > 0:
 		r.eventRecorder.AnnotatedEventf(obj, eventMeta(cur.ChartVersion, cur.ConfigDigest,
-			addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)), corev1.EventTypeNormal,
+			addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)), corev1.EventTypeNormal,
 			"DriftCorrected", "Cluster state of release %s has been corrected:\n%s",
 			obj.Status.History.Latest().FullReleaseName(), changeSet.String())
 	}
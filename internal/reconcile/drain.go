@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"time"
+)
+
+// drainContext returns a copy of parent which is not canceled when parent
+// is canceled, but instead after the given timeout has passed since the
+// cancellation of parent. The values of parent are retained.
+//
+// This allows an in-flight operation (e.g. a Helm install or upgrade) to
+// complete when the controller is shutting down, instead of being
+// interrupted and leaving the release in a pending state.
+//
+// When the timeout is zero or negative, the returned context is canceled
+// together with parent.
+//
+// Calling the returned context.CancelFunc releases the resources associated
+// with the context, and should be done as soon as the operation completes.
+func drainContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, func() {
+		t := time.AfterFunc(timeout, cancel)
+		context.AfterFunc(ctx, func() {
+			t.Stop()
+		})
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
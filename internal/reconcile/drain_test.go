@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_drainContext(t *testing.T) {
+	type contextKey struct{}
+
+	t.Run("canceled with parent without timeout", func(t *testing.T) {
+		g := NewWithT(t)
+
+		parent, cancelParent := context.WithCancel(context.Background())
+		ctx, cancel := drainContext(parent, 0)
+		defer cancel()
+
+		cancelParent()
+		g.Eventually(ctx.Done()).Should(BeClosed())
+	})
+
+	t.Run("outlives parent for timeout", func(t *testing.T) {
+		g := NewWithT(t)
+
+		parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), contextKey{}, "value"))
+		ctx, cancel := drainContext(parent, 200*time.Millisecond)
+		defer cancel()
+
+		g.Expect(ctx.Value(contextKey{})).To(Equal("value"))
+
+		cancelParent()
+		g.Consistently(ctx.Done(), 100*time.Millisecond).ShouldNot(BeClosed())
+		g.Eventually(ctx.Done(), time.Second).Should(BeClosed())
+		g.Expect(ctx.Err()).To(MatchError(context.Canceled))
+	})
+
+	t.Run("canceled by cancel func", func(t *testing.T) {
+		g := NewWithT(t)
+
+		parent, cancelParent := context.WithCancel(context.Background())
+		defer cancelParent()
+
+		ctx, cancel := drainContext(parent, time.Minute)
+		cancel()
+		g.Expect(ctx.Done()).To(BeClosed())
+	})
+}
@@ -115,6 +115,12 @@ func (r *HelmChartTemplate) Reconcile(ctx context.Context, req *Request) error {
 	if err := acl.AllowsAccessTo(req.Object, sourcev1.HelmChartKind, chartRef); err != nil {
 		return err
 	}
+	// Confirm we are allowed to deploy charts from the source of the
+	// HelmChart, which is in the same namespace as the HelmChart.
+	sourceRef := types.NamespacedName{Namespace: chartRef.Namespace, Name: obj.Spec.Chart.Spec.SourceRef.Name}
+	if err := acl.AllowsChartSource(req.Object, obj.Spec.Chart.Spec.SourceRef.Kind, sourceRef); err != nil {
+		return err
+	}
 
 	// Build new HelmChart based on the declared template.
 	newChart := buildHelmChartFromTemplate(req.Object)
@@ -20,11 +20,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/fluxcd/pkg/runtime/logger"
+	helmrelease "helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
-	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/fluxcd/pkg/runtime/conditions"
 
@@ -69,9 +69,10 @@ func NewInstall(cfg *action.ConfigFactory, recorder record.EventRecorder) *Insta
 
 func (r *Install) Reconcile(ctx context.Context, req *Request) error {
 	var (
-		logBuf      = action.NewLogBuffer(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.DebugLevel)), 10)
+		logBuf      = newLogBuffer(ctx, req.Object)
 		obsReleases = make(observedReleases)
-		cfg         = r.configFactory.Build(logBuf.Log, observeRelease(obsReleases))
+		cfg         = r.configFactory.Build(logBuf.Log, observeRelease(obsReleases),
+			observeHookEvents(r.eventRecorder, req.Object, time.Now(), helmrelease.HookPreInstall, helmrelease.HookPostInstall))
 	)
 
 	defer summarize(req)
@@ -89,10 +90,12 @@ func (r *Install) Reconcile(ctx context.Context, req *Request) error {
 	conditions.Delete(req.Object, v2.RemediatedCondition)
 
 	// Run the Helm install action.
+	start := time.Now()
 	_, err := action.Install(ctx, cfg, req.Object, req.Chart, req.Values)
 
 	// Record the history of releases observed during the install.
-	obsReleases.recordOnObject(req.Object, mutateOCIDigest)
+	obsReleases.recordOnObject(req.Object, mutateOCIDigest, mutateProvenance(req.Provenance))
+	recordAction(ctx, r, req, start, err)
 
 	if err != nil {
 		r.failure(req, logBuf, err)
@@ -147,17 +150,21 @@ func (r *Install) failure(req *Request, buffer *action.LogBuffer, err error) {
 		req.Chart.Metadata.Version, strings.TrimSpace(err.Error()))
 
 	// Mark install failure on object.
+	reason := failureReason(err, v2.InstallFailedReason)
 	req.Object.Status.Failures++
-	conditions.MarkFalse(req.Object, v2.ReleasedCondition, v2.InstallFailedReason, msg)
+	conditions.MarkFalse(req.Object, v2.ReleasedCondition, reason, msg)
+
+	// Record the logs of the failed attempt on object.
+	recordReleaseAttempt(req.Object, r.Name(), buffer)
 
 	// Record warning event, this message contains more data than the
 	// Condition summary.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
 		eventMeta(req.Chart.Metadata.Version, chartutil.DigestValues(digest.Canonical, req.Values).String(),
-			addAppVersion(req.Chart.AppVersion()), addOCIDigest(req.Object.Status.LastAttemptedRevisionDigest)),
+			addAppVersion(req.Chart.AppVersion()), addOCIDigest(req.Object.Status.LastAttemptedRevisionDigest), addProvenance(req.Provenance), addErrorCode(err)),
 		corev1.EventTypeWarning,
-		v2.InstallFailedReason,
+		reason,
 		eventMessageWithLog(msg, buffer),
 	)
 }
@@ -174,6 +181,7 @@ func (r *Install) success(req *Request) {
 
 	// Mark install success on object.
 	conditions.MarkTrue(req.Object, v2.ReleasedCondition, v2.InstallSucceededReason, msg)
+	req.Object.Status.LastReleaseAttempt = nil
 	if req.Object.GetTest().Enable && !cur.HasBeenTested() {
 		conditions.MarkUnknown(req.Object, v2.TestSuccessCondition, "AwaitingTests", fmtTestPending,
 			cur.FullReleaseName(), cur.VersionedChartName())
@@ -182,7 +190,7 @@ func (r *Install) success(req *Request) {
 	// Record event.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)),
 		corev1.EventTypeNormal,
 		v2.InstallSucceededReason,
 		msg,
@@ -99,9 +99,9 @@ func TestInstall_Reconcile(t *testing.T) {
 			name:  "install failure",
 			chart: testutil.BuildChart(testutil.ChartWithFailingHook()),
 			expectConditions: []metav1.Condition{
-				*conditions.FalseCondition(meta.ReadyCondition, v2.InstallFailedReason,
+				*conditions.FalseCondition(meta.ReadyCondition, v2.WaitTimeoutReason,
 					"failed post-install"),
-				*conditions.FalseCondition(v2.ReleasedCondition, v2.InstallFailedReason,
+				*conditions.FalseCondition(v2.ReleasedCondition, v2.WaitTimeoutReason,
 					"failed post-install"),
 			},
 			expectHistory: func(releases []*helmrelease.Release) v2.Snapshots {
@@ -344,6 +344,7 @@ func TestInstall_failure(t *testing.T) {
 						eventMetaGroupKey(eventv1.MetaRevisionKey): chrt.Metadata.Version,
 						eventMetaGroupKey(metaAppVersionKey):       chrt.Metadata.AppVersion,
 						eventMetaGroupKey(eventv1.MetaTokenKey):    chartutil.DigestValues(digest.Canonical, req.Values).String(),
+						eventMetaGroupKey(metaErrorCodeKey):        string(action.ErrorCodeUnknown),
 					},
 				},
 			},
@@ -358,7 +359,7 @@ func TestInstall_failure(t *testing.T) {
 			eventRecorder: recorder,
 		}
 		req := &Request{Object: obj.DeepCopy(), Chart: chrt}
-		r.failure(req, mockLogBuffer(5, 10), err)
+		r.failure(req, mockWarningLogBuffer(5, 10), err)
 
 		expectSubStr := "Last Helm logs"
 		g.Expect(conditions.IsFalse(req.Object, v2.ReleasedCondition)).To(BeTrue())
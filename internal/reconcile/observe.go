@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/diff"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+// observe observes the existing Helm release of an object which has not been
+// migrated yet, without running any action.
+//
+// It replaces the history of the object with the releases in the Helm
+// storage, determines the state of the latest release against the
+// Request, and checks the readiness of its objects. The object is marked
+// with Migrated=False describing the state of the release, and with a Ready
+// condition reflecting the health of the release. A detected drift is
+// reported with an event when it is first observed or changes, but not
+// corrected.
+func (r *AtomicRelease) observe(ctx context.Context, req *Request) error {
+	cfg := r.configFactory.Build(nil)
+	if err := observeHistory(cfg, req.Object); err != nil {
+		return fmt.Errorf("failed to observe history of release: %w", err)
+	}
+
+	conditions.Delete(req.Object, meta.ReconcilingCondition)
+	cur := req.Object.Status.History.Latest()
+	if cur == nil {
+		msg := fmt.Sprintf("No release %s/%s in storage to observe", req.Object.GetReleaseNamespace(),
+			release.ShortenName(req.Object.GetReleaseName()))
+		markMigrationPending(req.Object, msg)
+		conditions.MarkFalse(req.Object, meta.ReadyCondition, v2.MigrationPendingReason, "%s", msg)
+		req.Object.Status.Inventory = nil
+		return nil
+	}
+
+	state, err := DetermineReleaseState(ctx, r.configFactory, req)
+	if err != nil {
+		conditions.MarkFalse(req.Object, meta.ReadyCondition, "StateError", fmt.Sprintf("Could not determine release state: %s", err.Error()))
+		return fmt.Errorf("cannot determine release state: %w", err)
+	}
+	desc := string(state.Status)
+	if state.Reason != "" {
+		desc += ": " + state.Reason
+	}
+	if state.Status == ReleaseStatusDrifted {
+		desc += ": " + diff.SummarizeDiffSetBrief(state.Diff)
+	}
+	msg := fmt.Sprintf("Release %s is observed until it is migrated, state is %s", cur.FullReleaseName(), desc)
+
+	// Only report a drift when it is first observed or changes, as the
+	// observation is repeated every reconciliation while it persists.
+	if state.Status == ReleaseStatusDrifted && conditions.GetMessage(req.Object, v2.MigratedCondition) != msg {
+		r.eventRecorder.Eventf(req.Object, corev1.EventTypeWarning, "DriftDetected",
+			"Cluster state of release %s has drifted from the desired state:\n%s",
+			cur.FullReleaseName(), diff.SummarizeDiffSet(state.Diff),
+		)
+	}
+	markMigrationPending(req.Object, msg)
+
+	status, health := observeHealth(ctx, cfg, cur)
+	conditions.Set(req.Object, &metav1.Condition{
+		Type:               meta.ReadyCondition,
+		Status:             status,
+		Reason:             v2.MigrationPendingReason,
+		Message:            fmt.Sprintf("Observed release %s with chart %s: %s", cur.FullReleaseName(), cur.VersionedChartName(), health),
+		ObservedGeneration: req.Object.Generation,
+	})
+
+	if err := r.recordInventory(req); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to record inventory of Helm release")
+	}
+	return nil
+}
+
+// observeHistory replaces the history of the given object with snapshots of
+// the releases in the Helm storage, most recent first, and limited to the
+// max history of the object.
+func observeHistory(cfg *helmaction.Configuration, obj *v2.HelmRelease) error {
+	releases, err := cfg.Releases.History(release.ShortenName(obj.GetReleaseName()))
+	if err != nil && !errors.Is(err, helmdriver.ErrReleaseNotFound) {
+		return err
+	}
+	releaseutil.Reverse(releases, releaseutil.SortByRevision)
+
+	history := make(v2.Snapshots, 0, len(releases))
+	for _, rls := range releases {
+		history = append(history, release.ObservedToSnapshot(release.ObserveRelease(rls)))
+	}
+	if maxHistory := obj.GetMaxHistory(); maxHistory > 0 && len(history) > maxHistory {
+		history = history[:maxHistory]
+	}
+	obj.Status.History = history
+	return nil
+}
+
+// observeHealth returns the status of the Ready condition for the release of
+// the given snapshot, and a description of its health. A release is healthy
+// when it is deployed and all of its objects are ready.
+func observeHealth(ctx context.Context, cfg *helmaction.Configuration, cur *v2.Snapshot) (metav1.ConditionStatus, string) {
+	if cur.Status != helmrelease.StatusDeployed.String() {
+		return metav1.ConditionFalse, fmt.Sprintf("release has status '%s'", cur.Status)
+	}
+	rls, err := cfg.Releases.Get(cur.Name, cur.Version)
+	if err != nil {
+		return metav1.ConditionUnknown, fmt.Sprintf("failed to get release: %s", err)
+	}
+	notReady, err := action.NotReady(ctx, cfg, rls)
+	if err != nil {
+		return metav1.ConditionUnknown, fmt.Sprintf("failed to check readiness of objects: %s", err)
+	}
+	if len(notReady) > 0 {
+		return metav1.ConditionFalse, fmt.Sprintf("objects not ready: %s", strings.Join(notReady, ", "))
+	}
+	return metav1.ConditionTrue, "all objects are ready"
+}
+
+// markMigrationPending marks the given object with Migrated=False with the
+// given message.
+func markMigrationPending(obj *v2.HelmRelease, msg string) {
+	conditions.MarkFalse(obj, v2.MigratedCondition, v2.MigrationPendingReason, "%s", msg)
+}
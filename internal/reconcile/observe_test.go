@@ -0,0 +1,238 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/kube"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+)
+
+func TestAtomicRelease_observe(t *testing.T) {
+	chrt := testutil.BuildChart()
+	values := map[string]interface{}{"foo": "bar"}
+
+	tests := []struct {
+		name       string
+		releases   []*helmrelease.Release
+		maxHistory int
+		wantReady  metav1.ConditionStatus
+		wantMsg    string
+		wantLen    int
+	}{
+		{
+			name:      "no release in storage",
+			wantReady: metav1.ConditionFalse,
+			wantMsg:   "No release " + mockReleaseNamespace + "/" + mockReleaseName + " in storage to observe",
+		},
+		{
+			name: "failed release",
+			releases: []*helmrelease.Release{
+				testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+					Chart:     chrt,
+					Version:   1,
+					Status:    helmrelease.StatusSuperseded,
+				}, testutil.ReleaseWithConfig(values)),
+				testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+					Chart:     chrt,
+					Version:   2,
+					Status:    helmrelease.StatusFailed,
+				}, testutil.ReleaseWithConfig(values)),
+			},
+			wantReady: metav1.ConditionFalse,
+			wantMsg:   "release has status 'failed'",
+			wantLen:   2,
+		},
+		{
+			name: "history is limited to max history",
+			releases: []*helmrelease.Release{
+				testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+					Chart:     chrt,
+					Version:   1,
+					Status:    helmrelease.StatusSuperseded,
+				}, testutil.ReleaseWithConfig(values)),
+				testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+					Chart:     chrt,
+					Version:   2,
+					Status:    helmrelease.StatusSuperseded,
+				}, testutil.ReleaseWithConfig(values)),
+				testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+					Chart:     chrt,
+					Version:   3,
+					Status:    helmrelease.StatusFailed,
+				}, testutil.ReleaseWithConfig(values)),
+			},
+			maxHistory: 2,
+			wantReady:  metav1.ConditionFalse,
+			wantLen:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cfg, err := action.NewConfigFactory(&kube.MemoryRESTClientGetter{},
+				action.WithStorage(helmdriver.MemoryDriverName, mockReleaseNamespace),
+			)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			store := cfg.Build(nil).Releases
+			for _, rls := range tt.releases {
+				g.Expect(store.Create(rls)).To(Succeed())
+			}
+
+			recorder := testutil.NewFakeRecorder(10, false)
+			r := &AtomicRelease{configFactory: cfg, eventRecorder: recorder}
+
+			obj := &v2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+				},
+				Spec: v2.HelmReleaseSpec{
+					Migration:  &v2.Migration{},
+					MaxHistory: &tt.maxHistory,
+				},
+			}
+			req := &Request{Object: obj, Chart: chrt, Values: values}
+
+			g.Expect(r.Reconcile(context.TODO(), req)).To(Succeed())
+
+			g.Expect(obj.Status.History).To(HaveLen(tt.wantLen))
+			for i := 1; i < len(obj.Status.History); i++ {
+				g.Expect(obj.Status.History[i-1].Version).To(BeNumerically(">", obj.Status.History[i].Version))
+			}
+			if tt.wantLen > 0 {
+				g.Expect(obj.Status.History.Latest().Version).To(Equal(tt.releases[len(tt.releases)-1].Version))
+			}
+
+			g.Expect(conditions.IsFalse(obj, v2.MigratedCondition)).To(BeTrue())
+			g.Expect(conditions.GetReason(obj, v2.MigratedCondition)).To(Equal(v2.MigrationPendingReason))
+			g.Expect(conditions.Get(obj, meta.ReadyCondition).Status).To(Equal(tt.wantReady))
+			g.Expect(conditions.GetMessage(obj, meta.ReadyCondition)).To(ContainSubstring(tt.wantMsg))
+			g.Expect(conditions.Has(obj, meta.ReconcilingCondition)).To(BeFalse())
+
+			// The releases in storage are left untouched.
+			releases, err := store.History(mockReleaseName)
+			if len(tt.releases) == 0 {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(releases).To(HaveLen(len(tt.releases)))
+			for _, rls := range releases {
+				g.Expect(rls.Info.Status).To(Equal(tt.releases[rls.Version-1].Info.Status))
+			}
+		})
+	}
+
+	t.Run("reports drift only when it changes", func(t *testing.T) {
+		g := NewWithT(t)
+
+		namedNS, err := testEnv.CreateNamespace(context.TODO(), mockReleaseNamespace)
+		g.Expect(err).NotTo(HaveOccurred())
+		t.Cleanup(func() {
+			_ = testEnv.Delete(context.TODO(), namedNS)
+		})
+		releaseNamespace := namedNS.Name
+
+		// The objects of the release are not applied, which is observed as
+		// a drift.
+		rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+			Name:      mockReleaseName,
+			Namespace: releaseNamespace,
+			Chart:     chrt,
+			Version:   1,
+			Status:    helmrelease.StatusDeployed,
+		}, testutil.ReleaseWithConfig(values))
+
+		getter, err := RESTClientGetterFromManager(testEnv.Manager, releaseNamespace)
+		g.Expect(err).ToNot(HaveOccurred())
+		cfg, err := action.NewConfigFactory(getter,
+			action.WithStorage(action.DefaultStorageDriver, releaseNamespace),
+		)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cfg.Build(nil).Releases.Create(rls)).To(Succeed())
+
+		recorder := testutil.NewFakeRecorder(10, false)
+		r := &AtomicRelease{configFactory: cfg, eventRecorder: recorder}
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      mockReleaseName,
+				Namespace: releaseNamespace,
+			},
+			Spec: v2.HelmReleaseSpec{
+				ReleaseName:      mockReleaseName,
+				TargetNamespace:  releaseNamespace,
+				StorageNamespace: releaseNamespace,
+				Migration:        &v2.Migration{},
+				DriftDetection: &v2.DriftDetection{
+					Mode: v2.DriftDetectionEnabled,
+				},
+			},
+		}
+
+		for i := 0; i < 3; i++ {
+			g.Expect(r.Reconcile(context.TODO(), &Request{Object: obj, Chart: chrt, Values: values})).To(Succeed())
+		}
+		g.Expect(conditions.GetMessage(obj, v2.MigratedCondition)).To(ContainSubstring("state is " + string(ReleaseStatusDrifted)))
+
+		var driftEvents int
+		for _, e := range recorder.GetEvents() {
+			if e.Reason == "DriftDetected" {
+				driftEvents++
+			}
+		}
+		g.Expect(driftEvents).To(Equal(1))
+	})
+
+	t.Run("migrated release is no longer observed", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			Spec: v2.HelmReleaseSpec{Migration: &v2.Migration{Migrated: true}},
+		}
+		g.Expect(obj.IsObserveOnly()).To(BeFalse())
+		obj.Spec.Migration.Migrated = false
+		g.Expect(obj.IsObserveOnly()).To(BeTrue())
+		obj.Spec.Migration = nil
+		g.Expect(obj.IsObserveOnly()).To(BeFalse())
+	})
+}
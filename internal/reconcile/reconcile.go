@@ -23,6 +23,7 @@ import (
 	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/actionhook"
 )
 
 const (
@@ -83,6 +84,14 @@ type Request struct {
 	// Values is the Helm chart values to be used for the installation or
 	// upgrade.
 	Values helmchartutil.Values
+	// Provenance is the metadata of the source the Chart originates from,
+	// which is recorded on the release snapshot and events of an
+	// installation or upgrade.
+	Provenance map[string]string
+	// Webhooks are the release action webhooks of the Object, which are
+	// called before and after the Helm actions performed for it. When nil,
+	// no webhooks are called.
+	Webhooks *actionhook.Webhooks
 }
 
 // ActionReconciler is an interface which defines the methods that a reconciler
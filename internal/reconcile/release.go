@@ -17,18 +17,40 @@ limitations under the License.
 package reconcile
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/runtime/logger"
 	helmrelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/apicompat"
+	"github.com/fluxcd/helm-controller/internal/audit"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+	"github.com/fluxcd/helm-controller/internal/crdschema"
+	"github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/kube"
+	"github.com/fluxcd/helm-controller/internal/metrics"
+	"github.com/fluxcd/helm-controller/internal/podsecurity"
+	"github.com/fluxcd/helm-controller/internal/quota"
+	"github.com/fluxcd/helm-controller/internal/recreate"
 	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/signature"
 	"github.com/fluxcd/helm-controller/internal/storage"
 )
 
@@ -43,6 +65,28 @@ var (
 	ErrReleaseMismatch = errors.New("release mismatch")
 )
 
+const (
+	// logBufferSize is the number of Helm log lines retained for a Helm
+	// action.
+	logBufferSize = 10
+	// debugLogBufferSize is the number of Helm log lines retained for a
+	// Helm action of a HelmRelease with debug logging enabled.
+	debugLogBufferSize = 50
+)
+
+// maxReleaseAttemptLogsLength is the maximum length in bytes of the logs
+// recorded in the v2.ReleaseAttempt of a HelmRelease.
+const maxReleaseAttemptLogsLength = 4096
+
+const (
+	// HookSucceededReason is the event reason for a Helm hook which has
+	// been executed successfully.
+	HookSucceededReason = "HookSucceeded"
+	// HookFailedReason is the event reason for a Helm hook which has failed
+	// to execute.
+	HookFailedReason = "HookFailed"
+)
+
 // mutateObservedRelease is a function that mutates the Observation with the
 // given HelmRelease object.
 type mutateObservedRelease func(*v2.HelmRelease, release.Observation) release.Observation
@@ -62,7 +106,12 @@ func (r observedReleases) sortedVersions() (versions []int) {
 }
 
 // recordOnObject records the observed releases on the HelmRelease object.
+// Only the notes of the most recent release are retained in the history.
 func (r observedReleases) recordOnObject(obj *v2.HelmRelease, mutators ...mutateObservedRelease) {
+	if len(r) > 0 {
+		defer retainLatestNotes(obj)
+	}
+
 	switch len(r) {
 	case 0:
 		return
@@ -89,6 +138,7 @@ func (r observedReleases) recordOnObject(obj *v2.HelmRelease, mutators ...mutate
 				if snap.Targets(r[ver].Name, r[ver].Namespace, r[ver].Version) {
 					obs := r[ver]
 					obs.OCIDigest = snap.OCIDigest
+					obs.Provenance = snap.Provenance
 					newSnap := release.ObservedToSnapshot(obs)
 					newSnap.SetTestHooks(snap.GetTestHooks())
 					obj.Status.History[i] = newSnap
@@ -99,14 +149,96 @@ func (r observedReleases) recordOnObject(obj *v2.HelmRelease, mutators ...mutate
 	}
 }
 
+// pruneHistory prunes the history of the given object according to its
+// v2.HistoryRetention configuration.
+func pruneHistory(obj *v2.HelmRelease) {
+	retention := obj.GetHistory()
+	ignoreFailures := obj.GetTest().IgnoreFailures
+	if remediation := obj.GetActiveRemediation(); remediation != nil {
+		ignoreFailures = remediation.MustIgnoreTestFailures(obj.GetTest().IgnoreFailures)
+	}
+	obj.Status.History.Prune(retention.GetMaxSnapshots(), retention.GetTTL(), time.Now(), ignoreFailures)
+}
+
+// retainLatestNotes clears the notes of all but the first Snapshot in the
+// history of the given object, to limit the size of the status.
+func retainLatestNotes(obj *v2.HelmRelease) {
+	for _, snap := range obj.Status.History[1:] {
+		snap.Notes = ""
+	}
+}
+
+const (
+	// compactMessageLength is the maximum length in bytes of a condition
+	// message in a compacted status.
+	compactMessageLength = 256
+	// compactMessageSuffix is appended to condition messages which were
+	// truncated in a compacted status.
+	compactMessageSuffix = "... (truncated, see events)"
+)
+
+// CompactStatus reduces the size of the status of the given object, for
+// clusters where the size of HelmRelease objects materially impacts etcd.
+// It truncates the condition messages to compactMessageLength, and trims the
+// history to the Latest and Previous Snapshot without notes. The logs of the
+// last release attempt are limited to entries with at least
+// action.LogLevelWarning. The full messages remain available in the events
+// recorded for the object.
+func CompactStatus(obj *v2.HelmRelease) {
+	for i := range obj.Status.Conditions {
+		obj.Status.Conditions[i].Message = compactMessage(obj.Status.Conditions[i].Message)
+	}
+
+	ignoreFailures := obj.GetTest().IgnoreFailures
+	if remediation := obj.GetActiveRemediation(); remediation != nil {
+		ignoreFailures = remediation.MustIgnoreTestFailures(obj.GetTest().IgnoreFailures)
+	}
+	obj.Status.History.Truncate(ignoreFailures)
+	for _, snap := range obj.Status.History {
+		snap.Notes = ""
+	}
+	if attempt := obj.Status.LastReleaseAttempt; attempt != nil {
+		var logs []v2.LogEntry
+		for _, e := range attempt.Logs {
+			if action.LogLevel(e.Level).AtLeast(action.LogLevelWarning) {
+				logs = append(logs, e)
+			}
+		}
+		attempt.Logs = logs
+	}
+}
+
+// compactMessage truncates the given message to compactMessageLength,
+// without splitting a multibyte character.
+func compactMessage(msg string) string {
+	if len(msg) <= compactMessageLength {
+		return msg
+	}
+	i := compactMessageLength
+	for i > 0 && !utf8.RuneStart(msg[i]) {
+		i--
+	}
+	return msg[:i] + compactMessageSuffix
+}
+
 func mutateOCIDigest(obj *v2.HelmRelease, obs release.Observation) release.Observation {
 	obs.OCIDigest = obj.Status.LastAttemptedRevisionDigest
 	return obs
 }
 
+// mutateProvenance returns a mutateObservedRelease which records the given
+// source provenance on the observed release.
+func mutateProvenance(provenance map[string]string) mutateObservedRelease {
+	return func(_ *v2.HelmRelease, obs release.Observation) release.Observation {
+		obs.Provenance = provenance
+		return obs
+	}
+}
+
 func releaseToObservation(rls *helmrelease.Release, snapshot *v2.Snapshot) release.Observation {
 	obs := release.ObserveRelease(rls)
 	obs.OCIDigest = snapshot.OCIDigest
+	obs.Provenance = snapshot.Provenance
 	return obs
 }
 
@@ -121,6 +253,215 @@ func observeRelease(observed observedReleases) storage.ObserveFunc {
 	}
 }
 
+// observeHookEvents returns a storage.ObserveFunc that records an event for
+// each Helm hook for one of the given events which has completed since the
+// given time, as observed on the releases written to the Helm storage. Each
+// execution of a hook is recorded once, as Helm may write the same release
+// multiple times during an action.
+func observeHookEvents(recorder record.EventRecorder, obj *v2.HelmRelease, since time.Time, events ...helmrelease.HookEvent) storage.ObserveFunc {
+	recorded := make(map[string]struct{})
+	return func(rls *helmrelease.Release) {
+		for _, h := range rls.Hooks {
+			run := h.LastRun
+			if run.StartedAt.Time.Before(since) {
+				continue
+			}
+
+			var eventType, reason string
+			switch run.Phase {
+			case helmrelease.HookPhaseSucceeded:
+				eventType, reason = corev1.EventTypeNormal, HookSucceededReason
+			case helmrelease.HookPhaseFailed:
+				eventType, reason = corev1.EventTypeWarning, HookFailedReason
+			default:
+				continue
+			}
+
+			var phases []string
+			for _, e := range events {
+				if release.IsHookForEvent(h, e) {
+					phases = append(phases, e.String())
+				}
+			}
+			if len(phases) == 0 {
+				continue
+			}
+
+			key := fmt.Sprintf("%s/%s/%d", h.Kind, h.Name, run.StartedAt.UnixNano())
+			if _, ok := recorded[key]; ok {
+				continue
+			}
+			recorded[key] = struct{}{}
+
+			var metadata map[string]string
+			if rls.Chart != nil && rls.Chart.Metadata != nil {
+				metadata = eventMeta(rls.Chart.Metadata.Version, chartutil.DigestValues(digest.Canonical, rls.Config).String(),
+					addAppVersion(rls.Chart.AppVersion()))
+			}
+			recorder.AnnotatedEventf(obj, metadata, eventType, reason,
+				"Helm %s hook %s/%s %s after %s for release %s/%s.v%d", strings.Join(phases, ","), h.Kind, h.Name,
+				strings.ToLower(run.Phase.String()), run.CompletedAt.Time.Sub(run.StartedAt.Time).Round(time.Millisecond),
+				rls.Namespace, rls.Name, rls.Version)
+		}
+	}
+}
+
+// recordAction records the result of the Helm action performed by the
+// ActionReconciler, started at the given time for the Request, in the action
+// duration metrics and the audit log. It should be called after the history
+// of the Request.Object has been updated with the observed releases.
+func recordAction(ctx context.Context, r ActionReconciler, req *Request, start time.Time, err error) {
+	name := r.Name()
+	metrics.ObserveActionDuration(name, start, err)
+
+	obj := req.Object
+	rec := audit.Record{
+		Time:        time.Now(),
+		ReconcileID: string(controller.ReconcileIDFromContext(ctx)),
+		Action:      name,
+		Result:      audit.ResultSuccess,
+		Duration:    time.Since(start).String(),
+		Object:      auditObject(obj),
+		Release:     auditRelease(r, req),
+	}
+	if err != nil {
+		rec.Result = audit.ResultFailure
+		rec.Error = err.Error()
+	}
+
+	if obj.Spec.KubeConfig != nil {
+		rec.KubeConfig = obj.Spec.KubeConfig.SecretRef.Name
+	}
+	rec.ServiceAccount = obj.Spec.ServiceAccountName
+	if rec.ServiceAccount == "" {
+		rec.ServiceAccount = kube.DefaultServiceAccountName
+	}
+
+	if logErr := audit.Log(ctx, rec); logErr != nil {
+		ctrl.LoggerFrom(ctx).Error(logErr, "failed to write audit record", "action", name)
+	}
+}
+
+// auditObject returns the audit.Object identifying the given object.
+func auditObject(obj *v2.HelmRelease) audit.Object {
+	return audit.Object{
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+		UID:        string(obj.GetUID()),
+		Generation: obj.GetGeneration(),
+	}
+}
+
+// auditRelease returns the audit.Release describing the Helm release the
+// Helm action of the ActionReconciler is performed on for the Request.
+func auditRelease(r ActionReconciler, req *Request) audit.Release {
+	obj := req.Object
+	rls := audit.Release{
+		Name:      release.ShortenName(obj.GetReleaseName()),
+		Namespace: obj.GetReleaseNamespace(),
+	}
+	if cur := obj.Status.History.Latest(); cur != nil {
+		rls.Name = cur.Name
+		rls.Namespace = cur.Namespace
+		rls.Version = cur.Version
+		rls.ChartName = cur.ChartName
+		rls.ChartVersion = cur.ChartVersion
+		rls.ValuesDigest = cur.ConfigDigest
+	}
+	// The chart and values of the Request take precedence for a new release,
+	// as the action may have failed before a release was made with them.
+	if r.Type() == ReconcilerTypeRelease && req.Chart != nil && req.Chart.Metadata != nil {
+		rls.ChartName = req.Chart.Name()
+		rls.ChartVersion = req.Chart.Metadata.Version
+		rls.ValuesDigest = chartutil.DigestValues(digest.Canonical, req.Values).String()
+	}
+	return rls
+}
+
+// recordReleaseAttempt records the failure of the Helm action with the given
+// name on the object, with the tail of the logs in the given buffer.
+func recordReleaseAttempt(obj *v2.HelmRelease, name string, buffer *action.LogBuffer) {
+	attempt := &v2.ReleaseAttempt{
+		Action: name,
+		Time:   metav1.Now(),
+	}
+	if buffer != nil {
+		for _, e := range buffer.TailEntries(maxReleaseAttemptLogsLength) {
+			attempt.Logs = append(attempt.Logs, v2.LogEntry{
+				Time:       metav1.NewTime(e.Time),
+				Level:      string(e.Level),
+				Component:  e.Component,
+				Message:    e.Message,
+				Duplicates: e.Duplicates,
+			})
+		}
+	}
+	obj.Status.LastReleaseAttempt = attempt
+}
+
+// failureReason returns the reason for the given error of a Helm install or
+// upgrade action, based on the action.ErrorCode of the error. It returns
+// v2.PolicyViolationReason for a podsecurity.ViolationError or a
+// signature.VerificationError, v2.QuotaExceededReason for a
+// quota.ExceededError, v2.IncompatibleAPIsReason for an
+// apicompat.IncompatibleError, v2.InvalidCustomResourcesReason for a
+// crdschema.InvalidError, v2.StorageTooLargeReason for a
+// storage.TooLargeError, or the given reason if the error can not be
+// classified.
+func failureReason(err error, reason string) string {
+	var (
+		violationErr    *podsecurity.ViolationError
+		verificationErr *signature.VerificationError
+	)
+	if errors.As(err, &violationErr) || errors.As(err, &verificationErr) {
+		return v2.PolicyViolationReason
+	}
+	var quotaErr *quota.ExceededError
+	if errors.As(err, &quotaErr) {
+		return v2.QuotaExceededReason
+	}
+	var incompatibleErr *apicompat.IncompatibleError
+	if errors.As(err, &incompatibleErr) {
+		return v2.IncompatibleAPIsReason
+	}
+	var invalidErr *crdschema.InvalidError
+	if errors.As(err, &invalidErr) {
+		return v2.InvalidCustomResourcesReason
+	}
+	var recreateErr *recreate.RequiredError
+	if errors.As(err, &recreateErr) {
+		return v2.RecreateRequiredReason
+	}
+	var tooLargeErr *storage.TooLargeError
+	if errors.As(err, &tooLargeErr) {
+		return v2.StorageTooLargeReason
+	}
+	switch action.ClassifyError(err) {
+	case action.ErrorCodeRender:
+		return v2.RenderFailedReason
+	case action.ErrorCodeAdmission:
+		return v2.AdmissionDeniedReason
+	case action.ErrorCodeRBAC:
+		return v2.RBACDeniedReason
+	case action.ErrorCodeWaitTimeout:
+		return v2.WaitTimeoutReason
+	case action.ErrorCodeStorage:
+		return v2.StorageFailedReason
+	}
+	return reason
+}
+
+// newLogBuffer returns a new action.LogBuffer for a Helm action of the given
+// HelmRelease, which logs to the debug level of the logger in the context.
+// The buffer retains more log lines when v2.IsDebugEnabled.
+func newLogBuffer(ctx context.Context, obj *v2.HelmRelease) *action.LogBuffer {
+	size := logBufferSize
+	if v2.IsDebugEnabled(obj) {
+		size = debugLogBufferSize
+	}
+	return action.NewLogBuffer(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.DebugLevel)), size)
+}
+
 // summarize composes a Ready condition out of the Remediated, TestSuccess and
 // Released conditions of the given Request.Object, and sets it on the object.
 //
@@ -150,6 +491,15 @@ func summarize(req *Request) {
 		conditions.Delete(req.Object, v2.TestSuccessCondition)
 	}
 
+	// Remove any stale PreflightPassed condition as soon as the preflight
+	// checks are disabled, and surface a failure of the checks, as the
+	// upgrade has then not been attempted.
+	if req.Object.GetUpgrade().Preflight == nil {
+		conditions.Delete(req.Object, v2.PreflightPassedCondition)
+	} else if conditions.IsFalse(req.Object, v2.PreflightPassedCondition) {
+		sumConds = append([]string{v2.PreflightPassedCondition}, sumConds...)
+	}
+
 	conds := req.Object.Status.Conditions
 	if len(conds) == 0 {
 		// Nothing to summarize if there are no conditions.
@@ -191,10 +541,14 @@ func summarize(req *Request) {
 }
 
 // eventMessageWithLog returns an event message composed out of the given
-// message and any log messages by appending them to the message.
+// message and any log messages with at least action.LogLevelWarning by
+// appending them to the message.
 func eventMessageWithLog(msg string, log *action.LogBuffer) string {
-	if log != nil && log.Len() > 0 {
-		msg = msg + "\n\nLast Helm logs:\n\n" + log.String()
+	if log == nil {
+		return msg
+	}
+	if logs := log.StringAtLevel(action.LogLevelWarning); logs != "" {
+		msg = msg + "\n\nLast Helm logs:\n\n" + logs
 	}
 	return msg
 }
@@ -208,6 +562,14 @@ const (
 
 	// metaAppVersionKey is the key for the app version found in chart metadata.
 	metaAppVersionKey = "app-version"
+
+	// metaUpgradeDiffKey is the key for the JSON encoded v2.DiffSummary of
+	// an upgrade preview.
+	metaUpgradeDiffKey = "upgrade-diff"
+
+	// metaErrorCodeKey is the key for the action.ErrorCode of a failed Helm
+	// action.
+	metaErrorCodeKey = action.MetaErrorCodeKey
 )
 
 // eventMeta returns the event (annotation) metadata based on the given
@@ -242,6 +604,19 @@ func addOCIDigest(digest string) addMeta {
 	}
 }
 
+// addProvenance adds the entries of the given source provenance to the
+// event metadata.
+func addProvenance(provenance map[string]string) addMeta {
+	return func(m map[string]string) {
+		if m == nil {
+			return
+		}
+		for k, v := range provenance {
+			m[eventMetaGroupKey(k)] = v
+		}
+	}
+}
+
 func addAppVersion(appVersion string) addMeta {
 	return func(m map[string]string) {
 		if appVersion != "" {
@@ -253,6 +628,30 @@ func addAppVersion(appVersion string) addMeta {
 	}
 }
 
+// addDiffSummary adds the JSON encoding of the given summary to the event
+// metadata.
+func addDiffSummary(summary *v2.DiffSummary) addMeta {
+	return func(m map[string]string) {
+		if m == nil || summary == nil {
+			return
+		}
+		if b, err := json.Marshal(summary); err == nil {
+			m[eventMetaGroupKey(metaUpgradeDiffKey)] = string(b)
+		}
+	}
+}
+
+// addErrorCode adds the action.ErrorCode of the given error to the event
+// metadata.
+func addErrorCode(err error) addMeta {
+	return func(m map[string]string) {
+		if m == nil || err == nil {
+			return
+		}
+		m[eventMetaGroupKey(metaErrorCodeKey)] = string(action.ClassifyError(err))
+	}
+}
+
 // eventMetaGroupKey returns the event (annotation) metadata key prefixed with
 // the group.
 func eventMetaGroupKey(key string) string {
@@ -17,13 +17,21 @@ limitations under the License.
 package reconcile
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
 	"helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	"github.com/fluxcd/pkg/apis/kustomize"
 	"github.com/fluxcd/pkg/apis/meta"
@@ -31,6 +39,15 @@ import (
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/apicompat"
+	"github.com/fluxcd/helm-controller/internal/audit"
+	"github.com/fluxcd/helm-controller/internal/crdschema"
+	"github.com/fluxcd/helm-controller/internal/podsecurity"
+	"github.com/fluxcd/helm-controller/internal/quota"
+	"github.com/fluxcd/helm-controller/internal/recreate"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/storage"
+	"github.com/fluxcd/helm-controller/internal/testutil"
 )
 
 const (
@@ -441,6 +458,60 @@ func Test_summarize(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:       "with failed preflight",
+			generation: 1,
+			spec: &v2.HelmReleaseSpec{
+				Upgrade: &v2.Upgrade{
+					Preflight: &v2.UpgradePreflight{
+						MinKubernetesVersion: "1.30",
+					},
+				},
+			},
+			status: v2.HelmReleaseStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:               v2.ReleasedCondition,
+						Status:             metav1.ConditionTrue,
+						Reason:             v2.UpgradeSucceededReason,
+						Message:            "Upgrade finished",
+						ObservedGeneration: 1,
+					},
+					{
+						Type:               v2.PreflightPassedCondition,
+						Status:             metav1.ConditionFalse,
+						Reason:             v2.PreflightFailedReason,
+						Message:            "preflight checks failed",
+						ObservedGeneration: 1,
+					},
+				},
+			},
+			expectedStatus: &v2.HelmReleaseStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:               meta.ReadyCondition,
+						Status:             metav1.ConditionFalse,
+						Reason:             v2.PreflightFailedReason,
+						Message:            "preflight checks failed",
+						ObservedGeneration: 1,
+					},
+					{
+						Type:               v2.PreflightPassedCondition,
+						Status:             metav1.ConditionFalse,
+						Reason:             v2.PreflightFailedReason,
+						Message:            "preflight checks failed",
+						ObservedGeneration: 1,
+					},
+					{
+						Type:               v2.ReleasedCondition,
+						Status:             metav1.ConditionTrue,
+						Reason:             v2.UpgradeSucceededReason,
+						Message:            "Upgrade finished",
+						ObservedGeneration: 1,
+					},
+				},
+			},
+		},
 		{
 			name:       "with stale observed generation",
 			generation: 5,
@@ -538,6 +609,47 @@ func mockLogBuffer(size int, lines int) *action.LogBuffer {
 	return log
 }
 
+func mockWarningLogBuffer(size int, lines int) *action.LogBuffer {
+	log := action.NewLogBuffer(action.NewDebugLog(logr.Discard()), size)
+	for i := 0; i < lines; i++ {
+		log.Log("warning: line %d", i+1)
+	}
+	return log
+}
+
+func Test_recordReleaseAttempt(t *testing.T) {
+	g := NewWithT(t)
+
+	log := action.NewLogBuffer(action.NewDebugLog(logr.Discard()), 5)
+	log.Log("creating 1 resource(s)")
+	log.Log("warning: Hook post-install failed: token=secret")
+
+	obj := &v2.HelmRelease{}
+	recordReleaseAttempt(obj, "install", log)
+
+	attempt := obj.Status.LastReleaseAttempt
+	g.Expect(attempt).ToNot(BeNil())
+	g.Expect(attempt.Action).To(Equal("install"))
+	g.Expect(attempt.Logs).To(HaveLen(2))
+	g.Expect(attempt.Logs[0].Level).To(Equal(string(action.LogLevelInfo)))
+	g.Expect(attempt.Logs[1].Level).To(Equal(string(action.LogLevelWarning)))
+	g.Expect(attempt.Logs[1].Message).To(Equal("warning: Hook post-install failed: token=***"))
+}
+
+func Test_eventMessageWithLog(t *testing.T) {
+	g := NewWithT(t)
+
+	log := action.NewLogBuffer(action.NewDebugLog(logr.Discard()), 5)
+	log.Log("creating 1 resource(s)")
+	g.Expect(eventMessageWithLog("msg", log)).To(Equal("msg"))
+
+	log.Log("warning: Hook post-install failed")
+	msg := eventMessageWithLog("msg", log)
+	g.Expect(msg).To(ContainSubstring("Last Helm logs"))
+	g.Expect(msg).To(ContainSubstring("warning: Hook post-install failed"))
+	g.Expect(msg).ToNot(ContainSubstring("creating 1 resource(s)"))
+}
+
 func Test_RecordOnObject(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -696,6 +808,49 @@ func Test_RecordOnObject(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name: "record observed releases retains notes of latest",
+			obj: &v2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+				},
+				Status: v2.HelmReleaseStatus{
+					History: v2.Snapshots{
+						{
+							Name:    mockReleaseName,
+							Version: 1,
+							Notes:   "notes of release 1",
+						},
+					},
+				},
+			},
+			r: observedReleases{
+				2: {
+					Name:    mockReleaseName,
+					Version: 2,
+					ChartMetadata: chart.Metadata{
+						Name:    mockReleaseName,
+						Version: "2.0.0",
+					},
+					Info: helmrelease.Info{
+						Notes: "notes of release 2",
+					},
+				},
+			},
+			testFunc: func(obj *v2.HelmRelease) error {
+				if len(obj.Status.History) != 2 {
+					return fmt.Errorf("want history length 2, got %d", len(obj.Status.History))
+				}
+				if notes := obj.Status.History[0].Notes; notes != "notes of release 2" {
+					return fmt.Errorf("want notes %q, got %q", "notes of release 2", notes)
+				}
+				if notes := obj.Status.History[1].Notes; notes != "" {
+					return fmt.Errorf("want no notes for previous release, got %q", notes)
+				}
+				return nil
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -713,3 +868,318 @@ func Test_RecordOnObject(t *testing.T) {
 	}
 
 }
+
+func Test_RecordOnObject_provenance(t *testing.T) {
+	g := NewWithT(t)
+
+	provenance := map[string]string{"source": "GitRepository/flux-system/apps"}
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      mockReleaseName,
+			Namespace: mockReleaseNamespace,
+		},
+	}
+	observedReleases{
+		1: {
+			Name:          mockReleaseName,
+			Version:       1,
+			ChartMetadata: chart.Metadata{Name: mockReleaseName, Version: "1.0.0"},
+		},
+	}.recordOnObject(obj, mutateProvenance(provenance))
+	g.Expect(obj.Status.History).To(HaveLen(1))
+	g.Expect(obj.Status.History[0].Provenance).To(Equal(provenance))
+
+	// The provenance is retained by the observation of a recorded release.
+	obs := releaseToObservation(&helmrelease.Release{
+		Name:    mockReleaseName,
+		Version: 1,
+		Info:    &helmrelease.Info{},
+		Chart:   &chart.Chart{Metadata: &chart.Metadata{Name: mockReleaseName, Version: "1.0.0"}},
+	}, obj.Status.History[0])
+	g.Expect(obs.Provenance).To(Equal(provenance))
+
+	// The provenance is part of the digest of the snapshot.
+	withoutProvenance := obs
+	withoutProvenance.Provenance = nil
+	g.Expect(release.ObservedToSnapshot(obs).Digest).ToNot(Equal(release.ObservedToSnapshot(withoutProvenance).Digest))
+}
+
+func Test_failureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "other error",
+			err:  errors.New("something went wrong"),
+			want: v2.UpgradeFailedReason,
+		},
+		{
+			name: "wait timeout",
+			err:  errors.New("post-upgrade hooks failed: 1 error occurred:\n\t* timed out waiting for the condition"),
+			want: v2.WaitTimeoutReason,
+		},
+		{
+			name: "render error",
+			err:  errors.New("template: podinfo/templates/deployment.yaml:3:4: executing \"podinfo/templates/deployment.yaml\" at <.Values.foo>: nil pointer"),
+			want: v2.RenderFailedReason,
+		},
+		{
+			name: "rbac denied",
+			err:  errors.New("secrets is forbidden: User \"system:serviceaccount:apps:default\" cannot create resource \"secrets\""),
+			want: v2.RBACDeniedReason,
+		},
+		{
+			name: "pod security violation",
+			err:  fmt.Errorf("pre-check failed: %w", &podsecurity.ViolationError{Level: podsecurity.LevelRestricted}),
+			want: v2.PolicyViolationReason,
+		},
+		{
+			name: "quota exceeded",
+			err:  fmt.Errorf("pre-check failed: %w", &quota.ExceededError{Namespace: "default"}),
+			want: v2.QuotaExceededReason,
+		},
+		{
+			name: "incompatible APIs",
+			err:  fmt.Errorf("pre-check failed: %w", &apicompat.IncompatibleError{Unavailable: []string{"batch/v1beta1 apps/CronJob/backup"}}),
+			want: v2.IncompatibleAPIsReason,
+		},
+		{
+			name: "invalid custom resources",
+			err:  fmt.Errorf("pre-check failed: %w", &crdschema.InvalidError{Invalid: []string{"Widget/apps/widget: spec.size is required"}}),
+			want: v2.InvalidCustomResourcesReason,
+		},
+		{
+			name: "recreate required",
+			err:  fmt.Errorf("post render: %w", &recreate.RequiredError{Objects: []string{"PersistentVolumeClaim/apps/data: removed from the release"}}),
+			want: v2.RecreateRequiredReason,
+		},
+		{
+			name: "storage too large",
+			err:  fmt.Errorf("create: failed to create: %w", &storage.TooLargeError{Name: "sh.helm.release.v1.podinfo.v1"}),
+			want: v2.StorageTooLargeReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(failureReason(tt.err, v2.UpgradeFailedReason)).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_observeHookEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	since := time.Now()
+	hookRun := func(started time.Duration, took time.Duration, phase helmrelease.HookPhase) helmrelease.HookExecution {
+		return helmrelease.HookExecution{
+			StartedAt:   helmtime.Time{Time: since.Add(started)},
+			CompletedAt: helmtime.Time{Time: since.Add(started + took)},
+			Phase:       phase,
+		}
+	}
+
+	rls := testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+		Name:      mockReleaseName,
+		Namespace: mockReleaseNamespace,
+		Version:   2,
+		Chart:     testutil.BuildChart(),
+	})
+	rls.Hooks = []*helmrelease.Hook{
+		{
+			Name:    "migrate",
+			Kind:    "Job",
+			Events:  []helmrelease.HookEvent{helmrelease.HookPreInstall, helmrelease.HookPreUpgrade},
+			LastRun: hookRun(time.Second, 2*time.Second, helmrelease.HookPhaseSucceeded),
+		},
+		{
+			Name:    "notify",
+			Kind:    "Job",
+			Events:  []helmrelease.HookEvent{helmrelease.HookPostUpgrade},
+			LastRun: hookRun(4*time.Second, time.Second, helmrelease.HookPhaseFailed),
+		},
+		{
+			Name:    "previous",
+			Kind:    "Job",
+			Events:  []helmrelease.HookEvent{helmrelease.HookPreUpgrade},
+			LastRun: hookRun(-time.Hour, time.Second, helmrelease.HookPhaseSucceeded),
+		},
+		{
+			Name:    "running",
+			Kind:    "Job",
+			Events:  []helmrelease.HookEvent{helmrelease.HookPostUpgrade},
+			LastRun: hookRun(time.Second, 0, helmrelease.HookPhaseRunning),
+		},
+		{
+			Name:    "test",
+			Kind:    "Pod",
+			Events:  []helmrelease.HookEvent{helmrelease.HookTest},
+			LastRun: hookRun(time.Second, time.Second, helmrelease.HookPhaseSucceeded),
+		},
+	}
+
+	recorder := testutil.NewFakeRecorder(10, false)
+	observe := observeHookEvents(recorder, &v2.HelmRelease{}, since, helmrelease.HookPreUpgrade, helmrelease.HookPostUpgrade)
+
+	// Observe the release twice, as Helm may write it multiple times.
+	observe(rls)
+	observe(rls)
+
+	events := recorder.GetEvents()
+	g.Expect(events).To(HaveLen(2))
+	g.Expect(events[0].Type).To(Equal(corev1.EventTypeNormal))
+	g.Expect(events[0].Reason).To(Equal(HookSucceededReason))
+	g.Expect(events[0].Message).To(Equal("Helm pre-upgrade hook Job/migrate succeeded after 2s for release mock-ns/mock-release.v2"))
+	g.Expect(events[1].Type).To(Equal(corev1.EventTypeWarning))
+	g.Expect(events[1].Reason).To(Equal(HookFailedReason))
+	g.Expect(events[1].Message).To(Equal("Helm post-upgrade hook Job/notify failed after 1s for release mock-ns/mock-release.v2"))
+}
+
+// auditRecorder is an audit.Sink which stores the written records.
+type auditRecorder []audit.Record
+
+func (r *auditRecorder) Write(_ context.Context, rec audit.Record) error {
+	*r = append(*r, rec)
+	return nil
+}
+
+func Test_recordAction(t *testing.T) {
+	g := NewWithT(t)
+
+	records := &auditRecorder{}
+	audit.SetSink(records)
+	t.Cleanup(func() { audit.SetSink(nil) })
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       mockReleaseName,
+			Namespace:  mockReleaseNamespace,
+			UID:        "uid",
+			Generation: 2,
+		},
+		Spec: v2.HelmReleaseSpec{
+			ServiceAccountName: "deployer",
+		},
+		Status: v2.HelmReleaseStatus{
+			History: v2.Snapshots{
+				{
+					Name:         mockReleaseName,
+					Namespace:    mockReleaseNamespace,
+					Version:      1,
+					ChartName:    "chart",
+					ChartVersion: "0.1.0",
+					ConfigDigest: "sha256:previous",
+				},
+			},
+		},
+	}
+	chrt := testutil.BuildChart()
+	values := map[string]interface{}{"foo": "bar"}
+
+	start := time.Now()
+	recordAction(context.TODO(), NewUpgrade(nil, nil), &Request{Object: obj, Chart: chrt, Values: values}, start, errors.New("upgrade failed"))
+	recordAction(context.TODO(), NewRollbackRemediation(nil, nil), &Request{Object: obj, Chart: chrt, Values: values}, start, nil)
+
+	g.Expect(*records).To(HaveLen(2))
+
+	upgrade := (*records)[0]
+	g.Expect(upgrade.Action).To(Equal("upgrade"))
+	g.Expect(upgrade.Result).To(Equal(audit.ResultFailure))
+	g.Expect(upgrade.Error).To(Equal("upgrade failed"))
+	g.Expect(upgrade.ServiceAccount).To(Equal("deployer"))
+	g.Expect(upgrade.Object).To(Equal(audit.Object{
+		Name:       mockReleaseName,
+		Namespace:  mockReleaseNamespace,
+		UID:        "uid",
+		Generation: 2,
+	}))
+	g.Expect(upgrade.Release.Version).To(Equal(1))
+	g.Expect(upgrade.Release.ChartName).To(Equal(chrt.Name()))
+	g.Expect(upgrade.Release.ChartVersion).To(Equal(chrt.Metadata.Version))
+	g.Expect(upgrade.Release.ValuesDigest).ToNot(Equal("sha256:previous"))
+
+	rollback := (*records)[1]
+	g.Expect(rollback.Action).To(Equal("rollback"))
+	g.Expect(rollback.Result).To(Equal(audit.ResultSuccess))
+	g.Expect(rollback.Error).To(BeEmpty())
+	g.Expect(rollback.Release).To(Equal(audit.Release{
+		Name:         mockReleaseName,
+		Namespace:    mockReleaseNamespace,
+		Version:      1,
+		ChartName:    "chart",
+		ChartVersion: "0.1.0",
+		ValuesDigest: "sha256:previous",
+	}))
+}
+
+func Test_pruneHistory(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v2.HelmRelease{
+		Spec: v2.HelmReleaseSpec{
+			History: &v2.HistoryRetention{
+				MaxSnapshots: ptr.To(3),
+				TTL:          &metav1.Duration{Duration: time.Hour},
+			},
+		},
+		Status: v2.HelmReleaseStatus{
+			History: v2.Snapshots{
+				{Version: 5, Status: helmrelease.StatusFailed.String(), LastDeployed: metav1.Now()},
+				{Version: 4, Status: helmrelease.StatusFailed.String(), LastDeployed: metav1.NewTime(time.Now().Add(-2 * time.Hour))},
+				{Version: 3, Status: helmrelease.StatusFailed.String(), LastDeployed: metav1.Now()},
+				{Version: 2, Status: helmrelease.StatusFailed.String(), LastDeployed: metav1.Now()},
+				{Version: 1, Status: helmrelease.StatusSuperseded.String(), LastDeployed: metav1.NewTime(time.Now().Add(-2 * time.Hour))},
+			},
+		},
+	}
+
+	pruneHistory(obj)
+
+	var versions []int
+	for _, snap := range obj.Status.History {
+		versions = append(versions, snap.Version)
+	}
+	g.Expect(versions).To(Equal([]int{5, 3, 1}))
+}
+
+func TestCompactStatus(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v2.HelmRelease{
+		Status: v2.HelmReleaseStatus{
+			Conditions: []metav1.Condition{
+				{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Message: strings.Repeat("a", compactMessageLength-1) + "é and more"},
+				{Type: v2.ReleasedCondition, Status: metav1.ConditionTrue, Message: "short"},
+			},
+			History: v2.Snapshots{
+				{Version: 4, Status: helmrelease.StatusFailed.String(), Notes: "notes"},
+				{Version: 3, Status: helmrelease.StatusFailed.String()},
+				{Version: 2, Status: helmrelease.StatusSuperseded.String(), Notes: "notes"},
+				{Version: 1, Status: helmrelease.StatusSuperseded.String()},
+			},
+			LastReleaseAttempt: &v2.ReleaseAttempt{
+				Action: "upgrade",
+				Logs: []v2.LogEntry{
+					{Level: "info", Message: "logs"},
+					{Level: "warning", Message: "warning: failed"},
+				},
+			},
+		},
+	}
+
+	CompactStatus(obj)
+
+	g.Expect(obj.Status.Conditions[0].Message).To(Equal(strings.Repeat("a", compactMessageLength-1) + compactMessageSuffix))
+	g.Expect(obj.Status.Conditions[1].Message).To(Equal("short"))
+
+	var versions []int
+	for _, snap := range obj.Status.History {
+		versions = append(versions, snap.Version)
+		g.Expect(snap.Notes).To(BeEmpty())
+	}
+	g.Expect(versions).To(Equal([]int{4, 3, 2}))
+
+	g.Expect(obj.Status.LastReleaseAttempt.Logs).To(Equal([]v2.LogEntry{{Level: "warning", Message: "warning: failed"}}))
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/chartutil"
+	"github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+const (
+	// RenderedManifestKey is the key of the rendered manifest in the data of
+	// the ConfigMap written by Render.
+	RenderedManifestKey = "manifest.yaml"
+
+	// maxRenderedManifestSize is the maximum size of a rendered manifest
+	// which can be written to a ConfigMap, leaving room for the metadata of
+	// the ConfigMap within the 1MiB limit of the Kubernetes API.
+	maxRenderedManifestSize = 1000 * 1024
+)
+
+// errRenderedManifestNotOwned is returned when the ConfigMap a rendered
+// manifest is written to exists, but is not owned by the HelmRelease.
+var errRenderedManifestNotOwned = errors.New("ConfigMap exists and is not owned by the HelmRelease")
+
+var (
+	// renderedDigestAnnotation is the annotation on the ConfigMap written by
+	// Render containing the digest of the rendered manifest.
+	renderedDigestAnnotation = v2.GroupVersion.Group + "/digest"
+	// renderedChartAnnotation is the annotation on the ConfigMap written by
+	// Render containing the name and version of the rendered chart.
+	renderedChartAnnotation = v2.GroupVersion.Group + "/chart"
+)
+
+// Render renders the manifest of the Helm release for the Request.Object,
+// Request.Chart and Request.Values, and writes it to a ConfigMap for review
+// without touching the Helm storage. The ConfigMap is named after the
+// Request.Object, and is owned by it. An existing ConfigMap with the same
+// name which is not owned by the Request.Object is not overwritten.
+//
+// On success, the digest of the manifest is written to the
+// Status.LastRenderedManifestDigest field, the object is marked with
+// Ready=True, and an event is emitted when the digest changed. On failure,
+// the object is marked with Ready=False, a warning event is emitted and the
+// error is returned.
+type Render struct {
+	client        client.Client
+	configFactory *action.ConfigFactory
+	eventRecorder record.EventRecorder
+}
+
+// NewRender returns a new Render reconciler configured with the provided
+// values.
+func NewRender(client client.Client, cfg *action.ConfigFactory, recorder record.EventRecorder) *Render {
+	return &Render{client: client, configFactory: cfg, eventRecorder: recorder}
+}
+
+const (
+	// fmtRenderFailure is the message format for a render failure.
+	fmtRenderFailure = "Failed to render manifest for release %s with chart %s: %s"
+	// fmtRenderSuccess is the message format for a successful render.
+	fmtRenderSuccess = "Rendered manifest for release %s with chart %s to ConfigMap '%s'"
+)
+
+func (r *Render) Reconcile(ctx context.Context, req *Request) error {
+	var (
+		obj         = req.Object
+		releaseName = fmt.Sprintf("%s/%s", obj.GetReleaseNamespace(), release.ShortenName(obj.GetReleaseName()))
+		chartName   = fmt.Sprintf("%s@%s", req.Chart.Name(), req.Chart.Metadata.Version)
+	)
+
+	manifest, manifestDigest, err := action.Render(ctx, r.configFactory.Build(nil), obj, req.Chart, req.Values)
+	if err == nil && len(manifest) > maxRenderedManifestSize {
+		err = fmt.Errorf("rendered manifest of %d bytes exceeds the maximum size of %d bytes", len(manifest), maxRenderedManifestSize)
+	}
+	if err != nil {
+		msg := fmt.Sprintf(fmtRenderFailure, releaseName, chartName, err.Error())
+		conditions.MarkFalse(obj, meta.ReadyCondition, v2.RenderFailedReason, "%s", msg)
+		r.eventRecorder.Eventf(obj, corev1.EventTypeWarning, v2.RenderFailedReason, "%s", msg)
+		return err
+	}
+
+	key, err := writeRenderedManifest(ctx, r.client, obj, manifest, manifestDigest, chartName)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, v2.RenderFailedReason, "%s", err.Error())
+		return err
+	}
+
+	msg := fmt.Sprintf(fmtRenderSuccess, releaseName, chartName, key)
+	if manifestDigest != obj.Status.LastRenderedManifestDigest {
+		ctrl.LoggerFrom(ctx).Info(msg)
+		r.eventRecorder.AnnotatedEventf(obj,
+			eventMeta(req.Chart.Metadata.Version, chartutil.DigestValues(digest.Canonical, req.Values).String()),
+			corev1.EventTypeNormal, v2.RenderSucceededReason, "%s", msg)
+	}
+	obj.Status.LastRenderedManifestDigest = manifestDigest
+	conditions.MarkTrue(obj, meta.ReadyCondition, v2.RenderSucceededReason, "%s", msg)
+	return nil
+}
+
+// writeRenderedManifest writes the given manifest to the ConfigMap named
+// after the given object, and returns the key of the ConfigMap. It refuses to
+// overwrite a ConfigMap which is not owned by the object.
+func writeRenderedManifest(ctx context.Context, c client.Client, obj *v2.HelmRelease,
+	manifest, manifestDigest, chartName string) (client.ObjectKey, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RenderedManifestName(obj),
+			Namespace: obj.GetNamespace(),
+		},
+	}
+	key := client.ObjectKeyFromObject(cm)
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, cm, func() error {
+		if cm.ResourceVersion != "" && !metav1.IsControlledBy(cm, obj) {
+			return errRenderedManifestNotOwned
+		}
+		if cm.Annotations == nil {
+			cm.Annotations = make(map[string]string, 2)
+		}
+		cm.Annotations[renderedDigestAnnotation] = manifestDigest
+		cm.Annotations[renderedChartAnnotation] = chartName
+		cm.Data = map[string]string{RenderedManifestKey: manifest}
+		return controllerutil.SetControllerReference(obj, cm, c.Scheme())
+	}); err != nil {
+		return key, fmt.Errorf("failed to write rendered manifest to ConfigMap '%s': %w", key, err)
+	}
+	return key, nil
+}
+
+// RenderedManifestName returns the name of the ConfigMap the rendered
+// manifest of the given object is written to.
+func RenderedManifestName(obj *v2.HelmRelease) string {
+	return obj.GetName() + "-rendered"
+}
+
+// DeleteRenderedManifest deletes the ConfigMap with the rendered manifest of
+// the given object, and clears the Status.LastRenderedManifestDigest field.
+// It is a no-op if no manifest was rendered. A ConfigMap with the same name
+// which is not owned by the object is left in place.
+func DeleteRenderedManifest(ctx context.Context, c client.Client, obj *v2.HelmRelease) error {
+	if obj.Status.LastRenderedManifestDigest == "" {
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: RenderedManifestName(obj)}
+	if err := c.Get(ctx, key, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get rendered manifest ConfigMap '%s': %w", key, err)
+		}
+	} else if metav1.IsControlledBy(cm, obj) {
+		if err = c.Delete(ctx, cm, client.Preconditions{UID: &cm.UID}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete rendered manifest ConfigMap '%s': %w", key, err)
+		}
+	}
+	obj.Status.LastRenderedManifestDigest = ""
+	return nil
+}
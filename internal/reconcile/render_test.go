@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func Test_writeRenderedManifest(t *testing.T) {
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "release",
+			Namespace: "default",
+			UID:       "uid",
+		},
+	}
+
+	t.Run("creates and updates owned ConfigMap", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := fake.NewClientBuilder().WithScheme(NewTestScheme()).Build()
+
+		key, err := writeRenderedManifest(context.TODO(), c, obj, "v1", "sha256:1", "podinfo@6.0.0")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(key).To(Equal(client.ObjectKey{Namespace: "default", Name: "release-rendered"}))
+
+		_, err = writeRenderedManifest(context.TODO(), c, obj, "v2", "sha256:2", "podinfo@6.0.1")
+		g.Expect(err).ToNot(HaveOccurred())
+
+		cm := &corev1.ConfigMap{}
+		g.Expect(c.Get(context.TODO(), key, cm)).To(Succeed())
+		g.Expect(cm.Data).To(HaveKeyWithValue(RenderedManifestKey, "v2"))
+		g.Expect(metav1.IsControlledBy(cm, obj)).To(BeTrue())
+	})
+
+	t.Run("refuses to overwrite ConfigMap not owned by object", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "release-rendered",
+				},
+				Data: map[string]string{"key": "value"},
+			}).
+			Build()
+
+		key, err := writeRenderedManifest(context.TODO(), c, obj, "v1", "sha256:1", "podinfo@6.0.0")
+		g.Expect(err).To(MatchError(errRenderedManifestNotOwned))
+
+		cm := &corev1.ConfigMap{}
+		g.Expect(c.Get(context.TODO(), key, cm)).To(Succeed())
+		g.Expect(cm.Data).To(Equal(map[string]string{"key": "value"}))
+	})
+}
+
+func TestDeleteRenderedManifest(t *testing.T) {
+	t.Run("deletes rendered manifest", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "release",
+				Namespace: "default",
+				UID:       "uid",
+			},
+			Status: v2.HelmReleaseStatus{
+				LastRenderedManifestDigest: "sha256:1dabc4e3cbbd6a0818bd460f3a6c9855bfe95d506c74726bc0f2edb0aecb1f4e",
+			},
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "release-rendered",
+			},
+		}
+		g.Expect(controllerutil.SetControllerReference(obj, cm, NewTestScheme())).To(Succeed())
+
+		c := fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithObjects(cm).
+			Build()
+
+		g.Expect(DeleteRenderedManifest(context.TODO(), c, obj)).To(Succeed())
+		g.Expect(obj.Status.LastRenderedManifestDigest).To(BeEmpty())
+
+		err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "release-rendered"}, &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		// Deleting again is a no-op.
+		g.Expect(DeleteRenderedManifest(context.TODO(), c, obj)).To(Succeed())
+	})
+
+	t.Run("leaves ConfigMap not owned by object", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := fake.NewClientBuilder().
+			WithScheme(NewTestScheme()).
+			WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "release-rendered",
+				},
+			}).
+			Build()
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "release",
+				Namespace: "default",
+				UID:       "uid",
+			},
+			Status: v2.HelmReleaseStatus{
+				LastRenderedManifestDigest: "sha256:1dabc4e3cbbd6a0818bd460f3a6c9855bfe95d506c74726bc0f2edb0aecb1f4e",
+			},
+		}
+		g.Expect(DeleteRenderedManifest(context.TODO(), c, obj)).To(Succeed())
+		g.Expect(obj.Status.LastRenderedManifestDigest).To(BeEmpty())
+		g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "release-rendered"}, &corev1.ConfigMap{})).To(Succeed())
+	})
+
+	t.Run("ignores missing ConfigMap", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := fake.NewClientBuilder().WithScheme(NewTestScheme()).Build()
+
+		obj := &v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "release",
+				Namespace: "default",
+			},
+			Status: v2.HelmReleaseStatus{
+				LastRenderedManifestDigest: "sha256:1dabc4e3cbbd6a0818bd460f3a6c9855bfe95d506c74726bc0f2edb0aecb1f4e",
+			},
+		}
+		g.Expect(DeleteRenderedManifest(context.TODO(), c, obj)).To(Succeed())
+		g.Expect(obj.Status.LastRenderedManifestDigest).To(BeEmpty())
+	})
+}
@@ -20,14 +20,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
-	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/fluxcd/pkg/runtime/conditions"
-	"github.com/fluxcd/pkg/runtime/logger"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/action"
@@ -76,8 +75,9 @@ func NewRollbackRemediation(configFactory *action.ConfigFactory, eventRecorder r
 func (r *RollbackRemediation) Reconcile(ctx context.Context, req *Request) error {
 	var (
 		cur    = req.Object.Status.History.Latest().DeepCopy()
-		logBuf = action.NewLogBuffer(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.DebugLevel)), 10)
-		cfg    = r.configFactory.Build(logBuf.Log, observeRollback(req.Object))
+		logBuf = newLogBuffer(ctx, req.Object)
+		cfg    = r.configFactory.Build(logBuf.Log, observeRollback(req.Object),
+			observeHookEvents(r.eventRecorder, req.Object, time.Now(), helmrelease.HookPreRollback, helmrelease.HookPostRollback))
 	)
 
 	defer summarize(req)
@@ -95,7 +95,10 @@ func (r *RollbackRemediation) Reconcile(ctx context.Context, req *Request) error
 	}
 
 	// Run the Helm rollback action.
-	if err := action.Rollback(cfg, req.Object, prev.Name, action.RollbackToVersion(prev.Version)); err != nil {
+	start := time.Now()
+	err := action.Rollback(ctx, cfg, req.Object, prev.Name, action.RollbackToVersion(prev.Version))
+	recordAction(ctx, r, req, start, err)
+	if err != nil {
 		r.failure(req, prev, logBuf, err)
 
 		// Return error if we did not store a release, as this does not
@@ -139,12 +142,15 @@ func (r *RollbackRemediation) failure(req *Request, prev *v2.Snapshot, buffer *a
 	req.Object.Status.Failures++
 	conditions.MarkFalse(req.Object, v2.RemediatedCondition, v2.RollbackFailedReason, msg)
 
+	// Record the logs of the failed attempt on object.
+	recordReleaseAttempt(req.Object, r.Name(), buffer)
+
 	// Record warning event, this message contains more data than the
 	// Condition summary.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
 		eventMeta(prev.ChartVersion, chartutil.DigestValues(digest.Canonical, req.Values).String(),
-			addAppVersion(prev.AppVersion), addOCIDigest(prev.OCIDigest)),
+			addAppVersion(prev.AppVersion), addOCIDigest(prev.OCIDigest), addProvenance(prev.Provenance), addErrorCode(err)),
 		corev1.EventTypeWarning,
 		v2.RollbackFailedReason,
 		eventMessageWithLog(msg, buffer),
@@ -164,7 +170,7 @@ func (r *RollbackRemediation) success(req *Request, prev *v2.Snapshot) {
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
 		eventMeta(prev.ChartVersion, chartutil.DigestValues(digest.Canonical, req.Values).String(),
-			addAppVersion(prev.AppVersion), addOCIDigest(prev.OCIDigest)),
+			addAppVersion(prev.AppVersion), addOCIDigest(prev.OCIDigest), addProvenance(prev.Provenance)),
 		corev1.EventTypeNormal,
 		v2.RollbackSucceededReason,
 		msg,
@@ -416,6 +416,7 @@ func TestRollbackRemediation_failure(t *testing.T) {
 						eventMetaGroupKey(eventv1.MetaRevisionKey): prev.Chart.Metadata.Version,
 						eventMetaGroupKey(metaAppVersionKey):       prev.Chart.Metadata.AppVersion,
 						eventMetaGroupKey(eventv1.MetaTokenKey):    chartutil.DigestValues(digest.Canonical, req.Values).String(),
+						eventMetaGroupKey(metaErrorCodeKey):        string(action.ErrorCodeUnknown),
 					},
 				},
 			},
@@ -430,7 +431,7 @@ func TestRollbackRemediation_failure(t *testing.T) {
 			eventRecorder: recorder,
 		}
 		req := &Request{Object: obj.DeepCopy()}
-		r.failure(req, release.ObservedToSnapshot(release.ObserveRelease(prev)), mockLogBuffer(5, 10), err)
+		r.failure(req, release.ObservedToSnapshot(release.ObserveRelease(prev)), mockWarningLogBuffer(5, 10), err)
 
 		expectSubStr := "Last Helm logs"
 		g.Expect(conditions.IsFalse(req.Object, v2.RemediatedCondition)).To(BeTrue())
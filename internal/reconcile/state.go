@@ -29,9 +29,9 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/fluxcd/helm-controller/internal/action"
-	"github.com/fluxcd/helm-controller/internal/digest"
 	interrors "github.com/fluxcd/helm-controller/internal/errors"
 	"github.com/fluxcd/helm-controller/internal/postrender"
+	"github.com/fluxcd/helm-controller/internal/release"
 )
 
 // ReleaseStatus represents the status of a Helm release as determined by
@@ -154,11 +154,13 @@ func DetermineReleaseState(ctx context.Context, cfg *action.ConfigFactory, req *
 		// for new generations only.
 		ready := conditions.Get(req.Object, meta.ReadyCondition)
 		if ready != nil && ready.ObservedGeneration != req.Object.Generation {
-			var postrenderersDigest string
-			if req.Object.Spec.PostRenderers != nil {
-				postrenderersDigest = postrender.Digest(digest.Canonical, req.Object.Spec.PostRenderers).String()
+			// The observed digest is verified with its own algorithm, as it
+			// may have been calculated with a previously configured one.
+			changed := req.Object.Status.ObservedPostRenderersDigest != ""
+			if postrender.HasPostRenderers(req.Object) {
+				changed = !postrender.VerifyDigest(req.Object.Status.ObservedPostRenderersDigest, req.Object)
 			}
-			if postrenderersDigest != req.Object.Status.ObservedPostRenderersDigest {
+			if changed {
 				return ReleaseState{Status: ReleaseStatusOutOfSync, Reason: "postrenderers digest has changed"}, nil
 			}
 		}
@@ -167,8 +169,9 @@ func DetermineReleaseState(ctx context.Context, cfg *action.ConfigFactory, req *
 		// observed state of the object. As tests can be run manually by
 		// users running e.g. `helm test`.
 		if testSpec := req.Object.GetTest(); testSpec.Enable {
-			// Confirm the release has been tested if enabled.
-			if !cur.HasBeenTested() {
+			// Confirm the release has been tested if enabled, unless it
+			// was created by a rollback which must not be tested.
+			if !cur.HasBeenTested() && !(req.Object.GetRollback().DisableTestHooks && release.IsRollback(rls)) {
 				return ReleaseState{Status: ReleaseStatusUntested}, nil
 			}
 
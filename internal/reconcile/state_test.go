@@ -219,6 +219,39 @@ func Test_DetermineReleaseState(t *testing.T) {
 				Status: ReleaseStatusUntested,
 			},
 		},
+		{
+			name: "untested rollback release with disabled test hooks",
+			releases: []*helmrelease.Release{
+				testutil.BuildRelease(&helmrelease.MockReleaseOptions{
+					Name:      mockReleaseName,
+					Namespace: mockReleaseNamespace,
+					Version:   1,
+					Status:    helmrelease.StatusDeployed,
+					Chart:     testutil.BuildChart(),
+				}, testutil.ReleaseWithConfig(map[string]interface{}{"foo": "bar"}),
+					testutil.ReleaseWithDescription("Rollback to 0")),
+			},
+			spec: func(spec *v2.HelmReleaseSpec) {
+				spec.Test = &v2.Test{
+					Enable: true,
+				}
+				spec.Rollback = &v2.Rollback{
+					DisableTestHooks: true,
+				}
+			},
+			status: func(releases []*helmrelease.Release) v2.HelmReleaseStatus {
+				return v2.HelmReleaseStatus{
+					History: v2.Snapshots{
+						release.ObservedToSnapshot(release.ObserveRelease(releases[0])),
+					},
+				}
+			},
+			chart:  testutil.BuildChart(),
+			values: map[string]interface{}{"foo": "bar"},
+			want: ReleaseState{
+				Status: ReleaseStatusInSync,
+			},
+		},
 		{
 			name: "failed test",
 			releases: []*helmrelease.Release{
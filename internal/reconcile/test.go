@@ -20,12 +20,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/fluxcd/pkg/runtime/logger"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
-	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/fluxcd/pkg/runtime/conditions"
 
@@ -72,8 +71,9 @@ func NewTest(cfg *action.ConfigFactory, recorder record.EventRecorder) *Test {
 
 func (r *Test) Reconcile(ctx context.Context, req *Request) error {
 	var (
-		cur = req.Object.Status.History.Latest().DeepCopy()
-		cfg = r.configFactory.Build(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.DebugLevel)), observeTest(req.Object))
+		cur    = req.Object.Status.History.Latest().DeepCopy()
+		logBuf = newLogBuffer(ctx, req.Object)
+		cfg    = r.configFactory.Build(logBuf.Log, observeTest(req.Object))
 	)
 
 	defer summarize(req)
@@ -84,7 +84,9 @@ func (r *Test) Reconcile(ctx context.Context, req *Request) error {
 	}
 
 	// Run the Helm test action.
+	start := time.Now()
 	rls, err := action.Test(ctx, cfg, req.Object)
+	recordAction(ctx, r, req, start, err)
 
 	// The Helm test action does always target the latest release. Before
 	// accepting results, we need to confirm this is actually the release we
@@ -96,7 +98,7 @@ func (r *Test) Reconcile(ctx context.Context, req *Request) error {
 
 	// Something went wrong.
 	if err != nil {
-		r.failure(req, err)
+		r.failure(req, logBuf, err)
 
 		// If we failed to observe anything happened at all, we want to retry
 		// and return the error to indicate this.
@@ -129,10 +131,12 @@ const (
 
 // failure records the failure of a Helm test action in the status of the given
 // Request.Object by marking TestSuccess=False and increasing the failure
-// counter. In addition, it emits a warning event for the Request.Object.
+// counter, and records the tail of the logs in the given buffer as the last
+// release attempt. In addition, it emits a warning event for the
+// Request.Object.
 // The active remediation failure count is only incremented if test failures
 // are not ignored.
-func (r *Test) failure(req *Request, err error) {
+func (r *Test) failure(req *Request, buffer *action.LogBuffer, err error) {
 	// Compose failure message.
 	cur := req.Object.Status.History.Latest()
 	msg := fmt.Sprintf(fmtTestFailure, cur.FullReleaseName(), cur.VersionedChartName(), strings.TrimSpace(err.Error()))
@@ -141,14 +145,17 @@ func (r *Test) failure(req *Request, err error) {
 	req.Object.Status.Failures++
 	conditions.MarkFalse(req.Object, v2.TestSuccessCondition, v2.TestFailedReason, msg)
 
+	// Record the logs of the failed attempt on object.
+	recordReleaseAttempt(req.Object, r.Name(), buffer)
+
 	// Record warning event, this message contains more data than the
 	// Condition summary.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance), addErrorCode(err)),
 		corev1.EventTypeWarning,
 		v2.TestFailedReason,
-		msg,
+		eventMessageWithLog(msg, buffer),
 	)
 
 	if req.Object.Status.History.Latest().HasBeenTested() {
@@ -181,7 +188,7 @@ func (r *Test) success(req *Request) {
 	// Record event.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)),
 		corev1.EventTypeNormal,
 		v2.TestSucceededReason,
 		msg,
@@ -485,7 +485,7 @@ func TestTest_failure(t *testing.T) {
 		}
 
 		req := &Request{Object: obj.DeepCopy()}
-		r.failure(req, err)
+		r.failure(req, nil, err)
 
 		expectMsg := fmt.Sprintf(fmtTestFailure,
 			fmt.Sprintf("%s/%s.v%d", cur.Namespace, cur.Name, cur.Version),
@@ -508,12 +508,34 @@ func TestTest_failure(t *testing.T) {
 						eventMetaGroupKey(eventv1.MetaRevisionKey): cur.Chart.Metadata.Version,
 						eventMetaGroupKey(metaAppVersionKey):       cur.Chart.Metadata.AppVersion,
 						eventMetaGroupKey(eventv1.MetaTokenKey):    chartutil.DigestValues(digest.Canonical, cur.Config).String(),
+						eventMetaGroupKey(metaErrorCodeKey):        string(action.ErrorCodeUnknown),
 					},
 				},
 			},
 		}))
 	})
 
+	t.Run("records release attempt", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := testutil.NewFakeRecorder(10, false)
+		r := &Test{
+			eventRecorder: recorder,
+		}
+
+		req := &Request{Object: obj.DeepCopy()}
+		r.failure(req, mockWarningLogBuffer(5, 10), err)
+
+		attempt := req.Object.Status.LastReleaseAttempt
+		g.Expect(attempt).ToNot(BeNil())
+		g.Expect(attempt.Action).To(Equal(r.Name()))
+		g.Expect(attempt.Logs).To(HaveLen(5))
+		g.Expect(attempt.Logs[4].Message).To(Equal("warning: line 10"))
+
+		expectSubStr := "Last Helm logs"
+		g.Expect(recorder.GetEvents()[0].Message).To(ContainSubstring(expectSubStr))
+	})
+
 	t.Run("increases remediation failure count", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -526,7 +548,7 @@ func TestTest_failure(t *testing.T) {
 		obj.Status.LastAttemptedReleaseAction = v2.ReleaseActionInstall
 		obj.Status.History.Latest().SetTestHooks(map[string]*v2.TestHookStatus{})
 		req := &Request{Object: obj}
-		r.failure(req, err)
+		r.failure(req, nil, err)
 
 		g.Expect(req.Object.Status.InstallFailures).To(Equal(int64(1)))
 	})
@@ -543,7 +565,7 @@ func TestTest_failure(t *testing.T) {
 		obj.Spec.Test = &v2.Test{IgnoreFailures: true}
 		obj.Status.History.Latest().SetTestHooks(map[string]*v2.TestHookStatus{})
 		req := &Request{Object: obj}
-		r.failure(req, err)
+		r.failure(req, nil, err)
 
 		g.Expect(req.Object.Status.InstallFailures).To(BeZero())
 	})
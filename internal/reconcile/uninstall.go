@@ -21,7 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	helmaction "helm.sh/helm/v3/pkg/action"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
@@ -29,7 +31,6 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/fluxcd/pkg/runtime/conditions"
-	"github.com/fluxcd/pkg/runtime/logger"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/action"
@@ -81,8 +82,9 @@ func NewUninstall(cfg *action.ConfigFactory, recorder record.EventRecorder) *Uni
 func (r *Uninstall) Reconcile(ctx context.Context, req *Request) error {
 	var (
 		cur    = req.Object.Status.History.Latest().DeepCopy()
-		logBuf = action.NewLogBuffer(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.DebugLevel)), 10)
-		cfg    = r.configFactory.Build(logBuf.Log, observeUninstall(req.Object))
+		logBuf = newLogBuffer(ctx, req.Object)
+		cfg    = r.configFactory.Build(logBuf.Log, observeUninstall(req.Object),
+			observeHookEvents(r.eventRecorder, req.Object, time.Now(), helmrelease.HookPreDelete, helmrelease.HookPostDelete))
 	)
 
 	defer summarize(req)
@@ -92,8 +94,13 @@ func (r *Uninstall) Reconcile(ctx context.Context, req *Request) error {
 		return fmt.Errorf("%w: required to uninstall", ErrNoLatest)
 	}
 
+	// Record the resources about to be deleted, as a last audit trail.
+	r.preview(ctx, cfg, req, cur)
+
 	// Run the Helm uninstall action.
+	start := time.Now()
 	res, err := action.Uninstall(ctx, cfg, req.Object, cur.Name)
+	recordAction(ctx, r, req, start, err)
 
 	// When the release is not found, something else has already uninstalled
 	// the release. As such, we can assume the release is uninstalled while
@@ -158,12 +165,50 @@ func (r *Uninstall) Type() ReconcilerType {
 }
 
 const (
+	// fmtUninstallPreview is the message format for the preview of an
+	// uninstall.
+	fmtUninstallPreview = "Helm uninstall for release %s with chart %s will delete %s"
 	// fmtUninstallFailed is the message format for an uninstall failure.
 	fmtUninstallFailure = "Helm uninstall failed for release %s with chart %s: %s"
 	// fmtUninstallSuccess is the message format for a successful uninstall.
 	fmtUninstallSuccess = "Helm uninstall succeeded for release %s with chart %s"
 )
 
+// maxPreviewResources is the maximum number of resources listed in the
+// event emitted by preview.
+const maxPreviewResources = 20
+
+// preview emits an event listing the resources of the given current release
+// which are about to be deleted by the Helm uninstall action. Failing to
+// compose the preview does not prevent the uninstall, and is only logged.
+func (r *Uninstall) preview(ctx context.Context, cfg *helmaction.Configuration, req *Request, cur *v2.Snapshot) {
+	ids, err := action.UninstallPreview(cfg, req.Object, cur.Name)
+	if err != nil {
+		if !errors.Is(err, helmdriver.ErrReleaseNotFound) {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to compose uninstall preview")
+		}
+		return
+	}
+
+	resources := "no resources"
+	if len(ids) > 0 {
+		listed := ids
+		if len(listed) > maxPreviewResources {
+			listed = append(listed[:maxPreviewResources:maxPreviewResources],
+				fmt.Sprintf("and %d more", len(ids)-maxPreviewResources))
+		}
+		resources = fmt.Sprintf("%d resources: %s", len(ids), strings.Join(listed, ", "))
+	}
+
+	r.eventRecorder.AnnotatedEventf(
+		req.Object,
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)),
+		corev1.EventTypeNormal,
+		v2.UninstallPreviewReason,
+		fmtUninstallPreview, cur.FullReleaseName(), cur.VersionedChartName(), resources,
+	)
+}
+
 // failure records the failure of a Helm uninstall action in the status of the
 // given Request.Object by marking Released=False and emitting a warning
 // event.
@@ -176,11 +221,14 @@ func (r *Uninstall) failure(req *Request, buffer *action.LogBuffer, err error) {
 	req.Object.Status.Failures++
 	conditions.MarkFalse(req.Object, v2.ReleasedCondition, v2.UninstallFailedReason, msg)
 
+	// Record the logs of the failed attempt on object.
+	recordReleaseAttempt(req.Object, r.Name(), buffer)
+
 	// Record warning event, this message contains more data than the
 	// Condition summary.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance), addErrorCode(err)),
 		corev1.EventTypeWarning, v2.UninstallFailedReason,
 		eventMessageWithLog(msg, buffer),
 	)
@@ -201,7 +249,7 @@ func (r *Uninstall) success(req *Request) {
 	// Condition summary.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)),
 		corev1.EventTypeNormal,
 		v2.UninstallSucceededReason,
 		msg,
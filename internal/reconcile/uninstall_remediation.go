@@ -21,13 +21,13 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	helmrelease "helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
-	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/fluxcd/pkg/runtime/conditions"
-	"github.com/fluxcd/pkg/runtime/logger"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/action"
@@ -81,8 +81,9 @@ func NewUninstallRemediation(cfg *action.ConfigFactory, recorder record.EventRec
 func (r *UninstallRemediation) Reconcile(ctx context.Context, req *Request) error {
 	var (
 		cur    = req.Object.Status.History.Latest().DeepCopy()
-		logBuf = action.NewLogBuffer(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.DebugLevel)), 10)
-		cfg    = r.configFactory.Build(logBuf.Log, observeUninstall(req.Object))
+		logBuf = newLogBuffer(ctx, req.Object)
+		cfg    = r.configFactory.Build(logBuf.Log, observeUninstall(req.Object),
+			observeHookEvents(r.eventRecorder, req.Object, time.Now(), helmrelease.HookPreDelete, helmrelease.HookPostDelete))
 	)
 
 	// Require current to run uninstall.
@@ -91,7 +92,9 @@ func (r *UninstallRemediation) Reconcile(ctx context.Context, req *Request) erro
 	}
 
 	// Run the Helm uninstall action.
+	start := time.Now()
 	res, err := action.Uninstall(ctx, cfg, req.Object, cur.Name)
+	recordAction(ctx, r, req, start, err)
 
 	// The Helm uninstall action does always target the latest release. Before
 	// accepting results, we need to confirm this is actually the release we
@@ -150,11 +153,14 @@ func (r *UninstallRemediation) failure(req *Request, buffer *action.LogBuffer, e
 	req.Object.Status.Failures++
 	conditions.MarkFalse(req.Object, v2.RemediatedCondition, v2.UninstallFailedReason, msg)
 
+	// Record the logs of the failed attempt on object.
+	recordReleaseAttempt(req.Object, r.Name(), buffer)
+
 	// Record warning event, this message contains more data than the
 	// Condition summary.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance), addErrorCode(err)),
 		corev1.EventTypeWarning,
 		v2.UninstallFailedReason,
 		eventMessageWithLog(msg, buffer),
@@ -175,7 +181,7 @@ func (r *UninstallRemediation) success(req *Request) {
 	// Record event.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)),
 		corev1.EventTypeNormal,
 		v2.UninstallSucceededReason,
 		msg,
@@ -119,7 +119,7 @@ func (r *Unlock) failure(req *Request, cur *v2.Snapshot, status helmrelease.Stat
 	// Record warning event.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance), addErrorCode(err)),
 		corev1.EventTypeWarning,
 		"PendingRelease",
 		msg,
@@ -138,7 +138,7 @@ func (r *Unlock) success(req *Request, cur *v2.Snapshot, status helmrelease.Stat
 	// Record event.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)),
 		corev1.EventTypeNormal,
 		"PendingRelease",
 		msg,
@@ -162,14 +162,15 @@ func observeUnlock(obj *v2.HelmRelease) storage.ObserveFunc {
 }
 
 // processCurrentSnaphot processes the current snapshot based on a Helm release.
-// It also looks for the OCIDigest in the corresponding v2.HelmRelease history and
-// updates the current snapshot with the OCIDigest if found.
+// It also looks for the OCIDigest and Provenance in the corresponding
+// v2.HelmRelease history and updates the current snapshot with them if found.
 func processCurrentSnaphot(obj *v2.HelmRelease, rls *helmrelease.Release) *v2.Snapshot {
 	cur := release.ObservedToSnapshot(release.ObserveRelease(rls))
 	for i := range obj.Status.History {
 		snap := obj.Status.History[i]
 		if snap.Targets(rls.Name, rls.Namespace, rls.Version) {
 			cur.OCIDigest = snap.OCIDigest
+			cur.Provenance = snap.Provenance
 		}
 	}
 	return cur
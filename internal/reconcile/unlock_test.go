@@ -406,6 +406,7 @@ func TestUnlock_failure(t *testing.T) {
 					eventMetaGroupKey(eventv1.MetaRevisionKey): cur.Chart.Metadata.Version,
 					eventMetaGroupKey(metaAppVersionKey):       cur.Chart.Metadata.AppVersion,
 					eventMetaGroupKey(eventv1.MetaTokenKey):    chartutil.DigestValues(digest.Canonical, cur.Config).String(),
+					eventMetaGroupKey(metaErrorCodeKey):        string(action.ErrorCodeUnknown),
 				},
 			},
 		},
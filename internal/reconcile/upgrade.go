@@ -20,18 +20,22 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/fluxcd/pkg/runtime/conditions"
-	"github.com/fluxcd/pkg/runtime/logger"
 
 	v2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/fluxcd/helm-controller/internal/action"
 	"github.com/fluxcd/helm-controller/internal/chartutil"
 	"github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/features"
 )
 
 // Upgrade is an ActionReconciler which attempts to upgrade a Helm release
@@ -65,9 +69,10 @@ func NewUpgrade(cfg *action.ConfigFactory, recorder record.EventRecorder) *Upgra
 
 func (r *Upgrade) Reconcile(ctx context.Context, req *Request) error {
 	var (
-		logBuf      = action.NewLogBuffer(action.NewDebugLog(ctrl.LoggerFrom(ctx).V(logger.DebugLevel)), 10)
+		logBuf      = newLogBuffer(ctx, req.Object)
 		obsReleases = make(observedReleases)
-		cfg         = r.configFactory.Build(logBuf.Log, observeRelease(obsReleases))
+		cfg         = r.configFactory.Build(logBuf.Log, observeRelease(obsReleases),
+			observeHookEvents(r.eventRecorder, req.Object, time.Now(), helmrelease.HookPreUpgrade, helmrelease.HookPostUpgrade))
 	)
 
 	defer summarize(req)
@@ -75,15 +80,43 @@ func (r *Upgrade) Reconcile(ctx context.Context, req *Request) error {
 	// Mark upgrade attempt on object.
 	req.Object.Status.LastAttemptedReleaseAction = v2.ReleaseActionUpgrade
 
+	// Take note of the chart version before the upgrade, to describe the
+	// changes of the chart on success.
+	var prevChartVersion string
+	if cur := req.Object.Status.History.Latest(); cur != nil {
+		prevChartVersion = cur.ChartVersion
+	}
+
 	// If we are upgrading, none of the previous conditions apply.
 	conditions.Delete(req.Object, v2.TestSuccessCondition)
 	conditions.Delete(req.Object, v2.RemediatedCondition)
 
-	// Run the Helm upgrade action.
-	_, err := action.Upgrade(ctx, cfg, req.Object, req.Chart, req.Values)
+	// Check the target cluster meets the requirements of the upgrade before
+	// performing it. A failed check does not modify the Helm storage, and
+	// the caller should retry.
+	if err := r.preflight(ctx, cfg, req); err != nil {
+		return err
+	}
+
+	// Publish the changes the upgrade will make before performing it.
+	if preview, _ := features.Enabled(features.UpgradePreview); preview {
+		r.preview(ctx, cfg, req)
+	}
+
+	// Validate the rendered manifests if configured, and run the Helm
+	// upgrade action. A validation error does not modify the Helm storage.
+	start := time.Now()
+	var err error
+	if req.Object.GetUpgrade().Validation == v2.ValidationDryRun {
+		err = action.ValidateUpgrade(ctx, cfg, req.Object, req.Chart, req.Values)
+	}
+	if err == nil {
+		_, err = action.Upgrade(ctx, cfg, req.Object, req.Chart, req.Values)
+	}
 
 	// Record the history of releases observed during the upgrade.
-	obsReleases.recordOnObject(req.Object, mutateOCIDigest)
+	obsReleases.recordOnObject(req.Object, mutateOCIDigest, mutateProvenance(req.Provenance))
+	recordAction(ctx, r, req, start, err)
 
 	if err != nil {
 		r.failure(req, logBuf, err)
@@ -104,10 +137,31 @@ func (r *Upgrade) Reconcile(ctx context.Context, req *Request) error {
 		return nil
 	}
 
-	r.success(req)
+	r.success(req, prevChartVersion)
+	recordDiffSummary(ctx, cfg, req.Object, obsReleases)
 	return nil
 }
 
+// recordDiffSummary records a summary of the changes between the objects of
+// the previous release and the new release observed during the upgrade on
+// the object. The summary is removed if the previous release has not been
+// observed.
+func recordDiffSummary(ctx context.Context, cfg *helmaction.Configuration, obj *v2.HelmRelease, releases observedReleases) {
+	obj.Status.LastUpgradeDiff = nil
+
+	versions := releases.sortedVersions()
+	if len(versions) < 2 {
+		return
+	}
+
+	summary, err := action.SummarizeDiff(cfg, releases[versions[1]], releases[versions[0]])
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to summarize changes of upgrade")
+		return
+	}
+	obj.Status.LastUpgradeDiff = summary
+}
+
 func (r *Upgrade) Name() string {
 	return "upgrade"
 }
@@ -117,12 +171,97 @@ func (r *Upgrade) Type() ReconcilerType {
 }
 
 const (
+	// fmtUpgradePreflightFailure is the message format for a failure of the
+	// preflight checks of an upgrade.
+	fmtUpgradePreflightFailure = "Helm upgrade preflight failed for release %s/%s with chart %s@%s: %s"
+	// fmtUpgradePreview is the message format for the preview of an upgrade.
+	fmtUpgradePreview = "Helm upgrade for release %s/%s with chart %s@%s will change %s"
 	// fmtUpgradeFailure is the message format for an upgrade failure.
 	fmtUpgradeFailure = "Helm upgrade failed for release %s/%s with chart %s@%s: %s"
 	// fmtUpgradeSuccess is the message format for a successful upgrade.
 	fmtUpgradeSuccess = "Helm upgrade succeeded for release %s with chart %s"
 )
 
+// maxChartChanges is the maximum number of changes of the chart listed in the
+// event emitted on upgrade success.
+const maxChartChanges = 20
+
+// preflight performs the preflight checks of the upgrade of the given
+// Request, and records the result on the object with the
+// PreflightPassedCondition. On failure, it emits a warning event and returns
+// the error. The condition is removed when no checks are configured.
+func (r *Upgrade) preflight(ctx context.Context, cfg *helmaction.Configuration, req *Request) error {
+	if req.Object.GetUpgrade().Preflight == nil {
+		conditions.Delete(req.Object, v2.PreflightPassedCondition)
+		return nil
+	}
+
+	if err := action.Preflight(ctx, cfg, req.Object); err != nil {
+		msg := fmt.Sprintf(fmtUpgradePreflightFailure, req.Object.GetReleaseNamespace(), req.Object.GetReleaseName(),
+			req.Chart.Name(), req.Chart.Metadata.Version, strings.TrimSpace(err.Error()))
+		conditions.MarkFalse(req.Object, v2.PreflightPassedCondition, v2.PreflightFailedReason, "%s", msg)
+		r.eventRecorder.AnnotatedEventf(
+			req.Object,
+			eventMeta(req.Chart.Metadata.Version, chartutil.DigestValues(digest.Canonical, req.Values).String(),
+				addAppVersion(req.Chart.AppVersion()), addOCIDigest(req.Object.Status.LastAttemptedRevisionDigest),
+				addProvenance(req.Provenance)),
+			corev1.EventTypeWarning,
+			v2.PreflightFailedReason,
+			msg,
+		)
+		return err
+	}
+
+	conditions.MarkTrue(req.Object, v2.PreflightPassedCondition, v2.PreflightSucceededReason,
+		"Preflight checks passed for release %s/%s", req.Object.GetReleaseNamespace(), req.Object.GetReleaseName())
+	return nil
+}
+
+// preview emits an event with a summary of the changes the upgrade of the
+// deployed release to the chart and values of the given Request will make to
+// the objects of the release. A failure to compose the preview does not
+// prevent the upgrade, and is only logged.
+func (r *Upgrade) preview(ctx context.Context, cfg *helmaction.Configuration, req *Request) {
+	summary, err := action.UpgradePreview(ctx, cfg, req.Object, req.Chart, req.Values)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to compose upgrade preview")
+		return
+	}
+
+	r.eventRecorder.AnnotatedEventf(
+		req.Object,
+		eventMeta(req.Chart.Metadata.Version, chartutil.DigestValues(digest.Canonical, req.Values).String(),
+			addAppVersion(req.Chart.AppVersion()), addOCIDigest(req.Object.Status.LastAttemptedRevisionDigest),
+			addProvenance(req.Provenance), addDiffSummary(summary)),
+		corev1.EventTypeNormal,
+		v2.UpgradePreviewReason,
+		fmtUpgradePreview, req.Object.GetReleaseNamespace(), req.Object.GetReleaseName(), req.Chart.Name(),
+		req.Chart.Metadata.Version, previewChanges(summary),
+	)
+}
+
+// previewChanges returns a description of the changes in the given summary,
+// listing at most maxPreviewResources changed objects.
+func previewChanges(summary *v2.DiffSummary) string {
+	total := summary.Added + summary.Modified + summary.Removed
+	if total == 0 {
+		return "no objects"
+	}
+
+	listed := make([]string, 0, min(len(summary.Changes), maxPreviewResources)+1)
+	for _, c := range summary.Changes {
+		if len(listed) == maxPreviewResources {
+			break
+		}
+		listed = append(listed, fmt.Sprintf("%s (%s)", c.ID, c.Action))
+	}
+	if n := total - len(listed); n > 0 {
+		listed = append(listed, fmt.Sprintf("and %d more", n))
+	}
+	return fmt.Sprintf("%d objects (%d added, %d modified, %d removed): %s", total,
+		summary.Added, summary.Modified, summary.Removed, strings.Join(listed, ", "))
+}
+
 // failure records the failure of a Helm upgrade action in the status of the
 // given Request.Object by marking ReleasedCondition=False and increasing the
 // failure counter. In addition, it emits a warning event for the
@@ -137,17 +276,21 @@ func (r *Upgrade) failure(req *Request, buffer *action.LogBuffer, err error) {
 	msg := fmt.Sprintf(fmtUpgradeFailure, req.Object.GetReleaseNamespace(), req.Object.GetReleaseName(), req.Chart.Name(), req.Chart.Metadata.Version, strings.TrimSpace(err.Error()))
 
 	// Mark upgrade failure on object.
+	reason := failureReason(err, v2.UpgradeFailedReason)
 	req.Object.Status.Failures++
-	conditions.MarkFalse(req.Object, v2.ReleasedCondition, v2.UpgradeFailedReason, msg)
+	conditions.MarkFalse(req.Object, v2.ReleasedCondition, reason, msg)
+
+	// Record the logs of the failed attempt on object.
+	recordReleaseAttempt(req.Object, r.Name(), buffer)
 
 	// Record warning event, this message contains more data than the
 	// Condition summary.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
 		eventMeta(req.Chart.Metadata.Version, chartutil.DigestValues(digest.Canonical, req.Values).String(),
-			addAppVersion(req.Chart.AppVersion()), addOCIDigest(req.Object.Status.LastAttemptedRevisionDigest)),
+			addAppVersion(req.Chart.AppVersion()), addOCIDigest(req.Object.Status.LastAttemptedRevisionDigest), addProvenance(req.Provenance), addErrorCode(err)),
 		corev1.EventTypeWarning,
-		v2.UpgradeFailedReason,
+		reason,
 		eventMessageWithLog(msg, buffer),
 	)
 }
@@ -156,14 +299,16 @@ func (r *Upgrade) failure(req *Request, buffer *action.LogBuffer, err error) {
 // given Request.Object by marking ReleasedCondition=True and emitting an
 // event. In addition, it marks TestSuccessCondition=False when tests are
 // enabled to indicate we are awaiting test results after having made the
-// release.
-func (r *Upgrade) success(req *Request) {
+// release. When the chart version differs from the given previous chart
+// version, the event lists the changes of the chart.
+func (r *Upgrade) success(req *Request, prevChartVersion string) {
 	// Compose success message.
 	cur := req.Object.Status.History.Latest()
 	msg := fmt.Sprintf(fmtUpgradeSuccess, cur.FullReleaseName(), cur.VersionedChartName())
 
 	// Mark upgrade success on object.
 	conditions.MarkTrue(req.Object, v2.ReleasedCondition, v2.UpgradeSucceededReason, msg)
+	req.Object.Status.LastReleaseAttempt = nil
 	if req.Object.GetTest().Enable && !cur.HasBeenTested() {
 		conditions.MarkUnknown(req.Object, v2.TestSuccessCondition, "AwaitingTests", fmtTestPending,
 			cur.FullReleaseName(), cur.VersionedChartName())
@@ -172,9 +317,34 @@ func (r *Upgrade) success(req *Request) {
 	// Record event.
 	r.eventRecorder.AnnotatedEventf(
 		req.Object,
-		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest)),
+		eventMeta(cur.ChartVersion, cur.ConfigDigest, addAppVersion(cur.AppVersion), addOCIDigest(cur.OCIDigest), addProvenance(cur.Provenance)),
 		corev1.EventTypeNormal,
 		v2.UpgradeSucceededReason,
-		msg,
+		"%s", msg+chartChanges(prevChartVersion, req.Chart),
 	)
 }
+
+// chartChanges returns a description of the changes of the given chart as
+// returned by chartutil.Changes, listing at most maxChartChanges changes. It
+// returns an empty string if the chart version equals the given previous
+// chart version, or the chart does not describe its changes.
+func chartChanges(prevChartVersion string, chrt *helmchart.Chart) string {
+	if chrt == nil || chrt.Metadata == nil || chrt.Metadata.Version == prevChartVersion {
+		return ""
+	}
+	changes := chartutil.Changes(chrt)
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\nChanges in chart %s@%s:", chrt.Name(), chrt.Metadata.Version)
+	for i, c := range changes {
+		if i == maxChartChanges {
+			fmt.Fprintf(&b, "\n- and %d more", len(changes)-maxChartChanges)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s", c)
+	}
+	return b.String()
+}
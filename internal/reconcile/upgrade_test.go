@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -139,9 +140,9 @@ func TestUpgrade_Reconcile(t *testing.T) {
 				}
 			},
 			expectConditions: []metav1.Condition{
-				*conditions.FalseCondition(meta.ReadyCondition, v2.UpgradeFailedReason,
+				*conditions.FalseCondition(meta.ReadyCondition, v2.WaitTimeoutReason,
 					"post-upgrade hooks failed: 1 error occurred:\n\t* timed out waiting for the condition"),
-				*conditions.FalseCondition(v2.ReleasedCondition, v2.UpgradeFailedReason,
+				*conditions.FalseCondition(v2.ReleasedCondition, v2.WaitTimeoutReason,
 					"post-upgrade hooks failed: 1 error occurred:\n\t* timed out waiting for the condition"),
 			},
 			expectHistory: func(releases []*helmrelease.Release) v2.Snapshots {
@@ -475,6 +476,7 @@ func TestUpgrade_failure(t *testing.T) {
 						eventMetaGroupKey(eventv1.MetaRevisionKey): chrt.Metadata.Version,
 						eventMetaGroupKey(metaAppVersionKey):       chrt.Metadata.AppVersion,
 						eventMetaGroupKey(eventv1.MetaTokenKey):    chartutil.DigestValues(digest.Canonical, req.Values).String(),
+						eventMetaGroupKey(metaErrorCodeKey):        string(action.ErrorCodeUnknown),
 					},
 				},
 			},
@@ -489,7 +491,7 @@ func TestUpgrade_failure(t *testing.T) {
 			eventRecorder: recorder,
 		}
 		req := &Request{Object: obj.DeepCopy(), Chart: chrt}
-		r.failure(req, mockLogBuffer(5, 10), err)
+		r.failure(req, mockWarningLogBuffer(5, 10), err)
 
 		expectSubStr := "Last Helm logs"
 		g.Expect(conditions.IsFalse(req.Object, v2.ReleasedCondition)).To(BeTrue())
@@ -528,7 +530,7 @@ func TestUpgrade_success(t *testing.T) {
 		req := &Request{
 			Object: obj.DeepCopy(),
 		}
-		r.success(req)
+		r.success(req, obj.Status.History.Latest().ChartVersion)
 
 		expectMsg := fmt.Sprintf(fmtUpgradeSuccess,
 			fmt.Sprintf("%s/%s.v%d", mockReleaseNamespace, mockReleaseName, obj.Status.History.Latest().Version),
@@ -565,7 +567,7 @@ func TestUpgrade_success(t *testing.T) {
 		obj.Spec.Test = &v2.Test{Enable: true}
 
 		req := &Request{Object: obj}
-		r.success(req)
+		r.success(req, obj.Status.History.Latest().ChartVersion)
 
 		g.Expect(conditions.IsTrue(req.Object, v2.ReleasedCondition)).To(BeTrue())
 
@@ -577,4 +579,68 @@ func TestUpgrade_success(t *testing.T) {
 			fmt.Sprintf("%s@%s", obj.Status.History.Latest().ChartName, obj.Status.History.Latest().ChartVersion))
 		g.Expect(cond.Message).To(Equal(expectMsg))
 	})
+
+	t.Run("records chart changes verbatim", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := testutil.NewFakeRecorder(10, false)
+		r := &Upgrade{
+			eventRecorder: recorder,
+		}
+
+		chrt := testutil.BuildChart()
+		chrt.Metadata.Annotations = map[string]string{
+			chartutil.ChangesAnnotation: "- kind: changed\n  description: Lower the CPU target to 50%s\n",
+		}
+		req := &Request{Object: obj.DeepCopy(), Chart: chrt}
+		r.success(req, "0.0.1")
+
+		events := recorder.GetEvents()
+		g.Expect(events).To(HaveLen(1))
+		g.Expect(events[0].Message).To(HaveSuffix("- changed: Lower the CPU target to 50%s"))
+	})
+}
+
+func Test_previewChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(previewChanges(&v2.DiffSummary{})).To(Equal("no objects"))
+
+	summary := &v2.DiffSummary{
+		Added:    1,
+		Modified: 1,
+		Changes: []v2.ObjectChange{
+			{ID: "default_podinfo-hpa_autoscaling_HorizontalPodAutoscaler", Action: v2.ObjectAdded},
+			{ID: "default_podinfo_apps_Deployment", Action: v2.ObjectModified},
+		},
+	}
+	g.Expect(previewChanges(summary)).To(Equal("2 objects (1 added, 1 modified, 0 removed): " +
+		"default_podinfo-hpa_autoscaling_HorizontalPodAutoscaler (added), default_podinfo_apps_Deployment (modified)"))
+
+	summary = &v2.DiffSummary{Removed: maxPreviewResources + 5}
+	for i := 0; i < maxPreviewResources+5; i++ {
+		summary.Changes = append(summary.Changes, v2.ObjectChange{ID: fmt.Sprintf("default_cm-%d__ConfigMap", i), Action: v2.ObjectRemoved})
+	}
+	g.Expect(previewChanges(summary)).To(HaveSuffix(", and 5 more"))
+}
+
+func Test_chartChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	chrt := testutil.BuildChart()
+	chrt.Metadata.Annotations = map[string]string{
+		chartutil.ChangesAnnotation: "- kind: added\n  description: Ingress class name\n- kind: fixed\n  description: Probes\n",
+	}
+	g.Expect(chartChanges(chrt.Metadata.Version, chrt)).To(BeEmpty())
+	g.Expect(chartChanges("0.0.1", chrt)).To(Equal("\n\nChanges in chart hello@0.1.0:\n- added: Ingress class name\n- fixed: Probes"))
+
+	var changes []string
+	for i := 0; i < maxChartChanges+5; i++ {
+		changes = append(changes, fmt.Sprintf("- change %d", i))
+	}
+	chrt.Metadata.Annotations[chartutil.ChangesAnnotation] = strings.Join(changes, "\n")
+	g.Expect(chartChanges("0.0.1", chrt)).To(HaveSuffix("\n- change 19\n- and 5 more"))
+
+	chrt.Metadata.Annotations = nil
+	g.Expect(chartChanges("0.0.1", chrt)).To(BeEmpty())
 }
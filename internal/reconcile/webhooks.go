@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/actionhook"
+	"github.com/fluxcd/helm-controller/internal/audit"
+)
+
+// CallWebhooks calls the release action webhooks of the Request in the given
+// phase of the Helm action performed by the ActionReconciler. In the post
+// phase, actionErr is the error of the action.
+//
+// If any of the blocking webhooks fails, a warning event is emitted and the
+// error is returned. In the pre phase, the caller must then not perform the
+// Helm action.
+func CallWebhooks(ctx context.Context, recorder record.EventRecorder, r ActionReconciler, req *Request, phase v2.WebhookPhase, actionErr error) error {
+	if req.Webhooks == nil {
+		return nil
+	}
+
+	payload := actionhook.Payload{
+		Phase:       phase,
+		Action:      r.Name(),
+		Time:        time.Now(),
+		ReconcileID: string(controller.ReconcileIDFromContext(ctx)),
+		Object:      auditObject(req.Object),
+		Release:     auditRelease(r, req),
+	}
+	if phase == v2.WebhookPhasePost {
+		payload.Result = audit.ResultSuccess
+		if actionErr != nil {
+			payload.Result = audit.ResultFailure
+			payload.Error = actionErr.Error()
+		}
+	}
+
+	if err := req.Webhooks.Call(ctx, payload); err != nil {
+		recorder.Eventf(req.Object, corev1.EventTypeWarning, v2.WebhookFailedReason,
+			"Release action webhook for %s-%s failed: %s", phase, r.Name(), err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/actionhook"
+	"github.com/fluxcd/helm-controller/internal/audit"
+	"github.com/fluxcd/helm-controller/internal/outbound"
+)
+
+func TestCallWebhooks(t *testing.T) {
+	received := make(chan actionhook.Payload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p actionhook.Payload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		if p.Phase == v2.WebhookPhasePre && p.Action == "upgrade" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		received <- p
+	}))
+	t.Cleanup(server.Close)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: mockReleaseNamespace},
+		Data:       map[string][]byte{v2.ActionWebhookAddressKey: []byte(server.URL)},
+	}).Build()
+
+	obj := &v2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       mockReleaseName,
+			Namespace:  mockReleaseNamespace,
+			Generation: 3,
+		},
+		Spec: v2.HelmReleaseSpec{
+			Hooks: &v2.Hooks{
+				Webhooks: []v2.ActionWebhook{{
+					Name:      "change-management",
+					SecretRef: meta.LocalObjectReference{Name: "webhook"},
+					Blocking:  true,
+				}},
+			},
+		},
+		Status: v2.HelmReleaseStatus{
+			History: v2.Snapshots{{Name: mockReleaseName, Namespace: mockReleaseNamespace, Version: 4}},
+		},
+	}
+	httpClient, err := outbound.NewClient(outbound.Options{AllowedHosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &Request{Object: obj, Webhooks: actionhook.New(reader, httpClient, obj)}
+
+	t.Run("sends result of action in post phase", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := record.NewFakeRecorder(10)
+		err := CallWebhooks(context.TODO(), recorder, NewUninstall(nil, recorder), req, v2.WebhookPhasePost, errors.New("timed out"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(recorder.Events).To(BeEmpty())
+
+		var p actionhook.Payload
+		g.Expect(received).To(Receive(&p))
+		g.Expect(p.Phase).To(Equal(v2.WebhookPhasePost))
+		g.Expect(p.Action).To(Equal("uninstall"))
+		g.Expect(p.Result).To(Equal(audit.ResultFailure))
+		g.Expect(p.Error).To(Equal("timed out"))
+		g.Expect(p.Object.Generation).To(Equal(int64(3)))
+		g.Expect(p.Release.Version).To(Equal(4))
+	})
+
+	t.Run("emits event on failure", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := record.NewFakeRecorder(10)
+		err := CallWebhooks(context.TODO(), recorder, NewUpgrade(nil, recorder), req, v2.WebhookPhasePre, nil)
+		g.Expect(err).To(MatchError("webhook 'change-management' failed: unexpected status code 403"))
+		g.Expect(recorder.Events).To(Receive(HavePrefix(
+			"Warning WebhookFailed Release action webhook for pre-upgrade failed: webhook 'change-management' failed: unexpected status code 403")))
+
+		var p actionhook.Payload
+		g.Expect(received).To(Receive(&p))
+		g.Expect(p.Result).To(BeEmpty())
+	})
+
+	t.Run("without webhooks", func(t *testing.T) {
+		g := NewWithT(t)
+
+		recorder := record.NewFakeRecorder(10)
+		g.Expect(CallWebhooks(context.TODO(), recorder, NewUpgrade(nil, recorder), &Request{Object: obj}, v2.WebhookPhasePre, nil)).To(Succeed())
+		g.Expect(received).ToNot(Receive())
+	})
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recreate detects changes to the objects of a Helm release which
+// can not be applied in place, and would require StatefulSets or
+// PersistentVolumeClaims to be deleted and recreated, together with their
+// storage.
+package recreate
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	helmkube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RequiredError is returned by Check when the next objects of a release
+// would require objects to be recreated.
+type RequiredError struct {
+	// Objects are the descriptions of the objects which would be recreated
+	// or removed, and the reason why.
+	Objects []string
+}
+
+// Error returns an error string containing the objects.
+func (e *RequiredError) Error() string {
+	return fmt.Sprintf("upgrade requires StatefulSets or PersistentVolumeClaims to be recreated: %s",
+		strings.Join(e.Objects, "; "))
+}
+
+var (
+	statefulSetKind = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	pvcKind         = schema.GroupKind{Kind: "PersistentVolumeClaim"}
+)
+
+// mutableSpecFields are the fields of the spec of the kinds checked by Check
+// which can be changed in place.
+var mutableSpecFields = map[schema.GroupKind]map[string]bool{
+	statefulSetKind: {
+		"replicas":                             true,
+		"ordinals":                             true,
+		"template":                             true,
+		"updateStrategy":                       true,
+		"persistentVolumeClaimRetentionPolicy": true,
+		"minReadySeconds":                      true,
+		"revisionHistoryLimit":                 true,
+	},
+	pvcKind: {
+		"resources":                 true,
+		"volumeAttributesClassName": true,
+	},
+}
+
+// Check returns a RequiredError if the given next objects of a release change
+// immutable fields of the spec of StatefulSets or PersistentVolumeClaims in
+// the given current objects, or if PersistentVolumeClaims in the current
+// objects are not in the next objects and do not have the Helm resource
+// policy annotation with the keep policy. Objects without a namespace are
+// taken to be in the given namespace.
+func Check(namespace string, current, next []*unstructured.Unstructured) error {
+	nextObjects := make(map[string]*unstructured.Unstructured, len(next))
+	for _, obj := range next {
+		if k, ok := key(namespace, obj); ok {
+			nextObjects[k] = obj
+		}
+	}
+
+	var objects []string
+	for _, cur := range current {
+		k, ok := key(namespace, cur)
+		if !ok {
+			continue
+		}
+		nxt, ok := nextObjects[k]
+		if !ok {
+			if cur.GroupVersionKind().GroupKind() == pvcKind &&
+				cur.GetAnnotations()[helmkube.ResourcePolicyAnno] != helmkube.KeepPolicy {
+				objects = append(objects, fmt.Sprintf("%s: removed from the release", describe(namespace, cur)))
+			}
+			continue
+		}
+		if fields := changedImmutableFields(cur, nxt); len(fields) > 0 {
+			objects = append(objects, fmt.Sprintf("%s: immutable fields changed: %s",
+				describe(namespace, cur), strings.Join(fields, ", ")))
+		}
+	}
+
+	if len(objects) > 0 {
+		sort.Strings(objects)
+		return &RequiredError{Objects: objects}
+	}
+	return nil
+}
+
+// key returns a key identifying the given object in the given namespace, and
+// true if the object is of a kind checked by Check.
+func key(namespace string, obj *unstructured.Unstructured) (string, bool) {
+	gk := obj.GroupVersionKind().GroupKind()
+	if _, ok := mutableSpecFields[gk]; !ok {
+		return "", false
+	}
+	return gk.Group + "/" + describe(namespace, obj), true
+}
+
+// describe returns the kind, namespace and name of the given object, which is
+// taken to be in the given namespace if it does not have one.
+func describe(namespace string, obj *unstructured.Unstructured) string {
+	if ns := obj.GetNamespace(); ns != "" {
+		namespace = ns
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetKind(), namespace, obj.GetName())
+}
+
+// changedImmutableFields returns the sorted paths of the fields of the spec
+// which differ between the given objects, and can not be changed in place.
+func changedImmutableFields(cur, next *unstructured.Unstructured) []string {
+	mutable := mutableSpecFields[cur.GroupVersionKind().GroupKind()]
+	curSpec, _, _ := unstructured.NestedMap(cur.Object, "spec")
+	nextSpec, _, _ := unstructured.NestedMap(next.Object, "spec")
+
+	var fields []string
+	for _, spec := range []map[string]interface{}{curSpec, nextSpec} {
+		for f := range spec {
+			if mutable[f] || reflect.DeepEqual(curSpec[f], nextSpec[f]) {
+				continue
+			}
+			if p := "spec." + f; !slices.Contains(fields, p) {
+				fields = append(fields, p)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
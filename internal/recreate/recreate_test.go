@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+const currentManifest = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 1
+  serviceName: db
+  template:
+    spec:
+      containers:
+      - name: db
+        image: postgres:15
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+    spec:
+      resources:
+        requests:
+          storage: 1Gi
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: cache
+  namespace: apps
+spec:
+  storageClassName: standard
+  resources:
+    requests:
+      storage: 1Gi
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: backup
+  annotations:
+    helm.sh/resource-policy: keep
+spec:
+  resources:
+    requests:
+      storage: 1Gi
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  foo: bar`
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		next    string
+		objects []string
+	}{
+		{
+			name: "in place changes",
+			next: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: apps
+spec:
+  replicas: 3
+  serviceName: db
+  template:
+    spec:
+      containers:
+      - name: db
+        image: postgres:16
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+    spec:
+      resources:
+        requests:
+          storage: 1Gi
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: cache
+spec:
+  storageClassName: standard
+  resources:
+    requests:
+      storage: 2Gi`,
+		},
+		{
+			name: "immutable changes and removals",
+			next: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 1
+  serviceName: postgres
+  template:
+    spec:
+      containers:
+      - name: db
+        image: postgres:15
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+    spec:
+      resources:
+        requests:
+          storage: 2Gi
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: cache
+  namespace: other
+spec:
+  storageClassName: standard
+  resources:
+    requests:
+      storage: 1Gi`,
+			objects: []string{
+				"PersistentVolumeClaim/apps/cache: removed from the release",
+				"StatefulSet/apps/db: immutable fields changed: spec.serviceName, spec.volumeClaimTemplates",
+			},
+		},
+		{
+			name: "changed storage class",
+			next: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 1
+  serviceName: db
+  template:
+    spec:
+      containers:
+      - name: db
+        image: postgres:15
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+    spec:
+      resources:
+        requests:
+          storage: 1Gi
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: cache
+spec:
+  resources:
+    requests:
+      storage: 1Gi`,
+			objects: []string{
+				"PersistentVolumeClaim/apps/cache: immutable fields changed: spec.storageClassName",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			current, err := ssautil.ReadObjects(strings.NewReader(currentManifest))
+			g.Expect(err).ToNot(HaveOccurred())
+			next, err := ssautil.ReadObjects(strings.NewReader(tt.next))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = Check("apps", current, next)
+			if len(tt.objects) == 0 {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			var requiredErr *RequiredError
+			g.Expect(errors.As(err, &requiredErr)).To(BeTrue())
+			g.Expect(requiredErr.Objects).To(Equal(tt.objects))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"sort"
+	"strings"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+// containerFields are the fields which contain a list of containers in the
+// spec of a Pod or a Pod template.
+var containerFields = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// imagesFromObservation returns the sorted list of unique container image
+// references in the manifest and hooks of the given Observation.
+func imagesFromObservation(rls Observation) []string {
+	manifests := make([]string, 0, len(rls.Hooks)+1)
+	manifests = append(manifests, rls.Manifest)
+	for _, h := range rls.Hooks {
+		manifests = append(manifests, h.Manifest)
+	}
+	return ImagesFromManifests(manifests...)
+}
+
+// ImagesFromManifests returns the sorted list of unique container image
+// references in the objects of the given YAML manifests. Manifests which
+// can not be parsed are ignored.
+//
+// Images are discovered by looking for lists of containers at any depth of
+// an object, which covers Pods, built-in workload resources and custom
+// resources embedding a Pod template.
+func ImagesFromManifests(manifests ...string) []string {
+	seen := make(map[string]struct{})
+	for _, m := range manifests {
+		if strings.TrimSpace(m) == "" {
+			continue
+		}
+		objects, err := ssautil.ReadObjects(strings.NewReader(m))
+		if err != nil {
+			continue
+		}
+		for _, obj := range objects {
+			collectImages(obj.Object, seen)
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	images := make([]string, 0, len(seen))
+	for img := range seen {
+		images = append(images, img)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// collectImages adds the image references of the containers found in v to
+// seen.
+func collectImages(v interface{}, seen map[string]struct{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, field := range containerFields {
+			containers, ok := t[field].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, c := range containers {
+				if c, ok := c.(map[string]interface{}); ok {
+					if img, ok := c["image"].(string); ok && img != "" {
+						seen[img] = struct{}{}
+					}
+				}
+			}
+		}
+		for _, vv := range t {
+			collectImages(vv, seen)
+		}
+	case []interface{}:
+		for _, vv := range t {
+			collectImages(vv, seen)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+)
+
+func TestImagesFromManifests(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests []string
+		want      []string
+	}{
+		{
+			name: "workloads",
+			manifests: []string{`---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    image: ghcr.io/stefanprodan/podinfo:6.5.3
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: app
+        image: ghcr.io/stefanprodan/podinfo:6.5.3
+      - name: sidecar
+        image: envoyproxy/envoy@sha256:4c6c9aa8bc7ac8ab3e1de3a7462d6a8c9b2e94b5e5e8e4f0f9c33c58cf3f0b52
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cronjob
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image: alpine:3.19
+`},
+			want: []string{
+				"alpine:3.19",
+				"busybox:1.36",
+				"envoyproxy/envoy@sha256:4c6c9aa8bc7ac8ab3e1de3a7462d6a8c9b2e94b5e5e8e4f0f9c33c58cf3f0b52",
+				"ghcr.io/stefanprodan/podinfo:6.5.3",
+			},
+		},
+		{
+			name: "multiple manifests",
+			manifests: []string{`---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: a
+spec:
+  containers:
+  - name: a
+    image: a:1
+`, `---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: b
+spec:
+  containers:
+  - name: b
+    image: b:1
+`},
+			want: []string{"a:1", "b:1"},
+		},
+		{
+			name: "no containers",
+			manifests: []string{`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  image: not-an-image
+`},
+			want: nil,
+		},
+		{
+			name:      "invalid manifest",
+			manifests: []string{"invalid: [", ""},
+			want:      nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ImagesFromManifests(tt.manifests...)).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_imagesFromObservation(t *testing.T) {
+	g := NewWithT(t)
+
+	obs := Observation{
+		Manifest: `---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  containers:
+  - name: app
+    image: app:1
+`,
+		Hooks: []helmrelease.Hook{
+			{
+				Manifest: `---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: hook
+spec:
+  template:
+    spec:
+      containers:
+      - name: hook
+        image: hook:1
+`,
+			},
+		},
+	}
+	g.Expect(imagesFromObservation(obs)).To(Equal([]string{"app:1", "hook:1"}))
+}
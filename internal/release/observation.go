@@ -19,6 +19,7 @@ package release
 import (
 	"encoding/json"
 	"io"
+	"unicode/utf8"
 
 	"github.com/mitchellh/copystructure"
 	"helm.sh/helm/v3/pkg/chart"
@@ -30,6 +31,10 @@ import (
 	"github.com/fluxcd/helm-controller/internal/digest"
 )
 
+// MaxNotesLength is the maximum length in bytes of the notes of a release
+// recorded in a v2.Snapshot.
+const MaxNotesLength = 4096
+
 var (
 	DefaultDataFilters = []DataFilter{
 		IgnoreHookTestEvents,
@@ -81,6 +86,9 @@ type Observation struct {
 	Namespace string `json:"namespace"`
 	// OCIDigest is the digest of the OCI artifact that was used to
 	OCIDigest string `json:"ociDigest,omitempty"`
+	// Provenance is the metadata of the source the chart of the release
+	// originates from.
+	Provenance map[string]string `json:"provenance,omitempty"`
 }
 
 // Targets returns if the release matches the given name, namespace and
@@ -170,7 +178,23 @@ func ObservedToSnapshot(rls Observation) *v2.Snapshot {
 		Deleted:       metav1.NewTime(rls.Info.Deleted.Time),
 		Status:        rls.Info.Status.String(),
 		OCIDigest:     rls.OCIDigest,
+		Provenance:    rls.Provenance,
+		Notes:         truncateNotes(rls.Info.Notes),
+		Images:        imagesFromObservation(rls),
+	}
+}
+
+// truncateNotes truncates the given notes to MaxNotesLength bytes, without
+// splitting a multibyte character.
+func truncateNotes(notes string) string {
+	if len(notes) <= MaxNotesLength {
+		return notes
+	}
+	i := MaxNotesLength
+	for i > 0 && !utf8.RuneStart(notes[i]) {
+		i--
 	}
+	return notes[:i]
 }
 
 // TestHooksFromRelease returns the list of v2.TestHookStatus for the
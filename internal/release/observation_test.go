@@ -18,6 +18,7 @@ package release
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -318,6 +319,38 @@ func TestObservedToSnapshot(t *testing.T) {
 
 	g.Expect(got.ConfigDigest).ToNot(BeEmpty())
 	g.Expect(digest.Digest(got.ConfigDigest).Validate()).To(Succeed())
+
+	g.Expect(got.Notes).To(Equal(obs.Info.Notes))
+}
+
+func Test_truncateNotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		notes string
+		want  string
+	}{
+		{
+			name:  "within limit",
+			notes: "Get the application URL by running these commands",
+			want:  "Get the application URL by running these commands",
+		},
+		{
+			name:  "exceeds limit",
+			notes: strings.Repeat("a", MaxNotesLength+10),
+			want:  strings.Repeat("a", MaxNotesLength),
+		},
+		{
+			name:  "does not split multibyte character",
+			notes: strings.Repeat("a", MaxNotesLength-1) + "é",
+			want:  strings.Repeat("a", MaxNotesLength-1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(truncateNotes(tt.notes)).To(Equal(tt.want))
+		})
+	}
 }
 
 func TestTestHooksFromRelease(t *testing.T) {
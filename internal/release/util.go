@@ -17,9 +17,15 @@ limitations under the License.
 package release
 
 import (
+	"strings"
+
 	helmrelease "helm.sh/helm/v3/pkg/release"
 )
 
+// rollbackDescriptionPrefix is the prefix of the description of a release
+// created by a Helm rollback action.
+const rollbackDescriptionPrefix = "Rollback to "
+
 // GetTestHooks returns the list of test hooks for the given release, indexed
 // by hook name.
 func GetTestHooks(rls *helmrelease.Release) map[string]*helmrelease.Hook {
@@ -43,3 +49,9 @@ func IsHookForEvent(hook *helmrelease.Hook, event helmrelease.HookEvent) bool {
 	}
 	return false
 }
+
+// IsRollback returns if the given release was created by a Helm rollback
+// action.
+func IsRollback(rls *helmrelease.Release) bool {
+	return rls != nil && rls.Info != nil && strings.HasPrefix(rls.Info.Description, rollbackDescriptionPrefix)
+}
@@ -77,3 +77,12 @@ func TestIsHookForEvent(t *testing.T) {
 	g.Expect(IsHookForEvent(hook, helmrelease.HookPostInstall)).To(BeTrue())
 	g.Expect(IsHookForEvent(hook, helmrelease.HookTest)).To(BeFalse())
 }
+
+func TestIsRollback(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(IsRollback(&helmrelease.Release{Info: &helmrelease.Info{Description: "Rollback to 3"}})).To(BeTrue())
+	g.Expect(IsRollback(&helmrelease.Release{Info: &helmrelease.Info{Description: "Upgrade complete"}})).To(BeFalse())
+	g.Expect(IsRollback(&helmrelease.Release{})).To(BeFalse())
+	g.Expect(IsRollback(nil)).To(BeFalse())
+}
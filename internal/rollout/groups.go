@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rollout coordinates the upgrades of the HelmReleases in a rollout
+// group, to limit the number of members of the group which are rolling out a
+// new release at the same time.
+package rollout
+
+import (
+	"sort"
+	"sync"
+)
+
+// Groups tracks the active members of rollout groups. A member is active from
+// the moment it is allowed to start an upgrade, until it is released after
+// the upgrade has resulted in a ready release. A member with a failed upgrade
+// thereby keeps holding its slot, which stops the rollout of the group until
+// the failure has been resolved.
+//
+// The state is kept in memory, and is not shared between controller
+// instances.
+type Groups struct {
+	mu    sync.Mutex
+	limit int
+	// members maps the active members to the group they are active in.
+	members map[string]string
+}
+
+// NewGroups returns a new Groups allowing the given number of members of a
+// group to be active at the same time. A limit of zero or less defaults to
+// one.
+func NewGroups(limit int) *Groups {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &Groups{
+		limit:   limit,
+		members: make(map[string]string),
+	}
+}
+
+// Limit returns the number of members of a group which are allowed to be
+// active at the same time.
+func (g *Groups) Limit() int {
+	return g.limit
+}
+
+// TryAcquire marks the member as active in the group if the limit of the
+// group has not been reached, or if the member is already active in it. It
+// returns true if the member is active, and the sorted list of the other
+// active members of the group.
+func (g *Groups) TryAcquire(group, member string) (bool, []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// A member which moved to another group no longer counts towards the
+	// limit of the previous group.
+	if cur, ok := g.members[member]; ok && cur != group {
+		delete(g.members, member)
+	}
+
+	others := g.activeLocked(group, member)
+	if _, ok := g.members[member]; ok {
+		return true, others
+	}
+	if len(others) >= g.limit {
+		return false, others
+	}
+	g.members[member] = group
+	return true, others
+}
+
+// Active returns the sorted list of the active members of the group.
+func (g *Groups) Active(group string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.activeLocked(group, "")
+}
+
+// Release marks the member as no longer active, allowing another member of
+// its group to become active.
+func (g *Groups) Release(member string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.members, member)
+}
+
+// activeLocked returns the sorted list of the active members of the group,
+// excluding the given member. It must be called with the lock held.
+func (g *Groups) activeLocked(group, exclude string) []string {
+	var active []string
+	for m, grp := range g.members {
+		if grp == group && m != exclude {
+			active = append(active, m)
+		}
+	}
+	sort.Strings(active)
+	return active
+}
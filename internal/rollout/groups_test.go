@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGroups(t *testing.T) {
+	t.Run("limits active members per group", func(t *testing.T) {
+		g := NewWithT(t)
+
+		groups := NewGroups(1)
+		ok, others := groups.TryAcquire("regions", "flux-system/eu")
+		g.Expect(ok).To(BeTrue())
+		g.Expect(others).To(BeEmpty())
+
+		ok, others = groups.TryAcquire("regions", "flux-system/us")
+		g.Expect(ok).To(BeFalse())
+		g.Expect(others).To(Equal([]string{"flux-system/eu"}))
+
+		// Other groups are not affected.
+		ok, _ = groups.TryAcquire("other", "flux-system/us")
+		g.Expect(ok).To(BeTrue())
+	})
+
+	t.Run("is reentrant for active members", func(t *testing.T) {
+		g := NewWithT(t)
+
+		groups := NewGroups(1)
+		ok, _ := groups.TryAcquire("regions", "flux-system/eu")
+		g.Expect(ok).To(BeTrue())
+		ok, _ = groups.TryAcquire("regions", "flux-system/eu")
+		g.Expect(ok).To(BeTrue())
+	})
+
+	t.Run("release allows the next member", func(t *testing.T) {
+		g := NewWithT(t)
+
+		groups := NewGroups(2)
+		for _, m := range []string{"ns/a", "ns/b"} {
+			ok, _ := groups.TryAcquire("regions", m)
+			g.Expect(ok).To(BeTrue())
+		}
+		ok, _ := groups.TryAcquire("regions", "ns/c")
+		g.Expect(ok).To(BeFalse())
+		g.Expect(groups.Active("regions")).To(Equal([]string{"ns/a", "ns/b"}))
+
+		groups.Release("ns/a")
+		ok, others := groups.TryAcquire("regions", "ns/c")
+		g.Expect(ok).To(BeTrue())
+		g.Expect(others).To(Equal([]string{"ns/b"}))
+	})
+
+	t.Run("member moving groups frees its slot", func(t *testing.T) {
+		g := NewWithT(t)
+
+		groups := NewGroups(1)
+		ok, _ := groups.TryAcquire("regions", "ns/a")
+		g.Expect(ok).To(BeTrue())
+		ok, _ = groups.TryAcquire("zones", "ns/a")
+		g.Expect(ok).To(BeTrue())
+
+		ok, _ = groups.TryAcquire("regions", "ns/b")
+		g.Expect(ok).To(BeTrue())
+	})
+
+	t.Run("defaults limit to one", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(NewGroups(0).Limit()).To(Equal(1))
+	})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schedule provides the parsing and evaluation of the cron
+// expressions of HelmRelease reconcile schedules.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// parser parses cron expressions in the standard five field format, and the
+// shorthands for common expressions (e.g. "@daily").
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Cron is a parsed cron expression in the standard five field format of
+// minute, hour, day of month, month and day of week, evaluated in a time
+// zone.
+type Cron struct {
+	schedule *cron.SpecSchedule
+}
+
+// Parse parses the given cron expression, to be evaluated in the time zone
+// with the given IANA name. An empty time zone defaults to UTC.
+func Parse(expr, timeZone string) (*Cron, error) {
+	loc := time.UTC
+	if timeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone '%s': %w", timeZone, err)
+		}
+	}
+
+	// The time zone is configured separately, and intervals are configured
+	// with the interval of the object.
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		return nil, fmt.Errorf("invalid cron expression '%s': time zone must be set as timeZone", expr)
+	}
+	if strings.HasPrefix(spec, "@every") {
+		return nil, fmt.Errorf("invalid cron expression '%s': @every is not supported", expr)
+	}
+
+	s, err := parser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression '%s': %w", expr, err)
+	}
+	schedule, ok := s.(*cron.SpecSchedule)
+	if !ok {
+		return nil, fmt.Errorf("invalid cron expression '%s': unsupported schedule", expr)
+	}
+	schedule.Location = loc
+	return &Cron{schedule: schedule}, nil
+}
+
+// ForHelmRelease returns the parsed v2.ReconcileSchedule of the given
+// HelmRelease, or nil if it has none.
+func ForHelmRelease(obj *v2.HelmRelease) (*Cron, error) {
+	if obj.Spec.Schedule == nil {
+		return nil, nil
+	}
+	return Parse(obj.Spec.Schedule.Cron, obj.Spec.Schedule.TimeZone)
+}
+
+// Next returns the first time after the given time which matches the
+// expression, in the time zone of the expression. It returns the zero time
+// if no such time exists within the next five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	return c.schedule.Next(t)
+}
+
+// Until returns the duration from the given time until the next time which
+// matches the expression, or zero if there is none.
+func (c *Cron) Until(t time.Time) time.Duration {
+	next := c.Next(t)
+	if next.IsZero() {
+		return 0
+	}
+	return next.Sub(t)
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		timeZone string
+		wantErr  string
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists ranges and steps", expr: "0,30 8-18/2 1-15 */3 mon-fri"},
+		{name: "names", expr: "0 0 * JAN,jul SUN"},
+		{name: "macro", expr: "@daily"},
+		{name: "time zone", expr: "0 2 * * *", timeZone: "America/New_York"},
+		{name: "too few fields", expr: "0 2 * *", wantErr: "expected exactly 5 fields, found 4"},
+		{name: "out of range", expr: "60 * * * *", wantErr: "end of range (60) above maximum (59)"},
+		{name: "inverted range", expr: "* 18-8 * * *", wantErr: "beginning of range (18) beyond end of range (8)"},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: "step of range should be a positive number"},
+		{name: "unknown name", expr: "0 0 * foo *", wantErr: "failed to parse int from foo"},
+		{name: "every", expr: "@every 1h", wantErr: "@every is not supported"},
+		{name: "time zone prefix", expr: "CRON_TZ=UTC 0 2 * * *", wantErr: "time zone must be set as timeZone"},
+		{name: "invalid time zone", expr: "0 2 * * *", timeZone: "Mars/Olympus", wantErr: "invalid time zone 'Mars/Olympus'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := Parse(tt.expr, tt.timeZone)
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		timeZone string
+		from     time.Time
+		want     time.Time
+	}{
+		{
+			name: "next minute",
+			expr: "* * * * *",
+			from: time.Date(2024, 5, 7, 10, 15, 30, 0, time.UTC),
+			want: time.Date(2024, 5, 7, 10, 16, 0, 0, time.UTC),
+		},
+		{
+			name: "exact match is not returned",
+			expr: "0 2 * * *",
+			from: time.Date(2024, 5, 7, 2, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 8, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next day",
+			expr: "0 2 * * *",
+			from: time.Date(2024, 5, 7, 3, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 8, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next month",
+			expr: "30 4 1 * *",
+			from: time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2025, 1, 1, 4, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "day of week",
+			expr: "0 9 * * mon",
+			from: time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday as 0",
+			expr: "0 0 * * 0",
+			from: time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 0 10 * mon",
+			from: time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			from: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "time zone",
+			expr:     "0 2 * * *",
+			timeZone: "Europe/Amsterdam",
+			from:     time.Date(2024, 5, 7, 0, 30, 0, 0, time.UTC),
+			want:     time.Date(2024, 5, 8, 2, 0, 0, 0, amsterdam),
+		},
+		{
+			name:     "skipped by daylight saving time",
+			expr:     "30 2 * * *",
+			timeZone: "Europe/Amsterdam",
+			from:     time.Date(2024, 3, 31, 0, 0, 0, 0, amsterdam),
+			want:     time.Date(2024, 4, 1, 2, 30, 0, 0, amsterdam),
+		},
+		{
+			name: "never",
+			expr: "0 0 30 2 *",
+			from: time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC),
+			want: time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c, err := Parse(tt.expr, tt.timeZone)
+			g.Expect(err).ToNot(HaveOccurred())
+			got := c.Next(tt.from)
+			g.Expect(got.Equal(tt.want)).To(BeTrue(), "got %s, want %s", got, tt.want)
+		})
+	}
+}
+
+func TestForHelmRelease(t *testing.T) {
+	g := NewWithT(t)
+
+	c, err := ForHelmRelease(&v2.HelmRelease{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c).To(BeNil())
+
+	c, err = ForHelmRelease(&v2.HelmRelease{Spec: v2.HelmReleaseSpec{
+		Schedule: &v2.ReconcileSchedule{Cron: "@hourly"},
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.Until(time.Date(2024, 5, 7, 1, 45, 0, 0, time.UTC))).To(Equal(15 * time.Minute))
+}
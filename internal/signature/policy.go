@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signature verifies the cosign signatures of the container images
+// referenced by rendered manifests against a policy.
+package signature
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Policy configures the public keys the container images of a repository
+// must be signed with.
+type Policy struct {
+	// Images are the rules for the images to verify. The first rule with a
+	// pattern matching the repository of an image applies. Images without a
+	// matching rule are not verified.
+	Images []ImageRule `json:"images"`
+}
+
+// ImageRule configures the public keys the images of the repositories
+// matching a pattern must be signed with.
+type ImageRule struct {
+	// Pattern is matched against the normalized repository of an image
+	// (e.g. "docker.io/library/nginx"), in the syntax of path.Match.
+	Pattern string `json:"pattern"`
+	// PublicKeys are the PEM-encoded cosign public keys of the rule. An
+	// image must have a signature made with any of the keys.
+	PublicKeys []string `json:"publicKeys"`
+
+	keys []crypto.PublicKey
+}
+
+// LoadPolicy reads a Policy from the YAML file at the given path, and
+// validates its rules.
+func LoadPolicy(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image signature policy: %w", err)
+	}
+
+	p := &Policy{}
+	if err = yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, fmt.Errorf("failed to decode image signature policy from '%s': %w", path, err)
+	}
+	if err = p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid image signature policy in '%s': %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate returns an error if any of the rules of the Policy has an invalid
+// pattern or public key. It parses the public keys of the rules.
+func (p *Policy) Validate() error {
+	for i := range p.Images {
+		rule := &p.Images[i]
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule %d must have a pattern", i)
+		}
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("pattern '%s' of rule %d: %w", rule.Pattern, i, err)
+		}
+		if len(rule.PublicKeys) == 0 {
+			return fmt.Errorf("rule '%s' must have at least one public key", rule.Pattern)
+		}
+		rule.keys = make([]crypto.PublicKey, 0, len(rule.PublicKeys))
+		for j, k := range rule.PublicKeys {
+			key, err := ParsePublicKey([]byte(k))
+			if err != nil {
+				return fmt.Errorf("public key %d of rule '%s': %w", j, rule.Pattern, err)
+			}
+			rule.keys = append(rule.keys, key)
+		}
+	}
+	return nil
+}
+
+// rule returns the first rule matching the given repository, or nil.
+func (p *Policy) rule(repository string) *ImageRule {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Images {
+		if ok, _ := path.Match(p.Images[i].Pattern, repository); ok {
+			return &p.Images[i]
+		}
+	}
+	return nil
+}
+
+// ParsePublicKey parses a PEM-encoded ECDSA, RSA or Ed25519 public key.
+func ParsePublicKey(b []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unexpected PEM block type '%s'", block.Type)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
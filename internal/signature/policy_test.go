@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	_, pub := generateKey(t)
+	indentedPub := "      " + strings.ReplaceAll(strings.TrimSpace(pub), "\n", "\n      ")
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			data: `images:
+  - pattern: ghcr.io/stefanprodan/*
+    publicKeys:
+    - |
+` + indentedPub + "\n",
+		},
+		{
+			name: "invalid pattern",
+			data: `images:
+  - pattern: ghcr.io/[
+    publicKeys:
+    - |
+` + indentedPub + "\n",
+			wantErr: "syntax error in pattern",
+		},
+		{
+			name: "missing public keys",
+			data: `images:
+  - pattern: ghcr.io/stefanprodan/*
+`,
+			wantErr: "rule 'ghcr.io/stefanprodan/*' must have at least one public key",
+		},
+		{
+			name: "invalid public key",
+			data: `images:
+  - pattern: ghcr.io/stefanprodan/*
+    publicKeys:
+    - invalid
+`,
+			wantErr: "public key 0 of rule 'ghcr.io/stefanprodan/*': no PEM data found",
+		},
+		{
+			name:    "unknown field",
+			data:    `keys: []`,
+			wantErr: "failed to decode image signature policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			g.Expect(os.WriteFile(path, []byte(tt.data), 0o644)).To(Succeed())
+
+			got, err := LoadPolicy(path)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got.rule("ghcr.io/stefanprodan/podinfo")).ToNot(BeNil())
+			g.Expect(got.rule("ghcr.io/stefanprodan/podinfo").keys).To(HaveLen(1))
+			g.Expect(got.rule("docker.io/library/nginx")).To(BeNil())
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/containerd/containerd/reference/docker"
+	"github.com/containerd/containerd/remotes"
+	containerddocker "github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// registryAuth is the authentication entry of a registry in a Docker config.
+type registryAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// credentials returns the username and password of the entry.
+func (a registryAuth) credentials() (string, string, error) {
+	if a.Auth == "" {
+		return a.Username, a.Password, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid auth: %w", err)
+	}
+	username, password, _ := strings.Cut(string(b), ":")
+	return username, password, nil
+}
+
+// WithPullSecrets returns a copy of the Verifier which authenticates to
+// registries with the credentials for them in the given image pull secrets,
+// and to other registries like the Verifier does. When multiple
+// Secrets contain credentials for the same registry, the first applies.
+// Secrets of a type other than kubernetes.io/dockerconfigjson or
+// kubernetes.io/dockercfg are ignored.
+func (v *Verifier) WithPullSecrets(secrets []corev1.Secret) (*Verifier, error) {
+	registries := make(map[string]remotes.Resolver)
+	for _, secret := range secrets {
+		auths, err := pullSecretAuths(secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image pull secret '%s/%s': %w", secret.Namespace, secret.Name, err)
+		}
+		for registry, auth := range auths {
+			domain := registryDomain(registry)
+			if _, ok := registries[domain]; ok {
+				continue
+			}
+			username, password, err := auth.credentials()
+			if err != nil {
+				return nil, fmt.Errorf("invalid credentials for registry '%s' in image pull secret '%s/%s': %w",
+					registry, secret.Namespace, secret.Name, err)
+			}
+			registries[domain] = containerddocker.NewResolver(containerddocker.ResolverOptions{
+				Credentials: func(string) (string, string, error) {
+					return username, password, nil
+				},
+			})
+		}
+	}
+	if len(registries) == 0 {
+		return v, nil
+	}
+	return &Verifier{
+		policy:   v.policy,
+		resolver: &pullSecretResolver{Resolver: v.resolver, registries: registries},
+	}, nil
+}
+
+// ValidatePullSecret returns an error if the given Secret is not a
+// kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg Secret with valid
+// credentials for at least one registry.
+func ValidatePullSecret(secret corev1.Secret) error {
+	if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+		return fmt.Errorf("image pull secret '%s/%s' has unsupported type '%s'", secret.Namespace, secret.Name, secret.Type)
+	}
+	auths, err := pullSecretAuths(secret)
+	if err != nil {
+		return fmt.Errorf("invalid image pull secret '%s/%s': %w", secret.Namespace, secret.Name, err)
+	}
+	if len(auths) == 0 {
+		return fmt.Errorf("image pull secret '%s/%s' does not contain credentials for any registry", secret.Namespace, secret.Name)
+	}
+	for registry, auth := range auths {
+		if _, _, err := auth.credentials(); err != nil {
+			return fmt.Errorf("invalid credentials for registry '%s' in image pull secret '%s/%s': %w",
+				registry, secret.Namespace, secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// pullSecretAuths returns the registry authentication entries of the given
+// image pull secret, keyed by registry.
+func pullSecretAuths(secret corev1.Secret) (map[string]registryAuth, error) {
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var config struct {
+			Auths map[string]registryAuth `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, err
+		}
+		return config.Auths, nil
+	case corev1.SecretTypeDockercfg:
+		var auths map[string]registryAuth
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil, err
+		}
+		return auths, nil
+	default:
+		return nil, nil
+	}
+}
+
+// registryDomain returns the domain of the registry of the given key of a
+// Docker config, which may be a URL, in the normalized form of the domain of
+// an image reference.
+func registryDomain(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	domain, _, _ := strings.Cut(registry, "/")
+	switch domain {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return domain
+}
+
+// pullSecretResolver is a remotes.Resolver which resolves references to the
+// registries with credentials in image pull secrets with a resolver
+// authenticating with these credentials, and other references with the
+// embedded resolver.
+type pullSecretResolver struct {
+	remotes.Resolver
+	registries map[string]remotes.Resolver
+}
+
+func (r *pullSecretResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	return r.resolver(ref).Resolve(ctx, ref)
+}
+
+func (r *pullSecretResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	return r.resolver(ref).Fetcher(ctx, ref)
+}
+
+func (r *pullSecretResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	return r.resolver(ref).Pusher(ctx, ref)
+}
+
+// resolver returns the resolver for the registry of the given reference.
+func (r *pullSecretResolver) resolver(ref string) remotes.Resolver {
+	if named, err := docker.ParseDockerRef(ref); err == nil {
+		if resolver, ok := r.registries[docker.Domain(named)]; ok {
+			return resolver
+		}
+	}
+	return r.Resolver
+}
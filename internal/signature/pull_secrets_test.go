@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVerifier_WithPullSecrets(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v2/app/manifests/1.0.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(manifest).String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(manifest)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(manifest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	registry := strings.TrimPrefix(server.URL, "http://")
+	ref := registry + "/app:1.0.0"
+
+	fallback := newFakeResolver()
+	fallback.pushImage("ghcr.io/stefanprodan/podinfo:6.5.3")
+	verifier := NewVerifier(&Policy{}, fallback)
+
+	newSecret := func(secretType corev1.SecretType, key, data string) corev1.Secret {
+		return corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "default"},
+			Type:       secretType,
+			Data:       map[string][]byte{key: []byte(data)},
+		}
+	}
+
+	t.Run("authenticates with dockerconfigjson Secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v, err := verifier.WithPullSecrets([]corev1.Secret{
+			newSecret(corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+				`{"auths":{"http://`+registry+`/v2/":{"username":"user","password":"pass"}}}`),
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, desc, err := v.resolver.Resolve(context.TODO(), ref)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(desc.Digest).To(Equal(digest.FromBytes(manifest)))
+
+		_, _, err = v.resolver.Resolve(context.TODO(), "ghcr.io/stefanprodan/podinfo:6.5.3")
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("authenticates with dockercfg Secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v, err := verifier.WithPullSecrets([]corev1.Secret{
+			newSecret(corev1.SecretTypeDockercfg, corev1.DockerConfigKey, `{"`+registry+`":{"auth":"dXNlcjpwYXNz"}}`),
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = v.resolver.Resolve(context.TODO(), ref)
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("without credentials for registry", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v, err := verifier.WithPullSecrets([]corev1.Secret{
+			newSecret(corev1.SecretTypeOpaque, corev1.DockerConfigJsonKey,
+				`{"auths":{"`+registry+`":{"username":"user","password":"pass"}}}`),
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(v).To(BeIdenticalTo(verifier))
+	})
+
+	t.Run("invalid Secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := verifier.WithPullSecrets([]corev1.Secret{
+			newSecret(corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey, `{"auths":{"`+registry+`":{"auth":"invalid"}}}`),
+		})
+		g.Expect(err).To(MatchError(ContainSubstring("invalid credentials for registry '" + registry + "' in image pull secret 'default/pull-secret'")))
+	})
+}
+
+func TestValidatePullSecret(t *testing.T) {
+	newSecret := func(secretType corev1.SecretType, key, data string) corev1.Secret {
+		return corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "default"},
+			Type:       secretType,
+			Data:       map[string][]byte{key: []byte(data)},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		secret  corev1.Secret
+		wantErr string
+	}{
+		{
+			name: "dockerconfigjson",
+			secret: newSecret(corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+				`{"auths":{"ghcr.io":{"username":"user","password":"pass"}}}`),
+		},
+		{
+			name:   "dockercfg",
+			secret: newSecret(corev1.SecretTypeDockercfg, corev1.DockerConfigKey, `{"ghcr.io":{"auth":"dXNlcjpwYXNz"}}`),
+		},
+		{
+			name:    "unsupported type",
+			secret:  newSecret(corev1.SecretTypeOpaque, corev1.DockerConfigJsonKey, `{"auths":{}}`),
+			wantErr: "image pull secret 'default/pull-secret' has unsupported type 'Opaque'",
+		},
+		{
+			name:    "invalid JSON",
+			secret:  newSecret(corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey, `{`),
+			wantErr: "invalid image pull secret 'default/pull-secret'",
+		},
+		{
+			name:    "without registries",
+			secret:  newSecret(corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey, `{"auths":{}}`),
+			wantErr: "image pull secret 'default/pull-secret' does not contain credentials for any registry",
+		},
+		{
+			name: "invalid auth",
+			secret: newSecret(corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+				`{"auths":{"ghcr.io":{"auth":"invalid"}}}`),
+			wantErr: "invalid credentials for registry 'ghcr.io' in image pull secret 'default/pull-secret'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := ValidatePullSecret(tt.secret)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func Test_registryDomain(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(registryDomain("ghcr.io")).To(Equal("ghcr.io"))
+	g.Expect(registryDomain("https://index.docker.io/v1/")).To(Equal("docker.io"))
+	g.Expect(registryDomain("http://localhost:5000")).To(Equal("localhost:5000"))
+}
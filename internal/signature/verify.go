@@ -0,0 +1,334 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference/docker"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dockerauth "oras.land/oras-go/pkg/auth/docker"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	intdigest "github.com/fluxcd/helm-controller/internal/digest"
+	"github.com/fluxcd/helm-controller/internal/kube"
+)
+
+const (
+	// signatureAnnotation is the annotation of a cosign signature layer
+	// containing the base64-encoded signature of the layer.
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	// simpleSigningMediaType is the media type of a cosign signature
+	// layer.
+	simpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// maxFetchSize is the maximum size of a signature manifest or payload.
+	maxFetchSize = 4 << 20
+)
+
+var (
+	// ImageVerifier is a global Verifier for the container images of the
+	// rendered manifests of a release. When nil, images are not verified.
+	ImageVerifier *Verifier
+
+	// registryMirrors maps the domain of a registry to the domain of the
+	// mirror its images and signatures are retrieved from.
+	registryMirrors   map[string]string
+	registryMirrorsMu sync.RWMutex
+)
+
+// SetRegistryMirrors sets the mirrors the images and their signatures are
+// retrieved from, as a map of registry domains to mirror domains, e.g.
+// "docker.io" to "mirror.example.com". It replaces the mirrors set by a
+// previous call, and is safe to call concurrently with Verify.
+func SetRegistryMirrors(mirrors map[string]string) {
+	m := make(map[string]string, len(mirrors))
+	for registry, mirror := range mirrors {
+		m[registry] = mirror
+	}
+
+	registryMirrorsMu.Lock()
+	defer registryMirrorsMu.Unlock()
+	registryMirrors = m
+}
+
+// mirrorReference returns the given reference with its domain replaced by
+// the domain of the mirror of the registry, if any.
+func mirrorReference(ref string) string {
+	registryMirrorsMu.RLock()
+	defer registryMirrorsMu.RUnlock()
+
+	domain, remainder, ok := strings.Cut(ref, "/")
+	if !ok {
+		return ref
+	}
+	if mirror, ok := registryMirrors[domain]; ok {
+		return mirror + "/" + remainder
+	}
+	return ref
+}
+
+// VerificationError is returned by Verifier.Verify when images do not have
+// a valid signature.
+type VerificationError struct {
+	// Failures are the reasons of the verification failures per image.
+	Failures []string
+}
+
+// Error returns an error string containing the failures.
+func (e *VerificationError) Error() string {
+	return "image signature verification failed: " + strings.Join(e.Failures, "; ")
+}
+
+// signatureError is returned by verifyImage when an image does not have a
+// valid signature.
+type signatureError string
+
+func (e signatureError) Error() string {
+	return string(e)
+}
+
+// Verifier verifies the cosign signatures of container images against a
+// Policy.
+type Verifier struct {
+	policy   *Policy
+	resolver remotes.Resolver
+}
+
+// NewVerifier returns a new Verifier for the given Policy, which retrieves
+// images and their signatures with the given resolver.
+func NewVerifier(policy *Policy, resolver remotes.Resolver) *Verifier {
+	return &Verifier{
+		policy:   policy,
+		resolver: resolver,
+	}
+}
+
+// NewResolver returns a resolver which authenticates to registries with the
+// credentials in the Docker config file of the current user (or
+// $DOCKER_CONFIG), if any.
+func NewResolver() (remotes.Resolver, error) {
+	client, err := dockerauth.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry credentials: %w", err)
+	}
+	return client.ResolverWithOpts()
+}
+
+// Verify verifies the signatures of the given images against the rules of
+// the Policy matching their repository, and returns the verified digests of
+// the images with a matching rule, keyed by image. It returns a
+// VerificationError listing the images without a valid signature, or an
+// error if an image or its signatures could not be retrieved.
+func (v *Verifier) Verify(ctx context.Context, images []string) (map[string]digest.Digest, error) {
+	digests := make(map[string]digest.Digest)
+	var failures []string
+	for _, image := range images {
+		named, err := docker.ParseDockerRef(image)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: invalid image reference: %s", image, err))
+			continue
+		}
+		rule := v.policy.rule(named.Name())
+		if rule == nil {
+			continue
+		}
+		d, err := v.verifyImage(ctx, named, rule.keys)
+		if err != nil {
+			var sigErr signatureError
+			if !errors.As(err, &sigErr) {
+				return nil, err
+			}
+			failures = append(failures, fmt.Sprintf("%s: %s", image, sigErr))
+			continue
+		}
+		digests[image] = d
+	}
+	if len(failures) > 0 {
+		return nil, &VerificationError{Failures: failures}
+	}
+	return digests, nil
+}
+
+// verifyImage verifies the image has a cosign signature for its digest made
+// with any of the given keys, and returns the digest. It returns a
+// signatureError if it does not.
+func (v *Verifier) verifyImage(ctx context.Context, named docker.Named, keys []crypto.PublicKey) (digest.Digest, error) {
+	ref := mirrorReference(named.String())
+	_, desc, err := v.resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve image '%s': %w", ref, err)
+	}
+	if err = intdigest.Allowed(desc.Digest.Algorithm()); err != nil {
+		return "", signatureError(err.Error())
+	}
+
+	// Cosign stores the signatures of an image in a manifest tagged with
+	// the digest of the image.
+	sigRef := mirrorReference(fmt.Sprintf("%s:%s-%s.sig", named.Name(), desc.Digest.Algorithm(), desc.Digest.Encoded()))
+	_, sigDesc, err := v.resolver.Resolve(ctx, sigRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", signatureError("no signatures found")
+		}
+		return "", fmt.Errorf("failed to resolve signatures '%s': %w", sigRef, err)
+	}
+	fetcher, err := v.resolver.Fetcher(ctx, sigRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch signatures '%s': %w", sigRef, err)
+	}
+	b, err := fetch(ctx, fetcher, sigDesc)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch signatures '%s': %w", sigRef, err)
+	}
+	var manifest ocispec.Manifest
+	if err = json.Unmarshal(b, &manifest); err != nil {
+		return "", fmt.Errorf("failed to decode signatures '%s': %w", sigRef, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != simpleSigningMediaType {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(layer.Annotations[signatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+		payload, err := fetch(ctx, fetcher, layer)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch signature payload '%s': %w", layer.Digest, err)
+		}
+		if !VerifySignature(keys, payload, sig) {
+			continue
+		}
+		if payloadDigest(payload) == desc.Digest.String() {
+			return desc.Digest, nil
+		}
+	}
+	return "", signatureError("no signature matching the public keys of the policy")
+}
+
+// fetch returns the content of the given descriptor, after verifying its
+// digest.
+func fetch(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	if desc.Size > maxFetchSize {
+		return nil, fmt.Errorf("size %d exceeds the maximum of %d bytes", desc.Size, maxFetchSize)
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(io.LimitReader(rc, maxFetchSize))
+	if err != nil {
+		return nil, err
+	}
+	if d := digest.FromBytes(b); d != desc.Digest {
+		return nil, fmt.Errorf("digest mismatch: expected %s, got %s", desc.Digest, d)
+	}
+	return b, nil
+}
+
+// VerifySignature returns if the signature of the payload was made with any
+// of the given keys.
+func VerifySignature(keys []crypto.PublicKey, payload, sig []byte) bool {
+	h := sha256.Sum256(payload)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, h[:], sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, payload, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// payloadDigest returns the image digest the given simple signing payload
+// was made for.
+func payloadDigest(payload []byte) string {
+	var p struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(payload)).Decode(&p); err != nil {
+		return ""
+	}
+	return p.Critical.Image.DockerManifestDigest
+}
+
+// Images returns the sorted unique container images of the Pod specs of the
+// given objects. Objects without a Pod spec are ignored.
+func Images(objects []*unstructured.Unstructured) ([]string, error) {
+	seen := make(map[string]struct{})
+	for _, obj := range objects {
+		spec, err := kube.PodSpec(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Pod spec of %s: %w", ssautil.FmtUnstructured(obj), err)
+		}
+		if spec == nil {
+			continue
+		}
+		for _, c := range spec.InitContainers {
+			seen[c.Image] = struct{}{}
+		}
+		for _, c := range spec.Containers {
+			seen[c.Image] = struct{}{}
+		}
+		for _, c := range spec.EphemeralContainers {
+			seen[c.Image] = struct{}{}
+		}
+	}
+	delete(seen, "")
+
+	images := make([]string, 0, len(seen))
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
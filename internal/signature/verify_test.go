@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+// fakeResolver is a remotes.Resolver serving manifests and blobs from
+// memory.
+type fakeResolver struct {
+	refs  map[string]ocispec.Descriptor
+	blobs map[digest.Digest][]byte
+}
+
+func newFakeResolver() *fakeResolver {
+	return &fakeResolver{
+		refs:  make(map[string]ocispec.Descriptor),
+		blobs: make(map[digest.Digest][]byte),
+	}
+}
+
+func (r *fakeResolver) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	desc, ok := r.refs[ref]
+	if _, d, found := strings.Cut(ref, "@"); found {
+		_, ok = r.blobs[digest.Digest(d)]
+		desc = ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.Digest(d)}
+	}
+	if !ok {
+		return "", ocispec.Descriptor{}, fmt.Errorf("%s: %w", ref, errdefs.ErrNotFound)
+	}
+	return ref, desc, nil
+}
+
+func (r *fakeResolver) Fetcher(_ context.Context, _ string) (remotes.Fetcher, error) {
+	return remotes.FetcherFunc(func(_ context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		b, ok := r.blobs[desc.Digest]
+		if !ok {
+			return nil, errdefs.ErrNotFound
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}), nil
+}
+
+func (r *fakeResolver) Pusher(_ context.Context, _ string) (remotes.Pusher, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeResolver) add(ref, mediaType string, b []byte, annotations map[string]string) ocispec.Descriptor {
+	desc := ocispec.Descriptor{
+		MediaType:   mediaType,
+		Digest:      digest.FromBytes(b),
+		Size:        int64(len(b)),
+		Annotations: annotations,
+	}
+	r.blobs[desc.Digest] = b
+	if ref != "" {
+		r.refs[ref] = desc
+	}
+	return desc
+}
+
+// pushImage adds an image with the given reference, and returns its digest.
+func (r *fakeResolver) pushImage(ref string) digest.Digest {
+	return r.add(ref, ocispec.MediaTypeImageManifest, []byte(`{"schemaVersion":2,"ref":"`+ref+`"}`), nil).Digest
+}
+
+// sign adds a cosign signature for the image digest made with the given key.
+func (r *fakeResolver) sign(repository string, d digest.Digest, payloadDigest digest.Digest, key *ecdsa.PrivateKey) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`,
+		repository, payloadDigest))
+	h := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+	if err != nil {
+		panic(err)
+	}
+
+	layer := r.add("", simpleSigningMediaType, payload, map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+	})
+	manifest, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+	if err != nil {
+		panic(err)
+	}
+	r.add(fmt.Sprintf("%s:sha256-%s.sig", repository, d.Encoded()), ocispec.MediaTypeImageManifest, manifest, nil)
+}
+
+func generateKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key, pub := generateKey(t)
+	otherKey, _ := generateKey(t)
+
+	resolver := newFakeResolver()
+	signed := resolver.pushImage("ghcr.io/stefanprodan/podinfo:6.5.3")
+	resolver.sign("ghcr.io/stefanprodan/podinfo", signed, signed, key)
+	otherSigned := resolver.pushImage("ghcr.io/stefanprodan/podinfo:6.5.2")
+	resolver.sign("ghcr.io/stefanprodan/podinfo", otherSigned, otherSigned, otherKey)
+	replayed := resolver.pushImage("ghcr.io/stefanprodan/podinfo:6.5.1")
+	resolver.sign("ghcr.io/stefanprodan/podinfo", replayed, signed, key)
+	resolver.pushImage("ghcr.io/stefanprodan/podinfo:6.5.0")
+
+	policy := &Policy{Images: []ImageRule{{Pattern: "ghcr.io/stefanprodan/*", PublicKeys: []string{pub}}}}
+	NewWithT(t).Expect(policy.Validate()).To(Succeed())
+
+	tests := []struct {
+		name        string
+		images      []string
+		wantDigests map[string]digest.Digest
+		wantErr     []string
+	}{
+		{
+			name:        "signed image",
+			images:      []string{"ghcr.io/stefanprodan/podinfo:6.5.3"},
+			wantDigests: map[string]digest.Digest{"ghcr.io/stefanprodan/podinfo:6.5.3": signed},
+		},
+		{
+			name:        "signed image by digest",
+			images:      []string{"ghcr.io/stefanprodan/podinfo@" + signed.String()},
+			wantDigests: map[string]digest.Digest{"ghcr.io/stefanprodan/podinfo@" + signed.String(): signed},
+		},
+		{
+			name:        "image without matching rule",
+			images:      []string{"nginx:1.25"},
+			wantDigests: map[string]digest.Digest{},
+		},
+		{
+			name:    "image signed with other key",
+			images:  []string{"ghcr.io/stefanprodan/podinfo:6.5.2"},
+			wantErr: []string{"ghcr.io/stefanprodan/podinfo:6.5.2: no signature matching the public keys of the policy"},
+		},
+		{
+			name:    "signature for other digest",
+			images:  []string{"ghcr.io/stefanprodan/podinfo:6.5.1"},
+			wantErr: []string{"ghcr.io/stefanprodan/podinfo:6.5.1: no signature matching the public keys of the policy"},
+		},
+		{
+			name:   "unsigned images",
+			images: []string{"ghcr.io/stefanprodan/podinfo:6.5.0", "ghcr.io/stefanprodan/podinfo:6.5.3", "ghcr.io/stefanprodan/Podinfo"},
+			wantErr: []string{
+				"ghcr.io/stefanprodan/podinfo:6.5.0: no signatures found",
+				"ghcr.io/stefanprodan/Podinfo: invalid image reference",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			digests, err := NewVerifier(policy, resolver).Verify(context.TODO(), tt.images)
+			if len(tt.wantErr) == 0 {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(digests).To(Equal(tt.wantDigests))
+				return
+			}
+			var verificationErr *VerificationError
+			g.Expect(errors.As(err, &verificationErr)).To(BeTrue())
+			g.Expect(verificationErr.Failures).To(HaveLen(len(tt.wantErr)))
+			for _, want := range tt.wantErr {
+				g.Expect(err.Error()).To(ContainSubstring(want))
+			}
+		})
+	}
+
+	t.Run("image not found", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := NewVerifier(policy, resolver).Verify(context.TODO(), []string{"ghcr.io/stefanprodan/podinfo:missing"})
+		g.Expect(err).To(MatchError(ContainSubstring("failed to resolve image 'ghcr.io/stefanprodan/podinfo:missing'")))
+		var verificationErr *VerificationError
+		g.Expect(errors.As(err, &verificationErr)).To(BeFalse())
+	})
+
+	t.Run("registry mirror", func(t *testing.T) {
+		g := NewWithT(t)
+
+		mirrored := resolver.pushImage("mirror.example.com/stefanprodan/podinfo:6.4.0")
+		resolver.sign("mirror.example.com/stefanprodan/podinfo", mirrored, mirrored, key)
+
+		SetRegistryMirrors(map[string]string{"ghcr.io": "mirror.example.com"})
+		t.Cleanup(func() { SetRegistryMirrors(nil) })
+
+		digests, err := NewVerifier(policy, resolver).Verify(context.TODO(), []string{"ghcr.io/stefanprodan/podinfo:6.4.0"})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(digests).To(HaveKeyWithValue("ghcr.io/stefanprodan/podinfo:6.4.0", mirrored))
+	})
+}
+
+func TestImages(t *testing.T) {
+	g := NewWithT(t)
+
+	objects, err := ssautil.ReadObjects(strings.NewReader(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: podinfo
+        image: ghcr.io/stefanprodan/podinfo:6.5.3
+      - name: sidecar
+        image: busybox:1.36
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: podinfo
+data:
+  image: nginx
+`))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	images, err := Images(objects)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(images).To(Equal([]string{"busybox:1.36", "ghcr.io/stefanprodan/podinfo:6.5.3"}))
+}
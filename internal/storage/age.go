@@ -0,0 +1,204 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// ageSecretKeyHRP is the human-readable part of the Bech32 encoding of
+	// an age X25519 identity.
+	ageSecretKeyHRP = "age-secret-key-"
+	// ageX25519Label is the HKDF info of the wrapping key of an age X25519
+	// recipient stanza.
+	ageX25519Label = "age-encryption.org/v1/X25519"
+)
+
+// AgeKey is a KeyEncryptionKey wrapping data encryption keys for an age
+// X25519 identity, as generated by age-keygen. A data encryption key is
+// wrapped like the file key of an age X25519 recipient stanza: with
+// ChaCha20-Poly1305, using a key derived from an ephemeral X25519 share and
+// the recipient of the identity.
+type AgeKey struct {
+	id       string
+	identity *ecdh.PrivateKey
+}
+
+// NewAgeKey returns a new AgeKey with the given ID for the given age X25519
+// identity, e.g. "AGE-SECRET-KEY-1...".
+func NewAgeKey(id, identity string) (*AgeKey, error) {
+	if id == "" {
+		return nil, errors.New("key ID must not be empty")
+	}
+	hrp, data, err := bech32Decode(strings.TrimSpace(identity))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity of key '%s': %w", id, err)
+	}
+	if hrp != ageSecretKeyHRP {
+		return nil, fmt.Errorf("invalid age identity of key '%s': unexpected type '%s'", id, hrp)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity of key '%s': %w", id, err)
+	}
+	return &AgeKey{id: id, identity: key}, nil
+}
+
+// ID returns the ID of the key.
+func (k *AgeKey) ID() string {
+	return k.id
+}
+
+// Wrap encrypts the given data encryption key for the recipient of the
+// identity. The result is prefixed with the ephemeral share.
+func (k *AgeKey) Wrap(dek []byte) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(k.identity.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	share := ephemeral.PublicKey().Bytes()
+	aead, err := k.aead(share, shared)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(share, make([]byte, chacha20poly1305.NonceSize), dek, nil), nil
+}
+
+// Unwrap decrypts the given wrapped data encryption key with the identity.
+func (k *AgeKey) Unwrap(wrapped []byte) ([]byte, error) {
+	size := len(k.identity.PublicKey().Bytes())
+	if len(wrapped) < size {
+		return nil, errors.New("wrapped key too short")
+	}
+	share, err := ecdh.X25519().NewPublicKey(wrapped[:size])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := k.identity.ECDH(share)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := k.aead(wrapped[:size], shared)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), wrapped[size:], nil)
+}
+
+// aead returns the AEAD for the given ephemeral share and shared secret.
+func (k *AgeKey) aead(share, shared []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, share...), k.identity.PublicKey().Bytes()...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(ageX25519Label)), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+// bech32Charset is the alphabet of the data part of a Bech32 string.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Decode returns the lowercase human-readable part and the 8-bit data
+// of the given Bech32 string, after verifying its checksum. Unlike BIP 173,
+// it does not limit the length of the string, like age.
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("mixed case")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, errors.New("invalid separator position")
+	}
+	hrp := s[:pos]
+
+	values := make([]byte, 0, len(s)-pos-1)
+	for _, c := range s[pos+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid character '%c'", c)
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), values...)) != 1 {
+		return "", nil, errors.New("invalid checksum")
+	}
+
+	// Convert the 5-bit groups without the checksum to bytes.
+	var (
+		data []byte
+		acc  uint32
+		bits uint
+	)
+	for _, v := range values[:len(values)-6] {
+		acc = acc<<5 | uint32(v)
+		bits += 5
+		for bits >= 8 {
+			bits -= 8
+			data = append(data, byte(acc>>bits))
+		}
+	}
+	if bits >= 5 || acc&(1<<bits-1) != 0 {
+		return "", nil, errors.New("invalid padding")
+	}
+	return hrp, data, nil
+}
+
+// bech32ExpandHRP returns the human-readable part expanded for the checksum
+// computation.
+func bech32ExpandHRP(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// bech32Polymod returns the Bech32 checksum of the given values.
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// mockAgeIdentity returns an age X25519 identity with a key of 32 times the
+// given byte.
+func mockAgeIdentity(t *testing.T, b byte) string {
+	t.Helper()
+
+	var (
+		values []byte
+		acc    uint32
+		bits   uint
+	)
+	for _, d := range bytes.Repeat([]byte{b}, 32) {
+		acc = acc<<8 | uint32(d)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			values = append(values, byte(acc>>bits)&31)
+		}
+	}
+	if bits > 0 {
+		values = append(values, byte(acc<<(5-bits))&31)
+	}
+	polymod := bech32Polymod(append(append(bech32ExpandHRP(ageSecretKeyHRP), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(polymod>>(5*(5-i)))&31)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(ageSecretKeyHRP + "1")
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	return strings.ToUpper(sb.String())
+}
+
+func TestNewAgeKey(t *testing.T) {
+	g := NewWithT(t)
+
+	// The identity of the age test vectors, of which the key is 32 times
+	// 0x42.
+	key, err := NewAgeKey("key-1", "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.identity.Bytes()).To(Equal(bytes.Repeat([]byte{0x42}, 32)))
+	g.Expect(mockAgeIdentity(t, 0x42)).To(Equal("AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX"))
+
+	_, err = NewAgeKey("key-1", "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEZ")
+	g.Expect(err).To(MatchError("invalid age identity of key 'key-1': invalid checksum"))
+
+	_, err = NewAgeKey("key-1", "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj")
+	g.Expect(err).To(MatchError(ContainSubstring("invalid age identity of key 'key-1'")))
+
+	_, err = NewAgeKey("", mockAgeIdentity(t, 1))
+	g.Expect(err).To(MatchError("key ID must not be empty"))
+}
+
+func TestAgeKey_Wrap(t *testing.T) {
+	g := NewWithT(t)
+
+	key, err := NewAgeKey("key-1", mockAgeIdentity(t, 1))
+	g.Expect(err).ToNot(HaveOccurred())
+	other, err := NewAgeKey("key-1", mockAgeIdentity(t, 2))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	dek := bytes.Repeat([]byte{3}, 32)
+	wrapped, err := key.Wrap(dek)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(wrapped).ToNot(ContainSubstring(string(dek)))
+
+	unwrapped, err := key.Unwrap(wrapped)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(unwrapped).To(Equal(dek))
+
+	_, err = other.Unwrap(wrapped)
+	g.Expect(err).To(HaveOccurred())
+	_, err = key.Unwrap(wrapped[:16])
+	g.Expect(err).To(MatchError("wrapped key too short"))
+}
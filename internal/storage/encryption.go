@@ -0,0 +1,486 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// releaseDataKey is the key of the release payload in the data of a Helm
+// storage Secret.
+const releaseDataKey = "release"
+
+// envelopePrefix is the prefix of an encrypted release payload.
+var envelopePrefix = []byte("fluxcd.io/envelope:v1:")
+
+var (
+	// Encryption is the global Envelope used to encrypt the release payload
+	// of the Helm storage Secrets. When nil, the payload is not encrypted.
+	Encryption *Envelope
+)
+
+// KeyEncryptionKey wraps and unwraps the data encryption keys of an
+// Envelope. It can be implemented by a KMS provider.
+type KeyEncryptionKey interface {
+	// ID returns the identifier of the key, which is recorded in the
+	// envelope to select the key for decryption.
+	ID() string
+	// Wrap encrypts the given data encryption key.
+	Wrap(dek []byte) ([]byte, error)
+	// Unwrap decrypts the given wrapped data encryption key.
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// Envelope encrypts data with a random AES-256-GCM data encryption key, which
+// is stored next to the data wrapped with a KeyEncryptionKey.
+type Envelope struct {
+	// keys holds the KeyEncryptionKeys, of which the first one is used for
+	// encryption, and all for decryption.
+	keys []KeyEncryptionKey
+}
+
+// NewEnvelope returns a new Envelope encrypting with the first of the given
+// keys, and decrypting with any of them. This allows for the rotation of
+// keys, by prepending a new key while keeping the old.
+func NewEnvelope(keys ...KeyEncryptionKey) (*Envelope, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key encryption key is required")
+	}
+	return &Envelope{keys: keys}, nil
+}
+
+// envelope is the encoding of encrypted data.
+type envelope struct {
+	// KeyID is the ID of the KeyEncryptionKey which wrapped Key.
+	KeyID string `json:"kid"`
+	// Key is the wrapped data encryption key.
+	Key []byte `json:"key"`
+	// Data is the encrypted data, prefixed with the nonce.
+	Data []byte `json:"data"`
+}
+
+// IsEncrypted returns if the given data was encrypted by an Envelope.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, envelopePrefix)
+}
+
+// Encrypt encrypts the given data with a new data encryption key. The
+// ciphertext is bound to the given associated data, which must be passed
+// to Decrypt as is.
+func (e *Envelope) Encrypt(data, associatedData []byte) ([]byte, error) {
+	dek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(dek, data, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	kek := e.keys[0]
+	wrapped, err := kek.Wrap(dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data encryption key with key '%s': %w", kek.ID(), err)
+	}
+	b, err := json.Marshal(envelope{KeyID: kek.ID(), Key: wrapped, Data: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, envelopePrefix...), b...), nil
+}
+
+// Decrypt decrypts the given data encrypted by an Envelope with any of its
+// keys, and the associated data it was encrypted with. Data which is not
+// encrypted is returned as is.
+func (e *Envelope) Decrypt(data, associatedData []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if e == nil {
+		return nil, errors.New("data is encrypted, but no encryption keys are configured")
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data[len(envelopePrefix):], &env); err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	for _, kek := range e.keys {
+		if kek.ID() != env.KeyID {
+			continue
+		}
+		dek, err := kek.Unwrap(env.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data encryption key with key '%s': %w", kek.ID(), err)
+		}
+		return open(dek, env.Data, associatedData)
+	}
+	return nil, fmt.Errorf("no key encryption key with ID '%s' configured", env.KeyID)
+}
+
+// EncryptRelease encrypts the given release payload of the Helm storage
+// record with the given key. The payload is encoded by Helm with base64,
+// which is decoded before encryption, as the envelope is encoded with base64
+// itself. The ciphertext is bound to the key, so that the records of
+// releases can not be swapped.
+func (e *Envelope) EncryptRelease(key string, data []byte) ([]byte, error) {
+	payload, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode release payload: %w", err)
+	}
+	return e.Encrypt(payload, []byte(key))
+}
+
+// DecryptRelease decrypts the given release payload of the Helm storage
+// record with the given key, as encrypted by EncryptRelease, and encodes it
+// with base64 as expected by Helm. A payload which is not encrypted is
+// returned as is.
+func (e *Envelope) DecryptRelease(key string, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	payload, err := e.Decrypt(data, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(payload)), nil
+}
+
+// AESKey is a KeyEncryptionKey wrapping data encryption keys with
+// AES-256-GCM.
+type AESKey struct {
+	id  string
+	key []byte
+}
+
+// NewAESKey returns a new AESKey with the given ID and 32 byte key.
+func NewAESKey(id string, key []byte) (*AESKey, error) {
+	if id == "" {
+		return nil, errors.New("key ID must not be empty")
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key '%s' must be 32 bytes, got %d", id, len(key))
+	}
+	return &AESKey{id: id, key: key}, nil
+}
+
+// ID returns the ID of the key.
+func (k *AESKey) ID() string {
+	return k.id
+}
+
+// Wrap encrypts the given data encryption key.
+func (k *AESKey) Wrap(dek []byte) ([]byte, error) {
+	return seal(k.key, dek, nil)
+}
+
+// Unwrap decrypts the given wrapped data encryption key.
+func (k *AESKey) Unwrap(wrapped []byte) ([]byte, error) {
+	return open(k.key, wrapped, nil)
+}
+
+// LoadKeys reads the KeyEncryptionKeys from the YAML file at the given path.
+// The file contains a list of keys, each with an ID and either a
+// base64-encoded 32 byte AES secret, an age X25519 identity, or the
+// configuration of a Vault transit key. The first key is used for
+// encryption.
+func LoadKeys(path string) ([]KeyEncryptionKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage encryption keys: %w", err)
+	}
+
+	var config struct {
+		Keys []struct {
+			ID     string `json:"id"`
+			Secret string `json:"secret,omitempty"`
+			Age    string `json:"age,omitempty"`
+			Vault  *struct {
+				Address   string `json:"address"`
+				Mount     string `json:"mount,omitempty"`
+				Key       string `json:"key"`
+				TokenFile string `json:"tokenFile"`
+			} `json:"vault,omitempty"`
+		} `json:"keys"`
+	}
+	if err = yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode storage encryption keys from '%s': %w", path, err)
+	}
+	if len(config.Keys) == 0 {
+		return nil, fmt.Errorf("no storage encryption keys in '%s'", path)
+	}
+
+	keys := make([]KeyEncryptionKey, 0, len(config.Keys))
+	for _, k := range config.Keys {
+		var key KeyEncryptionKey
+		switch {
+		case k.Secret != "" && k.Age == "" && k.Vault == nil:
+			secret, err := base64.StdEncoding.DecodeString(k.Secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode secret of key '%s': %w", k.ID, err)
+			}
+			if key, err = NewAESKey(k.ID, secret); err != nil {
+				return nil, err
+			}
+		case k.Age != "" && k.Secret == "" && k.Vault == nil:
+			if key, err = NewAgeKey(k.ID, k.Age); err != nil {
+				return nil, err
+			}
+		case k.Vault != nil && k.Secret == "" && k.Age == "":
+			if key, err = NewVaultTransitKey(k.ID, k.Vault.Address, k.Vault.Mount, k.Vault.Key, k.Vault.TokenFile); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("key '%s' must have exactly one of 'secret', 'age' or 'vault'", k.ID)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// seal encrypts the given data with AES-GCM, bound to the given associated
+// data, and prefixes it with a random nonce.
+func seal(key, data, associatedData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, associatedData), nil
+}
+
+// open decrypts the given data sealed with seal with the same associated
+// data.
+func open(key, data, associatedData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], associatedData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptedSecrets is a Secrets client which encrypts the release payload of
+// the Helm storage Secrets written with it, and decrypts the payload of the
+// Secrets read with it. It is used as the client of the Helm storage Secrets
+// driver, and leaves the labels of the Secrets used to query releases as is.
+//
+// Secrets which are not encrypted, e.g. because they were written before
+// encryption was configured, are read as is and encrypted on their next
+// write.
+type EncryptedSecrets struct {
+	corev1client.SecretInterface
+
+	envelope *Envelope
+}
+
+// NewEncryptedSecrets returns a new EncryptedSecrets for the given Secrets
+// client and Envelope.
+func NewEncryptedSecrets(secrets corev1client.SecretInterface, envelope *Envelope) *EncryptedSecrets {
+	return &EncryptedSecrets{
+		SecretInterface: secrets,
+		envelope:        envelope,
+	}
+}
+
+// Get returns the Secret with the given name, with its payload decrypted.
+func (s *EncryptedSecrets) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+	obj, err := s.SecretInterface.Get(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return obj, s.decrypt(obj)
+}
+
+// List returns the Secrets matching the given options, with their payload
+// decrypted.
+func (s *EncryptedSecrets) List(ctx context.Context, opts metav1.ListOptions) (*corev1.SecretList, error) {
+	list, err := s.SecretInterface.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		if err = s.decrypt(&list.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Create creates the given Secret, with its payload encrypted.
+func (s *EncryptedSecrets) Create(ctx context.Context, obj *corev1.Secret, opts metav1.CreateOptions) (*corev1.Secret, error) {
+	obj, err := s.encrypt(obj)
+	if err != nil {
+		return nil, err
+	}
+	return s.SecretInterface.Create(ctx, obj, opts)
+}
+
+// Update updates the given Secret, with its payload encrypted.
+func (s *EncryptedSecrets) Update(ctx context.Context, obj *corev1.Secret, opts metav1.UpdateOptions) (*corev1.Secret, error) {
+	obj, err := s.encrypt(obj)
+	if err != nil {
+		return nil, err
+	}
+	return s.SecretInterface.Update(ctx, obj, opts)
+}
+
+// encrypt returns a copy of the given Secret with its payload encrypted.
+func (s *EncryptedSecrets) encrypt(obj *corev1.Secret) (*corev1.Secret, error) {
+	data, ok := obj.Data[releaseDataKey]
+	if !ok || IsEncrypted(data) {
+		return obj, nil
+	}
+	encrypted, err := s.envelope.EncryptRelease(obj.Name, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt Helm storage Secret '%s': %w", obj.Name, err)
+	}
+	obj = obj.DeepCopy()
+	obj.Data[releaseDataKey] = encrypted
+	return obj, nil
+}
+
+// decrypt decrypts the payload of the given Secret in place.
+func (s *EncryptedSecrets) decrypt(obj *corev1.Secret) error {
+	data, ok := obj.Data[releaseDataKey]
+	if !ok {
+		return nil
+	}
+	decrypted, err := s.envelope.DecryptRelease(obj.Name, data)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt Helm storage Secret '%s': %w", obj.Name, err)
+	}
+	obj.Data[releaseDataKey] = decrypted
+	return nil
+}
+
+// EncryptedConfigMaps is a ConfigMaps client which encrypts the release
+// payload of the Helm storage ConfigMaps written with it, and decrypts the
+// payload of the ConfigMaps read with it, like EncryptedSecrets does for
+// the Helm storage Secrets driver.
+type EncryptedConfigMaps struct {
+	corev1client.ConfigMapInterface
+
+	envelope *Envelope
+}
+
+// NewEncryptedConfigMaps returns a new EncryptedConfigMaps for the given
+// ConfigMaps client and Envelope.
+func NewEncryptedConfigMaps(configMaps corev1client.ConfigMapInterface, envelope *Envelope) *EncryptedConfigMaps {
+	return &EncryptedConfigMaps{
+		ConfigMapInterface: configMaps,
+		envelope:           envelope,
+	}
+}
+
+// Get returns the ConfigMap with the given name, with its payload decrypted.
+func (c *EncryptedConfigMaps) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.ConfigMap, error) {
+	obj, err := c.ConfigMapInterface.Get(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return obj, c.decrypt(obj)
+}
+
+// List returns the ConfigMaps matching the given options, with their
+// payload decrypted.
+func (c *EncryptedConfigMaps) List(ctx context.Context, opts metav1.ListOptions) (*corev1.ConfigMapList, error) {
+	list, err := c.ConfigMapInterface.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		if err = c.decrypt(&list.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Create creates the given ConfigMap, with its payload encrypted.
+func (c *EncryptedConfigMaps) Create(ctx context.Context, obj *corev1.ConfigMap, opts metav1.CreateOptions) (*corev1.ConfigMap, error) {
+	obj, err := c.encrypt(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConfigMapInterface.Create(ctx, obj, opts)
+}
+
+// Update updates the given ConfigMap, with its payload encrypted.
+func (c *EncryptedConfigMaps) Update(ctx context.Context, obj *corev1.ConfigMap, opts metav1.UpdateOptions) (*corev1.ConfigMap, error) {
+	obj, err := c.encrypt(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConfigMapInterface.Update(ctx, obj, opts)
+}
+
+// encrypt returns a copy of the given ConfigMap with its payload encrypted.
+func (c *EncryptedConfigMaps) encrypt(obj *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	data, ok := obj.Data[releaseDataKey]
+	if !ok || IsEncrypted([]byte(data)) {
+		return obj, nil
+	}
+	encrypted, err := c.envelope.EncryptRelease(obj.Name, []byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt Helm storage ConfigMap '%s': %w", obj.Name, err)
+	}
+	obj = obj.DeepCopy()
+	obj.Data[releaseDataKey] = string(encrypted)
+	return obj, nil
+}
+
+// decrypt decrypts the payload of the given ConfigMap in place.
+func (c *EncryptedConfigMaps) decrypt(obj *corev1.ConfigMap) error {
+	data, ok := obj.Data[releaseDataKey]
+	if !ok {
+		return nil
+	}
+	decrypted, err := c.envelope.DecryptRelease(obj.Name, []byte(data))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt Helm storage ConfigMap '%s': %w", obj.Name, err)
+	}
+	obj.Data[releaseDataKey] = string(decrypted)
+	return nil
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func mockAESKey(t *testing.T, id string, b byte) *AESKey {
+	t.Helper()
+	key, err := NewAESKey(id, bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEnvelope(t *testing.T) {
+	t.Run("encrypts and decrypts", func(t *testing.T) {
+		g := NewWithT(t)
+
+		e, err := NewEnvelope(mockAESKey(t, "key-1", 1))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		encrypted, err := e.Encrypt([]byte("payload"), []byte("key"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(IsEncrypted(encrypted)).To(BeTrue())
+		g.Expect(encrypted).ToNot(ContainSubstring("payload"))
+
+		decrypted, err := e.Decrypt(encrypted, []byte("key"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(decrypted)).To(Equal("payload"))
+	})
+
+	t.Run("decrypts with rotated key", func(t *testing.T) {
+		g := NewWithT(t)
+
+		old, err := NewEnvelope(mockAESKey(t, "key-1", 1))
+		g.Expect(err).ToNot(HaveOccurred())
+		encrypted, err := old.Encrypt([]byte("payload"), []byte("key"))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		rotated, err := NewEnvelope(mockAESKey(t, "key-2", 2), mockAESKey(t, "key-1", 1))
+		g.Expect(err).ToNot(HaveOccurred())
+		decrypted, err := rotated.Decrypt(encrypted, []byte("key"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(decrypted)).To(Equal("payload"))
+
+		reencrypted, err := rotated.Encrypt(decrypted, []byte("key"))
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = old.Decrypt(reencrypted, []byte("key"))
+		g.Expect(err).To(MatchError("no key encryption key with ID 'key-2' configured"))
+	})
+
+	t.Run("fails to decrypt with wrong key", func(t *testing.T) {
+		g := NewWithT(t)
+
+		e, err := NewEnvelope(mockAESKey(t, "key-1", 1))
+		g.Expect(err).ToNot(HaveOccurred())
+		encrypted, err := e.Encrypt([]byte("payload"), []byte("key"))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		other, err := NewEnvelope(mockAESKey(t, "key-1", 2))
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = other.Decrypt(encrypted, []byte("key"))
+		g.Expect(err).To(MatchError(ContainSubstring("failed to unwrap data encryption key with key 'key-1'")))
+	})
+
+	t.Run("fails to decrypt with other associated data", func(t *testing.T) {
+		g := NewWithT(t)
+
+		e, err := NewEnvelope(mockAESKey(t, "key-1", 1))
+		g.Expect(err).ToNot(HaveOccurred())
+		encrypted, err := e.Encrypt([]byte("payload"), []byte("key"))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, err = e.Decrypt(encrypted, []byte("other"))
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("returns unencrypted data as is", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var e *Envelope
+		decrypted, err := e.Decrypt([]byte("payload"), nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(decrypted)).To(Equal("payload"))
+
+		_, err = e.Decrypt(append(append([]byte{}, envelopePrefix...), '{', '}'), nil)
+		g.Expect(err).To(MatchError("data is encrypted, but no encryption keys are configured"))
+	})
+
+	t.Run("requires a key", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := NewEnvelope()
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func TestLoadKeys(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	identity := mockAgeIdentity(t, 1)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "valid keys",
+			data: "keys:\n- id: key-2\n  secret: " + secret + "\n- id: key-1\n  secret: " + secret + "\n",
+			want: []string{"key-2", "key-1"},
+		},
+		{
+			name: "age and Vault keys",
+			data: "keys:\n- id: key-3\n  vault:\n    address: https://vault.example.com:8200\n    key: helm\n    tokenFile: /var/run/secrets/vault/token\n" +
+				"- id: key-2\n  age: " + identity + "\n- id: key-1\n  secret: " + secret + "\n",
+			want: []string{"key-3", "key-2", "key-1"},
+		},
+		{
+			name:    "invalid key size",
+			data:    "keys:\n- id: key-1\n  secret: " + base64.StdEncoding.EncodeToString([]byte("short")) + "\n",
+			wantErr: "key 'key-1' must be 32 bytes, got 5",
+		},
+		{
+			name:    "invalid age identity",
+			data:    "keys:\n- id: key-1\n  age: AGE-SECRET-KEY-1INVALID\n",
+			wantErr: "invalid age identity of key 'key-1'",
+		},
+		{
+			name:    "invalid Vault address",
+			data:    "keys:\n- id: key-1\n  vault:\n    address: vault:8200\n    key: helm\n    tokenFile: /token\n",
+			wantErr: "invalid Vault address of key 'key-1'",
+		},
+		{
+			name:    "multiple key types",
+			data:    "keys:\n- id: key-1\n  secret: " + secret + "\n  age: " + identity + "\n",
+			wantErr: "key 'key-1' must have exactly one of 'secret', 'age' or 'vault'",
+		},
+		{
+			name:    "missing ID",
+			data:    "keys:\n- secret: " + secret + "\n",
+			wantErr: "key ID must not be empty",
+		},
+		{
+			name:    "no keys",
+			data:    "keys: []\n",
+			wantErr: "no storage encryption keys",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			path := filepath.Join(t.TempDir(), "keys.yaml")
+			g.Expect(os.WriteFile(path, []byte(tt.data), 0o600)).To(Succeed())
+
+			keys, err := LoadKeys(path)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			var ids []string
+			for _, k := range keys {
+				ids = append(ids, k.ID())
+			}
+			g.Expect(ids).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestEncryptedSecrets(t *testing.T) {
+	g := NewWithT(t)
+
+	e, err := NewEnvelope(mockAESKey(t, "key-1", 1))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	clientSet := fake.NewSimpleClientset()
+	secrets := clientSet.CoreV1().Secrets("default")
+
+	// Write an unencrypted release, as written before encryption was
+	// configured.
+	rls := releaseStub("podinfo", 1, "default", helmrelease.StatusSuperseded)
+	g.Expect(helmdriver.NewSecrets(secrets).Create(testKey(rls.Name, rls.Version), rls)).To(Succeed())
+
+	driver := helmdriver.NewSecrets(NewEncryptedSecrets(secrets, e))
+	rls2 := releaseStub("podinfo", 2, "default", helmrelease.StatusDeployed)
+	g.Expect(driver.Create(testKey(rls2.Name, rls2.Version), rls2)).To(Succeed())
+
+	raw, err := secrets.Get(context.TODO(), testKey(rls2.Name, rls2.Version), metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(IsEncrypted(raw.Data[releaseDataKey])).To(BeTrue())
+	g.Expect(raw.Labels).To(HaveKeyWithValue("status", "deployed"))
+
+	// The payload is encrypted without the base64 encoding of Helm, and
+	// bound to the key of the record.
+	payload, err := e.Decrypt(raw.Data[releaseDataKey], []byte(raw.Name))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(payload).To(HavePrefix(string(gzipMagic)))
+
+	got, err := driver.Get(testKey(rls2.Name, rls2.Version))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.Info.Status).To(Equal(helmrelease.StatusDeployed))
+
+	list, err := driver.Query(map[string]string{"name": "podinfo", "owner": "helm"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(list).To(HaveLen(2))
+
+	rlsUpdate := releaseStub("podinfo", 1, "default", helmrelease.StatusUninstalled)
+	g.Expect(driver.Update(testKey(rlsUpdate.Name, rlsUpdate.Version), rlsUpdate)).To(Succeed())
+	raw, err = secrets.Get(context.TODO(), testKey(rlsUpdate.Name, rlsUpdate.Version), metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(IsEncrypted(raw.Data[releaseDataKey])).To(BeTrue())
+
+	// The payload of a record can not be swapped with the payload of
+	// another release.
+	swapped := releaseStub("other", 1, "default", helmrelease.StatusDeployed)
+	g.Expect(driver.Create(testKey(swapped.Name, swapped.Version), swapped)).To(Succeed())
+	rawSwapped, err := secrets.Get(context.TODO(), testKey(swapped.Name, swapped.Version), metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	rawSwapped.Data[releaseDataKey] = raw.Data[releaseDataKey]
+	_, err = secrets.Update(context.TODO(), rawSwapped, metav1.UpdateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = driver.Get(testKey(swapped.Name, swapped.Version))
+	g.Expect(err).To(MatchError(ContainSubstring("failed to decrypt Helm storage Secret")))
+}
+
+func TestEncryptedConfigMaps(t *testing.T) {
+	g := NewWithT(t)
+
+	e, err := NewEnvelope(mockAESKey(t, "key-1", 1))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	clientSet := fake.NewSimpleClientset()
+	configMaps := clientSet.CoreV1().ConfigMaps("default")
+
+	// Write an unencrypted release, as written before encryption was
+	// configured.
+	rls := releaseStub("podinfo", 1, "default", helmrelease.StatusSuperseded)
+	g.Expect(helmdriver.NewConfigMaps(configMaps).Create(testKey(rls.Name, rls.Version), rls)).To(Succeed())
+
+	driver := helmdriver.NewConfigMaps(NewEncryptedConfigMaps(configMaps, e))
+	rls2 := releaseStub("podinfo", 2, "default", helmrelease.StatusDeployed)
+	g.Expect(driver.Create(testKey(rls2.Name, rls2.Version), rls2)).To(Succeed())
+
+	raw, err := configMaps.Get(context.TODO(), testKey(rls2.Name, rls2.Version), metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(IsEncrypted([]byte(raw.Data[releaseDataKey]))).To(BeTrue())
+	g.Expect(raw.Labels).To(HaveKeyWithValue("status", "deployed"))
+
+	got, err := driver.Get(testKey(rls2.Name, rls2.Version))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.Info.Status).To(Equal(helmrelease.StatusDeployed))
+
+	list, err := driver.Query(map[string]string{"name": "podinfo", "owner": "helm"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(list).To(HaveLen(2))
+
+	rlsUpdate := releaseStub("podinfo", 1, "default", helmrelease.StatusUninstalled)
+	g.Expect(driver.Update(testKey(rlsUpdate.Name, rlsUpdate.Version), rlsUpdate)).To(Succeed())
+	raw, err = configMaps.Get(context.TODO(), testKey(rlsUpdate.Name, rlsUpdate.Version), metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(IsEncrypted([]byte(raw.Data[releaseDataKey]))).To(BeTrue())
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// MetadataSecrets is a Secrets client which adds labels and annotations to
+// the Helm storage Secrets written with it, e.g. to allow backup tooling and
+// admission policies to classify them. The labels set by the Helm storage
+// Secrets driver to query releases take precedence over the added labels.
+type MetadataSecrets struct {
+	corev1client.SecretInterface
+
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// NewMetadataSecrets returns a new MetadataSecrets for the given Secrets
+// client, adding the given labels and annotations.
+func NewMetadataSecrets(secrets corev1client.SecretInterface, labels, annotations map[string]string) *MetadataSecrets {
+	return &MetadataSecrets{
+		SecretInterface: secrets,
+		labels:          labels,
+		annotations:     annotations,
+	}
+}
+
+// Create creates the given Secret, with the labels and annotations added.
+func (s *MetadataSecrets) Create(ctx context.Context, obj *corev1.Secret, opts metav1.CreateOptions) (*corev1.Secret, error) {
+	return s.SecretInterface.Create(ctx, s.apply(obj), opts)
+}
+
+// Update updates the given Secret, with the labels and annotations added.
+func (s *MetadataSecrets) Update(ctx context.Context, obj *corev1.Secret, opts metav1.UpdateOptions) (*corev1.Secret, error) {
+	return s.SecretInterface.Update(ctx, s.apply(obj), opts)
+}
+
+// apply returns a copy of the given Secret with the labels and annotations
+// added.
+func (s *MetadataSecrets) apply(obj *corev1.Secret) *corev1.Secret {
+	obj = obj.DeepCopy()
+	for k, v := range s.labels {
+		if _, ok := obj.Labels[k]; ok {
+			continue
+		}
+		if obj.Labels == nil {
+			obj.Labels = make(map[string]string, len(s.labels))
+		}
+		obj.Labels[k] = v
+	}
+	for k, v := range s.annotations {
+		if obj.Annotations == nil {
+			obj.Annotations = make(map[string]string, len(s.annotations))
+		}
+		obj.Annotations[k] = v
+	}
+	return obj
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetadataSecrets(t *testing.T) {
+	g := NewWithT(t)
+
+	clientSet := fake.NewSimpleClientset()
+	secrets := clientSet.CoreV1().Secrets("default")
+	driver := helmdriver.NewSecrets(NewMetadataSecrets(secrets,
+		map[string]string{"backup.example.com/include": "true", "owner": "team-a"},
+		map[string]string{"example.com/classification": "confidential"},
+	))
+
+	rls := &helmrelease.Release{
+		Name:      "podinfo",
+		Namespace: "default",
+		Version:   1,
+		Info:      &helmrelease.Info{Status: helmrelease.StatusPendingInstall},
+	}
+	key := "sh.helm.release.v1.podinfo.v1"
+	g.Expect(driver.Create(key, rls)).To(Succeed())
+
+	rls.Info.Status = helmrelease.StatusDeployed
+	g.Expect(driver.Update(key, rls)).To(Succeed())
+
+	obj, err := secrets.Get(context.TODO(), key, metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(obj.Labels).To(HaveKeyWithValue("backup.example.com/include", "true"))
+	// The labels of the driver take precedence.
+	g.Expect(obj.Labels).To(HaveKeyWithValue("owner", "helm"))
+	g.Expect(obj.Labels).To(HaveKeyWithValue("status", "deployed"))
+	g.Expect(obj.Annotations).To(HaveKeyWithValue("example.com/classification", "confidential"))
+
+	got, err := driver.Get(key)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got.Info.Status).To(Equal(helmrelease.StatusDeployed))
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// MaxSecretSize is the maximum size of the data of a Secret accepted by
+	// the Kubernetes API server.
+	MaxSecretSize = 1 << 20
+
+	// largestManifestsCount is the number of largest manifests named by a
+	// TooLargeError.
+	largestManifestsCount = 5
+)
+
+// gzipMagic is the header of gzip compressed data.
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// TooLargeError is returned when the release payload of a Helm storage
+// Secret exceeds MaxSecretSize.
+type TooLargeError struct {
+	// Name is the name of the Helm storage Secret.
+	Name string
+	// Size is the size of the release payload in bytes.
+	Size int
+	// Manifests are the largest manifests of the release, with their size.
+	Manifests []string
+}
+
+// Error returns an error string naming the largest manifests of the release.
+func (e *TooLargeError) Error() string {
+	msg := fmt.Sprintf("release payload of %d bytes for Helm storage Secret '%s' exceeds the limit of %d bytes",
+		e.Size, e.Name, MaxSecretSize)
+	if len(e.Manifests) > 0 {
+		msg += ", largest manifests: " + strings.Join(e.Manifests, ", ")
+	}
+	return msg
+}
+
+// SizeGuardedSecrets is a Secrets client which checks the size of the
+// release payload of the Helm storage Secrets written with it, to fail with a
+// TooLargeError naming the largest manifests of the release instead of the
+// validation error of the API server.
+//
+// The Helm storage Secrets driver compresses the payload with the highest
+// gzip compression level, which means the size can not be reduced further
+// while remaining readable by Helm.
+type SizeGuardedSecrets struct {
+	corev1client.SecretInterface
+}
+
+// NewSizeGuardedSecrets returns a new SizeGuardedSecrets for the given
+// Secrets client.
+func NewSizeGuardedSecrets(secrets corev1client.SecretInterface) *SizeGuardedSecrets {
+	return &SizeGuardedSecrets{SecretInterface: secrets}
+}
+
+// Create creates the given Secret, or returns a TooLargeError if its
+// release payload is too large.
+func (s *SizeGuardedSecrets) Create(ctx context.Context, obj *corev1.Secret, opts metav1.CreateOptions) (*corev1.Secret, error) {
+	if err := checkSize(obj); err != nil {
+		return nil, err
+	}
+	res, err := s.SecretInterface.Create(ctx, obj, opts)
+	return res, tooLargeError(obj, err)
+}
+
+// Update updates the given Secret, or returns a TooLargeError if its
+// release payload is too large.
+func (s *SizeGuardedSecrets) Update(ctx context.Context, obj *corev1.Secret, opts metav1.UpdateOptions) (*corev1.Secret, error) {
+	if err := checkSize(obj); err != nil {
+		return nil, err
+	}
+	res, err := s.SecretInterface.Update(ctx, obj, opts)
+	return res, tooLargeError(obj, err)
+}
+
+// checkSize returns a TooLargeError if the release payload of the given
+// Secret exceeds MaxSecretSize.
+func checkSize(obj *corev1.Secret) error {
+	if data := obj.Data[releaseDataKey]; len(data) > MaxSecretSize {
+		return newTooLargeError(obj)
+	}
+	return nil
+}
+
+// tooLargeError returns a TooLargeError for the given Secret if err is a
+// rejection of its size by the API server, e.g. because the payload grew
+// beyond MaxSecretSize after encryption. Otherwise, it returns err.
+func tooLargeError(obj *corev1.Secret, err error) error {
+	if err == nil {
+		return nil
+	}
+	if apierrors.IsRequestEntityTooLargeError(err) ||
+		(apierrors.IsInvalid(err) && strings.Contains(err.Error(), "Too long")) {
+		return newTooLargeError(obj)
+	}
+	return err
+}
+
+// newTooLargeError returns a TooLargeError for the given Secret, naming the
+// largest manifests of its release.
+func newTooLargeError(obj *corev1.Secret) *TooLargeError {
+	data := obj.Data[releaseDataKey]
+	e := &TooLargeError{Name: obj.Name, Size: len(data)}
+	if rls, err := DecodeRelease(data); err == nil {
+		e.Manifests = largestManifests(rls, largestManifestsCount)
+	}
+	return e
+}
+
+// largestManifests returns the n largest manifests of the given release and
+// its hooks, by the template they originate from, with their size.
+func largestManifests(rls *helmrelease.Release, n int) []string {
+	sizes := make(map[string]int)
+	for _, doc := range strings.Split(rls.Manifest, "\n---\n") {
+		sizes[manifestSource(doc)] += len(doc)
+	}
+	for _, h := range rls.Hooks {
+		if h != nil {
+			sizes[h.Path] += len(h.Manifest)
+		}
+	}
+	delete(sizes, "")
+
+	sources := make([]string, 0, len(sizes))
+	for source := range sizes {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sizes[sources[i]] != sizes[sources[j]] {
+			return sizes[sources[i]] > sizes[sources[j]]
+		}
+		return sources[i] < sources[j]
+	})
+	if len(sources) > n {
+		sources = sources[:n]
+	}
+
+	manifests := make([]string, 0, len(sources))
+	for _, source := range sources {
+		manifests = append(manifests, fmt.Sprintf("%s (%d bytes)", source, sizes[source]))
+	}
+	return manifests
+}
+
+// manifestSource returns the template the given manifest originates from,
+// as recorded by Helm in its "# Source:" comment.
+func manifestSource(doc string) string {
+	for _, line := range strings.Split(doc, "\n") {
+		if source, ok := strings.CutPrefix(line, "# Source: "); ok {
+			return strings.TrimSpace(source)
+		}
+	}
+	return ""
+}
+
+// DecodeRelease decodes the release payload of a Helm storage Secret, as
+// encoded by the Helm storage Secrets driver.
+func DecodeRelease(data []byte) (*helmrelease.Release, error) {
+	b, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(b, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		if b, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var rls helmrelease.Release
+	if err = json.Unmarshal(b, &rls); err != nil {
+		return nil, err
+	}
+	return &rls, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"math/rand"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSizeGuardedSecrets(t *testing.T) {
+	g := NewWithT(t)
+
+	clientSet := fake.NewSimpleClientset()
+	driver := helmdriver.NewSecrets(NewSizeGuardedSecrets(clientSet.CoreV1().Secrets("default")))
+
+	rls := &helmrelease.Release{
+		Name:      "podinfo",
+		Namespace: "default",
+		Version:   1,
+		Info:      &helmrelease.Info{Status: helmrelease.StatusDeployed},
+		Manifest:  "---\n# Source: podinfo/templates/service.yaml\nkind: Service\n",
+	}
+	g.Expect(driver.Create("sh.helm.release.v1.podinfo.v1", rls)).To(Succeed())
+
+	// Random data does not compress, which makes the payload exceed the
+	// limit after encoding.
+	large := make([]byte, MaxSecretSize)
+	rand.New(rand.NewSource(1)).Read(large)
+	rls = &helmrelease.Release{
+		Name:      "podinfo",
+		Namespace: "default",
+		Version:   2,
+		Info:      &helmrelease.Info{Status: helmrelease.StatusDeployed},
+		Manifest: "---\n# Source: podinfo/templates/service.yaml\nkind: Service\n" +
+			"---\n# Source: podinfo/templates/configmap.yaml\nkind: ConfigMap\ndata:\n  blob: " +
+			base64.StdEncoding.EncodeToString(large) + "\n",
+		Hooks: []*helmrelease.Hook{{Path: "podinfo/templates/tests/test.yaml", Manifest: "kind: Pod\n"}},
+	}
+	err := driver.Create("sh.helm.release.v1.podinfo.v2", rls)
+	var tooLargeErr *TooLargeError
+	g.Expect(errors.As(err, &tooLargeErr)).To(BeTrue())
+	g.Expect(tooLargeErr.Name).To(Equal("sh.helm.release.v1.podinfo.v2"))
+	g.Expect(tooLargeErr.Size).To(BeNumerically(">", MaxSecretSize))
+	g.Expect(tooLargeErr.Manifests).To(HaveLen(3))
+	g.Expect(tooLargeErr.Manifests[0]).To(HavePrefix("podinfo/templates/configmap.yaml ("))
+	g.Expect(err.Error()).To(ContainSubstring("largest manifests: podinfo/templates/configmap.yaml"))
+
+	_, err = clientSet.CoreV1().Secrets("default").Get(context.TODO(), "sh.helm.release.v1.podinfo.v2", metav1.GetOptions{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+func Test_tooLargeError(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.podinfo.v1"}}
+
+	g.Expect(tooLargeError(obj, nil)).To(BeNil())
+
+	err := errors.New("connection refused")
+	g.Expect(tooLargeError(obj, err)).To(Equal(err))
+
+	err = apierrors.NewRequestEntityTooLargeError("limit is 3145728")
+	g.Expect(tooLargeError(obj, err)).To(BeAssignableToTypeOf(&TooLargeError{}))
+}
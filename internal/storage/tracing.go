@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/fluxcd/helm-controller/internal/tracing"
+)
+
+// TracingDriverName contains the string representation of Tracing.
+const TracingDriverName = "tracing"
+
+// Tracing is a Helm storage driver which records a span for every write
+// operation to the underlying driver.
+//
+// As the Helm storage driver interface does not accept a context, the spans
+// are recorded as children of the span in the context provided to
+// NewTracing.
+type Tracing struct {
+	// driver holds the underlying driver.Driver implementation which is used
+	// to persist data to, and retrieve from.
+	driver helmdriver.Driver
+	// ctx holds the context.Context used as parent for the recorded spans.
+	ctx context.Context
+}
+
+// NewTracing creates a new Tracing driver for the given Helm storage driver,
+// recording spans as children of the span in the given context.
+func NewTracing(ctx context.Context, driver helmdriver.Driver) *Tracing {
+	return &Tracing{
+		driver: driver,
+		ctx:    ctx,
+	}
+}
+
+// Name returns the name of the driver.
+func (t *Tracing) Name() string {
+	return TracingDriverName
+}
+
+// Get returns the release named by key or returns ErrReleaseNotFound.
+func (t *Tracing) Get(key string) (*helmrelease.Release, error) {
+	return t.driver.Get(key)
+}
+
+// List returns the list of all releases such that filter(release) == true.
+func (t *Tracing) List(filter func(*helmrelease.Release) bool) ([]*helmrelease.Release, error) {
+	return t.driver.List(filter)
+}
+
+// Query returns the set of releases that match the provided set of labels.
+func (t *Tracing) Query(keyvals map[string]string) ([]*helmrelease.Release, error) {
+	return t.driver.Query(keyvals)
+}
+
+// Create creates a new release or returns driver.ErrReleaseExists.
+// It records a span for the creation.
+func (t *Tracing) Create(key string, rls *helmrelease.Release) error {
+	_, span := t.start("helm storage create", rls)
+	err := t.driver.Create(key, rls)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// Update updates a release or returns driver.ErrReleaseNotFound.
+// It records a span for the update.
+func (t *Tracing) Update(key string, rls *helmrelease.Release) error {
+	_, span := t.start("helm storage update", rls)
+	err := t.driver.Update(key, rls)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// Delete deletes a release or returns driver.ErrReleaseNotFound.
+// It records a span for the deletion.
+func (t *Tracing) Delete(key string) (*helmrelease.Release, error) {
+	_, span := t.start("helm storage delete", nil)
+	rls, err := t.driver.Delete(key)
+	if rls != nil {
+		span.SetAttributes(releaseAttributes(rls)...)
+	}
+	tracing.EndSpan(span, err)
+	return rls, err
+}
+
+// start starts a new span with the given name, with the attributes of the
+// given release if not nil.
+func (t *Tracing) start(name string, rls *helmrelease.Release) (context.Context, trace.Span) {
+	var attrs []attribute.KeyValue
+	if rls != nil {
+		attrs = releaseAttributes(rls)
+	}
+	return tracing.Tracer().Start(t.ctx, name, trace.WithAttributes(attrs...))
+}
+
+// releaseAttributes returns the span attributes for the given release.
+func releaseAttributes(rls *helmrelease.Release) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		tracing.ReleaseNameKey.String(rls.Name),
+		tracing.ReleaseNamespaceKey.String(rls.Namespace),
+		tracing.ReleaseVersionKey.Int(rls.Version),
+	}
+	if rls.Info != nil {
+		attrs = append(attrs, tracing.ReleaseStatusKey.String(rls.Info.Status.String()))
+	}
+	return attrs
+}
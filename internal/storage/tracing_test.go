@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/fluxcd/helm-controller/internal/tracing"
+)
+
+func TestTracing_Name(t *testing.T) {
+	g := NewWithT(t)
+
+	d := NewTracing(context.TODO(), helmdriver.NewMemory())
+	g.Expect(d.Name()).To(Equal(TracingDriverName))
+}
+
+func TestTracing(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.TODO())
+	})
+
+	ctx, parent := provider.Tracer("test").Start(context.TODO(), "parent")
+	d := NewTracing(ctx, helmdriver.NewMemory())
+
+	rel := releaseStub("success", 1, "ns1", helmrelease.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	g.Expect(d.Create(key, rel)).To(Succeed())
+	g.Expect(d.Create(key, rel)).To(MatchError(helmdriver.ErrReleaseExists))
+	g.Expect(d.Update(key, rel)).To(Succeed())
+	_, err := d.Get(key)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = d.Delete(key)
+	g.Expect(err).ToNot(HaveOccurred())
+	parent.End()
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(5))
+
+	var names []string
+	for _, s := range spans[:4] {
+		names = append(names, s.Name())
+		g.Expect(s.Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+		g.Expect(s.Attributes()).To(ContainElement(tracing.ReleaseNameKey.String(rel.Name)))
+	}
+	g.Expect(names).To(Equal([]string{
+		"helm storage create",
+		"helm storage create",
+		"helm storage update",
+		"helm storage delete",
+	}))
+	g.Expect(spans[0].Status().Code).To(Equal(codes.Unset))
+	g.Expect(spans[1].Status().Code).To(Equal(codes.Error))
+}
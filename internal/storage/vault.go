@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultTimeout is the timeout of a request to Vault.
+const vaultTimeout = 30 * time.Second
+
+// VaultTransitKey is a KeyEncryptionKey wrapping data encryption keys with a
+// key of the transit secrets engine of HashiCorp Vault (or OpenBao), so the
+// key encryption key never leaves the KMS.
+type VaultTransitKey struct {
+	id        string
+	address   string
+	mount     string
+	key       string
+	tokenFile string
+	client    *http.Client
+}
+
+// NewVaultTransitKey returns a new VaultTransitKey with the given ID for the
+// transit key with the given name of the Vault server at the given address.
+// The transit secrets engine is expected at the given mount path, or at
+// "transit" if empty. The Vault token is read from the given file for every
+// request, so it can be renewed by e.g. a Vault Agent sidecar.
+func NewVaultTransitKey(id, address, mount, key, tokenFile string) (*VaultTransitKey, error) {
+	if id == "" {
+		return nil, errors.New("key ID must not be empty")
+	}
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Vault address of key '%s': must be an HTTP(S) URL", id)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("no Vault transit key name for key '%s'", id)
+	}
+	if tokenFile == "" {
+		return nil, fmt.Errorf("no Vault token file for key '%s'", id)
+	}
+	if mount == "" {
+		mount = "transit"
+	}
+	return &VaultTransitKey{
+		id:        id,
+		address:   strings.TrimSuffix(address, "/"),
+		mount:     strings.Trim(mount, "/"),
+		key:       key,
+		tokenFile: tokenFile,
+		client:    &http.Client{Timeout: vaultTimeout},
+	}, nil
+}
+
+// ID returns the ID of the key.
+func (k *VaultTransitKey) ID() string {
+	return k.id
+}
+
+// Wrap encrypts the given data encryption key with the transit key.
+func (k *VaultTransitKey) Wrap(dek []byte) ([]byte, error) {
+	var resp struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := k.do("encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dek)}, &resp); err != nil {
+		return nil, err
+	}
+	return []byte(resp.Ciphertext), nil
+}
+
+// Unwrap decrypts the given wrapped data encryption key with the transit
+// key.
+func (k *VaultTransitKey) Unwrap(wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := k.do("decrypt", map[string]string{"ciphertext": string(wrapped)}, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+// do sends the given request to the given operation endpoint of the transit
+// key, and decodes the data of the response into v.
+func (k *VaultTransitKey) do(operation string, req interface{}, v interface{}) error {
+	token, err := os.ReadFile(k.tokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read Vault token: %w", err)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", k.address, k.mount, operation, url.PathEscape(k.key))
+	r, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Vault-Token", strings.TrimSpace(string(token)))
+
+	resp, err := k.client.Do(r)
+	if err != nil {
+		return fmt.Errorf("vault transit %s request failed: %w", operation, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("vault transit %s request failed: %w", operation, err)
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err = json.Unmarshal(b, &result); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("vault transit %s request failed: invalid response: %w", operation, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault transit %s request failed: unexpected status code %d: %s",
+			operation, resp.StatusCode, strings.Join(result.Errors, "; "))
+	}
+	if err = json.Unmarshal(result.Data, v); err != nil {
+		return fmt.Errorf("vault transit %s request failed: invalid response: %w", operation, err)
+	}
+	return nil
+}
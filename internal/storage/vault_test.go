@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestVaultTransitKey(t *testing.T) {
+	// The server "encrypts" by reversing the base64-encoded plaintext.
+	reverse := func(s string) string {
+		b := []byte(s)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return string(b)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/helm":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"ciphertext": "vault:v1:" + reverse(req["plaintext"])},
+			})
+		case "/v1/transit/decrypt/helm":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"plaintext": reverse(strings.TrimPrefix(req["ciphertext"], "vault:v1:"))},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("wraps and unwraps", func(t *testing.T) {
+		g := NewWithT(t)
+
+		key, err := NewVaultTransitKey("key-1", server.URL, "", "helm", tokenFile)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		dek := bytes.Repeat([]byte{3}, 32)
+		wrapped, err := key.Wrap(dek)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(wrapped)).To(HavePrefix("vault:v1:"))
+
+		unwrapped, err := key.Unwrap(wrapped)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(unwrapped).To(Equal(dek))
+	})
+
+	t.Run("with other mount", func(t *testing.T) {
+		g := NewWithT(t)
+
+		key, err := NewVaultTransitKey("key-1", server.URL, "kms", "helm", tokenFile)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, err = key.Wrap([]byte("dek"))
+		g.Expect(err).To(MatchError("vault transit encrypt request failed: unexpected status code 404: "))
+	})
+
+	t.Run("with invalid token", func(t *testing.T) {
+		g := NewWithT(t)
+
+		invalid := filepath.Join(t.TempDir(), "token")
+		g.Expect(os.WriteFile(invalid, []byte("invalid"), 0o600)).To(Succeed())
+		key, err := NewVaultTransitKey("key-1", server.URL, "", "helm", invalid)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, err = key.Unwrap([]byte("vault:v1:abc"))
+		g.Expect(err).To(MatchError("vault transit decrypt request failed: unexpected status code 403: permission denied"))
+	})
+
+	t.Run("without token file", func(t *testing.T) {
+		g := NewWithT(t)
+
+		key, err := NewVaultTransitKey("key-1", server.URL, "", "helm", filepath.Join(t.TempDir(), "missing"))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, err = key.Wrap([]byte("dek"))
+		g.Expect(err).To(MatchError(ContainSubstring("failed to read Vault token")))
+	})
+}
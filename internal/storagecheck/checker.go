@@ -0,0 +1,195 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package storagecheck periodically verifies the controller can write, read
+// and delete releases in its configured Helm storage backend, to surface
+// permission or connectivity regressions of the backend before the next
+// Helm action fails on them.
+package storagecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	flag "github.com/spf13/pflag"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+
+	"github.com/fluxcd/helm-controller/internal/metrics"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+const (
+	flagInterval = "storage-check-interval"
+
+	// ProbeReleaseName is the name of the release written to and deleted
+	// from the Helm storage by the Checker, suffixed with the name of the
+	// instance of the controller.
+	ProbeReleaseName = "helm-controller-storage-probe"
+)
+
+// Options contains the configuration options for the Checker.
+type Options struct {
+	// Interval is the interval at which the Checker runs. The Checker is
+	// disabled when it is zero, which is the default.
+	Interval time.Duration
+}
+
+// BindFlags will parse the given pflag.FlagSet for storage check option
+// flags and set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.Interval, flagInterval, 0,
+		"The interval at which the controller verifies it can write, read and delete releases in the Helm storage backend. Disabled when 0.")
+}
+
+// Checker periodically writes, reads and deletes a probe release in a Helm
+// storage backend, and records the result in the helm_storage_healthy
+// metric. The result of the last check is reported by Check, which can be
+// used as a readiness check of the controller.
+type Checker struct {
+	driver      helmdriver.Driver
+	driverName  string
+	namespace   string
+	releaseName string
+	interval    time.Duration
+	logger      logr.Logger
+
+	mu      sync.RWMutex
+	lastErr error
+}
+
+// New returns a new Checker which checks the given Helm storage driver, in
+// the given namespace, with the given Options. The probe release is named
+// after the given instance of the controller, e.g. the name of its Pod, so
+// that the instances of a controller running with multiple replicas do not
+// write and delete the same release.
+func New(driver helmdriver.Driver, namespace, instance string, opts Options, logger logr.Logger) (*Checker, error) {
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("invalid interval '%s': must be greater than 0", opts.Interval)
+	}
+	releaseName := ProbeReleaseName
+	if instance != "" {
+		releaseName = release.ShortenName(releaseName + "-" + instance)
+	}
+	return &Checker{
+		driver:      driver,
+		driverName:  driver.Name(),
+		namespace:   namespace,
+		releaseName: releaseName,
+		interval:    opts.Interval,
+		logger:      logger,
+	}, nil
+}
+
+// Start runs Probe right away and then at the configured interval, until the
+// context is canceled. It implements manager.Runnable.
+func (c *Checker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.run()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, to run the
+// Checker on every instance of the controller, as each instance reports its
+// own readiness.
+func (c *Checker) NeedLeaderElection() bool {
+	return false
+}
+
+// Check returns the error of the last run of Probe, or nil if it succeeded
+// or has not run yet. It implements healthz.Checker.
+func (c *Checker) Check(_ *http.Request) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastErr
+}
+
+// run runs Probe, records the result, and logs changes of the result.
+func (c *Checker) run() {
+	err := c.Probe()
+	metrics.RecordStorageHealth(c.driverName, err)
+
+	c.mu.Lock()
+	prevErr := c.lastErr
+	c.lastErr = err
+	c.mu.Unlock()
+
+	switch {
+	case err != nil:
+		c.logger.Error(err, "Helm storage check failed", "driver", c.driverName)
+	case prevErr != nil:
+		c.logger.Info("Helm storage check recovered", "driver", c.driverName)
+	}
+}
+
+// Probe writes a probe release to the Helm storage, reads it back, and
+// deletes it again. It returns an error describing the first operation
+// which failed.
+func (c *Checker) Probe() error {
+	key := c.probeKey()
+
+	// Remove any probe release left behind by an interrupted probe.
+	if _, err := c.driver.Delete(key); err != nil && !errors.Is(err, helmdriver.ErrReleaseNotFound) {
+		return fmt.Errorf("failed to delete from Helm storage: %w", err)
+	}
+
+	now := helmtime.Now()
+	rls := &helmrelease.Release{
+		Name:      c.releaseName,
+		Namespace: c.namespace,
+		Version:   1,
+		Info: &helmrelease.Info{
+			FirstDeployed: now,
+			LastDeployed:  now,
+			Status:        helmrelease.StatusUnknown,
+			Description:   "Helm storage probe of the helm-controller",
+		},
+	}
+	if err := c.driver.Create(key, rls); err != nil {
+		return fmt.Errorf("failed to write to Helm storage: %w", err)
+	}
+	got, err := c.driver.Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from Helm storage: %w", err)
+	}
+	if got.Name != c.releaseName || got.Version != rls.Version {
+		return fmt.Errorf("read unexpected release %s (version %d) from Helm storage", got.Name, got.Version)
+	}
+	if _, err = c.driver.Delete(key); err != nil {
+		return fmt.Errorf("failed to delete from Helm storage: %w", err)
+	}
+	return nil
+}
+
+// probeKey returns the storage key of the probe release, in the format used
+// by the Helm storage.
+func (c *Checker) probeKey() string {
+	return fmt.Sprintf("sh.helm.release.v1.%s.v1", c.releaseName)
+}
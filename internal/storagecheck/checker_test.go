@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagecheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/fluxcd/helm-controller/internal/metrics"
+)
+
+// readOnlyDriver is a helmdriver.Driver which fails to create releases.
+type readOnlyDriver struct {
+	*helmdriver.Memory
+}
+
+func (d readOnlyDriver) Create(string, *helmrelease.Release) error {
+	return errors.New("secrets is forbidden")
+}
+
+func TestNew(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := New(helmdriver.NewMemory(), "flux-system", "helm-controller-0", Options{}, logr.Discard())
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestChecker_probeKey(t *testing.T) {
+	g := NewWithT(t)
+
+	driver := helmdriver.NewMemory()
+	a, err := New(driver, "flux-system", "helm-controller-5d8f7b9c4-abcde", Options{Interval: time.Minute}, logr.Discard())
+	g.Expect(err).ToNot(HaveOccurred())
+	b, err := New(driver, "flux-system", "helm-controller-5d8f7b9c4-fghij", Options{Interval: time.Minute}, logr.Discard())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The instances of the controller probe distinct releases, with names
+	// within the maximum length of a Helm release name.
+	g.Expect(a.probeKey()).ToNot(Equal(b.probeKey()))
+	g.Expect(len(a.releaseName)).To(BeNumerically("<=", 53))
+	g.Expect(a.releaseName).To(HavePrefix(ProbeReleaseName + "-"))
+}
+
+func TestChecker_run(t *testing.T) {
+	t.Cleanup(metrics.StorageHealthy.Reset)
+
+	t.Run("healthy storage", func(t *testing.T) {
+		g := NewWithT(t)
+
+		driver := helmdriver.NewMemory()
+		driver.SetNamespace("flux-system")
+		c, err := New(driver, "flux-system", "helm-controller-0", Options{Interval: time.Minute}, logr.Discard())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		c.run()
+		g.Expect(c.Check(nil)).To(Succeed())
+		g.Expect(testutil.ToFloat64(metrics.StorageHealthy.WithLabelValues(driver.Name()))).To(Equal(float64(1)))
+
+		// The probe release is removed again.
+		_, err = driver.Get(c.probeKey())
+		g.Expect(err).To(MatchError(helmdriver.ErrReleaseNotFound))
+	})
+
+	t.Run("read-only storage", func(t *testing.T) {
+		g := NewWithT(t)
+
+		driver := readOnlyDriver{Memory: helmdriver.NewMemory()}
+		c, err := New(driver, "flux-system", "helm-controller-0", Options{Interval: time.Minute}, logr.Discard())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		c.run()
+		g.Expect(c.Check(nil)).To(MatchError(ContainSubstring("failed to write to Helm storage: secrets is forbidden")))
+		g.Expect(testutil.ToFloat64(metrics.StorageHealthy.WithLabelValues(driver.Name()))).To(Equal(float64(0)))
+	})
+}
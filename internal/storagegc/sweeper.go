@@ -0,0 +1,279 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package storagegc provides a garbage collector for Helm storage Secrets
+// which were left behind by the controller.
+package storagegc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	flag "github.com/spf13/pflag"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/storage"
+)
+
+const (
+	flagInterval = "storage-gc-interval"
+	flagMode     = "storage-gc-mode"
+)
+
+// Mode is the action taken by the Sweeper on a leaked Helm storage Secret.
+type Mode string
+
+const (
+	// ModeLabel labels leaked Helm storage Secrets with LeakedLabel.
+	ModeLabel Mode = "label"
+	// ModeDelete deletes leaked Helm storage Secrets.
+	ModeDelete Mode = "delete"
+)
+
+var (
+	// LeakedLabel is the label added to leaked Helm storage Secrets in
+	// ModeLabel.
+	LeakedLabel = v2.GroupVersion.Group + "/leaked"
+
+	// originNameLabel and originNamespaceLabel are the labels added by the
+	// controller to the objects of a Helm release, referring to the
+	// HelmRelease the release belongs to.
+	originNameLabel      = v2.GroupVersion.Group + "/name"
+	originNamespaceLabel = v2.GroupVersion.Group + "/namespace"
+)
+
+const (
+	// releaseSecretType is the type of the Secrets used by the Helm storage
+	// Secrets driver.
+	releaseSecretType = "helm.sh/release.v1"
+	// releaseSecretOwnerLabel is the label set by the Helm storage Secrets
+	// driver on the Secrets it manages.
+	releaseSecretOwnerLabel = "owner"
+)
+
+// Options contains the configuration options for the Sweeper.
+type Options struct {
+	// Interval is the interval at which the Sweeper runs. Helm storage
+	// Secrets younger than the interval are never considered leaked.
+	Interval time.Duration
+	// Mode is the action taken on a leaked Helm storage Secret.
+	Mode string
+}
+
+// BindFlags will parse the given pflag.FlagSet for storage garbage collection
+// option flags and set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.Interval, flagInterval, time.Hour,
+		"The interval at which leaked Helm storage Secrets are garbage collected. Requires feature gate 'GarbageCollectStorage' to be enabled.")
+	fs.StringVar(&o.Mode, flagMode, string(ModeLabel),
+		"The action taken on leaked Helm storage Secrets, one of 'label' or 'delete'. Requires feature gate 'GarbageCollectStorage' to be enabled.")
+}
+
+// Sweeper periodically detects Helm storage Secrets which were created by the
+// controller, but which do not belong to the current release of any
+// HelmRelease. For example, because the controller crashed while a
+// HelmRelease was deleted, or the release name or storage namespace of a
+// HelmRelease changed with an older version of the controller.
+//
+// Leaked Secrets are labeled with LeakedLabel, or deleted, depending on the
+// configured Mode.
+type Sweeper struct {
+	reader    client.Reader
+	writer    client.Writer
+	namespace string
+	interval  time.Duration
+	mode      Mode
+	logger    logr.Logger
+}
+
+// New returns a new Sweeper which sweeps the given namespace, or all
+// namespaces when empty, with the given Options.
+//
+// The reader is used to list both the HelmRelease objects and the Secrets,
+// and is expected to not be limited by for example a watch label selector.
+func New(reader client.Reader, writer client.Writer, namespace string, opts Options, logger logr.Logger) (*Sweeper, error) {
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("invalid interval '%s': must be greater than 0", opts.Interval)
+	}
+	mode := Mode(opts.Mode)
+	if mode != ModeLabel && mode != ModeDelete {
+		return nil, fmt.Errorf("invalid mode '%s': must be one of '%s' or '%s'", opts.Mode, ModeLabel, ModeDelete)
+	}
+	return &Sweeper{
+		reader:    reader,
+		writer:    writer,
+		namespace: namespace,
+		interval:  opts.Interval,
+		mode:      mode,
+		logger:    logger,
+	}, nil
+}
+
+// Start runs Sweep at the configured interval until the context is canceled.
+// It implements manager.Runnable.
+func (s *Sweeper) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if n, err := s.Sweep(ctx); err != nil {
+				s.logger.Error(err, "failed to garbage collect leaked Helm storage Secrets")
+			} else if n > 0 {
+				s.logger.Info(fmt.Sprintf("garbage collected %d leaked Helm storage Secret(s)", n), "mode", s.mode)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, to only run
+// the Sweeper on the leader.
+func (s *Sweeper) NeedLeaderElection() bool {
+	return true
+}
+
+// Sweep runs a single garbage collection of leaked Helm storage Secrets,
+// returning the number of Secrets which were labeled or deleted.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	var objs v2.HelmReleaseList
+	if err := s.reader.List(ctx, &objs, client.InNamespace(s.namespace)); err != nil {
+		return 0, fmt.Errorf("failed to list HelmReleases: %w", err)
+	}
+	live := liveReleases(objs.Items)
+
+	var secrets corev1.SecretList
+	if err := s.reader.List(ctx, &secrets, client.InNamespace(s.namespace),
+		client.MatchingLabels{releaseSecretOwnerLabel: "helm"}); err != nil {
+		return 0, fmt.Errorf("failed to list Helm storage Secrets: %w", err)
+	}
+
+	var n int
+	threshold := time.Now().Add(-s.interval)
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Type != releaseSecretType || secret.CreationTimestamp.After(threshold) {
+			continue
+		}
+		if _, ok := secret.Labels[LeakedLabel]; ok && s.mode == ModeLabel {
+			continue
+		}
+
+		rls, err := decodeRelease(secret.Name, secret.Data["release"])
+		if err != nil {
+			s.logger.V(1).Info("skipping undecodable Helm storage Secret", "secret", client.ObjectKeyFromObject(secret), "error", err.Error())
+			continue
+		}
+		if !hasOrigin(rls) {
+			continue
+		}
+		if _, ok := live[storageKey{namespace: secret.Namespace, name: rls.Name}]; ok {
+			continue
+		}
+
+		if err = s.collect(ctx, secret); err != nil {
+			return n, err
+		}
+		s.logger.V(1).Info("garbage collected leaked Helm storage Secret", "secret", client.ObjectKeyFromObject(secret), "mode", s.mode)
+		n++
+	}
+	return n, nil
+}
+
+// collect labels or deletes the given leaked Secret, depending on the mode.
+func (s *Sweeper) collect(ctx context.Context, secret *corev1.Secret) error {
+	if s.mode == ModeDelete {
+		if err := s.writer.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete leaked Helm storage Secret '%s': %w", client.ObjectKeyFromObject(secret), err)
+		}
+		return nil
+	}
+
+	patch := client.MergeFrom(secret.DeepCopy())
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string, 1)
+	}
+	secret.Labels[LeakedLabel] = "true"
+	if err := s.writer.Patch(ctx, secret, patch); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to label leaked Helm storage Secret '%s': %w", client.ObjectKeyFromObject(secret), err)
+	}
+	return nil
+}
+
+// storageKey identifies the Helm storage records of a release.
+type storageKey struct {
+	namespace string
+	name      string
+}
+
+// liveReleases returns the storage keys of the releases of the given
+// HelmReleases, including the release targeted by the current spec and the
+// releases in the history. The release name of the spec is shortened like
+// it is when the release is stored.
+func liveReleases(objs []v2.HelmRelease) map[storageKey]struct{} {
+	live := make(map[storageKey]struct{}, len(objs))
+	for i := range objs {
+		obj := &objs[i]
+		live[storageKey{namespace: obj.GetStorageNamespace(), name: release.ShortenName(obj.GetReleaseName())}] = struct{}{}
+
+		storageNamespace := obj.Status.StorageNamespace
+		if storageNamespace == "" {
+			storageNamespace = obj.GetStorageNamespace()
+		}
+		for _, snap := range obj.Status.History {
+			live[storageKey{namespace: storageNamespace, name: snap.Name}] = struct{}{}
+		}
+	}
+	return live
+}
+
+// hasOrigin returns if the objects of the given release carry the labels the
+// controller adds to the objects of the releases it manages.
+func hasOrigin(rls *helmrelease.Release) bool {
+	objects, err := ssautil.ReadObjects(strings.NewReader(rls.Manifest))
+	if err != nil {
+		return false
+	}
+	for _, obj := range objects {
+		labels := obj.GetLabels()
+		if labels[originNameLabel] != "" && labels[originNamespaceLabel] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeRelease decodes the release data of the Helm storage Secret with the
+// given name, as encoded by the Helm storage Secrets driver and optionally
+// encrypted with storage.Encryption.
+func decodeRelease(name string, data []byte) (*helmrelease.Release, error) {
+	data, err := storage.Encryption.DecryptRelease(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return storage.DecodeRelease(data)
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagegc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/release"
+)
+
+func TestNew(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := New(nil, nil, "", Options{Interval: time.Hour, Mode: string(ModeDelete)}, logr.Discard())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = New(nil, nil, "", Options{Mode: string(ModeLabel)}, logr.Discard())
+	g.Expect(err).To(MatchError(ContainSubstring("invalid interval")))
+
+	_, err = New(nil, nil, "", Options{Interval: time.Hour, Mode: "purge"}, logr.Discard())
+	g.Expect(err).To(MatchError("invalid mode 'purge': must be one of 'label' or 'delete'"))
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	var (
+		old    = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+		recent = metav1.NewTime(time.Now())
+	)
+
+	longName := strings.Repeat("a", 60)
+
+	objs := []client.Object{
+		&v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "long", Namespace: "apps"},
+			Spec: v2.HelmReleaseSpec{
+				ReleaseName: longName,
+			},
+		},
+		&v2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps"},
+			Spec: v2.HelmReleaseSpec{
+				ReleaseName: "podinfo",
+			},
+			Status: v2.HelmReleaseStatus{
+				StorageNamespace: "apps",
+				History: v2.Snapshots{
+					{Name: "podinfo-old", Namespace: "apps", Version: 1},
+				},
+			},
+		},
+		// Belongs to the spec of the HelmRelease.
+		mockSecret(t, "apps", "podinfo", 2, old, true),
+		// Belongs to the history of the HelmRelease.
+		mockSecret(t, "apps", "podinfo-old", 1, old, true),
+		// Belongs to the spec of the HelmRelease with a long release name.
+		mockSecret(t, "apps", release.ShortenName(longName), 1, old, true),
+		// Belongs to a deleted HelmRelease.
+		mockSecret(t, "apps", "deleted", 1, old, true),
+		// Belongs to a deleted HelmRelease, but is too recent.
+		mockSecret(t, "apps", "recent", 1, recent, true),
+		// Not created by the controller.
+		mockSecret(t, "apps", "manual", 1, old, false),
+	}
+
+	newClient := func() client.Client {
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v2.AddToScheme(scheme)
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	}
+
+	t.Run("labels leaked Secrets", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newClient()
+		s, err := New(c, c, "", Options{Interval: time.Hour, Mode: string(ModeLabel)}, logr.Discard())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		n, err := s.Sweep(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(n).To(Equal(1))
+
+		for name, leaked := range map[string]bool{
+			"sh.helm.release.v1.podinfo.v2":                               false,
+			"sh.helm.release.v1.podinfo-old.v1":                           false,
+			"sh.helm.release.v1.deleted.v1":                               true,
+			"sh.helm.release.v1.recent.v1":                                false,
+			"sh.helm.release.v1.manual.v1":                                false,
+			"sh.helm.release.v1." + release.ShortenName(longName) + ".v1": false,
+		} {
+			secret := &corev1.Secret{}
+			g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "apps", Name: name}, secret)).To(Succeed())
+			if leaked {
+				g.Expect(secret.Labels).To(HaveKeyWithValue(LeakedLabel, "true"), name)
+			} else {
+				g.Expect(secret.Labels).ToNot(HaveKey(LeakedLabel), name)
+			}
+		}
+
+		// Labeled Secrets are not collected again.
+		n, err = s.Sweep(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(n).To(BeZero())
+	})
+
+	t.Run("deletes leaked Secrets", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newClient()
+		s, err := New(c, c, "", Options{Interval: time.Hour, Mode: string(ModeDelete)}, logr.Discard())
+		g.Expect(err).ToNot(HaveOccurred())
+
+		n, err := s.Sweep(context.TODO())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(n).To(Equal(1))
+
+		err = c.Get(context.TODO(), client.ObjectKey{Namespace: "apps", Name: "sh.helm.release.v1.deleted.v1"}, &corev1.Secret{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "apps", Name: "sh.helm.release.v1.podinfo.v2"}, &corev1.Secret{})).To(Succeed())
+		g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "apps", Name: "sh.helm.release.v1." + release.ShortenName(longName) + ".v1"}, &corev1.Secret{})).To(Succeed())
+	})
+}
+
+func Test_decodeRelease(t *testing.T) {
+	g := NewWithT(t)
+
+	rls := &helmrelease.Release{Name: "podinfo", Version: 3}
+
+	got, err := decodeRelease("sh.helm.release.v1.podinfo.v3", encodeRelease(t, rls))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(Equal(rls))
+
+	b, err := json.Marshal(rls)
+	g.Expect(err).ToNot(HaveOccurred())
+	got, err = decodeRelease("sh.helm.release.v1.podinfo.v3", []byte(base64.StdEncoding.EncodeToString(b)))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(Equal(rls))
+
+	_, err = decodeRelease("sh.helm.release.v1.podinfo.v3", []byte("invalid"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+// mockSecret returns a Helm storage Secret for the given release, with a
+// manifest containing the origin labels of the controller if origin is true.
+func mockSecret(t *testing.T, namespace, name string, version int, created metav1.Time, origin bool) *corev1.Secret {
+	t.Helper()
+
+	labels := ""
+	if origin {
+		labels = fmt.Sprintf(`
+  labels:
+    %s: %s
+    %s: %s`, originNameLabel, name, originNamespaceLabel, namespace)
+	}
+	rls := &helmrelease.Release{
+		Name:      name,
+		Namespace: namespace,
+		Version:   version,
+		Manifest: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s%s
+`, name, labels),
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, version),
+			Namespace:         namespace,
+			CreationTimestamp: created,
+			Labels: map[string]string{
+				releaseSecretOwnerLabel: "helm",
+				"name":                  name,
+				"version":               fmt.Sprint(version),
+			},
+		},
+		Type: releaseSecretType,
+		Data: map[string][]byte{"release": encodeRelease(t, rls)},
+	}
+}
+
+// encodeRelease encodes the given release as the Helm storage Secrets driver
+// does.
+func encodeRelease(t *testing.T, rls *helmrelease.Release) []byte {
+	t.Helper()
+
+	b, err := json.Marshal(rls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err = w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
@@ -57,6 +57,13 @@ func ReleaseWithLabels(labels map[string]string) ReleaseOption {
 	}
 }
 
+// ReleaseWithDescription sets the description of the release info.
+func ReleaseWithDescription(description string) ReleaseOption {
+	return func(options *ReleaseOptions) {
+		options.Release.Info.Description = description
+	}
+}
+
 // ReleaseWithFailingHook appends a failing hook to the release.
 func ReleaseWithFailingHook() ReleaseOption {
 	return func(options *ReleaseOptions) {
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing provides the OpenTelemetry instrumentation of the
+// controller, exporting spans using OTLP.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the instrumentation library used for
+// all spans emitted by the controller.
+const instrumentationName = "github.com/fluxcd/helm-controller"
+
+const (
+	flagEndpoint    = "tracing-endpoint"
+	flagInsecure    = "tracing-insecure"
+	flagSampleRatio = "tracing-sample-ratio"
+)
+
+// Attribute keys used on the spans emitted by the controller.
+const (
+	// ObjectNameKey is the name of the HelmRelease object.
+	ObjectNameKey = attribute.Key("helmrelease.name")
+	// ObjectNamespaceKey is the namespace of the HelmRelease object.
+	ObjectNamespaceKey = attribute.Key("helmrelease.namespace")
+	// ReleaseNameKey is the name of the Helm release.
+	ReleaseNameKey = attribute.Key("helm.release.name")
+	// ReleaseNamespaceKey is the namespace of the Helm release.
+	ReleaseNamespaceKey = attribute.Key("helm.release.namespace")
+	// ReleaseVersionKey is the version of the Helm release.
+	ReleaseVersionKey = attribute.Key("helm.release.version")
+	// ReleaseStatusKey is the status of the Helm release.
+	ReleaseStatusKey = attribute.Key("helm.release.status")
+	// ChartNameKey is the name of the Helm chart.
+	ChartNameKey = attribute.Key("helm.chart.name")
+	// ChartVersionKey is the version of the Helm chart.
+	ChartVersionKey = attribute.Key("helm.chart.version")
+	// ArtifactRevisionKey is the revision of the source artifact.
+	ArtifactRevisionKey = attribute.Key("artifact.revision")
+	// ReleaseStateKey is the state of the Helm release as determined by the
+	// controller.
+	ReleaseStateKey = attribute.Key("helm.release.state")
+	// ReconcilerTypeKey is the type of the action reconciler.
+	ReconcilerTypeKey = attribute.Key("reconciler.type")
+	// ResourcesKey is the list of Kubernetes resources an operation acts on.
+	ResourcesKey = attribute.Key("k8s.resources")
+)
+
+// Options contains the configuration options for the export of spans.
+type Options struct {
+	// Endpoint is the address (host:port) of the OTLP gRPC receiver to
+	// export spans to. Tracing is disabled when empty.
+	Endpoint string
+	// Insecure disables client transport security for the connection to
+	// the Endpoint.
+	Insecure bool
+	// SampleRatio is the ratio of traces to sample, between 0 and 1.
+	SampleRatio float64
+}
+
+// BindFlags will parse the given pflag.FlagSet for tracing option flags and
+// set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Endpoint, flagEndpoint, "",
+		"The address (host:port) of the OTLP gRPC receiver to export traces to. Tracing is disabled when not set.")
+	fs.BoolVar(&o.Insecure, flagInsecure, false,
+		"Disable transport security for the connection to the OTLP gRPC receiver.")
+	fs.Float64Var(&o.SampleRatio, flagSampleRatio, 1,
+		"The ratio of traces to sample, between 0 and 1.")
+}
+
+// Setup configures the global OpenTelemetry TracerProvider to export spans
+// to the configured Endpoint, identifying the spans with the given service
+// name. It returns a function which flushes any remaining spans and stops
+// the export, which should be called before the program exits.
+//
+// When no Endpoint is configured, the global TracerProvider is left
+// untouched and all spans are discarded.
+func Setup(ctx context.Context, opts Options, serviceName string) (func(context.Context) error, error) {
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", opts.SampleRatio)
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the trace.Tracer to use for the instrumentation of the
+// controller.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// EndSpan records the given error on the span if not nil, and ends the span.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
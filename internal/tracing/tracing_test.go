@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("disabled without endpoint", func(t *testing.T) {
+		g := NewWithT(t)
+
+		shutdown, err := Setup(context.TODO(), Options{}, "test")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(shutdown(context.TODO())).To(Succeed())
+	})
+
+	t.Run("invalid sample ratio", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := Setup(context.TODO(), Options{Endpoint: "localhost:4317", SampleRatio: 2}, "test")
+		g.Expect(err).To(MatchError(ContainSubstring("sample ratio must be between 0 and 1")))
+	})
+}
+
+func TestEndSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	t.Run("without error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, span := tracer.Start(context.TODO(), "success")
+		EndSpan(span, nil)
+
+		g.Expect(span.IsRecording()).To(BeFalse())
+		g.Expect(recorder.Ended()[len(recorder.Ended())-1].Status().Code).To(Equal(codes.Unset))
+	})
+
+	t.Run("with error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, span := tracer.Start(context.TODO(), "failure")
+		EndSpan(span, errors.New("some error"))
+
+		got := recorder.Ended()[len(recorder.Ended())-1]
+		g.Expect(got.Status().Code).To(Equal(codes.Error))
+		g.Expect(got.Status().Description).To(Equal("some error"))
+		g.Expect(got.Events()).To(HaveLen(1))
+	})
+}
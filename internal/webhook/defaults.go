@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+const (
+	// DefaultIntervalKey is the key in the defaults ConfigMap for the
+	// default of .spec.interval.
+	DefaultIntervalKey = "interval"
+	// DefaultTimeoutKey is the key in the defaults ConfigMap for the
+	// default of .spec.timeout.
+	DefaultTimeoutKey = "timeout"
+	// DefaultMaxHistoryKey is the key in the defaults ConfigMap for the
+	// default of .spec.maxHistory.
+	DefaultMaxHistoryKey = "maxHistory"
+	// DefaultInstallRetriesKey is the key in the defaults ConfigMap for the
+	// default of .spec.install.remediation.retries.
+	DefaultInstallRetriesKey = "install.remediation.retries"
+	// DefaultUpgradeRetriesKey is the key in the defaults ConfigMap for the
+	// default of .spec.upgrade.remediation.retries.
+	DefaultUpgradeRetriesKey = "upgrade.remediation.retries"
+)
+
+// Defaults holds the defaults applied to the fields of a v2.HelmRelease
+// which have been omitted. A nil field does not have a default.
+type Defaults struct {
+	Interval       *metav1.Duration
+	Timeout        *metav1.Duration
+	MaxHistory     *int
+	InstallRetries *int
+	UpgradeRetries *int
+}
+
+// LoadDefaults loads the Defaults from the ConfigMap with the given key.
+func LoadDefaults(ctx context.Context, reader client.Reader, key types.NamespacedName) (*Defaults, error) {
+	cm := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, key, cm); err != nil {
+		return nil, err
+	}
+	return DefaultsFromConfigMap(cm)
+}
+
+// DefaultsFromConfigMap parses the Defaults from the data of the given
+// ConfigMap.
+func DefaultsFromConfigMap(cm *corev1.ConfigMap) (*Defaults, error) {
+	d := &Defaults{}
+	for k, v := range cm.Data {
+		var err error
+		switch k {
+		case DefaultIntervalKey:
+			d.Interval, err = parseDuration(v)
+		case DefaultTimeoutKey:
+			d.Timeout, err = parseDuration(v)
+		case DefaultMaxHistoryKey:
+			d.MaxHistory, err = parseInt(v)
+		case DefaultInstallRetriesKey:
+			d.InstallRetries, err = parseInt(v)
+		case DefaultUpgradeRetriesKey:
+			d.UpgradeRetries, err = parseInt(v)
+		default:
+			err = errors.New("unknown key")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for '%s' in ConfigMap '%s/%s': %w", k, cm.Namespace, cm.Name, err)
+		}
+	}
+	return d, nil
+}
+
+// Apply sets the Defaults on the fields of the given object which have been
+// omitted. The remediation retries are only set when the remediation
+// configuration of the respective action has been omitted.
+func (d *Defaults) Apply(obj *v2.HelmRelease) {
+	if d.Interval != nil && obj.Spec.Interval.Duration == 0 {
+		obj.Spec.Interval = *d.Interval
+	}
+	if d.Timeout != nil && obj.Spec.Timeout == nil {
+		timeout := *d.Timeout
+		obj.Spec.Timeout = &timeout
+	}
+	if d.MaxHistory != nil && obj.Spec.MaxHistory == nil {
+		maxHistory := *d.MaxHistory
+		obj.Spec.MaxHistory = &maxHistory
+	}
+	if d.InstallRetries != nil {
+		if obj.Spec.Install == nil {
+			obj.Spec.Install = &v2.Install{}
+		}
+		if obj.Spec.Install.Remediation == nil {
+			obj.Spec.Install.Remediation = &v2.InstallRemediation{Retries: *d.InstallRetries}
+		}
+	}
+	if d.UpgradeRetries != nil {
+		if obj.Spec.Upgrade == nil {
+			obj.Spec.Upgrade = &v2.Upgrade{}
+		}
+		if obj.Spec.Upgrade.Remediation == nil {
+			obj.Spec.Upgrade.Remediation = &v2.UpgradeRemediation{Retries: *d.UpgradeRetries}
+		}
+	}
+}
+
+// Defaulter is an admission.CustomDefaulter which applies the
+// v2.HelmReleaseDefaults in the namespace of v2.HelmRelease objects, and the
+// Defaults to the fields which are still omitted.
+type Defaulter struct {
+	Defaults *Defaults
+	// Reader is used to list the v2.HelmReleaseDefaults. When nil, only the
+	// Defaults are applied.
+	Reader client.Reader
+}
+
+// Default applies the v2.HelmReleaseDefaults in the namespace of the given
+// object and the Defaults to the object.
+func (d *Defaulter) Default(ctx context.Context, obj runtime.Object) error {
+	hr, ok := obj.(*v2.HelmRelease)
+	if !ok {
+		return fmt.Errorf("expected a HelmRelease object but got %T", obj)
+	}
+	if d.Reader != nil {
+		namespace := hr.Namespace
+		if req, err := admission.RequestFromContext(ctx); err == nil && req.Namespace != "" {
+			namespace = req.Namespace
+		}
+		defaults, err := ListNamespaceDefaults(ctx, d.Reader, namespace)
+		if err != nil {
+			return err
+		}
+		ApplyNamespaceDefaults(hr, defaults...)
+	}
+	d.Defaults.Apply(hr)
+	return nil
+}
+
+var _ admission.CustomDefaulter = &Defaulter{}
+
+func parseDuration(s string) (*metav1.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+	if d <= 0 {
+		return nil, errors.New("must be greater than 0")
+	}
+	return &metav1.Duration{Duration: d}, nil
+}
+
+func parseInt(s string) (*int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestDefaultsFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *Defaults
+		wantErr string
+	}{
+		{
+			name: "all defaults",
+			data: map[string]string{
+				DefaultIntervalKey:       "10m",
+				DefaultTimeoutKey:        "5m",
+				DefaultMaxHistoryKey:     "3",
+				DefaultInstallRetriesKey: "2",
+				DefaultUpgradeRetriesKey: "1",
+			},
+			want: &Defaults{
+				Interval:       &metav1.Duration{Duration: 10 * time.Minute},
+				Timeout:        &metav1.Duration{Duration: 5 * time.Minute},
+				MaxHistory:     ptr.To(3),
+				InstallRetries: ptr.To(2),
+				UpgradeRetries: ptr.To(1),
+			},
+		},
+		{
+			name: "no defaults",
+			want: &Defaults{},
+		},
+		{
+			name:    "invalid duration",
+			data:    map[string]string{DefaultIntervalKey: "0s"},
+			wantErr: "invalid value for 'interval' in ConfigMap 'flux-system/defaults': must be greater than 0",
+		},
+		{
+			name:    "invalid integer",
+			data:    map[string]string{DefaultMaxHistoryKey: "ten"},
+			wantErr: "invalid value for 'maxHistory'",
+		},
+		{
+			name:    "unknown key",
+			data:    map[string]string{"retries": "1"},
+			wantErr: "invalid value for 'retries' in ConfigMap 'flux-system/defaults': unknown key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := DefaultsFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "flux-system"},
+				Data:       tt.data,
+			})
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestDefaults_Apply(t *testing.T) {
+	defaults := &Defaults{
+		Interval:       &metav1.Duration{Duration: 10 * time.Minute},
+		Timeout:        &metav1.Duration{Duration: 5 * time.Minute},
+		MaxHistory:     ptr.To(3),
+		InstallRetries: ptr.To(2),
+		UpgradeRetries: ptr.To(1),
+	}
+
+	t.Run("sets omitted fields", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{}
+		defaults.Apply(obj)
+
+		g.Expect(obj.Spec.Interval).To(Equal(metav1.Duration{Duration: 10 * time.Minute}))
+		g.Expect(obj.Spec.Timeout).To(Equal(&metav1.Duration{Duration: 5 * time.Minute}))
+		g.Expect(obj.Spec.MaxHistory).To(Equal(ptr.To(3)))
+		g.Expect(obj.Spec.Install.Remediation).To(Equal(&v2.InstallRemediation{Retries: 2}))
+		g.Expect(obj.Spec.Upgrade.Remediation).To(Equal(&v2.UpgradeRemediation{Retries: 1}))
+	})
+
+	t.Run("preserves configured fields", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			Spec: v2.HelmReleaseSpec{
+				Interval:   metav1.Duration{Duration: time.Minute},
+				Timeout:    &metav1.Duration{Duration: time.Minute},
+				MaxHistory: ptr.To(0),
+				Install: &v2.Install{
+					Remediation: &v2.InstallRemediation{},
+				},
+				Upgrade: &v2.Upgrade{
+					Force:       true,
+					Remediation: &v2.UpgradeRemediation{Retries: 5},
+				},
+			},
+		}
+		want := obj.DeepCopy()
+		defaults.Apply(obj)
+
+		g.Expect(obj).To(Equal(want))
+	})
+
+	t.Run("without defaults", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{}
+		(&Defaults{}).Apply(obj)
+
+		g.Expect(obj).To(Equal(&v2.HelmRelease{}))
+	})
+}
+
+func TestDefaulter_Default(t *testing.T) {
+	g := NewWithT(t)
+
+	d := &Defaulter{Defaults: &Defaults{MaxHistory: ptr.To(3)}}
+
+	obj := &v2.HelmRelease{}
+	g.Expect(d.Default(context.TODO(), obj)).To(Succeed())
+	g.Expect(obj.Spec.MaxHistory).To(Equal(ptr.To(3)))
+
+	g.Expect(d.Default(context.TODO(), &corev1.ConfigMap{})).To(MatchError(ContainSubstring("expected a HelmRelease object")))
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// DeprecationsPath is the path of the validating webhook which warns about
+// the use of deprecated fields. The webhook handles all served versions of
+// the HelmRelease API.
+const DeprecationsPath = "/validate-helm-toolkit-fluxcd-io-helmrelease-deprecations"
+
+// DeprecationWarner is an admission.Handler which allows all requests, but
+// returns a warning for each v2.DeprecatedFields entry set on the object.
+//
+// It operates on the raw object of the request instead of a decoded
+// v2.HelmRelease, as fields of older API versions may have been removed
+// from the v2 API.
+type DeprecationWarner struct{}
+
+// Handle returns the deprecation warnings for the object of the request.
+func (w *DeprecationWarner) Handle(_ context.Context, req admission.Request) admission.Response {
+	if req.Operation == admissionv1.Delete || len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	return admission.Allowed("").WithWarnings(DeprecationWarnings(obj)...)
+}
+
+// DeprecationWarnings returns a sorted list of warnings for the
+// v2.DeprecatedFields set on the given object.
+func DeprecationWarnings(obj map[string]interface{}) []string {
+	var warnings []string
+	for path, replacement := range v2.DeprecatedFields {
+		if isSet(obj, strings.Split(path, ".")) {
+			warnings = append(warnings, fmt.Sprintf("%s is deprecated, use %s instead", path, replacement))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// isSet returns if the field at the given path segments is set on the
+// object. A segment suffixed with "[]" matches if the field is set on any
+// item of the list.
+func isSet(obj interface{}, path []string) bool {
+	if len(path) == 0 {
+		return obj != nil
+	}
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	segment, isList := strings.CutSuffix(path[0], "[]")
+	v, ok := m[segment]
+	if !ok {
+		return false
+	}
+	if !isList {
+		return isSet(v, path[1:])
+	}
+
+	items, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if isSet(item, path[1:]) {
+			return true
+		}
+	}
+	return false
+}
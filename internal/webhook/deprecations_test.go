@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  string
+		want []string
+	}{
+		{
+			name: "no deprecated fields",
+			obj:  `{"spec":{"install":{"crds":"Skip"},"postRenderers":[{"kustomize":{"patches":[]}}]}}`,
+		},
+		{
+			name: "deprecated fields",
+			obj: `{"spec":{
+				"install":{"skipCRDs":true},
+				"chart":{"spec":{"valuesFile":"values.yaml"}},
+				"postRenderers":[{"kustomize":{"patches":[]}},{"kustomize":{"patchesStrategicMerge":[{}]}}]
+			}}`,
+			want: []string{
+				"spec.chart.spec.valuesFile is deprecated, use spec.chart.spec.valuesFiles instead",
+				"spec.install.skipCRDs is deprecated, use spec.install.crds with value Skip instead",
+				"spec.postRenderers[].kustomize.patchesStrategicMerge is deprecated, use spec.postRenderers[].kustomize.patches instead",
+			},
+		},
+		{
+			name: "unexpected types",
+			obj:  `{"spec":{"install":"invalid","postRenderers":{"kustomize":{}}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			resp := (&DeprecationWarner{}).Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Create,
+					Object:    runtime.RawExtension{Raw: []byte(tt.obj)},
+				},
+			})
+			g.Expect(resp.Allowed).To(BeTrue())
+			g.Expect(resp.Warnings).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestDeprecationWarner_Handle(t *testing.T) {
+	g := NewWithT(t)
+
+	w := &DeprecationWarner{}
+
+	resp := w.Handle(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Delete},
+	})
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	resp = w.Handle(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Object:    runtime.RawExtension{Raw: []byte("invalid")},
+		},
+	})
+	g.Expect(resp.Allowed).To(BeFalse())
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// ListNamespaceDefaults returns the v2.HelmReleaseDefaults in the given
+// namespace, sorted by name.
+func ListNamespaceDefaults(ctx context.Context, reader client.Reader, namespace string) ([]v2.HelmReleaseDefaults, error) {
+	list := &v2.HelmReleaseDefaultsList{}
+	if err := reader.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list HelmReleaseDefaults in namespace '%s': %w", namespace, err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return list.Items, nil
+}
+
+// ApplyNamespaceDefaults sets the given v2.HelmReleaseDefaults on the fields
+// of the given object which have been omitted. When multiple defaults set
+// the same field, the first one takes precedence.
+func ApplyNamespaceDefaults(obj *v2.HelmRelease, defaults ...v2.HelmReleaseDefaults) {
+	for _, d := range defaults {
+		spec := d.Spec
+		if spec.Interval != nil && obj.Spec.Interval.Duration == 0 {
+			obj.Spec.Interval = *spec.Interval
+		}
+		if spec.ServiceAccountName != "" && obj.Spec.ServiceAccountName == "" {
+			obj.Spec.ServiceAccountName = spec.ServiceAccountName
+		}
+		if spec.DriftDetection != nil {
+			if obj.Spec.DriftDetection == nil {
+				obj.Spec.DriftDetection = spec.DriftDetection.DeepCopy()
+			} else if obj.Spec.DriftDetection.Mode == "" {
+				obj.Spec.DriftDetection.Mode = spec.DriftDetection.Mode
+			}
+		}
+		if spec.CommonMetadata != nil {
+			if obj.Spec.CommonMetadata == nil {
+				obj.Spec.CommonMetadata = &v2.CommonMetadata{}
+			}
+			obj.Spec.CommonMetadata.Labels = mergeOmitted(obj.Spec.CommonMetadata.Labels, spec.CommonMetadata.Labels)
+			obj.Spec.CommonMetadata.Annotations = mergeOmitted(obj.Spec.CommonMetadata.Annotations, spec.CommonMetadata.Annotations)
+		}
+	}
+}
+
+// mergeOmitted returns dst with the entries of src of which the key is not
+// in dst.
+func mergeOmitted(dst, src map[string]string) map[string]string {
+	for k, v := range src {
+		if dst == nil {
+			dst = make(map[string]string, len(src))
+		}
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+	return dst
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+func TestApplyNamespaceDefaults(t *testing.T) {
+	defaults := v2.HelmReleaseDefaults{
+		Spec: v2.HelmReleaseDefaultsSpec{
+			Interval:           &metav1.Duration{Duration: 10 * time.Minute},
+			ServiceAccountName: "tenant",
+			DriftDetection:     &v2.DriftDetection{Mode: v2.DriftDetectionEnabled},
+			CommonMetadata: &v2.CommonMetadata{
+				Labels:      map[string]string{"team": "a", "env": "prod"},
+				Annotations: map[string]string{"owner": "team-a"},
+			},
+		},
+	}
+
+	t.Run("sets omitted fields", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{}
+		ApplyNamespaceDefaults(obj, defaults)
+
+		g.Expect(obj.Spec.Interval).To(Equal(metav1.Duration{Duration: 10 * time.Minute}))
+		g.Expect(obj.Spec.ServiceAccountName).To(Equal("tenant"))
+		g.Expect(obj.Spec.DriftDetection).To(Equal(&v2.DriftDetection{Mode: v2.DriftDetectionEnabled}))
+		g.Expect(obj.Spec.CommonMetadata).To(Equal(defaults.Spec.CommonMetadata))
+		g.Expect(obj.Spec.CommonMetadata).ToNot(BeIdenticalTo(defaults.Spec.CommonMetadata))
+	})
+
+	t.Run("preserves configured fields", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := &v2.HelmRelease{
+			Spec: v2.HelmReleaseSpec{
+				Interval:           metav1.Duration{Duration: time.Minute},
+				ServiceAccountName: "other",
+				DriftDetection: &v2.DriftDetection{
+					Ignore: []v2.IgnoreRule{{Paths: []string{"/spec/replicas"}}},
+				},
+				CommonMetadata: &v2.CommonMetadata{
+					Labels: map[string]string{"env": "dev"},
+				},
+			},
+		}
+		ApplyNamespaceDefaults(obj, defaults)
+
+		g.Expect(obj.Spec.Interval).To(Equal(metav1.Duration{Duration: time.Minute}))
+		g.Expect(obj.Spec.ServiceAccountName).To(Equal("other"))
+		g.Expect(obj.Spec.DriftDetection).To(Equal(&v2.DriftDetection{
+			Mode:   v2.DriftDetectionEnabled,
+			Ignore: []v2.IgnoreRule{{Paths: []string{"/spec/replicas"}}},
+		}))
+		g.Expect(obj.Spec.CommonMetadata).To(Equal(&v2.CommonMetadata{
+			Labels:      map[string]string{"team": "a", "env": "dev"},
+			Annotations: map[string]string{"owner": "team-a"},
+		}))
+	})
+
+	t.Run("first defaults take precedence", func(t *testing.T) {
+		g := NewWithT(t)
+
+		other := v2.HelmReleaseDefaults{
+			Spec: v2.HelmReleaseDefaultsSpec{
+				Interval:           &metav1.Duration{Duration: time.Hour},
+				ServiceAccountName: "other",
+			},
+		}
+
+		obj := &v2.HelmRelease{}
+		ApplyNamespaceDefaults(obj, other, defaults)
+
+		g.Expect(obj.Spec.Interval).To(Equal(metav1.Duration{Duration: time.Hour}))
+		g.Expect(obj.Spec.ServiceAccountName).To(Equal("other"))
+		g.Expect(obj.Spec.DriftDetection).ToNot(BeNil())
+	})
+}
+
+func TestDefaulter_Default_namespaceDefaults(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(v2.AddToScheme(scheme)).To(Succeed())
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v2.HelmReleaseDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "tenant"},
+			Spec:       v2.HelmReleaseDefaultsSpec{ServiceAccountName: "b"},
+		},
+		&v2.HelmReleaseDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "tenant"},
+			Spec:       v2.HelmReleaseDefaultsSpec{ServiceAccountName: "a"},
+		},
+		&v2.HelmReleaseDefaults{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other"},
+			Spec:       v2.HelmReleaseDefaultsSpec{Interval: &metav1.Duration{Duration: time.Hour}},
+		},
+	).Build()
+
+	d := &Defaulter{
+		Defaults: &Defaults{
+			Interval:   &metav1.Duration{Duration: 10 * time.Minute},
+			MaxHistory: ptr.To(3),
+		},
+		Reader: reader,
+	}
+
+	obj := &v2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "tenant"}}
+	g.Expect(d.Default(context.TODO(), obj)).To(Succeed())
+	g.Expect(obj.Spec.ServiceAccountName).To(Equal("a"))
+	g.Expect(obj.Spec.Interval).To(Equal(metav1.Duration{Duration: 10 * time.Minute}))
+	g.Expect(obj.Spec.MaxHistory).To(Equal(ptr.To(3)))
+}
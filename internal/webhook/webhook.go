@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook provides the admission webhooks for the HelmRelease API.
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+const (
+	flagPort              = "webhook-port"
+	flagCertDir           = "webhook-cert-dir"
+	flagDefaultsConfigMap = "defaults-config-map"
+)
+
+// Options contains the configuration options for the admission webhooks.
+type Options struct {
+	// Port is the port the webhook server listens on. The webhooks are
+	// disabled when set to 0.
+	Port int
+	// CertDir is the directory containing the TLS certificate and key of the
+	// webhook server.
+	CertDir string
+	// DefaultsConfigMap is the name of the ConfigMap in the namespace of the
+	// controller containing the defaults applied to HelmRelease objects.
+	DefaultsConfigMap string
+}
+
+// BindFlags will parse the given pflag.FlagSet for webhook option flags and
+// set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.Port, flagPort, 0,
+		"The port the admission webhook server listens on. The webhooks are disabled when set to 0.")
+	fs.StringVar(&o.CertDir, flagCertDir, "",
+		"The directory containing the TLS certificate and key of the admission webhook server.")
+	fs.StringVar(&o.DefaultsConfigMap, flagDefaultsConfigMap, "",
+		"The name of the ConfigMap in the namespace of the controller containing the defaults applied to HelmRelease objects.")
+}
+
+// Enabled returns if the admission webhooks are enabled.
+func (o Options) Enabled() bool {
+	return o.Port != 0
+}
+
+// Server returns the webhook server configured according to the Options.
+func (o Options) Server() ctrlwebhook.Server {
+	return ctrlwebhook.NewServer(ctrlwebhook.Options{
+		Port:    o.Port,
+		CertDir: o.CertDir,
+	})
+}
+
+// +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleasedefaults,verbs=get;list;watch
+
+// Setup registers the admission webhooks for the HelmRelease API with the
+// manager: the defaulting webhook, and the DeprecationWarner at
+// DeprecationsPath. The defaults are loaded from the configured
+// DefaultsConfigMap in the given namespace, changes to the ConfigMap require
+// a restart of the controller to take effect. The HelmReleaseDefaults in the
+// namespace of a HelmRelease take precedence over these defaults.
+func Setup(ctx context.Context, mgr ctrl.Manager, opts Options, namespace string) error {
+	defaults := &Defaults{}
+	if opts.DefaultsConfigMap != "" {
+		var err error
+		key := types.NamespacedName{Namespace: namespace, Name: opts.DefaultsConfigMap}
+		if defaults, err = LoadDefaults(ctx, mgr.GetAPIReader(), key); err != nil {
+			return fmt.Errorf("failed to load defaults: %w", err)
+		}
+	}
+
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&v2.HelmRelease{}).
+		WithDefaulter(&Defaulter{Defaults: defaults, Reader: mgr.GetClient()}).
+		Complete(); err != nil {
+		return err
+	}
+
+	mgr.GetWebhookServer().Register(DeprecationsPath, &ctrlwebhook.Admission{Handler: &DeprecationWarner{}})
+	return nil
+}
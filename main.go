@@ -17,22 +17,33 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
 	flag "github.com/spf13/pflag"
 	"helm.sh/helm/v3/pkg/kube"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
+	kuberecorder "k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlcfg "sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	crtlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/fluxcd/pkg/runtime/acl"
@@ -55,10 +66,33 @@ import (
 	// +kubebuilder:scaffold:imports
 
 	intacl "github.com/fluxcd/helm-controller/internal/acl"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/audit"
+	"github.com/fluxcd/helm-controller/internal/chartscan"
+	"github.com/fluxcd/helm-controller/internal/cli"
+	"github.com/fluxcd/helm-controller/internal/concurrency"
+	intconfig "github.com/fluxcd/helm-controller/internal/config"
 	"github.com/fluxcd/helm-controller/internal/controller"
+	intdebug "github.com/fluxcd/helm-controller/internal/debug"
+	intevents "github.com/fluxcd/helm-controller/internal/events"
 	"github.com/fluxcd/helm-controller/internal/features"
+	"github.com/fluxcd/helm-controller/internal/freeze"
+	"github.com/fluxcd/helm-controller/internal/health"
+	"github.com/fluxcd/helm-controller/internal/httpauth"
 	intkube "github.com/fluxcd/helm-controller/internal/kube"
+	"github.com/fluxcd/helm-controller/internal/manifest"
+	intmetrics "github.com/fluxcd/helm-controller/internal/metrics"
 	"github.com/fluxcd/helm-controller/internal/oomwatch"
+	"github.com/fluxcd/helm-controller/internal/outbound"
+	"github.com/fluxcd/helm-controller/internal/preview"
+	intreconcile "github.com/fluxcd/helm-controller/internal/reconcile"
+	"github.com/fluxcd/helm-controller/internal/rollout"
+	"github.com/fluxcd/helm-controller/internal/signature"
+	intstorage "github.com/fluxcd/helm-controller/internal/storage"
+	"github.com/fluxcd/helm-controller/internal/storagecheck"
+	"github.com/fluxcd/helm-controller/internal/storagegc"
+	"github.com/fluxcd/helm-controller/internal/tracing"
+	intwebhook "github.com/fluxcd/helm-controller/internal/webhook"
 )
 
 const controllerName = "helm-controller"
@@ -78,13 +112,31 @@ func init() {
 }
 
 func main() {
+	// Run a subcommand instead of the controller, if one is given.
+	if len(os.Args) > 1 {
+		if cmd, ok := cli.Commands[os.Args[1]]; ok {
+			if err := cmd(context.Background(), os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s: %s\n", controllerName, os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var (
 		metricsAddr               string
 		eventsAddr                string
+		eventsDedupInterval       time.Duration
 		healthAddr                string
 		concurrent                int
+		maxConcurrent             int
+		concurrentDriftDetection  int
 		requeueDependency         time.Duration
 		gracefulShutdownTimeout   time.Duration
+		drainTimeout              time.Duration
+		interruptedReleasePolicy  string
+		freezeActions             bool
+		rolloutGroupConcurrency   int
 		httpRetry                 int
 		clientOptions             client.Options
 		kubeConfigOpts            client.KubeConfigOptions
@@ -95,29 +147,71 @@ func main() {
 		rateLimiterOptions        helper.RateLimiterOptions
 		watchOptions              helper.WatchOptions
 		intervalJitterOptions     jitter.IntervalOptions
+		tracingOptions            tracing.Options
+		auditOptions              audit.Options
+		outboundOptions           outbound.Options
+		webhookOptions            intwebhook.Options
+		endpointsOptions          httpauth.Options
+		storageGCOptions          storagegc.Options
+		storageCheckOptions       storagecheck.Options
+		healthOptions             health.Options
 		oomWatchInterval          time.Duration
 		oomWatchMemoryThreshold   uint8
 		oomWatchMaxMemoryPath     string
 		oomWatchCurrentMemoryPath string
 		snapshotDigestAlgo        string
+		fipsMode                  bool
+		storageDriver             string
+		sqlConnectionPath         string
+		storageLabels             map[string]string
+		storageAnnotations        map[string]string
+		chartSourcePolicyPath     string
+		enforceTenantNamespaces   bool
+		imageSignaturePolicyPath  string
+		chartVulnPolicyPath       string
+		storageEncryptionKeysPath string
+		serviceAccountTokens      bool
+		serviceAccountTokenExpiry time.Duration
+		serviceAccountAudiences   []string
+		configFilePath            string
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080",
 		"The address the metric endpoint binds to.")
 	flag.StringVar(&eventsAddr, "events-addr", "",
 		"The address of the events receiver.")
+	flag.DurationVar(&eventsDedupInterval, "events-dedup-interval", 30*time.Minute,
+		"The interval during which identical warning events for a HelmRelease are only recorded once. Set to 0 to disable.")
 	flag.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
 	flag.IntVar(&concurrent, "concurrent", 4,
 		"The number of concurrent HelmRelease reconciles.")
+	flag.IntVar(&maxConcurrent, "max-concurrent", 0,
+		"The maximum number of concurrent HelmRelease reconciles the controller configuration file can raise the number to at runtime. Defaults to --concurrent.")
+	flag.IntVar(&concurrentDriftDetection, "concurrent-drift-detection", 0,
+		"The number of concurrent drift detections. Set to 0 to only limit them by --concurrent.")
 	flag.DurationVar(&requeueDependency, "requeue-dependency", 30*time.Second,
 		"The interval at which failing dependencies are reevaluated.")
 	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 600*time.Second,
 		"The duration given to the reconciler to finish before forcibly stopping.")
+	flag.DurationVar(&drainTimeout, "drain-timeout", 5*time.Minute,
+		"The duration given to in-flight Helm actions to complete on shutdown, before they are canceled. Can not exceed the graceful-shutdown-timeout.")
+	flag.StringVar(&interruptedReleasePolicy, "interrupted-release-policy", string(intreconcile.InterruptedReleaseUnlock),
+		"The remediation of releases left in a pending state by an interrupted Helm action, e.g. due to a crash of the controller. One of 'unlock' (remediate according to the HelmRelease) or 'rollback' (roll back or uninstall right away).")
+	flag.BoolVar(&freezeActions, "freeze", false,
+		"Hold all mutating Helm actions of all HelmReleases during a cluster-wide change freeze, while continuing to observe their releases.")
+	flag.IntVar(&rolloutGroupConcurrency, "rollout-group-concurrency", 1,
+		"The number of HelmReleases in a rollout group which are allowed to upgrade at the same time.")
 	flag.IntVar(&httpRetry, "http-retry", 9,
 		"The maximum number of retries when failing to fetch artifacts over HTTP.")
 	flag.StringVar(&intkube.DefaultServiceAccountName, "default-service-account", "",
 		"Default service account used for impersonation.")
+	flag.BoolVar(&serviceAccountTokens, "service-account-tokens", false,
+		"Authenticate as the service account of a HelmRelease with short-lived tokens issued with the TokenRequest API, instead of impersonating it.")
+	flag.DurationVar(&serviceAccountTokenExpiry, "service-account-token-expiration", intkube.DefaultTokenExpiration,
+		"The requested lifetime of the service account tokens. Requires --service-account-tokens.")
+	flag.StringSliceVar(&serviceAccountAudiences, "service-account-token-audiences", nil,
+		"The audiences of the service account tokens. Defaults to the audiences of the API server. Requires --service-account-tokens.")
 	flag.Uint8Var(&oomWatchMemoryThreshold, "oom-watch-memory-threshold", 95,
 		"The memory threshold in percentage at which the OOM watcher will trigger a graceful shutdown. Requires feature gate 'OOMWatch' to be enabled.")
 	flag.DurationVar(&oomWatchInterval, "oom-watch-interval", 500*time.Millisecond,
@@ -126,8 +220,30 @@ func main() {
 		"The path to the cgroup memory limit file. Requires feature gate 'OOMWatch' to be enabled. If not set, the path will be automatically detected.")
 	flag.StringVar(&oomWatchCurrentMemoryPath, "oom-watch-current-memory-path", "",
 		"The path to the cgroup current memory usage file. Requires feature gate 'OOMWatch' to be enabled. If not set, the path will be automatically detected.")
+	flag.StringVar(&chartSourcePolicyPath, "chart-source-policy", "",
+		"The path to a YAML file restricting the chart sources the HelmReleases in a namespace may reference.")
+	flag.BoolVar(&enforceTenantNamespaces, "enforce-tenant-namespaces", false,
+		"Require the target and storage namespace of HelmReleases to equal their own namespace, unless the namespace is labeled with "+intacl.PrivilegedLabel+"=true.")
+	flag.StringVar(&imageSignaturePolicyPath, "image-signature-policy", "",
+		"The path to a YAML file configuring the public keys the container images of the rendered manifests must be signed with.")
+	flag.StringVar(&chartVulnPolicyPath, "chart-vulnerability-policy", "",
+		"The path to a YAML file configuring the requirements on the vulnerability attestations of OCI charts.")
+	flag.StringVar(&storageEncryptionKeysPath, "storage-encryption-keys", "",
+		"The path to a YAML file with the keys to encrypt the release payload of Helm storage Secrets and ConfigMaps with. The first key is used for encryption.")
+	flag.StringVar(&storageDriver, "storage-driver", "secret",
+		"The Helm storage driver to store the releases with. Supported values are 'secret', 'configmap' and 'sql'.")
+	flag.StringVar(&sqlConnectionPath, "storage-sql-connection-file", "",
+		"The path to a file with the PostgreSQL connection string of the 'sql' Helm storage driver, e.g. mounted from a Secret.")
+	flag.StringToStringVar(&storageLabels, "storage-labels", nil,
+		"The labels to add to the Helm storage Secrets of all releases, e.g. 'backup.example.com/include=true'.")
+	flag.StringToStringVar(&storageAnnotations, "storage-annotations", nil,
+		"The annotations to add to the Helm storage Secrets of all releases.")
 	flag.StringVar(&snapshotDigestAlgo, "snapshot-digest-algo", intdigest.Canonical.String(),
-		"The algorithm to use to calculate the digest of Helm release storage snapshots.")
+		"The algorithm to use to calculate the digests of Helm release storage snapshots and values (e.g. sha256, sha512). Digests in the status of HelmReleases are migrated when the algorithm changes.")
+	flag.BoolVar(&fipsMode, "fips-mode", intdigest.FIPS,
+		"Restrict the digest algorithms which can be configured and verified to FIPS-approved algorithms (sha256, sha384, sha512).")
+	flag.StringVar(&configFilePath, "config-file", "",
+		"The path to a YAML file with controller configuration which is reloaded when it changes or on SIGHUP, e.g. mounted from a ConfigMap. Values set in the file take precedence over their flags.")
 
 	clientOptions.BindFlags(flag.CommandLine)
 	logOptions.BindFlags(flag.CommandLine)
@@ -138,6 +254,14 @@ func main() {
 	featureGates.BindFlags(flag.CommandLine)
 	watchOptions.BindFlags(flag.CommandLine)
 	intervalJitterOptions.BindFlags(flag.CommandLine)
+	tracingOptions.BindFlags(flag.CommandLine)
+	auditOptions.BindFlags(flag.CommandLine)
+	outboundOptions.BindFlags(flag.CommandLine)
+	webhookOptions.BindFlags(flag.CommandLine)
+	endpointsOptions.BindFlags(flag.CommandLine)
+	storageGCOptions.BindFlags(flag.CommandLine)
+	storageCheckOptions.BindFlags(flag.CommandLine)
+	healthOptions.BindFlags(flag.CommandLine)
 
 	flag.Parse()
 
@@ -172,8 +296,16 @@ func main() {
 		setupLog.Error(err, "unable to check feature gate CacheSecretsAndConfigMaps")
 		os.Exit(1)
 	}
+	cacheByObject := map[ctrlclient.Object]ctrlcache.ByObject{
+		&v2.HelmRelease{}: {Label: watchSelector},
+	}
 	if !shouldCache {
 		disableCacheFor = append(disableCacheFor, &corev1.Secret{}, &corev1.ConfigMap{})
+		// Secrets are only cached to watch the credentials of sources for
+		// rotations, limit this to the Secrets labeled to be watched.
+		cacheByObject[&corev1.Secret{}] = ctrlcache.ByObject{
+			Label: labels.SelectorFromSet(labels.Set{v2.PullSecretWatchLabel: "true"}),
+		}
 	}
 
 	leaderElectionId := fmt.Sprintf("%s-%s", controllerName, "leader-election")
@@ -186,8 +318,90 @@ func main() {
 
 	// Configure the ACL policy.
 	intacl.AllowCrossNamespaceRef = !aclOptions.NoCrossNamespaceRefs
+	intacl.EnforceTenantNamespaces = enforceTenantNamespaces
+	if chartSourcePolicyPath != "" {
+		policy, err := intacl.LoadSourcePolicy(chartSourcePolicyPath)
+		if err != nil {
+			setupLog.Error(err, "unable to configure chart source policy")
+			os.Exit(1)
+		}
+		intacl.ChartSourcePolicy = policy
+	}
+
+	// Configure the image signature policy.
+	if imageSignaturePolicyPath != "" {
+		policy, err := signature.LoadPolicy(imageSignaturePolicyPath)
+		if err != nil {
+			setupLog.Error(err, "unable to configure image signature policy")
+			os.Exit(1)
+		}
+		resolver, err := signature.NewResolver()
+		if err != nil {
+			setupLog.Error(err, "unable to configure image signature verification")
+			os.Exit(1)
+		}
+		signature.ImageVerifier = signature.NewVerifier(policy, resolver)
+	}
+
+	// Configure the chart vulnerability policy.
+	if chartVulnPolicyPath != "" {
+		policy, err := chartscan.LoadPolicy(chartVulnPolicyPath)
+		if err != nil {
+			setupLog.Error(err, "unable to configure chart vulnerability policy")
+			os.Exit(1)
+		}
+		resolver, err := signature.NewResolver()
+		if err != nil {
+			setupLog.Error(err, "unable to configure chart vulnerability attestation lookup")
+			os.Exit(1)
+		}
+		chartscan.ChartScanner = chartscan.NewScanner(policy, resolver)
+	}
+
+	// Configure the client for the requests to the addresses configured by
+	// tenants.
+	outboundClient, err := outbound.NewClient(outboundOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to configure outbound requests")
+		os.Exit(1)
+	}
+
+	// Configure the encryption of the Helm storage.
+	if storageEncryptionKeysPath != "" {
+		keys, err := intstorage.LoadKeys(storageEncryptionKeysPath)
+		if err != nil {
+			setupLog.Error(err, "unable to configure Helm storage encryption")
+			os.Exit(1)
+		}
+		if intstorage.Encryption, err = intstorage.NewEnvelope(keys...); err != nil {
+			setupLog.Error(err, "unable to configure Helm storage encryption")
+			os.Exit(1)
+		}
+	}
+
+	// Configure the Helm storage driver.
+	helmStorageDriver, err := action.ParseStorageDriver(storageDriver)
+	if err != nil {
+		setupLog.Error(err, "unable to configure Helm storage driver")
+		os.Exit(1)
+	}
+	if helmStorageDriver == helmdriver.SQLDriverName {
+		if intstorage.Encryption != nil {
+			setupLog.Error(errors.New("--storage-encryption-keys is not supported by the 'sql' storage driver"), "unable to configure Helm storage driver")
+			os.Exit(1)
+		}
+		if sqlConnectionPath == "" {
+			setupLog.Error(errors.New("--storage-sql-connection-file is required"), "unable to configure Helm storage driver")
+			os.Exit(1)
+		}
+		if action.SQLConnectionString, err = action.LoadSQLConnectionString(sqlConnectionPath); err != nil {
+			setupLog.Error(err, "unable to configure Helm storage driver")
+			os.Exit(1)
+		}
+	}
 
 	// Configure the digest algorithm.
+	intdigest.FIPS = fipsMode
 	if snapshotDigestAlgo != intdigest.Canonical.String() {
 		algo, err := intdigest.AlgorithmForName(snapshotDigestAlgo)
 		if err != nil {
@@ -197,8 +411,88 @@ func main() {
 		intdigest.Canonical = algo
 	}
 
+	// Load the controller configuration file, which takes precedence over
+	// the flags.
+	var controllerConfig *intconfig.Config
+	if configFilePath != "" {
+		if controllerConfig, err = intconfig.Load(configFilePath); err != nil {
+			setupLog.Error(err, "unable to load controller configuration")
+			os.Exit(1)
+		}
+	}
+
+	// Configure the number of concurrent reconciles, which can be changed at
+	// runtime up to the number of workers of the controller.
+	maxConcurrent = max(maxConcurrent, concurrent)
+	if controllerConfig != nil && controllerConfig.Concurrent != nil {
+		maxConcurrent = max(maxConcurrent, *controllerConfig.Concurrent)
+	}
+	reconcileConcurrency := concurrency.NewLimiter(concurrent)
+	action.SetDiffConcurrency(concurrentDriftDetection)
+
+	// Limit the drain timeout to the graceful shutdown timeout, as the manager
+	// forcibly stops after this duration regardless.
+	if drainTimeout > gracefulShutdownTimeout {
+		setupLog.Info(fmt.Sprintf("drain timeout %s exceeds graceful shutdown timeout, limiting it to %s",
+			drainTimeout, gracefulShutdownTimeout))
+		drainTimeout = gracefulShutdownTimeout
+	}
+
+	interruptedPolicy, err := intreconcile.ParseInterruptedReleasePolicy(interruptedReleasePolicy)
+	if err != nil {
+		setupLog.Error(err, "unable to configure interrupted release policy")
+		os.Exit(1)
+	}
+
+	if freezeActions {
+		freeze.Set(&freeze.Freeze{})
+	}
+
 	restConfig := client.GetConfigOrDie(clientOptions)
 
+	metricsHandlers := pprof.GetHandlers()
+	// The endpoints which authenticate requests with bearer tokens are served
+	// over TLS, separately from the metrics.
+	endpointHandlers := make(map[string]http.Handler)
+	if ok, _ := features.Enabled(features.DebugEndpoint); ok {
+		setupLog.Info("enabling HelmRelease debug endpoint", "path", intdebug.Path)
+		// Use a client which reads directly from the API server, as the
+		// handler is registered before the manager is created, and to dump
+		// the latest state of the object.
+		debugClient, err := ctrlclient.New(restConfig, ctrlclient.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for HelmRelease debug endpoint")
+			os.Exit(1)
+		}
+		endpointHandlers[intdebug.Path] = intdebug.NewHandler(debugClient)
+	}
+	var previewHandler *preview.Handler
+	if ok, _ := features.Enabled(features.PreviewEndpoint); ok {
+		setupLog.Info("enabling HelmRelease preview endpoint", "path", preview.Path)
+		// Use a client which reads directly from the API server, as the
+		// handler is registered before the manager is created.
+		previewClient, err := ctrlclient.New(restConfig, ctrlclient.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for HelmRelease preview endpoint")
+			os.Exit(1)
+		}
+		previewHandler = preview.NewHandler(previewClient)
+		endpointHandlers[preview.Path] = previewHandler
+	}
+	var manifestHandler *manifest.Handler
+	if ok, _ := features.Enabled(features.ManifestEndpoint); ok {
+		setupLog.Info("enabling HelmRelease manifest endpoint", "path", manifest.Path)
+		// Use a client which reads directly from the API server, as the
+		// handler is registered before the manager is created.
+		manifestClient, err := ctrlclient.New(restConfig, ctrlclient.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for HelmRelease manifest endpoint")
+			os.Exit(1)
+		}
+		manifestHandler = manifest.NewHandler(manifestClient)
+		endpointHandlers[manifest.Path] = manifestHandler
+	}
+
 	mgrConfig := ctrl.Options{
 		Scheme:                        scheme,
 		HealthProbeBindAddress:        healthAddr,
@@ -216,20 +510,22 @@ func main() {
 			},
 		},
 		Cache: ctrlcache.Options{
-			ByObject: map[ctrlclient.Object]ctrlcache.ByObject{
-				&v2.HelmRelease{}: {Label: watchSelector},
-			},
+			ByObject: cacheByObject,
 		},
 		Controller: ctrlcfg.Controller{
 			RecoverPanic:            ptr.To(true),
-			MaxConcurrentReconciles: concurrent,
+			MaxConcurrentReconciles: maxConcurrent,
 		},
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
-			ExtraHandlers: pprof.GetHandlers(),
+			ExtraHandlers: metricsHandlers,
 		},
 	}
 
+	if webhookOptions.Enabled() {
+		mgrConfig.WebhookServer = webhookOptions.Server()
+	}
+
 	if watchNamespace != "" {
 		mgrConfig.Cache.DefaultNamespaces = map[string]ctrlcache.Config{
 			watchNamespace: ctrlcache.Config{},
@@ -244,6 +540,70 @@ func main() {
 
 	probes.SetupChecks(mgr, setupLog)
 
+	if len(endpointHandlers) > 0 {
+		if !endpointsOptions.Enabled() {
+			setupLog.Error(errors.New("no port configured"), "unable to serve authenticated HelmRelease endpoints, set --endpoints-port")
+			os.Exit(1)
+		}
+		if err = mgr.Add(endpointsOptions.Server(endpointHandlers)); err != nil {
+			setupLog.Error(err, "unable to add server of authenticated HelmRelease endpoints")
+			os.Exit(1)
+		}
+	}
+
+	// Periodically verify the controller can write to and read from the Helm
+	// storage backend, in the namespace the controller runs in.
+	var storageChecker *storagecheck.Checker
+	if runtimeNamespace := os.Getenv("RUNTIME_NAMESPACE"); storageCheckOptions.Interval > 0 && runtimeNamespace != "" {
+		factory, err := action.NewConfigFactory(intkube.NewMemoryRESTClientGetter(restConfig),
+			action.WithStorageMetadata(storageLabels, storageAnnotations),
+			action.WithStorage(helmStorageDriver, runtimeNamespace))
+		if err != nil {
+			setupLog.Error(err, "unable to setup Helm storage check")
+			os.Exit(1)
+		}
+		// The hostname of the container is the name of its Pod.
+		instance, err := os.Hostname()
+		if err != nil {
+			setupLog.Error(err, "unable to setup Helm storage check")
+			os.Exit(1)
+		}
+		if storageChecker, err = storagecheck.New(factory.Driver, runtimeNamespace, instance, storageCheckOptions,
+			ctrl.Log.WithName("storage-check")); err != nil {
+			setupLog.Error(err, "unable to setup Helm storage check")
+			os.Exit(1)
+		}
+		if err = mgr.Add(storageChecker); err != nil {
+			setupLog.Error(err, "unable to add Helm storage check to manager")
+			os.Exit(1)
+		}
+	}
+
+	// Report the controller as degraded when one of its dependencies can not
+	// be reached, if enabled.
+	artifactHost := health.NewArtifactHost()
+	discoveryConfig := rest.CopyConfig(restConfig)
+	discoveryConfig.Timeout = healthOptions.Timeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(discoveryConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client for readiness checks")
+		os.Exit(1)
+	}
+	dependencyChecks := map[string]healthz.Checker{
+		health.DiscoveryCheck:    health.Discovery(discoveryClient),
+		health.ArtifactHostCheck: artifactHost.Check,
+	}
+	switch {
+	case storageChecker != nil:
+		dependencyChecks[health.StorageCheck] = storageChecker.Check
+	case helmStorageDriver == helmdriver.SQLDriverName:
+		dependencyChecks[health.StorageCheck] = health.Ping(action.PingSQLStorage)
+	}
+	if err = health.SetupChecks(mgr, healthOptions, dependencyChecks); err != nil {
+		setupLog.Error(err, "unable to create dependency readiness checks")
+		os.Exit(1)
+	}
+
 	metricsH := helper.NewMetrics(mgr, metrics.MustMakeRecorder(), v2.HelmReleaseFinalizer)
 	var eventRecorder *events.Recorder
 	if eventRecorder, err = events.NewRecorder(mgr, ctrl.Log, eventsAddr, controllerName); err != nil {
@@ -251,7 +611,83 @@ func main() {
 		os.Exit(1)
 	}
 
+	var recorder kuberecorder.EventRecorder = eventRecorder
+	if ok, _ := features.Enabled(features.EventSinks); ok {
+		eventsSink := intevents.NewSink(eventRecorder, mgr.GetClient(), outboundClient, controllerName,
+			ctrl.Log.WithName("event-sink"))
+		if err = mgr.Add(eventsSink); err != nil {
+			setupLog.Error(err, "unable to add event sink")
+			os.Exit(1)
+		}
+		recorder = eventsSink
+	}
+	eventsFilter := intevents.NewFilter(intevents.NewMetadata(recorder), eventsDedupInterval)
+
+	// Apply the controller configuration file, and again when it changes.
+	if controllerConfig != nil {
+		applyConfig := func(cfg *intconfig.Config) {
+			reconciles := concurrent
+			if cfg.Concurrent != nil {
+				reconciles = *cfg.Concurrent
+			}
+			if reconciles > maxConcurrent {
+				setupLog.Info(fmt.Sprintf("concurrent reconciles %d exceeds max concurrent reconciles, limiting it to %d",
+					reconciles, maxConcurrent))
+				reconciles = maxConcurrent
+			}
+			reconcileConcurrency.SetLimit(reconciles)
+			driftDetections := concurrentDriftDetection
+			if cfg.ConcurrentDriftDetection != nil {
+				driftDetections = *cfg.ConcurrentDriftDetection
+			}
+			action.SetDiffConcurrency(driftDetections)
+			var defaultTimeout time.Duration
+			if cfg.DefaultTimeout != nil {
+				defaultTimeout = cfg.DefaultTimeout.Duration
+			}
+			action.SetDefaultTimeout(defaultTimeout)
+			dedupInterval := eventsDedupInterval
+			if cfg.EventsDedupInterval != nil {
+				dedupInterval = cfg.EventsDedupInterval.Duration
+			}
+			eventsFilter.SetInterval(dedupInterval)
+			// The feature gates have been validated when loading the file.
+			_ = features.SetReloadable(cfg.FeatureGates)
+			signature.SetRegistryMirrors(cfg.RegistryMirrors)
+			switch {
+			case cfg.Freeze != nil:
+				f := &freeze.Freeze{Reason: cfg.Freeze.Reason}
+				if cfg.Freeze.Until != nil {
+					f.Until = cfg.Freeze.Until.Time
+				}
+				freeze.Set(f)
+			case freezeActions:
+				freeze.Set(&freeze.Freeze{})
+			default:
+				freeze.Set(nil)
+			}
+		}
+		applyConfig(controllerConfig)
+		if err = mgr.Add(intconfig.NewWatcher(configFilePath, applyConfig, ctrl.Log.WithName("config"))); err != nil {
+			setupLog.Error(err, "unable to add controller configuration watcher to manager")
+			os.Exit(1)
+		}
+	}
+
 	ctx := ctrl.SetupSignalHandler()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingOptions, controllerName)
+	if err != nil {
+		setupLog.Error(err, "unable to setup tracing")
+		os.Exit(1)
+	}
+
+	closeAudit, err := audit.Setup(auditOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to setup audit log")
+		os.Exit(1)
+	}
+
 	if ok, _ := features.Enabled(features.OOMWatch); ok {
 		setupLog.Info("setting up OOM watcher")
 		ow, err := oomwatch.New(
@@ -268,27 +704,94 @@ func main() {
 		ctx = ow.Watch(ctx)
 	}
 
-	if err = (&controller.HelmReleaseReconciler{
-		Client:           mgr.GetClient(),
-		EventRecorder:    eventRecorder,
-		Metrics:          metricsH,
-		GetClusterConfig: ctrl.GetConfig,
-		ClientOpts:       clientOptions,
-		KubeConfigOpts:   kubeConfigOpts,
-		FieldManager:     controllerName,
-	}).SetupWithManager(ctx, mgr, controller.HelmReleaseReconcilerOptions{
+	var serviceAccountTokenCache *intkube.TokenCache
+	if serviceAccountTokens {
+		clientSet, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to create client for service account tokens")
+			os.Exit(1)
+		}
+		serviceAccountTokenCache = intkube.NewTokenCache(clientSet.CoreV1(), serviceAccountAudiences, serviceAccountTokenExpiry)
+	}
+
+	helmReleaseReconciler := &controller.HelmReleaseReconciler{
+		Client:               mgr.GetClient(),
+		APIReader:            mgr.GetAPIReader(),
+		EventRecorder:        eventsFilter,
+		Metrics:              metricsH,
+		GetClusterConfig:     ctrl.GetConfig,
+		ClientOpts:           clientOptions,
+		KubeConfigOpts:       kubeConfigOpts,
+		FieldManager:         controllerName,
+		ServiceAccountTokens: serviceAccountTokenCache,
+		StorageDriver:        helmStorageDriver,
+		StorageLabels:        storageLabels,
+		StorageAnnotations:   storageAnnotations,
+	}
+	if ok, _ := features.Enabled(features.ActionWebhooks); ok {
+		helmReleaseReconciler.ActionWebhookClient = outboundClient
+	}
+	if err = helmReleaseReconciler.SetupWithManager(ctx, mgr, controller.HelmReleaseReconcilerOptions{
 		DependencyRequeueInterval: requeueDependency,
 		HTTPRetry:                 httpRetry,
+		DrainTimeout:              drainTimeout,
 		RateLimiter:               helper.GetRateLimiter(rateLimiterOptions),
+		RateLimiterOptions:        rateLimiterOptions,
+		Concurrency:               reconcileConcurrency,
+		RolloutGroups:             rollout.NewGroups(rolloutGroupConcurrency),
+		ArtifactHost:              artifactHost,
+		InterruptedReleasePolicy:  interruptedPolicy,
 	}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", v2.HelmReleaseKind)
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
 
+	if webhookOptions.Enabled() {
+		if err = intwebhook.Setup(ctx, mgr, webhookOptions, os.Getenv("RUNTIME_NAMESPACE")); err != nil {
+			setupLog.Error(err, "unable to setup admission webhooks")
+			os.Exit(1)
+		}
+	}
+
+	if previewHandler != nil {
+		previewHandler.SetPreviewer(helmReleaseReconciler)
+	}
+	if manifestHandler != nil {
+		manifestHandler.SetGetter(helmReleaseReconciler)
+	}
+
+	if ok, _ := features.Enabled(features.GarbageCollectStorage); ok {
+		setupLog.Info("setting up Helm storage garbage collection")
+		sweeper, err := storagegc.New(mgr.GetAPIReader(), mgr.GetClient(), watchNamespace,
+			storageGCOptions, ctrl.Log.WithName("storage-gc"))
+		if err != nil {
+			setupLog.Error(err, "unable to setup Helm storage garbage collection")
+			os.Exit(1)
+		}
+		if err = mgr.Add(sweeper); err != nil {
+			setupLog.Error(err, "unable to add Helm storage garbage collection to manager")
+			os.Exit(1)
+		}
+	}
+
+	if watchOptions.LabelSelector != "" {
+		crtlmetrics.Registry.MustRegister(intmetrics.NewShardCollector(mgr.GetCache()))
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	// Flush any remaining spans before exiting.
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		setupLog.Error(err, "failed to flush traces")
+	}
+	if err := closeAudit(); err != nil {
+		setupLog.Error(err, "failed to close audit log")
+	}
 }
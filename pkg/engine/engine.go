@@ -0,0 +1,386 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package engine provides the release engine of helm-controller, for
+// embedding the install, upgrade, test and remediation logic of a HelmRelease
+// in other operators.
+//
+// The package exposes a stable API on top of the internal packages of the
+// controller: the ConfigFactory to configure the Helm actions, the Request
+// describing the desired state of a release, and the ActionReconcilers which
+// act on it. The AtomicRelease reconciler combines the ActionReconcilers to
+// bring a release to the desired state, like the controller does for every
+// reconciliation of a HelmRelease. The types of the package are converted to
+// the internal types of the controller when calling into it, so changes to the
+// implementation do not change the API of the package.
+//
+// The results of the actions are written to the status of the v2.HelmRelease
+// of the Request. The caller is responsible for persisting the object.
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/fluxcd/pkg/runtime/patch"
+	helmaction "helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/record"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/action"
+	"github.com/fluxcd/helm-controller/internal/reconcile"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/rollout"
+)
+
+// ConfigFactory is a factory for the Helm action configuration of a
+// HelmRelease.
+type ConfigFactory struct {
+	factory *action.ConfigFactory
+}
+
+// ConfigFactoryOption is a function that configures a ConfigFactory.
+type ConfigFactoryOption func(*ConfigFactory) error
+
+// NewConfigFactory returns a new ConfigFactory configured with the given
+// options.
+func NewConfigFactory(getter genericclioptions.RESTClientGetter, opts ...ConfigFactoryOption) (*ConfigFactory, error) {
+	factory, err := action.NewConfigFactory(getter, func(f *action.ConfigFactory) error {
+		c := &ConfigFactory{factory: f}
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigFactory{factory: factory}, nil
+}
+
+// WithStorage configures the Helm storage driver and namespace of the
+// ConfigFactory.
+func WithStorage(driver, namespace string) ConfigFactoryOption {
+	return func(c *ConfigFactory) error {
+		return action.WithStorage(driver, namespace)(c.factory)
+	}
+}
+
+// WithStorageMetadata configures the labels and annotations of the Helm
+// storage Secrets created by the ConfigFactory.
+func WithStorageMetadata(labels, annotations map[string]string) ConfigFactoryOption {
+	return func(c *ConfigFactory) error {
+		return action.WithStorageMetadata(labels, annotations)(c.factory)
+	}
+}
+
+// WithDriver configures the Helm storage driver of the ConfigFactory.
+func WithDriver(driver helmdriver.Driver) ConfigFactoryOption {
+	return func(c *ConfigFactory) error {
+		return action.WithDriver(driver)(c.factory)
+	}
+}
+
+// WithStorageLog configures the logger of the Helm storage of the
+// ConfigFactory.
+func WithStorageLog(log helmaction.DebugLog) ConfigFactoryOption {
+	return func(c *ConfigFactory) error {
+		return action.WithStorageLog(log)(c.factory)
+	}
+}
+
+// Driver returns the Helm storage driver of the ConfigFactory.
+func (c *ConfigFactory) Driver() helmdriver.Driver {
+	if f := c.internal(); f != nil {
+		return f.Driver
+	}
+	return nil
+}
+
+// Valid returns an error if the ConfigFactory is missing configuration
+// required to run a Helm action.
+func (c *ConfigFactory) Valid() error {
+	return c.internal().Valid()
+}
+
+// internal returns the action.ConfigFactory of the ConfigFactory.
+func (c *ConfigFactory) internal() *action.ConfigFactory {
+	if c == nil {
+		return nil
+	}
+	return c.factory
+}
+
+// Request is a request to be performed by an ActionReconciler.
+type Request struct {
+	// Object is the HelmRelease to be reconciled, and describes the desired
+	// state to the ActionReconciler. The result of the request is written
+	// to its status.
+	Object *v2.HelmRelease
+	// Chart is the Helm chart to be installed or upgraded.
+	Chart *helmchart.Chart
+	// Values is the Helm chart values to be used for the installation or
+	// upgrade.
+	Values helmchartutil.Values
+	// Provenance is the metadata of the source the Chart originates from,
+	// which is recorded on the release snapshot and events of an
+	// installation or upgrade.
+	Provenance map[string]string
+}
+
+// internal returns the reconcile.Request of the Request.
+func (r *Request) internal() *reconcile.Request {
+	return &reconcile.Request{
+		Object:     r.Object,
+		Chart:      r.Chart,
+		Values:     r.Values,
+		Provenance: r.Provenance,
+	}
+}
+
+// ActionReconciler is the interface of a reconciler of a Helm action.
+type ActionReconciler interface {
+	// Reconcile performs the action for the given Request, and writes the
+	// result to the status of the Request.Object. An error is returned if
+	// the action cannot be performed and did not result in a modification
+	// of the Helm storage.
+	Reconcile(ctx context.Context, req *Request) error
+	// Name returns the name of the ActionReconciler.
+	Name() string
+	// Type returns the ReconcilerType of the ActionReconciler.
+	Type() ReconcilerType
+}
+
+// ReconcilerType identifies the type of an ActionReconciler.
+type ReconcilerType string
+
+const (
+	// ReconcilerTypeRelease is an ActionReconciler which produces a new
+	// release.
+	ReconcilerTypeRelease = ReconcilerType(reconcile.ReconcilerTypeRelease)
+	// ReconcilerTypeRemediate is an ActionReconciler which remediates a
+	// failed release.
+	ReconcilerTypeRemediate = ReconcilerType(reconcile.ReconcilerTypeRemediate)
+	// ReconcilerTypeTest is an ActionReconciler which tests a release.
+	ReconcilerTypeTest = ReconcilerType(reconcile.ReconcilerTypeTest)
+	// ReconcilerTypeUnlock is an ActionReconciler which unlocks a release.
+	ReconcilerTypeUnlock = ReconcilerType(reconcile.ReconcilerTypeUnlock)
+	// ReconcilerTypeDriftCorrection is an ActionReconciler which corrects
+	// the drift of a release from the cluster state.
+	ReconcilerTypeDriftCorrection = ReconcilerType(reconcile.ReconcilerTypeDriftCorrection)
+)
+
+// actionReconciler is an ActionReconciler performing the Request with a
+// reconcile.ActionReconciler.
+type actionReconciler struct {
+	reconciler reconcile.ActionReconciler
+}
+
+// Reconcile implements ActionReconciler.
+func (r actionReconciler) Reconcile(ctx context.Context, req *Request) error {
+	return r.reconciler.Reconcile(ctx, req.internal())
+}
+
+// Name implements ActionReconciler.
+func (r actionReconciler) Name() string {
+	return r.reconciler.Name()
+}
+
+// Type implements ActionReconciler.
+func (r actionReconciler) Type() ReconcilerType {
+	return ReconcilerType(r.reconciler.Type())
+}
+
+var (
+	// ErrExceededMaxRetries is returned by AtomicRelease when there are no
+	// remaining retry attempts for the release config.
+	ErrExceededMaxRetries = reconcile.ErrExceededMaxRetries
+	// ErrMustRequeue is returned by AtomicRelease when the caller must
+	// requeue the object to continue the reconciliation.
+	ErrMustRequeue = reconcile.ErrMustRequeue
+	// ErrMissingRollbackTarget is returned by AtomicRelease when the target
+	// release for a rollback is missing.
+	ErrMissingRollbackTarget = reconcile.ErrMissingRollbackTarget
+	// ErrWaitForRolloutGroup is returned by AtomicRelease when the upgrade
+	// of the release must wait for other members of its rollout group.
+	ErrWaitForRolloutGroup = reconcile.ErrWaitForRolloutGroup
+	// ErrWaitForCanary is returned by AtomicRelease when the upgrade of the
+	// release must wait for the analysis of its canary release.
+	ErrWaitForCanary = reconcile.ErrWaitForCanary
+	// ErrCanaryFailed is returned by AtomicRelease when the release is not
+	// upgraded because the analysis of its canary release failed.
+	ErrCanaryFailed = reconcile.ErrCanaryFailed
+)
+
+// OwnedConditions returns the condition types the release engine owns on
+// the status of a v2.HelmRelease.
+func OwnedConditions() []string {
+	return append([]string(nil), reconcile.OwnedConditions...)
+}
+
+// AtomicRelease is an ActionReconciler which runs the ActionReconcilers
+// required to bring the release of a Request to the desired state.
+type AtomicRelease struct {
+	actionReconciler
+}
+
+// AtomicReleaseOption is a function that configures an AtomicRelease.
+type AtomicReleaseOption func(*atomicReleaseOptions)
+
+// atomicReleaseOptions holds the options of the reconcile.AtomicRelease of
+// an AtomicRelease.
+type atomicReleaseOptions struct {
+	opts []reconcile.AtomicReleaseOption
+}
+
+// WithDrainTimeout configures the time an in-flight Helm action of the
+// AtomicRelease is allowed to complete after the context is canceled.
+func WithDrainTimeout(timeout time.Duration) AtomicReleaseOption {
+	return func(o *atomicReleaseOptions) {
+		o.opts = append(o.opts, reconcile.WithDrainTimeout(timeout))
+	}
+}
+
+// RolloutGroups coordinates the upgrades of the HelmReleases in rollout
+// groups, which are labeled with v2.RolloutGroupLabel.
+type RolloutGroups struct {
+	groups *rollout.Groups
+}
+
+// NewRolloutGroups returns new RolloutGroups allowing the given number of
+// members of a group to upgrade at the same time.
+func NewRolloutGroups(limit int) *RolloutGroups {
+	return &RolloutGroups{groups: rollout.NewGroups(limit)}
+}
+
+// Active returns the members of the given group which are upgrading.
+func (g *RolloutGroups) Active(group string) []string {
+	return g.groups.Active(group)
+}
+
+// WithRolloutGroups configures the RolloutGroups which coordinate the
+// upgrades of the AtomicRelease. The same RolloutGroups must be shared by
+// all AtomicReleases of the members of a group.
+func WithRolloutGroups(groups *RolloutGroups) AtomicReleaseOption {
+	return func(o *atomicReleaseOptions) {
+		if groups != nil {
+			o.opts = append(o.opts, reconcile.WithRolloutGroups(groups.groups))
+		}
+	}
+}
+
+// NewAtomicRelease returns a new AtomicRelease. The patch helper is used to
+// persist the status of the object between the actions.
+func NewAtomicRelease(patchHelper *patch.SerialPatcher, cfg *ConfigFactory, recorder record.EventRecorder,
+	fieldManager string, opts ...AtomicReleaseOption) *AtomicRelease {
+	o := &atomicReleaseOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &AtomicRelease{actionReconciler{
+		reconciler: reconcile.NewAtomicRelease(patchHelper, cfg.internal(), recorder, fieldManager, o.opts...),
+	}}
+}
+
+// NewInstall returns an ActionReconciler which installs the release.
+func NewInstall(cfg *ConfigFactory, recorder record.EventRecorder) ActionReconciler {
+	return actionReconciler{reconciler: reconcile.NewInstall(cfg.internal(), recorder)}
+}
+
+// NewUpgrade returns an ActionReconciler which upgrades the release.
+func NewUpgrade(cfg *ConfigFactory, recorder record.EventRecorder) ActionReconciler {
+	return actionReconciler{reconciler: reconcile.NewUpgrade(cfg.internal(), recorder)}
+}
+
+// NewTest returns an ActionReconciler which tests the release.
+func NewTest(cfg *ConfigFactory, recorder record.EventRecorder) ActionReconciler {
+	return actionReconciler{reconciler: reconcile.NewTest(cfg.internal(), recorder)}
+}
+
+// NewRollbackRemediation returns an ActionReconciler which remediates a
+// failed release by rolling back to the previous release.
+func NewRollbackRemediation(cfg *ConfigFactory, recorder record.EventRecorder) ActionReconciler {
+	return actionReconciler{reconciler: reconcile.NewRollbackRemediation(cfg.internal(), recorder)}
+}
+
+// NewUninstallRemediation returns an ActionReconciler which remediates a
+// failed release by uninstalling it.
+func NewUninstallRemediation(cfg *ConfigFactory, recorder record.EventRecorder) ActionReconciler {
+	return actionReconciler{reconciler: reconcile.NewUninstallRemediation(cfg.internal(), recorder)}
+}
+
+// NewUninstall returns an ActionReconciler which uninstalls the release.
+func NewUninstall(cfg *ConfigFactory, recorder record.EventRecorder) ActionReconciler {
+	return actionReconciler{reconciler: reconcile.NewUninstall(cfg.internal(), recorder)}
+}
+
+// NewUnlock returns an ActionReconciler which unlocks a release stuck in a
+// pending state.
+func NewUnlock(cfg *ConfigFactory, recorder record.EventRecorder) ActionReconciler {
+	return actionReconciler{reconciler: reconcile.NewUnlock(cfg.internal(), recorder)}
+}
+
+// Observation is the observation of a Helm release, as recorded in the
+// history of a v2.HelmRelease.
+type Observation struct {
+	// Name of the release.
+	Name string
+	// Version of the release, at times also called revision.
+	Version int
+	// Info provides information about the release.
+	Info helmrelease.Info
+	// ChartMetadata contains the current Chartfile data of the release.
+	ChartMetadata helmchart.Metadata
+	// Config is the set of extra Values added to the chart.
+	Config map[string]interface{}
+	// Manifest is the string representation of the rendered template.
+	Manifest string
+	// Hooks are all the hooks declared for this release, and the current
+	// state they are in.
+	Hooks []helmrelease.Hook
+	// Namespace is the Kubernetes namespace of the release.
+	Namespace string
+	// OCIDigest is the digest of the OCI artifact the chart of the release
+	// was pulled from.
+	OCIDigest string
+	// Provenance is the metadata of the source the chart of the release
+	// originates from.
+	Provenance map[string]string
+}
+
+// ObserveRelease returns an Observation of the given Helm release.
+func ObserveRelease(rls *helmrelease.Release) Observation {
+	obs := release.ObserveRelease(rls)
+	return Observation{
+		Name:          obs.Name,
+		Version:       obs.Version,
+		Info:          obs.Info,
+		ChartMetadata: obs.ChartMetadata,
+		Config:        obs.Config,
+		Manifest:      obs.Manifest,
+		Hooks:         obs.Hooks,
+		Namespace:     obs.Namespace,
+		OCIDigest:     obs.OCIDigest,
+		Provenance:    obs.Provenance,
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmchartutil "helm.sh/helm/v3/pkg/chartutil"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/kube"
+)
+
+func TestNewConfigFactory(t *testing.T) {
+	getter := kube.NewMemoryRESTClientGetter(&rest.Config{Host: "https://example.com"})
+
+	t.Run("with storage", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cfg, err := NewConfigFactory(getter,
+			WithStorageMetadata(map[string]string{"team": "a"}, nil),
+			WithStorage(helmdriver.MemoryDriverName, "default"),
+		)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cfg.Valid()).To(Succeed())
+		g.Expect(cfg.Driver()).To(BeAssignableToTypeOf(&helmdriver.Memory{}))
+	})
+
+	t.Run("with invalid storage", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := NewConfigFactory(getter, WithStorage("invalid", "default"))
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("without storage", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := NewConfigFactory(getter)
+		g.Expect(err).To(MatchError("no Helm storage driver configured"))
+	})
+}
+
+func TestRequest_internal(t *testing.T) {
+	g := NewWithT(t)
+
+	req := &Request{
+		Object:     &v2.HelmRelease{},
+		Values:     helmchartutil.Values{"foo": "bar"},
+		Provenance: map[string]string{"revision": "v1.0.0"},
+	}
+	got := req.internal()
+	g.Expect(got.Object).To(BeIdenticalTo(req.Object))
+	g.Expect(got.Values).To(Equal(req.Values))
+	g.Expect(got.Provenance).To(Equal(req.Provenance))
+}
+
+func TestObserveRelease(t *testing.T) {
+	g := NewWithT(t)
+
+	rls := helmrelease.Mock(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Namespace: "default",
+		Version:   2,
+		Status:    helmrelease.StatusDeployed,
+	})
+	obs := ObserveRelease(rls)
+	g.Expect(obs.Name).To(Equal("release"))
+	g.Expect(obs.Namespace).To(Equal("default"))
+	g.Expect(obs.Version).To(Equal(2))
+	g.Expect(obs.Info.Status).To(Equal(helmrelease.StatusDeployed))
+	g.Expect(obs.ChartMetadata.Name).To(Equal(rls.Chart.Metadata.Name))
+	g.Expect(obs.Manifest).To(Equal(rls.Manifest))
+	g.Expect(obs.Hooks).To(HaveLen(len(rls.Hooks)))
+}
+
+func TestActionReconcilers(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(10)
+	cfg := &ConfigFactory{}
+
+	for _, tt := range []struct {
+		reconciler ActionReconciler
+		want       ReconcilerType
+	}{
+		{NewInstall(cfg, recorder), ReconcilerTypeRelease},
+		{NewUpgrade(cfg, recorder), ReconcilerTypeRelease},
+		{NewTest(cfg, recorder), ReconcilerTypeTest},
+		{NewRollbackRemediation(cfg, recorder), ReconcilerTypeRemediate},
+		{NewUninstallRemediation(cfg, recorder), ReconcilerTypeRemediate},
+		{NewUninstall(cfg, recorder), ReconcilerTypeRelease},
+		{NewUnlock(cfg, recorder), ReconcilerTypeUnlock},
+		{NewAtomicRelease(nil, cfg, recorder, "helm-controller"), ReconcilerTypeRelease},
+	} {
+		g.Expect(tt.reconciler.Type()).To(Equal(tt.want), tt.reconciler.Name())
+	}
+}
+
+func TestOwnedConditions(t *testing.T) {
+	g := NewWithT(t)
+
+	conditions := OwnedConditions()
+	g.Expect(conditions).ToNot(BeEmpty())
+
+	conditions[0] = "mutated"
+	g.Expect(OwnedConditions()[0]).ToNot(Equal("mutated"))
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package enginetest provides helpers for testing code which creates
+// HelmReleases, or embeds the release engine of the engine package.
+//
+// It offers a ConfigFactory backed by in-memory Helm storage, and mock
+// charts, releases and snapshots to compose the Helm storage and the status
+// of a v2.HelmRelease, as the controller would observe them.
+package enginetest
+
+import (
+	"fmt"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	helmdriver "helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/rest"
+
+	v2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/helm-controller/internal/kube"
+	"github.com/fluxcd/helm-controller/internal/release"
+	"github.com/fluxcd/helm-controller/internal/testutil"
+	"github.com/fluxcd/helm-controller/pkg/engine"
+)
+
+// NewConfigFactory returns an engine.ConfigFactory for the cluster of the
+// given REST config (e.g. of an envtest environment), which stores the
+// releases in memory instead of in the cluster. The Helm storage is seeded
+// with the given releases, and can be accessed with the Driver method of the
+// returned ConfigFactory.
+func NewConfigFactory(cfg *rest.Config, namespace string, releases ...*helmrelease.Release) (*engine.ConfigFactory, error) {
+	driver := helmdriver.NewMemory()
+	driver.SetNamespace(namespace)
+	store := helmstorage.Init(driver)
+	for _, rls := range releases {
+		if err := store.Create(rls); err != nil {
+			return nil, fmt.Errorf("failed to seed Helm storage with release '%s/%s.v%d': %w",
+				rls.Namespace, rls.Name, rls.Version, err)
+		}
+	}
+
+	getter := kube.NewMemoryRESTClientGetter(cfg, kube.WithNamespace(namespace))
+	return engine.NewConfigFactory(getter, engine.WithDriver(driver))
+}
+
+// ChartOption is a function that modifies a mock chart.
+type ChartOption func(*chartOptions)
+
+// chartOptions holds the testutil.ChartOption of the options given to
+// NewChart.
+type chartOptions struct {
+	opts []testutil.ChartOption
+}
+
+// NewChart returns a mock chart with a ConfigMap template, modified with the
+// given options.
+func NewChart(opts ...ChartOption) *helmchart.Chart {
+	o := &chartOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return testutil.BuildChart(o.opts...)
+}
+
+// ChartWithName sets the name of the chart.
+func ChartWithName(name string) ChartOption {
+	return func(o *chartOptions) {
+		o.opts = append(o.opts, testutil.ChartWithName(name))
+	}
+}
+
+// ChartWithVersion sets the version of the chart.
+func ChartWithVersion(version string) ChartOption {
+	return func(o *chartOptions) {
+		o.opts = append(o.opts, testutil.ChartWithVersion(version))
+	}
+}
+
+// ChartWithFailingHook adds a hook to the chart which fails on install and
+// upgrade.
+func ChartWithFailingHook() ChartOption {
+	return func(o *chartOptions) {
+		o.opts = append(o.opts, testutil.ChartWithFailingHook())
+	}
+}
+
+// ChartWithTestHook adds a succeeding test hook to the chart.
+func ChartWithTestHook() ChartOption {
+	return func(o *chartOptions) {
+		o.opts = append(o.opts, testutil.ChartWithTestHook())
+	}
+}
+
+// ChartWithFailingTestHook adds a failing test hook to the chart.
+func ChartWithFailingTestHook() ChartOption {
+	return func(o *chartOptions) {
+		o.opts = append(o.opts, testutil.ChartWithFailingTestHook())
+	}
+}
+
+// ReleaseOption is a function that modifies a mock release.
+type ReleaseOption func(*releaseOptions)
+
+// releaseOptions holds the testutil.ReleaseOption of the options given to
+// NewRelease.
+type releaseOptions struct {
+	opts []testutil.ReleaseOption
+}
+
+// NewRelease returns a mock release for the given options, modified with the
+// given release options.
+func NewRelease(mockOpts *helmrelease.MockReleaseOptions, opts ...ReleaseOption) *helmrelease.Release {
+	o := &releaseOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return testutil.BuildRelease(mockOpts, o.opts...)
+}
+
+// ReleaseWithConfig sets the values of the release.
+func ReleaseWithConfig(config map[string]interface{}) ReleaseOption {
+	return func(o *releaseOptions) {
+		o.opts = append(o.opts, testutil.ReleaseWithConfig(config))
+	}
+}
+
+// ReleaseWithDescription sets the description of the release.
+func ReleaseWithDescription(description string) ReleaseOption {
+	return func(o *releaseOptions) {
+		o.opts = append(o.opts, testutil.ReleaseWithDescription(description))
+	}
+}
+
+// ReleaseWithTestHook adds an executed, succeeded test hook to the release.
+func ReleaseWithTestHook() ReleaseOption {
+	return func(o *releaseOptions) {
+		o.opts = append(o.opts, testutil.ReleaseWithTestHook())
+	}
+}
+
+// ReleaseWithFailingTestHook adds an executed, failed test hook to the
+// release.
+func ReleaseWithFailingTestHook() ReleaseOption {
+	return func(o *releaseOptions) {
+		o.opts = append(o.opts, testutil.ReleaseWithFailingTestHook())
+	}
+}
+
+// Snapshot returns the v2.Snapshot the controller records in the history of
+// a HelmRelease for the given release, including the results of its tests.
+func Snapshot(rls *helmrelease.Release) *v2.Snapshot {
+	snap := release.ObservedToSnapshot(release.ObserveRelease(rls))
+	if tests := release.TestHooksFromRelease(rls); len(tests) > 0 {
+		snap.SetTestHooks(tests)
+	}
+	return snap
+}
+
+// History returns the v2.Snapshots for the given releases, sorted by version
+// from newest to oldest.
+func History(releases ...*helmrelease.Release) v2.Snapshots {
+	history := make(v2.Snapshots, 0, len(releases))
+	for _, rls := range releases {
+		history = append(history, Snapshot(rls))
+	}
+	history.SortByVersion()
+	return history
+}
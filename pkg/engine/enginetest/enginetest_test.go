@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package enginetest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	"k8s.io/client-go/rest"
+)
+
+func TestNewConfigFactory(t *testing.T) {
+	g := NewWithT(t)
+
+	chart := NewChart()
+	superseded := NewRelease(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Namespace: "default",
+		Version:   1,
+		Status:    helmrelease.StatusSuperseded,
+		Chart:     chart,
+	})
+	deployed := NewRelease(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Namespace: "default",
+		Version:   2,
+		Status:    helmrelease.StatusDeployed,
+		Chart:     chart,
+	})
+
+	cfg, err := NewConfigFactory(&rest.Config{Host: "https://example.com"}, "default", superseded, deployed)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cfg.Valid()).To(Succeed())
+
+	rls, err := helmstorage.Init(cfg.Driver()).Deployed("release")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rls.Version).To(Equal(2))
+}
+
+func TestHistory(t *testing.T) {
+	g := NewWithT(t)
+
+	chart := NewChart(ChartWithTestHook())
+	older := NewRelease(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Namespace: "default",
+		Version:   1,
+		Status:    helmrelease.StatusSuperseded,
+		Chart:     chart,
+	})
+	latest := NewRelease(&helmrelease.MockReleaseOptions{
+		Name:      "release",
+		Namespace: "default",
+		Version:   2,
+		Status:    helmrelease.StatusDeployed,
+		Chart:     chart,
+	}, ReleaseWithTestHook())
+
+	history := History(older, latest)
+	g.Expect(history).To(HaveLen(2))
+	g.Expect(history.Latest().Version).To(Equal(2))
+	g.Expect(history.Latest().Status).To(Equal(helmrelease.StatusDeployed.String()))
+	g.Expect(history.Latest().HasBeenTested()).To(BeTrue())
+	g.Expect(history.Previous(false).Version).To(Equal(1))
+	g.Expect(history.Latest().Digest).To(Equal(Snapshot(latest).Digest))
+}